@@ -0,0 +1,52 @@
+package hcl
+
+// Annotations is a side table of metadata attached to AST nodes, so that
+// multi-pass tools such as resolvers, linters and code generators can
+// decorate nodes without defining parallel data structures.
+//
+// Keys are compared as with map keys, and should be of an unexported type to
+// avoid collisions between packages, as with context.Context values.
+//
+// Annotations are keyed by node identity, so are not carried over by Clone.
+// An Annotations is not safe for concurrent use.
+type Annotations struct {
+	nodes map[Node]map[interface{}]interface{}
+}
+
+// NewAnnotations creates an empty side table.
+func NewAnnotations() *Annotations {
+	return &Annotations{nodes: map[Node]map[interface{}]interface{}{}}
+}
+
+// Set the value of key on node.
+func (a *Annotations) Set(node Node, key, value interface{}) {
+	meta, ok := a.nodes[node]
+	if !ok {
+		meta = map[interface{}]interface{}{}
+		a.nodes[node] = meta
+	}
+	meta[key] = value
+}
+
+// Get the value of key on node, and whether it was set.
+func (a *Annotations) Get(node Node, key interface{}) (interface{}, bool) {
+	value, ok := a.nodes[node][key]
+	return value, ok
+}
+
+// Delete key from node.
+func (a *Annotations) Delete(node Node, key interface{}) {
+	meta, ok := a.nodes[node]
+	if !ok {
+		return
+	}
+	delete(meta, key)
+	if len(meta) == 0 {
+		delete(a.nodes, node)
+	}
+}
+
+// Nodes returns the number of nodes with annotations.
+func (a *Annotations) Nodes() int {
+	return len(a.nodes)
+}
@@ -0,0 +1,42 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type annotationKey string
+
+func TestAnnotations(t *testing.T) {
+	ast, err := ParseString(`
+server "a" {
+  port = 80
+}
+`)
+	require.NoError(t, err)
+	block := ast.Entries[0].Block
+	port := block.Body[0].Attribute
+
+	meta := NewAnnotations()
+	meta.Set(block, annotationKey("resolved"), true)
+	meta.Set(port, annotationKey("resolved"), 8080)
+	meta.Set(port, annotationKey("lint"), "ok")
+
+	value, ok := meta.Get(port, annotationKey("resolved"))
+	require.True(t, ok)
+	require.Equal(t, 8080, value)
+	_, ok = meta.Get(port, "resolved")
+	require.False(t, ok, "keys of different types must not collide")
+	require.Equal(t, 2, meta.Nodes())
+
+	// Annotations are per node, so clones have none.
+	_, ok = meta.Get(ast.Clone().Entries[0].Block, annotationKey("resolved"))
+	require.False(t, ok)
+
+	meta.Delete(port, annotationKey("resolved"))
+	meta.Delete(port, annotationKey("lint"))
+	_, ok = meta.Get(port, annotationKey("resolved"))
+	require.False(t, ok)
+	require.Equal(t, 1, meta.Nodes())
+}
@@ -0,0 +1,142 @@
+package hcl
+
+import (
+	"sync"
+	"testing"
+)
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseString(complexHCLExample)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInterning(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := ParseString(complexHCLExample, WithInterning(true))
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseInto(b *testing.B) {
+	pool := sync.Pool{New: func() interface{} { return &AST{} }}
+	src := []byte(complexHCLExample)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ast := pool.Get().(*AST)
+		err := ParseInto(ast, src)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(ast)
+	}
+}
+
+func BenchmarkUnmarshalAST(b *testing.B) {
+	ast, err := ParseString(complexHCLExample)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		config := Config{}
+		if err := UnmarshalAST(ast, &config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	config := benchmarkConfig(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalAST(b *testing.B) {
+	ast, err := ParseString(complexHCLExample)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := MarshalAST(ast); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkConfig(tb testing.TB) *Config {
+	config := &Config{}
+	if err := Unmarshal([]byte(complexHCLExample), config); err != nil {
+		tb.Fatal(err)
+	}
+	return config
+}
+
+// TestAllocationBudgets guards against regressions in the number of
+// allocations on hot paths. Budgets include some headroom for variation
+// between Go versions.
+func TestAllocationBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation budgets are not checked in short mode")
+	}
+	if raceEnabled {
+		t.Skip("allocation budgets are not checked with the race detector enabled")
+	}
+	ast, err := ParseString(complexHCLExample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := benchmarkConfig(t)
+	tests := []struct {
+		name   string
+		budget float64
+		fn     func() error
+	}{
+		{"Parse", 4500, func() error {
+			_, err := ParseString(complexHCLExample)
+			return err
+		}},
+		{"UnmarshalAST", 300, func() error {
+			return UnmarshalAST(ast, &Config{})
+		}},
+		{"Marshal", 400, func() error {
+			_, err := Marshal(config)
+			return err
+		}},
+		{"MarshalAST", 130, func() error {
+			_, err := MarshalAST(ast)
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var err error
+			allocs := testing.AllocsPerRun(20, func() {
+				if e := test.fn(); e != nil {
+					err = e
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allocs > test.budget {
+				t.Fatalf("%s made %.0f allocations, exceeding its budget of %.0f", test.name, allocs, test.budget)
+			}
+		})
+	}
+}
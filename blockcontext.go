@@ -0,0 +1,74 @@
+package hcl
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/alecthomas/participle"
+)
+
+var contextUnmarshalerInterface = reflect.TypeOf((*ContextUnmarshaler)(nil)).Elem()
+
+// A ContextUnmarshaler unmarshals itself from an attribute value, with the
+// decode context of the enclosing block.
+//
+// The decode context is that passed to UnmarshalContext, or
+// context.Background(), as derived for each enclosing block by the function
+// passed to WithBlockContext.
+type ContextUnmarshaler interface {
+	UnmarshalHCL(ctx context.Context, v *Value) error
+}
+
+// WithBlockContext derives the decode context of each block from that of its
+// enclosing block, or the document, with fn, eg. to pass a tenant ID from a
+// block label to the ContextUnmarshalers within it:
+//
+//	hcl.WithBlockContext(func(ctx context.Context, block *hcl.Block) context.Context {
+//		if block.Name == "tenant" {
+//			return context.WithValue(ctx, tenantKey{}, block.Labels[0])
+//		}
+//		return ctx
+//	})
+func WithBlockContext(fn func(ctx context.Context, block *Block) context.Context) MarshalOption {
+	return func(options *marshalOptions) {
+		options.blockContext = fn
+	}
+}
+
+type baseDirKey struct{}
+
+// ContextWithBaseDir returns a copy of ctx in which relative Path values are
+// resolved against dir, in preference to the BaseDir option and the filename
+// of the value, eg. to resolve them against the directory of the file a block
+// was included from.
+func ContextWithBaseDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, baseDirKey{}, dir)
+}
+
+// BaseDirFromContext returns the base directory set with ContextWithBaseDir,
+// if any.
+func BaseDirFromContext(ctx context.Context) (string, bool) {
+	dir, ok := ctx.Value(baseDirKey{}).(string)
+	return dir, ok
+}
+
+// enterBlockContext sets the decode context for the contents of block,
+// returning a function restoring that of the enclosing block.
+func (o *marshalOptions) enterBlockContext(block *Block) func() {
+	ctx := o.ctx
+	o.ctx = o.blockContext(o.context(), block)
+	return func() { o.ctx = ctx }
+}
+
+// decodeContextUnmarshaler unmarshals v into rv if it is a
+// ContextUnmarshaler, returning true if it is.
+func decodeContextUnmarshaler(rv reflect.Value, v *Value, opt *marshalOptions) (bool, error) {
+	uv, ok := implements(rv, contextUnmarshalerInterface)
+	if !ok {
+		return false, nil
+	}
+	if err := uv.Interface().(ContextUnmarshaler).UnmarshalHCL(opt.context(), v); err != nil {
+		return true, participle.Wrapf(v.Pos, err, "invalid value")
+	}
+	return true, nil
+}
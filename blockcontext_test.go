@@ -0,0 +1,82 @@
+package hcl
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tenantKey struct{}
+
+// tenantName is qualified with the tenant of the enclosing block.
+type tenantName string
+
+func (t *tenantName) UnmarshalHCL(ctx context.Context, v *Value) error {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok {
+		return fmt.Errorf("no tenant")
+	}
+	if v.Str == nil {
+		return fmt.Errorf("expected a string but got %s", v)
+	}
+	*t = tenantName(tenant + "/" + *v.Str)
+	return nil
+}
+
+func TestWithBlockContext(t *testing.T) {
+	type service struct {
+		Name   string       `hcl:"name,label"`
+		Data   Path         `hcl:"data"`
+		Owner  tenantName   `hcl:"owner,optional"`
+		Queues []tenantName `hcl:"queues,optional"`
+	}
+	type tenant struct {
+		ID       string    `hcl:"id,label"`
+		Services []service `hcl:"service,block"`
+	}
+	type config struct {
+		Tenants []tenant `hcl:"tenant,block"`
+	}
+	blockContext := WithBlockContext(func(ctx context.Context, block *Block) context.Context {
+		if block.Name == "tenant" {
+			ctx = context.WithValue(ctx, tenantKey{}, block.Labels[0])
+			ctx = ContextWithBaseDir(ctx, filepath.Join("/srv", block.Labels[0]))
+		}
+		return ctx
+	})
+	data := []byte(`
+		tenant "acme" {
+			service "api" {
+				data = "data"
+				owner = "ops"
+				queues = ["jobs"]
+			}
+		}
+		tenant "globex" {
+			service "api" {
+				data = "/var/data"
+			}
+		}
+	`)
+	actual := config{}
+	err := Unmarshal(data, &actual, blockContext, BaseDir("/etc"))
+	require.NoError(t, err)
+	require.Equal(t, config{Tenants: []tenant{
+		{ID: "acme", Services: []service{{
+			Name:   "api",
+			Data:   Path{Path: filepath.FromSlash("/srv/acme/data"), Raw: "data", BaseDir: filepath.FromSlash("/srv/acme")},
+			Owner:  "acme/ops",
+			Queues: []tenantName{"acme/jobs"},
+		}}},
+		{ID: "globex", Services: []service{{
+			Name: "api",
+			Data: Path{Path: filepath.FromSlash("/var/data"), Raw: "/var/data"},
+		}}},
+	}}, actual)
+
+	err = Unmarshal(data, &config{})
+	require.EqualError(t, err, `5:13: tenant[0].service[0].owner: invalid value: no tenant`)
+}
@@ -0,0 +1,145 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/alecthomas/participle"
+)
+
+// blockMapElem returns the struct type of the blocks stored in a map block
+// field of type t, such as map[string]Rule or map[string][]*Rule, and whether
+// each key holds a slice of blocks.
+func blockMapElem(t reflect.Type) (elem reflect.Type, slice bool) {
+	elem = t.Elem()
+	if elem.Kind() == reflect.Slice {
+		elem = elem.Elem()
+		slice = true
+	}
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, slice
+}
+
+// checkBlockMapType returns an error if t, the type of a map block field, is
+// not a map with string keys and struct values.
+func checkBlockMapType(t reflect.Type) error {
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("map of blocks must have string keys but has %s", t.Key())
+	}
+	if elem, _ := blockMapElem(t); elem.Kind() != reflect.Struct {
+		return fmt.Errorf("map of blocks must have struct values but has %s", t.Elem())
+	}
+	return nil
+}
+
+// unmarshalBlockMap decodes blocks into a map block field. The first label of
+// each block is its key in the map, and the remaining labels are decoded
+// into the label fields of the block's struct.
+func unmarshalBlockMap(v reflect.Value, tag tag, entries []*Entry, opt *marshalOptions) error {
+	t := v.Type()
+	elem, slice := blockMapElem(t)
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	// Blocks are merged into existing elements, other than slices of blocks,
+	// which are replaced unless appending.
+	decoded := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Attribute != nil {
+			return participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", tag.name)
+		}
+		block := entry.Block
+		if len(block.Labels) == 0 {
+			return participle.Errorf(block.Pos, "block %q expects a label for its key", block.Name)
+		}
+		key := reflect.New(t.Key()).Elem()
+		key.SetString(block.Labels[0])
+		if !slice && decoded[block.Labels[0]] {
+			return participle.Errorf(block.Pos, "duplicate block %s %q", block.Name, block.Labels[0])
+		}
+		first := !decoded[block.Labels[0]]
+		decoded[block.Labels[0]] = true
+		inner := *block
+		inner.Labels = block.Labels[1:]
+		el := reflect.New(elem)
+		if existing := v.MapIndex(key); !slice && existing.IsValid() {
+			existing = reflect.Indirect(existing)
+			if existing.IsValid() {
+				el.Elem().Set(existing)
+			}
+		}
+		if err := unmarshalBlock(el.Elem(), &inner, opt); err != nil {
+			return withFieldPath(fmt.Sprintf("[%q]", block.Labels[0]), annotateFieldError(entry.Pos, err))
+		}
+		value := el
+		if (slice && t.Elem().Elem().Kind() != reflect.Ptr) || (!slice && t.Elem().Kind() != reflect.Ptr) {
+			value = el.Elem()
+		}
+		if slice {
+			existing := v.MapIndex(key)
+			if !existing.IsValid() || (first && !opt.appendSlices) {
+				existing = reflect.Zero(t.Elem())
+			}
+			value = reflect.Append(existing, value)
+		}
+		v.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+// mapToBlocks marshals a map block field to blocks labelled with their keys,
+// in key order.
+func mapToBlocks(v reflect.Value, tag tag, schema bool, opt *marshalOptions) ([]*Block, error) {
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return nil, unsupportedTypeError{t}
+	}
+	elem, slice := blockMapElem(t)
+	if elem.Kind() != reflect.Struct {
+		return nil, unsupportedTypeError{t}
+	}
+	if schema {
+		block, err := valueToBlock(reflect.New(elem).Elem(), tag, true, opt)
+		if err != nil {
+			return nil, err
+		}
+		block.Labels = append([]string{"key"}, block.Labels...)
+		block.Repeated = true
+		return []*Block{block}, nil
+	}
+	keys := make([]string, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		keys = append(keys, key.String())
+	}
+	sort.Strings(keys)
+	blocks := []*Block{}
+	for _, key := range keys {
+		value := v.MapIndex(reflect.ValueOf(key).Convert(t.Key()))
+		values := []reflect.Value{value}
+		if slice {
+			values = values[:0]
+			for i := 0; i < value.Len(); i++ {
+				values = append(values, value.Index(i))
+			}
+		}
+		for i, value := range values {
+			if value.Kind() == reflect.Ptr && value.IsNil() {
+				continue
+			}
+			block, err := valueToBlock(value, tag, false, opt)
+			if err != nil {
+				path := fmt.Sprintf("[%q]", key)
+				if slice {
+					path += fmt.Sprintf("[%d]", i)
+				}
+				return nil, withFieldPath(path, err)
+			}
+			block.Labels = append([]string{key}, block.Labels...)
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
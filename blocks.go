@@ -0,0 +1,47 @@
+package hcl
+
+import "reflect"
+
+var blocksType = reflect.TypeOf(Blocks{})
+
+// Blocks collects child blocks of any name, for extensible configuration
+// where block types are defined by users or plugins.
+//
+// A "remain" field of type Blocks is populated with the blocks that are not
+// decoded into any other field, in source order, eg.
+//
+//	Plugins hcl.Blocks `hcl:"plugins,remain"`
+type Blocks []*DynamicBlock
+
+// DynamicBlock is a block collected by a Blocks field.
+type DynamicBlock struct {
+	Name   string
+	Labels []string
+	// Block is the undecoded block.
+	Block *Block
+}
+
+// Decode the block into v, which must be a pointer to a struct.
+//
+// Options used to unmarshal the enclosing document are not inherited.
+func (b *DynamicBlock) Decode(v interface{}, options ...MarshalOption) error {
+	return UnmarshalBlock(b.Block, v, options...)
+}
+
+// collectBlocks returns the blocks in entries whose names are unclaimed, in
+// source order, removing them from unclaimed.
+func collectBlocks(entries []*Entry, unclaimed map[string]*Entry) Blocks {
+	out := Blocks{}
+	collected := map[string]bool{}
+	for _, entry := range entries {
+		if entry.Block == nil || unclaimed[entry.Key()] == nil {
+			continue
+		}
+		out = append(out, &DynamicBlock{Name: entry.Block.Name, Labels: entry.Block.Labels, Block: entry.Block})
+		collected[entry.Key()] = true
+	}
+	for key := range collected {
+		delete(unclaimed, key)
+	}
+	return out
+}
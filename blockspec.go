@@ -0,0 +1,210 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle"
+)
+
+// AttributeType is the type of an attribute in a BlockSpec.
+type AttributeType int
+
+// Attribute types.
+const (
+	TypeAny AttributeType = iota
+	TypeString
+	TypeNumber
+	TypeBool
+	TypeList
+	TypeMap
+)
+
+func (t AttributeType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeNumber:
+		return "number"
+	case TypeBool:
+		return "boolean"
+	case TypeList:
+		return "list"
+	case TypeMap:
+		return "map"
+	default:
+		return "any"
+	}
+}
+
+// matches returns true if v is of type t.
+func (t AttributeType) matches(v *Value) bool {
+	switch t {
+	case TypeString:
+		return v.Str != nil || v.HeredocDelimiter != "" || v.Type != nil
+	case TypeNumber:
+		return v.Number != nil
+	case TypeBool:
+		return v.Bool != nil
+	case TypeList:
+		return v.HaveList
+	case TypeMap:
+		return v.HaveMap
+	default:
+		return true
+	}
+}
+
+// AttributeSpec describes an attribute in a BlockSpec.
+type AttributeSpec struct {
+	Name     string
+	Type     AttributeType
+	Required bool
+}
+
+// BlockSpec describes the structure of a block, for applications whose
+// configuration is only known at runtime, such as plugin systems. The root of
+// a document is described by a BlockSpec with an empty Name.
+//
+// Unlike schemas reflected from Go types, a BlockSpec is constructed
+// programmatically:
+//
+//	spec := hcl.NewBlockSpec("").
+//		Attribute("version", hcl.TypeNumber, true).
+//		Block(hcl.NewBlockSpec("plugin", "name").Repeatable().
+//			Attribute("path", hcl.TypeString, true))
+type BlockSpec struct {
+	Name string
+	// Names of the block's labels.
+	Labels []string
+	// The block may occur more than once.
+	Repeated bool
+	// The block must occur at least once.
+	Required   bool
+	Attributes []*AttributeSpec
+	Blocks     []*BlockSpec
+}
+
+// NewBlockSpec creates a new BlockSpec for blocks with the given name and label names.
+func NewBlockSpec(name string, labels ...string) *BlockSpec {
+	return &BlockSpec{Name: name, Labels: labels}
+}
+
+// Attribute adds an attribute to the spec.
+func (s *BlockSpec) Attribute(name string, typ AttributeType, required bool) *BlockSpec {
+	s.Attributes = append(s.Attributes, &AttributeSpec{Name: name, Type: typ, Required: required})
+	return s
+}
+
+// Block adds a child block to the spec.
+func (s *BlockSpec) Block(block *BlockSpec) *BlockSpec {
+	s.Blocks = append(s.Blocks, block)
+	return s
+}
+
+// Repeatable marks the block as allowed to occur more than once.
+func (s *BlockSpec) Repeatable() *BlockSpec {
+	s.Repeated = true
+	return s
+}
+
+// Require marks the block as required.
+func (s *BlockSpec) Require() *BlockSpec {
+	s.Required = true
+	return s
+}
+
+// Validate that ast conforms to the spec.
+func (s *BlockSpec) Validate(ast *AST) error {
+	_, err := s.decodeEntries(ast.Entries)
+	return err
+}
+
+// Decode validates ast against the spec and decodes it into a map.
+//
+// Attributes are decoded as with an interface{} field. Each block is decoded
+// into a map[string]interface{} containing its attributes and child blocks,
+// and its labels keyed by their names. Repeated blocks are decoded into a
+// []interface{} of such maps.
+func (s *BlockSpec) Decode(ast *AST) (map[string]interface{}, error) {
+	return s.decodeEntries(ast.Entries)
+}
+
+func (s *BlockSpec) decodeEntries(entries []*Entry) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	attrs := map[string]*AttributeSpec{}
+	attrNames := []string{}
+	for _, attr := range s.Attributes {
+		attrs[attr.Name] = attr
+		attrNames = append(attrNames, attr.Name)
+	}
+	blocks := map[string]*BlockSpec{}
+	blockNames := []string{}
+	for _, block := range s.Blocks {
+		blocks[block.Name] = block
+		blockNames = append(blockNames, block.Name)
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Attribute != nil:
+			attr := entry.Attribute
+			spec, ok := attrs[attr.Key]
+			if !ok {
+				return nil, participle.Errorf(entry.Pos, "unknown attribute %q%s", attr.Key, didYouMean(attr.Key, attrNames))
+			}
+			if _, ok := out[attr.Key]; ok {
+				return nil, participle.Errorf(entry.Pos, "duplicate attribute %q", attr.Key)
+			}
+			if !spec.Type.matches(attr.Value) {
+				return nil, participle.Errorf(attr.Value.Pos, "expected a %s for %q but got %s", spec.Type, attr.Key, attr.Value)
+			}
+			value, err := valueToInterface(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[attr.Key] = value
+
+		case entry.Block != nil:
+			block := entry.Block
+			spec, ok := blocks[block.Name]
+			if !ok {
+				return nil, participle.Errorf(entry.Pos, "unknown block %q%s", block.Name, didYouMean(block.Name, blockNames))
+			}
+			if len(block.Labels) != len(spec.Labels) {
+				names := ""
+				if len(spec.Labels) > 0 {
+					names = " (" + strings.Join(spec.Labels, ", ") + ")"
+				}
+				return nil, participle.Errorf(entry.Pos, "block %q expects %d labels%s, got %d",
+					block.Name, len(spec.Labels), names, len(block.Labels))
+			}
+			value, err := spec.decodeEntries(block.Body)
+			if err != nil {
+				return nil, err
+			}
+			for i, label := range spec.Labels {
+				value[label] = block.Labels[i]
+			}
+			if !spec.Repeated {
+				if _, ok := out[block.Name]; ok {
+					return nil, participle.Errorf(entry.Pos, "block %q may only occur once", block.Name)
+				}
+				out[block.Name] = value
+				continue
+			}
+			list, _ := out[block.Name].([]interface{})
+			out[block.Name] = append(list, value)
+		}
+	}
+	for _, attr := range s.Attributes {
+		if _, ok := out[attr.Name]; !ok && attr.Required {
+			return nil, fmt.Errorf("missing required attribute %q", attr.Name)
+		}
+	}
+	for _, block := range s.Blocks {
+		if _, ok := out[block.Name]; !ok && block.Required {
+			return nil, fmt.Errorf("missing required block %q", block.Name)
+		}
+	}
+	return out, nil
+}
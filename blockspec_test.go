@@ -0,0 +1,71 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockSpec(t *testing.T) {
+	spec := NewBlockSpec("").
+		Attribute("version", TypeNumber, true).
+		Block(NewBlockSpec("plugin", "name").Repeatable().
+			Attribute("path", TypeString, true).
+			Attribute("args", TypeList, false)).
+		Block(NewBlockSpec("logging").
+			Attribute("level", TypeString, false))
+	ast, err := ParseString(`
+version = 1
+plugin "a" {
+  path = "/bin/a"
+  args = ["-v"]
+}
+plugin "b" {
+  path = "/bin/b"
+}
+logging {
+  level = "debug"
+}
+`)
+	require.NoError(t, err)
+	require.NoError(t, spec.Validate(ast))
+	actual, err := spec.Decode(ast)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"version": 1.0,
+		"plugin": []interface{}{
+			map[string]interface{}{"name": "a", "path": "/bin/a", "args": []interface{}{"-v"}},
+			map[string]interface{}{"name": "b", "path": "/bin/b"},
+		},
+		"logging": map[string]interface{}{"level": "debug"},
+	}, actual)
+}
+
+func TestBlockSpecErrors(t *testing.T) {
+	spec := NewBlockSpec("").
+		Attribute("version", TypeNumber, true).
+		Block(NewBlockSpec("logging").Require().
+			Attribute("level", TypeString, false))
+	tests := []struct {
+		name string
+		hcl  string
+		fail string
+	}{
+		{name: "MissingAttribute", hcl: `logging {}`, fail: `missing required attribute "version"`},
+		{name: "MissingBlock", hcl: `version = 1`, fail: `missing required block "logging"`},
+		{name: "WrongType", hcl: `version = "1"`, fail: `1:11: expected a number for "version" but got "1"`},
+		{name: "UnknownAttribute", hcl: "version = 1\nname = 1", fail: `2:1: unknown attribute "name"`},
+		{name: "UnknownBlock", hcl: "version = 1\nother {}", fail: `2:1: unknown block "other"`},
+		{name: "Labels", hcl: `logging "x" {}`, fail: `1:1: block "logging" expects 0 labels, got 1`},
+		{name: "Repeated", hcl: "version = 1\nlogging {}\nlogging {}", fail: `3:1: block "logging" may only occur once`},
+		{name: "Nested", hcl: "version = 1\nlogging {\n  level = 1\n}", fail: `3:11: expected a string for "level" but got 1`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ast, err := ParseString(test.hcl)
+			require.NoError(t, err)
+			err = spec.Validate(ast)
+			require.EqualError(t, err, test.fail)
+		})
+	}
+}
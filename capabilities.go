@@ -0,0 +1,51 @@
+package hcl
+
+// CapabilitySet describes the syntax features supported by this version of the package.
+type CapabilitySet struct {
+	// Heredocs are supported, including indented "<<-" heredocs.
+	Heredocs bool
+	// Expressions such as arithmetic, function calls and references are evaluated.
+	Expressions bool
+	// Includes of other HCL files are resolved.
+	Includes bool
+	// BlockComments of the form /* ... */ are supported.
+	BlockComments bool
+	// MultiLineBlockComments are block comments that span more than one line.
+	MultiLineBlockComments bool
+	// NumberLiterals may be hexadecimal, octal or binary, and contain "_" digit separators.
+	NumberLiterals bool
+	// RawStrings delimited by backticks are supported.
+	RawStrings bool
+	// Directives such as "@include" are supported, see RegisterDirective.
+	Directives bool
+	// UnitSuffixes on numbers, eg. "30s" or "512MiB", are preserved.
+	UnitSuffixes bool
+	// SingleQuotedStrings are supported in addition to double-quoted strings.
+	SingleQuotedStrings bool
+	// HCL2Objects, with "=" separating keys from values, are supported. See also MixedBlockSyntax.
+	HCL2Objects bool
+	// UnicodeIdentifiers are supported, see WithIdentifierProfile.
+	UnicodeIdentifiers bool
+	// ListBlocks, ie. blocks nested in list values, are supported.
+	ListBlocks bool
+}
+
+// Capabilities reports the syntax features supported by this version of the package.
+//
+// Tools supporting multiple versions of the package can use this to adapt to
+// the available feature set at runtime.
+func Capabilities() CapabilitySet {
+	return CapabilitySet{
+		Heredocs:               true,
+		BlockComments:          true,
+		MultiLineBlockComments: true,
+		NumberLiterals:         true,
+		RawStrings:             true,
+		Directives:             true,
+		UnitSuffixes:           true,
+		SingleQuotedStrings:    true,
+		HCL2Objects:            true,
+		UnicodeIdentifiers:     true,
+		ListBlocks:             true,
+	}
+}
@@ -0,0 +1,52 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	if caps.Heredocs {
+		_, err := ParseString("a = <<EOF\nb\nEOF\n")
+		require.NoError(t, err)
+	}
+	if caps.BlockComments {
+		_, err := ParseString("/* comment */\na = 1\n")
+		require.NoError(t, err)
+	}
+	if caps.NumberLiterals {
+		_, err := ParseString("a = 0xFF\nb = 0o755\nc = 0b101\nd = 1_000\n")
+		require.NoError(t, err)
+	}
+	if caps.RawStrings {
+		_, err := ParseString("a = `C:\\path`\n")
+		require.NoError(t, err)
+	}
+	if caps.Directives {
+		handler := func(*Directive) error { return nil }
+		_, err := ParseString("@include \"base.hcl\"\na = 1\n", WithDirective("include", handler))
+		require.NoError(t, err)
+	}
+	if caps.UnitSuffixes {
+		_, err := ParseString("timeout = 30s\ncache = 512MiB\n")
+		require.NoError(t, err)
+	}
+	if caps.SingleQuotedStrings {
+		_, err := ParseString("a = 'b'\n")
+		require.NoError(t, err)
+	}
+	if caps.HCL2Objects {
+		_, err := ParseString("a = {\n  b = 1\n  c = \"d\"\n}\n")
+		require.NoError(t, err)
+	}
+	if caps.UnicodeIdentifiers {
+		_, err := ParseString("größe = 1\n", WithIdentifierProfile(IdentifiersUnicode))
+		require.NoError(t, err)
+	}
+	if caps.ListBlocks {
+		_, err := ParseString("rules = [\n  rule \"allow\" {\n    path = \"/\"\n  }\n]\n")
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,126 @@
+package hcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+// Kinds of Change.
+const (
+	// ChangeAdded is an entry added by a merged document.
+	ChangeAdded ChangeKind = "added"
+	// ChangeReplaced is an attribute replaced by a merged document.
+	ChangeReplaced ChangeKind = "replaced"
+)
+
+// Change made to a document by a merge, recorded in a ChangeLog.
+type Change struct {
+	// Actor making the change, from the ChangeLog.
+	Actor string     `json:"actor,omitempty"`
+	Kind  ChangeKind `json:"kind"`
+	// Path of the entry, eg. service["api"].port.
+	Path string `json:"path"`
+	// Old is the value of a replaced attribute, and New the value of the
+	// attribute, or the compact form of the block, that is now in place.
+	Old string `json:"old,omitempty"`
+	New string `json:"new"`
+	// Pos of the new entry, including the file it was parsed from, and OldPos
+	// of the attribute it replaced, if any.
+	Pos    lexer.Position `json:"pos"`
+	OldPos lexer.Position `json:"old_pos,omitempty"`
+}
+
+func (c Change) String() string {
+	actor := ""
+	if c.Actor != "" {
+		actor = c.Actor + " "
+	}
+	if c.Kind == ChangeReplaced {
+		return fmt.Sprintf("%s: %s%s %s: %s -> %s", c.Pos, actor, c.Kind, c.Path, c.Old, c.New)
+	}
+	return fmt.Sprintf("%s: %s%s %s: %s", c.Pos, actor, c.Kind, c.Path, c.New)
+}
+
+// A ChangeLog records the changes merges make to documents, for audit trails
+// of configuration changes.
+//
+// It is safe for concurrent use.
+type ChangeLog struct {
+	// Actor making the changes, eg. a user or service, recorded in each Change.
+	Actor string
+
+	lock    sync.Mutex
+	changes []Change
+}
+
+// NewChangeLog creates a ChangeLog recording changes made by actor.
+func NewChangeLog(actor string) *ChangeLog {
+	return &ChangeLog{Actor: actor}
+}
+
+// Merge documents as Merge does, recording each entry that documents after
+// the first add or replace.
+func (c *ChangeLog) Merge(asts ...*AST) (*AST, error) {
+	return merge(c, asts)
+}
+
+// Changes returns the changes recorded so far, in order.
+func (c *ChangeLog) Changes() []Change {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]Change{}, c.changes...)
+}
+
+// added records that entry was added, if c isn't nil.
+func (c *ChangeLog) added(path string, entry *Entry) {
+	if c == nil {
+		return
+	}
+	c.record(Change{Kind: ChangeAdded, Path: path, New: changeValue(entry), Pos: entry.Pos})
+}
+
+// replaced records that entry replaced old, if c isn't nil.
+func (c *ChangeLog) replaced(path string, old, entry *Entry) {
+	if c == nil {
+		return
+	}
+	c.record(Change{Kind: ChangeReplaced, Path: path,
+		Old: changeValue(old), New: changeValue(entry),
+		Pos: entry.Pos, OldPos: old.Pos})
+}
+
+func (c *ChangeLog) record(change Change) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	change.Actor = c.Actor
+	c.changes = append(c.changes, change)
+}
+
+// changeValue returns the value of an attribute, or a block in compact form.
+func changeValue(entry *Entry) string {
+	if entry.Attribute != nil {
+		return compactValue(entry.Attribute.Value)
+	}
+	data, err := NewPrinter(Compact()).Print(entry.Block)
+	if err != nil {
+		return err.Error()
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// blockPath returns the path segment of a block, eg. service["api"].
+func blockPath(block *Block) string {
+	w := &strings.Builder{}
+	w.WriteString(block.Name)
+	for _, label := range block.Labels {
+		w.WriteString("[" + strconv.Quote(label) + "]")
+	}
+	return w.String()
+}
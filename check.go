@@ -0,0 +1,213 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CheckStruct validates that the struct v, or the struct it points to, can be
+// marshalled and unmarshalled, without any input, so that programs can fail
+// at startup rather than on the first configuration that reaches a bad
+// field.
+//
+// It reports invalid tags, fields of unsupported types such as channels,
+// functions and maps without string keys, block fields that aren't structs,
+// struct fields missing a block tag, interface fields without a registered
+// union, labels that can't be decoded from strings, names used by more than
+// one field, and constraints and labels naming unknown fields. v is not
+// modified.
+func CheckStruct(v interface{}, options ...MarshalOption) (err error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("can't check %T, expected a struct or a pointer to a struct", v)
+	}
+	// Invalid tags panic when parsed.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	c := &structChecker{opt: newMarshalOptions(options...), seen: map[reflect.Type]bool{}}
+	return c.checkStruct(t)
+}
+
+type structChecker struct {
+	opt  *marshalOptions
+	seen map[reflect.Type]bool
+}
+
+func (c *structChecker) checkStruct(t reflect.Type) error {
+	if c.seen[t] {
+		return nil
+	}
+	c.seen[t] = true
+	fields, err := flattenFields(reflect.New(t).Elem(), c.opt)
+	if err != nil {
+		return fmt.Errorf("%s: %s", t, err)
+	}
+	names := map[string]string{}
+	var constrained []dependentField
+	for _, field := range fields {
+		tag := parseTag(t, field, c.opt)
+		id := fieldID(t, field.t)
+		switch {
+		case tag.part != "":
+			if err := checkLabelType(field.t.Type); err != nil {
+				return fmt.Errorf("%s: %s", id, err)
+			}
+			continue
+		case tag.name == "":
+			continue
+		case tag.label:
+			if err := checkLabelType(field.t.Type); err != nil {
+				return fmt.Errorf("%s: %s", id, err)
+			}
+			if tag.labelTemplate != nil {
+				lookupLabelParts(t, tag.labelTemplate, labelPartFields(t, fields, c.opt))
+			}
+			continue
+
+		case tag.remain:
+			if field.t.Type != remainType && field.t.Type != blocksType {
+				return fmt.Errorf("%s: \"remain\" field must be of type []*hcl.Entry or hcl.Blocks but is %s", id, field.t.Type)
+			}
+			continue
+		}
+		for _, name := range append([]string{tag.name}, tag.aliases...) {
+			if other, ok := names[name]; ok {
+				return fmt.Errorf("%s: name %q is also used by %s", id, name, other)
+			}
+			names[name] = id
+		}
+		if len(tag.requiredWith) > 0 || len(tag.conflictsWith) > 0 {
+			constrained = append(constrained, dependentField{field: field, tag: tag})
+		}
+		switch {
+		case tag.block && tag.union != nil:
+		case tag.block || tag.inline:
+			if err := c.checkBlock(field.t.Type); err != nil {
+				return fmt.Errorf("%s: %s", id, err)
+			}
+		default:
+			if err := c.checkAttribute(field.t.Type); err != nil {
+				return fmt.Errorf("%s: %s", id, err)
+			}
+		}
+	}
+	for _, dep := range constrained {
+		for _, name := range append(append([]string{}, dep.tag.requiredWith...), dep.tag.conflictsWith...) {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("unknown field %q in constraint on %s", name, fieldID(t, dep.field.t))
+			}
+		}
+	}
+	return nil
+}
+
+// checkBlock checks the type of a field decoded from blocks.
+func (c *structChecker) checkBlock(t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		t = t.Elem()
+	case reflect.Map:
+		if err := checkBlockMapType(t); err != nil {
+			return err
+		}
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return fmt.Errorf("no union is registered for %s", t)
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("block must be a struct, or a slice or map of structs, but is %s", t)
+	}
+	return c.checkStruct(t)
+}
+
+// checkAttribute checks the type of a field decoded from an attribute. Unlike
+// structs nested in values, struct fields are decoded from blocks.
+func (c *structChecker) checkAttribute(t reflect.Type) error {
+	st := t
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() == reflect.Struct && !c.scalar(st) {
+		return fmt.Errorf("struct %s used as attribute, is it missing a \"block\" tag?", st)
+	}
+	return c.checkValue(t)
+}
+
+// scalar returns true if t is decoded from a single value, rather than
+// according to its kind.
+func (c *structChecker) scalar(t reflect.Type) bool {
+	if s := c.opt.lookupScalar(t); s.encode != nil || s.decode != nil {
+		return true
+	}
+	return t == durationType || t == timeType || t == bytesType || t == numberType || t == pathType ||
+		typeImplements(t, textUnmarshalerInterface) || typeImplements(t, jsonUnmarshalerInterface)
+}
+
+// checkValue checks the type of a value decoded from an attribute.
+func (c *structChecker) checkValue(t reflect.Type) error {
+	if c.scalar(t) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		// Only unions of blocks are decoded into non-empty interfaces.
+		if t.NumMethod() > 0 {
+			return fmt.Errorf("can't decode a value into non-empty interface %s", t)
+		}
+		return nil
+
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return c.checkValue(t.Elem())
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		return c.checkValue(t.Elem())
+
+	case reflect.Struct:
+		// Structs nested in values are objects.
+		return c.checkStruct(t)
+
+	default:
+		return unsupportedTypeError{t}
+	}
+}
+
+// checkLabelType checks that labels can be decoded into t.
+func checkLabelType(t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if typeImplements(t, textUnmarshalerInterface) {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return nil
+	default:
+		return fmt.Errorf("unsupported label type %s", t)
+	}
+}
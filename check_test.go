@@ -0,0 +1,93 @@
+package hcl
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStruct(t *testing.T) {
+	type listener struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type valid struct {
+		Name      string              `hcl:"name"`
+		Timeout   time.Duration       `hcl:"timeout"`
+		Tags      map[string][]string `hcl:"tags"`
+		Listeners []*listener         `hcl:"listener,block"`
+		ByName    map[string]listener `hcl:"named,block"`
+		Limits    struct{ CPU int }   `hcl:"limits,block"`
+		Any       interface{}         `hcl:"any"`
+		Ignored   chan int            `hcl:"-"`
+		Remain    []*Entry            `hcl:",remain"`
+	}
+	require.NoError(t, CheckStruct(&valid{}))
+	require.NoError(t, CheckStruct(valid{}))
+
+	type withChan struct {
+		Events chan int `hcl:"events"`
+	}
+	type badMap struct {
+		Ports map[int]string `hcl:"ports"`
+	}
+	type badBlock struct {
+		Server string `hcl:"server,block"`
+	}
+	type badLabel struct {
+		Name []string `hcl:"name,label"`
+	}
+	type nested struct {
+		Listener []struct {
+			Callback func() `hcl:"callback"`
+		} `hcl:"listener,block"`
+	}
+	type duplicate struct {
+		Host    string `hcl:"host"`
+		Address string `hcl:"address" alias:"host"`
+	}
+	type badTag struct {
+		Port int `hcl:"port,bogus"`
+	}
+	type badBlockMap struct {
+		Rules map[int]listener `hcl:"rule,block"`
+	}
+	type missingBlockTag struct {
+		Limits *struct{ CPU int } `hcl:"limits"`
+	}
+	type noUnion struct {
+		Handler fmt.Stringer `hcl:"handler"`
+	}
+	type noUnionBlock struct {
+		Handlers []fmt.Stringer `hcl:"handler,block"`
+	}
+	type badConstraint struct {
+		Cert string `hcl:"cert,optional" required_with:"ky"`
+		Key  string `hcl:"key,optional"`
+	}
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{&withChan{}, `github.com/alecthomas/hcl.withChan.Events: unsupported type chan int`},
+		{&badMap{}, `github.com/alecthomas/hcl.badMap.Ports: unsupported map key type int`},
+		{&badBlock{}, `github.com/alecthomas/hcl.badBlock.Server: block must be a struct, or a slice or map of structs, but is string`},
+		{&badLabel{}, `github.com/alecthomas/hcl.badLabel.Name: unsupported label type []string`},
+		{&nested{}, `.Callback: unsupported type func()`},
+		{&duplicate{}, `github.com/alecthomas/hcl.duplicate.Address: name "host" is also used by github.com/alecthomas/hcl.duplicate.Host`},
+		{&badTag{}, `invalid HCL tag option bogus on github.com/alecthomas/hcl.badTag.Port`},
+		{&badBlockMap{}, `github.com/alecthomas/hcl.badBlockMap.Rules: map of blocks must have string keys but has int`},
+		{&missingBlockTag{}, `github.com/alecthomas/hcl.missingBlockTag.Limits: struct struct { CPU int } used as attribute, is it missing a "block" tag?`},
+		{&noUnion{}, `github.com/alecthomas/hcl.noUnion.Handler: can't decode a value into non-empty interface fmt.Stringer`},
+		{&noUnionBlock{}, `github.com/alecthomas/hcl.noUnionBlock.Handlers: no union is registered for fmt.Stringer`},
+		{&badConstraint{}, `unknown field "ky" in constraint on github.com/alecthomas/hcl.badConstraint.Cert`},
+		{"string", `can't check string, expected a struct or a pointer to a struct`},
+	}
+	for _, test := range tests {
+		err := CheckStruct(test.v)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), test.err)
+	}
+}
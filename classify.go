@@ -0,0 +1,123 @@
+package hcl
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle"
+)
+
+// SpanKind is the syntactic class of a Span.
+type SpanKind int
+
+// Span kinds.
+const (
+	SpanIdent SpanKind = iota
+	SpanKeyword
+	SpanString
+	SpanNumber
+	SpanComment
+	SpanPunctuation
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKeyword:
+		return "keyword"
+	case SpanString:
+		return "string"
+	case SpanNumber:
+		return "number"
+	case SpanComment:
+		return "comment"
+	case SpanPunctuation:
+		return "punctuation"
+	default:
+		return "ident"
+	}
+}
+
+// Span is a classified range of bytes in source, exclusive of End.
+type Span struct {
+	Kind  SpanKind
+	Start int
+	End   int
+}
+
+// Classify splits src into spans of keywords, identifiers, strings, numbers,
+// comments and punctuation, eg. for syntax highlighting.
+//
+// Whitespace is not included in any span. src need not be valid HCL: input
+// that can't be tokenised is skipped a character at a time.
+func Classify(src []byte) []Span {
+	offset := 0
+	if bytes.HasPrefix(src, utf8BOM) {
+		offset = len(utf8BOM)
+	}
+	spans := []Span{}
+	for offset < len(src) {
+		next, err := classifyFrom(src, offset, &spans)
+		if err == nil {
+			break
+		}
+		// Skip the character that failed to lex, and carry on.
+		_, size := utf8.DecodeRune(src[next:])
+		offset = next + size
+	}
+	return spans
+}
+
+// classifyFrom appends spans for the tokens in src from offset to spans,
+// returning the offset and error at which lexing failed, if any.
+func classifyFrom(src []byte, offset int, spans *[]Span) (int, error) {
+	symbols := lex.Definition.Symbols()
+	tokens, err := lex.Definition.Lex(bytes.NewReader(src[offset:]))
+	if err != nil {
+		return offset, err
+	}
+	heredoc := -1
+	for {
+		token, err := tokens.Next()
+		if err != nil {
+			if perr, ok := err.(participle.Error); ok {
+				return offset + perr.Token().Pos.Offset, err
+			}
+			return len(src), nil
+		}
+		if token.EOF() {
+			break
+		}
+		span := Span{Start: offset + token.Pos.Offset, End: offset + token.Pos.Offset + len(token.Value)}
+		switch token.Type {
+		case symbols["Heredoc"]:
+			heredoc = span.Start
+			continue
+		case symbols["Body"], symbols["EOL"]:
+			continue
+		case symbols["End"]:
+			span = Span{Kind: SpanString, Start: heredoc, End: span.End}
+			heredoc = -1
+		case symbols["Ident"]:
+			if token.Value == "true" || token.Value == "false" {
+				span.Kind = SpanKeyword
+			}
+		case symbols["String"], symbols["RawString"], symbols["SingleString"]:
+			span.Kind = SpanString
+		case symbols["Number"]:
+			span.Kind = SpanNumber
+		case symbols["Comment"]:
+			span.Kind = SpanComment
+			span.End = offset + token.Pos.Offset + len(bytes.TrimRight([]byte(token.Value), "\r"))
+		case symbols["Punct"]:
+			span.Kind = SpanPunctuation
+		default:
+			continue
+		}
+		*spans = append(*spans, span)
+	}
+	if heredoc != -1 {
+		// Unterminated.
+		*spans = append(*spans, Span{Kind: SpanString, Start: heredoc, End: len(src)})
+	}
+	return len(src), nil
+}
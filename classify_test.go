@@ -0,0 +1,46 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	src := "\xef\xbb\xbf// héllo\r\nblock \"label\" {\r\n  enabled = true\r\n  size = 0x10 # hex\r\n  text = <<EOF\r\nbody\r\nEOF\r\n}\r\n"
+	type span struct {
+		kind SpanKind
+		text string
+	}
+	actual := []span{}
+	for _, s := range Classify([]byte(src)) {
+		actual = append(actual, span{s.Kind, src[s.Start:s.End]})
+	}
+	require.Equal(t, []span{
+		{SpanComment, "// héllo"},
+		{SpanIdent, "block"},
+		{SpanString, `"label"`},
+		{SpanPunctuation, "{"},
+		{SpanIdent, "enabled"},
+		{SpanPunctuation, "="},
+		{SpanKeyword, "true"},
+		{SpanIdent, "size"},
+		{SpanPunctuation, "="},
+		{SpanNumber, "0x10"},
+		{SpanComment, "# hex"},
+		{SpanIdent, "text"},
+		{SpanPunctuation, "="},
+		{SpanString, "<<EOF\r\nbody\r\nEOF"},
+		{SpanPunctuation, "}"},
+	}, actual)
+}
+
+func TestClassifyInvalid(t *testing.T) {
+	src := "a = ? \"b\" <<EOF\nunterminated"
+	require.Equal(t, []Span{
+		{Kind: SpanIdent, Start: 0, End: 1},
+		{Kind: SpanPunctuation, Start: 2, End: 3},
+		{Kind: SpanString, Start: 6, End: 9},
+		{Kind: SpanString, Start: 10, End: len(src)},
+	}, Classify([]byte(src)))
+}
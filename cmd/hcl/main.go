@@ -0,0 +1,63 @@
+// Command hcl provides tooling for working with HCL files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/alecthomas/hcl"
+)
+
+const usage = `usage: hcl <command> [<flags>] [<args>]
+
+Commands:
+  gen [-package <pkg>] [-type <name>] [-schema] <file>
+    Generate Go structs from an example HCL document or schema.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = gen(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hcl: error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func gen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	pkg := flags.String("package", "main", "Go package name for generated code.")
+	typeName := flags.String("type", "Config", "Name of the top-level Go struct.")
+	schema := flags.Bool("schema", false, "Treat the input as a schema rather than an example document.")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("expected a single HCL file")
+	}
+	data, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	ast, err := hcl.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	ast.Schema = *schema
+	out, err := hcl.GenerateStruct(ast, *pkg, *typeName)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
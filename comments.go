@@ -0,0 +1,199 @@
+package hcl
+
+import (
+	"io"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// CommentStyle describes the syntax of a parsed comment.
+type CommentStyle struct {
+	// Marker is the comment delimiter, one of "#", "//" or "/*".
+	Marker string `json:"marker,omitempty"`
+	// Trailing is true if the comment followed other tokens on the same line.
+	Trailing bool `json:"trailing,omitempty"`
+}
+
+// trailingCommentMarker is prepended to the raw text of trailing comments by
+// the lexer, and removed again by splitComments.
+const trailingCommentMarker = "\x00"
+
+// commentLexerDefinition wraps a lexer to emit comments that follow other
+// tokens on the same line as TrailingComment tokens.
+type commentLexerDefinition struct {
+	lexer.Definition
+}
+
+func (d commentLexerDefinition) Symbols() map[string]rune {
+	symbols := map[string]rune{}
+	min := rune(0)
+	for name, r := range d.Definition.Symbols() {
+		symbols[name] = r
+		if r < min {
+			min = r
+		}
+	}
+	symbols["TrailingComment"] = min - 1
+	return symbols
+}
+
+func (d commentLexerDefinition) Lex(r io.Reader) (lexer.Lexer, error) {
+	lex, err := d.Definition.Lex(r)
+	if err != nil {
+		return nil, err
+	}
+	symbols := d.Symbols()
+	return &commentLexer{Lexer: lex, comment: symbols["Comment"], trailing: symbols["TrailingComment"]}, nil
+}
+
+type commentLexer struct {
+	lexer.Lexer
+	comment  rune
+	trailing rune
+	lastLine int
+}
+
+func (l *commentLexer) Next() (lexer.Token, error) {
+	token, err := l.Lexer.Next()
+	if err != nil {
+		return token, err
+	}
+	switch {
+	case token.Type == l.comment:
+		if token.Pos.Line == l.lastLine {
+			token.Type = l.trailing
+			token.Value = trailingCommentMarker + token.Value
+		}
+	case strings.TrimSpace(token.Value) != "":
+		l.lastLine = token.Pos.Line + strings.Count(token.Value, "\n")
+	}
+	return token, nil
+}
+
+// parseComment splits a raw comment into its text and style.
+func parseComment(raw string) (string, CommentStyle) {
+	style := CommentStyle{}
+	if strings.HasPrefix(raw, trailingCommentMarker) {
+		style.Trailing = true
+		raw = raw[len(trailingCommentMarker):]
+	}
+	switch {
+	case strings.HasPrefix(raw, "#"):
+		style.Marker = "#"
+		raw = strings.TrimPrefix(raw[1:], " ")
+	case strings.HasPrefix(raw, "//"):
+		style.Marker = "//"
+		raw = strings.TrimPrefix(raw[2:], " ")
+	case strings.HasPrefix(raw, "/*"):
+		style.Marker = "/*"
+		raw = strings.TrimSpace(strings.TrimSuffix(raw[2:], "*/"))
+	}
+	return strings.TrimRight(raw, " \t\r"), style
+}
+
+// splitComments converts raw comments captured by the parser into their text and styles.
+func splitComments(raw []string) ([]string, []CommentStyle) {
+	if raw == nil {
+		return nil, nil
+	}
+	texts := make([]string, len(raw))
+	styles := make([]CommentStyle, len(raw))
+	for i, comment := range raw {
+		texts[i], styles[i] = parseComment(comment)
+	}
+	return texts, styles
+}
+
+// normaliseComments strips comment delimiters from all comments in a freshly parsed AST.
+func normaliseComments(node Node) error {
+	return Visit(node, func(node Node, next func() error) error {
+		switch node := node.(type) {
+		case *AST:
+			node.TrailingComments, node.TrailingCommentStyles = splitComments(node.TrailingComments)
+
+		case *Attribute:
+			node.Comments, node.CommentStyles = splitComments(node.Comments)
+			node.TrailingComments, node.TrailingCommentStyles = splitComments(node.TrailingComments)
+
+		case *Block:
+			node.Comments, node.CommentStyles = splitComments(node.Comments)
+			node.TrailingComments, node.TrailingCommentStyles = splitComments(node.TrailingComments)
+
+		case *MapEntry:
+			node.Comments, node.CommentStyles = splitComments(node.Comments)
+
+		case *Directive:
+			node.Comments, node.CommentStyles = splitComments(node.Comments)
+			node.TrailingComments, node.TrailingCommentStyles = splitComments(node.TrailingComments)
+		}
+		return next()
+	})
+}
+
+// commentStyle returns the style of the i'th comment, if known.
+func commentStyle(styles []CommentStyle, i int) CommentStyle {
+	if i < len(styles) {
+		return styles[i]
+	}
+	return CommentStyle{}
+}
+
+func cloneCommentStyles(styles []CommentStyle) []CommentStyle {
+	if styles == nil {
+		return nil
+	}
+	out := make([]CommentStyle, len(styles))
+	copy(out, styles)
+	return out
+}
+
+// DocComment returns the documentation of the entry's attribute or block: its
+// leading comments, cleaned up for display.
+//
+// Comments detached from the entry by a blank line, such as section headings,
+// comments trailing the preceding line, such as a comment following the
+// opening brace of the enclosing block, and directives such as hcl:ignore
+// aren't documentation. Decorative "*" prefixes of /* */ comment lines are
+// stripped, and hard-wrapped lines are joined into paragraphs, separated by
+// blank lines, for the consumer to wrap.
+func (e *Entry) DocComment() string {
+	var (
+		comments []string
+		styles   []CommentStyle
+		detached int
+	)
+	switch {
+	case e.Attribute != nil:
+		comments, styles, detached = e.Attribute.Comments, e.Attribute.CommentStyles, e.Attribute.DetachedComments
+	case e.Block != nil:
+		comments, styles, detached = e.Block.Comments, e.Block.CommentStyles, e.Block.DetachedComments
+	}
+	paragraphs := []string{}
+	paragraph := []string{}
+	endParagraph := func() {
+		if len(paragraph) > 0 {
+			paragraphs = append(paragraphs, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+	for i := detached; i < len(comments); i++ {
+		style := commentStyle(styles, i)
+		if style.Trailing || strings.HasPrefix(strings.TrimSpace(comments[i]), "hcl:") {
+			continue
+		}
+		for _, line := range strings.Split(comments[i], "\n") {
+			line = strings.TrimSpace(line)
+			if style.Marker == "/*" {
+				line = strings.TrimSpace(strings.TrimLeft(line, "*"))
+			}
+			if line == "" {
+				endParagraph()
+				continue
+			}
+			paragraph = append(paragraph, line)
+		}
+	}
+	endParagraph()
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,50 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const docCommentSource = `# Copyright notice.
+
+# Port to listen on.
+port = 80
+
+server { # Servers.
+  # Connection settings.
+
+  # hcl:ignore unknown-attribute
+  /* Host to connect to,
+   * without a scheme.
+   *
+   * Defaults to localhost. */
+  host = "example.com"
+}
+`
+
+func TestDocComment(t *testing.T) {
+	ast, err := ParseString(docCommentSource)
+	require.NoError(t, err)
+	require.Equal(t, "Port to listen on.", ast.Entries[0].DocComment())
+	require.Equal(t, 1, ast.Entries[0].Attribute.DetachedComments)
+	require.Equal(t, "", ast.Entries[1].DocComment())
+	host := ast.Entries[1].Block.Body[0]
+	require.Equal(t, 2, host.Attribute.DetachedComments)
+	require.Equal(t, "Host to connect to, without a scheme.\n\nDefaults to localhost.", host.DocComment())
+
+	// Detached comments remain separated from the entry when printed.
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "# Copyright notice.\n\n# Port to listen on.\nport = 80\n")
+	require.Contains(t, string(data), "  # Connection settings.\n\n  # hcl:ignore")
+	reparsed, err := ParseBytes(data)
+	require.NoError(t, err)
+	require.Equal(t, 1, reparsed.Entries[0].Attribute.DetachedComments)
+
+	// Comments without a blank line before the entry are all documentation.
+	ast, err = ParseString("# First.\n# Second.\nport = 80\n")
+	require.NoError(t, err)
+	require.Equal(t, 0, ast.Entries[0].Attribute.DetachedComments)
+	require.Equal(t, "First. Second.", ast.Entries[0].DocComment())
+}
@@ -0,0 +1,302 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// DiagnosticKind classifies a Diagnostic.
+type DiagnosticKind int
+
+// Diagnostic kinds.
+const (
+	// DiagnosticIgnored is an attribute or block that no field would be populated from.
+	DiagnosticIgnored DiagnosticKind = iota
+	// DiagnosticMissing is a required attribute or block that is not present.
+	DiagnosticMissing
+	// DiagnosticMismatch is a value or block that can't be decoded into its field.
+	DiagnosticMismatch
+)
+
+func (k DiagnosticKind) String() string {
+	switch k {
+	case DiagnosticMissing:
+		return "missing"
+	case DiagnosticMismatch:
+		return "mismatch"
+	default:
+		return "ignored"
+	}
+}
+
+// Diagnostic is a compatibility problem between a document and a Go type.
+//
+// Diagnostic implements participle.Error, so it can be rendered with
+// DiagnosticWriter.WriteError.
+type Diagnostic struct {
+	Kind DiagnosticKind
+	Pos  lexer.Position
+	// Path of the enclosing block, as passed to WithUnusedCallback, eg. "server.web".
+	Path string
+	Msg  string
+	// Code identifies the class of problem, eg. CodeMissingAttribute, and is
+	// stable across releases, unlike Msg.
+	Code string
+	// Args are the arguments for Code passed to a MessageCatalog.
+	Args []interface{}
+}
+
+func (d Diagnostic) Error() string {
+	if d.Pos.Line == 0 {
+		return d.Msg
+	}
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// Message returns the diagnostic message without position information.
+func (d Diagnostic) Message() string { return d.Msg }
+
+// Token returns a token positioned at the problem.
+func (d Diagnostic) Token() lexer.Token { return lexer.Token{Pos: d.Pos} }
+
+// Diagnostics reported by CheckCompatibility, ordered by position.
+type Diagnostics []Diagnostic
+
+// CheckCompatibility reports, without decoding into v, the parts of data that
+// no field of v would be populated from, the required fields of v that are
+// missing, and the values that can't be decoded into their fields.
+//
+// v is a struct or a pointer to a struct, and is not modified. Unlike
+// Unmarshal, checking continues past the first problem, so all of them can be
+// reported at once, eg. as a preflight check when deploying a new version of a
+// program against existing configuration.
+//
+// An error is returned only if data can't be parsed or v is not a struct.
+func CheckCompatibility(data []byte, v interface{}, options ...MarshalOption) (Diagnostics, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't check compatibility with %T, expected a struct or a pointer to a struct", v)
+	}
+	opt := &marshalOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	ast, err := ParseBytes(data, opt.parseOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	ast, err = migrateSchemaVersion(ast, opt)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := preprocessEntries(ast.Entries, opt)
+	if err != nil {
+		return nil, err
+	}
+	// Values are decoded into scratch copies, one entry at a time.
+	partial := *opt
+	partial.partial = true
+	partial.unused = nil
+	partial.warning = nil
+	partial.suppressed = nil
+	c := &compatibilityChecker{opt: &partial, suppressed: opt.suppressed, diagnostics: Diagnostics{}}
+	c.checkEntries(t, nil, ast.Pos, entries)
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		a, b := c.diagnostics[i].Pos, c.diagnostics[j].Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return c.diagnostics, nil
+}
+
+type compatibilityChecker struct {
+	opt         *marshalOptions
+	path        []string
+	diagnostics Diagnostics
+	suppressed  func(diagnostic Diagnostic)
+}
+
+// report a diagnostic about entry, or the document if entry is nil, with the
+// default message msg, and the arguments for code.
+func (c *compatibilityChecker) report(kind DiagnosticKind, entry *Entry, pos lexer.Position, code, msg string, args ...interface{}) {
+	diagnostic := Diagnostic{
+		Kind: kind,
+		Pos:  pos,
+		Path: strings.Join(c.path, "."),
+		Msg:  msg,
+		Code: code,
+		Args: args,
+	}
+	c.add(entry, diagnostic.Localize(c.opt.messages))
+}
+
+// add a diagnostic about entry, unless it is suppressed by an hcl:ignore
+// comment.
+func (c *compatibilityChecker) add(entry *Entry, diagnostic Diagnostic) {
+	if !isSuppressed(entry, diagnostic.Code) {
+		c.diagnostics = append(c.diagnostics, diagnostic)
+	} else if c.suppressed != nil {
+		c.suppressed(diagnostic)
+	}
+}
+
+// mismatch reports err decoding entry at pos.
+func (c *compatibilityChecker) mismatch(entry *Entry, pos lexer.Position, err error) {
+	if perr, ok := err.(participle.Error); ok {
+		if perr.Token().Pos.Line != 0 {
+			pos = perr.Token().Pos
+		}
+		c.report(DiagnosticMismatch, entry, pos, CodeMismatch, perr.Message(), perr.Message())
+		return
+	}
+	c.report(DiagnosticMismatch, entry, pos, CodeMismatch, err.Error(), err.Error())
+}
+
+// checkEntries checks entries against the fields of struct type t. scope is
+// the entry of the enclosing block, or nil for the document, and pos its
+// position, at which missing fields are reported.
+func (c *compatibilityChecker) checkEntries(t reflect.Type, scope *Entry, pos lexer.Position, entries []*Entry) {
+	fields, err := flattenFields(reflect.New(t).Elem(), c.opt)
+	if err != nil {
+		c.mismatch(scope, pos, err)
+		return
+	}
+	claimed := map[*Entry]bool{}
+	remain, remainBlocks := false, false
+	for _, field := range fields {
+		tag := parseTag(t, field, c.opt) // nolint: govet
+		switch {
+		case tag.name == "" || tag.label:
+			continue
+		case tag.remain && field.t.Type == blocksType:
+			remainBlocks = true
+			continue
+		case tag.remain:
+			remain = true
+			continue
+		}
+		matched := c.matchEntries(tag, entries)
+		if len(matched) == 0 && !tag.optional {
+			if tag.block {
+				c.report(DiagnosticMissing, scope, pos, CodeMissingBlock, fmt.Sprintf("missing required block %q", tag.name), tag.name)
+			} else {
+				c.report(DiagnosticMissing, scope, pos, CodeMissingAttribute, fmt.Sprintf("missing required attribute %q", tag.name), tag.name)
+			}
+		}
+		for _, entry := range matched {
+			claimed[entry] = true
+			c.checkEntry(t, field, tag, entry)
+		}
+	}
+	known := fieldNames(t, fields, c.opt)
+	for _, entry := range entries {
+		switch {
+		case claimed[entry] || remain:
+		case entry.Block != nil && (remainBlocks || c.opt.lookupBlockDecoder(entry.Block.Name) != nil):
+		default:
+			c.add(entry, ignoredDiagnostic(strings.Join(c.path, "."), entry, known, c.opt.messages))
+		}
+	}
+}
+
+// matchEntries returns the entries populating the field with tag, as matched
+// by Unmarshal.
+func (c *compatibilityChecker) matchEntries(tag tag, entries []*Entry) []*Entry {
+	names := append([]string{tag.name}, tag.aliases...)
+	out := []*Entry{}
+	for _, entry := range entries {
+		for _, name := range names {
+			if entry.Key() == name || (c.opt.caseInsensitive && strings.EqualFold(entry.Key(), name)) {
+				out = append(out, entry)
+				break
+			}
+		}
+		if tag.union != nil && entry.Block != nil && entry.Key() != tag.name && tag.union.has(entry.Key()) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// checkEntry checks a single entry populating field of struct type t.
+func (c *compatibilityChecker) checkEntry(t reflect.Type, field field, tag tag, entry *Entry) {
+	elem, keyed := blockFieldElem(field.t.Type, tag)
+	switch {
+	case elem != nil && entry.Block != nil:
+		block := entry.Block
+		if keyed {
+			if len(block.Labels) == 0 {
+				msg := fmt.Sprintf("block %q expects a label for its key", block.Name)
+				c.report(DiagnosticMismatch, entry, block.Pos, CodeMismatch, msg, msg)
+				return
+			}
+			inner := *block
+			inner.Labels = block.Labels[1:]
+			block = &inner
+		}
+		// Decode the labels alone, then check the body entry by entry.
+		header := *block
+		header.Body = nil
+		if err := unmarshalBlock(reflect.New(elem).Elem(), &header, c.opt); err != nil {
+			c.mismatch(entry, entry.Pos, err)
+			return
+		}
+		depth := len(c.path)
+		c.path = append(append(c.path, entry.Block.Name), entry.Block.Labels...)
+		c.checkEntries(elem, entry, block.Pos, block.Body)
+		c.path = c.path[:depth]
+
+	case elem != nil && tag.inline && entry.Attribute != nil:
+		block, err := objectToBlock(tag.name, entry.Attribute.Value)
+		if err != nil {
+			c.mismatch(entry, entry.Pos, err)
+			return
+		}
+		depth := len(c.path)
+		c.path = append(c.path, tag.name)
+		c.checkEntries(elem, entry, entry.Pos, block.Body)
+		c.path = c.path[:depth]
+
+	default:
+		if err := unmarshalEntries(reflect.New(t).Elem(), []*Entry{entry}, c.opt); err != nil {
+			c.mismatch(entry, entry.Pos, err)
+		}
+	}
+}
+
+// blockFieldElem returns the struct type of the blocks decoded into a field of
+// type t, or nil if the field is not populated from blocks, and whether blocks
+// are keyed by their first label, as for maps of blocks.
+func blockFieldElem(t reflect.Type, tag tag) (elem reflect.Type, keyed bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		if !tag.block {
+			return nil, false
+		}
+		elem, _ = blockMapElem(t)
+		keyed = true
+	case reflect.Slice:
+		elem = t.Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+	case reflect.Struct:
+		elem = t
+	default:
+		return nil, false
+	}
+	if elem.Kind() != reflect.Struct || elem == pathType || typeImplements(elem, textUnmarshalerInterface) {
+		return nil, false
+	}
+	return elem, keyed
+}
@@ -0,0 +1,85 @@
+// Package hcl1 mirrors the decoding API of hashicorp/hcl v1, backed by
+// github.com/alecthomas/hcl, so that projects migrating from it can switch
+// imports with minimal changes:
+//
+//	import hcl "github.com/alecthomas/hcl/compat/hcl1"
+//
+// Struct tags are interpreted as by hashicorp/hcl v1, as described by
+// hcl.HCL1Tags. Names are matched to fields regardless of case, and values are
+// converted between types as with hcl.WithWeakTypes.
+//
+// ASTs are those of github.com/alecthomas/hcl, rather than the hcl/ast
+// package of v1.
+package hcl1
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/alecthomas/hcl"
+)
+
+// Options used to decode, mirroring hashicorp/hcl v1.
+func options() []hcl.MarshalOption {
+	return []hcl.MarshalOption{hcl.HCL1Tags(true), hcl.CaseInsensitive(true), hcl.WithWeakTypes()}
+}
+
+// Decode reads the given input and decodes it into the structure given by
+// out.
+func Decode(out interface{}, in string) error {
+	return Unmarshal([]byte(in), out)
+}
+
+// Unmarshal accepts a byte slice as input and writes the data to the value
+// pointed to by v.
+func Unmarshal(bs []byte, v interface{}) error {
+	ast, err := hcl.ParseBytes(bs)
+	if err != nil {
+		return err
+	}
+	return DecodeObject(v, ast)
+}
+
+// DecodeObject is a lower-level version of Decode. It decodes a raw node,
+// either a *hcl.AST or a *hcl.Block, into the given output.
+func DecodeObject(out interface{}, node hcl.Node) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("result must be a pointer")
+	}
+	// v1 decodes into an empty interface as a map.
+	if rv.Elem().Kind() == reflect.Interface && rv.Elem().NumMethod() == 0 {
+		m := map[string]interface{}{}
+		if err := DecodeObject(&m, node); err != nil {
+			return err
+		}
+		rv.Elem().Set(reflect.ValueOf(m))
+		return nil
+	}
+	switch node := node.(type) {
+	case *hcl.AST:
+		return hcl.UnmarshalAST(node, out, options()...)
+	case *hcl.Block:
+		if rv.Elem().Kind() == reflect.Map {
+			return hcl.UnmarshalAST(&hcl.AST{Entries: node.Body}, out, options()...)
+		}
+		return hcl.UnmarshalBlock(node, out, options()...)
+	default:
+		return fmt.Errorf("can't decode %T", node)
+	}
+}
+
+// Parse parses the given input and returns the root of the AST.
+func Parse(input string) (*hcl.AST, error) {
+	return hcl.ParseString(input)
+}
+
+// ParseBytes accepts as input a byte slice and returns the root of the AST.
+func ParseBytes(in []byte) (*hcl.AST, error) {
+	return hcl.ParseBytes(in)
+}
+
+// ParseString accepts input as a string and returns the root of the AST.
+func ParseString(input string) (*hcl.AST, error) {
+	return hcl.ParseString(input)
+}
@@ -0,0 +1,80 @@
+package hcl1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/hcl"
+)
+
+type service struct {
+	Name    string   `hcl:",key"`
+	Port    int      `hcl:"port"`
+	Hosts   []string `hcl:"hosts"`
+	Decoded []string `hcl:",decodedFields"`
+}
+
+type backend struct {
+	Port int `hcl:"port"`
+}
+
+type config struct {
+	Region   string             `hcl:"region"`
+	Debug    bool               `hcl:"debug"`
+	Services []service          `hcl:"service"`
+	Backends map[string]backend `hcl:"backend"`
+	Timeout  int
+}
+
+const source = `
+region = "us-east-1"
+timeout = "30"
+
+service "api" {
+  port = 8080
+  hosts = ["a", "b"]
+}
+
+service "web" {
+  port = 80
+}
+
+backend "db" {
+  port = 5432
+}
+`
+
+func TestDecode(t *testing.T) {
+	out := config{}
+	err := Decode(&out, source)
+	require.NoError(t, err)
+	require.Equal(t, config{
+		Region: "us-east-1",
+		Services: []service{
+			{Name: "api", Port: 8080, Hosts: []string{"a", "b"}},
+			{Name: "web", Port: 80},
+		},
+		Backends: map[string]backend{"db": {Port: 5432}},
+		Timeout:  30,
+	}, out)
+}
+
+func TestDecodeObject(t *testing.T) {
+	ast, err := Parse(source)
+	require.NoError(t, err)
+	svc := service{}
+	err = DecodeObject(&svc, ast.Entries[2].Block)
+	require.NoError(t, err)
+	require.Equal(t, service{Name: "api", Port: 8080, Hosts: []string{"a", "b"}}, svc)
+
+	var generic interface{}
+	named, err := hcl.ParseString(`name = "x"`)
+	require.NoError(t, err)
+	err = DecodeObject(&generic, named)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"name": "x"}, generic)
+
+	err = DecodeObject(svc, ast)
+	require.EqualError(t, err, "result must be a pointer")
+}
@@ -0,0 +1,77 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	type listener struct {
+		Port int    `hcl:"port"`
+		TLS  bool   `hcl:"tls,optional"`
+		Mode string `hcl:"mode,optional" enum:"http,grpc"`
+	}
+	type service struct {
+		Name      string     `hcl:"name,label"`
+		Replicas  int        `hcl:"replicas"`
+		Listeners []listener `hcl:"listener,block"`
+	}
+	type config struct {
+		Env      string    `hcl:"env"`
+		Debug    bool      `hcl:"debug,optional"`
+		Services []service `hcl:"service,block"`
+	}
+	data := []byte(`
+env = "prod"
+verbose = true
+
+service "api" {
+  replicas = "three"
+  listener {
+    port = 8080
+    mode = "ftp"
+  }
+}
+
+service "web" "extra" {
+  replicas = 1
+}
+
+service "db" {
+  listener {
+    tls = true
+  }
+}
+`)
+	v := &config{Env: "dev"}
+	diagnostics, err := CheckCompatibility(data, v)
+	require.NoError(t, err)
+	require.Equal(t, &config{Env: "dev"}, v)
+	pos := func(line, column int) lexer.Position {
+		return lexer.Position{Line: line, Column: column}
+	}
+	actual := Diagnostics{}
+	for _, diagnostic := range diagnostics {
+		diagnostic.Pos = pos(diagnostic.Pos.Line, diagnostic.Pos.Column)
+		actual = append(actual, diagnostic)
+	}
+	require.Equal(t, Diagnostics{
+		{Kind: DiagnosticIgnored, Pos: pos(3, 1), Msg: `attribute "verbose" would be ignored`,
+			Code: CodeIgnoredAttribute, Args: []interface{}{"verbose", ""}},
+		{Kind: DiagnosticMismatch, Pos: pos(6, 14), Path: "service.api", Msg: `replicas: expected a number but got "three"`,
+			Code: CodeMismatch, Args: []interface{}{`replicas: expected a number but got "three"`}},
+		{Kind: DiagnosticMismatch, Pos: pos(9, 5), Path: "service.api.listener", Msg: `mode: value "ftp" does not match anything within enum "http", "grpc"`,
+			Code: CodeMismatch, Args: []interface{}{`mode: value "ftp" does not match anything within enum "http", "grpc"`}},
+		{Kind: DiagnosticMismatch, Pos: pos(13, 1), Msg: `block "service" expects 1 label ("name"), got 2`,
+			Code: CodeMismatch, Args: []interface{}{`block "service" expects 1 label ("name"), got 2`}},
+		{Kind: DiagnosticMissing, Pos: pos(17, 1), Path: "service.db", Msg: `missing required attribute "replicas"`,
+			Code: CodeMissingAttribute, Args: []interface{}{"replicas"}},
+		{Kind: DiagnosticMissing, Pos: pos(18, 3), Path: "service.db.listener", Msg: `missing required attribute "port"`,
+			Code: CodeMissingAttribute, Args: []interface{}{"port"}},
+	}, actual)
+
+	_, err = CheckCompatibility(data, 1)
+	require.EqualError(t, err, "can't check compatibility with int, expected a struct or a pointer to a struct")
+}
@@ -0,0 +1,75 @@
+package hcl
+
+import (
+	"math/big"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"github.com/alecthomas/participle"
+)
+
+// Compiled pattern:"" tags, as tags are parsed for every value decoded.
+var patterns sync.Map
+
+// compilePattern compiles the pattern:"" tag of a field, panicking if it is invalid.
+func compilePattern(parent reflect.Type, t reflect.StructField, pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		panic("invalid pattern " + pattern + " on " + fieldID(parent, t) + ": " + err.Error())
+	}
+	patterns.Store(pattern, re)
+	return re
+}
+
+// parseBound parses the min:"" or max:"" tag of a field, panicking if it is invalid.
+func parseBound(parent reflect.Type, t reflect.StructField, name string) *big.Float {
+	bound := t.Tag.Get(name)
+	if bound == "" {
+		return nil
+	}
+	n, err := parseNumber(bound)
+	if err != nil {
+		panic("invalid " + name + " " + bound + " on " + fieldID(parent, t))
+	}
+	return n
+}
+
+// checkConstraints checks v, or each element of v if it is a list, against
+// the pattern, min and max tags of a field.
+func checkConstraints(v *Value, tag tag) error {
+	if tag.pattern == nil && tag.min == nil && tag.max == nil {
+		return nil
+	}
+	if v.HaveList {
+		for _, el := range v.List {
+			if err := checkConstraints(el, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tag.pattern != nil && (v.Str != nil || v.HeredocDelimiter != "") {
+		var s string
+		if v.Str != nil {
+			s = *v.Str
+		} else {
+			s = v.GetHeredoc()
+		}
+		if !tag.pattern.MatchString(s) {
+			return participle.Errorf(v.Pos, "value %q does not match pattern %s", s, tag.pattern)
+		}
+	}
+	if v.Number != nil {
+		if tag.min != nil && v.Number.Cmp(tag.min) < 0 {
+			return participle.Errorf(v.Pos, "value %s is less than the minimum of %s", v, formatNumber(tag.min, 10))
+		}
+		if tag.max != nil && v.Number.Cmp(tag.max) > 0 {
+			return participle.Errorf(v.Pos, "value %s is greater than the maximum of %s", v, formatNumber(tag.max, 10))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// A Decoder decodes a document into a struct incrementally, as entries are
+// appended to it, eg. by an interactive configuration console.
+//
+// Each call decodes the whole document so far into a copy of the struct as it
+// was when the Decoder was created, so that duplicate attributes and blocks
+// and merges into existing values are handled exactly as if the document had
+// been decoded at once. The struct is only updated if decoding succeeds.
+//
+// As the document is incomplete until Finish is called, missing required
+// attributes and blocks of the document itself aren't errors before then.
+type Decoder struct {
+	v       reflect.Value
+	initial reflect.Value
+	options []MarshalOption
+	ast     *AST
+	// Position in the document at which appended source starts.
+	next origin
+}
+
+// DecoderSnapshot is the state of a Decoder, to restore with Restore.
+type DecoderSnapshot struct {
+	entries int
+	next    origin
+}
+
+// NewDecoder creates a Decoder decoding into the struct v points to.
+func NewDecoder(v interface{}, options ...MarshalOption) (*Decoder, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T must be a pointer to a struct", v)
+	}
+	return &Decoder{
+		v:       rv.Elem(),
+		initial: deepCopy(rv.Elem()),
+		options: options,
+		ast:     &AST{},
+	}, nil
+}
+
+// Decode parses data, appended to the source decoded so far, and decodes the
+// resulting document.
+//
+// Positions continue from the source decoded so far, with data starting on a
+// new line. If data fails to parse or decode, the Decoder is unchanged.
+func (d *Decoder) Decode(data []byte) error {
+	ast, err := ParseBytes(data, newMarshalOptions(d.options...).parseOptions()...)
+	if err != nil {
+		return d.next.shiftError(err)
+	}
+	if err := d.next.shiftPositions(ast); err != nil {
+		return err
+	}
+	if err := d.DecodeAST(ast); err != nil {
+		return err
+	}
+	d.next.lines += bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		d.next.lines++
+	}
+	d.next.offset += len(data)
+	return nil
+}
+
+// DecodeAST appends the entries of ast to the document and decodes it.
+//
+// If the document fails to decode, the Decoder is unchanged.
+func (d *Decoder) DecodeAST(ast *AST) error {
+	entries := append(append([]*Entry{}, d.ast.Entries...), ast.Entries...)
+	if err := d.decode(entries, true); err != nil {
+		return err
+	}
+	d.ast.Entries = entries
+	return AddParentRefs(d.ast)
+}
+
+// Finish decodes the document, checking that it is complete.
+func (d *Decoder) Finish() error {
+	return d.decode(d.ast.Entries, false)
+}
+
+// AST returns the document decoded so far.
+func (d *Decoder) AST() *AST {
+	return d.ast
+}
+
+// Snapshot returns the current state of the Decoder.
+func (d *Decoder) Snapshot() DecoderSnapshot {
+	return DecoderSnapshot{entries: len(d.ast.Entries), next: d.next}
+}
+
+// Restore the Decoder and its struct to the state of snapshot, discarding the
+// entries decoded since.
+func (d *Decoder) Restore(snapshot DecoderSnapshot) error {
+	if snapshot.entries > len(d.ast.Entries) {
+		return fmt.Errorf("snapshot of %d entries is newer than the document of %d entries", snapshot.entries, len(d.ast.Entries))
+	}
+	entries := d.ast.Entries[:snapshot.entries:snapshot.entries]
+	if err := d.decode(entries, true); err != nil {
+		return err
+	}
+	d.ast.Entries = entries
+	d.next = snapshot.next
+	return nil
+}
+
+// decode entries into a copy of the initial struct, replacing the struct if
+// it succeeds.
+func (d *Decoder) decode(entries []*Entry, partial bool) error {
+	opt := newMarshalOptions(d.options...)
+	opt.partialRoot = partial
+	ast, err := migrateSchemaVersion(&AST{Entries: entries}, opt)
+	if err != nil {
+		return err
+	}
+	entries, err = preprocessEntries(ast.Entries, opt)
+	if err != nil {
+		return err
+	}
+	v := deepCopy(d.initial)
+	if err := unmarshalEntries(v, entries, opt); err != nil {
+		return err
+	}
+	if err := resolveFallbacks(v, opt); err != nil {
+		return err
+	}
+	d.v.Set(v)
+	return nil
+}
+
+// deepCopy returns a copy of v that shares no pointers, maps or slices with
+// it, other than those in unexported fields.
+func deepCopy(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			out.Set(reflect.New(v.Type().Elem()))
+			out.Elem().Set(deepCopy(v.Elem()))
+		}
+
+	case reflect.Interface:
+		if !v.IsNil() {
+			out.Set(deepCopy(v.Elem()))
+		}
+
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			for i := 0; i < v.Len(); i++ {
+				out.Index(i).Set(deepCopy(v.Index(i)))
+			}
+		}
+
+	case reflect.Map:
+		if !v.IsNil() {
+			out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+			}
+		}
+
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				out.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+
+	default:
+		out.Set(v)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	type service struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Env      string            `hcl:"env"`
+		Tags     map[string]string `hcl:"tags,optional"`
+		Services []service         `hcl:"service,block"`
+	}
+	cfg := &config{Tags: map[string]string{"team": "core"}}
+	decoder, err := NewDecoder(cfg)
+	require.NoError(t, err)
+
+	err = decoder.Decode([]byte("service \"api\" {\n  port = 80\n}\n"))
+	require.NoError(t, err)
+	require.Equal(t, &config{Tags: map[string]string{"team": "core"}, Services: []service{{Name: "api", Port: 80}}}, cfg)
+
+	// Missing required attributes of the document aren't errors until Finish.
+	require.EqualError(t, decoder.Finish(), `missing required attribute "env"`)
+
+	snapshot := decoder.Snapshot()
+	err = decoder.Decode([]byte("env = \"dev\"\ntags = { owner: \"alice\" }\nservice \"web\" {\n  port = 8080\n}\n"))
+	require.NoError(t, err)
+	require.Equal(t, &config{
+		Env:      "dev",
+		Tags:     map[string]string{"team": "core", "owner": "alice"},
+		Services: []service{{Name: "api", Port: 80}, {Name: "web", Port: 8080}},
+	}, cfg)
+	require.NoError(t, decoder.Finish())
+
+	// Duplicates across calls are errors, positioned in the whole document,
+	// and leave the Decoder unchanged.
+	err = decoder.Decode([]byte(`env = "prod"`))
+	require.EqualError(t, err, `4:1: env: duplicate field "env" at 9:1`)
+	require.Equal(t, "dev", cfg.Env)
+	err = decoder.Decode([]byte("service \"db\" {\n}\n"))
+	require.EqualError(t, err, `9:1: service[2]: missing required attribute "port"`)
+	require.Len(t, decoder.AST().Entries, 4)
+
+	// Restoring a snapshot discards later entries, without losing values
+	// set before decoding.
+	err = decoder.Restore(snapshot)
+	require.NoError(t, err)
+	require.Equal(t, &config{Tags: map[string]string{"team": "core"}, Services: []service{{Name: "api", Port: 80}}}, cfg)
+	err = decoder.Decode([]byte(`env = "prod"`))
+	require.NoError(t, err)
+	require.Equal(t, "prod", cfg.Env)
+	require.Equal(t, 4, decoder.AST().Entries[1].Pos.Line)
+
+	_, err = NewDecoder(config{})
+	require.EqualError(t, err, "hcl.config must be a pointer to a struct")
+}
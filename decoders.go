@@ -0,0 +1,62 @@
+package hcl
+
+import (
+	"sync"
+)
+
+// A BlockDecoder decodes a block claimed via RegisterBlockDecoder.
+type BlockDecoder func(block *Block) error
+
+var (
+	blockDecodersLock sync.RWMutex
+	blockDecoders     = map[string]BlockDecoder{}
+)
+
+// RegisterBlockDecoder registers decoder to decode blocks called name.
+//
+// During unmarshalling, each such block that is not claimed by a field of the
+// target struct is passed to decoder rather than being reported as an extra
+// field. This allows third-party packages to decode their own blocks
+// independently of the root struct, eg.
+//
+//	hcl.RegisterBlockDecoder("datasource", func(block *hcl.Block) error {
+//		ds := &DataSource{}
+//		if err := hcl.UnmarshalBlock(block, ds); err != nil {
+//			return err
+//		}
+//		dataSources = append(dataSources, ds)
+//		return nil
+//	})
+//
+// Registering a nil decoder removes any existing decoder for name.
+func RegisterBlockDecoder(name string, decoder BlockDecoder) {
+	blockDecodersLock.Lock()
+	defer blockDecodersLock.Unlock()
+	if decoder == nil {
+		delete(blockDecoders, name)
+		return
+	}
+	blockDecoders[name] = decoder
+}
+
+// WithBlockDecoder registers decoder to decode blocks called name, as with
+// RegisterBlockDecoder, for a single call to Unmarshal, taking precedence over
+// any decoder registered for name. A nil decoder disables decoding of the
+// blocks during the call.
+func WithBlockDecoder(name string, decoder BlockDecoder) MarshalOption {
+	return func(options *marshalOptions) {
+		if options.blockDecoders == nil {
+			options.blockDecoders = map[string]BlockDecoder{}
+		}
+		options.blockDecoders[name] = decoder
+	}
+}
+
+func (o *marshalOptions) lookupBlockDecoder(name string) BlockDecoder {
+	if decoder, ok := o.blockDecoders[name]; ok {
+		return decoder
+	}
+	blockDecodersLock.RLock()
+	defer blockDecodersLock.RUnlock()
+	return blockDecoders[name]
+}
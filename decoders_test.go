@@ -0,0 +1,54 @@
+package hcl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterBlockDecoder(t *testing.T) {
+	type dataSource struct {
+		Name string `hcl:"name,label"`
+		URL  string `hcl:"url"`
+	}
+	dataSources := []*dataSource{}
+	RegisterBlockDecoder("datasource", func(block *Block) error {
+		ds := &dataSource{}
+		if err := UnmarshalBlock(block, ds); err != nil {
+			return err
+		}
+		if ds.URL == "" {
+			return fmt.Errorf("datasource %q has no URL", ds.Name)
+		}
+		dataSources = append(dataSources, ds)
+		return nil
+	})
+	defer RegisterBlockDecoder("datasource", nil)
+
+	type config struct {
+		Version int `hcl:"version"`
+	}
+	actual := &config{}
+	err := Unmarshal([]byte(`
+version = 1
+datasource "a" { url = "http://a" }
+datasource "b" { url = "http://b" }
+`), actual)
+	require.NoError(t, err)
+	require.Equal(t, &config{Version: 1}, actual)
+	require.Equal(t, []*dataSource{{Name: "a", URL: "http://a"}, {Name: "b", URL: "http://b"}}, dataSources)
+
+	err = Unmarshal([]byte(`
+version = 1
+datasource "c" { url = "" }
+`), actual)
+	require.EqualError(t, err, `3:1: datasource "c" has no URL`)
+
+	RegisterBlockDecoder("datasource", nil)
+	err = Unmarshal([]byte(`
+version = 1
+datasource "a" { url = "http://a" }
+`), actual)
+	require.EqualError(t, err, `3:1: found extra fields "datasource"`)
+}
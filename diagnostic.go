@@ -0,0 +1,140 @@
+package hcl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+)
+
+// ANSI escape sequences used by DiagnosticWriter.
+const (
+	ansiReset      = "\033[0m"
+	ansiBoldRed    = "\033[1;31m"
+	ansiBoldYellow = "\033[1;33m"
+	ansiBold       = "\033[1m"
+	ansiBlue       = "\033[34m"
+)
+
+// DiagnosticOption configures a DiagnosticWriter.
+type DiagnosticOption func(d *DiagnosticWriter)
+
+// DiagnosticWidth sets the maximum width of rendered source excerpts.
+//
+// Defaults to DefaultSnippetWidth.
+func DiagnosticWidth(width int) DiagnosticOption {
+	return func(d *DiagnosticWriter) {
+		d.width = width
+	}
+}
+
+// DiagnosticColor enables or disables ANSI colour output.
+func DiagnosticColor(color bool) DiagnosticOption {
+	return func(d *DiagnosticWriter) {
+		d.color = color
+	}
+}
+
+// DiagnosticColumns sets the unit in which columns are reported.
+//
+// Defaults to ColumnRunes.
+func DiagnosticColumns(unit ColumnUnit) DiagnosticOption {
+	return func(d *DiagnosticWriter) {
+		d.columns = unit
+	}
+}
+
+// DiagnosticMessages replaces the messages of Diagnostics, and of errors with
+// an ErrorCode, with those from catalog.
+func DiagnosticMessages(catalog MessageCatalog) DiagnosticOption {
+	return func(d *DiagnosticWriter) {
+		d.messages = catalog
+	}
+}
+
+// DiagnosticWriter renders errors as caret-annotated source excerpts, eg.
+//
+//	error: unexpected token "}"
+//	 --> config.hcl:3:7
+//	  |
+//	3 |   a = }
+//	  |       ^
+type DiagnosticWriter struct {
+	w        io.Writer
+	width    int
+	color    bool
+	columns  ColumnUnit
+	messages MessageCatalog
+}
+
+// NewDiagnosticWriter creates a new DiagnosticWriter writing to w.
+func NewDiagnosticWriter(w io.Writer, options ...DiagnosticOption) *DiagnosticWriter {
+	d := &DiagnosticWriter{w: w, width: DefaultSnippetWidth}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// WriteError renders err with an excerpt of the source at which it occurred.
+//
+// Errors without position information are rendered without an excerpt.
+func (d *DiagnosticWriter) WriteError(err error, source []byte) error {
+	return d.write("error:", ansiBoldRed, err, source)
+}
+
+// WriteWarning renders a warning with an excerpt of the source at which it occurred.
+func (d *DiagnosticWriter) WriteWarning(warning Warning, source []byte) error {
+	return d.write("warning:", ansiBoldYellow, warning, source)
+}
+
+func (d *DiagnosticWriter) write(severity, color string, err error, source []byte) error {
+	w := &strings.Builder{}
+	perr, ok := err.(participle.Error)
+	if diagnostic, isDiagnostic := err.(Diagnostic); isDiagnostic {
+		perr = diagnostic.Localize(d.messages)
+	} else if code := ErrorCode(err); ok && code != "" && d.messages != nil {
+		if msg, localized := d.messages.Message(code, []interface{}{perr.Message()}); localized {
+			perr = participle.Errorf(perr.Token().Pos, "%s", msg).(participle.Error)
+		}
+	}
+	if !ok {
+		fmt.Fprintf(w, "%s %s\n", d.paint(color, severity), d.paint(ansiBold, err.Error()))
+		_, err := io.WriteString(d.w, w.String())
+		return err
+	}
+	pos := perr.Token().Pos
+	fmt.Fprintf(w, "%s %s\n", d.paint(color, severity), d.paint(ansiBold, perr.Message()))
+	lineNo := strconv.Itoa(pos.Line)
+	gutter := strings.Repeat(" ", len(lineNo))
+	column := pos.Column
+	if len(source) > 0 {
+		column = ColumnOf(source, pos, d.columns)
+	}
+	location := fmt.Sprintf("%d:%d", pos.Line, column)
+	if pos.Filename != "" {
+		location = pos.Filename + ":" + location
+	}
+	fmt.Fprintf(w, "%s%s %s\n", gutter, d.paint(ansiBlue, "-->"), location)
+	if len(source) > 0 {
+		width := d.width
+		if width <= 0 {
+			width = DefaultSnippetWidth
+		}
+		snippet, caret := snippetWindow(source, resolveOffset(pos, source), width)
+		fmt.Fprintf(w, "%s %s\n", gutter, d.paint(ansiBlue, "|"))
+		fmt.Fprintf(w, "%s %s %s\n", d.paint(ansiBlue, lineNo), d.paint(ansiBlue, "|"), snippet)
+		fmt.Fprintf(w, "%s %s %s%s\n", gutter, d.paint(ansiBlue, "|"), strings.Repeat(" ", caret), d.paint(color, "^"))
+	}
+	_, err = io.WriteString(d.w, w.String())
+	return err
+}
+
+func (d *DiagnosticWriter) paint(color, s string) string {
+	if !d.color {
+		return s
+	}
+	return color + s + ansiReset
+}
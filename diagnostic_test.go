@@ -0,0 +1,86 @@
+package hcl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticWriter(t *testing.T) {
+	source := []byte("a = 1\nb = }\n")
+	err := participle.Errorf(lexer.Position{Filename: "config.hcl", Line: 2, Column: 5}, "unexpected token %q", "}")
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w).WriteError(err, source))
+	require.Equal(t, `error: unexpected token "}"
+ --> config.hcl:2:5
+  |
+2 | b = }
+  |     ^
+`, w.String())
+}
+
+func TestDiagnosticWriterWarning(t *testing.T) {
+	source := []byte("old = 1\n")
+	warning := Warning{Pos: lexer.Position{Line: 1, Column: 1}, Msg: `attribute "old" is deprecated: use new instead`}
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w).WriteWarning(warning, source))
+	require.Equal(t, `warning: attribute "old" is deprecated: use new instead
+ --> 1:1
+  |
+1 | old = 1
+  | ^
+`, w.String())
+}
+
+func TestDiagnosticWriterColor(t *testing.T) {
+	source := []byte("b = }\n")
+	err := participle.Errorf(lexer.Position{Line: 1, Column: 5}, "oops")
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w, DiagnosticColor(true)).WriteError(err, source))
+	require.Equal(t, "\033[1;31merror:\033[0m \033[1moops\033[0m\n"+
+		" \033[34m-->\033[0m 1:5\n"+
+		"  \033[34m|\033[0m\n"+
+		"\033[34m1\033[0m \033[34m|\033[0m b = }\n"+
+		"  \033[34m|\033[0m     \033[1;31m^\033[0m\n", w.String())
+}
+
+func TestDiagnosticWriterWidth(t *testing.T) {
+	source := []byte("a = \"" + strings.Repeat("x", 100) + "\" }\n")
+	err := participle.Errorf(lexer.Position{Line: 1, Column: 108}, "oops")
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w, DiagnosticWidth(20)).WriteError(err, source))
+	require.Equal(t, `error: oops
+ --> 1:108
+  |
+1 | ...xxxxxxxx" }
+  |              ^
+`, w.String())
+}
+
+func TestDiagnosticWriterWithoutPosition(t *testing.T) {
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w).WriteError(errors.New("oops"), nil))
+	require.Equal(t, "error: oops\n", w.String())
+}
+
+func TestDiagnosticWriterMultiByte(t *testing.T) {
+	source := []byte("// héllo wörld\nname = \"日本語\" ?\n")
+	_, err := ParseBytes(source)
+	require.Error(t, err)
+	w := &strings.Builder{}
+	require.NoError(t, NewDiagnosticWriter(w).WriteError(err, source))
+	require.Equal(t, `error: no lexer rules in state "Root" matched input text "?\n"
+ --> 2:14
+  |
+2 | name = "日本語" ?
+  |              ^
+`, w.String())
+
+	w.Reset()
+	require.NoError(t, NewDiagnosticWriter(w, DiagnosticColumns(ColumnBytes)).WriteError(err, source))
+	require.Contains(t, w.String(), " --> 2:20\n")
+}
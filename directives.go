@@ -0,0 +1,210 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Directive is a top-level directive registered with RegisterDirective, eg.
+//
+//	@include "base.hcl", "overrides.hcl"
+//	@require_version "1.2"
+//
+// Directives precede the attributes and blocks of a document, and their
+// arguments are a comma separated list of values starting on the same line
+// as the directive's name.
+type Directive struct {
+	Pos    lexer.Position `parser:"" json:"-"`
+	Parent Node           `parser:"" json:"-"`
+
+	Comments      []string       `parser:"" json:"comments,omitempty"`
+	CommentStyles []CommentStyle `parser:"" json:"comment_styles,omitempty"`
+
+	// Name of the directive, without the leading "@".
+	Name string   `parser:"" json:"name"`
+	Args []*Value `parser:"" json:"args,omitempty"`
+
+	// Comments on the same line as, and following, the directive.
+	TrailingComments      []string       `parser:"" json:"trailing_comments,omitempty"`
+	TrailingCommentStyles []CommentStyle `parser:"" json:"trailing_comment_styles,omitempty"`
+}
+
+func (*Directive) node() {}
+
+var (
+	directiveSymbol       = lex.Symbols()["Directive"]
+	commentSymbol         = lex.Symbols()["Comment"]
+	trailingCommentSymbol = lex.Symbols()["TrailingComment"]
+	punctSymbol           = lex.Symbols()["Punct"]
+)
+
+// Parse a directive, preceded by any comments.
+//
+// Directives are parsed by hand so that comments preceding an entry, rather
+// than a directive, are not consumed, and so that the first argument must be
+// on the same line as the directive's name.
+func (d *Directive) Parse(lex *lexer.PeekingLexer) error {
+	n := 0
+	for {
+		token, err := lex.Peek(n)
+		if err != nil {
+			return err
+		}
+		if token.Type == directiveSymbol {
+			break
+		}
+		if token.Type != commentSymbol && token.Type != trailingCommentSymbol {
+			return participle.NextMatch
+		}
+		n++
+	}
+	line := 0
+	for i := 0; i <= n; i++ {
+		token, err := lex.Next()
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			d.Pos = token.Pos
+		}
+		if i < n {
+			d.Comments = append(d.Comments, token.Value)
+		} else {
+			d.Name = strings.TrimPrefix(token.Value, "@")
+			line = token.Pos.Line
+		}
+	}
+	// Arguments start on the directive's line, and continue after commas.
+	token, err := lex.Peek(0)
+	if err != nil {
+		return err
+	}
+	more := !token.EOF() && token.Pos.Line == line && token.Type != commentSymbol && token.Type != trailingCommentSymbol
+	for more {
+		arg := &Value{}
+		if err := valueParser.ParseFromLexer(lex, arg, participle.AllowTrailing(true)); err != nil {
+			return err
+		}
+		d.Args = append(d.Args, arg)
+		if token, err = lex.Peek(0); err != nil {
+			return err
+		}
+		if more = token.Type == punctSymbol && token.Value == ","; more {
+			_, _ = lex.Next()
+		}
+	}
+	for {
+		token, err := lex.Peek(0)
+		if err != nil {
+			return err
+		}
+		if token.Type != trailingCommentSymbol {
+			return nil
+		}
+		d.TrailingComments = append(d.TrailingComments, token.Value)
+		_, _ = lex.Next()
+	}
+}
+
+func (d *Directive) String() string {
+	args := make([]string, 0, len(d.Args))
+	for _, arg := range d.Args {
+		args = append(args, compactValue(arg))
+	}
+	if len(args) == 0 {
+		return "@" + d.Name
+	}
+	return "@" + d.Name + " " + strings.Join(args, ", ")
+}
+
+// Clone the Directive.
+func (d *Directive) Clone() *Directive {
+	if d == nil {
+		return nil
+	}
+	return &Directive{
+		Pos:           d.Pos,
+		Comments:      cloneStrings(d.Comments),
+		CommentStyles: cloneCommentStyles(d.CommentStyles),
+		Name:          d.Name,
+		Args:          cloneValues(d.Args),
+
+		TrailingComments:      cloneStrings(d.TrailingComments),
+		TrailingCommentStyles: cloneCommentStyles(d.TrailingCommentStyles),
+	}
+}
+
+// A DirectiveHandler validates a directive when it is parsed, eg. its
+// arguments, returning an error if it is invalid.
+type DirectiveHandler func(directive *Directive) error
+
+var (
+	directiveHandlersLock sync.RWMutex
+	directiveHandlers     = map[string]DirectiveHandler{}
+)
+
+// RegisterDirective allows documents to contain the directive @name, which is
+// otherwise a syntax error. Parsed directives are in AST.Directives, for user
+// code to process, eg.
+//
+//	hcl.RegisterDirective("include", func(directive *hcl.Directive) error {
+//		if len(directive.Args) == 0 {
+//			return fmt.Errorf("expected files to include")
+//		}
+//		return nil
+//	})
+//
+// handler is called for each directive as it is parsed. Registering a nil
+// handler removes any existing registration.
+func RegisterDirective(name string, handler DirectiveHandler) {
+	directiveHandlersLock.Lock()
+	defer directiveHandlersLock.Unlock()
+	if handler == nil {
+		delete(directiveHandlers, name)
+		return
+	}
+	directiveHandlers[name] = handler
+}
+
+// WithDirective allows the directive @name, as with RegisterDirective, for a
+// single call to Parse, taking precedence over any handler registered for
+// name. A nil handler rejects the directive during the call.
+func WithDirective(name string, handler DirectiveHandler) ParseOption {
+	return func(options *parseOptions) {
+		if options.directives == nil {
+			options.directives = map[string]DirectiveHandler{}
+		}
+		options.directives[name] = handler
+	}
+}
+
+func (o *parseOptions) lookupDirective(name string) DirectiveHandler {
+	if handler, ok := o.directives[name]; ok {
+		return handler
+	}
+	directiveHandlersLock.RLock()
+	defer directiveHandlersLock.RUnlock()
+	return directiveHandlers[name]
+}
+
+// checkDirectives calls the handlers of the directives in node.
+func checkDirectives(node Node, opt *parseOptions) error {
+	ast, ok := node.(*AST)
+	if !ok {
+		return nil
+	}
+	for _, directive := range ast.Directives {
+		handler := opt.lookupDirective(directive.Name)
+		if handler == nil {
+			return participle.Errorf(directive.Pos, "unknown directive @%s", directive.Name)
+		}
+		if err := handler(directive); err != nil {
+			return participle.AnnotateError(directive.Pos, fmt.Errorf("@%s: %v", directive.Name, err))
+		}
+	}
+	return nil
+}
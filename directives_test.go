@@ -0,0 +1,84 @@
+package hcl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectives(t *testing.T) {
+	var seen []string
+	RegisterDirective("include", func(directive *Directive) error {
+		seen = append(seen, directive.String())
+		if len(directive.Args) == 0 {
+			return fmt.Errorf("expected files to include")
+		}
+		return nil
+	})
+	RegisterDirective("require_version", func(directive *Directive) error { return nil })
+	defer RegisterDirective("include", nil)
+	defer RegisterDirective("require_version", nil)
+
+	ast, err := ParseString(`// Base configuration.
+@include "base.hcl", "prod.hcl"
+@require_version "1.2"
+
+name = "app"
+`)
+	require.NoError(t, err)
+	require.Equal(t, []string{`@include "base.hcl", "prod.hcl"`}, seen)
+	require.Len(t, ast.Directives, 2)
+	include := ast.Directives[0]
+	require.Equal(t, "include", include.Name)
+	require.Equal(t, []string{"Base configuration."}, include.Comments)
+	require.Equal(t, "1:1", include.Pos.String())
+	require.Equal(t, "3:1", ast.Directives[1].Pos.String())
+	require.Equal(t, "2:10", include.Args[0].Pos.String())
+	require.Equal(t, "2:22", include.Args[1].Pos.String())
+	require.Equal(t, "prod.hcl", *include.Args[1].Str)
+	require.True(t, include.Parent == Node(ast))
+	require.Len(t, ast.Entries, 1)
+
+	out, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `// Base configuration.
+@include "base.hcl", "prod.hcl"
+@require_version "1.2"
+
+name = "app"
+`, string(out))
+
+	noArgs, err := ParseString(`@require_version // Any version.
+name = "app"
+`)
+	require.NoError(t, err)
+	require.Empty(t, noArgs.Directives[0].Args)
+	require.Equal(t, []string{"Any version."}, noArgs.Directives[0].TrailingComments)
+	require.Len(t, noArgs.Entries, 1)
+
+	clone := ast.Clone()
+	require.True(t, ASTEqual(ast, clone))
+	clone.Directives[1].Args[0] = str("1.3")
+	require.False(t, ASTEqual(ast, clone))
+}
+
+func TestDirectiveErrors(t *testing.T) {
+	RegisterDirective("include", func(directive *Directive) error {
+		if len(directive.Args) == 0 {
+			return fmt.Errorf("expected files to include")
+		}
+		return nil
+	})
+	defer RegisterDirective("include", nil)
+
+	_, err := ParseString("@import \"a.hcl\"\n")
+	require.EqualError(t, err, `1:1: unknown directive @import`)
+	_, err = ParseString("@include\n")
+	require.EqualError(t, err, `1:1: @include: expected files to include`)
+	_, err = ParseString("@include \"a.hcl\" =\n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1:18:")
+	_, err = ParseString("a = 1\n@include \"a.hcl\"\n")
+	require.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package hcl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// DocumentSeparator separates the documents in a multi-document stream.
+type DocumentSeparator int
+
+// Document separators.
+const (
+	// SeparatorMarker is a line containing only "---".
+	SeparatorMarker DocumentSeparator = iota
+	// SeparatorNUL is a NUL byte.
+	SeparatorNUL
+)
+
+// DocumentReader reads a stream of HCL documents, each separated from the
+// next by either a line containing only "---", or a NUL byte, eg.
+//
+//	name = "a"
+//	---
+//	name = "b"
+//
+// Separators take precedence over HCL syntax, so a heredoc may not contain a
+// "---" line. Documents containing only whitespace are skipped.
+type DocumentReader struct {
+	r        *bufio.Reader
+	opt      *parseOptions
+	filename string
+	// Position of the next document in the stream.
+	next origin
+	eof  bool
+}
+
+// NewDocumentReader creates a DocumentReader reading from r, parsing each
+// document with options.
+//
+// Positions in the parsed documents, and in errors, are relative to the start
+// of the stream. The limit from WithMaxInputSize applies to each document.
+func NewDocumentReader(r io.Reader, options ...ParseOption) *DocumentReader {
+	return &DocumentReader{
+		r:        bufio.NewReader(r),
+		opt:      newParseOptions(options),
+		filename: lexer.NameOfReader(r),
+	}
+}
+
+// Next parses and returns the next document, or io.EOF if there are no more.
+func (d *DocumentReader) Next() (*AST, error) {
+	for {
+		if d.eof {
+			return nil, io.EOF
+		}
+		at := d.next
+		data, err := d.read()
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		opt := *d.opt
+		opt.ignoreLineDirectives = true
+		directives := scanLineDirectives(data, at.lines)
+		ast := &AST{}
+		if err := parseSource(d.filename, data, ast, &opt); err != nil {
+			return nil, directives.mapError(at.shiftError(err))
+		}
+		if err := at.shiftPositions(ast); err != nil {
+			return nil, err
+		}
+		return ast, directives.mapPositions(ast)
+	}
+}
+
+// read the next document, up to and excluding its separator.
+func (d *DocumentReader) read() ([]byte, error) {
+	buf := []byte{}
+	lineStart := 0
+	for {
+		c, err := d.r.ReadByte()
+		if err == io.EOF {
+			d.eof = true
+			if isDocumentMarker(buf[lineStart:]) {
+				buf = buf[:lineStart]
+			}
+			return buf, nil
+		} else if err != nil {
+			return nil, err
+		}
+		d.next.offset++
+		d.next.columns++
+		switch c {
+		case 0:
+			return buf, nil
+
+		case '\n':
+			d.next.lines++
+			d.next.columns = 0
+			if isDocumentMarker(buf[lineStart:]) {
+				return buf[:lineStart], nil
+			}
+			buf = append(buf, c)
+			lineStart = len(buf)
+
+		default:
+			buf = append(buf, c)
+		}
+		if d.opt.maxInputSize > 0 && len(buf) > d.opt.maxInputSize {
+			return nil, inputSizeError(d.opt.maxInputSize)
+		}
+	}
+}
+
+func isDocumentMarker(line []byte) bool {
+	return string(bytes.TrimRight(line, " \t\r")) == "---"
+}
+
+// DocumentWriter writes a stream of HCL documents that can be read with a
+// DocumentReader.
+type DocumentWriter struct {
+	w         io.Writer
+	printer   *Printer
+	separator DocumentSeparator
+	written   bool
+}
+
+// NewDocumentWriter creates a DocumentWriter writing documents to w,
+// separated by separator, and printed with options.
+func NewDocumentWriter(w io.Writer, separator DocumentSeparator, options ...PrinterOption) *DocumentWriter {
+	return &DocumentWriter{w: w, printer: NewPrinter(options...), separator: separator}
+}
+
+// Write a document to the stream.
+func (d *DocumentWriter) Write(ast *AST) error {
+	if d.written {
+		separator := "---\n"
+		if d.separator == SeparatorNUL {
+			separator = "\x00"
+		}
+		if _, err := io.WriteString(d.w, separator); err != nil {
+			return err
+		}
+	}
+	d.written = true
+	return d.printer.Fprint(d.w, ast)
+}
@@ -0,0 +1,63 @@
+package hcl
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentReader(t *testing.T) {
+	source := "---\nname = \"a\"\n---\n\n// Second.\nname = \"b\"\n--- \nname = \"c\"\x00name = \"d\"\n---\n"
+	r := NewDocumentReader(strings.NewReader(source))
+	names := []string{}
+	positions := []string{}
+	for {
+		ast, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Len(t, ast.Entries, 1)
+		names = append(names, *ast.Entries[0].Attribute.Value.Str)
+		positions = append(positions, ast.Entries[0].Pos.String())
+	}
+	require.Equal(t, []string{"a", "b", "c", "d"}, names)
+	require.Equal(t, []string{"2:1", "5:1", "8:1", "8:12"}, positions)
+
+	r = NewDocumentReader(strings.NewReader("a = 1\n---\na = 1\nb = }\n"))
+	_, err := r.Next()
+	require.NoError(t, err)
+	_, err = r.Next()
+	require.Error(t, err)
+	require.Equal(t, 4, err.(interface{ Token() lexer.Token }).Token().Pos.Line)
+}
+
+func TestDocumentWriter(t *testing.T) {
+	for _, separator := range []DocumentSeparator{SeparatorMarker, SeparatorNUL} {
+		w := &bytes.Buffer{}
+		dw := NewDocumentWriter(w, separator)
+		documents := []*AST{}
+		for _, source := range []string{"a = 1\n", "block {\n  b = 2\n}\n", "// Comment.\nc = 3\n"} {
+			ast, err := ParseString(source)
+			require.NoError(t, err)
+			documents = append(documents, ast)
+			require.NoError(t, dw.Write(ast))
+		}
+		if separator == SeparatorMarker {
+			require.Equal(t, "a = 1\n---\nblock {\n  b = 2\n}\n---\n// Comment.\nc = 3\n", w.String())
+		}
+		r := NewDocumentReader(w)
+		for _, expected := range documents {
+			actual, err := r.Next()
+			require.NoError(t, err)
+			require.True(t, ASTEqual(expected, actual, IgnorePositions()))
+		}
+		_, err := r.Next()
+		require.Equal(t, io.EOF, err)
+	}
+}
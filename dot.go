@@ -0,0 +1,77 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxDotValueLength is the number of characters of an attribute's value
+// shown in nodes exported by ToDot.
+const maxDotValueLength = 40
+
+// ToDot exports the block structure of ast as a Graphviz graph, for
+// visualising large configurations, eg. with "dot -Tsvg".
+//
+// The document and each block are nodes, labelled with the block's name and
+// labels and annotated with its attributes, and with edges from each block
+// to the blocks it contains. Long attribute values are truncated.
+func ToDot(ast *AST) []byte {
+	w := &bytes.Buffer{}
+	fmt.Fprintln(w, "digraph hcl {")
+	fmt.Fprintln(w, "  node [shape=box, fontname=monospace];")
+	name := ast.Pos.Filename
+	if name == "" {
+		name = "document"
+	}
+	d := &dotExporter{w: w}
+	d.node(name, ast.Entries, "style=rounded, ")
+	fmt.Fprintln(w, "}")
+	return w.Bytes()
+}
+
+type dotExporter struct {
+	w     *bytes.Buffer
+	nodes int
+}
+
+// node writes a node headed by header for a body, followed by the nodes of
+// the blocks in the body, returning its ID.
+func (d *dotExporter) node(header string, body []*Entry, attrs string) string {
+	id := "n" + strconv.Itoa(d.nodes)
+	d.nodes++
+	label := dotEscape(header) + `\l`
+	for _, entry := range body {
+		if attr := entry.Attribute; attr != nil {
+			label += dotEscape(attr.Key+" = "+truncateDotValue(compactValue(attr.Value))) + `\l`
+		}
+	}
+	fmt.Fprintf(d.w, "  %s [%slabel=\"%s\"];\n", id, attrs, label)
+	for _, entry := range body {
+		block := entry.Block
+		if block == nil {
+			continue
+		}
+		header := block.Name
+		for _, label := range block.Labels {
+			header += " " + strconv.Quote(label)
+		}
+		child := d.node(header, block.Body, "")
+		fmt.Fprintf(d.w, "  %s -> %s;\n", id, child)
+	}
+	return id
+}
+
+func truncateDotValue(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxDotValueLength {
+		return s
+	}
+	return string(runes[:maxDotValueLength-3]) + "..."
+}
+
+// dotEscape escapes s for inclusion in a quoted Graphviz string.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
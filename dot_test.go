@@ -0,0 +1,33 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestToDot(t *testing.T) {
+	ast, err := ParseString(`
+region = "us-east-1"
+service "api" {
+  port = 8080
+  description = "A \"quoted\" description that is much too long to show in full"
+  upstream "db" {}
+}
+service "web" {}
+`)
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimLeft(`
+digraph hcl {
+  node [shape=box, fontname=monospace];
+  n0 [style=rounded, label="document\lregion = \"us-east-1\"\l"];
+  n1 [label="service \"api\"\lport = 8080\ldescription = \"A \\\"quoted\\\" description that is muc...\l"];
+  n2 [label="upstream \"db\"\l"];
+  n1 -> n2;
+  n0 -> n1;
+  n3 [label="service \"web\"\l"];
+  n0 -> n3;
+}
+`, "\n"), string(ToDot(ast)))
+}
@@ -0,0 +1,150 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Dump writes the tree of nodes in ast to w, for debugging, eg.
+//
+//	AST 1:1
+//	  Entry 1:1
+//	    Attribute 1:1 port
+//	      Value 1:8 number 80
+//	  Entry 2:1
+//	    Block 2:1 server "web"
+//	      Entry 3:3
+//	        Attribute 3:3 hosts
+//	          Value 3:11 list
+//	            Value 3:12 string "a"
+//
+// Each line is a node's kind, position and contents, indented beneath its
+// parent. Comments are listed on the nodes they are attached to.
+func (a *AST) Dump(w io.Writer) error {
+	d := &dumper{w: &bytes.Buffer{}}
+	d.node(0, a)
+	_, err := w.Write(d.w.Bytes())
+	return err
+}
+
+type dumper struct {
+	w *bytes.Buffer
+}
+
+func (d *dumper) line(depth int, kind string, pos lexer.Position, details ...string) {
+	d.w.WriteString(strings.Repeat("  ", depth))
+	d.w.WriteString(kind)
+	if pos.Line != 0 {
+		d.w.WriteString(" " + pos.String())
+	}
+	for _, detail := range details {
+		if detail != "" {
+			d.w.WriteString(" " + detail)
+		}
+	}
+	d.w.WriteString("\n")
+}
+
+func (d *dumper) node(depth int, node Node) {
+	switch node := node.(type) {
+	case *AST:
+		d.line(depth, "AST", node.Pos, dumpComments("trailing_comments", node.TrailingComments))
+		for _, directive := range node.Directives {
+			d.node(depth+1, directive)
+		}
+		for _, entry := range node.Entries {
+			d.node(depth+1, entry)
+		}
+
+	case *Directive:
+		d.line(depth, "Directive", node.Pos, "@"+node.Name, dumpComments("comments", node.Comments))
+		for _, arg := range node.Args {
+			d.node(depth+1, arg)
+		}
+
+	case *Entry:
+		d.line(depth, "Entry", node.Pos)
+		if node.Attribute != nil {
+			d.node(depth+1, node.Attribute)
+		} else if node.Block != nil {
+			d.node(depth+1, node.Block)
+		}
+
+	case *Attribute:
+		d.line(depth, "Attribute", node.Pos, node.Key,
+			dumpComments("comments", node.Comments), dumpComments("trailing_comments", node.TrailingComments))
+		d.node(depth+1, node.Value)
+
+	case *Block:
+		header := node.Name
+		for _, label := range node.Labels {
+			header += " " + strconv.Quote(label)
+		}
+		d.line(depth, "Block", node.Pos, header,
+			dumpComments("comments", node.Comments), dumpComments("trailing_comments", node.TrailingComments))
+		for _, entry := range node.Body {
+			d.node(depth+1, entry)
+		}
+
+	case *MapEntry:
+		d.line(depth, "MapEntry", node.Pos, dumpComments("comments", node.Comments))
+		d.node(depth+1, node.Key)
+		d.node(depth+1, node.Value)
+
+	case *Value:
+		if node == nil {
+			d.line(depth, "Value", lexer.Position{}, "nil")
+			return
+		}
+		d.line(depth, "Value", node.Pos, dumpValue(node))
+		if node.Block != nil {
+			d.node(depth+1, node.Block)
+		}
+		for _, el := range node.List {
+			d.node(depth+1, el)
+		}
+		for _, entry := range node.Map {
+			d.node(depth+1, entry)
+		}
+	}
+}
+
+// dumpValue describes the type and, for scalars, the contents of v.
+func dumpValue(v *Value) string {
+	switch {
+	case v.Bool != nil:
+		return fmt.Sprintf("bool %v", bool(*v.Bool))
+	case v.Number != nil:
+		return "number " + v.String()
+	case v.Type != nil:
+		return "type " + *v.Type
+	case v.Str != nil:
+		return "string " + strconv.Quote(*v.Str)
+	case v.HeredocDelimiter != "":
+		return "heredoc " + strconv.Quote(v.GetHeredoc())
+	case v.Block != nil:
+		return "block"
+	case v.HaveList:
+		return "list"
+	case v.HaveMap:
+		return "map"
+	default:
+		return "invalid"
+	}
+}
+
+func dumpComments(name string, comments []string) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(comments))
+	for i, comment := range comments {
+		quoted[i] = strconv.Quote(comment)
+	}
+	return name + "=[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,44 @@
+package hcl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	ast, err := ParseString(`// The port.
+port = 80
+server "web" {
+  hosts = ["a", 1.5s]
+  env = { debug: true }
+  kind = string
+}
+`)
+	require.NoError(t, err)
+	w := &bytes.Buffer{}
+	err = ast.Dump(w)
+	require.NoError(t, err)
+	require.Equal(t, `AST 1:1
+  Entry 1:1
+    Attribute 1:1 port comments=["The port."]
+      Value 2:8 number 80
+  Entry 3:1
+    Block 3:1 server "web"
+      Entry 4:3
+        Attribute 4:3 hosts
+          Value 4:11 list
+            Value 4:12 string "a"
+            Value 4:17 number 1.5s
+      Entry 5:3
+        Attribute 5:3 env
+          Value 5:9 map
+            MapEntry 5:11
+              Value 5:11 string "debug"
+              Value 5:18 bool true
+      Entry 6:3
+        Attribute 6:3 kind
+          Value 6:10 type string
+`, w.String())
+}
@@ -0,0 +1,103 @@
+package hcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// ExtendedDurations specifies whether time.Duration fields accept days ("d")
+// and weeks ("w") in addition to the units accepted by time.ParseDuration,
+// eg. "2d" or "1w3d12h".
+//
+// Durations are also marshalled in their most compact form, eg. "1h30m"
+// rather than "1h30m0s", using days and weeks where possible.
+func ExtendedDurations(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.extendedDurations = v
+	}
+}
+
+// parseExtendedDuration parses a duration that may include days and weeks.
+func parseExtendedDuration(s string) (time.Duration, error) {
+	rest := s
+	sign := time.Duration(1)
+	if strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+		if rest[0] == '-' {
+			sign = -1
+		}
+		rest = rest[1:]
+	}
+	if rest == "0" {
+		return 0, nil
+	}
+	if rest == "" {
+		return 0, fmt.Errorf("time: invalid duration %q", s)
+	}
+	total := time.Duration(0)
+	for rest != "" {
+		number := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if number <= 0 {
+			return 0, fmt.Errorf("time: invalid duration %q", s)
+		}
+		unit := strings.IndexFunc(rest[number:], func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if unit == -1 {
+			unit = len(rest) - number
+		}
+		component := rest[:number+unit]
+		rest = rest[number+unit:]
+		var scale time.Duration
+		switch component[number:] {
+		case "d":
+			scale = day
+		case "w":
+			scale = week
+		default:
+			d, err := time.ParseDuration(component)
+			if err != nil {
+				return 0, fmt.Errorf("time: invalid duration %q", s)
+			}
+			total += d
+			continue
+		}
+		n, err := strconv.ParseFloat(component[:number], 64)
+		if err != nil {
+			return 0, fmt.Errorf("time: invalid duration %q", s)
+		}
+		total += time.Duration(n * float64(scale))
+	}
+	return sign * total, nil
+}
+
+// formatExtendedDuration formats d in its most compact form, omitting zero
+// components and using days and weeks.
+func formatExtendedDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	w := &strings.Builder{}
+	if d < 0 {
+		w.WriteString("-")
+		d = -d
+	}
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"w", week}, {"d", day}, {"h", time.Hour}, {"m", time.Minute}} {
+		if n := d / unit.size; n > 0 {
+			fmt.Fprintf(w, "%d%s", n, unit.suffix)
+			d -= n * unit.size
+		}
+	}
+	if d > 0 {
+		// Seconds and fractions thereof, eg. "1.5s" or "250ms".
+		w.WriteString(d.String())
+	}
+	return w.String()
+}
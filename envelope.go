@@ -0,0 +1,111 @@
+package hcl
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle"
+)
+
+// Prefix of encrypted string values, followed by the base64 encoded
+// ciphertext produced by a Keyring.
+const envelopePrefix = "enc:v1:"
+
+// A Keyring encrypts and decrypts the values of secret fields.
+//
+// Implementations may wrap a KMS or secrets manager. NewAESKeyring provides
+// one for a local key.
+type Keyring interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// WithKeyring protects credentials at rest with keyring.
+//
+// When unmarshalling, string values of the form "enc:v1:<base64>" are
+// decrypted before decoding, into any field. When marshalling, the values of
+// string fields tagged "secret", eg. hcl:"password,secret", are encrypted
+// into that form. Without a keyring, values are decoded and encoded as is.
+func WithKeyring(keyring Keyring) MarshalOption {
+	return func(options *marshalOptions) {
+		options.keyring = keyring
+	}
+}
+
+// NewAESKeyring creates a Keyring encrypting with AES-GCM and a random nonce,
+// with a 16, 24 or 32 byte key selecting AES-128, AES-192 or AES-256.
+func NewAESKeyring(key []byte) (Keyring, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKeyring{gcm: gcm}, nil
+}
+
+type aesKeyring struct {
+	gcm cipher.AEAD
+}
+
+func (a *aesKeyring) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, a.gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return a.gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (a *aesKeyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < a.gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	nonce, ciphertext := ciphertext[:a.gcm.NonceSize()], ciphertext[a.gcm.NonceSize():]
+	return a.gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// encryptValue encrypts the string value v into an envelope.
+func encryptValue(v *Value, keyring Keyring) (*Value, error) {
+	if v.Str == nil {
+		return v, nil
+	}
+	ciphertext, err := keyring.Encrypt([]byte(*v.Str))
+	if err != nil {
+		return nil, fmt.Errorf("can't encrypt secret: %v", err)
+	}
+	envelope := envelopePrefix + base64.StdEncoding.EncodeToString(ciphertext)
+	return &Value{Str: &envelope}, nil
+}
+
+// decryptValues decrypts the enveloped string values in entries, in place.
+func decryptValues(entries []*Entry, keyring Keyring) error {
+	for _, entry := range entries {
+		err := Visit(entry, func(node Node, next func() error) error {
+			v, ok := node.(*Value)
+			if !ok || v.Str == nil || !strings.HasPrefix(*v.Str, envelopePrefix) {
+				return next()
+			}
+			ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(*v.Str, envelopePrefix))
+			if err != nil {
+				return participle.Errorf(v.Pos, "invalid encrypted value: %v", err)
+			}
+			plaintext, err := keyring.Decrypt(ciphertext)
+			if err != nil {
+				return participle.Errorf(v.Pos, "can't decrypt value: %v", err)
+			}
+			str := string(plaintext)
+			v.Str = &str
+			return next()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package hcl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	keyring, err := NewAESKeyring(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	type database struct {
+		User     string  `hcl:"user"`
+		Password string  `hcl:"password,secret"`
+		Token    *string `hcl:"token,optional,secret"`
+	}
+	token := "t0k3n"
+	db := &database{User: "admin", Password: "hunter2", Token: &token}
+
+	data, err := Marshal(db, WithKeyring(keyring))
+	require.NoError(t, err)
+	require.Contains(t, string(data), `user = "admin"`)
+	require.NotContains(t, string(data), "hunter2")
+	require.NotContains(t, string(data), "t0k3n")
+	require.Equal(t, 2, strings.Count(string(data), `"enc:v1:`))
+
+	actual := &database{}
+	err = Unmarshal(data, actual, WithKeyring(keyring))
+	require.NoError(t, err)
+	require.Equal(t, db, actual)
+
+	// Without a keyring, values are left as is.
+	actual = &database{}
+	err = Unmarshal(data, actual)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(actual.Password, "enc:v1:"))
+
+	data, err = Marshal(db)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `password = "hunter2"`)
+
+	other, err := NewAESKeyring(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	err = Unmarshal([]byte(`user = "enc:v1:!!!"`), &database{}, WithKeyring(other))
+	require.EqualError(t, err, "1:8: invalid encrypted value: illegal base64 data at input byte 0")
+	encrypted, err := Marshal(db, WithKeyring(keyring))
+	require.NoError(t, err)
+	err = Unmarshal(encrypted, &database{}, WithKeyring(other))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "can't decrypt value")
+}
+
+func TestSecretMustBeString(t *testing.T) {
+	type config struct {
+		Port int `hcl:"port,secret"`
+	}
+	err := CheckStruct(&config{})
+	require.EqualError(t, err, `"secret" field github.com/alecthomas/hcl.config.Port must be a string`)
+}
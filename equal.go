@@ -0,0 +1,242 @@
+package hcl
+
+import (
+	"github.com/alecthomas/participle/lexer"
+)
+
+// EqualOption configures ASTEqual.
+type EqualOption func(options *equalOptions)
+
+type equalOptions struct {
+	ignoreComments  bool
+	ignorePositions bool
+}
+
+// IgnoreComments excludes comments from comparisons by ASTEqual.
+func IgnoreComments() EqualOption {
+	return func(options *equalOptions) {
+		options.ignoreComments = true
+	}
+}
+
+// IgnorePositions excludes source positions from comparisons by ASTEqual.
+func IgnorePositions() EqualOption {
+	return func(options *equalOptions) {
+		options.ignorePositions = true
+	}
+}
+
+// ASTEqual returns true if the nodes a and b are structurally equal.
+//
+// Parent references are ignored, and numbers are compared by value rather than
+// by how they are formatted.
+func ASTEqual(a, b Node, options ...EqualOption) bool {
+	opt := &equalOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	e := &equaler{opt}
+	switch a := a.(type) {
+	case *AST:
+		b, ok := b.(*AST)
+		return ok && e.ast(a, b)
+	case *Entry:
+		b, ok := b.(*Entry)
+		return ok && e.entry(a, b)
+	case *Attribute:
+		b, ok := b.(*Attribute)
+		return ok && e.attribute(a, b)
+	case *Block:
+		b, ok := b.(*Block)
+		return ok && e.block(a, b)
+	case *MapEntry:
+		b, ok := b.(*MapEntry)
+		return ok && e.mapEntry(a, b)
+	case *Value:
+		b, ok := b.(*Value)
+		return ok && e.value(a, b)
+	case *Directive:
+		b, ok := b.(*Directive)
+		return ok && e.directive(a, b)
+	default:
+		return a == b
+	}
+}
+
+type equaler struct {
+	*equalOptions
+}
+
+func (e *equaler) ast(a, b *AST) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if len(a.Directives) != len(b.Directives) {
+		return false
+	}
+	for i := range a.Directives {
+		if !e.directive(a.Directives[i], b.Directives[i]) {
+			return false
+		}
+	}
+	return e.pos(a.Pos, b.Pos) &&
+		e.entries(a.Entries, b.Entries) &&
+		e.comments(a.TrailingComments, b.TrailingComments, a.TrailingCommentStyles, b.TrailingCommentStyles) &&
+		a.Schema == b.Schema
+}
+
+func (e *equaler) entries(a, b []*Entry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !e.entry(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *equaler) entry(a, b *Entry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return e.pos(a.Pos, b.Pos) && e.attribute(a.Attribute, b.Attribute) && e.block(a.Block, b.Block)
+}
+
+func (e *equaler) attribute(a, b *Attribute) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return e.pos(a.Pos, b.Pos) &&
+		e.comments(a.Comments, b.Comments, a.CommentStyles, b.CommentStyles) &&
+		a.Key == b.Key &&
+		e.value(a.Value, b.Value) &&
+		e.comments(a.TrailingComments, b.TrailingComments, a.TrailingCommentStyles, b.TrailingCommentStyles) &&
+		e.value(a.Default, b.Default) &&
+		e.values(a.Enum, b.Enum) &&
+		a.Optional == b.Optional &&
+		a.Deprecated == b.Deprecated &&
+		a.ReadOnly == b.ReadOnly &&
+		a.Pattern == b.Pattern &&
+		e.value(a.Min, b.Min) &&
+		e.value(a.Max, b.Max)
+}
+
+func (e *equaler) block(a, b *Block) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return e.pos(a.Pos, b.Pos) &&
+		e.comments(a.Comments, b.Comments, a.CommentStyles, b.CommentStyles) &&
+		a.Name == b.Name &&
+		stringsEqual(a.Labels, b.Labels) &&
+		e.entries(a.Body, b.Body) &&
+		e.comments(a.TrailingComments, b.TrailingComments, a.TrailingCommentStyles, b.TrailingCommentStyles) &&
+		a.Repeated == b.Repeated &&
+		a.MinItems == b.MinItems &&
+		a.MaxItems == b.MaxItems &&
+		a.Recursive == b.Recursive &&
+		a.RecursivePath == b.RecursivePath
+}
+
+func (e *equaler) directive(a, b *Directive) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return e.pos(a.Pos, b.Pos) &&
+		e.comments(a.Comments, b.Comments, a.CommentStyles, b.CommentStyles) &&
+		a.Name == b.Name &&
+		e.values(a.Args, b.Args) &&
+		e.comments(a.TrailingComments, b.TrailingComments, a.TrailingCommentStyles, b.TrailingCommentStyles)
+}
+
+func (e *equaler) mapEntry(a, b *MapEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return e.pos(a.Pos, b.Pos) &&
+		e.comments(a.Comments, b.Comments, a.CommentStyles, b.CommentStyles) &&
+		e.value(a.Key, b.Key) &&
+		e.value(a.Value, b.Value)
+}
+
+func (e *equaler) values(a, b []*Value) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !e.value(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *equaler) value(a, b *Value) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !e.pos(a.Pos, b.Pos) || a.HaveList != b.HaveList || a.HaveMap != b.HaveMap || a.HeredocDelimiter != b.HeredocDelimiter {
+		return false
+	}
+	if (a.Bool == nil) != (b.Bool == nil) || (a.Bool != nil && *a.Bool != *b.Bool) {
+		return false
+	}
+	if (a.Number == nil) != (b.Number == nil) || (a.Number != nil && a.Number.Cmp(b.Number) != 0) || a.Unit != b.Unit {
+		return false
+	}
+	if !stringPtrEqual(a.Type, b.Type) || !stringPtrEqual(a.Str, b.Str) || !stringPtrEqual(a.Heredoc, b.Heredoc) {
+		return false
+	}
+	if (a.Block == nil) != (b.Block == nil) || (a.Block != nil && !e.block(a.Block, b.Block)) {
+		return false
+	}
+	if !e.values(a.List, b.List) || len(a.Map) != len(b.Map) {
+		return false
+	}
+	for i := range a.Map {
+		if !e.mapEntry(a.Map[i], b.Map[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *equaler) pos(a, b lexer.Position) bool {
+	return e.ignorePositions || a == b
+}
+
+func (e *equaler) comments(a, b []string, astyles, bstyles []CommentStyle) bool {
+	if e.ignoreComments {
+		return true
+	}
+	if !stringsEqual(a, b) {
+		return false
+	}
+	for i := range a {
+		if commentStyle(astyles, i) != commentStyle(bstyles, i) {
+			return false
+		}
+	}
+	return true
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stringPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
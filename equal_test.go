@@ -0,0 +1,52 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestASTEqual(t *testing.T) {
+	a, err := ParseString(`
+// A block.
+block "label" {
+  attr = 1.0
+  list = ["a", {b: true}]
+}
+`)
+	require.NoError(t, err)
+	b, err := ParseString(`block "label" { attr = 1 list = ["a", {b: true}] }`)
+	require.NoError(t, err)
+
+	require.True(t, ASTEqual(a, a.Clone()))
+	require.False(t, ASTEqual(a, b))
+	require.False(t, ASTEqual(a, b, IgnorePositions()))
+	require.True(t, ASTEqual(a, b, IgnorePositions(), IgnoreComments()))
+	require.True(t, ASTEqual(a.Entries[0].Block, b.Entries[0].Block, IgnorePositions(), IgnoreComments()))
+
+	*b.Entries[0].Block.Body[1].Attribute.Value.List[1].Map[0].Value.Bool = false
+	require.False(t, ASTEqual(a, b, IgnorePositions(), IgnoreComments()))
+	require.False(t, ASTEqual(a, a.Entries[0]))
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	ast, err := ParseString(`
+block "label" {
+  str = "a"
+  flag = true
+}
+`)
+	require.NoError(t, err)
+	block := ast.Entries[0].Block
+	clone := block.Clone()
+	require.True(t, ASTEqual(block, clone))
+	require.Equal(t, clone, clone.Body[0].Parent)
+
+	*clone.Body[0].Attribute.Value.Str = "b"
+	*clone.Body[1].Attribute.Value.Bool = false
+	clone.Labels[0] = "other"
+	require.Equal(t, "a", *block.Body[0].Attribute.Value.Str)
+	require.True(t, bool(*block.Body[1].Attribute.Value.Bool))
+	require.Equal(t, "label", block.Labels[0])
+	require.False(t, ASTEqual(block, clone))
+}
@@ -0,0 +1,186 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// FieldError is an error for a specific field, identified by its path from
+// the root, eg. "server[0].tls.ciphers[1]".
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (f *FieldError) Error() string { return f.Path + ": " + f.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (f *FieldError) Unwrap() error { return f.Err }
+
+// positionedFieldError is a FieldError wrapping a participle.Error, which
+// retains the position of the underlying error.
+type positionedFieldError struct {
+	*FieldError
+	perr participle.Error
+}
+
+func (p *positionedFieldError) Error() string {
+	return lexer.FormatError(p.perr.Token().Pos, p.Message())
+}
+
+func (p *positionedFieldError) Message() string    { return p.Path + ": " + p.perr.Message() }
+func (p *positionedFieldError) Token() lexer.Token { return p.perr.Token() }
+func (p *positionedFieldError) Unwrap() error      { return p.FieldError }
+
+// withFieldPath prefixes the path of err with segment, which is either a field
+// name or an index of the form "[n]".
+func withFieldPath(segment string, err error) error {
+	if err == nil {
+		return nil
+	}
+	ferr := splitFieldError(err)
+	if ferr == nil {
+		ferr = &FieldError{Path: segment, Err: err}
+	} else {
+		sep := "."
+		if strings.HasPrefix(ferr.Path, "[") {
+			sep = ""
+		}
+		ferr = &FieldError{Path: segment + sep + ferr.Path, Err: ferr.Err}
+	}
+	return newFieldError(ferr)
+}
+
+// annotateFieldError is participle.AnnotateError, but preserves any field path.
+func annotateFieldError(pos lexer.Position, err error) error {
+	ferr := splitFieldError(err)
+	if ferr == nil {
+		return participle.AnnotateError(pos, err)
+	}
+	return newFieldError(&FieldError{Path: ferr.Path, Err: participle.AnnotateError(pos, ferr.Err)})
+}
+
+func splitFieldError(err error) *FieldError {
+	switch err := err.(type) {
+	case *FieldError:
+		return err
+	case *positionedFieldError:
+		return err.FieldError
+	}
+	return nil
+}
+
+func newFieldError(ferr *FieldError) error {
+	if perr, ok := ferr.Err.(participle.Error); ok {
+		return &positionedFieldError{FieldError: ferr, perr: perr}
+	}
+	return ferr
+}
+
+// unsupportedTypeError is returned when marshalling a type that can't be represented in HCL.
+type unsupportedTypeError struct {
+	t reflect.Type
+}
+
+func (u unsupportedTypeError) Error() string { return "unsupported type " + u.t.String() }
+
+// DefaultSnippetWidth is the maximum width of source snippets rendered by FormatError
+// when no width is given.
+const DefaultSnippetWidth = 120
+
+// FormatError renders an error along with a snippet of the source at which it occurred.
+//
+// Only a window of at most maxWidth bytes around the error is included, so
+// errors in machine-generated files with very long lines remain readable.
+// If maxWidth is <= 0, DefaultSnippetWidth is used.
+//
+// Errors without position information are returned as-is.
+func FormatError(err error, source []byte, maxWidth int) string {
+	perr, ok := err.(participle.Error)
+	if !ok {
+		return err.Error()
+	}
+	if maxWidth <= 0 {
+		maxWidth = DefaultSnippetWidth
+	}
+	pos := perr.Token().Pos
+	offset := resolveOffset(pos, source)
+	w := &strings.Builder{}
+	if pos.Filename != "" {
+		fmt.Fprintf(w, "%s:", pos.Filename)
+	}
+	fmt.Fprintf(w, "%d:%d (offset %d): %s\n", pos.Line, pos.Column, offset, perr.Message())
+	snippet, caret := snippetWindow(source, offset, maxWidth)
+	fmt.Fprintf(w, "  %s\n", snippet)
+	fmt.Fprintf(w, "  %s^\n", strings.Repeat(" ", caret))
+	return w.String()
+}
+
+// resolveOffset returns the byte offset of pos in source.
+//
+// Positions constructed by hand often lack an offset, in which case it is
+// computed from the line and column.
+func resolveOffset(pos lexer.Position, source []byte) int {
+	if pos.Offset > 0 || (pos.Line <= 1 && pos.Column <= 1) {
+		if pos.Offset > len(source) {
+			return len(source)
+		}
+		return pos.Offset
+	}
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		nl := bytes.IndexByte(source[offset:], '\n')
+		if nl == -1 {
+			return len(source)
+		}
+		offset += nl + 1
+	}
+	for col := 1; col < pos.Column && offset < len(source) && source[offset] != '\n'; col++ {
+		_, size := utf8.DecodeRune(source[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// snippetWindow extracts at most maxWidth bytes of the line containing offset,
+// returning the snippet and the rune column of offset within it.
+//
+// The source is never scanned further than maxWidth bytes from offset.
+func snippetWindow(source []byte, offset, maxWidth int) (string, int) {
+	half := maxWidth / 2
+	start := offset
+	for start > 0 && offset-start < half && source[start-1] != '\n' {
+		start--
+	}
+	end := offset
+	for end < len(source) && end-start < maxWidth && source[end] != '\n' {
+		end++
+	}
+	// Don't split multi-byte runes.
+	for start < offset && !utf8.RuneStart(source[start]) {
+		start++
+	}
+	for end > offset && end < len(source) && !utf8.RuneStart(source[end]) {
+		end--
+	}
+	prefix, suffix := "", ""
+	if start > 0 && source[start-1] != '\n' {
+		prefix = "..."
+	}
+	if end < len(source) && source[end] != '\n' {
+		suffix = "..."
+	}
+	line := strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, string(source[start:end]))
+	return prefix + line + suffix, len(prefix) + utf8.RuneCount(source[start:offset])
+}
@@ -0,0 +1,34 @@
+package hcl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatError(t *testing.T) {
+	source := []byte("a = 1\nb = \"str\"\n")
+	err := participle.Errorf(lexer.Position{Filename: "test.hcl", Line: 2, Column: 5}, "invalid value")
+	require.Equal(t, `test.hcl:2:5 (offset 10): invalid value
+  b = "str"
+      ^
+`, FormatError(err, source, 0))
+}
+
+func TestFormatErrorLongLine(t *testing.T) {
+	source := []byte("a = [" + strings.Repeat("1, ", 10000) + "x]\n")
+	offset := len(source) - 3
+	err := participle.Errorf(lexer.Position{Line: 1, Column: offset + 1, Offset: offset}, "unexpected token")
+	require.Equal(t, `1:30006 (offset 30005): unexpected token
+  ... 1, 1, 1, x]
+               ^
+`, FormatError(err, source, 20))
+}
+
+func TestFormatErrorWithoutPosition(t *testing.T) {
+	require.Equal(t, "plain", FormatError(errors.New("plain"), nil, 0))
+}
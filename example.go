@@ -0,0 +1,218 @@
+package hcl
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Example returns an example configuration for v, a struct or a pointer to a
+// struct, suitable as a starter configuration file.
+//
+// Each field's help:"" text is included as comments. Required attributes are
+// set to their value in v, or their zero value if it is unset. Optional
+// attributes are commented out, set to their value in v or their default.
+// Blocks in v are included as is, and an example of each optional block that
+// is not present is commented out.
+func Example(v interface{}, options ...MarshalOption) ([]byte, error) {
+	opt := newMarshalOptions(options...)
+	ast, err := exampleToAST(v, opt)
+	if err != nil {
+		return nil, err
+	}
+	return NewPrinter(opt.printerOptions...).Print(ast)
+}
+
+func exampleToAST(v interface{}, opt *marshalOptions) (*AST, error) {
+	rv, err := structPointer(reflect.ValueOf(v), v)
+	if err != nil {
+		return nil, err
+	}
+	opt.omitHelpComments = false
+	entries, trailing, _, err := exampleEntries(rv.Elem(), false, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &AST{Entries: entries, TrailingComments: trailing}, nil
+}
+
+// exampleEntries returns the example entries for the struct v, followed by
+// any commented out entries that trail them, and its labels.
+//
+// Within a block that is itself commented out, nested optional entries are
+// not commented out again.
+func exampleEntries(v reflect.Value, commented bool, opt *marshalOptions) (entries []*Entry, pending []string, labels []string, err error) {
+	fields, err := flattenFields(v, opt)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Adds an entry, or comments it out, with the help text of tag preceding it.
+	add := func(entry *Entry, tag tag, comment bool) error {
+		if !comment {
+			if entry.Attribute != nil {
+				entry.Attribute.Comments = append(pending, entry.Attribute.Comments...) // nolint: gocritic
+			} else {
+				entry.Block.Comments = append(pending, entry.Block.Comments...) // nolint: gocritic
+			}
+			pending = nil
+			entries = append(entries, entry)
+			return nil
+		}
+		help := tag.comments()
+		if entry.Attribute != nil {
+			entry.Attribute.Comments = nil
+		} else {
+			entry.Block.Comments = nil
+		}
+		data, err := MarshalAST(&AST{Entries: []*Entry{entry}})
+		if err != nil {
+			return err
+		}
+		pending = append(pending, help...)
+		pending = append(pending, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+		return nil
+	}
+	for _, field := range fields {
+		tag := parseTag(v.Type(), field, opt)
+		switch {
+		case tag.name == "" || tag.remain:
+			continue
+
+		case tag.label:
+			label := tag.name
+			if field.v.Kind() == reflect.String && field.v.String() != "" {
+				label = field.v.String()
+			}
+			labels = append(labels, label)
+
+		case tag.block && tag.union == nil:
+			fv := field.v
+			present := !fv.IsZero() || fv.Kind() == reflect.Struct
+			if present {
+				blocks, err := exampleBlocks(fv, tag, opt)
+				if err != nil {
+					return nil, nil, nil, withFieldPath(tag.name, err)
+				}
+				for _, block := range blocks {
+					if err := add(&Entry{Block: block}, tag, false); err != nil {
+						return nil, nil, nil, err
+					}
+				}
+				continue
+			}
+			block, err := exampleBlock(fv.Type(), tag, opt)
+			if err != nil {
+				return nil, nil, nil, withFieldPath(tag.name, err)
+			}
+			if err := add(&Entry{Block: block}, tag, !commented); err != nil {
+				return nil, nil, nil, err
+			}
+
+		case tag.block:
+			continue
+
+		default:
+			attr, err := exampleAttr(field, tag, opt)
+			if errors.As(err, &unsupportedTypeError{}) {
+				continue
+			} else if err != nil {
+				return nil, nil, nil, withFieldPath(tag.name, err)
+			}
+			if err := add(&Entry{Attribute: attr}, tag, tag.optional && !commented); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+	}
+	return entries, pending, labels, nil
+}
+
+// exampleBlocks returns the blocks present in fv, a block field.
+func exampleBlocks(fv reflect.Value, tag tag, opt *marshalOptions) ([]*Block, error) {
+	switch fv.Kind() {
+	case reflect.Map:
+		return mapToBlocks(fv, tag, false, opt)
+	case reflect.Slice:
+		return sliceToBlocks(fv, tag, opt)
+	default:
+		block, err := exampleBlock(fv.Type(), tag, opt)
+		if err != nil {
+			return nil, err
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+		// Use the block's values in place of the zero value.
+		block.Body, block.TrailingComments, block.Labels, err = exampleEntries(fv, false, opt)
+		return []*Block{block}, err
+	}
+}
+
+// exampleBlock returns an example block for a block field of type t, with
+// placeholder labels.
+func exampleBlock(t reflect.Type, tag tag, opt *marshalOptions) (*Block, error) {
+	keyed := false
+	switch t.Kind() {
+	case reflect.Map:
+		t, _ = blockMapElem(t)
+		keyed = true
+	case reflect.Slice:
+		t = t.Elem()
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	leave, err := opt.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	block := &Block{Name: tag.name, Comments: tag.comments()}
+	block.Body, block.TrailingComments, block.Labels, err = exampleEntries(reflect.New(t).Elem(), true, opt)
+	if keyed {
+		block.Labels = append([]string{"key"}, block.Labels...)
+	}
+	return block, err
+}
+
+// exampleAttr returns the attribute for a field, set to its value if it is
+// set, or otherwise to its default or zero value.
+func exampleAttr(f field, tag tag, opt *marshalOptions) (*Attribute, error) {
+	if f.v.IsZero() && tag.defaultValue != "" {
+		value, err := defaultValueFromTag(f, tag.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		return &Attribute{Key: tag.name, Comments: tag.comments(), Value: value}, nil
+	}
+	if tag.inline {
+		var block *Block
+		var err error
+		if f.v.IsZero() {
+			block, err = exampleBlock(f.v.Type(), tag, opt)
+		} else {
+			block, err = valueToBlock(f.v, tag, false, opt)
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := blockToObject(block)
+		if err != nil {
+			return nil, err
+		}
+		return &Attribute{Key: tag.name, Comments: tag.comments(), Value: value}, nil
+	}
+	v := f.v
+	for v.Kind() == reflect.Ptr && v.IsNil() {
+		v = reflect.New(v.Type().Elem()).Elem()
+	}
+	if v.Kind() == reflect.Interface && v.IsNil() {
+		return nil, unsupportedTypeError{v.Type()}
+	}
+	f.v = v
+	attr, err := fieldToAttr(f, tag, false, opt)
+	if err != nil {
+		return nil, err
+	}
+	attr.Default, attr.Enum = nil, nil
+	return attr, nil
+}
@@ -0,0 +1,66 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExample(t *testing.T) {
+	type upstream struct {
+		Name string `hcl:"name,label"`
+		Host string `hcl:"host" help:"Host to proxy to."`
+		Port int    `hcl:"port,optional" default:"80"`
+	}
+	type tls struct {
+		Cert string `hcl:"cert"`
+	}
+	type config struct {
+		Listen    string     `hcl:"listen" help:"Address to listen on."`
+		Debug     bool       `hcl:"debug,optional" help:"Enable debug logging."`
+		Timeout   string     `hcl:"timeout" default:"10s"`
+		TLS       *tls       `hcl:"tls,block" help:"Serve HTTPS."`
+		Upstreams []upstream `hcl:"upstream,block"`
+	}
+	data, err := Example(&config{Listen: ":8080"})
+	require.NoError(t, err)
+	require.Equal(t, `// Address to listen on.
+listen = ":8080"
+// Enable debug logging.
+// debug = false
+// timeout = "10s"
+// Serve HTTPS.
+// tls {
+//   cert = ""
+// }
+// upstream "name" {
+//   // Host to proxy to.
+//   host = ""
+//   port = 80
+// }
+`, string(data))
+
+	data, err = Example(config{Upstreams: []upstream{{Name: "api", Host: "api.internal", Port: 8080}}})
+	require.NoError(t, err)
+	require.Equal(t, `// Address to listen on.
+listen = ""
+
+// Enable debug logging.
+// debug = false
+// timeout = "10s"
+// Serve HTTPS.
+// tls {
+//   cert = ""
+// }
+upstream "api" {
+  // Host to proxy to.
+  host = "api.internal"
+  port = 8080
+}
+`, string(data))
+
+	// The example is valid configuration.
+	out := &config{}
+	require.NoError(t, Unmarshal(data, out))
+	require.Equal(t, "10s", out.Timeout)
+}
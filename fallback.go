@@ -0,0 +1,109 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A fallback is an omitted attribute whose value is taken from another field
+// via a default_from:"" tag, once decoding is complete.
+type fallback struct {
+	target reflect.Value
+	name   string
+	path   string
+}
+
+type fallbackKey struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+func keyOf(v reflect.Value) fallbackKey {
+	return fallbackKey{v.Addr().Pointer(), v.Type()}
+}
+
+// resolveFallbacks applies all fallbacks collected while unmarshalling into root.
+//
+// The source of a fallback may itself be a fallback, in which case it is
+// resolved first. Cycles are reported as errors.
+func resolveFallbacks(root reflect.Value, opt *marshalOptions) error {
+	fallbacks := opt.fallbacks
+	opt.fallbacks = nil
+	pending := map[fallbackKey]*fallback{}
+	for _, f := range fallbacks {
+		pending[keyOf(f.target)] = f
+	}
+	resolving := map[fallbackKey]bool{}
+	var resolve func(f *fallback) error
+	resolve = func(f *fallback) error {
+		key := keyOf(f.target)
+		if _, ok := pending[key]; !ok {
+			return nil
+		}
+		if resolving[key] {
+			return fmt.Errorf("default_from for %q: cycle detected at %q", f.name, f.path)
+		}
+		resolving[key] = true
+		source, err := lookupPath(root, f.path, opt)
+		if err != nil {
+			return fmt.Errorf("default_from for %q: %s", f.name, err)
+		}
+		if !source.IsValid() {
+			delete(pending, key)
+			return nil
+		}
+		if next, ok := pending[keyOf(source)]; ok {
+			if err := resolve(next); err != nil {
+				return err
+			}
+		}
+		if !source.Type().AssignableTo(f.target.Type()) {
+			return fmt.Errorf("default_from for %q: %q is of type %s, not %s", f.name, f.path, source.Type(), f.target.Type())
+		}
+		// A zero source leaves any default:"" value in place.
+		if !source.IsZero() {
+			f.target.Set(source)
+		}
+		delete(pending, key)
+		return nil
+	}
+	for _, f := range fallbacks {
+		if err := resolve(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupPath finds the field at the dotted HCL path below root.
+//
+// An invalid reflect.Value is returned if the path traverses an unset block.
+func lookupPath(root reflect.Value, path string, opt *marshalOptions) (reflect.Value, error) {
+	v := root
+next:
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("can't resolve %q in %q, %s is not a block", name, path, v.Type())
+		}
+		fields, err := flattenFields(v, opt)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		for _, field := range fields {
+			tag := parseTag(v.Type(), field, opt)
+			if tag.name == name && !tag.label && !tag.remain {
+				v = field.v
+				continue next
+			}
+		}
+		return reflect.Value{}, fmt.Errorf("unknown field %q in %q", name, path)
+	}
+	return v, nil
+}
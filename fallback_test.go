@@ -0,0 +1,89 @@
+package hcl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDefaultFrom(t *testing.T) {
+	type global struct {
+		Timeout time.Duration `hcl:"timeout,optional"`
+	}
+	type service struct {
+		Name    string        `hcl:"name,label"`
+		Timeout time.Duration `hcl:"timeout" default_from:"global.timeout"`
+	}
+	tests := []test{
+		{name: "FromOtherBlock",
+			hcl: `
+				global {
+					timeout = "5s"
+				}
+				service "a" {}
+				service "b" {
+					timeout = "1s"
+				}
+			`,
+			dest: struct {
+				Global   global    `hcl:"global,block"`
+				Services []service `hcl:"service,block"`
+			}{
+				Global: global{Timeout: time.Second * 5},
+				Services: []service{
+					{Name: "a", Timeout: time.Second * 5},
+					{Name: "b", Timeout: time.Second},
+				},
+			},
+		},
+		{name: "Chained",
+			hcl: `
+				a = "value"
+			`,
+			dest: struct {
+				A string `hcl:"a"`
+				B string `hcl:"b" default_from:"a"`
+				C string `hcl:"c" default_from:"b"`
+			}{A: "value", B: "value", C: "value"},
+		},
+		{name: "ZeroSourceKeepsDefault",
+			hcl: ``,
+			dest: struct {
+				A string `hcl:"a,optional"`
+				B string `hcl:"b" default_from:"a" default:"default"`
+			}{B: "default"},
+		},
+		{name: "UnsetBlock",
+			hcl: ``,
+			dest: struct {
+				Global *global       `hcl:"global,block"`
+				B      time.Duration `hcl:"b" default_from:"global.timeout"`
+			}{},
+		},
+		{name: "Cycle",
+			hcl: ``,
+			dest: struct {
+				A string `hcl:"a" default_from:"b"`
+				B string `hcl:"b" default_from:"a"`
+			}{},
+			fail: `default_from for "a": cycle detected at "b"`,
+		},
+		{name: "UnknownField",
+			hcl: ``,
+			dest: struct {
+				A string `hcl:"a" default_from:"global.missing"`
+			}{},
+			fail: `default_from for "a": unknown field "global" in "global.missing"`,
+		},
+		{name: "TypeMismatch",
+			hcl: `
+				a = 1
+			`,
+			dest: struct {
+				A int    `hcl:"a"`
+				B string `hcl:"b" default_from:"a"`
+			}{},
+			fail: `default_from for "b": "a" is of type int, not string`,
+		},
+	}
+	runTests(t, tests)
+}
@@ -0,0 +1,261 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Flatten returns the scalar values in ast keyed by dotted paths, eg.
+// "server.0.port" = "8080", for interoperating with flag libraries,
+// environment variables and key/value stores.
+//
+// The path of an attribute is its key, and of a block is its name followed by
+// its labels and its index among the blocks in the same body with the same
+// name and labels. Elements of lists are identified by their index, and
+// entries of maps by their key. Strings and heredocs are flattened to their
+// contents, numbers to their canonical form, and types to their name. Empty
+// lists and maps are omitted.
+func Flatten(ast *AST) map[string]string {
+	out := map[string]string{}
+	for path, value := range FlattenTyped(ast) {
+		if s, ok := flatString(value); ok {
+			out[path] = s
+		}
+	}
+	return out
+}
+
+// FlattenTyped is like Flatten, but retains the values at each path,
+// including empty lists and maps.
+func FlattenTyped(ast *AST) map[string]*Value {
+	out := map[string]*Value{}
+	flattenEntries(out, "", ast.Entries)
+	return out
+}
+
+func flattenEntries(out map[string]*Value, prefix string, entries []*Entry) {
+	indexes := map[string]int{}
+	for _, entry := range entries {
+		switch {
+		case entry.Attribute != nil:
+			flattenValue(out, prefix+entry.Attribute.Key, entry.Attribute.Value)
+
+		case entry.Block != nil:
+			block := entry.Block
+			path := prefix + strings.Join(append([]string{block.Name}, block.Labels...), ".")
+			index := indexes[path]
+			indexes[path]++
+			flattenEntries(out, path+"."+strconv.Itoa(index)+".", block.Body)
+		}
+	}
+}
+
+func flattenValue(out map[string]*Value, path string, value *Value) {
+	switch {
+	case value.HaveList && len(value.List) > 0:
+		for i, element := range value.List {
+			flattenValue(out, path+"."+strconv.Itoa(i), element)
+		}
+
+	case value.HaveMap && len(value.Map) > 0:
+		for _, entry := range value.Map {
+			key := entry.Key.String()
+			if entry.Key.Str != nil {
+				key = *entry.Key.Str
+			}
+			flattenValue(out, path+"."+key, entry.Value)
+		}
+
+	default:
+		out[path] = value
+	}
+}
+
+// flatString returns the string form of a scalar value.
+func flatString(value *Value) (string, bool) {
+	switch {
+	case value.Bool != nil:
+		return strconv.FormatBool(bool(*value.Bool)), true
+	case value.Number != nil:
+		return formatNumber(value.Number, 10) + value.Unit, true
+	case value.Type != nil:
+		return *value.Type, true
+	case value.Str != nil:
+		return *value.Str, true
+	case value.HeredocDelimiter != "":
+		return value.GetHeredoc(), true
+	}
+	return "", false
+}
+
+// Unflatten builds an AST from values keyed by dotted paths, the inverse of
+// Flatten.
+//
+// Values that are valid HCL numbers or booleans are converted to them, and
+// all other values are strings.
+//
+// As paths don't record whether a segment is a label, a numeric segment
+// other than the first or last is taken to be the index of a block, the
+// segment before the first such segment is the block's name, and any
+// segments between them are its labels. Other numeric segments are list
+// indexes, and non-numeric segments of values are map keys. So blocks with
+// numeric labels, and lists of maps, don't survive flattening.
+func Unflatten(values map[string]string) (*AST, error) {
+	typed := make(map[string]*Value, len(values))
+	for path, s := range values {
+		s := s
+		value, err := ParseValue(s)
+		if err != nil || (value.Bool == nil && value.Number == nil) {
+			value = &Value{Str: &s}
+		}
+		value.Pos = lexer.Position{}
+		typed[path] = value
+	}
+	return UnflattenTyped(typed)
+}
+
+// UnflattenTyped is like Unflatten, but for the values returned by FlattenTyped.
+func UnflattenTyped(values map[string]*Value) (*AST, error) {
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return pathLess(paths[i], paths[j]) })
+	root := &flatBody{}
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		if err := root.insert(segments, values[path].Clone()); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	ast := &AST{Entries: root.entries}
+	return ast, AddParentRefs(ast)
+}
+
+// pathLess orders paths segment by segment, with numeric segments in numeric order.
+func pathLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		if aerr == nil && berr == nil {
+			return an < bn
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
+
+// flatBody is a body being built by UnflattenTyped.
+type flatBody struct {
+	entries []*Entry
+	// Blocks by name, labels and index, and attributes by key.
+	blocks map[string]*flatBlock
+	attrs  map[string]*Attribute
+}
+
+type flatBlock struct {
+	block *Block
+	body  *flatBody
+}
+
+func (b *flatBody) insert(segments []string, value *Value) error {
+	// A numeric segment other than the first or last is a block index.
+	for i := 1; i < len(segments)-1; i++ {
+		if _, err := strconv.Atoi(segments[i]); err != nil {
+			continue
+		}
+		key := strings.Join(segments[:i+1], ".")
+		block, ok := b.blocks[key]
+		if !ok {
+			if _, ok := b.attrs[segments[0]]; ok {
+				return fmt.Errorf("%q is both an attribute and a block", segments[0])
+			}
+			block = &flatBlock{
+				block: &Block{Name: segments[0], Labels: segments[1:i]},
+				body:  &flatBody{},
+			}
+			if b.blocks == nil {
+				b.blocks = map[string]*flatBlock{}
+			}
+			b.blocks[key] = block
+			b.entries = append(b.entries, &Entry{Block: block.block})
+		}
+		if err := block.body.insert(segments[i+1:], value); err != nil {
+			return err
+		}
+		block.block.Body = block.body.entries
+		return nil
+	}
+	attr, ok := b.attrs[segments[0]]
+	if !ok {
+		for key := range b.blocks {
+			if strings.HasPrefix(key, segments[0]+".") {
+				return fmt.Errorf("%q is both an attribute and a block", segments[0])
+			}
+		}
+		attr = &Attribute{Key: segments[0]}
+		if b.attrs == nil {
+			b.attrs = map[string]*Attribute{}
+		}
+		b.attrs[segments[0]] = attr
+		b.entries = append(b.entries, &Entry{Attribute: attr})
+	}
+	var err error
+	attr.Value, err = insertFlatValue(attr.Value, segments[1:], value)
+	return err
+}
+
+// insertFlatValue inserts value at the path segments within existing, a list
+// or map, returning the result.
+func insertFlatValue(existing *Value, segments []string, value *Value) (*Value, error) {
+	if len(segments) == 0 {
+		if existing != nil {
+			return nil, fmt.Errorf("conflicting values")
+		}
+		return value, nil
+	}
+	if index, err := strconv.Atoi(segments[0]); err == nil {
+		if existing == nil {
+			existing = &Value{HaveList: true}
+		}
+		if !existing.HaveList {
+			return nil, fmt.Errorf("%q is not a list index", segments[0])
+		}
+		switch {
+		case index < len(existing.List):
+			existing.List[index], err = insertFlatValue(existing.List[index], segments[1:], value)
+		case index == len(existing.List):
+			var element *Value
+			element, err = insertFlatValue(nil, segments[1:], value)
+			existing.List = append(existing.List, element)
+		default:
+			return nil, fmt.Errorf("list index %d is not contiguous", index)
+		}
+		return existing, err
+	}
+	if existing == nil {
+		existing = &Value{HaveMap: true}
+	}
+	if !existing.HaveMap {
+		return nil, fmt.Errorf("%q is not a map key", segments[0])
+	}
+	for _, entry := range existing.Map {
+		if *entry.Key.Str == segments[0] {
+			var err error
+			entry.Value, err = insertFlatValue(entry.Value, segments[1:], value)
+			return existing, err
+		}
+	}
+	element, err := insertFlatValue(nil, segments[1:], value)
+	key := segments[0]
+	existing.Map = append(existing.Map, &MapEntry{Key: &Value{Str: &key}, Value: element})
+	return existing, err
+}
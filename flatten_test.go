@@ -0,0 +1,126 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	ast, err := ParseString(`
+name = "app"
+debug = true
+tags = ["a", "b"]
+env = {"HOME": "/root", PATH: "/bin"}
+empty = []
+server {
+  port = 8080
+}
+server {
+  port = 0x50
+}
+service "api" {
+  port = 9000
+}
+`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"name":               "app",
+		"debug":              "true",
+		"tags.0":             "a",
+		"tags.1":             "b",
+		"env.HOME":           "/root",
+		"env.PATH":           "/bin",
+		"server.0.port":      "8080",
+		"server.1.port":      "80",
+		"service.api.0.port": "9000",
+	}, Flatten(ast))
+
+	typed := FlattenTyped(ast)
+	require.True(t, typed["empty"].HaveList)
+	require.Empty(t, typed["empty"].List)
+}
+
+func TestUnflatten(t *testing.T) {
+	ast, err := Unflatten(map[string]string{
+		"name":               "app",
+		"debug":              "true",
+		"tags.1":             "b",
+		"tags.0":             "a",
+		"env.HOME":           "/root",
+		"server.0.port":      "8080",
+		"server.0.host":      "localhost",
+		"server.1.port":      "80",
+		"server.10.port":     "10",
+		"service.api.0.port": "9000",
+		"ratio":              "1.5",
+		"version":            "1.2.3",
+	})
+	require.NoError(t, err)
+	expected, err := ParseString(`
+debug = true
+env = {
+  "HOME": "/root",
+}
+name = "app"
+ratio = 1.5
+server {
+  host = "localhost"
+  port = 8080
+}
+server {
+  port = 80
+}
+server {
+  port = 10
+}
+service "api" {
+  port = 9000
+}
+tags = ["a", "b"]
+version = "1.2.3"
+`)
+	require.NoError(t, err)
+	require.True(t, ASTEqual(expected, ast, IgnorePositions()))
+}
+
+func TestFlattenRoundTrip(t *testing.T) {
+	ast, err := ParseString(`
+a = [1, 2]
+b = {
+  "c": "d",
+}
+block "label" {
+  nested {
+    f = true
+  }
+}
+e = []
+`)
+	require.NoError(t, err)
+	actual, err := UnflattenTyped(FlattenTyped(ast))
+	require.NoError(t, err)
+	require.True(t, ASTEqual(ast, actual, IgnorePositions()))
+	actual, err = Unflatten(Flatten(ast))
+	require.NoError(t, err)
+	require.Equal(t, Flatten(ast), Flatten(actual))
+}
+
+func TestUnflattenErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		err    string
+	}{
+		{"AttributeAndBlock", map[string]string{"a": "1", "a.0.b": "2"}, `a.0.b: "a" is both an attribute and a block`},
+		{"ScalarAndList", map[string]string{"a": "1", "a.0": "2"}, `a.0: "0" is not a list index`},
+		{"ListAndMap", map[string]string{"a.0": "1", "a.b": "2"}, `a.b: "b" is not a map key`},
+		{"NotContiguous", map[string]string{"a.0": "1", "a.2": "2"}, `a.2: list index 2 is not contiguous`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Unflatten(test.values)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package hcl
+
+import (
+	"bytes"
+)
+
+// Edit replaces Length bytes at Offset in a source with Replacement.
+type Edit struct {
+	Offset      int    `json:"offset"`
+	Length      int    `json:"length"`
+	Replacement string `json:"replacement"`
+}
+
+// FormatDiff returns the edits formatting src in canonical form, as printed by
+// MarshalAST, rather than the whole formatted document, so that editors and
+// bots can apply formatting as targeted patches.
+//
+// Edits replace whole lines, are ordered by offset and don't overlap. Lines
+// already formatted are left untouched, so a formatted document has no edits.
+func FormatDiff(src []byte) ([]Edit, error) {
+	ast, err := ParseBytes(src)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := MarshalAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	return diffLines(splitLines(src), splitLines(formatted)), nil
+}
+
+// splitLines splits data into lines, each including its line ending.
+func splitLines(data []byte) []string {
+	lines := []string{}
+	for len(data) > 0 {
+		eol := bytes.IndexByte(data, '\n') + 1
+		if eol == 0 {
+			eol = len(data)
+		}
+		lines = append(lines, string(data[:eol]))
+		data = data[eol:]
+	}
+	return lines
+}
+
+// diffLines returns the edits transforming lines a into lines b, with the
+// shortest edit script found by Myers' algorithm.
+func diffLines(a, b []string) []Edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] is v[-d:d+1] at the start of step d, for backtracking.
+	trace := [][]int{}
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+	// Backtrack, marking the lines of a that are deleted, and the lines of b
+	// inserted before each line of a.
+	deleted := make([]bool, n+1)
+	inserted := make([][]string, n+1)
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = v[d+prevK]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+		}
+		if d > 0 {
+			if x == prevX {
+				inserted[x] = append([]string{b[prevY]}, inserted[x]...)
+			} else {
+				deleted[prevX] = true
+			}
+		}
+		x, y = prevX, prevY
+	}
+	// Merge runs of deleted and inserted lines into edits.
+	edits := []Edit{}
+	var edit *Edit
+	pos := 0
+	for i := 0; i <= n; i++ {
+		changed := len(inserted[i]) > 0 || deleted[i]
+		if changed && edit == nil {
+			edit = &Edit{Offset: pos}
+		}
+		if edit != nil {
+			for _, line := range inserted[i] {
+				edit.Replacement += line
+			}
+			if deleted[i] {
+				edit.Length += len(a[i])
+			}
+		}
+		if !deleted[i] && i < n && edit != nil {
+			edits = append(edits, *edit)
+			edit = nil
+		}
+		if i < n {
+			pos += len(a[i])
+		}
+	}
+	if edit != nil {
+		edits = append(edits, *edit)
+	}
+	return edits
+}
@@ -0,0 +1,65 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// applyEdits applies ordered, non-overlapping edits to src.
+func applyEdits(src []byte, edits []Edit) string {
+	out := ""
+	pos := 0
+	for _, edit := range edits {
+		out += string(src[pos:edit.Offset]) + edit.Replacement
+		pos = edit.Offset + edit.Length
+	}
+	return out + string(src[pos:])
+}
+
+func TestFormatDiff(t *testing.T) {
+	src := []byte(`a = 1
+b    = 2
+
+server {
+    port = 80
+  host = "x"
+}
+c = 3
+`)
+	edits, err := FormatDiff(src)
+	require.NoError(t, err)
+	require.Equal(t, []Edit{
+		{Offset: 6, Length: 9, Replacement: "b = 2\n"},
+		{Offset: 25, Length: 14, Replacement: "  port = 80\n"},
+	}, edits)
+	ast, err := ParseBytes(src)
+	require.NoError(t, err)
+	formatted, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, string(formatted), applyEdits(src, edits))
+
+	edits, err = FormatDiff(formatted)
+	require.NoError(t, err)
+	require.Empty(t, edits)
+
+	for _, src := range []string{
+		"",
+		"a = 1",
+		"a=1\nb=2\nc=3\n",
+		"\n\n\na = 1\n\n\n\nb = 2\n\n",
+		"x { y { z = [1,\n2,\n3] } }\n",
+		"a = 1\r\nb   = 2\r\n",
+	} {
+		edits, err := FormatDiff([]byte(src))
+		require.NoError(t, err)
+		ast, err := ParseString(src)
+		require.NoError(t, err)
+		formatted, err := MarshalAST(ast)
+		require.NoError(t, err)
+		require.Equal(t, string(formatted), applyEdits([]byte(src), edits), "%q", src)
+	}
+
+	_, err = FormatDiff([]byte(`a = `))
+	require.Error(t, err)
+}
@@ -0,0 +1,27 @@
+//go:build gofuzz
+// +build gofuzz
+
+package hcl
+
+// Fuzz is the entry point for fuzzing the parser with go-fuzz. A seed corpus
+// can be generated with WriteFuzzCorpus.
+//
+// Documents that parse must survive a round trip through MarshalAST.
+func Fuzz(data []byte) int {
+	ast, err := ParseBytes(data)
+	if err != nil {
+		return 0
+	}
+	out, err := MarshalAST(ast)
+	if err != nil {
+		panic(err)
+	}
+	reparsed, err := ParseBytes(out)
+	if err != nil {
+		panic(err)
+	}
+	if !ASTEqual(ast, reparsed, IgnorePositions(), IgnoreComments()) {
+		panic("round trip changed the document:\n" + string(out))
+	}
+	return 1
+}
@@ -0,0 +1,311 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/participle"
+)
+
+// GenerateStruct generates Go struct definitions, with hcl tags, from an
+// example HCL document or schema.
+//
+// The top-level struct is named typeName and nested blocks become their own
+// types. Attributes that are missing from some instances of a block, or that
+// are marked optional in a schema, are tagged as optional.
+func GenerateStruct(ast *AST, pkg, typeName string) ([]byte, error) {
+	g := &generator{names: map[string]bool{}}
+	root := g.newStruct(typeName, "")
+	if err := g.body(root, ast.Entries, ast.Schema); err != nil {
+		return nil, err
+	}
+	w := &bytes.Buffer{}
+	fmt.Fprintf(w, "package %s\n", pkg)
+	for _, s := range g.structs {
+		fmt.Fprintln(w)
+		s.write(w)
+	}
+	out, err := format.Source(w.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid Go: %s", err)
+	}
+	return out, nil
+}
+
+type generator struct {
+	structs []*genStruct
+	names   map[string]bool
+}
+
+type genStruct struct {
+	name   string
+	fields []*genField
+	keys   map[string]*genField
+	names  map[string]bool
+	labels int
+	bodies int
+}
+
+type genField struct {
+	name     string
+	key      string
+	typ      string
+	label    bool
+	block    *genStruct
+	repeated bool
+	optional bool
+	bodies   int
+	help     []string
+	// Occurrence bounds of repeated blocks, from schemas.
+	minItems int
+	maxItems int
+}
+
+func (g *generator) newStruct(name, parent string) *genStruct {
+	if g.names[name] {
+		name = parent + name
+	}
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", strings.TrimRight(name, "0123456789"), i)
+	}
+	g.names[name] = true
+	s := &genStruct{name: name, keys: map[string]*genField{}, names: map[string]bool{}}
+	g.structs = append(g.structs, s)
+	return s
+}
+
+func (s *genStruct) field(key string) *genField {
+	if f, ok := s.keys[key]; ok {
+		return f
+	}
+	name := goName(key)
+	for i := 2; s.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", goName(key), i)
+	}
+	s.names[name] = true
+	f := &genField{name: name, key: key}
+	s.keys[key] = f
+	s.fields = append(s.fields, f)
+	return f
+}
+
+func (g *generator) body(s *genStruct, entries []*Entry, schema bool) error {
+	s.bodies++
+	seen := map[string]int{}
+	for _, entry := range entries {
+		key := entry.Key()
+		f := s.field(key)
+		seen[key]++
+		if seen[key] == 1 {
+			f.bodies++
+		}
+		switch {
+		case entry.Attribute != nil:
+			if f.block != nil {
+				return participle.Errorf(entry.Pos, "%s cannot be both block and attribute", key)
+			}
+			if f.help == nil {
+				f.help = entryHelp(entry)
+			}
+			typ, err := genType(entry.Attribute.Value)
+			if err != nil {
+				return err
+			}
+			if f.typ == "" {
+				f.typ = typ
+			} else {
+				f.typ = unifyGenTypes(f.typ, typ)
+			}
+			f.optional = f.optional || entry.Attribute.Optional || entry.Attribute.Default != nil
+
+		case entry.Block != nil:
+			block := entry.Block
+			if f.typ != "" {
+				return participle.Errorf(entry.Pos, "%s cannot be both block and attribute", key)
+			}
+			if f.help == nil {
+				f.help = entryHelp(entry)
+			}
+			if f.block == nil {
+				f.block = g.newStruct(goName(key), s.name)
+			}
+			f.repeated = f.repeated || block.Repeated || seen[key] > 1
+			if block.MinItems != 0 {
+				f.minItems = block.MinItems
+			}
+			if block.MaxItems != 0 {
+				f.maxItems = block.MaxItems
+			}
+			for f.block.labels < len(block.Labels) {
+				i := f.block.labels
+				labelKey := "label"
+				if schema {
+					labelKey = block.Labels[i]
+				} else if len(block.Labels) > 1 {
+					labelKey = "label" + strconv.Itoa(i)
+				}
+				label := &genField{name: goName(labelKey), key: labelKey, typ: "string", label: true}
+				f.block.names[label.name] = true
+				f.block.fields = append(f.block.fields[:i], append([]*genField{label}, f.block.fields[i:]...)...)
+				f.block.labels++
+			}
+			if err := g.body(f.block, block.Body, schema); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// entryHelp returns the doc comment of entry as the help of a field.
+func entryHelp(entry *Entry) []string {
+	if doc := entry.DocComment(); doc != "" {
+		return []string{doc}
+	}
+	return nil
+}
+
+func (s *genStruct) write(w *bytes.Buffer) {
+	fmt.Fprintf(w, "type %s struct {\n", s.name)
+	for _, f := range s.fields {
+		for _, help := range f.help {
+			for _, line := range strings.Split(help, "\n") {
+				if line == "" {
+					fmt.Fprintln(w, "//")
+				} else {
+					fmt.Fprintf(w, "// %s\n", line)
+				}
+			}
+		}
+		typ := completeGenType(f.typ)
+		opts := ""
+		switch {
+		case f.label:
+			opts = ",label"
+		case f.block != nil:
+			typ = f.block.name
+			if f.repeated {
+				typ = "[]" + typ
+			}
+			opts = ",block"
+			if f.repeated && f.minItems != 0 {
+				opts += ",min=" + strconv.Itoa(f.minItems)
+			}
+			if f.repeated && f.maxItems != 0 {
+				opts += ",max=" + strconv.Itoa(f.maxItems)
+			}
+		case f.optional || f.bodies < s.bodies:
+			opts = ",optional"
+		}
+		fmt.Fprintf(w, "%s %s `hcl:\"%s%s\"`\n", f.name, typ, f.key, opts)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// genType returns the Go type for an example or schema value.
+func genType(v *Value) (string, error) {
+	switch {
+	case v.Str != nil, v.HeredocDelimiter != "":
+		return "string", nil
+
+	case v.Bool != nil:
+		return "bool", nil
+
+	case v.Number != nil:
+		if v.NumberKind() == NumberInt {
+			return "int", nil
+		}
+		return "float64", nil
+
+	case v.Type != nil:
+		switch *v.Type {
+		case numType:
+			return "float64", nil
+		case boolType:
+			return "bool", nil
+		default:
+			return "string", nil
+		}
+
+	case v.HaveList:
+		el := ""
+		for _, value := range v.List {
+			typ, err := genType(value)
+			if err != nil {
+				return "", err
+			}
+			el = unifyGenTypes(el, typ)
+		}
+		// The element type of an empty list is unknown until unified with
+		// other lists, see completeGenType.
+		return "[]" + el, nil
+
+	case v.HaveMap:
+		el := ""
+		for _, entry := range v.Map {
+			typ, err := genType(entry.Value)
+			if err != nil {
+				return "", err
+			}
+			el = unifyGenTypes(el, typ)
+		}
+		return "map[string]" + el, nil
+
+	default:
+		return "", participle.Errorf(v.Pos, "can't determine type of value")
+	}
+}
+
+// unifyGenTypes returns a type that can hold values of both a and b.
+func unifyGenTypes(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case b == "":
+		return a
+	case strings.HasPrefix(a, "[]") && strings.HasPrefix(b, "[]"):
+		return "[]" + unifyGenTypes(a[len("[]"):], b[len("[]"):])
+	case strings.HasPrefix(a, "map[string]") && strings.HasPrefix(b, "map[string]"):
+		return "map[string]" + unifyGenTypes(a[len("map[string]"):], b[len("map[string]"):])
+	case (a == "int" && b == "float64") || (a == "float64" && b == "int"):
+		return "float64"
+	default:
+		return "interface{}"
+	}
+}
+
+// completeGenType completes a type ending in a list or map of unknown
+// element type, from an empty list or map, with interface{}.
+func completeGenType(typ string) string {
+	if strings.HasSuffix(typ, "[]") || strings.HasSuffix(typ, "map[string]") {
+		return typ + "interface{}"
+	}
+	return typ
+}
+
+// goName converts a HCL key into an exported Go identifier.
+func goName(key string) string {
+	out := strings.Builder{}
+	upper := true
+	for _, rn := range key {
+		switch {
+		case rn == '_' || rn == '-' || rn == '.' || rn == ' ' || rn == '/':
+			upper = true
+		case !unicode.IsLetter(rn) && !unicode.IsDigit(rn):
+		case upper:
+			out.WriteRune(unicode.ToUpper(rn))
+			upper = false
+		default:
+			out.WriteRune(rn)
+		}
+	}
+	name := out.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
@@ -0,0 +1,125 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateStruct(t *testing.T) {
+	ast := hcl(
+		attr("name", str("app")),
+		attr("max-connections", num(10)),
+		attr("ratio", num(0.5)),
+		attr("tags", list(str("a"), str("b"))),
+		attr("labels", hmap(hkv("env", str("prod")))),
+		block("service", []string{"api"},
+			attr("port", num(8080)),
+			attr("debug", hbool(true)),
+		),
+		block("service", []string{"web"},
+			attr("port", num(80)),
+		),
+		block("log", nil,
+			attr("level", str("info")),
+		),
+	)
+	ast.Entries[0].Attribute.Comments = []string{"Name of the app."}
+	data, err := GenerateStruct(ast, "config", "Config")
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimLeft(`
+package config
+
+type Config struct {
+	// Name of the app.
+	Name           string            `+"`hcl:\"name\"`"+`
+	MaxConnections int               `+"`hcl:\"max-connections\"`"+`
+	Ratio          float64           `+"`hcl:\"ratio\"`"+`
+	Tags           []string          `+"`hcl:\"tags\"`"+`
+	Labels         map[string]string `+"`hcl:\"labels\"`"+`
+	Service        []Service         `+"`hcl:\"service,block\"`"+`
+	Log            Log               `+"`hcl:\"log,block\"`"+`
+}
+
+type Service struct {
+	Label string `+"`hcl:\"label,label\"`"+`
+	Port  int    `+"`hcl:\"port\"`"+`
+	Debug bool   `+"`hcl:\"debug,optional\"`"+`
+}
+
+type Log struct {
+	Level string `+"`hcl:\"level\"`"+`
+}
+`, "\n"), string(data))
+}
+
+func TestGenerateStructFromSchema(t *testing.T) {
+	schema, err := Schema(&testSchema{})
+	require.NoError(t, err)
+	data, err := GenerateStruct(schema, "config", "Config")
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimLeft(`
+package config
+
+type Config struct {
+	// A string field.
+	Str  string   `+"`hcl:\"str\"`"+`
+	Num  float64  `+"`hcl:\"num,optional\"`"+`
+	Bool bool     `+"`hcl:\"bool\"`"+`
+	List []string `+"`hcl:\"list\"`"+`
+	// A map.
+	Map map[string]float64 `+"`hcl:\"map\"`"+`
+	// A block.
+	Block Block `+"`hcl:\"block,block\"`"+`
+	// Repeated blocks.
+	BlockSlice []BlockSlice `+"`hcl:\"block_slice,block\"`"+`
+	DefaultStr string       `+"`hcl:\"default_str,optional\"`"+`
+	EnumStr    string       `+"`hcl:\"enum_str\"`"+`
+}
+
+type Block struct {
+	Name string `+"`hcl:\"name,label\"`"+`
+	Attr string `+"`hcl:\"attr\"`"+`
+}
+
+type BlockSlice struct {
+	Label0 string `+"`hcl:\"label0,label\"`"+`
+	Label1 string `+"`hcl:\"label1,label\"`"+`
+	Attr   string `+"`hcl:\"attr\"`"+`
+}
+`, "\n"), string(data))
+}
+
+func TestGenerateStructBlockOccurrences(t *testing.T) {
+	type upstream struct {
+		Host string `hcl:"host"`
+	}
+	schema, err := Schema(&struct {
+		Upstreams []upstream `hcl:"upstream,block,min=1,max=5"`
+	}{})
+	require.NoError(t, err)
+	data, err := GenerateStruct(schema, "config", "Config")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Upstream []Upstream `hcl:\"upstream,block,min=1,max=5\"`")
+}
+
+func TestGenerateStructNestedLists(t *testing.T) {
+	ast, err := ParseString(`
+matrix = [[], [1, 2]]
+groups = [["a"], []]
+empty = [[]]
+`)
+	require.NoError(t, err)
+	data, err := GenerateStruct(ast, "config", "Config")
+	require.NoError(t, err)
+	require.Equal(t, strings.TrimLeft(`
+package config
+
+type Config struct {
+	Matrix [][]int         `+"`hcl:\"matrix\"`"+`
+	Groups [][]string      `+"`hcl:\"groups\"`"+`
+	Empty  [][]interface{} `+"`hcl:\"empty\"`"+`
+}
+`, "\n"), string(data))
+}
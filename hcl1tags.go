@@ -0,0 +1,46 @@
+package hcl
+
+import (
+	"reflect"
+)
+
+// HCL1Tags specifies whether hcl:"" tags are interpreted as they are by
+// hashicorp/hcl v1, for code migrating from it: every field is optional,
+// fields of struct types, and slices and maps of them, are blocks, the "key"
+// option marks a label, and "decodedFields" and "unusedKeys" fields are
+// ignored.
+//
+// The compat/hcl1 package decodes with this option.
+func HCL1Tags(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.hcl1Tags = v
+	}
+}
+
+// hcl1Tag adapts the tag of an attribute of type t to hashicorp/hcl v1
+// semantics.
+func hcl1Tag(attr tag, t reflect.Type, opt *marshalOptions) tag {
+	attr.optional = true
+	if attr.inline || !isHCL1Block(t, opt) {
+		return attr
+	}
+	return tag{name: attr.name, block: true, optional: true, help: attr.help, aliases: attr.aliases,
+		requiredWith: attr.requiredWith, conflictsWith: attr.conflictsWith,
+		union: opt.lookupUnion(t)}
+}
+
+// isHCL1Block returns true if hashicorp/hcl v1 decodes values of type t from
+// blocks, as it does structs, and slices and maps of them.
+func isHCL1Block(t reflect.Type, opt *marshalOptions) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct && t != timeType && t != pathType &&
+		!typeImplements(t, textUnmarshalerInterface) && opt.lookupScalar(t).decode == nil
+}
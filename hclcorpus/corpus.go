@@ -0,0 +1,252 @@
+// Package hclcorpus provides test helpers asserting that a corpus of real HCL
+// files round trips stably through the parser and printer, so that changes to
+// the grammar or printer can be gated on real configuration.
+//
+// A file is stable if it parses, its formatted output parses to an equal AST,
+// and formatting that AST again reproduces the same output.
+package hclcorpus
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+
+	"github.com/alecthomas/hcl"
+)
+
+// Stage is the step of a round trip at which a file diverged.
+type Stage string
+
+// Stages of a round trip.
+const (
+	// StageParse is parsing the file.
+	StageParse Stage = "parse"
+	// StageFormat is formatting the parsed AST.
+	StageFormat Stage = "format"
+	// StageReparse is parsing the formatted output.
+	StageReparse Stage = "reparse"
+	// StageAST is comparing the AST of the formatted output to that of the file.
+	StageAST Stage = "ast"
+	// StageReformat is comparing the output of formatting the AST of the
+	// formatted output to the formatted output.
+	StageReformat Stage = "reformat"
+)
+
+// Divergence is where a file of a corpus failed to round trip.
+type Divergence struct {
+	// Path of the file.
+	Path  string
+	Stage Stage
+	// Position of the first divergence, in the file for StageParse and
+	// StageAST, and in its formatted output otherwise.
+	Pos     lexer.Position
+	Message string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.Path, d.Pos.Line, d.Pos.Column, d.Stage, d.Message)
+}
+
+// Check round trips each file with a ".hcl" extension in dir and its
+// subdirectories, in filename order, returning the first divergence of each
+// file that isn't stable.
+//
+// An error is returned if the corpus can't be read.
+func Check(dir string, options ...hcl.ParseOption) ([]Divergence, error) {
+	files, err := corpusFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	divergences := []Divergence{}
+	for _, path := range files {
+		divergence, err := CheckFile(path, options...)
+		if err != nil {
+			return nil, err
+		}
+		if divergence != nil {
+			divergences = append(divergences, *divergence)
+		}
+	}
+	return divergences, nil
+}
+
+// AssertStable asserts that each ".hcl" file in dir and its subdirectories
+// round trips stably, reporting the first divergence of each file that
+// doesn't.
+//
+// An empty corpus is an error, so that a misconfigured path can't pass.
+func AssertStable(t testing.TB, dir string, options ...hcl.ParseOption) {
+	t.Helper()
+	files, err := corpusFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no .hcl files in corpus %s", dir)
+	}
+	divergences, err := Check(dir, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, divergence := range divergences {
+		t.Error(divergence.String())
+	}
+}
+
+// CheckFile round trips the file at path, returning its first divergence, or
+// nil if it is stable.
+func CheckFile(path string, options ...hcl.ParseOption) (*Divergence, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	diverged := func(stage Stage, pos lexer.Position, format string, args ...interface{}) (*Divergence, error) {
+		pos.Filename = path
+		return &Divergence{Path: path, Stage: stage, Pos: pos, Message: fmt.Sprintf(format, args...)}, nil
+	}
+	ast, err := hcl.ParseBytes(source, options...)
+	if err != nil {
+		return diverged(StageParse, errorPos(err), "%s", errorMessage(err))
+	}
+	formatted, err := hcl.MarshalAST(ast)
+	if err != nil {
+		return diverged(StageFormat, errorPos(err), "%s", errorMessage(err))
+	}
+	reparsed, err := hcl.ParseBytes(formatted, options...)
+	if err != nil {
+		return diverged(StageReparse, errorPos(err), "%s", errorMessage(err))
+	}
+	if !hcl.ASTEqual(ast, reparsed, hcl.IgnorePositions()) {
+		pos, message := firstDivergence(ast, reparsed)
+		return diverged(StageAST, pos, "%s", message)
+	}
+	reformatted, err := hcl.MarshalAST(reparsed)
+	if err != nil {
+		return diverged(StageFormat, errorPos(err), "%s", errorMessage(err))
+	}
+	if !bytes.Equal(formatted, reformatted) {
+		offset := 0
+		for offset < len(formatted) && offset < len(reformatted) && formatted[offset] == reformatted[offset] {
+			offset++
+		}
+		return diverged(StageReformat, offsetPos(formatted, offset), "formatting is not idempotent: %q became %q",
+			line(formatted, offset), line(reformatted, offset))
+	}
+	return nil, nil
+}
+
+// corpusFiles returns the ".hcl" files in dir and its subdirectories, sorted.
+func corpusFiles(dir string) ([]string, error) {
+	files := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".hcl") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// firstDivergence returns the position in a of the first entry that differs
+// from b, descending into blocks to find the innermost such entry.
+func firstDivergence(a, b *hcl.AST) (lexer.Position, string) {
+	if entry, other := firstDivergentEntry(a.Entries, b.Entries); entry != nil {
+		if other == nil {
+			return entry.Pos, fmt.Sprintf("%s %q is missing after formatting", entryKind(entry), entry.Key())
+		}
+		return entry.Pos, fmt.Sprintf("%s %q differs after formatting: %q became %q",
+			entryKind(entry), entry.Key(), format(entry), format(other))
+	}
+	if len(a.Entries) < len(b.Entries) {
+		extra := b.Entries[len(a.Entries)]
+		return a.Pos, fmt.Sprintf("%s %q was added by formatting", entryKind(extra), extra.Key())
+	}
+	return a.Pos, "directives or trailing comments differ after formatting"
+}
+
+// firstDivergentEntry returns the first entry of a that differs from the
+// corresponding entry of b, if any, and that entry.
+func firstDivergentEntry(a, b []*hcl.Entry) (*hcl.Entry, *hcl.Entry) {
+	for i, entry := range a {
+		if i >= len(b) {
+			return entry, nil
+		}
+		other := b[i]
+		if hcl.ASTEqual(entry, other, hcl.IgnorePositions()) {
+			continue
+		}
+		if entry.Block != nil && other.Block != nil {
+			if inner, innerOther := firstDivergentEntry(entry.Block.Body, other.Block.Body); inner != nil {
+				return inner, innerOther
+			}
+		}
+		return entry, other
+	}
+	return nil, nil
+}
+
+func entryKind(entry *hcl.Entry) string {
+	if entry.Block != nil {
+		return "block"
+	}
+	return "attribute"
+}
+
+// format an entry on a single line, for messages.
+func format(entry *hcl.Entry) string {
+	data, err := hcl.MarshalAST(&hcl.AST{Entries: []*hcl.Entry{entry}})
+	if err != nil {
+		return err.Error()
+	}
+	return strings.Join(strings.Fields(string(data)), " ")
+}
+
+func errorPos(err error) lexer.Position {
+	if perr, ok := err.(participle.Error); ok {
+		return perr.Token().Pos
+	}
+	return lexer.Position{}
+}
+
+func errorMessage(err error) string {
+	if perr, ok := err.(participle.Error); ok {
+		return perr.Message()
+	}
+	return err.Error()
+}
+
+// offsetPos returns the position of offset in data.
+func offsetPos(data []byte, offset int) lexer.Position {
+	before := data[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return lexer.Position{
+		Offset: offset,
+		Line:   bytes.Count(before, []byte("\n")) + 1,
+		Column: len(before) - lineStart + 1,
+	}
+}
+
+// line returns the line of data containing offset.
+func line(data []byte, offset int) string {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := bytes.IndexByte(data[offset:], '\n')
+	if end == -1 {
+		return string(data[start:])
+	}
+	return string(data[start : offset+end])
+}
@@ -0,0 +1,62 @@
+package hclcorpus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/hcl"
+)
+
+func TestAssertStable(t *testing.T) {
+	AssertStable(t, "testdata/stable")
+}
+
+func TestCheck(t *testing.T) {
+	divergences, err := Check("testdata")
+	require.NoError(t, err)
+	require.Len(t, divergences, 1)
+	divergence := divergences[0]
+	require.Equal(t, "testdata/broken/broken.hcl", divergence.Path)
+	require.Equal(t, StageParse, divergence.Stage)
+	require.Equal(t, 3, divergence.Pos.Line)
+	require.Contains(t, divergence.String(), "testdata/broken/broken.hcl:3:3: parse: ")
+
+	_, err = Check("testdata/missing")
+	require.Error(t, err)
+}
+
+func TestFirstDivergence(t *testing.T) {
+	a, err := hcl.ParseString(`
+port = 80
+server {
+  host = "a"
+  port = 1
+}
+`)
+	require.NoError(t, err)
+	b, err := hcl.ParseString(`
+port = 80
+server {
+  host = "a"
+  port = 2
+}
+`)
+	require.NoError(t, err)
+	pos, message := firstDivergence(a, b)
+	require.Equal(t, 5, pos.Line)
+	require.Equal(t, `attribute "port" differs after formatting: "port = 1" became "port = 2"`, message)
+
+	b.Entries = b.Entries[:1]
+	pos, message = firstDivergence(a, b)
+	require.Equal(t, 3, pos.Line)
+	require.Equal(t, `block "server" is missing after formatting`, message)
+}
+
+func TestOffsetPos(t *testing.T) {
+	data := []byte("a = 1\nbb = 2\n")
+	pos := offsetPos(data, 9)
+	require.Equal(t, 2, pos.Line)
+	require.Equal(t, 4, pos.Column)
+	require.Equal(t, "bb = 2", line(data, 9))
+}
@@ -0,0 +1,4 @@
+port = 80
+server {
+  host = 
+}
@@ -0,0 +1,8 @@
+motd = <<EOF
+Hello,
+  world.
+EOF
+
+tls {
+  cert = 'cert.pem'
+}
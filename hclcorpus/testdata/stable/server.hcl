@@ -0,0 +1,11 @@
+// Server configuration.
+listen = ":8080"
+
+server "api" {
+  # Upstream hosts.
+  hosts = ["a", "b"]
+  limits = {
+    cpu: 2,
+    mem: "512M",
+  }
+}
@@ -0,0 +1,178 @@
+// Package hclcty converts between HCL values and github.com/zclconf/go-cty values.
+//
+// Strings and heredocs map to cty.String, numbers to cty.Number and booleans
+// to cty.Bool. Lists map to tuples and maps to objects, as element types in
+// HCL are not necessarily homogeneous.
+package hclcty
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/hcl"
+	"github.com/alecthomas/participle"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// ValueToCty converts a HCL value to a cty.Value.
+func ValueToCty(v *hcl.Value) (cty.Value, error) {
+	switch {
+	case v.Str != nil:
+		return cty.StringVal(*v.Str), nil
+
+	case v.HeredocDelimiter != "":
+		return cty.StringVal(v.GetHeredoc()), nil
+
+	case v.Number != nil:
+		return cty.NumberVal(v.Number), nil
+
+	case v.Bool != nil:
+		return cty.BoolVal(bool(*v.Bool)), nil
+
+	case v.HaveList:
+		if len(v.List) == 0 {
+			return cty.EmptyTupleVal, nil
+		}
+		elements := make([]cty.Value, 0, len(v.List))
+		for _, el := range v.List {
+			cv, err := ValueToCty(el)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			elements = append(elements, cv)
+		}
+		return cty.TupleVal(elements), nil
+
+	case v.HaveMap:
+		if len(v.Map) == 0 {
+			return cty.EmptyObjectVal, nil
+		}
+		attrs := make(map[string]cty.Value, len(v.Map))
+		for _, entry := range v.Map {
+			if entry.Key.Str == nil {
+				return cty.NilVal, participle.Errorf(entry.Key.Pos, "map key must be a string but is %s", entry.Key)
+			}
+			cv, err := ValueToCty(entry.Value)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			attrs[*entry.Key.Str] = cv
+		}
+		return cty.ObjectVal(attrs), nil
+
+	default:
+		return cty.NilVal, participle.Errorf(v.Pos, "can't convert %s to a cty value", v)
+	}
+}
+
+// CtyToValue converts a cty.Value to a HCL value.
+//
+// Lists, sets and tuples become HCL lists, and maps and objects become HCL
+// maps. Null and unknown values can't be represented in HCL and are an error.
+func CtyToValue(v cty.Value) (*hcl.Value, error) {
+	if !v.IsKnown() {
+		return nil, fmt.Errorf("can't convert unknown %s value", v.Type().FriendlyName())
+	}
+	if v.IsNull() {
+		return nil, fmt.Errorf("can't convert null %s value", v.Type().FriendlyName())
+	}
+	t := v.Type()
+	switch {
+	case t.Equals(cty.String):
+		s := v.AsString()
+		return &hcl.Value{Str: &s}, nil
+
+	case t.Equals(cty.Number):
+		return &hcl.Value{Number: v.AsBigFloat()}, nil
+
+	case t.Equals(cty.Bool):
+		b := hcl.Bool(v.True())
+		return &hcl.Value{Bool: &b}, nil
+
+	case t.IsListType(), t.IsSetType(), t.IsTupleType():
+		out := &hcl.Value{HaveList: true}
+		for it := v.ElementIterator(); it.Next(); {
+			_, el := it.Element()
+			hv, err := CtyToValue(el)
+			if err != nil {
+				return nil, err
+			}
+			out.List = append(out.List, hv)
+		}
+		return out, nil
+
+	case t.IsMapType(), t.IsObjectType():
+		out := &hcl.Value{HaveMap: true}
+		// Elements are iterated in lexical key order.
+		for it := v.ElementIterator(); it.Next(); {
+			k, el := it.Element()
+			hv, err := CtyToValue(el)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k.AsString(), err)
+			}
+			key := k.AsString()
+			out.Map = append(out.Map, &hcl.MapEntry{Key: &hcl.Value{Str: &key}, Value: hv})
+		}
+		return out, nil
+
+	default:
+		return nil, fmt.Errorf("can't convert %s value", t.FriendlyName())
+	}
+}
+
+// ASTToCty converts the body of a HCL document to a cty object.
+//
+// Attributes become object attributes. Blocks are nested under their name
+// and then each of their labels, in the same manner as the HCL JSON
+// encoding, with repeated blocks collected into a tuple.
+func ASTToCty(ast *hcl.AST) (cty.Value, error) {
+	return entriesToCty(ast.Entries)
+}
+
+func entriesToCty(entries []*hcl.Entry) (cty.Value, error) {
+	attrs := map[string]cty.Value{}
+	blocks := map[string][]*hcl.Block{}
+	order := []string{}
+	for _, entry := range entries {
+		key := entry.Key()
+		if entry.Block != nil {
+			if _, ok := blocks[key]; !ok {
+				order = append(order, key)
+			}
+			blocks[key] = append(blocks[key], entry.Block)
+			continue
+		}
+		if _, ok := attrs[key]; ok {
+			return cty.NilVal, participle.Errorf(entry.Pos, "duplicate attribute %q", key)
+		}
+		cv, err := ValueToCty(entry.Attribute.Value)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		attrs[key] = cv
+	}
+	for _, key := range order {
+		if _, ok := attrs[key]; ok {
+			return cty.NilVal, participle.Errorf(blocks[key][0].Pos, "%s cannot be both block and attribute", key)
+		}
+		values := make([]cty.Value, 0, len(blocks[key]))
+		for _, block := range blocks[key] {
+			cv, err := entriesToCty(block.Body)
+			if err != nil {
+				return cty.NilVal, err
+			}
+			for i := len(block.Labels) - 1; i >= 0; i-- {
+				cv = cty.ObjectVal(map[string]cty.Value{block.Labels[i]: cv})
+			}
+			values = append(values, cv)
+		}
+		if len(values) == 1 {
+			attrs[key] = values[0]
+		} else {
+			attrs[key] = cty.TupleVal(values)
+		}
+	}
+	if len(attrs) == 0 {
+		return cty.EmptyObjectVal, nil
+	}
+	return cty.ObjectVal(attrs), nil
+}
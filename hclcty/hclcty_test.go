@@ -0,0 +1,72 @@
+package hclcty
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/alecthomas/hcl"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestValueToCty(t *testing.T) {
+	value, err := hcl.ParseValue(`{"name": "app", "ports": [80, 443], "debug": true}`)
+	require.NoError(t, err)
+	actual, err := ValueToCty(value)
+	require.NoError(t, err)
+	expected := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("app"),
+		"ports": cty.TupleVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+		"debug": cty.True,
+	})
+	require.True(t, expected.RawEquals(actual), "%#v", actual)
+}
+
+func TestCtyToValue(t *testing.T) {
+	value := cty.ObjectVal(map[string]cty.Value{
+		"name":  cty.StringVal("app"),
+		"ports": cty.ListVal([]cty.Value{cty.NumberIntVal(80), cty.NumberIntVal(443)}),
+		"debug": cty.True,
+	})
+	actual, err := CtyToValue(value)
+	require.NoError(t, err)
+	require.Equal(t, `{"debug": true, "name": "app", "ports": [80, 443]}`, hcl.FormatValue(actual))
+}
+
+func TestCtyToValueErrors(t *testing.T) {
+	_, err := CtyToValue(cty.NullVal(cty.String))
+	require.EqualError(t, err, "can't convert null string value")
+	_, err = CtyToValue(cty.ObjectVal(map[string]cty.Value{"a": cty.UnknownVal(cty.Number)}))
+	require.EqualError(t, err, "a: can't convert unknown number value")
+}
+
+func TestRoundTrip(t *testing.T) {
+	value, err := CtyToValue(cty.NumberVal(big.NewFloat(1.5)))
+	require.NoError(t, err)
+	actual, err := ValueToCty(value)
+	require.NoError(t, err)
+	require.True(t, cty.NumberFloatVal(1.5).RawEquals(actual))
+}
+
+func TestASTToCty(t *testing.T) {
+	ast, err := hcl.ParseString(`
+		name = "app"
+		service "http" {
+			port = 80
+		}
+		service "https" {
+			port = 443
+		}
+	`)
+	require.NoError(t, err)
+	actual, err := ASTToCty(ast)
+	require.NoError(t, err)
+	expected := cty.ObjectVal(map[string]cty.Value{
+		"name": cty.StringVal("app"),
+		"service": cty.TupleVal([]cty.Value{
+			cty.ObjectVal(map[string]cty.Value{"http": cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(80)})}),
+			cty.ObjectVal(map[string]cty.Value{"https": cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(443)})}),
+		}),
+	})
+	require.True(t, expected.RawEquals(actual), "%#v", actual)
+}
@@ -0,0 +1,70 @@
+// Package hclgolden provides test helpers for comparing HCL output against
+// golden files.
+//
+// Run tests with the -hclgolden.update flag to rewrite golden files from the
+// current output rather than comparing against them.
+package hclgolden
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alecthomas/hcl"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("hclgolden.update", false, "rewrite HCL golden files rather than comparing against them")
+
+// AssertMarshal asserts that v marshals to the contents of the golden file at path.
+//
+// v is marshalled twice to verify that the output is stable.
+func AssertMarshal(t testing.TB, path string, v interface{}, options ...hcl.MarshalOption) {
+	t.Helper()
+	data, err := hcl.Marshal(v, options...)
+	require.NoError(t, err)
+	again, err := hcl.Marshal(v, options...)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(again), "marshalling is not deterministic")
+	assertGolden(t, path, data)
+}
+
+// AssertAST asserts that ast formats to the contents of the golden file at path.
+func AssertAST(t testing.TB, path string, ast *hcl.AST) {
+	t.Helper()
+	data, err := hcl.MarshalAST(ast)
+	require.NoError(t, err)
+	assertGolden(t, path, data)
+}
+
+// AssertRoundTrip asserts that the golden file at path unmarshals into v, and
+// that v marshals back to identical bytes.
+func AssertRoundTrip(t testing.TB, path string, v interface{}, options ...hcl.MarshalOption) {
+	t.Helper()
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	err = hcl.Unmarshal(expected, v, options...)
+	require.NoError(t, err)
+	actual, err := hcl.Marshal(v, options...)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual), "%s did not round trip", path)
+}
+
+func assertGolden(t testing.TB, path string, actual []byte) {
+	t.Helper()
+	if *update {
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path, actual, 0600) // nolint: gosec
+		require.NoError(t, err)
+		return
+	}
+	expected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist, run with -hclgolden.update to create it", path)
+	}
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual), "output differs from %s, run with -hclgolden.update to update it", path)
+}
@@ -0,0 +1,41 @@
+package hclgolden
+
+import (
+	"testing"
+
+	"github.com/alecthomas/hcl"
+)
+
+type service struct {
+	Name  string            `hcl:"name,label"`
+	Port  int               `hcl:"port"`
+	Attrs map[string]string `hcl:"attrs"`
+}
+
+type config struct {
+	Version  int       `hcl:"version"`
+	Services []service `hcl:"service,block"`
+}
+
+func TestAssertMarshal(t *testing.T) {
+	AssertMarshal(t, "testdata/config.hcl", &config{
+		Version: 2,
+		Services: []service{
+			{Name: "api", Port: 8080, Attrs: map[string]string{"z": "last", "a": "first", "m": "middle"}},
+		},
+	})
+}
+
+func TestAssertAST(t *testing.T) {
+	ast, err := hcl.MarshalToAST(&config{Version: 2, Services: []service{
+		{Name: "api", Port: 8080, Attrs: map[string]string{"z": "last", "a": "first", "m": "middle"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertAST(t, "testdata/config.hcl", ast)
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	AssertRoundTrip(t, "testdata/config.hcl", &config{})
+}
@@ -0,0 +1,10 @@
+version = 2
+
+service "api" {
+  port = 8080
+  attrs = {
+    "a": "first",
+    "m": "middle",
+    "z": "last",
+  }
+}
@@ -0,0 +1,207 @@
+// Package hclhttp provides HTTP handlers for building configuration
+// validation services.
+//
+// A Handler accepts an HCL document as the request body, validates it against
+// a Go struct, and responds with the resulting diagnostics as JSON, eg.
+//
+//	http.Handle("/validate", hclhttp.NewHandler(func() interface{} { return &Config{} }))
+package hclhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+
+	"github.com/alecthomas/hcl"
+)
+
+// Severity of a Diagnostic.
+type Severity string
+
+// Diagnostic severities.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Position in the validated document.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// Diagnostic is a problem with the validated document.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	// Code is the stable code of the problem, as returned by hcl.ErrorCode.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+	// Pos is the position of the problem, or nil if it is not known.
+	Pos *Position `json:"pos,omitempty"`
+	// Path of the enclosing block, eg. "server.web".
+	Path string `json:"path,omitempty"`
+}
+
+// Response is the JSON body of a Handler's responses.
+type Response struct {
+	Valid       bool         `json:"valid"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// DefaultMaxBodySize is the default maximum size of request bodies.
+const DefaultMaxBodySize = 1 << 20
+
+// Message of the error returned by http.MaxBytesReader once its limit is
+// exceeded, which has no type of its own before Go 1.19.
+const errBodyTooLarge = "http: request body too large"
+
+// Option configures a Handler.
+type Option func(h *Handler)
+
+// MaxBodySize sets the maximum size of request bodies, beyond which requests
+// are rejected with 413 Request Entity Too Large.
+//
+// Defaults to DefaultMaxBodySize.
+func MaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		h.maxBodySize = n
+	}
+}
+
+// MarshalOptions sets the options used to validate documents.
+func MarshalOptions(options ...hcl.MarshalOption) Option {
+	return func(h *Handler) {
+		h.options = options
+	}
+}
+
+// Handler validates HCL documents POSTed to it against a Go struct.
+//
+// Responses are JSON encoded Responses, with status 200 OK if the document is
+// valid, or 422 Unprocessable Entity if it is not. Warnings, eg. for
+// deprecated fields, don't make a document invalid.
+type Handler struct {
+	newValue    func() interface{}
+	options     []hcl.MarshalOption
+	maxBodySize int64
+}
+
+var _ http.Handler = &Handler{}
+
+// NewHandler creates a Handler validating documents against the pointers to
+// structs returned by newValue, which is called for each request.
+func NewHandler(newValue func() interface{}, options ...Option) *Handler {
+	h := &Handler{newValue: newValue, maxBodySize: DefaultMaxBodySize}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+	source, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBodySize))
+	if err != nil && err.Error() == errBodyTooLarge {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body exceeds maximum size of %d bytes", h.maxBodySize)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body: %s", err)
+		return
+	}
+	response := Response{Valid: true, Diagnostics: h.Validate(source)}
+	for _, diagnostic := range response.Diagnostics {
+		if diagnostic.Severity == SeverityError {
+			response.Valid = false
+		}
+	}
+	status := http.StatusOK
+	if !response.Valid {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, response)
+}
+
+// Validate source, returning diagnostics ordered by position.
+//
+// Unlike hcl.Unmarshal, validation continues past the first problem where
+// possible, so that all of them are reported.
+func (h *Handler) Validate(source []byte) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	ast, err := hcl.ParseBytes(source)
+	if err != nil {
+		return append(diagnostics, ErrorDiagnostic(SeverityError, err))
+	}
+	problems, err := hcl.CheckCompatibility(source, h.newValue(), h.options...)
+	if err != nil {
+		return append(diagnostics, ErrorDiagnostic(SeverityError, err))
+	}
+	for _, problem := range problems {
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SeverityError,
+			Code:     problem.Code,
+			Message:  problem.Msg,
+			Pos:      position(problem.Pos),
+			Path:     problem.Path,
+		})
+	}
+	options := append(append([]hcl.MarshalOption{}, h.options...), hcl.WithWarningCallback(func(warning hcl.Warning) {
+		diagnostics = append(diagnostics, ErrorDiagnostic(SeverityWarning, warning))
+	}))
+	// Constraints spanning fields, eg. conflicts_with:"", are only checked by
+	// unmarshalling.
+	if err := hcl.UnmarshalAST(ast, h.newValue(), options...); err != nil && len(problems) == 0 {
+		diagnostics = append(diagnostics, ErrorDiagnostic(SeverityError, err))
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i].Pos, diagnostics[j].Pos
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return diagnostics
+}
+
+// ErrorDiagnostic converts err, eg. from hcl.Parse, into a Diagnostic, with
+// its position if it has one.
+func ErrorDiagnostic(severity Severity, err error) Diagnostic {
+	diagnostic := Diagnostic{Severity: severity, Code: hcl.ErrorCode(err), Message: err.Error()}
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		diagnostic.Message = perr.Message()
+		diagnostic.Pos = position(perr.Token().Pos)
+	}
+	return diagnostic
+}
+
+func position(pos lexer.Position) *Position {
+	if pos.Line == 0 {
+		return nil
+	}
+	return &Position{Line: pos.Line, Column: pos.Column, Offset: pos.Offset}
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, Response{Diagnostics: []Diagnostic{{
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
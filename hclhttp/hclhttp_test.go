@@ -0,0 +1,95 @@
+package hclhttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/hcl"
+)
+
+type config struct {
+	Host    string `hcl:"host"`
+	Port    int    `hcl:"port,optional"`
+	Timeout string `hcl:"timeout,optional" deprecated:"use timeouts"`
+}
+
+func validate(t *testing.T, h http.Handler, method, body string) (*httptest.ResponseRecorder, Response) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, "/validate", strings.NewReader(body)))
+	require.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	response := Response{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w, response
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(func() interface{} { return &config{} })
+
+	w, response := validate(t, h, "POST", "host = \"example.com\"\nport = 80\n")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, Response{Valid: true, Diagnostics: []Diagnostic{}}, response)
+
+	w, response = validate(t, h, "POST", "host = \"example.com\"\ntimeout = \"5s\"\n")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, Response{Valid: true, Diagnostics: []Diagnostic{{
+		Severity: SeverityWarning,
+		Message:  `attribute "timeout" is deprecated: use timeouts`,
+		Pos:      &Position{Line: 2, Column: 1, Offset: 21},
+	}}}, response)
+
+	w, response = validate(t, h, "POST", "port = \"eighty\"\nhots = \"\"\n")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, Response{Diagnostics: []Diagnostic{
+		{Severity: SeverityError, Code: hcl.CodeMissingAttribute, Message: `missing required attribute "host"`, Pos: &Position{Line: 1, Column: 1}},
+		{Severity: SeverityError, Code: hcl.CodeMismatch, Message: `port: expected a number but got "eighty"`, Pos: &Position{Line: 1, Column: 8, Offset: 7}},
+		{Severity: SeverityError, Code: hcl.CodeIgnoredAttribute, Message: `attribute "hots" would be ignored; did you mean "host"?`, Pos: &Position{Line: 2, Column: 1, Offset: 16}},
+	}}, response)
+
+	w, response = validate(t, h, "POST", "host = }\n")
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Len(t, response.Diagnostics, 1)
+	require.Equal(t, hcl.CodeSyntax, response.Diagnostics[0].Code)
+	require.Equal(t, &Position{Line: 1, Column: 8, Offset: 7}, response.Diagnostics[0].Pos)
+
+	w, _ = validate(t, h, "GET", "")
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	require.Equal(t, "POST, PUT", w.Header().Get("Allow"))
+
+	h = NewHandler(func() interface{} { return &config{} }, MaxBodySize(8))
+	w, response = validate(t, h, "POST", "host = \"example.com\"\n")
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	require.Equal(t, "request body exceeds maximum size of 8 bytes", response.Diagnostics[0].Message)
+}
+
+// failingReader returns some data followed by err.
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (f *failingReader) Read(b []byte) (int, error) {
+	if f.data == "" {
+		return 0, f.err
+	}
+	n := copy(b, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func TestHandlerReadError(t *testing.T) {
+	h := NewHandler(func() interface{} { return &config{} })
+	w := httptest.NewRecorder()
+	body := &failingReader{data: "host = ", err: io.ErrUnexpectedEOF}
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/validate", body))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	response := Response{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	require.Equal(t, "failed to read request body: unexpected EOF", response.Diagnostics[0].Message)
+}
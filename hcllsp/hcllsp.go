@@ -0,0 +1,413 @@
+// Package hcllsp provides the building blocks of a Language Server Protocol
+// server for HCL configuration files.
+//
+// Types mirror their LSP counterparts, including JSON field names, so they
+// can be returned to clients directly. As in LSP, lines and characters are
+// zero-based and characters are counted in UTF-16 code units.
+//
+// Hover and completion are driven by a schema, as returned by hcl.Schema(),
+// with documentation taken from help:"" tags.
+package hcllsp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+
+	"github.com/alecthomas/hcl"
+)
+
+// Position in a document.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range in a document, exclusive of End.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// SymbolKind is the kind of a DocumentSymbol.
+type SymbolKind int
+
+// Symbol kinds, with their LSP values.
+const (
+	SymbolKindProperty SymbolKind = 7
+	SymbolKindStruct   SymbolKind = 23
+)
+
+// DocumentSymbol is an attribute or block in a document.
+type DocumentSymbol struct {
+	Name   string     `json:"name"`
+	Detail string     `json:"detail,omitempty"`
+	Kind   SymbolKind `json:"kind"`
+	// Range of the entry, excluding leading comments.
+	Range Range `json:"range"`
+	// Range of the name of the entry.
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children,omitempty"`
+}
+
+// MarkupContent is documentation in Markdown.
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the documentation for an entry.
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+// CompletionItemKind is the kind of a CompletionItem.
+type CompletionItemKind int
+
+// Completion item kinds, with their LSP values.
+const (
+	CompletionItemKindProperty CompletionItemKind = 10
+	CompletionItemKindStruct   CompletionItemKind = 22
+)
+
+// CompletionItem is a candidate attribute or block name.
+type CompletionItem struct {
+	Label         string             `json:"label"`
+	Kind          CompletionItemKind `json:"kind"`
+	Detail        string             `json:"detail,omitempty"`
+	Documentation string             `json:"documentation,omitempty"`
+}
+
+// DiagnosticSeverity is the severity of a Diagnostic.
+type DiagnosticSeverity int
+
+// Diagnostic severities, with their LSP values.
+const (
+	SeverityError   DiagnosticSeverity = 1
+	SeverityWarning DiagnosticSeverity = 2
+)
+
+// Diagnostic is an error or warning in a document.
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity"`
+	// Code is the stable code of the error, as returned by hcl.ErrorCode.
+	Code    string `json:"code,omitempty"`
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// Document is a parsed HCL document.
+type Document struct {
+	// Source of the document, with any byte order mark removed and CRLF line
+	// endings converted to LF, as positions in the AST refer to.
+	Source []byte
+	// AST of the document, or nil if it could not be parsed.
+	AST *hcl.AST
+	// Err is the error parsing the document, if any.
+	Err error
+}
+
+// Parse a document.
+//
+// Parse errors are recorded in the Document rather than returned, so that
+// features that don't require an AST continue to work.
+func Parse(src []byte) *Document {
+	src = bytes.TrimPrefix(src, []byte("\xef\xbb\xbf"))
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	ast, err := hcl.ParseBytes(src)
+	return &Document{Source: src, AST: ast, Err: err}
+}
+
+// Diagnostics for errors parsing the document.
+func (d *Document) Diagnostics() []Diagnostic {
+	if d.Err == nil {
+		return nil
+	}
+	return []Diagnostic{d.diagnostic(SeverityError, d.Err)}
+}
+
+// Validate the document by unmarshalling it into v, returning diagnostics for
+// the resulting error and any warnings.
+//
+// Any warning callback in options is replaced.
+func (d *Document) Validate(v interface{}, options ...hcl.MarshalOption) []Diagnostic {
+	if d.AST == nil {
+		return d.Diagnostics()
+	}
+	diagnostics := []Diagnostic{}
+	options = append(options, hcl.WithWarningCallback(func(warning hcl.Warning) {
+		diagnostics = append(diagnostics, d.diagnostic(SeverityWarning, warning))
+	}))
+	if err := hcl.UnmarshalAST(d.AST, v, options...); err != nil {
+		diagnostics = append(diagnostics, d.diagnostic(SeverityError, err))
+	}
+	return diagnostics
+}
+
+func (d *Document) diagnostic(severity DiagnosticSeverity, err error) Diagnostic {
+	diagnostic := Diagnostic{Severity: severity, Code: hcl.ErrorCode(err), Source: "hcl", Message: err.Error()}
+	var perr participle.Error
+	if !errors.As(err, &perr) {
+		return diagnostic
+	}
+	diagnostic.Message = perr.Message()
+	pos := perr.Token().Pos
+	if pos.Line == 0 {
+		return diagnostic
+	}
+	start := hcl.PositionAt(d.Source, pos.Line, pos.Column, hcl.ColumnRunes).Offset
+	diagnostic.Range = d.rangeOf(start, tokenEnd(d.Source, start))
+	return diagnostic
+}
+
+// Symbols returns the hierarchy of attributes and blocks in the document.
+func (d *Document) Symbols() []DocumentSymbol {
+	if d.AST == nil {
+		return nil
+	}
+	return d.symbols(d.AST.Entries)
+}
+
+func (d *Document) symbols(entries []*hcl.Entry) []DocumentSymbol {
+	out := []DocumentSymbol{}
+	for _, entry := range entries {
+		key, _, end := entryExtent(d.Source, entry.Pos.Offset, entry.Block != nil)
+		symbol := DocumentSymbol{
+			Name:           entry.Key(),
+			Range:          d.rangeOf(key, end),
+			SelectionRange: d.rangeOf(key, key+len(entry.Key())),
+		}
+		if entry.Block != nil {
+			symbol.Kind = SymbolKindStruct
+			labels := make([]string, len(entry.Block.Labels))
+			for i, label := range entry.Block.Labels {
+				labels[i] = strconv.Quote(label)
+			}
+			symbol.Detail = strings.Join(labels, " ")
+			symbol.Children = d.symbols(entry.Block.Body)
+		} else {
+			symbol.Kind = SymbolKindProperty
+			symbol.Detail = entry.Attribute.Value.String()
+		}
+		out = append(out, symbol)
+	}
+	return out
+}
+
+// Hover returns documentation from schema for the name of the attribute or
+// block at pos, or nil if there is none.
+func (d *Document) Hover(schema *hcl.AST, pos Position) *Hover {
+	if d.AST == nil {
+		return nil
+	}
+	offset := d.offset(pos)
+	path, entries := []string{}, d.AST.Entries
+	for len(entries) > 0 {
+		entry, key, brace, end := d.entryAt(entries, offset)
+		if entry == nil {
+			return nil
+		}
+		path = append(path, entry.Key())
+		if offset >= key && offset < key+len(entry.Key()) {
+			found := schemaEntry(schema, path)
+			if found == nil {
+				return nil
+			}
+			return &Hover{
+				Contents: MarkupContent{Kind: "markdown", Value: documentation(found)},
+				Range:    d.rangeOf(key, key+len(entry.Key())),
+			}
+		}
+		if entry.Block == nil || brace == -1 || offset <= brace || offset >= end {
+			return nil
+		}
+		entries = entry.Block.Body
+	}
+	return nil
+}
+
+// Complete returns the attributes and blocks from schema that may be added
+// to the block enclosing pos.
+//
+// Attributes, and blocks that are not repeatable, are omitted if they are
+// already present. If the document can not be parsed, it is parsed again
+// without the line containing pos, which is assumed to be incomplete.
+func (d *Document) Complete(schema *hcl.AST, pos Position) []CompletionItem {
+	doc := d
+	if doc.AST == nil {
+		// Blank out the line containing pos.
+		src := append([]byte{}, d.Source...)
+		start := hcl.PositionAt(src, pos.Line+1, 1, hcl.ColumnUTF16).Offset
+		end := len(src)
+		if nl := bytes.IndexByte(src[start:], '\n'); nl != -1 {
+			end = start + nl
+		}
+		copy(src[start:end], bytes.Repeat([]byte(" "), end-start))
+		if doc = Parse(src); doc.AST == nil {
+			return nil
+		}
+	}
+	offset := doc.offset(pos)
+	path, entries := []string{}, doc.AST.Entries
+	for {
+		entry, _, brace, end := doc.entryAt(entries, offset)
+		if entry == nil || entry.Block == nil || brace == -1 || offset <= brace || offset >= end {
+			break
+		}
+		path = append(path, entry.Key())
+		entries = entry.Block.Body
+	}
+	candidates := schema.Entries
+	if len(path) > 0 {
+		block := schemaEntry(schema, path)
+		if block == nil || block.Block == nil {
+			return nil
+		}
+		candidates = block.Block.Body
+	}
+	present := map[string]bool{}
+	for _, entry := range entries {
+		present[entry.Key()] = true
+	}
+	items := []CompletionItem{}
+	for _, candidate := range candidates {
+		if present[candidate.Key()] && (candidate.Block == nil || !candidate.Block.Repeated) {
+			continue
+		}
+		item := CompletionItem{
+			Label:         candidate.Key(),
+			Kind:          CompletionItemKindProperty,
+			Documentation: strings.Join(comments(candidate), "\n"),
+		}
+		if candidate.Block != nil {
+			item.Kind = CompletionItemKindStruct
+			item.Detail = "block"
+		} else {
+			item.Detail = candidate.Attribute.Value.String()
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// entryAt returns the entry containing offset, along with the offsets of its
+// key, opening brace and end.
+func (d *Document) entryAt(entries []*hcl.Entry, offset int) (entry *hcl.Entry, key, brace, end int) {
+	for _, entry := range entries {
+		key, brace, end := entryExtent(d.Source, entry.Pos.Offset, entry.Block != nil)
+		if offset >= key && offset < end {
+			return entry, key, brace, end
+		}
+	}
+	return nil, 0, -1, 0
+}
+
+// offset returns the byte offset of pos.
+func (d *Document) offset(pos Position) int {
+	return hcl.PositionAt(d.Source, pos.Line+1, pos.Character+1, hcl.ColumnUTF16).Offset
+}
+
+// position returns the Position of the byte offset.
+func (d *Document) position(offset int) Position {
+	line := bytes.Count(d.Source[:offset], []byte("\n"))
+	column := hcl.ColumnOf(d.Source, lexer.Position{Offset: offset, Line: line + 1, Column: 1}, hcl.ColumnUTF16)
+	return Position{Line: line, Character: column - 1}
+}
+
+func (d *Document) rangeOf(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+// schemaEntry returns the entry in schema at path, a list of nested entry names.
+func schemaEntry(schema *hcl.AST, path []string) *hcl.Entry {
+	entries := schema.Entries
+	for i, name := range path {
+		var found *hcl.Entry
+		for _, entry := range entries {
+			if entry.Key() == name {
+				found = entry
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		if found.Block != nil && found.Block.Recursive {
+			found = resolveRecursive(schema, found)
+		}
+		if i == len(path)-1 {
+			return found
+		}
+		if found.Block == nil {
+			return nil
+		}
+		entries = found.Block.Body
+	}
+	return nil
+}
+
+// resolveRecursive returns a copy of the recursive block entry with the body
+// of the block it refers to.
+func resolveRecursive(schema *hcl.AST, entry *hcl.Entry) *hcl.Entry {
+	body := schema.Entries
+	if entry.Block.RecursivePath != "" {
+		target := schemaEntry(schema, strings.Split(entry.Block.RecursivePath, "."))
+		if target == nil || target.Block == nil {
+			return entry
+		}
+		body = target.Block.Body
+	}
+	block := *entry.Block
+	block.Body = body
+	resolved := *entry
+	resolved.Block = &block
+	return &resolved
+}
+
+func comments(entry *hcl.Entry) []string {
+	if entry.Block != nil {
+		return entry.Block.Comments
+	}
+	return entry.Attribute.Comments
+}
+
+// documentation renders the Markdown documentation of a schema entry.
+func documentation(entry *hcl.Entry) string {
+	w := &strings.Builder{}
+	w.WriteString("```hcl\n")
+	if entry.Block != nil {
+		w.WriteString(entry.Block.Name)
+		for _, label := range entry.Block.Labels {
+			fmt.Fprintf(w, " %q", label)
+		}
+		w.WriteString(" {}\n")
+	} else {
+		fmt.Fprintf(w, "%s = %s\n", entry.Attribute.Key, entry.Attribute.Value)
+	}
+	w.WriteString("```")
+	if help := strings.Join(comments(entry), "\n"); help != "" {
+		w.WriteString("\n\n" + help)
+	}
+	if attr := entry.Attribute; attr != nil {
+		if attr.Pattern != "" {
+			w.WriteString("\n\nPattern: `" + attr.Pattern + "`")
+		}
+		if attr.Min != nil {
+			w.WriteString("\n\nMinimum: " + attr.Min.String())
+		}
+		if attr.Max != nil {
+			w.WriteString("\n\nMaximum: " + attr.Max.String())
+		}
+		if attr.Deprecated != "" {
+			w.WriteString("\n\nDeprecated: " + attr.Deprecated)
+		}
+	}
+	return w.String()
+}
@@ -0,0 +1,129 @@
+package hcllsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/hcl"
+)
+
+type upstream struct {
+	Name string `hcl:"name,label"`
+	Host string `hcl:"host" help:"Host to proxy to."`
+	Port int    `hcl:"port,optional"`
+}
+
+type config struct {
+	Listen    string     `hcl:"listen" help:"Address to listen on."`
+	Timeout   string     `hcl:"timeout,optional" deprecated:"use upstream timeouts"`
+	Upstreams []upstream `hcl:"upstream,block" help:"Proxied servers."`
+}
+
+const source = `// Where to listen.
+listen = "日本:8080"
+
+upstream "api" {
+  host = "api.internal"
+  port = 8080
+}
+`
+
+func TestSymbols(t *testing.T) {
+	doc := Parse([]byte(source))
+	require.NoError(t, doc.Err)
+	require.Equal(t, []DocumentSymbol{
+		{Name: "listen", Detail: `"日本:8080"`, Kind: SymbolKindProperty,
+			Range:          Range{Start: Position{1, 0}, End: Position{1, 18}},
+			SelectionRange: Range{Start: Position{1, 0}, End: Position{1, 6}}},
+		{Name: "upstream", Detail: `"api"`, Kind: SymbolKindStruct,
+			Range:          Range{Start: Position{3, 0}, End: Position{6, 1}},
+			SelectionRange: Range{Start: Position{3, 0}, End: Position{3, 8}},
+			Children: []DocumentSymbol{
+				{Name: "host", Detail: `"api.internal"`, Kind: SymbolKindProperty,
+					Range:          Range{Start: Position{4, 2}, End: Position{4, 23}},
+					SelectionRange: Range{Start: Position{4, 2}, End: Position{4, 6}}},
+				{Name: "port", Detail: "8080", Kind: SymbolKindProperty,
+					Range:          Range{Start: Position{5, 2}, End: Position{5, 13}},
+					SelectionRange: Range{Start: Position{5, 2}, End: Position{5, 6}}},
+			}},
+	}, doc.Symbols())
+}
+
+func TestHover(t *testing.T) {
+	schema := hcl.MustSchema(&config{})
+	doc := Parse([]byte(source))
+	hover := doc.Hover(schema, Position{Line: 4, Character: 3})
+	require.Equal(t, &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```hcl\nhost = string\n```\n\nHost to proxy to."},
+		Range:    Range{Start: Position{4, 2}, End: Position{4, 6}},
+	}, hover)
+	hover = doc.Hover(schema, Position{Line: 3, Character: 0})
+	require.NotNil(t, hover)
+	require.Equal(t, "```hcl\nupstream \"name\" {}\n```\n\nProxied servers.", hover.Contents.Value)
+	// Values have no documentation.
+	require.Nil(t, doc.Hover(schema, Position{Line: 4, Character: 10}))
+}
+
+func TestComplete(t *testing.T) {
+	schema := hcl.MustSchema(&config{})
+	doc := Parse([]byte(source))
+	labels := func(items []CompletionItem) []string {
+		out := []string{}
+		for _, item := range items {
+			out = append(out, item.Label)
+		}
+		return out
+	}
+	items := doc.Complete(schema, Position{Line: 2, Character: 0})
+	require.Equal(t, []string{"timeout", "upstream"}, labels(items))
+	require.Equal(t, CompletionItem{Label: "upstream", Kind: CompletionItemKindStruct, Detail: "block", Documentation: "Proxied servers."}, items[1])
+
+	// The line being typed does not parse.
+	doc = Parse([]byte("upstream \"api\" {\n  ho\n  port = 80\n}\n"))
+	require.Error(t, doc.Err)
+	items = doc.Complete(schema, Position{Line: 1, Character: 4})
+	require.Equal(t, []CompletionItem{
+		{Label: "host", Kind: CompletionItemKindProperty, Detail: "string", Documentation: "Host to proxy to."},
+	}, items)
+}
+
+type rule struct {
+	Name   string `hcl:"name,label"`
+	Action string `hcl:"action"`
+	Rules  []rule `hcl:"rule,block"`
+}
+
+func TestCompleteRecursive(t *testing.T) {
+	schema := hcl.MustSchema(&struct {
+		Rules []rule `hcl:"rule,block"`
+	}{})
+	doc := Parse([]byte("rule \"a\" {\n  rule \"b\" {\n    \n  }\n}\n"))
+	require.NoError(t, doc.Err)
+	items := doc.Complete(schema, Position{Line: 2, Character: 4})
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	require.Equal(t, []string{"action", "rule"}, labels)
+}
+
+func TestDiagnostics(t *testing.T) {
+	doc := Parse([]byte("// ünïcode\r\nname = \"😀\" ?\r\n"))
+	require.Equal(t, []Diagnostic{{
+		Range:    Range{Start: Position{1, 12}, End: Position{1, 13}},
+		Severity: SeverityError,
+		Code:     hcl.CodeSyntax,
+		Source:   "hcl",
+		Message:  `no lexer rules in state "Root" matched input text "?\n"`,
+	}}, doc.Diagnostics())
+
+	doc = Parse([]byte("listen = \":80\"\ntimeout = \"5s\"\nupstream \"api\" {}\n"))
+	require.NoError(t, doc.Err)
+	require.Equal(t, []Diagnostic{
+		{Range: Range{Start: Position{1, 0}, End: Position{1, 7}}, Severity: SeverityWarning, Source: "hcl",
+			Message: `attribute "timeout" is deprecated: use upstream timeouts`},
+		{Range: Range{Start: Position{2, 0}, End: Position{2, 8}}, Severity: SeverityError, Code: hcl.CodeMissingAttribute, Source: "hcl",
+			Message: `upstream[0]: missing required attribute "host"`},
+	}, doc.Validate(&config{}))
+}
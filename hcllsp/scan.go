@@ -0,0 +1,167 @@
+package hcllsp
+
+import (
+	"bytes"
+	"strings"
+)
+
+// The AST only records where nodes start, so the extents of entries are
+// recovered by scanning the source from there. This only needs to be
+// accurate enough to step over tokens, so it is much more forgiving than the
+// lexer.
+
+const punctuation = "[]{}=:,"
+
+// skipSpace returns the offset of the next non-whitespace byte at or after i.
+func skipSpace(src []byte, i int) int {
+	for i < len(src) && isSpace(src[i]) {
+		i++
+	}
+	return i
+}
+
+// skipTrivia returns the offset of the next token at or after i that is not
+// whitespace or a comment.
+func skipTrivia(src []byte, i int) int {
+	for {
+		i = skipSpace(src, i)
+		if !isComment(src, i) {
+			return i
+		}
+		i = tokenEnd(src, i)
+	}
+}
+
+// tokenEnd returns the offset just past the end of the token starting at i.
+func tokenEnd(src []byte, i int) int {
+	if i >= len(src) {
+		return len(src)
+	}
+	rest := src[i:]
+	switch {
+	case rest[0] == '"':
+		j := 1
+		for j < len(rest) && rest[j] != '"' {
+			if rest[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		return minInt(i+j+1, len(src))
+
+	case bytes.HasPrefix(rest, []byte("/*")):
+		end := bytes.Index(rest[2:], []byte("*/"))
+		if end == -1 {
+			return len(src)
+		}
+		return i + 2 + end + 2
+
+	case isComment(src, i):
+		end := bytes.IndexByte(rest, '\n')
+		if end == -1 {
+			return len(src)
+		}
+		return i + end
+
+	case bytes.HasPrefix(rest, []byte("<<")):
+		j := 2
+		if j < len(rest) && rest[j] == '-' {
+			j++
+		}
+		start := j
+		for j < len(rest) && isWord(rest[j]) {
+			j++
+		}
+		delimiter := "\n" + string(rest[start:j])
+		for {
+			end := bytes.Index(rest[j:], []byte(delimiter))
+			if end == -1 {
+				return len(src)
+			}
+			j += end + len(delimiter)
+			if j == len(rest) || !isWord(rest[j]) {
+				return i + j
+			}
+		}
+
+	case strings.IndexByte(punctuation, rest[0]) != -1:
+		return i + 1
+	}
+	j := 1
+	for j < len(rest) && !isSpace(rest[j]) && rest[j] != '"' && strings.IndexByte(punctuation, rest[j]) == -1 {
+		j++
+	}
+	return i + j
+}
+
+// matchEnd returns the offset just past the bracket closing the one at i.
+func matchEnd(src []byte, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch src[i] {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+		i = tokenEnd(src, i)
+		if depth == 0 {
+			return i
+		}
+		i = skipTrivia(src, i)
+	}
+	return len(src)
+}
+
+// valueEnd returns the offset just past the end of the value starting at i.
+func valueEnd(src []byte, i int) int {
+	if i < len(src) && (src[i] == '[' || src[i] == '{') {
+		return matchEnd(src, i)
+	}
+	return tokenEnd(src, i)
+}
+
+// entryExtent returns the offsets of the key of the entry starting at
+// offset, following any comments, and just past the end of the entry.
+//
+// For blocks, the offset of the opening brace is also returned.
+func entryExtent(src []byte, offset int, block bool) (key, brace, end int) {
+	key = skipTrivia(src, offset)
+	i := tokenEnd(src, key)
+	for i = skipTrivia(src, i); i < len(src); i = skipTrivia(src, i) {
+		switch {
+		case block && src[i] == '{':
+			return key, i, matchEnd(src, i)
+		case !block && src[i] == '=':
+			return key, -1, valueEnd(src, skipTrivia(src, i+1))
+		case src[i] == '}':
+			// Malformed, don't run into the parent.
+			return key, -1, i
+		}
+		i = tokenEnd(src, i)
+	}
+	return key, -1, len(src)
+}
+
+func isComment(src []byte, i int) bool {
+	if i >= len(src) {
+		return false
+	}
+	rest := src[i:]
+	return rest[0] == '#' || bytes.HasPrefix(rest, []byte("//")) || bytes.HasPrefix(rest, []byte("/*"))
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func isWord(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
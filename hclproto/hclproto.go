@@ -0,0 +1,466 @@
+// Package hclproto maps HCL to and from protobuf messages.
+//
+// Singular message fields map to blocks, repeated message fields to repeated
+// blocks, and maps with message values to blocks labelled with the map key.
+// Scalar fields map to attributes, with repeated scalars as lists, enums as
+// their value names and bytes as base64 strings.
+package hclproto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/alecthomas/hcl"
+	"github.com/alecthomas/participle"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MarshalProto marshals a protobuf message to HCL.
+func MarshalProto(m proto.Message) ([]byte, error) {
+	ast, err := MarshalProtoToAST(m)
+	if err != nil {
+		return nil, err
+	}
+	return hcl.MarshalAST(ast)
+}
+
+// MarshalProtoToAST marshals a protobuf message to a hcl.AST.
+func MarshalProtoToAST(m proto.Message) (*hcl.AST, error) {
+	entries, err := messageToEntries(m.ProtoReflect())
+	if err != nil {
+		return nil, err
+	}
+	ast := &hcl.AST{Entries: entries}
+	return ast, hcl.AddParentRefs(ast)
+}
+
+// UnmarshalProto unmarshals HCL into a protobuf message.
+func UnmarshalProto(data []byte, m proto.Message) error {
+	ast, err := hcl.ParseBytes(data)
+	if err != nil {
+		return err
+	}
+	return UnmarshalProtoAST(ast, m)
+}
+
+// UnmarshalProtoAST unmarshals a hcl.AST into a protobuf message.
+func UnmarshalProtoAST(ast *hcl.AST, m proto.Message) error {
+	return entriesToMessage(ast.Entries, m.ProtoReflect())
+}
+
+func messageToEntries(msg protoreflect.Message) ([]*hcl.Entry, error) {
+	entries := []*hcl.Entry{}
+	fields := msg.Descriptor().Fields()
+	// Iterate over the descriptor rather than using Range() to keep output in declaration order.
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !msg.Has(fd) {
+			continue
+		}
+		key := string(fd.Name())
+		value := msg.Get(fd)
+		switch {
+		case fd.IsMap():
+			keys := sortedMapKeys(value.Map())
+			if isMessage(fd.MapValue()) {
+				for _, k := range keys {
+					body, err := messageToEntries(value.Map().Get(k).Message())
+					if err != nil {
+						return nil, err
+					}
+					entries = append(entries, &hcl.Entry{Block: &hcl.Block{Name: key, Labels: []string{k.String()}, Body: body}})
+				}
+				continue
+			}
+			out := &hcl.Value{HaveMap: true}
+			for _, k := range keys {
+				mk := k.String()
+				v, err := scalarToValue(fd.MapValue(), value.Map().Get(k))
+				if err != nil {
+					return nil, fmt.Errorf("%s[%s]: %s", key, mk, err)
+				}
+				out.Map = append(out.Map, &hcl.MapEntry{Key: &hcl.Value{Str: &mk}, Value: v})
+			}
+			entries = append(entries, &hcl.Entry{Attribute: &hcl.Attribute{Key: key, Value: out}})
+
+		case fd.IsList():
+			list := value.List()
+			if isMessage(fd) {
+				for j := 0; j < list.Len(); j++ {
+					body, err := messageToEntries(list.Get(j).Message())
+					if err != nil {
+						return nil, err
+					}
+					entries = append(entries, &hcl.Entry{Block: &hcl.Block{Name: key, Body: body}})
+				}
+				continue
+			}
+			out := &hcl.Value{HaveList: true}
+			for j := 0; j < list.Len(); j++ {
+				v, err := scalarToValue(fd, list.Get(j))
+				if err != nil {
+					return nil, fmt.Errorf("%s[%d]: %s", key, j, err)
+				}
+				out.List = append(out.List, v)
+			}
+			entries = append(entries, &hcl.Entry{Attribute: &hcl.Attribute{Key: key, Value: out}})
+
+		case isMessage(fd):
+			body, err := messageToEntries(value.Message())
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &hcl.Entry{Block: &hcl.Block{Name: key, Body: body}})
+
+		default:
+			v, err := scalarToValue(fd, value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", key, err)
+			}
+			entries = append(entries, &hcl.Entry{Attribute: &hcl.Attribute{Key: key, Value: v}})
+		}
+	}
+	return entries, nil
+}
+
+func entriesToMessage(entries []*hcl.Entry, msg protoreflect.Message) error {
+	fields := msg.Descriptor().Fields()
+	for _, entry := range entries {
+		key := entry.Key()
+		fd := fields.ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = fields.ByJSONName(key)
+		}
+		if fd == nil {
+			return participle.Errorf(entry.Pos, "unknown field %q in %s", key, msg.Descriptor().FullName())
+		}
+		switch {
+		case fd.IsMap():
+			m := msg.Mutable(fd).Map()
+			if isMessage(fd.MapValue()) {
+				block, err := expectBlock(entry)
+				if err != nil {
+					return err
+				}
+				if len(block.Labels) != 1 {
+					return participle.Errorf(block.Pos, "block %q expects 1 label, got %d", key, len(block.Labels))
+				}
+				k, err := parseMapKey(fd.MapKey(), block.Labels[0])
+				if err != nil {
+					return participle.Wrapf(block.Pos, err, "invalid key for %q", key)
+				}
+				v := m.NewValue()
+				if err := entriesToMessage(block.Body, v.Message()); err != nil {
+					return err
+				}
+				m.Set(k, v)
+				continue
+			}
+			attr, err := expectAttribute(entry)
+			if err != nil {
+				return err
+			}
+			if !attr.Value.HaveMap {
+				return participle.Errorf(attr.Value.Pos, "expected a map for %q but got %s", key, attr.Value)
+			}
+			for _, me := range attr.Value.Map {
+				if me.Key.Str == nil {
+					return participle.Errorf(me.Key.Pos, "map key must be a string but is %s", me.Key)
+				}
+				k, err := parseMapKey(fd.MapKey(), *me.Key.Str)
+				if err != nil {
+					return participle.Wrapf(me.Key.Pos, err, "invalid key for %q", key)
+				}
+				v, err := valueToScalar(fd.MapValue(), me.Value)
+				if err != nil {
+					return err
+				}
+				m.Set(k, v)
+			}
+
+		case fd.IsList():
+			list := msg.Mutable(fd).List()
+			if isMessage(fd) {
+				block, err := expectBlock(entry)
+				if err != nil {
+					return err
+				}
+				el := list.NewElement()
+				if err := entriesToMessage(block.Body, el.Message()); err != nil {
+					return err
+				}
+				list.Append(el)
+				continue
+			}
+			attr, err := expectAttribute(entry)
+			if err != nil {
+				return err
+			}
+			if !attr.Value.HaveList {
+				return participle.Errorf(attr.Value.Pos, "expected a list for %q but got %s", key, attr.Value)
+			}
+			for _, el := range attr.Value.List {
+				v, err := valueToScalar(fd, el)
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+
+		case isMessage(fd):
+			block, err := expectBlock(entry)
+			if err != nil {
+				return err
+			}
+			if err := entriesToMessage(block.Body, msg.Mutable(fd).Message()); err != nil {
+				return err
+			}
+
+		default:
+			attr, err := expectAttribute(entry)
+			if err != nil {
+				return err
+			}
+			v, err := valueToScalar(fd, attr.Value)
+			if err != nil {
+				return err
+			}
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func expectBlock(entry *hcl.Entry) (*hcl.Block, error) {
+	if entry.Block == nil {
+		return nil, participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", entry.Key())
+	}
+	return entry.Block, nil
+}
+
+func expectAttribute(entry *hcl.Entry) (*hcl.Attribute, error) {
+	if entry.Attribute == nil {
+		return nil, participle.Errorf(entry.Pos, "expected an attribute for %q but got a block", entry.Key())
+	}
+	return entry.Attribute, nil
+}
+
+func isMessage(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind
+}
+
+func sortedMapKeys(m protoreflect.Map) []protoreflect.MapKey {
+	keys := make([]protoreflect.MapKey, 0, m.Len())
+	m.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+func parseMapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(s)
+
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfBool(b)
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfInt32(int32(n))
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfInt64(n)
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfUint32(uint32(n))
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, err
+		}
+		v = protoreflect.ValueOfUint64(n)
+
+	default:
+		return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %s", fd.Kind())
+	}
+	return v.MapKey(), nil
+}
+
+func scalarToValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (*hcl.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b := hcl.Bool(v.Bool())
+		return &hcl.Value{Bool: &b}, nil
+
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			s := string(ev.Name())
+			return &hcl.Value{Str: &s}, nil
+		}
+		return &hcl.Value{Number: big.NewFloat(0).SetInt64(int64(v.Enum()))}, nil
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &hcl.Value{Number: big.NewFloat(0).SetInt64(v.Int())}, nil
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &hcl.Value{Number: big.NewFloat(0).SetUint64(v.Uint())}, nil
+
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f := v.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("can't represent %v in HCL", f)
+		}
+		return &hcl.Value{Number: big.NewFloat(f)}, nil
+
+	case protoreflect.StringKind:
+		s := v.String()
+		return &hcl.Value{Str: &s}, nil
+
+	case protoreflect.BytesKind:
+		s := base64.StdEncoding.EncodeToString(v.Bytes())
+		return &hcl.Value{Str: &s}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+}
+
+func valueToScalar(fd protoreflect.FieldDescriptor, v *hcl.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if v.Bool == nil {
+			return protoreflect.Value{}, participle.Errorf(v.Pos, "expected a bool for %q but got %s", fd.Name(), v)
+		}
+		return protoreflect.ValueOfBool(bool(*v.Bool)), nil
+
+	case protoreflect.EnumKind:
+		if v.Str != nil {
+			ev := fd.Enum().Values().ByName(protoreflect.Name(*v.Str))
+			if ev == nil {
+				return protoreflect.Value{}, participle.Errorf(v.Pos, "invalid value %s for enum %s", v, fd.Enum().FullName())
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := intValue(fd, v, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := intValue(fd, v, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := intValue(fd, v, math.MinInt64, math.MaxInt64)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt64(n), nil
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := uintValue(fd, v, math.MaxUint32)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := uintValue(fd, v, math.MaxUint64)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint64(n), nil
+
+	case protoreflect.FloatKind:
+		if v.Number == nil {
+			return protoreflect.Value{}, participle.Errorf(v.Pos, "expected a number for %q but got %s", fd.Name(), v)
+		}
+		f, _ := v.Number.Float32()
+		return protoreflect.ValueOfFloat32(f), nil
+
+	case protoreflect.DoubleKind:
+		if v.Number == nil {
+			return protoreflect.Value{}, participle.Errorf(v.Pos, "expected a number for %q but got %s", fd.Name(), v)
+		}
+		f, _ := v.Number.Float64()
+		return protoreflect.ValueOfFloat64(f), nil
+
+	case protoreflect.StringKind:
+		switch {
+		case v.Str != nil:
+			return protoreflect.ValueOfString(*v.Str), nil
+		case v.HeredocDelimiter != "":
+			return protoreflect.ValueOfString(v.GetHeredoc()), nil
+		default:
+			return protoreflect.Value{}, participle.Errorf(v.Pos, "expected a string for %q but got %s", fd.Name(), v)
+		}
+
+	case protoreflect.BytesKind:
+		if v.Str == nil {
+			return protoreflect.Value{}, participle.Errorf(v.Pos, "expected a base64 string for %q but got %s", fd.Name(), v)
+		}
+		b, err := base64.StdEncoding.DecodeString(*v.Str)
+		if err != nil {
+			return protoreflect.Value{}, participle.Wrapf(v.Pos, err, "invalid bytes for %q", fd.Name())
+		}
+		return protoreflect.ValueOfBytes(b), nil
+
+	default:
+		return protoreflect.Value{}, participle.Errorf(v.Pos, "unsupported field kind %s for %q", fd.Kind(), fd.Name())
+	}
+}
+
+func intValue(fd protoreflect.FieldDescriptor, v *hcl.Value, min, max int64) (int64, error) {
+	if v.Number == nil {
+		return 0, participle.Errorf(v.Pos, "expected a number for %q but got %s", fd.Name(), v)
+	}
+	n, acc := v.Number.Int64()
+	if acc != big.Exact || n < min || n > max {
+		return 0, participle.Errorf(v.Pos, "%s is out of range for %q", v, fd.Name())
+	}
+	return n, nil
+}
+
+func uintValue(fd protoreflect.FieldDescriptor, v *hcl.Value, max uint64) (uint64, error) {
+	if v.Number == nil {
+		return 0, participle.Errorf(v.Pos, "expected a number for %q but got %s", fd.Name(), v)
+	}
+	n, acc := v.Number.Uint64()
+	if acc != big.Exact || n > max {
+		return 0, participle.Errorf(v.Pos, "%s is out of range for %q", v, fd.Name())
+	}
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package hclproto
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const testHCL = `name = "test.proto"
+package = "test"
+dependency = ["a.proto"]
+
+message_type {
+  name = "Msg"
+
+  field {
+    name = "id"
+    number = 1
+    type = "TYPE_STRING"
+  }
+}
+`
+
+func testProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test.proto"),
+		Package:    proto.String("test"),
+		Dependency: []string{"a.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:   proto.String("id"),
+				Number: proto.Int32(1),
+				Type:   descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}},
+		}},
+	}
+}
+
+func TestMarshalProto(t *testing.T) {
+	data, err := MarshalProto(testProto())
+	require.NoError(t, err)
+	require.Equal(t, testHCL, string(data))
+}
+
+func TestUnmarshalProto(t *testing.T) {
+	actual := &descriptorpb.FileDescriptorProto{}
+	err := UnmarshalProto([]byte(testHCL), actual)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(testProto(), actual))
+}
+
+func TestUnmarshalProtoErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		hcl  string
+		fail string
+	}{
+		{name: "UnknownField",
+			hcl:  `nope = 1`,
+			fail: `1:1: unknown field "nope" in google.protobuf.FileDescriptorProto`},
+		{name: "InvalidEnum",
+			hcl:  `message_type { field { type = "TYPE_NOPE" } }`,
+			fail: `1:31: invalid value "TYPE_NOPE" for enum google.protobuf.FieldDescriptorProto.Type`},
+		{name: "OutOfRange",
+			hcl:  `message_type { field { number = 3000000000 } }`,
+			fail: `1:33: 3000000000 is out of range for "number"`},
+		{name: "BlockAsAttribute",
+			hcl:  `message_type = "Msg"`,
+			fail: `1:1: expected a block for "message_type" but got an attribute`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := UnmarshalProto([]byte(strings.TrimSpace(test.hcl)), &descriptorpb.FileDescriptorProto{})
+			require.EqualError(t, err, test.fail)
+		})
+	}
+}
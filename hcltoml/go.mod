@@ -0,0 +1,12 @@
+module github.com/alecthomas/hcl/hcltoml
+
+go 1.14
+
+require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/alecthomas/hcl v0.1.1-0.20200723030810-fa56972bbf92
+	github.com/alecthomas/participle v0.6.1-0.20200911005820-318127ca69ac
+	github.com/stretchr/testify v1.4.0
+)
+
+replace github.com/alecthomas/hcl => ../
@@ -0,0 +1,299 @@
+// Package hcltoml converts between TOML documents and HCL ASTs.
+//
+// Tables map to blocks and arrays of tables map to repeated blocks. When
+// converting HCL to TOML, block labels become nested tables, in the same
+// manner as the HCL JSON encoding.
+package hcltoml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/alecthomas/hcl"
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// FromTOML converts a TOML document to a HCL AST.
+//
+// Keys retain the order in which they appear in the TOML document. TOML
+// date-times are converted to RFC3339 strings.
+func FromTOML(data []byte) (*hcl.AST, error) {
+	doc := map[string]interface{}{}
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, err
+	}
+	order := map[string]int{}
+	for i, key := range md.Keys() {
+		path := strings.Join(key, "\x00")
+		if _, ok := order[path]; !ok {
+			order[path] = i
+		}
+	}
+	entries, err := tableToEntries(nil, doc, order)
+	if err != nil {
+		return nil, err
+	}
+	ast := &hcl.AST{Entries: entries}
+	return ast, hcl.AddParentRefs(ast)
+}
+
+// ToTOML converts a HCL AST to a TOML document.
+//
+// Comments are not preserved.
+func ToTOML(ast *hcl.AST) ([]byte, error) {
+	doc, err := entriesToTable(ast.Entries)
+	if err != nil {
+		return nil, err
+	}
+	w := &bytes.Buffer{}
+	if err := toml.NewEncoder(w).Encode(doc); err != nil {
+		return nil, err
+	}
+	return w.Bytes(), nil
+}
+
+func tableToEntries(path []string, table map[string]interface{}, order map[string]int) ([]*hcl.Entry, error) {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	position := func(key string) int {
+		if i, ok := order[strings.Join(append(path, key), "\x00")]; ok {
+			return i
+		}
+		return math.MaxInt32
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, pj := position(keys[i]), position(keys[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return keys[i] < keys[j]
+	})
+	entries := make([]*hcl.Entry, 0, len(keys))
+	for _, key := range keys {
+		subpath := append(append([]string{}, path...), key)
+		var tables []map[string]interface{}
+		switch value := table[key].(type) {
+		case map[string]interface{}:
+			tables = []map[string]interface{}{value}
+		case []map[string]interface{}:
+			tables = value
+		case []interface{}:
+			tables = asTables(value)
+		}
+		if tables == nil {
+			value, err := tomlToValue(table[key])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", strings.Join(subpath, "."), err)
+			}
+			entries = append(entries, &hcl.Entry{Attribute: &hcl.Attribute{Key: key, Value: value}})
+			continue
+		}
+		for _, t := range tables {
+			body, err := tableToEntries(subpath, t, order)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, &hcl.Entry{Block: &hcl.Block{Name: key, Body: body}})
+		}
+	}
+	return entries, nil
+}
+
+// asTables returns the elements of an array if they are all tables, or nil.
+func asTables(array []interface{}) []map[string]interface{} {
+	if len(array) == 0 {
+		return nil
+	}
+	tables := make([]map[string]interface{}, 0, len(array))
+	for _, el := range array {
+		table, ok := el.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func tomlToValue(v interface{}) (*hcl.Value, error) {
+	switch v := v.(type) {
+	case string:
+		return &hcl.Value{Str: &v}, nil
+
+	case int64:
+		return &hcl.Value{Number: big.NewFloat(0).SetInt64(v)}, nil
+
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("can't represent %v in HCL", v)
+		}
+		return &hcl.Value{Number: big.NewFloat(v)}, nil
+
+	case bool:
+		b := hcl.Bool(v)
+		return &hcl.Value{Bool: &b}, nil
+
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		return &hcl.Value{Str: &s}, nil
+
+	case []interface{}:
+		out := &hcl.Value{HaveList: true}
+		for _, el := range v {
+			value, err := tomlToValue(el)
+			if err != nil {
+				return nil, err
+			}
+			out.List = append(out.List, value)
+		}
+		return out, nil
+
+	case []map[string]interface{}:
+		out := &hcl.Value{HaveList: true}
+		for _, el := range v {
+			value, err := tomlToValue(el)
+			if err != nil {
+				return nil, err
+			}
+			out.List = append(out.List, value)
+		}
+		return out, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := &hcl.Value{HaveMap: true}
+		for _, key := range keys {
+			value, err := tomlToValue(v[key])
+			if err != nil {
+				return nil, err
+			}
+			key := key
+			out.Map = append(out.Map, &hcl.MapEntry{Key: &hcl.Value{Str: &key}, Value: value})
+		}
+		return out, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported TOML value of type %T", v)
+	}
+}
+
+func entriesToTable(entries []*hcl.Entry) (map[string]interface{}, error) {
+	table := map[string]interface{}{}
+	for _, entry := range entries {
+		if entry.Attribute != nil {
+			key := entry.Attribute.Key
+			if _, ok := table[key]; ok {
+				return nil, participle.Errorf(entry.Pos, "duplicate key %q", key)
+			}
+			value, err := valueToTOML(entry.Attribute.Value)
+			if err != nil {
+				return nil, err
+			}
+			table[key] = value
+			continue
+		}
+		block := entry.Block
+		body, err := entriesToTable(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		path := append([]string{block.Name}, block.Labels...)
+		if err := insertTable(table, path, body, block.Pos); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+// insertTable inserts body at path, converting existing tables at the same
+// path into arrays of tables.
+func insertTable(table map[string]interface{}, path []string, body map[string]interface{}, pos lexer.Position) error {
+	for _, key := range path[:len(path)-1] {
+		switch next := table[key].(type) {
+		case nil:
+			child := map[string]interface{}{}
+			table[key] = child
+			table = child
+		case map[string]interface{}:
+			table = next
+		default:
+			return participle.Errorf(pos, "%q conflicts with an existing key", strings.Join(path, "."))
+		}
+	}
+	key := path[len(path)-1]
+	switch existing := table[key].(type) {
+	case nil:
+		table[key] = body
+	case map[string]interface{}:
+		table[key] = []map[string]interface{}{existing, body}
+	case []map[string]interface{}:
+		table[key] = append(existing, body)
+	default:
+		return participle.Errorf(pos, "%q conflicts with an existing key", strings.Join(path, "."))
+	}
+	return nil
+}
+
+func valueToTOML(v *hcl.Value) (interface{}, error) {
+	switch {
+	case v.Str != nil:
+		return *v.Str, nil
+
+	case v.HeredocDelimiter != "":
+		return v.GetHeredoc(), nil
+
+	case v.Number != nil:
+		if v.Number.IsInt() {
+			if n, acc := v.Number.Int64(); acc == big.Exact {
+				return n, nil
+			}
+		}
+		f, _ := v.Number.Float64()
+		return f, nil
+
+	case v.Bool != nil:
+		return bool(*v.Bool), nil
+
+	case v.HaveList:
+		out := make([]interface{}, 0, len(v.List))
+		for _, el := range v.List {
+			value, err := valueToTOML(el)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+
+	case v.HaveMap:
+		out := make(map[string]interface{}, len(v.Map))
+		for _, entry := range v.Map {
+			if entry.Key.Str == nil {
+				return nil, participle.Errorf(entry.Key.Pos, "map key must be a string but is %s", entry.Key)
+			}
+			value, err := valueToTOML(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[*entry.Key.Str] = value
+		}
+		return out, nil
+
+	default:
+		return nil, participle.Errorf(v.Pos, "can't convert %s to TOML", v)
+	}
+}
@@ -0,0 +1,104 @@
+package hcltoml
+
+import (
+	"testing"
+
+	"github.com/alecthomas/hcl"
+	"github.com/stretchr/testify/require"
+)
+
+const tomlSource = `
+title = "Example"
+ports = [8000, 8001]
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00Z
+
+[[server]]
+host = "alpha"
+weight = 1.5
+
+[[server]]
+host = "beta"
+`
+
+func TestFromTOML(t *testing.T) {
+	ast, err := FromTOML([]byte(tomlSource))
+	require.NoError(t, err)
+	actual, err := hcl.MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `title = "Example"
+ports = [8000, 8001]
+
+owner {
+  name = "Tom"
+  dob = "1979-05-27T07:32:00Z"
+}
+
+server {
+  host = "alpha"
+  weight = 1.5
+}
+
+server {
+  host = "beta"
+}
+`, string(actual))
+}
+
+func TestToTOML(t *testing.T) {
+	ast, err := hcl.ParseString(`
+		title = "Example"
+		service "http" {
+			port = 80
+		}
+		service "https" {
+			port = 443
+		}
+		server {
+			host = "alpha"
+		}
+		server {
+			host = "beta"
+		}
+	`)
+	require.NoError(t, err)
+	data, err := ToTOML(ast)
+	require.NoError(t, err)
+	// Round-trip back to HCL, as TOML has no equivalent of block labels.
+	ast, err = FromTOML(data)
+	require.NoError(t, err)
+	actual, err := hcl.MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `title = "Example"
+
+server {
+  host = "alpha"
+}
+
+server {
+  host = "beta"
+}
+
+service {
+  http {
+    port = 80
+  }
+
+  https {
+    port = 443
+  }
+}
+`, string(actual))
+}
+
+func TestToTOMLConflict(t *testing.T) {
+	ast, err := hcl.ParseString(`
+		service = "a"
+		service "http" {}
+	`)
+	require.NoError(t, err)
+	_, err = ToTOML(ast)
+	require.EqualError(t, err, `3:3: "service.http" conflicts with an existing key`)
+}
@@ -0,0 +1,142 @@
+// Package hcltypes provides field types for common values in configuration,
+// such as IP addresses and URLs, that are validated when unmarshalled.
+//
+// Each type implements encoding.TextMarshaler and encoding.TextUnmarshaler,
+// so is represented as a string in HCL, eg.
+//
+//	type Config struct {
+//		Listen hcltypes.IP     `hcl:"listen"`
+//		Allow  []hcltypes.CIDR `hcl:"allow"`
+//	}
+package hcltypes
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+)
+
+// IP is an IPv4 or IPv6 address, eg. "10.0.0.1" or "::1".
+type IP struct {
+	net.IP
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (i IP) MarshalText() ([]byte, error) {
+	if i.IP == nil {
+		return []byte{}, nil
+	}
+	return []byte(i.IP.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *IP) UnmarshalText(text []byte) error {
+	ip := net.ParseIP(string(text))
+	if ip == nil {
+		return fmt.Errorf("invalid IP address %q", text)
+	}
+	i.IP = ip
+	return nil
+}
+
+// CIDR is an IP network in CIDR notation, eg. "10.0.0.0/8".
+//
+// Host bits are not retained, so "10.1.2.3/8" is the network "10.0.0.0/8".
+type CIDR struct {
+	net.IPNet
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c CIDR) MarshalText() ([]byte, error) {
+	if c.IP == nil {
+		return []byte{}, nil
+	}
+	return []byte(c.IPNet.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *CIDR) UnmarshalText(text []byte) error {
+	_, network, err := net.ParseCIDR(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid CIDR %q, expected an address and prefix length such as \"10.0.0.0/8\"", text)
+	}
+	c.IPNet = *network
+	return nil
+}
+
+// URL is an absolute URL, with a scheme and host, eg. "https://example.com/api".
+type URL struct {
+	url.URL
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u URL) MarshalText() ([]byte, error) {
+	return []byte(u.URL.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *URL) UnmarshalText(text []byte) error {
+	parsed, err := url.Parse(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid URL %q", text)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return fmt.Errorf("invalid URL %q, expected an absolute URL such as \"https://example.com\"", text)
+	}
+	u.URL = *parsed
+	return nil
+}
+
+// Regexp is a regular expression in the syntax accepted by the regexp package.
+type Regexp struct {
+	*regexp.Regexp
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Regexp) MarshalText() ([]byte, error) {
+	if r.Regexp == nil {
+		return []byte{}, nil
+	}
+	return []byte(r.Regexp.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *Regexp) UnmarshalText(text []byte) error {
+	re, err := regexp.Compile(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid regular expression %q: %s", text, unwrapRegexpError(err))
+	}
+	r.Regexp = re
+	return nil
+}
+
+// unwrapRegexpError returns the description of a regexp syntax error, without
+// the expression, which is already included in our error.
+func unwrapRegexpError(err error) string {
+	if serr, ok := err.(*syntax.Error); ok {
+		return serr.Code.String()
+	}
+	return err.Error()
+}
+
+// EmailAddress is a bare email address, eg. "ops@example.com", without a
+// display name.
+type EmailAddress string
+
+// MarshalText implements encoding.TextMarshaler.
+func (e EmailAddress) MarshalText() ([]byte, error) {
+	return []byte(e), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (e *EmailAddress) UnmarshalText(text []byte) error {
+	address, err := mail.ParseAddress(string(text))
+	if err != nil || address.Address != string(text) {
+		return fmt.Errorf("invalid email address %q", text)
+	}
+	*e = EmailAddress(text)
+	return nil
+}
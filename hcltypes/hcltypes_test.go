@@ -0,0 +1,76 @@
+package hcltypes
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/alecthomas/hcl"
+)
+
+type config struct {
+	Listen  IP           `hcl:"listen"`
+	Allow   []CIDR       `hcl:"allow"`
+	Webhook URL          `hcl:"webhook"`
+	Match   Regexp       `hcl:"match"`
+	Owner   EmailAddress `hcl:"owner"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	source := `listen = "::1"
+allow = ["10.0.0.0/8", "192.168.1.0/24"]
+webhook = "https://example.com/hooks?id=1"
+match = "^api-[0-9]+$"
+owner = "ops@example.com"
+`
+	actual := &config{}
+	err := hcl.Unmarshal([]byte(source), actual)
+	require.NoError(t, err)
+	require.Equal(t, "::1", actual.Listen.String())
+	require.True(t, actual.Allow[0].Contains(net.ParseIP("10.1.2.3")))
+	require.False(t, actual.Allow[1].Contains(net.ParseIP("10.1.2.3")))
+	require.Equal(t, "example.com", actual.Webhook.Host)
+	require.True(t, actual.Match.MatchString("api-123"))
+	require.Equal(t, EmailAddress("ops@example.com"), actual.Owner)
+
+	data, err := hcl.Marshal(actual)
+	require.NoError(t, err)
+	require.Equal(t, source, string(data))
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		value string
+		fail  string
+	}{
+		{"IP", "listen", `"10.0.0.256"`, `invalid IP address "10.0.0.256"`},
+		{"NotAString", "listen", `10`, `expected a string but got 10`},
+		{"CIDR", "allow", `["10.0.0.0"]`, `invalid CIDR "10.0.0.0", expected an address and prefix length such as "10.0.0.0/8"`},
+		{"URL", "webhook", `"example.com/hooks"`, `invalid URL "example.com/hooks", expected an absolute URL such as "https://example.com"`},
+		{"Regexp", "match", `"api-(["`, `invalid regular expression "api-([": missing closing ]`},
+		{"EmailAddress", "owner", `"Ops <ops@example.com>"`, `invalid email address "Ops <ops@example.com>"`},
+	}
+	for _, test := range tests {
+		// nolint: scopelint
+		t.Run(test.name, func(t *testing.T) {
+			fields := map[string]string{
+				"listen":  `"::1"`,
+				"allow":   `[]`,
+				"webhook": `"https://example.com"`,
+				"match":   `"."`,
+				"owner":   `"ops@example.com"`,
+			}
+			fields[test.field] = test.value
+			source := ""
+			for _, key := range []string{"listen", "allow", "webhook", "match", "owner"} {
+				source += key + " = " + fields[key] + "\n"
+			}
+			err := hcl.Unmarshal([]byte(source), &config{})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.fail)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Package hclwasm exposes parsing, formatting and validation of HCL to
+// JavaScript, so that browsers can validate configuration client-side.
+//
+// Its functions take HCL source and return a JSON encoded Result, and are
+// made callable from JavaScript by Register in a GOOS=js GOARCH=wasm build,
+// eg.
+//
+//	func main() {
+//		hclwasm.Register(func() interface{} { return &Config{} })
+//		select {}
+//	}
+//
+// after which JavaScript can call hcl.validate(source).
+package hclwasm
+
+import (
+	"encoding/json"
+
+	"github.com/alecthomas/hcl"
+	"github.com/alecthomas/hcl/hclhttp"
+)
+
+// Result of a call, as returned JSON encoded.
+type Result struct {
+	// Valid is false if there are any diagnostics with error severity.
+	Valid bool `json:"valid"`
+	// AST of the document, from Parse.
+	AST *hcl.AST `json:"ast,omitempty"`
+	// Formatted document, from Format.
+	Formatted   string               `json:"formatted,omitempty"`
+	Diagnostics []hclhttp.Diagnostic `json:"diagnostics"`
+}
+
+// Parse source, returning its AST.
+func Parse(source string) string {
+	ast, err := hcl.ParseString(source)
+	if err != nil {
+		return encode(Result{Diagnostics: []hclhttp.Diagnostic{hclhttp.ErrorDiagnostic(hclhttp.SeverityError, err)}})
+	}
+	return encode(Result{AST: ast})
+}
+
+// Format source in canonical form.
+func Format(source string) string {
+	ast, err := hcl.ParseString(source)
+	if err != nil {
+		return encode(Result{Diagnostics: []hclhttp.Diagnostic{hclhttp.ErrorDiagnostic(hclhttp.SeverityError, err)}})
+	}
+	formatted, err := hcl.MarshalAST(ast)
+	if err != nil {
+		return encode(Result{Diagnostics: []hclhttp.Diagnostic{hclhttp.ErrorDiagnostic(hclhttp.SeverityError, err)}})
+	}
+	return encode(Result{Formatted: string(formatted)})
+}
+
+// Validate source against the pointers to structs returned by newValue,
+// reporting all problems found, as with hclhttp.Handler.
+func Validate(source string, newValue func() interface{}, options ...hcl.MarshalOption) string {
+	handler := hclhttp.NewHandler(newValue, hclhttp.MarshalOptions(options...))
+	return encode(Result{Diagnostics: handler.Validate([]byte(source))})
+}
+
+// encode result as JSON, setting Valid.
+func encode(result Result) string {
+	if result.Diagnostics == nil {
+		result.Diagnostics = []hclhttp.Diagnostic{}
+	}
+	result.Valid = true
+	for _, diagnostic := range result.Diagnostics {
+		if diagnostic.Severity == hclhttp.SeverityError {
+			result.Valid = false
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		data, _ = json.Marshal(Result{Diagnostics: []hclhttp.Diagnostic{{Severity: hclhttp.SeverityError, Message: err.Error()}}})
+	}
+	return string(data)
+}
@@ -0,0 +1,44 @@
+package hclwasm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type config struct {
+	Name string `hcl:"name"`
+	Port int    `hcl:"port"`
+}
+
+func decode(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+	out := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(data), &out))
+	return out
+}
+
+func TestParse(t *testing.T) {
+	result := decode(t, Parse(`name = "app"`))
+	require.Equal(t, true, result["valid"])
+	require.NotNil(t, result["ast"])
+
+	result = decode(t, Parse(`name = `))
+	require.Equal(t, false, result["valid"])
+	require.Nil(t, result["ast"])
+	require.Len(t, result["diagnostics"], 1)
+}
+
+func TestFormat(t *testing.T) {
+	require.JSONEq(t, `{"valid": true, "formatted": "name = \"app\"\nport = 80\n", "diagnostics": []}`, Format(`name="app"
+port=80`))
+}
+
+func TestValidate(t *testing.T) {
+	newConfig := func() interface{} { return &config{} }
+	require.JSONEq(t, `{"valid": true, "diagnostics": []}`, Validate("name = \"app\"\nport = 80\n", newConfig))
+	require.JSONEq(t, `{"valid": false, "diagnostics": [
+		{"severity": "error", "code": "HCL0005", "message": "port: expected a number but got \"80\"", "pos": {"line": 2, "column": 8, "offset": 20}}
+	]}`, Validate("name = \"app\"\nport = \"80\"\n", newConfig))
+}
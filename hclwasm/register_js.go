@@ -0,0 +1,36 @@
+//go:build js && wasm
+// +build js,wasm
+
+package hclwasm
+
+import (
+	"syscall/js"
+
+	"github.com/alecthomas/hcl"
+)
+
+// Register exports parse, format and validate functions to JavaScript, as the
+// global object "hcl", validating documents against the pointers to structs
+// returned by newValue.
+//
+// Each function takes HCL source as a string and returns a JSON encoded
+// Result.
+func Register(newValue func() interface{}, options ...hcl.MarshalOption) {
+	source := func(args []js.Value) string {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return ""
+		}
+		return args[0].String()
+	}
+	exports := js.Global().Get("Object").New()
+	exports.Set("parse", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return Parse(source(args))
+	}))
+	exports.Set("format", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return Format(source(args))
+	}))
+	exports.Set("validate", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return Validate(source(args), newValue, options...)
+	}))
+	js.Global().Set("hcl", exports)
+}
@@ -0,0 +1,93 @@
+package hcl
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/alecthomas/participle/lexer/stateful"
+)
+
+// IdentifierProfile selects the characters allowed in identifiers, ie.
+// attribute keys, block names, and bare labels and strings, when parsing.
+type IdentifierProfile int
+
+const (
+	// IdentifiersASCII allows identifiers starting with an ASCII letter,
+	// followed by ASCII letters, digits and underscores, with single dashes
+	// between them, eg. health-check. This is the default.
+	IdentifiersASCII IdentifierProfile = iota
+	// IdentifiersUnicode allows identifiers starting with a Unicode letter or
+	// an underscore, followed by any number of Unicode letters, digits,
+	// underscores and dashes, as in HCL2 native syntax, eg. größe or
+	// _health--check.
+	IdentifiersUnicode
+)
+
+func (p IdentifierProfile) String() string {
+	switch p {
+	case IdentifiersASCII:
+		return "ascii"
+	case IdentifiersUnicode:
+		return "unicode"
+	default:
+		return fmt.Sprintf("IdentifierProfile(%d)", int(p))
+	}
+}
+
+// Patterns of the Ident lexer rule for each profile.
+var identifierPatterns = map[IdentifierProfile]string{
+	IdentifiersASCII:   `\b[[:alpha:]]\w*(-\w+)*\b`,
+	IdentifiersUnicode: `[\p{L}_][\p{L}\p{M}\p{N}_-]*`,
+}
+
+// Parsers for profiles other than the default, built on first use.
+var (
+	unicodeParserOnce sync.Once
+	unicodeParser     *participle.Parser
+)
+
+// WithIdentifierProfile parses identifiers according to profile, eg. to
+// accept the Unicode identifiers of existing HCL2 configuration.
+//
+// Entries keep their identifiers as written, so documents parsed with a
+// profile should be parsed with the same profile once printed.
+func WithIdentifierProfile(profile IdentifierProfile) ParseOption {
+	if _, ok := identifierPatterns[profile]; !ok {
+		panic("unknown identifier profile " + profile.String())
+	}
+	return func(options *parseOptions) {
+		options.identifiers = profile
+	}
+}
+
+// parser returns the document parser for the identifier profile of o.
+func (o *parseOptions) parser() *participle.Parser {
+	if o.identifiers != IdentifiersUnicode {
+		return parser
+	}
+	unicodeParserOnce.Do(func() {
+		unicodeParser = participle.MustBuild(&AST{}, profileParserOptions(IdentifiersUnicode)...)
+	})
+	return unicodeParser
+}
+
+// profileParserOptions returns the parser options with the Ident rule of the
+// lexer replaced by that of profile. Other rules are unchanged, so that token
+// types are shared with the default lexer.
+func profileParserOptions(profile IdentifierProfile) []participle.Option {
+	rules := make([]stateful.Rule, len(rootLexerRules))
+	copy(rules, rootLexerRules)
+	for i, rule := range rules {
+		if rule.Name == "Ident" {
+			rules[i].Pattern = identifierPatterns[profile]
+		}
+	}
+	def := lexer.Must(stateful.New(stateful.Rules{
+		"Root":    rules,
+		"Heredoc": heredocLexerRules,
+	}))
+	options := append([]participle.Option{}, parserOptions...)
+	return append(options, participle.Lexer(commentLexerDefinition{def}))
+}
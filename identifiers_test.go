@@ -0,0 +1,36 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIdentifierProfile(t *testing.T) {
+	src := "größe = 1\n_health--check \"web\" {\n  port-number = 80\n}\n"
+	_, err := ParseString(src)
+	require.Error(t, err)
+
+	ast, err := ParseString(src, WithIdentifierProfile(IdentifiersUnicode))
+	require.NoError(t, err)
+	require.Equal(t, "größe", ast.Entries[0].Key())
+	require.Equal(t, "_health--check", ast.Entries[1].Key())
+	require.Equal(t, "port-number", ast.Entries[1].Block.Body[0].Key())
+
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, src, string(data))
+
+	// Limits are enforced with the same profile.
+	_, err = ParseString(src, WithIdentifierProfile(IdentifiersUnicode), WithMaxEntries(2))
+	require.EqualError(t, err, "3:15: maximum of 2 entries exceeded")
+
+	// Dashes are allowed between words by default.
+	ast, err = ParseString("health-check {\n  max-retries = 3\n}\n")
+	require.NoError(t, err)
+	require.Equal(t, "health-check", ast.Entries[0].Key())
+}
+
+func TestUnknownIdentifierProfile(t *testing.T) {
+	require.Panics(t, func() { WithIdentifierProfile(IdentifierProfile(42)) })
+}
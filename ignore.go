@@ -0,0 +1,105 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ignoreDirective starts a comment suppressing diagnostics for the entry it
+// is attached to, eg.
+//
+//	# hcl:ignore unknown-attribute
+//	legacy_key = true
+const ignoreDirective = "hcl:ignore"
+
+// ignoreRules maps the rule names accepted by hcl:ignore comments to the codes
+// of the diagnostics they suppress. Codes themselves are also accepted, eg.
+// "hcl:ignore HCL0001".
+var ignoreRules = map[string]string{
+	"unknown-attribute": CodeIgnoredAttribute,
+	"unknown-block":     CodeIgnoredBlock,
+	"missing-attribute": CodeMissingAttribute,
+	"missing-block":     CodeMissingBlock,
+	"mismatch":          CodeMismatch,
+}
+
+// WithSuppressedCallback calls fn for each diagnostic suppressed by an
+// hcl:ignore comment, rather than discarding it, eg. to audit suppressions.
+//
+// Suppressed unknown attributes and blocks are reported by Unmarshal, and all
+// suppressed diagnostics by CheckCompatibility.
+func WithSuppressedCallback(fn func(diagnostic Diagnostic)) MarshalOption {
+	return func(options *marshalOptions) {
+		options.suppressed = fn
+	}
+}
+
+// entryIgnores returns the rules of the hcl:ignore comments of entry, with an
+// empty rule for a comment without rules, which suppresses everything.
+func entryIgnores(entry *Entry) []string {
+	var comments []string
+	switch {
+	case entry.Attribute != nil:
+		comments = append(append(comments, entry.Attribute.Comments...), entry.Attribute.TrailingComments...)
+	case entry.Block != nil:
+		comments = entry.Block.Comments
+	}
+	var rules []string
+	for _, comment := range comments {
+		comment = strings.TrimSpace(comment)
+		if !strings.HasPrefix(comment, ignoreDirective) {
+			continue
+		}
+		rest := comment[len(ignoreDirective):]
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		// Rules are the first word, comma separated, and any other words are
+		// the reason for the suppression.
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			rules = append(rules, "")
+			continue
+		}
+		rules = append(rules, strings.Split(fields[0], ",")...)
+	}
+	return rules
+}
+
+// isSuppressed returns true if an hcl:ignore comment on entry, or on a block
+// enclosing it, suppresses diagnostics with code.
+func isSuppressed(entry *Entry, code string) bool {
+	for entry != nil {
+		for _, rule := range entryIgnores(entry) {
+			if rule == "" || rule == code || ignoreRules[rule] == code {
+				return true
+			}
+		}
+		block, ok := entry.Parent.(*Block)
+		if !ok {
+			return false
+		}
+		entry, _ = block.Parent.(*Entry)
+	}
+	return false
+}
+
+// ignoredDiagnostic returns the diagnostic for entry not being decoded into any
+// field, as reported by CheckCompatibility.
+func ignoredDiagnostic(path string, entry *Entry, known []string, messages MessageCatalog) Diagnostic {
+	key := entry.Key()
+	suggestion, _ := suggest(key, known)
+	diagnostic := Diagnostic{
+		Kind: DiagnosticIgnored,
+		Pos:  entry.Pos,
+		Path: path,
+		Code: CodeIgnoredAttribute,
+		Msg:  fmt.Sprintf("attribute %q would be ignored%s", key, didYouMean(key, known)),
+		Args: []interface{}{key, suggestion},
+	}
+	if entry.Block != nil {
+		diagnostic.Code = CodeIgnoredBlock
+		diagnostic.Msg = fmt.Sprintf("block %q would be ignored%s", key, didYouMean(key, known))
+	}
+	return diagnostic.Localize(messages)
+}
@@ -0,0 +1,75 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreComments(t *testing.T) {
+	type server struct {
+		Port int `hcl:"port"`
+	}
+	type config struct {
+		Host   string  `hcl:"host"`
+		Server *server `hcl:"server,block"`
+	}
+	data := []byte(`
+host = "localhost"
+# hcl:ignore unknown-attribute consumed by the legacy loader
+legacy = true
+extra = 1 // hcl:ignore unknown-block
+// hcl:ignore
+server {
+  port = 80
+  debug = true
+}
+`)
+	var suppressed []string
+	var out config
+	err := Unmarshal(data, &out)
+	require.EqualError(t, err, `5:1: found extra fields "extra"`)
+
+	data = []byte(`
+host = "localhost"
+# hcl:ignore unknown-attribute consumed by the legacy loader
+legacy = true
+extra = 1 // hcl:ignore HCL0001
+// hcl:ignore
+server {
+  port = 80
+  debug = true
+}
+`)
+	err = Unmarshal(data, &out, WithSuppressedCallback(func(diagnostic Diagnostic) {
+		suppressed = append(suppressed, diagnostic.Code+" "+diagnostic.Error())
+	}))
+	require.NoError(t, err)
+	require.Equal(t, config{Host: "localhost", Server: &server{Port: 80}}, out)
+	require.Equal(t, []string{
+		`HCL0001 9:3: attribute "debug" would be ignored`,
+		`HCL0001 5:1: attribute "extra" would be ignored`,
+		`HCL0001 3:1: attribute "legacy" would be ignored`,
+	}, suppressed)
+
+	suppressed = nil
+	diagnostics, err := CheckCompatibility([]byte(`
+# hcl:ignore missing-attribute,mismatch
+server {
+  port = "eighty"
+}
+other = 1
+`), &config{}, WithSuppressedCallback(func(diagnostic Diagnostic) {
+		suppressed = append(suppressed, diagnostic.Code+" "+diagnostic.Error())
+	}))
+	require.NoError(t, err)
+	codes := []string{}
+	for _, diagnostic := range diagnostics {
+		codes = append(codes, diagnostic.Code+" "+diagnostic.Error())
+	}
+	require.Equal(t, []string{
+		`HCL0003 2:1: missing required attribute "host"`,
+		`HCL0001 6:1: attribute "other" would be ignored`,
+	}, codes)
+	require.Equal(t, []string{`HCL0005 4:10: port: expected a number but got "eighty"`}, suppressed)
+}
@@ -0,0 +1,259 @@
+package hcl
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alecthomas/participle"
+)
+
+// InferSchema infers a schema from a corpus of example documents, eg. to
+// bootstrap typed configuration for an existing dialect.
+//
+// Attributes are typed by their values, and are optional if they are missing
+// from any instance of the enclosing document or block. Blocks are repeated
+// if they occur more than once in an instance of the enclosing document or
+// block. Pass the schema to GenerateStruct to generate Go structs for it.
+//
+// An attribute whose values have different types in different places is an
+// error.
+func InferSchema(files ...[]byte) (*AST, error) {
+	root := &inferredBody{entries: map[string]*inferredEntry{}}
+	for i, data := range files {
+		ast, err := ParseBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		if err := root.add(ast.Entries); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+	}
+	return &AST{Entries: root.schema(), Schema: true}, nil
+}
+
+// inferredBody accumulates the entries of each instance of a document or
+// block.
+type inferredBody struct {
+	instances int
+	keys      []string
+	entries   map[string]*inferredEntry
+}
+
+type inferredEntry struct {
+	// Number of instances of the enclosing body containing the entry.
+	instances int
+	value     *Value
+	block     *inferredBody
+	repeated  bool
+	labels    int
+}
+
+func (b *inferredBody) add(entries []*Entry) error {
+	b.instances++
+	seen := map[string]int{}
+	for _, entry := range entries {
+		key := entry.Key()
+		inferred, ok := b.entries[key]
+		if !ok {
+			inferred = &inferredEntry{}
+			b.entries[key] = inferred
+			b.keys = append(b.keys, key)
+		}
+		seen[key]++
+		if seen[key] == 1 {
+			inferred.instances++
+		}
+		if entry.Attribute != nil {
+			if inferred.block != nil {
+				return participle.Errorf(entry.Pos, "%s cannot be both block and attribute", key)
+			}
+			value, err := inferType(entry.Attribute.Value)
+			if err != nil {
+				return err
+			}
+			if inferred.value, err = unifyInferredTypes(inferred.value, value); err != nil {
+				return participle.Errorf(entry.Pos, "%s: %v", key, err)
+			}
+			continue
+		}
+		if inferred.value != nil {
+			return participle.Errorf(entry.Pos, "%s cannot be both block and attribute", key)
+		}
+		if inferred.block == nil {
+			inferred.block = &inferredBody{entries: map[string]*inferredEntry{}}
+		}
+		inferred.repeated = inferred.repeated || seen[key] > 1
+		if len(entry.Block.Labels) > inferred.labels {
+			inferred.labels = len(entry.Block.Labels)
+		}
+		if err := inferred.block.add(entry.Block.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schema returns the schema entries for the body.
+func (b *inferredBody) schema() []*Entry {
+	entries := make([]*Entry, 0, len(b.keys))
+	for _, key := range b.keys {
+		inferred := b.entries[key]
+		if inferred.block == nil {
+			entries = append(entries, &Entry{Attribute: &Attribute{
+				Key:      key,
+				Value:    completeInferredType(inferred.value),
+				Optional: inferred.instances < b.instances,
+			}})
+			continue
+		}
+		block := &Block{Name: key, Body: inferred.block.schema(), Repeated: inferred.repeated}
+		for i := 0; i < inferred.labels; i++ {
+			if inferred.labels == 1 {
+				block.Labels = append(block.Labels, "label")
+			} else {
+				block.Labels = append(block.Labels, "label"+strconv.Itoa(i))
+			}
+		}
+		entries = append(entries, &Entry{Block: block})
+	}
+	return entries
+}
+
+// inferType returns the schema type of an example value. The element type of
+// empty lists and maps is nil, until unified with another value.
+func inferType(v *Value) (*Value, error) {
+	switch {
+	case v.Bool != nil:
+		return &Value{Type: &boolType}, nil
+
+	case (v.Number != nil || v.Literal != "") && v.Unit == "":
+		return &Value{Type: &numType}, nil
+
+	case v.HaveList:
+		var el *Value
+		for _, value := range v.List {
+			typ, err := inferType(value)
+			if err != nil {
+				return nil, err
+			}
+			if el, err = unifyInferredTypes(el, typ); err != nil {
+				return nil, participle.Errorf(value.Pos, "list element: %v", err)
+			}
+		}
+		return inferredList(el), nil
+
+	case v.HaveMap:
+		var el *Value
+		for _, entry := range v.Map {
+			typ, err := inferType(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			if el, err = unifyInferredTypes(el, typ); err != nil {
+				return nil, participle.Errorf(entry.Value.Pos, "map value: %v", err)
+			}
+		}
+		return inferredMap(el), nil
+
+	case v.Str != nil, v.HeredocDelimiter != "", v.Type != nil, v.Unit != "":
+		// Values with units, eg. 30s, decode as strings.
+		return &Value{Type: &strType}, nil
+
+	default:
+		return nil, participle.Errorf(v.Pos, "can't determine type of value")
+	}
+}
+
+func inferredList(el *Value) *Value {
+	if el == nil {
+		return &Value{HaveList: true}
+	}
+	return &Value{HaveList: true, List: []*Value{el}}
+}
+
+func inferredMap(el *Value) *Value {
+	if el == nil {
+		return &Value{HaveMap: true}
+	}
+	return &Value{HaveMap: true, Map: []*MapEntry{{Key: &Value{Type: &strType}, Value: el}}}
+}
+
+// unifyInferredTypes returns the type of values of both types a and b, either
+// of which may be nil.
+func unifyInferredTypes(a, b *Value) (*Value, error) {
+	switch {
+	case a == nil:
+		return b, nil
+
+	case b == nil:
+		return a, nil
+
+	case a.Type != nil && b.Type != nil && *a.Type == *b.Type:
+		return a, nil
+
+	case a.HaveList && b.HaveList:
+		el, err := unifyInferredTypes(inferredElem(a), inferredElem(b))
+		if err != nil {
+			return nil, err
+		}
+		return inferredList(el), nil
+
+	case a.HaveMap && b.HaveMap:
+		el, err := unifyInferredTypes(inferredElem(a), inferredElem(b))
+		if err != nil {
+			return nil, err
+		}
+		return inferredMap(el), nil
+
+	default:
+		return nil, fmt.Errorf("inconsistent types %s and %s", inferredTypeString(a), inferredTypeString(b))
+	}
+}
+
+// inferredElem returns the element type of a list or map type, if known.
+func inferredElem(v *Value) *Value {
+	switch {
+	case len(v.List) > 0:
+		return v.List[0]
+	case len(v.Map) > 0:
+		return v.Map[0].Value
+	default:
+		return nil
+	}
+}
+
+// completeInferredType completes a type with unknown element types as
+// strings.
+func completeInferredType(v *Value) *Value {
+	switch {
+	case v.HaveList:
+		return inferredList(completeInferredType(orString(inferredElem(v))))
+	case v.HaveMap:
+		return inferredMap(completeInferredType(orString(inferredElem(v))))
+	default:
+		return v
+	}
+}
+
+func orString(v *Value) *Value {
+	if v == nil {
+		return &Value{Type: &strType}
+	}
+	return v
+}
+
+func inferredTypeString(v *Value) string {
+	el := inferredElem(v)
+	switch {
+	case v.Type != nil:
+		return *v.Type
+	case v.HaveList && el == nil:
+		return "[]"
+	case v.HaveList:
+		return "[" + inferredTypeString(el) + "]"
+	case el == nil:
+		return "{}"
+	default:
+		return "{string: " + inferredTypeString(el) + "}"
+	}
+}
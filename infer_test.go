@@ -0,0 +1,93 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferSchema(t *testing.T) {
+	schema, err := InferSchema(
+		[]byte(`
+			name = "api"
+			port = 8080
+			tags = []
+			timeout = 30s
+
+			upstream "a" {
+				host = "a.local"
+				weight = 1
+			}
+			upstream "b" {
+				host = "b.local"
+			}
+		`),
+		[]byte(`
+			name = "worker"
+			port = 9090
+			tags = ["batch"]
+			limits = {cpu: 2, memory: 1.5}
+
+			tls {
+				cert = "cert.pem"
+				verify = true
+			}
+		`),
+	)
+	require.NoError(t, err)
+	data, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `name = string
+port = number
+tags = [string]
+timeout = string // (optional)
+
+upstream "label" { // (repeated)
+  host = string
+  weight = number // (optional)
+}
+
+limits = {
+  string: number,
+} // (optional)
+
+tls {
+  cert = string
+  verify = boolean
+}
+`, string(data))
+
+	out, err := GenerateStruct(schema, "config", "Config")
+	require.NoError(t, err)
+	require.Equal(t, `package config
+
+type Config struct {
+	Name     string             `+"`hcl:\"name\"`"+`
+	Port     float64            `+"`hcl:\"port\"`"+`
+	Tags     []string           `+"`hcl:\"tags\"`"+`
+	Timeout  string             `+"`hcl:\"timeout,optional\"`"+`
+	Upstream []Upstream         `+"`hcl:\"upstream,block\"`"+`
+	Limits   map[string]float64 `+"`hcl:\"limits,optional\"`"+`
+	Tls      Tls                `+"`hcl:\"tls,block\"`"+`
+}
+
+type Upstream struct {
+	Label  string  `+"`hcl:\"label,label\"`"+`
+	Host   string  `+"`hcl:\"host\"`"+`
+	Weight float64 `+"`hcl:\"weight,optional\"`"+`
+}
+
+type Tls struct {
+	Cert   string `+"`hcl:\"cert\"`"+`
+	Verify bool   `+"`hcl:\"verify\"`"+`
+}
+`, string(out))
+}
+
+func TestInferSchemaInconsistentTypes(t *testing.T) {
+	_, err := InferSchema([]byte(`port = 8080`), []byte("\nport = \"http\""))
+	require.EqualError(t, err, `document 1: 2:1: port: inconsistent types number and string`)
+
+	_, err = InferSchema([]byte(`ports = [1, "2"]`))
+	require.EqualError(t, err, `document 0: 1:13: list element: inconsistent types number and string`)
+}
@@ -0,0 +1,113 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/alecthomas/participle"
+)
+
+// blockToObject converts a block marshalled from an inline struct field into
+// a map value, eg. `limits = { cpu: 2, mem: 512 }`.
+func blockToObject(block *Block) (*Value, error) {
+	if len(block.Labels) > 0 {
+		return nil, fmt.Errorf("inline %q can't have labels", block.Name)
+	}
+	value := &Value{HaveMap: true, Map: []*MapEntry{}}
+	for _, entry := range block.Body {
+		if entry.Attribute == nil {
+			return nil, fmt.Errorf("inline %q can't contain block %q", block.Name, entry.Key())
+		}
+		key := entry.Attribute.Key
+		value.Map = append(value.Map, &MapEntry{
+			Comments: entry.Attribute.Comments,
+			Key:      &Value{Str: &key},
+			Value:    entry.Attribute.Value,
+		})
+	}
+	return value, nil
+}
+
+// objectToBlock converts the map value of an attribute for an inline struct
+// field into a block named name, so that it can be unmarshalled as one.
+func objectToBlock(name string, v *Value) (*Block, error) {
+	if !v.HaveMap {
+		return nil, participle.Errorf(v.Pos, "expected a map or block for %q but got %s", name, v)
+	}
+	block := &Block{Pos: v.Pos, Name: name}
+	for _, entry := range v.Map {
+		var key string
+		switch {
+		case entry.Key.Str != nil:
+			key = *entry.Key.Str
+		case entry.Key.Type != nil:
+			key = *entry.Key.Type
+		default:
+			return nil, participle.Errorf(entry.Key.Pos, "map key must be a string or type but is %s", entry.Key)
+		}
+		attr := &Entry{Pos: entry.Pos, Parent: block, Attribute: &Attribute{Pos: entry.Pos, Key: key, Value: entry.Value}}
+		attr.Attribute.Parent = attr
+		block.Body = append(block.Body, attr)
+	}
+	return block, nil
+}
+
+// mixBlockSyntax converts the entries for field between blocks and attributes
+// with object values, to the form the field expects, for MixedBlockSyntax.
+func mixBlockSyntax(field field, tag tag, entries []*Entry) ([]*Entry, error) {
+	t := field.t.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	repeated := t.Kind() == reflect.Slice
+	if repeated {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	switch {
+	case tag.block && tag.union == nil && t.Kind() == reflect.Struct:
+		out := make([]*Entry, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Attribute == nil {
+				out = append(out, entry)
+				continue
+			}
+			values := []*Value{entry.Attribute.Value}
+			if repeated && entry.Attribute.Value.HaveList {
+				values = entry.Attribute.Value.List
+			}
+			for _, value := range values {
+				block, err := objectToBlock(tag.name, value)
+				if err != nil {
+					return nil, err
+				}
+				block.Comments = entry.Attribute.Comments
+				converted := &Entry{Pos: value.Pos, Parent: entry.Parent, Block: block}
+				block.Parent = converted
+				out = append(out, converted)
+			}
+		}
+		return out, nil
+
+	case !tag.block && !tag.inline && !repeated && (t.Kind() == reflect.Map || t.Kind() == reflect.Struct):
+		out := make([]*Entry, 0, len(entries))
+		for _, entry := range entries {
+			if entry.Block == nil {
+				out = append(out, entry)
+				continue
+			}
+			value, err := blockToObject(entry.Block)
+			if err != nil {
+				return nil, participle.Wrapf(entry.Pos, err, "invalid object")
+			}
+			value.Pos = entry.Block.Pos
+			converted := &Entry{Pos: entry.Pos, Parent: entry.Parent}
+			converted.Attribute = &Attribute{Pos: entry.Pos, Parent: converted, Comments: entry.Block.Comments, Key: entry.Block.Name, Value: value}
+			out = append(out, converted)
+		}
+		return out, nil
+	}
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package hcl
+
+import (
+	"sync"
+)
+
+// An Interner returns a canonical instance of equal strings, so that
+// duplicates can be garbage collected.
+type Interner interface {
+	Intern(s string) string
+}
+
+// WithInterning specifies whether to intern identifiers, such as block names,
+// labels, attribute keys and map keys, when parsing.
+//
+// This reduces the memory retained by ASTs with many similar blocks, at the
+// cost of some CPU during parsing.
+func WithInterning(v bool) ParseOption {
+	return func(options *parseOptions) {
+		if v {
+			options.interner = NewInterner()
+		} else {
+			options.interner = nil
+		}
+	}
+}
+
+// WithInterner interns identifiers with interner when parsing.
+//
+// An Interner may be shared across parses, eg. of all files in a directory.
+func WithInterner(interner Interner) ParseOption {
+	return func(options *parseOptions) {
+		options.interner = interner
+	}
+}
+
+// NewInterner creates a new Interner backed by a map. It is safe for concurrent use.
+func NewInterner() Interner {
+	return &mapInterner{strings: map[string]string{}}
+}
+
+type mapInterner struct {
+	lock    sync.Mutex
+	strings map[string]string
+}
+
+func (m *mapInterner) Intern(s string) string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if interned, ok := m.strings[s]; ok {
+		return interned
+	}
+	m.strings[s] = s
+	return s
+}
+
+// internStrings interns the identifiers in node.
+func internStrings(node Node, interner Interner) error {
+	return Visit(node, func(node Node, next func() error) error {
+		switch node := node.(type) {
+		case *Attribute:
+			node.Key = interner.Intern(node.Key)
+
+		case *Block:
+			node.Name = interner.Intern(node.Name)
+			for i, label := range node.Labels {
+				node.Labels[i] = interner.Intern(label)
+			}
+
+		case *MapEntry:
+			if node.Key.Str != nil {
+				*node.Key.Str = interner.Intern(*node.Key.Str)
+			}
+
+		case *Value:
+			if node.Type != nil {
+				*node.Type = interner.Intern(*node.Type)
+			}
+		}
+		return next()
+	})
+}
@@ -0,0 +1,40 @@
+package hcl
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestInterning(t *testing.T) {
+	src := `
+service "api" { port = 1 }
+service "api" { port = 2 }
+`
+	ast, err := ParseString(src)
+	require.NoError(t, err)
+	a, b := ast.Entries[0].Block, ast.Entries[1].Block
+	require.NotEqual(t, stringData(a.Body[0].Attribute.Key), stringData(b.Body[0].Attribute.Key))
+
+	ast, err = ParseString(src, WithInterning(true))
+	require.NoError(t, err)
+	a, b = ast.Entries[0].Block, ast.Entries[1].Block
+	require.Equal(t, stringData(a.Name), stringData(b.Name))
+	require.Equal(t, stringData(a.Labels[0]), stringData(b.Labels[0]))
+	require.Equal(t, stringData(a.Body[0].Attribute.Key), stringData(b.Body[0].Attribute.Key))
+}
+
+func TestSharedInterner(t *testing.T) {
+	interner := NewInterner()
+	a, err := ParseString(`key = 1`, WithInterner(interner))
+	require.NoError(t, err)
+	b, err := ParseString(`key = 2`, WithInterner(interner))
+	require.NoError(t, err)
+	require.Equal(t, stringData(a.Entries[0].Attribute.Key), stringData(b.Entries[0].Attribute.Key))
+}
@@ -104,12 +104,22 @@ func (w *jsonVisitor) writeValue(node *Value) error {
 	case node.Bool != nil:
 		fmt.Fprintf(w, "%v", *node.Bool)
 
+	case node.Number != nil && node.Unit != "":
+		fmt.Fprintf(w, "%q", node.String())
+
 	case node.Number != nil:
 		fmt.Fprint(w, node.Number.String())
 
 	case node.Str != nil:
 		fmt.Fprintf(w, "%q", *node.Str)
 
+	case node.Block != nil:
+		fmt.Fprint(w, "{")
+		if err := Visit(node.Block, w.Visit); err != nil {
+			return err
+		}
+		fmt.Fprint(w, "}")
+
 	case node.HaveList:
 		fmt.Fprint(w, "[")
 		for i, e := range node.List {
@@ -0,0 +1,186 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/alecthomas/participle"
+)
+
+// Attributes identifying the type of a document decoded by UnmarshalKind.
+const (
+	KindAttribute    = "kind"
+	VersionAttribute = "version"
+)
+
+var (
+	kindsLock sync.RWMutex
+	kinds     = map[string]reflect.Type{}
+)
+
+// RegisterKind registers the Go type that documents of kind are decoded into
+// by UnmarshalKind, for multi-resource configuration processed by one
+// loader, eg.
+//
+//	hcl.RegisterKind("Service", &Service{})
+//	hcl.RegisterKind("Deployment", &Deployment{})
+//
+// v must be a struct or a pointer to a struct. Registering a nil v removes any
+// existing registration.
+func RegisterKind(kind string, v interface{}) {
+	t := kindType(kind, v)
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+	if t == nil {
+		delete(kinds, kind)
+		return
+	}
+	kinds[kind] = t
+}
+
+// WithKind registers the type of kind, as with RegisterKind, for a single call
+// to UnmarshalKind or MarshalKind, taking precedence over any type registered
+// for kind. A nil v makes kind unknown during the call.
+func WithKind(kind string, v interface{}) MarshalOption {
+	t := kindType(kind, v)
+	return func(options *marshalOptions) {
+		if options.kinds == nil {
+			options.kinds = map[string]reflect.Type{}
+		}
+		options.kinds[kind] = t
+	}
+}
+
+// kindType returns the struct type of v, registered for kind.
+func kindType(kind string, v interface{}) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("kind %q of type %T must be a struct or a pointer to a struct", kind, v))
+	}
+	return t
+}
+
+// registeredKinds returns the types of all registered kinds, including those
+// registered for a single call.
+func (o *marshalOptions) registeredKinds() map[string]reflect.Type {
+	kindsLock.RLock()
+	out := make(map[string]reflect.Type, len(kinds)+len(o.kinds))
+	for name, t := range kinds {
+		out[name] = t
+	}
+	kindsLock.RUnlock()
+	for name, t := range o.kinds {
+		if t == nil {
+			delete(out, name)
+		} else {
+			out[name] = t
+		}
+	}
+	return out
+}
+
+// lookupKind returns the type registered for kind, and the names of all
+// registered kinds.
+func (o *marshalOptions) lookupKind(kind string) (reflect.Type, []string) {
+	registered := o.registeredKinds()
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return registered[kind], names
+}
+
+// UnmarshalKind decodes a document declaring its type with "kind" and
+// optionally "version" attributes, eg.
+//
+//	kind = "Service"
+//	version = "v1"
+//
+//	port = 8080
+//
+// into a new value of the type registered for its kind with RegisterKind,
+// returning a pointer to it along with the kind and version. The kind and
+// version attributes are not decoded into the value.
+func UnmarshalKind(data []byte, options ...MarshalOption) (v interface{}, kind, version string, err error) {
+	ast, err := ParseBytes(data)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return UnmarshalKindAST(ast, options...)
+}
+
+// UnmarshalKindAST is like UnmarshalKind, but decodes an AST.
+func UnmarshalKindAST(ast *AST, options ...MarshalOption) (v interface{}, kind, version string, err error) {
+	body := &AST{Pos: ast.Pos, Schema: ast.Schema}
+	var kindAttr *Attribute
+	for _, entry := range ast.Entries {
+		attr := entry.Attribute
+		switch {
+		case attr != nil && attr.Key == KindAttribute:
+			if attr.Value.Str == nil {
+				return nil, "", "", participle.Errorf(attr.Value.Pos, "expected a string for %q but got %s", KindAttribute, attr.Value)
+			}
+			kindAttr, kind = attr, *attr.Value.Str
+
+		case attr != nil && attr.Key == VersionAttribute:
+			s, ok := flatString(attr.Value)
+			if !ok || (attr.Value.Str == nil && attr.Value.Number == nil) {
+				return nil, "", "", participle.Errorf(attr.Value.Pos, "expected a string or number for %q but got %s", VersionAttribute, attr.Value)
+			}
+			version = s
+
+		default:
+			body.Entries = append(body.Entries, entry)
+		}
+	}
+	if kindAttr == nil {
+		return nil, "", "", participle.Errorf(ast.Pos, "missing required attribute %q", KindAttribute)
+	}
+	t, known := newMarshalOptions(options...).lookupKind(kind)
+	if t == nil {
+		return nil, kind, version, participle.Errorf(kindAttr.Value.Pos, "unknown kind %q%s", kind, didYouMean(kind, known))
+	}
+	rv := reflect.New(t)
+	if err := UnmarshalAST(body, rv.Interface(), options...); err != nil {
+		return nil, kind, version, err
+	}
+	return rv.Interface(), kind, version, nil
+}
+
+// MarshalKind marshals v, a value of a type registered with RegisterKind,
+// preceded by its kind and, if not empty, version, such that it can be decoded
+// by UnmarshalKind.
+func MarshalKind(v interface{}, version string, options ...MarshalOption) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := ""
+	for name, kt := range newMarshalOptions(options...).registeredKinds() {
+		if kt == t && (kind == "" || name < kind) {
+			kind = name
+		}
+	}
+	if kind == "" {
+		return nil, fmt.Errorf("no kind registered for %T", v)
+	}
+	ast, err := MarshalToAST(v, options...)
+	if err != nil {
+		return nil, err
+	}
+	header := []*Entry{{Attribute: &Attribute{Key: KindAttribute, Value: &Value{Str: &kind}}}}
+	if version != "" {
+		header = append(header, &Entry{Attribute: &Attribute{Key: VersionAttribute, Value: &Value{Str: &version}}})
+	}
+	ast.Entries = append(header, ast.Entries...)
+	return MarshalAST(ast)
+}
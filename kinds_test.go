@@ -0,0 +1,83 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type kindService struct {
+	Port int `hcl:"port"`
+}
+
+type kindDeployment struct {
+	Replicas int `hcl:"replicas"`
+	Image    struct {
+		Name string `hcl:"name"`
+	} `hcl:"image,block"`
+}
+
+func TestUnmarshalKind(t *testing.T) {
+	RegisterKind("Service", &kindService{})
+	RegisterKind("Deployment", kindDeployment{})
+	defer RegisterKind("Service", nil)
+	defer RegisterKind("Deployment", nil)
+
+	v, kind, version, err := UnmarshalKind([]byte(`
+kind = "Service"
+version = "v1"
+port = 8080
+`))
+	require.NoError(t, err)
+	require.Equal(t, "Service", kind)
+	require.Equal(t, "v1", version)
+	require.Equal(t, &kindService{Port: 8080}, v)
+
+	v, kind, version, err = UnmarshalKind([]byte(`
+replicas = 3
+kind = "Deployment"
+version = 2
+image {
+  name = "nginx"
+}
+`))
+	require.NoError(t, err)
+	require.Equal(t, "Deployment", kind)
+	require.Equal(t, "2", version)
+	deployment := v.(*kindDeployment)
+	require.Equal(t, 3, deployment.Replicas)
+	require.Equal(t, "nginx", deployment.Image.Name)
+
+	data, err := MarshalKind(&kindService{Port: 80}, "v2")
+	require.NoError(t, err)
+	require.Equal(t, "kind = \"Service\"\nversion = \"v2\"\nport = 80\n", string(data))
+	v, kind, version, err = UnmarshalKind(data)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{&kindService{Port: 80}, "Service", "v2"}, []interface{}{v, kind, version})
+
+	_, err = MarshalKind(&kindDeployment{}, "")
+	require.NoError(t, err)
+	_, err = MarshalKind(&struct{}{}, "")
+	require.EqualError(t, err, "no kind registered for *struct {}")
+}
+
+func TestUnmarshalKindErrors(t *testing.T) {
+	RegisterKind("Service", &kindService{})
+	defer RegisterKind("Service", nil)
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"Missing", `port = 80`, `1:1: missing required attribute "kind"`},
+		{"Unknown", `kind = "Servce"`, `1:8: unknown kind "Servce"; did you mean "Service"?`},
+		{"NotString", `kind = 1`, `1:8: expected a string for "kind" but got 1`},
+		{"Body", "kind = \"Service\"\nport = \"80\"", `2:8: port: expected a number but got "80"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, _, err := UnmarshalKind([]byte(test.source))
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle"
+)
+
+// Compiled label:"" tags, keyed by their source.
+var labelTemplates sync.Map
+
+// labelTemplate is the label:"" tag of a label field, composing the label from
+// fields tagged as parts, eg. "${region}-${name}".
+type labelTemplate struct {
+	source string
+	// Names of the parts, in order, and the literal text around them, such
+	// that len(literals) == len(parts)+1.
+	parts    []string
+	literals []string
+	re       *regexp.Regexp
+}
+
+// compileLabelTemplate compiles the label:"" tag of a field, panicking if it
+// is invalid.
+func compileLabelTemplate(parent reflect.Type, t reflect.StructField, source string) *labelTemplate {
+	if tmpl, ok := labelTemplates.Load(source); ok {
+		return tmpl.(*labelTemplate)
+	}
+	invalid := func(reason string) {
+		panic("invalid label " + source + " on " + fieldID(parent, t) + ": " + reason)
+	}
+	tmpl := &labelTemplate{source: source}
+	pattern := &strings.Builder{}
+	pattern.WriteString("^")
+	rest := source
+	for {
+		start := strings.Index(rest, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			invalid("unterminated ${")
+		}
+		name := rest[start+2 : start+end]
+		if !IsIdent(name) {
+			invalid("invalid part name " + name)
+		}
+		for _, part := range tmpl.parts {
+			if part == name {
+				invalid("duplicate part " + name)
+			}
+		}
+		if start == 0 && len(tmpl.parts) > 0 {
+			invalid("parts " + tmpl.parts[len(tmpl.parts)-1] + " and " + name + " must be separated")
+		}
+		tmpl.literals = append(tmpl.literals, rest[:start])
+		tmpl.parts = append(tmpl.parts, name)
+		// Parts match as little as possible, so that later parts may contain
+		// the separators before them.
+		pattern.WriteString(regexp.QuoteMeta(rest[:start]) + "(.+?)")
+		rest = rest[start+end+1:]
+	}
+	if len(tmpl.parts) == 0 {
+		invalid("expected at least one ${part}")
+	}
+	tmpl.literals = append(tmpl.literals, rest)
+	pattern.WriteString(regexp.QuoteMeta(rest) + "$")
+	tmpl.re = regexp.MustCompile(pattern.String())
+	labelTemplates.Store(source, tmpl)
+	return tmpl
+}
+
+// format composes a label from the values of its parts.
+func (l *labelTemplate) format(values []string) string {
+	w := &strings.Builder{}
+	for i, value := range values {
+		w.WriteString(l.literals[i])
+		w.WriteString(value)
+	}
+	w.WriteString(l.literals[len(l.literals)-1])
+	return w.String()
+}
+
+// split a label into the values of its parts, returning false if it doesn't
+// match the template.
+func (l *labelTemplate) split(label string) ([]string, bool) {
+	match := l.re.FindStringSubmatch(label)
+	if match == nil {
+		return nil, false
+	}
+	return match[1:], true
+}
+
+// labelPartFields returns the fields of a struct tagged as parts of a label,
+// keyed by name.
+func labelPartFields(parent reflect.Type, fields []field, opt *marshalOptions) map[string]field {
+	parts := map[string]field{}
+	for _, field := range fields {
+		if tag := parseTag(parent, field, opt); tag.part != "" {
+			parts[tag.part] = field
+		}
+	}
+	return parts
+}
+
+// lookupLabelParts returns the part fields of tmpl, in order, panicking if one
+// doesn't exist.
+func lookupLabelParts(parent reflect.Type, tmpl *labelTemplate, parts map[string]field) []field {
+	out := make([]field, len(tmpl.parts))
+	for i, name := range tmpl.parts {
+		field, ok := parts[name]
+		if !ok {
+			panic("unknown part " + name + " in label " + tmpl.source + " of " + parent.String())
+		}
+		out[i] = field
+	}
+	return out
+}
+
+// unmarshalLabelParts splits a label decoded into labelField into its part
+// fields, if it is composed from them.
+func unmarshalLabelParts(v reflect.Value, fields []field, labelField field, block *Block, label string, opt *marshalOptions) error {
+	source := labelField.t.Tag.Get("label")
+	if source == "" {
+		return nil
+	}
+	tmpl := compileLabelTemplate(v.Type(), labelField.t, source)
+	values, ok := tmpl.split(label)
+	if !ok {
+		return participle.Errorf(block.Pos, "label %q for block %q doesn't match %q", label, block.Name, tmpl.source)
+	}
+	parts := lookupLabelParts(v.Type(), tmpl, labelPartFields(v.Type(), fields, opt))
+	for i, field := range parts {
+		if err := unmarshalLabel(field.v, values[i]); err != nil {
+			return participle.Wrapf(block.Pos, err, "invalid %s %q in label %q for block %q", tmpl.parts[i], values[i], label, block.Name)
+		}
+	}
+	return nil
+}
+
+// formatLabelParts composes the label of the label field with tag from its part
+// fields, returning an error if it can't be split back into the same parts.
+func formatLabelParts(v reflect.Value, fields []field, tag tag, opt *marshalOptions) (string, error) {
+	tmpl := tag.labelTemplate
+	parts := lookupLabelParts(v.Type(), tmpl, labelPartFields(v.Type(), fields, opt))
+	values := make([]string, len(parts))
+	for i, field := range parts {
+		value, err := labelFromValue(field.v)
+		if err != nil {
+			return "", withFieldPath(tmpl.parts[i], err)
+		}
+		values[i] = value
+	}
+	label := tmpl.format(values)
+	if split, ok := tmpl.split(label); !ok || !equalStrings(split, values) {
+		return "", fmt.Errorf("label %q composed by %q is ambiguous, as it can't be split back into %q", label, tmpl.source, values)
+	}
+	return label, nil
+}
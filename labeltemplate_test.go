@@ -0,0 +1,113 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type regionalService struct {
+	ID     string `hcl:"id,label" label:"${region}-${name}"`
+	Region string `hcl:"region,part"`
+	Name   string `hcl:"name,part"`
+	Port   int    `hcl:"port"`
+}
+
+type regionalConfig struct {
+	Services []regionalService `hcl:"service,block"`
+}
+
+func TestLabelTemplate(t *testing.T) {
+	config := &regionalConfig{}
+	err := Unmarshal([]byte(`
+service "eu-api" {
+  port = 80
+}
+service "us-web-frontend" {
+  port = 443
+}
+`), config)
+	require.NoError(t, err)
+	require.Equal(t, &regionalConfig{Services: []regionalService{
+		{ID: "eu-api", Region: "eu", Name: "api", Port: 80},
+		{ID: "us-web-frontend", Region: "us", Name: "web-frontend", Port: 443},
+	}}, config)
+
+	// The label is composed from its parts, rather than the label field.
+	config.Services[0].ID = ""
+	config.Services[0].Region = "ap"
+	data, err := Marshal(config)
+	require.NoError(t, err)
+	require.Equal(t, `service "ap-api" {
+  port = 80
+}
+
+service "us-web-frontend" {
+  port = 443
+}
+`, string(data))
+
+	err = Unmarshal([]byte(`service "eu" { port = 80 }`), config)
+	require.EqualError(t, err, `1:1: service[0]: label "eu" for block "service" doesn't match "${region}-${name}"`)
+
+	_, err = Marshal(&regionalConfig{Services: []regionalService{{Region: "eu-west", Name: "api"}}})
+	require.EqualError(t, err, `service[0].id: label "eu-west-api" composed by "${region}-${name}" is ambiguous, as it can't be split back into ["eu-west" "api"]`)
+
+	schema, err := Schema(&regionalConfig{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"id"}, schema.Entries[0].Block.Labels)
+}
+
+func TestLabelTemplateTypedParts(t *testing.T) {
+	type shard struct {
+		ID      string `hcl:"id,label" label:"shard-${index}.${zone}"`
+		Index   int    `hcl:"index,part"`
+		Zone    string `hcl:"zone,part"`
+		Primary bool   `hcl:"primary,optional"`
+	}
+	type config struct {
+		Shards []shard `hcl:"shard,block"`
+	}
+	out := &config{}
+	err := Unmarshal([]byte(`shard "shard-3.b" { primary = true }`), out)
+	require.NoError(t, err)
+	require.Equal(t, []shard{{ID: "shard-3.b", Index: 3, Zone: "b", Primary: true}}, out.Shards)
+
+	err = Unmarshal([]byte(`shard "shard-x.b" {}`), out)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid index "x" in label "shard-x.b" for block "shard"`)
+}
+
+func TestInvalidLabelTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		fail string
+	}{
+		{"NoParts", &struct {
+			ID string `hcl:"id,label" label:"static"`
+		}{}, `expected at least one ${part}`},
+		{"Adjacent", &struct {
+			ID string `hcl:"id,label" label:"${a}${b}"`
+			A  string `hcl:"a,part"`
+			B  string `hcl:"b,part"`
+		}{}, `parts a and b must be separated`},
+		{"Unterminated", &struct {
+			ID string `hcl:"id,label" label:"${a"`
+		}{}, `unterminated ${`},
+		{"UnknownPart", &struct {
+			ID string `hcl:"id,label" label:"${a}-${b}"`
+			A  string `hcl:"a,part"`
+		}{}, `unknown part b in label ${a}-${b}`},
+		{"NotALabel", &struct {
+			ID string `hcl:"id" label:"${a}"`
+		}{}, `label:"" is only valid on labels`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckStruct(test.v)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.fail)
+		})
+	}
+}
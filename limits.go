@@ -0,0 +1,219 @@
+package hcl
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// WithMaxParseDepth limits the nesting depth of blocks, lists and maps when
+// parsing, to guard against adversarial input.
+func WithMaxParseDepth(n int) ParseOption {
+	return func(options *parseOptions) {
+		options.maxDepth = n
+	}
+}
+
+// WithMaxDepth limits the nesting depth of blocks, lists and maps when
+// marshalling, and when parsing HCL for Unmarshal.
+func WithMaxDepth(n int) MarshalOption {
+	return func(options *marshalOptions) {
+		options.maxDepth = n
+	}
+}
+
+// parseOptions returns the options used to parse HCL for unmarshalling.
+func (o *marshalOptions) parseOptions() []ParseOption {
+	var options []ParseOption
+	if o.maxDepth > 0 {
+		options = append(options, WithMaxParseDepth(o.maxDepth))
+	}
+	if o.metrics != nil {
+		options = append(options, WithParseMetrics(o.metrics))
+	}
+	return options
+}
+
+// enter increments the nesting depth when marshalling, returning an error if
+// it exceeds the maximum. Call the returned function to decrement it again.
+func (o *marshalOptions) enter() (func(), error) {
+	o.depth++
+	if o.maxDepth > 0 && o.depth > o.maxDepth {
+		o.depth--
+		return nil, fmt.Errorf("maximum nesting depth of %d exceeded", o.maxDepth)
+	}
+	return func() { o.depth-- }, nil
+}
+
+// visit records that the pointer or map v is being marshalled, returning an
+// error if it already is, ie. if there is a cycle. Call the returned function
+// once v has been marshalled.
+func (o *marshalOptions) visit(v reflect.Value) (func(), error) {
+	ref := pointerRef{v.Pointer(), v.Type()}
+	if o.marshalling[ref] {
+		return nil, fmt.Errorf("pointer cycle detected in %s", v.Type())
+	}
+	if o.marshalling == nil {
+		o.marshalling = map[pointerRef]bool{}
+	}
+	o.marshalling[ref] = true
+	return func() { delete(o.marshalling, ref) }, nil
+}
+
+// WithMaxInputSize limits the size of the input to n bytes when parsing.
+func WithMaxInputSize(n int) ParseOption {
+	return func(options *parseOptions) {
+		options.maxInputSize = n
+	}
+}
+
+// WithMaxEntries limits the total number of attributes and blocks when parsing.
+func WithMaxEntries(n int) ParseOption {
+	return func(options *parseOptions) {
+		options.maxEntries = n
+	}
+}
+
+// WithMaxStringLength limits the length in bytes of strings and heredocs when parsing.
+func WithMaxStringLength(n int) ParseOption {
+	return func(options *parseOptions) {
+		options.maxStringLength = n
+	}
+}
+
+// limited returns true if tokens must be checked against limits during parsing.
+func (o *parseOptions) limited() bool {
+	return o.maxDepth > 0 || o.maxInputSize > 0 || o.maxEntries > 0 || o.maxStringLength > 0
+}
+
+// parseLimited parses r into dst, enforcing the limits in opt as tokens are lexed.
+func parseLimited(r io.Reader, dst interface{}, opt *parseOptions) error {
+	parser := opt.parser()
+	tokenLexer, err := parser.Lexer().Lex(r)
+	if err != nil {
+		return err
+	}
+	symbols := parser.Lexer().Symbols()
+	limited := &limitLexer{
+		Lexer:   tokenLexer,
+		opt:     opt,
+		entries: opt.entriesBefore,
+		punct:   symbols["Punct"],
+		str:     symbols["String"],
+		raw:     symbols["RawString"],
+		single:  symbols["SingleString"],
+		heredoc: symbols["Heredoc"],
+		body:    symbols["Body"],
+		eol:     symbols["EOL"],
+		ident:   symbols["Ident"],
+		comment: map[rune]bool{symbols["Comment"]: true, symbols["TrailingComment"]: true},
+	}
+	peeker, err := lexer.Upgrade(limited)
+	if err != nil {
+		return err
+	}
+	return parser.ParseFromLexer(peeker, dst)
+}
+
+// sizeLimitReader fails once more than max bytes have been read.
+type sizeLimitReader struct {
+	r         io.Reader
+	remaining int
+	max       int
+}
+
+func (s *sizeLimitReader) Read(b []byte) (int, error) {
+	if len(b) > s.remaining+1 {
+		b = b[:s.remaining+1]
+	}
+	n, err := s.r.Read(b)
+	s.remaining -= n
+	if s.remaining < 0 {
+		return 0, inputSizeError(s.max)
+	}
+	return n, err
+}
+
+func inputSizeError(max int) error {
+	return fmt.Errorf("input exceeds maximum size of %d bytes", max)
+}
+
+// Name of the underlying reader, so that positions retain the filename.
+func (s *sizeLimitReader) Name() string { return lexer.NameOfReader(s.r) }
+
+type limitLexer struct {
+	lexer.Lexer
+	opt *parseOptions
+
+	punct, str, raw, single, heredoc, body, eol, ident rune
+	comment                                            map[rune]bool
+
+	// Whether each "{" or "[" currently open is a block body, innermost last.
+	open    []bool
+	entries int
+	// Length of the body of the current heredoc.
+	heredocLength int
+	// Type of the previous token, ignoring comments.
+	prev rune
+}
+
+func (l *limitLexer) Next() (lexer.Token, error) {
+	token, err := l.Lexer.Next()
+	if err != nil {
+		return token, err
+	}
+	prev := l.prev
+	if !l.comment[token.Type] {
+		l.prev = token.Type
+	}
+	switch token.Type {
+	case l.punct:
+		switch token.Value {
+		case "{", "[":
+			// Blocks are the only braces preceded by a name or label.
+			block := token.Value == "{" && (prev == l.ident || prev == l.str)
+			l.open = append(l.open, block)
+			if l.opt.maxDepth > 0 && len(l.open) > l.opt.maxDepth {
+				return token, participle.Errorf(token.Pos, "maximum nesting depth of %d exceeded", l.opt.maxDepth)
+			}
+			if block {
+				return token, l.entry(token)
+			}
+		case "}", "]":
+			if len(l.open) > 0 {
+				l.open = l.open[:len(l.open)-1]
+			}
+		case "=":
+			// Map entries may also be separated by "=", but are not counted.
+			if len(l.open) == 0 || l.open[len(l.open)-1] {
+				return token, l.entry(token)
+			}
+		}
+
+	case l.str, l.raw, l.single:
+		if l.opt.maxStringLength > 0 && len(token.Value) > l.opt.maxStringLength {
+			return token, participle.Errorf(token.Pos, "string exceeds maximum length of %d bytes", l.opt.maxStringLength)
+		}
+
+	case l.heredoc:
+		l.heredocLength = 0
+
+	case l.body, l.eol:
+		l.heredocLength += len(token.Value)
+		if l.opt.maxStringLength > 0 && l.heredocLength > l.opt.maxStringLength {
+			return token, participle.Errorf(token.Pos, "heredoc exceeds maximum length of %d bytes", l.opt.maxStringLength)
+		}
+	}
+	return token, nil
+}
+
+func (l *limitLexer) entry(token lexer.Token) error {
+	l.entries++
+	if l.opt.maxEntries > 0 && l.entries > l.opt.maxEntries {
+		return participle.Errorf(token.Pos, "maximum of %d entries exceeded", l.opt.maxEntries)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxParseDepth(t *testing.T) {
+	deep := "a = " + strings.Repeat("[", 10000) + strings.Repeat("]", 10000)
+	_, err := ParseString(deep, WithMaxParseDepth(64))
+	require.EqualError(t, err, "1:69: maximum nesting depth of 64 exceeded")
+
+	src := "a { b { c = [{d: 1}] } }"
+	_, err = ParseString(src, WithMaxParseDepth(4))
+	require.NoError(t, err)
+	_, err = ParseString(src, WithMaxParseDepth(3))
+	require.EqualError(t, err, "1:14: maximum nesting depth of 3 exceeded")
+
+	var v struct {
+		A interface{} `hcl:"a"`
+	}
+	err = Unmarshal([]byte(deep), &v, WithMaxDepth(64))
+	require.EqualError(t, err, "1:69: maximum nesting depth of 64 exceeded")
+}
+
+func TestMaxMarshalDepth(t *testing.T) {
+	src := &condition{Op: "a", Children: []*condition{{Op: "b", Else: &condition{Op: "c"}}}}
+	_, err := Marshal(src, WithMaxDepth(2))
+	require.NoError(t, err)
+	_, err = Marshal(src, WithMaxDepth(1))
+	require.EqualError(t, err, "condition[0].else: maximum nesting depth of 1 exceeded")
+
+	list := []interface{}{}
+	for i := 0; i < 10; i++ {
+		list = []interface{}{list}
+	}
+	_, err = Marshal(&struct {
+		List []interface{} `hcl:"list"`
+	}{list}, WithMaxDepth(5))
+	require.EqualError(t, err, "list[0][0][0][0][0]: maximum nesting depth of 5 exceeded")
+}
+
+func TestMarshalMapCycle(t *testing.T) {
+	m := map[string]interface{}{}
+	m["self"] = m
+	_, err := Marshal(&struct {
+		Map map[string]interface{} `hcl:"map"`
+	}{m})
+	require.EqualError(t, err, `map["self"]: pointer cycle detected in map[string]interface {}`)
+}
+
+func TestParseLimits(t *testing.T) {
+	huge := strings.Repeat("x", 1<<20)
+	tests := []struct {
+		name    string
+		src     string
+		options []ParseOption
+		fail    string
+	}{
+		{name: "InputSize",
+			src:     `a = "` + huge + `"`,
+			options: []ParseOption{WithMaxInputSize(1024)},
+			fail:    "input exceeds maximum size of 1024 bytes"},
+		{name: "InputSizeWithinLimit",
+			src:     `a = "abc"`,
+			options: []ParseOption{WithMaxInputSize(9)}},
+		{name: "StringLength",
+			src:     "a = 1\nb = \"" + huge + "\"",
+			options: []ParseOption{WithMaxStringLength(1024)},
+			fail:    "2:5: string exceeds maximum length of 1024 bytes"},
+		{name: "HeredocLength",
+			src:     "a = <<EOF\n" + strings.Repeat(huge[:100]+"\n", 20) + "EOF\n",
+			options: []ParseOption{WithMaxStringLength(1024)},
+			fail:    "12:1: heredoc exceeds maximum length of 1024 bytes"},
+		{name: "Entries",
+			src:     strings.Repeat("block \"label\" { a = 1 }\n", 10000),
+			options: []ParseOption{WithMaxEntries(100)},
+			fail:    "51:15: maximum of 100 entries exceeded"},
+		{name: "EntriesIgnoresMaps",
+			src:     `a = {b: {c: [{d: 1}]}}` + "\nblock {}",
+			options: []ParseOption{WithMaxEntries(2)}},
+		{name: "EntriesIgnoresHCL2Maps",
+			src:     "a = {b = 1, c = {d = [{e = 1}]}}\nblock {}",
+			options: []ParseOption{WithMaxEntries(2)}},
+		{name: "EntriesCountsAttributesAfterHCL2Maps",
+			src:     "a = {b = 1}\nblock { c = 1 }",
+			options: []ParseOption{WithMaxEntries(2)},
+			fail:    "2:11: maximum of 2 entries exceeded"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseString(test.src, test.options...)
+			if test.fail != "" {
+				require.EqualError(t, err, test.fail)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
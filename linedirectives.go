@@ -0,0 +1,103 @@
+package hcl
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// lineDirectiveRe matches a line directive, eg. "#line config.hcl.tmpl:12",
+// which must start at the beginning of its line.
+var lineDirectiveRe = regexp.MustCompile(`^(?:#|//)line ([^\s].*):(\d+)[ \t\r]*$`)
+
+// lineDirective declares that the line following line is line target of filename.
+type lineDirective struct {
+	line     int
+	filename string
+	target   int
+}
+
+// lineDirectives in a source, ordered by line.
+//
+// Preprocessors, such as template engines, emit line directives so that
+// positions in the HCL they generate can be mapped back to their own sources,
+// as with Go's "//line" comments.
+type lineDirectives []lineDirective
+
+// scanLineDirectives returns the line directives in data, which starts after
+// line lines of a larger input.
+func scanLineDirectives(data []byte, line int) lineDirectives {
+	var directives lineDirectives
+	for len(data) > 0 {
+		line++
+		end := bytes.IndexByte(data, '\n')
+		if end == -1 {
+			end = len(data)
+		}
+		text := data[:end]
+		data = data[end:]
+		if len(data) > 0 {
+			data = data[1:]
+		}
+		if len(text) < len("#line") || (text[0] != '#' && text[0] != '/') {
+			continue
+		}
+		match := lineDirectiveRe.FindSubmatch(text)
+		if match == nil {
+			continue
+		}
+		target, err := strconv.Atoi(string(match[2]))
+		if err != nil || target < 1 {
+			continue
+		}
+		directives = append(directives, lineDirective{line: line, filename: string(match[1]), target: target})
+	}
+	return directives
+}
+
+// find returns the directive in effect at line, if any.
+func (d lineDirectives) find(line int) (lineDirective, bool) {
+	i := sort.Search(len(d), func(i int) bool { return d[i].line > line })
+	if i == 0 {
+		return lineDirective{}, false
+	}
+	return d[i-1], true
+}
+
+// mapPosition returns pos as a position in the source named by the directive
+// in effect at pos.
+//
+// A position on the directive's own line, such as that of an entry whose
+// comments start with the directive, maps to the line following it.
+func (d lineDirectives) mapPosition(pos lexer.Position) lexer.Position {
+	directive, ok := d.find(pos.Line)
+	if !ok || pos.Line == 0 {
+		return pos
+	}
+	pos.Filename = directive.filename
+	if pos.Line > directive.line {
+		pos.Line = directive.target + pos.Line - directive.line - 1
+	} else {
+		pos.Line = directive.target
+	}
+	return pos
+}
+
+// mapPositions maps the positions in node through the directives.
+func (d lineDirectives) mapPositions(node Node) error {
+	if len(d) == 0 {
+		return nil
+	}
+	return mapPositions(node, d.mapPosition)
+}
+
+// mapError maps the position of err through the directives.
+func (d lineDirectives) mapError(err error) error {
+	if len(d) == 0 {
+		return err
+	}
+	return mapErrorPosition(err, d.mapPosition)
+}
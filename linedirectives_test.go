@@ -0,0 +1,68 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const lineDirectiveSource = `name = "app"
+#line templates/server.hcl.tmpl:10
+server {
+  port = 8080
+}
+//line C:\templates\db.hcl.tmpl:3
+db = "postgres"
+`
+
+func TestLineDirectives(t *testing.T) {
+	positions := func(ast *AST) []string {
+		out := []string{}
+		_ = Visit(ast, func(node Node, next func() error) error {
+			if attr, ok := node.(*Attribute); ok {
+				out = append(out, attr.Key+"@"+attr.Value.Pos.String())
+			}
+			return next()
+		})
+		return out
+	}
+	expected := []string{
+		"name@1:8",
+		"port@templates/server.hcl.tmpl:11:10",
+		`db@C:\templates\db.hcl.tmpl:3:6`,
+	}
+	ast, err := ParseString(lineDirectiveSource)
+	require.NoError(t, err)
+	require.Equal(t, expected, positions(ast))
+	require.Equal(t, "templates/server.hcl.tmpl:10:1", ast.Entries[1].Pos.String())
+
+	ast, err = ParseReader(strings.NewReader(lineDirectiveSource))
+	require.NoError(t, err)
+	require.Equal(t, expected, positions(ast))
+
+	r := NewDocumentReader(strings.NewReader("a = 1\n---\n" + lineDirectiveSource))
+	ast, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, []string{"a@1:5"}, positions(ast))
+	ast, err = r.Next()
+	require.NoError(t, err)
+	require.Equal(t, []string{"name@3:8", expected[1], expected[2]}, positions(ast))
+}
+
+func TestLineDirectiveErrors(t *testing.T) {
+	source := "a = 1\n#line main.hcl.tmpl:20\nb = }\n"
+	_, err := ParseString(source)
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), `main.hcl.tmpl:20:5: unexpected token "}"`), err.Error())
+	_, err = ParseReader(strings.NewReader(source))
+	require.Error(t, err)
+	require.True(t, strings.HasPrefix(err.Error(), `main.hcl.tmpl:20:5: unexpected token "}"`), err.Error())
+
+	var config struct {
+		A int `hcl:"a"`
+		B int `hcl:"b"`
+	}
+	err = Unmarshal([]byte("a = 1\n#line main.hcl.tmpl:20\nb = \"x\"\n"), &config)
+	require.EqualError(t, err, `main.hcl.tmpl:20:5: b: expected a number but got "x"`)
+}
@@ -0,0 +1,60 @@
+package hcl
+
+import (
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// blockParser parses the blocks nested in lists.
+var blockParser = participle.MustBuild(&Block{}, parserOptions...)
+
+var (
+	identToken  = parser.Lexer().Symbols()["Ident"]
+	stringToken = parser.Lexer().Symbols()["String"]
+	punctToken  = parser.Lexer().Symbols()["Punct"]
+)
+
+// listBlocks captures consecutive blocks nested in a list, eg.
+// `[rule "a" {} rule "b" {}]`, which need not be separated by commas.
+//
+// Blocks are parsed only if their name, labels and opening brace are on the
+// same line, so that a block following an attribute whose value is a bare
+// identifier isn't mistaken for one nested in it.
+type listBlocks struct {
+	blocks []*Block
+}
+
+func (l *listBlocks) Parse(lex *lexer.PeekingLexer) error {
+	if !atBlockHeader(lex) {
+		return participle.NextMatch
+	}
+	for atBlockHeader(lex) {
+		block := &Block{}
+		if err := blockParser.ParseFromLexer(lex, block, participle.AllowTrailing(true)); err != nil {
+			return err
+		}
+		l.blocks = append(l.blocks, block)
+	}
+	return nil
+}
+
+// atBlockHeader returns true if the next tokens of lex are a block name,
+// labels and opening brace on one line.
+func atBlockHeader(lex *lexer.PeekingLexer) bool {
+	first, err := lex.Peek(0)
+	if err != nil || first.Type != identToken {
+		return false
+	}
+	for i := 1; ; i++ {
+		token, err := lex.Peek(i)
+		if err != nil || token.Pos.Line != first.Pos.Line {
+			return false
+		}
+		switch {
+		case token.Type == punctToken && token.Value == "{":
+			return true
+		case token.Type != identToken && token.Type != stringToken:
+			return false
+		}
+	}
+}
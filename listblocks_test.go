@@ -0,0 +1,81 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListBlocks(t *testing.T) {
+	src := `policy "default" {
+  rules = [
+    rule "allow" {
+      paths = ["/public"]
+    }
+    rule "deny" {
+      paths = ["/admin"]
+      log = true
+    },
+    "fallback",
+  ]
+}
+`
+	ast, err := ParseString(src)
+	require.NoError(t, err)
+	rules := ast.Entries[0].Block.Body[0].Attribute.Value
+	require.Len(t, rules.List, 3)
+	require.Equal(t, "rule", rules.List[0].Block.Name)
+	require.Equal(t, []string{"deny"}, rules.List[1].Block.Labels)
+	require.Equal(t, rules.List[1], rules.List[1].Block.Parent)
+	require.Equal(t, `rule "deny" {...}`, rules.List[1].String())
+	require.Equal(t, "fallback", *rules.List[2].Str)
+
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, src, string(data))
+	require.True(t, ASTEqual(ast, ast.Clone()))
+
+	data, err = NewPrinter(Compact()).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, `policy "default" { rules = [rule "allow" { paths = ["/public"] }, rule "deny" { paths = ["/admin"] log = true }, "fallback"] }`+"\n", string(data))
+	reparsed, err := ParseBytes(data)
+	require.NoError(t, err)
+	require.True(t, ASTEqual(ast, reparsed, IgnorePositions()))
+
+	// A block on the line after a bare identifier isn't nested in it.
+	ast, err = ParseString("mode = strict\nrule \"a\" {\n}\n")
+	require.NoError(t, err)
+	require.Len(t, ast.Entries, 2)
+
+	_, err = ParseString(`rule = allow "a" {}`)
+	require.EqualError(t, err, `1:8: blocks are only allowed as list elements`)
+}
+
+func TestUnmarshalListBlocks(t *testing.T) {
+	type rule struct {
+		Action string   `hcl:"action,label"`
+		Paths  []string `hcl:"paths"`
+		Log    bool     `hcl:"log,optional"`
+	}
+	type config struct {
+		Rules []rule `hcl:"rules"`
+	}
+	actual := config{}
+	err := Unmarshal([]byte(`
+		rules = [
+			rule "allow" { paths = ["/public"] }
+			rule "deny" {
+				paths = ["/admin"]
+				log = true
+			}
+		]
+	`), &actual)
+	require.NoError(t, err)
+	require.Equal(t, config{Rules: []rule{
+		{Action: "allow", Paths: []string{"/public"}},
+		{Action: "deny", Paths: []string{"/admin"}, Log: true},
+	}}, actual)
+
+	err = Unmarshal([]byte(`rules = [rule { paths = [] }]`), &config{})
+	require.EqualError(t, err, `1:10: rules[0]: block "rule" expects 1 label ("action"), got 0`)
+}
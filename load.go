@@ -0,0 +1,98 @@
+package hcl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// LoadOption configures LoadDir.
+type LoadOption func(options *loadOptions)
+
+type loadOptions struct {
+	parallelism  int
+	parseOptions []ParseOption
+}
+
+// WithParallelism sets the maximum number of files parsed concurrently by LoadDir.
+//
+// Defaults to runtime.GOMAXPROCS(0).
+func WithParallelism(n int) LoadOption {
+	return func(options *loadOptions) {
+		options.parallelism = n
+	}
+}
+
+// WithParseOptions sets the options used to parse each file.
+//
+// Pass WithInterner(NewInterner()) to share interned strings across files.
+func WithParseOptions(options ...ParseOption) LoadOption {
+	return func(opt *loadOptions) {
+		opt.parseOptions = append(opt.parseOptions, options...)
+	}
+}
+
+// LoadDir parses all files with a ".hcl" extension in dir.
+//
+// Files are parsed concurrently, but their entries are merged into a single
+// AST in filename order, so the result is deterministic. If any files fail to
+// parse, the error for the first such file is returned.
+func LoadDir(dir string, options ...LoadOption) (*AST, error) {
+	opt := &loadOptions{parallelism: runtime.GOMAXPROCS(0)}
+	for _, option := range options {
+		option(opt)
+	}
+	if opt.parallelism < 1 {
+		opt.parallelism = 1
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".hcl") {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+	asts := make([]*AST, len(files))
+	errs := make([]error, len(files))
+	work := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < opt.parallelism && i < len(files); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				asts[i], errs[i] = parseFile(files[i], opt.parseOptions)
+			}
+		}()
+	}
+	for i := range files {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	out := &AST{}
+	for i, ast := range asts {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out.Entries = append(out.Entries, ast.Entries...)
+		out.TrailingComments = append(out.TrailingComments, ast.TrailingComments...)
+		out.TrailingCommentStyles = append(out.TrailingCommentStyles, ast.TrailingCommentStyles...)
+	}
+	return out, AddParentRefs(out)
+}
+
+func parseFile(path string, options []ParseOption) (*AST, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close() // nolint: errcheck
+	return Parse(r, options...)
+}
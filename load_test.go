@@ -0,0 +1,43 @@
+package hcl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcl-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"b.hcl":      "b = 2\n",
+		"a.hcl":      "a = 1\n",
+		"c.hcl":      "c {\n  d = 3\n}\n",
+		"ignore.txt": "not hcl",
+	}
+	for name, content := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+		require.NoError(t, err)
+	}
+	for _, parallelism := range []int{1, 4} {
+		ast, err := LoadDir(dir, WithParallelism(parallelism))
+		require.NoError(t, err)
+		keys := []string{}
+		for _, entry := range ast.Entries {
+			keys = append(keys, entry.Key())
+		}
+		require.Equal(t, []string{"a", "b", "c"}, keys)
+		require.Equal(t, filepath.Join(dir, "b.hcl"), ast.Entries[1].Pos.Filename)
+		require.Equal(t, ast, ast.Entries[2].Parent)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "d.hcl"), []byte("d = }\n"), 0600)
+	require.NoError(t, err)
+	_, err = LoadDir(dir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), filepath.Join(dir, "d.hcl")+":1:5")
+}
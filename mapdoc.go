@@ -0,0 +1,66 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/alecthomas/participle"
+)
+
+// Attribute keys that can be written without quoting.
+var identRe = regexp.MustCompile(`^[[:alpha:]]\w*(-\w+)*$`)
+
+// unmarshalMapDocument decodes the attributes of a document into a map, such
+// as a map[string]string or map[string]interface{}.
+func unmarshalMapDocument(rv reflect.Value, entries []*Entry, opt *marshalOptions) error {
+	t := rv.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("map keys must be strings but are %s", t.Key())
+	}
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMap(t))
+	}
+	seen := map[string]*Entry{}
+	for _, entry := range entries {
+		if err := opt.checkContext(); err != nil {
+			return err
+		}
+		if entry.Block != nil {
+			return participle.Errorf(entry.Pos, "can't unmarshal block %q into %s", entry.Key(), t)
+		}
+		if prev, ok := seen[entry.Key()]; ok {
+			return participle.Errorf(entry.Pos, "duplicate field %q at %s", entry.Key(), prev.Pos)
+		}
+		seen[entry.Key()] = entry
+		value, err := resolveValueSources(entry.Attribute.Value, opt)
+		if err != nil {
+			return err
+		}
+		el := reflect.New(t.Elem()).Elem()
+		if err := unmarshalValue(el, value, opt); err != nil {
+			return withFieldPath(entry.Key(), annotateFieldError(value.Pos, err))
+		}
+		key := reflect.New(t.Key()).Elem()
+		key.SetString(entry.Key())
+		rv.SetMapIndex(key, el)
+	}
+	return nil
+}
+
+// mapToEntries marshals a map to attributes, in key order.
+func mapToEntries(v reflect.Value, opt *marshalOptions) ([]*Entry, error) {
+	value, err := valueToValue(v, opt)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, 0, len(value.Map))
+	for _, entry := range value.Map {
+		key := *entry.Key.Str
+		if !identRe.MatchString(key) {
+			return nil, fmt.Errorf("invalid attribute name %q", key)
+		}
+		entries = append(entries, &Entry{Attribute: &Attribute{Key: key, Value: entry.Value}})
+	}
+	return entries, nil
+}
@@ -2,23 +2,118 @@ package hcl
 
 import (
 	"bytes"
+	"context"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/alecthomas/participle"
 	"github.com/alecthomas/participle/lexer"
 )
 
 // marshalOptions defines options for the marshalling/unmarshalling process
 type marshalOptions struct {
 	inferHCLTags bool
+	// Interpret tags as hashicorp/hcl v1 does.
+	hcl1Tags     bool
+	baseDir      string
+	valueSources map[string]ValueSource
+	// Registrations for a single call, taking precedence over global ones.
+	blockDecoders map[string]BlockDecoder
+	unions        map[reflect.Type]*union
+	kinds         map[string]reflect.Type
+	scalars       map[reflect.Type]scalar
+	defaultsBlock string
+	templateBlock string
+	extendsAttr   string
+	profile       *string
+	unused        func(path string, pos lexer.Position)
+	warning       func(warning Warning)
+	suppressed    func(diagnostic Diagnostic)
+	tracer        func(event TraceEvent)
+	messages      MessageCatalog
+	// Filename of the positions of values from ApplyOverrides.
+	overrideSource string
+	// Format os.FileMode attributes as octal.
+	octalFileModes  bool
+	nonFiniteFloats NonFiniteFloatPolicy
+	mode            Mode
+	// Omit fields of types that can't be marshalled rather than failing.
+	skipUnsupportedTypes bool
+	nameTransform        func(string) string
+	caseInsensitive      bool
+	maxDepth             int
+	// Retain the source order of map entries in MarshalInto.
+	preserveMapOrder bool
+	// Convert between value types when unmarshalling.
+	weakTypes bool
+	// Silently truncate numbers that don't fit their field when unmarshalling.
+	truncateNumbers bool
+	// Struct tag key used in place of hcl:"".
+	tagName string
+	// Accept and produce durations with days and weeks.
+	extendedDurations bool
+	// Append to slices that already have elements when unmarshalling, rather
+	// than replacing them.
+	appendSlices bool
+	// Accept blocks and attributes with object values interchangeably.
+	mixedBlockSyntax bool
+	// Encrypts secret fields and decrypts enveloped values.
+	keyring Keyring
+	// Rewrites values before they are printed.
+	valueFormatter ValueFormatter
+	// Configure the printer of marshalled documents.
+	printerOptions []PrinterOption
+	// Omit help:"" text from marshalled documents, other than schemas.
+	omitHelpComments bool
+	// Schema version pinned by WithSchemaVersion, and migrations from older versions.
+	schemaVersion    int
+	schemaMigrations map[int]SchemaMigration
+	// Decode entries in isolation, skipping checks that apply to a block as a
+	// whole, such as required fields. Used by CheckCompatibility.
+	partial bool
+	// As partial, for the entries of the document being decoded, but not for
+	// the blocks within it. Used by Decoder.
+	partialRoot bool
+	// Context passed to UnmarshalContext, and the decode context of the
+	// current block, see WithBlockContext.
+	ctx          context.Context
+	blockContext func(ctx context.Context, block *Block) context.Context
+	// Receives measurements of parsing and unmarshalling, see WithMetrics.
+	metrics Metrics
+
+	// State accumulated during unmarshalling.
+	path []string
+	// Position of the block being decoded, if any.
+	blockPos  lexer.Position
+	fallbacks []*fallback
+
+	// State accumulated during marshalling: struct pointers being marshalled,
+	// to detect cycles, and types being reflected into schemas, to terminate
+	// recursion.
+	marshalling map[pointerRef]bool
+	schemaTypes map[reflect.Type]string
+	schemaPath  []string
+	depth       int
+}
+
+// pointerRef identifies a pointer by address and type, as a struct and its
+// first field share an address.
+type pointerRef struct {
+	ptr uintptr
+	t   reflect.Type
 }
 
 // MarshalOption configures optional marshalling behaviour.
@@ -33,6 +128,220 @@ func InferHCLTags(v bool) MarshalOption {
 	}
 }
 
+// OctalFileModes specifies whether to marshal os.FileMode fields as octal
+// literals, eg. "mode = 0o755".
+//
+// Fields may also be marshalled in a specific base with the base:"" tag.
+func OctalFileModes(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.octalFileModes = v
+	}
+}
+
+// SkipUnsupportedTypes specifies whether to omit fields with types that can't
+// be represented in HCL, such as channels and functions, rather than failing.
+func SkipUnsupportedTypes(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.skipUnsupportedTypes = v
+	}
+}
+
+// NonFiniteFloatPolicy controls how ±Inf and NaN float values are handled.
+type NonFiniteFloatPolicy int
+
+const (
+	// NonFiniteFloatsError fails to marshal ±Inf and NaN, which have no HCL representation.
+	NonFiniteFloatsError NonFiniteFloatPolicy = iota
+	// NonFiniteFloatsAsStrings marshals ±Inf and NaN as the strings "+Inf", "-Inf" and "NaN",
+	// and accepts those strings when unmarshalling float fields.
+	NonFiniteFloatsAsStrings
+)
+
+// NonFiniteFloats sets the policy for handling ±Inf and NaN float values.
+//
+// Defaults to NonFiniteFloatsError.
+func NonFiniteFloats(policy NonFiniteFloatPolicy) MarshalOption {
+	return func(options *marshalOptions) {
+		options.nonFiniteFloats = policy
+	}
+}
+
+// Mode selects what is marshalled from a Go value.
+//
+// Modes may be combined: ModeSchema|ModeData reflects a schema in which the
+// default of each attribute set in the value is that value, eg. to document
+// the configuration in effect.
+type Mode int
+
+const (
+	// ModeData marshals the values of fields. This is the default.
+	ModeData Mode = 1 << iota
+	// ModeSchema marshals the schema of fields, as with Schema.
+	ModeSchema
+	// ModeExample marshals an example configuration, as with Example.
+	ModeExample
+)
+
+func (m Mode) String() string {
+	names := []string{}
+	for _, mode := range []struct {
+		mode Mode
+		name string
+	}{{ModeData, "data"}, {ModeSchema, "schema"}, {ModeExample, "example"}} {
+		if m&mode.mode != 0 {
+			names = append(names, mode.name)
+			m &^= mode.mode
+		}
+	}
+	if m != 0 || len(names) == 0 {
+		names = append(names, strconv.Itoa(int(m)))
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalMode sets what Marshal and its variants marshal from a Go value.
+//
+// Defaults to ModeData.
+func MarshalMode(mode Mode) MarshalOption {
+	return func(options *marshalOptions) {
+		options.mode = mode
+	}
+}
+
+// WithUnusedCallback calls fn for each entry that is present in the input but not
+// decoded into any field, rather than failing.
+//
+// path is the dotted path to the entry, including the names and labels of its
+// enclosing blocks, eg. "service.api.port".
+func WithUnusedCallback(fn func(path string, pos lexer.Position)) MarshalOption {
+	return func(options *marshalOptions) {
+		options.unused = fn
+	}
+}
+
+// PreserveMapOrder specifies whether MarshalInto retains the order of map
+// entries already present in the AST, rather than ordering them by key.
+//
+// Entries for new keys follow those already present, in key order.
+func PreserveMapOrder(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.preserveMapOrder = v
+	}
+}
+
+// TruncateNumbers specifies whether numbers that can't be represented exactly
+// by an integer field, or are out of range for a numeric field, are truncated
+// when unmarshalling rather than rejected.
+func TruncateNumbers(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.truncateNumbers = v
+	}
+}
+
+// AppendSlices specifies whether unmarshalling into a slice that already has
+// elements, such as one populated with defaults before unmarshalling, appends
+// to it rather than replacing it. This applies to both lists and blocks.
+func AppendSlices(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.appendSlices = v
+	}
+}
+
+// MixedBlockSyntax specifies whether unmarshalling accepts blocks and
+// attributes with object values interchangeably, as users of HCL2 often mix
+// both, eg. `settings = { debug = true }` for a block field, and
+// `settings { debug = true }` for a map or object field.
+//
+// A repeated block field also accepts a list of objects.
+func MixedBlockSyntax(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.mixedBlockSyntax = v
+	}
+}
+
+// A ValueFormatter returns the value to print in place of v, the value at
+// path, or nil to print v unchanged. It may modify v in place.
+type ValueFormatter func(path string, v *Value) *Value
+
+// WithValueFormatter calls formatter with each value marshalled, to apply
+// policies such as rounding floats, normalising URLs or masking tokens to the
+// output without modifying the marshalled structs.
+//
+// path is the Query path of the value, eg. server["web"].hosts[0]. The
+// elements of lists and maps are formatted before the values containing
+// them. Schemas are not formatted.
+func WithValueFormatter(formatter ValueFormatter) MarshalOption {
+	return func(options *marshalOptions) {
+		options.valueFormatter = formatter
+	}
+}
+
+// WithPrinterOptions configures the Printer used by Marshal, MarshalIndent,
+// MarshalCompact and Example, eg. WithPrinterOptions(WrapComments(80)).
+func WithPrinterOptions(printerOptions ...PrinterOption) MarshalOption {
+	return func(options *marshalOptions) {
+		options.printerOptions = append(options.printerOptions, printerOptions...)
+	}
+}
+
+// WithTagName uses struct tags with the given key, eg. config:"", in place of
+// hcl:"" tags.
+func WithTagName(name string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.tagName = name
+	}
+}
+
+// WithHelpComments specifies whether Marshal emits the help:"" text of each
+// field as comments above its attribute or block, so that generated
+// configuration files are self-documenting. This is the default. Schemas
+// always include help text.
+func WithHelpComments(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.omitHelpComments = !v
+	}
+}
+
+// helpComments returns the comments for a field with tag.
+func (o *marshalOptions) helpComments(tag tag, schema bool) []string {
+	if o.omitHelpComments && !schema {
+		return nil
+	}
+	return tag.comments()
+}
+
+// tagKey returns the struct tag key for field names and options.
+func (o *marshalOptions) tagKey() string {
+	if o.tagName != "" {
+		return o.tagName
+	}
+	return "hcl"
+}
+
+// withContext sets the context passed to value sources during unmarshalling.
+func withContext(ctx context.Context) MarshalOption {
+	return func(options *marshalOptions) {
+		options.ctx = ctx
+	}
+}
+
+// context returns the context for unmarshalling, defaulting to context.Background().
+func (o *marshalOptions) context() context.Context {
+	if o.ctx == nil {
+		return context.Background()
+	}
+	return o.ctx
+}
+
+// checkContext returns the error of the unmarshalling context, if it has been
+// cancelled or its deadline has expired.
+func (o *marshalOptions) checkContext() error {
+	if o.ctx == nil {
+		return nil
+	}
+	return o.ctx.Err()
+}
+
 // newMarshalOptions creates marshal options from a set of options
 func newMarshalOptions(options ...MarshalOption) *marshalOptions {
 	opt := &marshalOptions{}
@@ -43,6 +352,14 @@ func newMarshalOptions(options ...MarshalOption) *marshalOptions {
 }
 
 // Marshal a Go type to HCL.
+//
+// v is usually a struct or a pointer to one, but may also be a map with string
+// keys, which is marshalled to a document of attributes.
+//
+// Output is deterministic: map entries are emitted in key order at every
+// level of nesting, including maps within lists, other maps and interface
+// values, and numbers are formatted independently of the Go version, so the
+// same value always marshals to the same bytes. Maps must have string keys.
 func Marshal(v interface{}, options ...MarshalOption) ([]byte, error) {
 	opt := &marshalOptions{}
 	for _, option := range options {
@@ -52,12 +369,196 @@ func Marshal(v interface{}, options ...MarshalOption) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	return MarshalAST(ast)
+	return NewPrinter(opt.printerOptions...).Print(ast)
+}
+
+// MarshalIndent is like Marshal but each line begins with prefix, and each
+// level of nesting is indented by indent.
+func MarshalIndent(v interface{}, prefix, indent string, options ...MarshalOption) ([]byte, error) {
+	ast, err := MarshalToAST(v, options...)
+	if err != nil {
+		return nil, err
+	}
+	printerOptions := append(newMarshalOptions(options...).printerOptions, Indent(prefix, indent))
+	return NewPrinter(printerOptions...).Print(ast)
+}
+
+// MarshalCompact is like Marshal but emits each top-level entry on a single
+// line, eg. `server "a" { port = 1 }`.
+//
+// Comments are emitted as /* */ comments, and heredocs as strings.
+func MarshalCompact(v interface{}, options ...MarshalOption) ([]byte, error) {
+	ast, err := MarshalToAST(v, options...)
+	if err != nil {
+		return nil, err
+	}
+	printerOptions := append(newMarshalOptions(options...).printerOptions, Compact())
+	return NewPrinter(printerOptions...).Print(ast)
 }
 
 // MarshalToAST marshals a Go type to a hcl.AST.
 func MarshalToAST(v interface{}, options ...MarshalOption) (*AST, error) {
-	return marshalToAST(v, false, newMarshalOptions(options...))
+	opt := newMarshalOptions(options...)
+	switch opt.mode {
+	case 0, ModeData:
+		return marshalToAST(v, false, opt)
+	case ModeSchema, ModeSchema | ModeData:
+		return marshalToAST(v, true, opt)
+	case ModeExample:
+		return exampleToAST(v, opt)
+	default:
+		return nil, fmt.Errorf("unsupported marshal mode %s", opt.mode)
+	}
+}
+
+// MarshalInto marshals a Go type into an existing AST.
+//
+// Attributes and blocks corresponding to fields of v are updated in place,
+// preserving their comments and order, while entries that don't correspond
+// to any field are left untouched. Entries for fields that no longer have a
+// value are removed, and new entries are appended.
+func MarshalInto(ast *AST, v interface{}, options ...MarshalOption) error {
+	generated, err := MarshalToAST(v, options...)
+	if err != nil {
+		return err
+	}
+	opt := newMarshalOptions(options...)
+	ast.Entries = mergeEntries(ast.Entries, generated.Entries, reflect.Indirect(reflect.ValueOf(v)).Type(), opt)
+	return AddParentRefs(ast)
+}
+
+// mergeEntries merges generated entries for struct type t into existing entries.
+func mergeEntries(existing, generated []*Entry, t reflect.Type, opt *marshalOptions) []*Entry {
+	known := knownKeys(t, opt)
+	used := make([]bool, len(generated))
+	match := func(entry *Entry) int {
+		candidate := -1
+		for i, gen := range generated {
+			if used[i] || gen.Key() != entry.Key() || (gen.Block == nil) != (entry.Block == nil) {
+				continue
+			}
+			if entry.Block == nil || equalStrings(gen.Block.Labels, entry.Block.Labels) {
+				return i
+			}
+			if candidate == -1 {
+				candidate = i
+			}
+		}
+		return candidate
+	}
+	out := make([]*Entry, 0, len(existing)+len(generated))
+	for _, entry := range existing {
+		if _, ok := known[entry.Key()]; !ok {
+			out = append(out, entry)
+			continue
+		}
+		i := match(entry)
+		if i == -1 {
+			continue
+		}
+		used[i] = true
+		gen := generated[i]
+		if entry.Attribute != nil {
+			if opt.preserveMapOrder {
+				orderMapEntries(entry.Attribute.Value, gen.Attribute.Value)
+			}
+			entry.Attribute.Value = gen.Attribute.Value
+		} else {
+			entry.Block.Labels = gen.Block.Labels
+			entry.Block.Body = mergeEntries(entry.Block.Body, gen.Block.Body, known[entry.Key()], opt)
+		}
+		out = append(out, entry)
+	}
+	for i, gen := range generated {
+		if !used[i] {
+			out = append(out, gen)
+		}
+	}
+	return out
+}
+
+// orderMapEntries reorders the map entries of generated, recursively, to match
+// the order of the same keys in existing. Entries for new keys follow.
+func orderMapEntries(existing, generated *Value) {
+	switch {
+	case existing.HaveList && generated.HaveList:
+		for i := 0; i < len(existing.List) && i < len(generated.List); i++ {
+			orderMapEntries(existing.List[i], generated.List[i])
+		}
+
+	case existing.HaveMap && generated.HaveMap:
+		remaining := make(map[string]*MapEntry, len(generated.Map))
+		for _, entry := range generated.Map {
+			remaining[mapKey(entry.Key)] = entry
+		}
+		out := make([]*MapEntry, 0, len(generated.Map))
+		for _, entry := range existing.Map {
+			key := mapKey(entry.Key)
+			if gen, ok := remaining[key]; ok {
+				orderMapEntries(entry.Value, gen.Value)
+				out = append(out, gen)
+				delete(remaining, key)
+			}
+		}
+		for _, entry := range generated.Map {
+			if _, ok := remaining[mapKey(entry.Key)]; ok {
+				out = append(out, entry)
+			}
+		}
+		generated.Map = out
+	}
+}
+
+// mapKey returns the key of a map entry as a string.
+func mapKey(key *Value) string {
+	switch {
+	case key.Str != nil:
+		return *key.Str
+	case key.Type != nil:
+		return *key.Type
+	default:
+		return key.String()
+	}
+}
+
+// knownKeys returns the HCL keys of the fields in struct type t, mapped to the
+// struct type of block fields.
+func knownKeys(t reflect.Type, opt *marshalOptions) map[string]reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	out := map[string]reflect.Type{}
+	if t.Kind() != reflect.Struct {
+		return out
+	}
+	fields, err := flattenFields(reflect.New(t).Elem(), opt)
+	if err != nil {
+		return out
+	}
+	for _, field := range fields {
+		tag := parseTag(t, field, opt)
+		if tag.name == "" || tag.label || tag.remain {
+			continue
+		}
+		ft := field.t.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Map {
+			ft = ft.Elem()
+		}
+		out[tag.name] = ft
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // MarshalAST marshals an AST to HCL bytes.
@@ -69,25 +570,35 @@ func MarshalAST(ast Node) ([]byte, error) {
 
 // MarshalASTToWriter marshals a hcl.AST to an io.Writer.
 func MarshalASTToWriter(ast Node, w io.Writer) error {
-	return marshalNode(w, "", ast)
+	return NewPrinter().Fprint(w, ast)
 }
 
 func marshalToAST(v interface{}, schema bool, opt *marshalOptions) (*AST, error) {
 	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Ptr {
-		return nil, fmt.Errorf("expected a pointer to a struct, not %T", v)
+	if mv := reflect.Indirect(rv); mv.Kind() == reflect.Map && !schema {
+		entries, err := mapToEntries(mv, opt)
+		if err != nil {
+			return nil, err
+		}
+		ast := &AST{Entries: entries}
+		if opt.valueFormatter != nil {
+			if err := formatValues(ast, opt.valueFormatter); err != nil {
+				return nil, err
+			}
+		}
+		return ast, nil
 	}
-	rv = rv.Elem()
-	if rv.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("expected a pointer to a struct, not %T", v)
+	rv, err := structPointer(rv, v)
+	if err != nil {
+		return nil, err
 	}
 	var (
-		err    error
 		labels []string
 		ast    = &AST{
 			Schema: schema,
 		}
 	)
+	// Pass the pointer so that cycles back to the root are detected.
 	ast.Entries, labels, err = structToEntries(rv, schema, opt)
 	if err != nil {
 		return nil, err
@@ -95,9 +606,70 @@ func marshalToAST(v interface{}, schema bool, opt *marshalOptions) (*AST, error)
 	if len(labels) > 0 {
 		return nil, fmt.Errorf("unexpected labels %s at top level", strings.Join(labels, ", "))
 	}
+	if opt.schemaVersion != 0 && !schema {
+		version := &Entry{Attribute: &Attribute{
+			Key:   schemaVersionAttr,
+			Value: &Value{Number: big.NewFloat(float64(opt.schemaVersion))},
+		}}
+		ast.Entries = append([]*Entry{version}, ast.Entries...)
+	}
+	if opt.valueFormatter != nil && !schema {
+		if err := formatValues(ast, opt.valueFormatter); err != nil {
+			return nil, err
+		}
+	}
 	return ast, nil
 }
 
+// formatValues replaces the attribute values in ast with those returned by
+// formatter.
+func formatValues(ast *AST, formatter ValueFormatter) error {
+	if err := AddParentRefs(ast); err != nil {
+		return err
+	}
+	var format func(path string, v *Value) *Value
+	format = func(path string, v *Value) *Value {
+		for i, el := range v.List {
+			v.List[i] = format(path+"["+strconv.Itoa(i)+"]", el)
+		}
+		for _, entry := range v.Map {
+			if entry.Key.Str != nil {
+				entry.Value = format(path+"["+strconv.Quote(*entry.Key.Str)+"]", entry.Value)
+			}
+		}
+		if out := formatter(path, v); out != nil {
+			return out
+		}
+		return v
+	}
+	return Visit(ast, func(node Node, next func() error) error {
+		if attr, ok := node.(*Attribute); ok {
+			attr.Value = format(attr.Path(), attr.Value)
+			return nil
+		}
+		return next()
+	})
+}
+
+// structPointer returns rv, which holds v, as a pointer to a struct, copying
+// it if it is a struct value.
+func structPointer(rv reflect.Value, v interface{}) (reflect.Value, error) {
+	switch {
+	case !rv.IsValid():
+		return rv, fmt.Errorf("can't marshal nil")
+	case rv.Kind() == reflect.Struct:
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		return ptr, nil
+	case rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Struct:
+		return rv, nil
+	case rv.Kind() == reflect.Ptr && rv.IsNil():
+		return rv, fmt.Errorf("can't marshal nil %T", v)
+	default:
+		return rv, fmt.Errorf("can't marshal %T, expected a struct, a pointer to a struct or a map", v)
+	}
+}
+
 func structToEntries(v reflect.Value, schema bool, opt *marshalOptions) (entries []*Entry, labels []string, err error) {
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
@@ -105,25 +677,77 @@ func structToEntries(v reflect.Value, schema bool, opt *marshalOptions) (entries
 				return nil, nil, nil
 			}
 			v = reflect.New(v.Type().Elem())
+		} else if !schema {
+			leave, err := opt.visit(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			defer leave()
 		}
 		v = v.Elem()
 	}
-	fields, err := flattenFields(v)
+	if schema {
+		// Recursive types are described once, and referred to by the path of
+		// the block describing them by nested occurrences.
+		if _, ok := opt.schemaTypes[v.Type()]; ok {
+			return nil, nil, nil
+		}
+		if opt.schemaTypes == nil {
+			opt.schemaTypes = map[reflect.Type]string{}
+		}
+		opt.schemaTypes[v.Type()] = strings.Join(opt.schemaPath, ".")
+		defer delete(opt.schemaTypes, v.Type())
+	}
+	fields, err := flattenFields(v, opt)
 	if err != nil {
 		return nil, nil, err
 	}
 	for _, field := range fields {
 		tag := parseTag(v.Type(), field, opt)
 		switch {
+		case tag.name == "":
+			continue
+
 		case tag.label:
 			if schema {
 				labels = append(labels, tag.name)
 			} else {
-				labels = append(labels, field.v.String())
+				label, err := labelFromValue(field.v)
+				if tag.labelTemplate != nil {
+					label, err = formatLabelParts(v, fields, tag, opt)
+				}
+				if err != nil {
+					return nil, nil, withFieldPath(tag.name, err)
+				}
+				labels = append(labels, label)
+			}
+
+		case tag.remain && field.t.Type == blocksType:
+			if schema {
+				continue
+			}
+			for _, block := range field.v.Interface().(Blocks) {
+				entries = append(entries, &Entry{Block: block.Block.Clone()})
 			}
 
 		case tag.block:
-			if field.v.Kind() == reflect.Slice {
+			if tag.union != nil {
+				blocks, err := unionToBlocks(field.v, tag, tag.union, schema, opt)
+				if err != nil {
+					return nil, nil, withFieldPath(tag.name, err)
+				}
+				for _, block := range blocks {
+					entries = append(entries, &Entry{Block: block})
+				}
+			} else if field.v.Kind() == reflect.Map {
+				blocks, err := mapToBlocks(field.v, tag, schema, opt)
+				if err != nil {
+					return nil, nil, withFieldPath(tag.name, err)
+				}
+				for _, block := range blocks {
+					entries = append(entries, &Entry{Block: block})
+				}
+			} else if field.v.Kind() == reflect.Slice {
 				var blocks []*Block
 				if schema {
 					block, err := sliceToBlockSchema(field.v.Type(), tag, opt)
@@ -135,23 +759,50 @@ func structToEntries(v reflect.Value, schema bool, opt *marshalOptions) (entries
 					blocks, err = sliceToBlocks(field.v, tag, opt)
 				}
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withFieldPath(tag.name, err)
 				}
 				for _, block := range blocks {
 					entries = append(entries, &Entry{Block: block})
 				}
 			} else {
+				if !schema && field.v.Kind() == reflect.Ptr && field.v.IsNil() {
+					continue
+				}
 				block, err := valueToBlock(field.v, tag, schema, opt)
 				if err != nil {
-					return nil, nil, err
+					return nil, nil, withFieldPath(tag.name, err)
 				}
 				entries = append(entries, &Entry{Block: block})
 			}
 
-		default:
-			attr, err := fieldToAttr(field, tag, schema)
+		case tag.inline:
+			if !schema && field.v.Kind() == reflect.Ptr && field.v.IsNil() {
+				continue
+			}
+			block, err := valueToBlock(field.v, tag, schema, opt)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, withFieldPath(tag.name, err)
+			}
+			value, err := blockToObject(block)
+			if err != nil {
+				return nil, nil, withFieldPath(tag.name, err)
+			}
+			entries = append(entries, &Entry{Attribute: &Attribute{
+				Key:      tag.name,
+				Comments: opt.helpComments(tag, schema),
+				Value:    value,
+				Optional: schema,
+			}})
+
+		default:
+			if tag.optional && !schema && tag.defaultValue == "" && field.v.IsZero() {
+				continue
+			}
+			attr, err := fieldToAttr(field, tag, schema, opt)
+			if opt.skipUnsupportedTypes && errors.As(err, &unsupportedTypeError{}) {
+				continue
+			} else if err != nil {
+				return nil, nil, withFieldPath(tag.name, err)
 			}
 			hasDefaultAndEqualsValue := attr.Default != nil && attr.Value.String() == attr.Default.String()
 			noDefaultButIsZero := attr.Default == nil && field.v.IsZero()
@@ -165,29 +816,99 @@ func structToEntries(v reflect.Value, schema bool, opt *marshalOptions) (entries
 	return entries, labels, nil
 }
 
-func fieldToAttr(field field, tag tag, schema bool) (*Attribute, error) {
+func fieldToAttr(field field, tag tag, schema bool, opt *marshalOptions) (*Attribute, error) {
 	attr := &Attribute{
 		Key:      tag.name,
-		Comments: tag.comments(),
+		Comments: opt.helpComments(tag, schema),
 	}
 	var err error
-	if schema {
-		attr.Value, err = attrSchema(field.v.Type())
-	} else {
-		attr.Value, err = valueToValue(field.v)
+	switch {
+	case schema:
+		attr.Value, err = attrSchema(field.v.Type(), opt)
+	case field.v.Type() == bytesType:
+		attr.Value, err = bytesToValue(field.v.Bytes(), tag.encoding)
+	default:
+		attr.Value, err = valueToValue(field.v, opt)
 	}
 	if err != nil {
 		return nil, err
 	}
+	if tag.secret && !schema && opt.keyring != nil {
+		attr.Value, err = encryptValue(attr.Value, opt.keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tag.set && !schema && attr.Value.HaveList {
+		sortValues(attr.Value.List)
+	}
+	if tag.base != 0 && !schema {
+		setNumberBase(attr.Value, tag.base)
+	}
+	if tag.quote != "" && !schema {
+		setQuoteStyle(attr.Value, tag.quote)
+	}
 	attr.Default, err = defaultValueFromTag(field, tag.defaultValue)
 	if err != nil {
 		return nil, err
 	}
+	if tag.trailingComment != "" {
+		attr.TrailingComments = []string{tag.trailingComment}
+	}
 	attr.Optional = (tag.optional || attr.Default != nil) && schema
+	// Values don't make required attributes optional, and secrets aren't
+	// disclosed as defaults.
+	if schema && opt.mode&ModeData != 0 && !tag.secret && !field.v.IsZero() {
+		attr.Default, err = valueToValue(field.v, opt)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if schema {
+		attr.Deprecated = tag.deprecated
+		attr.ReadOnly = tag.readonly
+		if tag.pattern != nil {
+			attr.Pattern = tag.pattern.String()
+		}
+		if tag.min != nil {
+			attr.Min = &Value{Number: tag.min}
+		}
+		if tag.max != nil {
+			attr.Max = &Value{Number: tag.max}
+		}
+	}
 	attr.Enum, err = enumValuesFromTag(field, tag.enum)
 	return attr, err
 }
 
+// floatToValue converts f to a Value, applying the policy for non-finite floats.
+func floatToValue(f float64, opt *marshalOptions) (*Value, error) {
+	if !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return &Value{Number: big.NewFloat(f)}, nil
+	}
+	if opt.nonFiniteFloats != NonFiniteFloatsAsStrings {
+		return nil, fmt.Errorf("can't marshal non-finite float %v", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	return &Value{Str: &s}, nil
+}
+
+// setNumberBase sets the base used to format v, or the elements of v if it is a list or map.
+func setNumberBase(v *Value, base int) {
+	switch {
+	case v.Number != nil:
+		v.Base = base
+	case v.HaveList:
+		for _, el := range v.List {
+			setNumberBase(el, base)
+		}
+	case v.HaveMap:
+		for _, entry := range v.Map {
+			setNumberBase(entry.Value, base)
+		}
+	}
+}
+
 func defaultValueFromTag(f field, defaultValue string) (*Value, error) {
 	v, err := valueFromTag(f, defaultValue)
 	if err != nil {
@@ -227,6 +948,15 @@ func valueFromTag(f field, defaultValue string) (*Value, error) {
 	if k == reflect.Ptr {
 		k = f.v.Elem().Kind()
 	}
+	if t := f.v.Type(); t == pathType || (t.Kind() == reflect.Ptr && t.Elem() == pathType) {
+		return &Value{Str: &defaultValue}, nil
+	} else if t == numberType || (t.Kind() == reflect.Ptr && t.Elem() == numberType) {
+		v, err := numberToValue(Number(defaultValue))
+		if err != nil {
+			return nil, fmt.Errorf("error converting %q to number", defaultValue)
+		}
+		return v, nil
+	}
 
 	switch k {
 	case reflect.String:
@@ -249,7 +979,7 @@ func valueFromTag(f field, defaultValue string) (*Value, error) {
 		}, nil
 	case reflect.Float32, reflect.Float64:
 		n, err := strconv.ParseFloat(defaultValue, 10)
-		if err != nil {
+		if err != nil || math.IsInf(n, 0) || math.IsNaN(n) {
 			return nil, fmt.Errorf("error converting %q to float", defaultValue)
 		}
 		return &Value{
@@ -309,7 +1039,7 @@ func valueFromTag(f field, defaultValue string) (*Value, error) {
 			HaveMap: true,
 			Map:     mapEntries,
 		}, nil
-	case reflect.Slice:
+	case reflect.Slice, reflect.Array:
 		slice := []*Value{}
 		list := strings.Split(defaultValue, ",")
 		valueType := f.t.Type.Elem()
@@ -328,6 +1058,9 @@ func valueFromTag(f field, defaultValue string) (*Value, error) {
 			}
 			slice = append(slice, value)
 		}
+		if f.t.Type.Kind() == reflect.Array && len(slice) != f.t.Type.Len() {
+			return nil, fmt.Errorf("expected %d elements but got %d", f.t.Type.Len(), len(slice))
+		}
 
 		return &Value{
 			HaveList: true,
@@ -335,15 +1068,29 @@ func valueFromTag(f field, defaultValue string) (*Value, error) {
 		}, nil
 	}
 
-	return nil, fmt.Errorf("only primitive types, map & slices can have tag value, not %q", f.v.Kind())
+	return nil, fmt.Errorf("only primitive types, map, slices & arrays can have tag value, not %q", f.v.Kind())
 }
 
-func valueToValue(v reflect.Value) (*Value, error) {
+func valueToValue(v reflect.Value, opt *marshalOptions) (*Value, error) {
 	// Special cased types.
 	t := v.Type()
+	if value, ok, err := encodeScalar(v, opt); ok {
+		return value, err
+	}
 	if t == durationType {
 		s := v.Interface().(time.Duration).String()
+		if opt.extendedDurations {
+			s = formatExtendedDuration(v.Interface().(time.Duration))
+		}
 		return &Value{Str: &s}, nil
+	} else if t == byteSizeType {
+		return byteSizeToValue(ByteSize(v.Int())), nil
+	} else if t == tristateType {
+		return tristateToValue(Tristate(v.Int()))
+	} else if t == bytesType {
+		return bytesToValue(v.Bytes(), "")
+	} else if t == numberType {
+		return numberToValue(v.Interface().(Number))
 	} else if uv, ok := implements(v, textMarshalerInterface); ok {
 		tm := uv.Interface().(encoding.TextMarshaler)
 		b, err := tm.MarshalText()
@@ -363,22 +1110,53 @@ func valueToValue(v reflect.Value) (*Value, error) {
 	}
 	switch t.Kind() {
 	case reflect.String:
-		s := v.Interface().(string)
+		s := v.String()
 		return &Value{Str: &s}, nil
 
-	case reflect.Slice:
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, fmt.Errorf("can't marshal nil %s", t)
+		}
+		if t.Kind() == reflect.Ptr {
+			leave, err := opt.visit(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return valueToValue(v.Elem(), opt)
+
+	case reflect.Slice, reflect.Array:
+		leave, err := opt.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
 		list := []*Value{}
 		for i := 0; i < v.Len(); i++ {
 			el := v.Index(i)
-			elv, err := valueToValue(el)
+			elv, err := valueToValue(el, opt)
 			if err != nil {
-				return nil, err
+				return nil, withFieldPath(fmt.Sprintf("[%d]", i), err)
 			}
 			list = append(list, elv)
 		}
 		return &Value{List: list, HaveList: true}, nil
 
 	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, unsupportedTypeError{t}
+		}
+		leave, err := opt.visit(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		leave, err = opt.enter()
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
 		entries := []*MapEntry{}
 		sorted := []reflect.Value{}
 		for _, key := range v.MapKeys() {
@@ -388,9 +1166,12 @@ func valueToValue(v reflect.Value) (*Value, error) {
 			return sorted[i].String() < sorted[j].String()
 		})
 		for _, key := range sorted {
-			value, err := valueToValue(v.MapIndex(key))
+			if t.Elem() == tristateType && !Tristate(v.MapIndex(key).Int()).IsSet() {
+				continue
+			}
+			value, err := valueToValue(v.MapIndex(key), opt)
 			if err != nil {
-				return nil, err
+				return nil, withFieldPath(fmt.Sprintf("[%q]", key), err)
 			}
 			keyStr := key.String()
 			entries = append(entries, &MapEntry{
@@ -401,7 +1182,7 @@ func valueToValue(v reflect.Value) (*Value, error) {
 		return &Value{Map: entries, HaveMap: true}, nil
 
 	case reflect.Float32, reflect.Float64:
-		return &Value{Number: big.NewFloat(v.Float())}, nil
+		return floatToValue(v.Float(), opt)
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return &Value{Number: big.NewFloat(0).SetInt64(v.Int())}, nil
@@ -413,149 +1194,112 @@ func valueToValue(v reflect.Value) (*Value, error) {
 		b := v.Bool()
 		return &Value{Bool: (*Bool)(&b)}, nil
 
-	default:
-		switch t {
-		case timeType:
+	case reflect.Struct:
+		if t == timeType {
 			s := v.Interface().(time.Time).Format(time.RFC3339)
 			return &Value{Str: &s}, nil
-
-		default:
-			panic(t.String())
 		}
-	}
-}
-
-func valueToBlock(v reflect.Value, tag tag, schema bool, opt *marshalOptions) (*Block, error) {
-	block := &Block{
-		Name:     tag.name,
-		Comments: tag.comments(),
-	}
-	var err error
-	block.Body, block.Labels, err = structToEntries(v, schema, opt)
-	return block, err
-}
-
-func sliceToBlocks(sv reflect.Value, tag tag, opt *marshalOptions) ([]*Block, error) {
-	blocks := []*Block{}
-	for i := 0; i != sv.Len(); i++ {
-		block, err := valueToBlock(sv.Index(i), tag, false, opt)
+		// Structs nested in values are objects, eg. `{ cpu: 1 }`.
+		leave, err := opt.enter()
 		if err != nil {
 			return nil, err
 		}
-		blocks = append(blocks, block)
+		defer leave()
+		body, labels, err := structToEntries(v, false, opt)
+		if err != nil {
+			return nil, err
+		}
+		return blockToObject(&Block{Name: t.Name(), Labels: labels, Body: body})
+
+	default:
+		return nil, unsupportedTypeError{t}
 	}
-	return blocks, nil
 }
 
-func marshalNode(w io.Writer, indent string, node Node) error {
-	switch node := node.(type) {
-	case *AST:
-		return marshalAST(w, indent, node)
-	case *Block:
-		return marshalBlock(w, indent, node)
-	case *Attribute:
-		return marshalAttribute(w, indent, node)
-	case *Value:
-		return marshalValue(w, indent, node)
+// bytesToValue encodes a []byte as a string using the encoding from an
+// encoding:"" tag, defaulting to base64.
+func bytesToValue(b []byte, enc string) (*Value, error) {
+	var s string
+	switch enc {
+	case "", "base64":
+		s = base64.StdEncoding.EncodeToString(b)
+	case "hex":
+		s = hex.EncodeToString(b)
 	default:
-		return fmt.Errorf("can't marshal node of type %T", node)
+		return nil, fmt.Errorf("unsupported encoding %q", enc)
 	}
+	return &Value{Str: &s}, nil
 }
 
-func marshalAST(w io.Writer, indent string, node *AST) error {
-	err := marshalEntries(w, indent, node.Entries)
-	if err != nil {
-		return err
+// labelFromValue converts a string, numeric, boolean or encoding.TextMarshaler
+// field into a block label.
+func labelFromValue(v reflect.Value) (string, error) {
+	if uv, ok := implements(v, textMarshalerInterface); ok {
+		b, err := uv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
 	}
-	marshalComments(w, indent, node.TrailingComments)
-	return nil
-}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
 
-func marshalEntries(w io.Writer, indent string, entries []*Entry) error {
-	prevAttr := true
-	for i, entry := range entries {
-		if block := entry.Block; block != nil {
-			if i > 0 {
-				fmt.Fprintln(w)
-			}
-			if err := marshalBlock(w, indent, block); err != nil {
-				return err
-			}
-			prevAttr = false
-		} else if attr := entry.Attribute; attr != nil {
-			if !prevAttr {
-				fmt.Fprintln(w)
-			}
-			if err := marshalAttribute(w, indent, attr); err != nil {
-				return err
-			}
-			prevAttr = true
-		} else {
-			panic("??")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", nil
 		}
+		return labelFromValue(v.Elem())
+
+	default:
+		return "", fmt.Errorf("unsupported label type %s", v.Type())
 	}
-	return nil
 }
 
-func marshalAttribute(w io.Writer, indent string, attribute *Attribute) error {
-	marshalComments(w, indent, attribute.Comments)
-	fmt.Fprintf(w, "%s%s = ", indent, attribute.Key)
-	err := marshalValue(w, indent, attribute.Value)
+func valueToBlock(v reflect.Value, tag tag, schema bool, opt *marshalOptions) (*Block, error) {
+	leave, err := opt.enter()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if attribute.Optional {
-		fmt.Fprint(w, " // (optional)")
+	defer leave()
+	block := &Block{
+		Name:     tag.name,
+		Comments: opt.helpComments(tag, schema),
 	}
-	fmt.Fprintln(w)
-	return nil
-}
-
-func marshalValue(w io.Writer, indent string, value *Value) error {
-	if value.HaveMap {
-		return marshalMap(w, indent+"  ", value.Map)
+	if schema {
+		return block, schemaBlockBody(block, v, opt)
 	}
-	fmt.Fprintf(w, "%s", value)
-	return nil
+	block.Body, block.Labels, err = structToEntries(v, schema, opt)
+	return block, err
 }
 
-func marshalMap(w io.Writer, indent string, entries []*MapEntry) error {
-	fmt.Fprintln(w, "{")
-	for _, entry := range entries {
-		marshalComments(w, indent, entry.Comments)
-		fmt.Fprintf(w, "%s%s: ", indent, entry.Key)
-		if err := marshalValue(w, indent+"  ", entry.Value); err != nil {
-			return err
+func sliceToBlocks(sv reflect.Value, tag tag, opt *marshalOptions) ([]*Block, error) {
+	blocks := []*Block{}
+	for i := 0; i != sv.Len(); i++ {
+		block, err := valueToBlock(sv.Index(i), tag, false, opt)
+		if err != nil {
+			return nil, withFieldPath(fmt.Sprintf("[%d]", i), err)
 		}
-		fmt.Fprintln(w, ",")
-	}
-	fmt.Fprintf(w, "%s}", indent[:len(indent)-2])
-	return nil
-}
-
-func marshalBlock(w io.Writer, indent string, block *Block) error {
-	marshalComments(w, indent, block.Comments)
-	fmt.Fprintf(w, "%s%s ", indent, block.Name)
-	for _, label := range block.Labels {
-		fmt.Fprintf(w, "%q ", label)
-	}
-	if block.Repeated {
-		fmt.Fprintln(w, "{ // (repeated)")
-	} else {
-		fmt.Fprintln(w, "{")
-	}
-	err := marshalEntries(w, indent+"  ", block.Body)
-	if err != nil {
-		return err
+		blocks = append(blocks, block)
 	}
-	fmt.Fprintf(w, "%s}\n", indent)
-	return nil
+	return blocks, nil
 }
 
-func marshalComments(w io.Writer, indent string, comments []string) {
-	for _, comment := range comments {
-		for _, line := range strings.Split(comment, "\n") {
-			fmt.Fprintf(w, "%s// %s\n", indent, line)
+// checkUTF8 ensures all strings in a value are valid UTF-8, as they could not otherwise be parsed.
+func checkUTF8(value *Value) error {
+	return Visit(value, func(node Node, next func() error) error {
+		if value, ok := node.(*Value); ok && value.Str != nil && !utf8.ValidString(*value.Str) {
+			return participle.Errorf(value.Pos, "invalid UTF-8 in string %q", *value.Str)
 		}
-	}
+		return next()
+	})
 }
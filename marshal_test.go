@@ -2,6 +2,11 @@ package hcl
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -131,6 +136,97 @@ list = [1, 2, 3]
 			expected: `
 time = "2020-01-02T15:04:05Z"
 duration = "5s"
+`,
+		},
+		{name: "Set",
+			src: &struct {
+				Tags  []string `hcl:"tags,set"`
+				Ports []int    `hcl:"ports,set"`
+			}{Tags: []string{"b", "c", "a"}, Ports: []int{443, 80, 8080}},
+			expected: `
+tags = ["a", "b", "c"]
+ports = [80, 443, 8080]
+`,
+		},
+		{name: "Tuple",
+			src: &struct {
+				Tuple []interface{}  `hcl:"tuple"`
+				Array [3]interface{} `hcl:"array"`
+			}{Tuple: []interface{}{1, "two", true}, Array: [3]interface{}{"a", 2, false}},
+			expected: `
+tuple = [1, "two", true]
+array = ["a", 2, false]
+`,
+		},
+		{name: "Base",
+			src: &struct {
+				Mode  os.FileMode `hcl:"mode"`
+				Flags []int       `hcl:"flags" base:"16"`
+				Mask  int         `hcl:"mask" base:"2"`
+			}{Mode: 0755, Flags: []int{255, -16}, Mask: 5},
+			options: []MarshalOption{OctalFileModes(true)},
+			expected: `
+mode = 0o755
+flags = [0xff, -0x10]
+mask = 0b101
+`,
+		},
+		{name: "TrailingComment",
+			src: &struct {
+				Port int    `hcl:"port" trailing_comment:"external"`
+				Host string `hcl:"host" help:"Host to bind." trailing_comment:"local"`
+			}{Port: 8080, Host: "localhost"},
+			expected: `
+port = 8080 // external
+// Host to bind.
+host = "localhost" // local
+`,
+		},
+		{name: "TypedLabels",
+			src: &struct {
+				Listener struct {
+					Port int    `hcl:"port,label"`
+					IP   net.IP `hcl:"ip,label"`
+				} `hcl:"listener,block"`
+			}{
+				Listener: struct {
+					Port int    `hcl:"port,label"`
+					IP   net.IP `hcl:"ip,label"`
+				}{Port: 8080, IP: net.IPv4(127, 0, 0, 1)},
+			},
+			expected: `
+listener "8080" "127.0.0.1" {
+}
+`,
+		},
+		{name: "Interface",
+			src: &struct {
+				Str     interface{} `hcl:"str"`
+				List    interface{} `hcl:"list"`
+				Ptr     interface{} `hcl:"ptr"`
+				Omitted interface{} `hcl:"omitted,optional"`
+			}{
+				Str:  "str",
+				List: []interface{}{1, "two"},
+				Ptr:  strp("ptr"),
+			},
+			expected: `
+str = "str"
+list = [1, "two"]
+ptr = "ptr"
+`,
+		},
+		{name: "Bytes",
+			src: &struct {
+				Base64 []byte `hcl:"base64"`
+				Hex    []byte `hcl:"hex" encoding:"hex"`
+			}{
+				Base64: []byte("hello"),
+				Hex:    []byte("world"),
+			},
+			expected: `
+base64 = "aGVsbG8="
+hex = "776f726c64"
 `,
 		},
 		{name: "Marshalers",
@@ -163,6 +259,24 @@ block {
 `,
 			options: []MarshalOption{InferHCLTags(true)},
 		},
+		{name: "SliceOfBlocksWithElemName",
+			src: &struct {
+				Rules []struct {
+					Name string `hcl:"name,label"`
+				} `hcl:"rules,block" elem:"rule"`
+			}{
+				Rules: []struct {
+					Name string `hcl:"name,label"`
+				}{{Name: "one"}, {Name: "two"}},
+			},
+			expected: `
+rule "one" {
+}
+
+rule "two" {
+}
+`,
+		},
 		{
 			name: "default values",
 			src: &struct {
@@ -230,6 +344,208 @@ mapDiffDefault = {
 	}
 }
 
+func TestMarshalNilInterface(t *testing.T) {
+	_, err := Marshal(&struct {
+		Value interface{} `hcl:"value"`
+	}{})
+	require.EqualError(t, err, "value: can't marshal nil interface {}")
+}
+
+func TestMarshalUnsupportedType(t *testing.T) {
+	type server struct {
+		Name  string   `hcl:"name,label"`
+		Weird chan int `hcl:"weird_field"`
+	}
+	type config struct {
+		Servers []server `hcl:"server,block"`
+	}
+	src := &config{Servers: []server{{Name: "a"}}}
+	_, err := Marshal(src)
+	require.EqualError(t, err, "server[0].weird_field: unsupported type chan int")
+	ferr := &FieldError{}
+	require.True(t, errors.As(err, &ferr))
+	require.Equal(t, "server[0].weird_field", ferr.Path)
+
+	data, err := Marshal(src, SkipUnsupportedTypes(true))
+	require.NoError(t, err)
+	require.Equal(t, "server \"a\" {\n}\n", string(data))
+}
+
+func TestMarshalMapOrdering(t *testing.T) {
+	type group struct {
+		Name   string                       `hcl:"name,label"`
+		Limits map[string]map[string]int    `hcl:"limits"`
+		Rules  []map[string]string          `hcl:"rules"`
+		Extra  map[string]interface{}       `hcl:"extra"`
+		Nested map[string][]map[string]bool `hcl:"nested"`
+	}
+	type config struct {
+		Groups []group `hcl:"group,block"`
+	}
+	src := &config{Groups: []group{{
+		Name:   "a",
+		Limits: map[string]map[string]int{"z": {"b": 2, "a": 1}, "m": {"d": 4, "c": 3}},
+		Rules:  []map[string]string{{"y": "1", "x": "2"}, {"q": "3", "p": "4"}},
+		Extra:  map[string]interface{}{"k": map[string]interface{}{"2": 2, "1": 1}, "j": []interface{}{map[string]int{"f": 1, "e": 2}}},
+		Nested: map[string][]map[string]bool{"o": {{"t": true, "s": false}}, "n": nil},
+	}}}
+	expected := `group "a" {
+  limits = {
+    "m": {
+      "c": 3,
+      "d": 4,
+    },
+    "z": {
+      "a": 1,
+      "b": 2,
+    },
+  }
+  rules = [{"x": "2", "y": "1"}, {"p": "4", "q": "3"}]
+  extra = {
+    "j": [{"e": 2, "f": 1}],
+    "k": {
+      "1": 1,
+      "2": 2,
+    },
+  }
+  nested = {
+    "n": [],
+    "o": [{"s": false, "t": true}],
+  }
+}
+`
+	for i := 0; i < 20; i++ {
+		data, err := Marshal(src)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(data))
+	}
+
+	_, err := Marshal(&struct {
+		Ports map[int]string `hcl:"ports"`
+	}{Ports: map[int]string{1: "a"}})
+	require.EqualError(t, err, "ports: unsupported type map[int]string")
+}
+
+func TestMarshalIntoPreserveMapOrder(t *testing.T) {
+	type config struct {
+		Tags   map[string]string           `hcl:"tags"`
+		Limits []map[string]map[string]int `hcl:"limits"`
+	}
+	ast, err := ParseString(`
+tags = {
+  zone: "a",
+  env: "prod",
+  app: "web",
+}
+limits = [{
+  "b": {"y": 1, "x": 2},
+  "a": {"z": 3},
+}]
+`)
+	require.NoError(t, err)
+	v := &config{
+		Tags:   map[string]string{"zone": "b", "app": "web", "owner": "me", "cost": "1"},
+		Limits: []map[string]map[string]int{{"a": {"z": 3}, "b": {"x": 2, "y": 1, "w": 0}}},
+	}
+	err = MarshalInto(ast, v, PreserveMapOrder(true))
+	require.NoError(t, err)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `tags = {
+  "zone": "b",
+  "app": "web",
+  "cost": "1",
+  "owner": "me",
+}
+limits = [{"b": {"y": 1, "x": 2, "w": 0}, "a": {"z": 3}}]
+`, string(data))
+
+	// By default, entries are ordered by key.
+	ast, err = ParseString("tags = {\n  b: \"1\",\n  a: \"2\",\n}\n")
+	require.NoError(t, err)
+	err = MarshalInto(ast, &config{Tags: map[string]string{"a": "2", "b": "1"}})
+	require.NoError(t, err)
+	data, err = MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, "tags = {\n  \"a\": \"2\",\n  \"b\": \"1\",\n}\nlimits = []\n", string(data))
+}
+
+func TestMarshalStringEscapesRoundTrip(t *testing.T) {
+	type conf struct {
+		Str string `hcl:"str"`
+	}
+	tests := []string{
+		"line\nbreak\ttab",
+		"\x00\x1b\x7f\u2028",
+		"emoji \U0001F600 and \u00e9",
+		"quote \" and \\ backslash",
+		"${interpolation} $${escaped}",
+	}
+	for _, test := range tests {
+		data, err := Marshal(&conf{Str: test})
+		require.NoError(t, err)
+		actual := &conf{}
+		err = Unmarshal(data, actual)
+		require.NoError(t, err)
+		require.Equal(t, test, actual.Str)
+	}
+
+	data, err := Marshal(&conf{Str: "${var}"})
+	require.NoError(t, err)
+	require.Equal(t, "str = \"$${var}\"\n", string(data))
+
+	_, err = Marshal(&conf{Str: "\xff"})
+	require.Error(t, err)
+}
+
+func TestMarshalInto(t *testing.T) {
+	type service struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Version  int       `hcl:"version"`
+		Debug    bool      `hcl:"debug,optional"`
+		Services []service `hcl:"service,block"`
+	}
+	ast := hcl(
+		attr("version", num(1)),
+		attr("owner", str("other-tool")),
+		attr("debug", hbool(true)),
+		block("service", []string{"api"},
+			attr("port", num(80)),
+			attr("extra", str("keep")),
+		),
+		block("service", []string{"old"},
+			attr("port", num(1)),
+		),
+	)
+	ast.Entries[0].Attribute.Comments = []string{"Config version."}
+	err := MarshalInto(ast, &config{
+		Version: 2,
+		Services: []service{
+			{Name: "api", Port: 8080},
+			{Name: "web", Port: 80},
+		},
+	})
+	require.NoError(t, err)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `// Config version.
+version = 2
+owner = "other-tool"
+
+service "api" {
+  port = 8080
+  extra = "keep"
+}
+
+service "web" {
+  port = 80
+}
+`, string(data))
+}
+
 type TestStruct struct {
 	Val         string `hcl:"val"`
 	DefaultVal  string `hcl:"default_val" default:"test"`
@@ -255,3 +571,505 @@ default_val = "2"
 	require.Equal(t, strings.TrimSpace(hcl), strings.TrimSpace(string(marshalled)))
 
 }
+
+type indentServer struct {
+	Name  string            `hcl:"name,label"`
+	Port  int               `hcl:"port" help:"Port to listen on."`
+	Tags  map[string]string `hcl:"tags,optional"`
+	Hosts []string          `hcl:"hosts,optional"`
+}
+
+type indentConfig struct {
+	Version int            `hcl:"version"`
+	Servers []indentServer `hcl:"server,block"`
+}
+
+var indentFixture = &indentConfig{
+	Version: 1,
+	Servers: []indentServer{
+		{Name: "a", Port: 1, Tags: map[string]string{"env": "prod"}},
+		{Name: "b", Port: 2, Hosts: []string{"x", "y"}},
+	},
+}
+
+func TestMarshalIndent(t *testing.T) {
+	data, err := MarshalIndent(indentFixture, "> ", "    ")
+	require.NoError(t, err)
+	require.Equal(t, `> version = 1
+>
+> server "a" {
+>     // Port to listen on.
+>     port = 1
+>     tags = {
+>         "env": "prod",
+>     }
+> }
+>
+> server "b" {
+>     // Port to listen on.
+>     port = 2
+>     hosts = ["x", "y"]
+> }
+`, string(data))
+}
+
+func TestMarshalCompact(t *testing.T) {
+	data, err := MarshalCompact(indentFixture)
+	require.NoError(t, err)
+	require.Equal(t, `version = 1
+server "a" { /* Port to listen on. */ port = 1 tags = {"env": "prod"} }
+server "b" { /* Port to listen on. */ port = 2 hosts = ["x", "y"] }
+`, string(data))
+}
+
+func TestMarshalNonFiniteFloats(t *testing.T) {
+	type config struct {
+		Values []float64 `hcl:"values"`
+	}
+	src := &config{Values: []float64{math.Inf(1), math.Inf(-1), math.NaN(), 1}}
+	_, err := Marshal(src)
+	require.EqualError(t, err, "values[0]: can't marshal non-finite float +Inf")
+
+	data, err := Marshal(src, NonFiniteFloats(NonFiniteFloatsAsStrings))
+	require.NoError(t, err)
+	require.Equal(t, "values = [\"+Inf\", \"-Inf\", \"NaN\", 1]\n", string(data))
+
+	actual := &config{}
+	err = Unmarshal(data, actual, NonFiniteFloats(NonFiniteFloatsAsStrings))
+	require.NoError(t, err)
+	require.True(t, math.IsInf(actual.Values[0], 1))
+	require.True(t, math.IsInf(actual.Values[1], -1))
+	require.True(t, math.IsNaN(actual.Values[2]))
+}
+
+type condition struct {
+	Op       string       `hcl:"op"`
+	Value    string       `hcl:"value,optional"`
+	Children []*condition `hcl:"condition,block"`
+	Else     *condition   `hcl:"else,block"`
+}
+
+func TestMarshalRecursiveTypes(t *testing.T) {
+	src := &condition{Op: "and", Children: []*condition{
+		{Op: "eq", Value: "a"},
+		{Op: "or", Children: []*condition{{Op: "eq", Value: "b"}}, Else: &condition{Op: "never"}},
+	}}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+	actual := &condition{}
+	err = Unmarshal(data, actual)
+	require.NoError(t, err)
+	require.Equal(t, src, actual)
+
+	schema, err := Schema(&condition{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `op = string
+value = string // (optional)
+
+condition { // (repeated, recursive: same as document)
+}
+
+else { // (recursive: same as document)
+}
+`, string(data))
+
+	type rule struct {
+		Name  string  `hcl:"name,label"`
+		Rules []*rule `hcl:"rule,block"`
+	}
+	type policy struct {
+		Rules []rule `hcl:"rule,block"`
+	}
+	schema, err = BlockSchema("policy", &policy{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `policy {
+  rule "name" { // (repeated)
+    rule "name" { // (repeated, recursive: same as policy.rule)
+    }
+  }
+}
+`, string(data))
+	nested := schema.Entries[0].Block.Body[0].Block.Body[0].Block
+	require.True(t, nested.Recursive)
+	require.Equal(t, "policy.rule", nested.RecursivePath)
+}
+
+func TestMarshalPointerCycle(t *testing.T) {
+	src := &condition{Op: "and"}
+	src.Children = []*condition{{Op: "or", Else: src}}
+	_, err := Marshal(src)
+	require.EqualError(t, err, "condition[0].else: pointer cycle detected in *hcl.condition")
+}
+
+func TestMarshalInline(t *testing.T) {
+	type limits struct {
+		CPU    int `hcl:"cpu" help:"Cores."`
+		Memory int `hcl:"mem,optional"`
+	}
+	type config struct {
+		Limits   limits  `hcl:"limits,inline" help:"Resource limits."`
+		Requests *limits `hcl:"requests,inline"`
+	}
+	data, err := Marshal(&config{Limits: limits{CPU: 2, Memory: 512}})
+	require.NoError(t, err)
+	require.Equal(t, `// Resource limits.
+limits = {
+  // Cores.
+  "cpu": 2,
+  "mem": 512,
+}
+`, string(data))
+
+	out := &config{}
+	err = Unmarshal(data, out)
+	require.NoError(t, err)
+	require.Equal(t, &config{Limits: limits{CPU: 2, Memory: 512}}, out)
+
+	_, err = Marshal(&struct {
+		Server struct {
+			Limits limits `hcl:"limits,block"`
+		} `hcl:"server,inline"`
+	}{})
+	require.EqualError(t, err, `server: inline "server" can't contain block "limits"`)
+}
+
+func TestMarshalExcludedAndReadOnly(t *testing.T) {
+	type config struct {
+		Name   string `hcl:"name"`
+		Secret string `hcl:"-"`
+		ID     string `hcl:"id,readonly" help:"Derived from name."`
+	}
+	data, err := Marshal(&config{Name: "web", Secret: "hunter2", ID: "web-1"})
+	require.NoError(t, err)
+	require.Equal(t, "name = \"web\"\n// Derived from name.\nid = \"web-1\"\n", string(data))
+
+	out := &config{}
+	err = Unmarshal([]byte(`name = "web"`), out)
+	require.NoError(t, err)
+	require.Equal(t, &config{Name: "web"}, out)
+
+	err = Unmarshal(data, &config{})
+	require.EqualError(t, err, `2:1: attribute "id" is read-only`)
+	require.Equal(t, CodeConstraint, ErrorCode(err))
+
+	err = Unmarshal([]byte("name = \"web\"\nSecret = \"x\"\n"), &config{})
+	require.Error(t, err)
+
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, "name = string\n// Derived from name.\nid = string // (optional, readonly)\n", string(data))
+}
+
+func TestMarshalHelpComments(t *testing.T) {
+	type server struct {
+		Port int `hcl:"port" help:"Port to listen on."`
+	}
+	type config struct {
+		Name   string `hcl:"name" help:"Name of the service.\nMust be unique."`
+		Server server `hcl:"server,block" help:"HTTP server."`
+	}
+	src := &config{Name: "api", Server: server{Port: 80}}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+	require.Equal(t, `// Name of the service.
+// Must be unique.
+name = "api"
+
+// HTTP server.
+server {
+  // Port to listen on.
+  port = 80
+}
+`, string(data))
+
+	data, err = Marshal(src, WithHelpComments(false))
+	require.NoError(t, err)
+	require.Equal(t, `name = "api"
+
+server {
+  port = 80
+}
+`, string(data))
+
+	schema, err := Schema(src, WithHelpComments(false))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Name of the service.", "Must be unique."}, schema.Entries[0].Attribute.Comments)
+}
+
+func TestMarshalNestedSlices(t *testing.T) {
+	type config struct {
+		Matrix [][]int    `hcl:"matrix"`
+		Groups [][]string `hcl:"groups"`
+	}
+	src := &config{
+		Matrix: [][]int{{1, 2}, {}, {3}},
+		Groups: [][]string{{"alpha", "bravo", "charlie"}, {"delta"}},
+	}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+	require.Equal(t, `matrix = [[1, 2], [], [3]]
+groups = [["alpha", "bravo", "charlie"], ["delta"]]
+`, string(data))
+
+	ast, err := MarshalToAST(src)
+	require.NoError(t, err)
+	data, err = NewPrinter(MaxWidth(36)).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, `matrix = [[1, 2], [], [3]]
+groups = [
+  ["alpha", "bravo", "charlie"],
+  ["delta"],
+]
+`, string(data))
+
+	out := &config{}
+	require.NoError(t, Unmarshal(data, out))
+	require.Equal(t, src, out)
+}
+
+func TestMarshalMapOfObjects(t *testing.T) {
+	type limits struct {
+		CPU    int `hcl:"cpu"`
+		Memory int `hcl:"mem,optional"`
+	}
+	type config struct {
+		Limits   map[string]limits   `hcl:"limits"`
+		Requests map[string]*limits  `hcl:"requests,optional"`
+		Tiers    []map[string]limits `hcl:"tiers,optional"`
+	}
+	src := &config{Limits: map[string]limits{"web": {CPU: 1}, "db": {CPU: 2, Memory: 512}}}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+	require.Equal(t, `limits = {
+  "db": {
+    "cpu": 2,
+    "mem": 512,
+  },
+  "web": {
+    "cpu": 1,
+  },
+}
+`, string(data))
+	out := &config{}
+	require.NoError(t, Unmarshal(data, out))
+	require.Equal(t, src, out)
+
+	out = &config{}
+	err = Unmarshal([]byte(`
+limits = { web: { cpu: 1 }, db: { cpu: 2 } }
+requests = { web: { cpu: 1, mem: 64 } }
+tiers = [{ small: { cpu: 1 } }]
+`), out)
+	require.NoError(t, err)
+	require.Equal(t, &config{
+		Limits:   map[string]limits{"web": {CPU: 1}, "db": {CPU: 2}},
+		Requests: map[string]*limits{"web": {CPU: 1, Memory: 64}},
+		Tiers:    []map[string]limits{{"small": {CPU: 1}}},
+	}, out)
+
+	err = Unmarshal([]byte(`limits = { web: 1 }`), &config{})
+	require.EqualError(t, err, `1:17: limits["web"]: expected a map but got 1`)
+	err = Unmarshal([]byte(`limits = { web: { cpu: 1, gpu: 1 } }`), &config{})
+	require.EqualError(t, err, `1:27: limits["web"]: found extra fields "gpu" (did you mean "cpu"?)`)
+
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `limits = {
+  string: {
+    "cpu": number,
+    "mem": number,
+  },
+}
+requests = {
+  string: {
+    "cpu": number,
+    "mem": number,
+  },
+} // (optional)
+tiers = [{string: {"cpu": number, "mem": number}}] // (optional)
+`, string(data))
+}
+
+func TestMarshalBlockMap(t *testing.T) {
+	type rule struct {
+		Method string `hcl:"method,label"`
+		Target string `hcl:"target"`
+	}
+	type config struct {
+		Routes map[string][]rule `hcl:"route,block"`
+	}
+	src := &config{Routes: map[string][]rule{
+		"/web": {{Method: "GET", Target: "b"}},
+		"/api": {{Method: "GET", Target: "a"}, {Method: "POST", Target: "c"}},
+	}}
+	data, err := Marshal(src)
+	require.NoError(t, err)
+	require.Equal(t, `route "/api" "GET" {
+  target = "a"
+}
+
+route "/api" "POST" {
+  target = "c"
+}
+
+route "/web" "GET" {
+  target = "b"
+}
+`, string(data))
+	out := &config{}
+	require.NoError(t, Unmarshal(data, out))
+	require.Equal(t, src, out)
+
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `route "key" "method" { // (repeated)
+  target = string
+}
+`, string(data))
+}
+
+func TestMarshalMapDocument(t *testing.T) {
+	data, err := Marshal(map[string]interface{}{"port": 80, "name": "app", "tags": []string{"a"}})
+	require.NoError(t, err)
+	require.Equal(t, "name = \"app\"\nport = 80\ntags = [\"a\"]\n", string(data))
+
+	strs := map[string]string{"env": "prod"}
+	data, err = Marshal(&strs)
+	require.NoError(t, err)
+	require.Equal(t, "env = \"prod\"\n", string(data))
+
+	_, err = Marshal(map[string]string{"not valid": "x"})
+	require.EqualError(t, err, `invalid attribute name "not valid"`)
+}
+
+func TestMarshalStructValue(t *testing.T) {
+	type config struct {
+		Name string `hcl:"name"`
+	}
+	data, err := Marshal(config{Name: "app"})
+	require.NoError(t, err)
+	require.Equal(t, "name = \"app\"\n", string(data))
+
+	schema, err := Schema(config{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, "name = string\n", string(data))
+
+	_, err = Marshal(nil)
+	require.EqualError(t, err, "can't marshal nil")
+	_, err = Marshal((*config)(nil))
+	require.EqualError(t, err, "can't marshal nil *hcl.config")
+	_, err = Marshal("name")
+	require.EqualError(t, err, "can't marshal string, expected a struct, a pointer to a struct or a map")
+	_, err = Marshal(&[]string{})
+	require.EqualError(t, err, "can't marshal *[]string, expected a struct, a pointer to a struct or a map")
+
+	err = Unmarshal([]byte(`name = "app"`), (*config)(nil))
+	require.EqualError(t, err, "can't unmarshal into nil *hcl.config")
+}
+
+func TestMarshalMode(t *testing.T) {
+	type config struct {
+		Host    string `hcl:"host"`
+		Port    int    `hcl:"port,optional" default:"80"`
+		Verbose bool   `hcl:"verbose,optional"`
+	}
+	value := &config{Host: "example.com", Port: 8080}
+
+	data, err := Marshal(value, MarshalMode(ModeData))
+	require.NoError(t, err)
+	require.Equal(t, "host = \"example.com\"\nport = 8080\n", string(data))
+
+	data, err = Marshal(value, MarshalMode(ModeSchema))
+	require.NoError(t, err)
+	schema, err := Schema(value)
+	require.NoError(t, err)
+	expected, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(data))
+
+	data, err = Marshal(value, MarshalMode(ModeExample))
+	require.NoError(t, err)
+	expected, err = Example(value)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(data))
+
+	// Values set in the struct fill in the defaults of the schema.
+	ast, err := MarshalToAST(value, MarshalMode(ModeSchema|ModeData))
+	require.NoError(t, err)
+	require.True(t, ast.Schema)
+	require.False(t, ast.Entries[0].Attribute.Optional)
+	require.Equal(t, `"example.com"`, ast.Entries[0].Attribute.Default.String())
+	require.Equal(t, "8080", ast.Entries[1].Attribute.Default.String())
+	require.Nil(t, ast.Entries[2].Attribute.Default)
+	ast, err = Schema(value, MarshalMode(ModeSchema|ModeData))
+	require.NoError(t, err)
+	require.Equal(t, "8080", ast.Entries[1].Attribute.Default.String())
+	ast, err = Schema(value)
+	require.NoError(t, err)
+	require.Equal(t, "80", ast.Entries[1].Attribute.Default.String())
+
+	_, err = Marshal(value, MarshalMode(ModeExample|ModeData))
+	require.EqualError(t, err, "unsupported marshal mode data|example")
+}
+
+func TestWithValueFormatter(t *testing.T) {
+	type server struct {
+		Name  string   `hcl:"name,label"`
+		Token string   `hcl:"token"`
+		Hosts []string `hcl:"hosts"`
+	}
+	type config struct {
+		Ratio   float64           `hcl:"ratio"`
+		Labels  map[string]string `hcl:"labels"`
+		Servers []server          `hcl:"server,block"`
+	}
+	paths := []string{}
+	formatter := func(path string, v *Value) *Value {
+		paths = append(paths, path)
+		switch {
+		case strings.HasSuffix(path, ".token"):
+			return &Value{Str: strp("****")}
+		case v.Number != nil:
+			f, _ := v.Number.Float64()
+			return &Value{Number: big.NewFloat(math.Round(f*100) / 100)}
+		case v.Str != nil:
+			s := strings.ToLower(*v.Str)
+			v.Str = &s
+		}
+		return nil
+	}
+	data, err := Marshal(&config{
+		Ratio:   0.123456,
+		Labels:  map[string]string{"env": "PROD"},
+		Servers: []server{{Name: "web", Token: "secret", Hosts: []string{"A.example.com"}}},
+	}, WithValueFormatter(formatter))
+	require.NoError(t, err)
+	require.Equal(t, `ratio = 0.12
+labels = {
+  "env": "prod",
+}
+
+server "web" {
+  token = "****"
+  hosts = ["a.example.com"]
+}
+`, string(data))
+	require.Equal(t, []string{
+		"ratio",
+		`labels["env"]`, "labels",
+		`server["web"].token`,
+		`server["web"].hosts[0]`, `server["web"].hosts`,
+	}, paths)
+}
@@ -0,0 +1,131 @@
+package hcl
+
+import (
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Provenance describes where an entry came from.
+type Provenance struct {
+	// Pos of the entry, including the file it was parsed from, if known.
+	Pos lexer.Position
+	// Overrides is the provenance of the entry this entry replaced when
+	// documents were merged, if any.
+	Overrides *Provenance
+}
+
+func (p Provenance) String() string {
+	if p.Overrides == nil {
+		return p.Pos.String()
+	}
+	return p.Pos.String() + " (overrides " + p.Overrides.String() + ")"
+}
+
+// Provenance returns where the entry came from, and the entries it overrode.
+func (e *Entry) Provenance() Provenance {
+	return Provenance{Pos: e.Pos, Overrides: e.overrides}
+}
+
+// overrode records that e replaced previous.
+func (e *Entry) overrode(previous *Entry) {
+	if e.overrides == nil {
+		provenance := previous.Provenance()
+		e.overrides = &provenance
+	}
+}
+
+// Merge documents into a new AST, in order.
+//
+// Attributes in later documents replace those with the same key in earlier
+// documents, and blocks with the same name and labels are merged
+// recursively. Other entries are appended. Entries in a single document are
+// never merged with each other.
+//
+// Each entry records its position, including the file it was parsed from,
+// and the entry it replaced, if any, in its Provenance. The documents
+// themselves are not modified.
+func Merge(asts ...*AST) (*AST, error) {
+	return merge(nil, asts)
+}
+
+// merge documents, recording the changes made by all but the first in log, if
+// not nil.
+func merge(log *ChangeLog, asts []*AST) (*AST, error) {
+	out := &AST{}
+	for i, ast := range asts {
+		m := &merger{}
+		if i > 0 {
+			m.log = log
+		}
+		entries, err := m.merge(out.Entries, cloneEntries(ast.Entries), "")
+		if err != nil {
+			return nil, err
+		}
+		out.Entries = entries
+		out.TrailingComments = append(out.TrailingComments, ast.TrailingComments...)
+		out.TrailingCommentStyles = append(out.TrailingCommentStyles, ast.TrailingCommentStyles...)
+	}
+	return out, AddParentRefs(out)
+}
+
+type merger struct {
+	log *ChangeLog
+}
+
+// merge overlay into base, where path is the path of the block containing
+// them, if any.
+func (m *merger) merge(base, overlay []*Entry, path string) ([]*Entry, error) {
+	attrs := map[string]int{}
+	blocks := map[string]int{}
+	blockNames := map[string]bool{}
+	for i, entry := range base {
+		if entry.Attribute != nil {
+			attrs[entry.Attribute.Key] = i
+		} else {
+			blocks[blockIdentity(entry.Block)] = i
+			blockNames[entry.Block.Name] = true
+		}
+	}
+	out := append([]*Entry{}, base...)
+	for _, entry := range overlay {
+		if entry.Attribute != nil {
+			if blockNames[entry.Attribute.Key] {
+				return nil, participle.Errorf(entry.Pos, "%q cannot be both block and attribute", entry.Key())
+			}
+			if i, ok := attrs[entry.Attribute.Key]; ok {
+				m.log.replaced(path+entry.Attribute.Key, out[i], entry)
+				entry.overrode(out[i])
+				out[i] = entry
+				continue
+			}
+			m.log.added(path+entry.Attribute.Key, entry)
+			out = append(out, entry)
+			continue
+		}
+		if _, ok := attrs[entry.Block.Name]; ok {
+			return nil, participle.Errorf(entry.Pos, "%q cannot be both block and attribute", entry.Key())
+		}
+		id := path + blockPath(entry.Block)
+		i, ok := blocks[blockIdentity(entry.Block)]
+		if !ok {
+			m.log.added(id, entry)
+			out = append(out, entry)
+			continue
+		}
+		body, err := m.merge(out[i].Block.Body, entry.Block.Body, id+".")
+		if err != nil {
+			return nil, err
+		}
+		out[i].Block.Body = body
+	}
+	return out, nil
+}
+
+// blockIdentity returns a key identifying blocks by name and labels.
+func blockIdentity(block *Block) string {
+	key := block.Name
+	for _, label := range block.Labels {
+		key += "\x00" + label
+	}
+	return key
+}
@@ -0,0 +1,109 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	parse := func(filename, source string) *AST {
+		ast, err := Parse(&namedReader{Reader: strings.NewReader(source), name: filename})
+		require.NoError(t, err)
+		return ast
+	}
+	base := parse("base.hcl", `
+env = "dev"
+replicas = 1
+
+service "api" {
+  port = 80
+  debug = true
+}
+`)
+	override := parse("prod.hcl", `
+env = "prod"
+
+service "api" {
+  port = 8080
+}
+
+service "web" {
+  port = 80
+}
+`)
+	local := parse("local.hcl", `env = "local"`)
+	ast, err := Merge(base, override, local)
+	require.NoError(t, err)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `env = "local"
+replicas = 1
+
+service "api" {
+  port = 8080
+  debug = true
+}
+
+service "web" {
+  port = 80
+}
+`, string(data))
+
+	require.Equal(t, "local.hcl:1:1 (overrides prod.hcl:2:1 (overrides base.hcl:2:1))", ast.Entries[0].Provenance().String())
+	require.Equal(t, "base.hcl:3:1", ast.Entries[1].Provenance().String())
+	api := ast.Entries[2].Block
+	require.Equal(t, "prod.hcl:5:3 (overrides base.hcl:6:3)", api.Body[0].Provenance().String())
+	require.Nil(t, api.Body[1].Provenance().Overrides)
+	require.Equal(t, "prod.hcl", ast.Entries[3].Provenance().Pos.Filename)
+
+	// Inputs are unchanged.
+	require.Equal(t, "base.hcl:2:1", base.Entries[0].Provenance().String())
+	require.Len(t, base.Entries[2].Block.Body, 2)
+
+	_, err = Merge(base, parse("bad.hcl", `service = "api"`))
+	require.EqualError(t, err, `bad.hcl:1:1: "service" cannot be both block and attribute`)
+}
+
+func TestChangeLog(t *testing.T) {
+	parse := func(filename, source string) *AST {
+		ast, err := Parse(&namedReader{Reader: strings.NewReader(source), name: filename})
+		require.NoError(t, err)
+		return ast
+	}
+	base := parse("base.hcl", `
+env = "dev"
+service "api" {
+  port = 80
+}
+`)
+	override := parse("prod.hcl", `
+env = "prod"
+service "api" {
+  port = 8080
+  debug = false
+}
+service "web" {
+  port = 80
+}
+`)
+	log := NewChangeLog("alice")
+	ast, err := log.Merge(base, override)
+	require.NoError(t, err)
+	expected, err := Merge(base, override)
+	require.NoError(t, err)
+	require.True(t, ASTEqual(expected, ast))
+
+	changes := []string{}
+	for _, change := range log.Changes() {
+		changes = append(changes, change.String())
+	}
+	require.Equal(t, []string{
+		`prod.hcl:2:1: alice replaced env: "dev" -> "prod"`,
+		`prod.hcl:4:3: alice replaced service["api"].port: 80 -> 8080`,
+		`prod.hcl:5:3: alice added service["api"].debug: false`,
+		`prod.hcl:7:1: alice added service["web"]: service "web" { port = 80 }`,
+	}, changes)
+	require.Equal(t, "base.hcl:2:1", log.Changes()[0].OldPos.String())
+}
@@ -0,0 +1,131 @@
+package hcl
+
+import (
+	"regexp"
+
+	"github.com/alecthomas/participle"
+)
+
+// Stable codes identifying classes of Diagnostic and of errors returned by
+// parsing and unmarshalling, as returned by ErrorCode, and used to key a
+// MessageCatalog. The arguments passed to the catalog for each Diagnostic code
+// are listed with it. For errors, the only argument is the default message.
+const (
+	// CodeIgnoredAttribute is an attribute that no field would be populated
+	// from. Arguments: the attribute key, and a suggested key or "".
+	CodeIgnoredAttribute = "HCL0001"
+	// CodeIgnoredBlock is a block that no field would be populated from.
+	// Arguments: the block name, and a suggested name or "".
+	CodeIgnoredBlock = "HCL0002"
+	// CodeMissingAttribute is a required attribute that is not present.
+	// Arguments: the attribute key.
+	CodeMissingAttribute = "HCL0003"
+	// CodeMissingBlock is a required block that is not present. Arguments:
+	// the block name.
+	CodeMissingBlock = "HCL0004"
+	// CodeMismatch is a value or block that can't be decoded into its field.
+	// Arguments: the default message.
+	CodeMismatch = "HCL0005"
+	// CodeSyntax is invalid HCL syntax.
+	CodeSyntax = "HCL0006"
+	// CodeInvalidUTF8 is a string, label or comment that is not valid UTF-8.
+	CodeInvalidUTF8 = "HCL0007"
+	// CodeLimitExceeded is input exceeding a limit, eg. from WithMaxEntries.
+	CodeLimitExceeded = "HCL0008"
+	// CodeTypeMismatch is a value of the wrong type for its field.
+	CodeTypeMismatch = "HCL0009"
+	// CodeUnknownField is an attribute or block that doesn't correspond to
+	// any field.
+	CodeUnknownField = "HCL0010"
+	// CodeLabels is a block with the wrong number of labels.
+	CodeLabels = "HCL0011"
+	// CodeDuplicate is an attribute, block or set element that occurs more
+	// times than allowed.
+	CodeDuplicate = "HCL0012"
+	// CodeConstraint is a value violating a constraint, eg. from enum:"",
+	// pattern:"" or conflicts_with:"" tags, or a read-only attribute.
+	CodeConstraint = "HCL0013"
+	// CodeSchemaVersion is a document whose schema version is missing,
+	// unsupported or can't be migrated.
+	CodeSchemaVersion = "HCL0014"
+)
+
+// errorCodes classifies errors by their message, in order of precedence.
+var errorCodes = []struct {
+	code    string
+	message *regexp.Regexp
+}{
+	{CodeSchemaVersion, regexp.MustCompile(`schema_version|no migration from`)},
+	{CodeLimitExceeded, regexp.MustCompile(`^maximum .* exceeded|exceeds maximum (size|length)`)},
+	{CodeInvalidUTF8, regexp.MustCompile(`invalid UTF-8`)},
+	{CodeSyntax, regexp.MustCompile(`^unexpected token|matched input text|^invalid (number|string literal)`)},
+	{CodeMissingAttribute, regexp.MustCompile(`missing required attribute`)},
+	{CodeMissingBlock, regexp.MustCompile(`missing required block|expected at least \d+ .* blocks`)},
+	{CodeUnknownField, regexp.MustCompile(`found extra fields|unknown (attribute|block|field|profile)|unknown \S+ block`)},
+	{CodeDuplicate, regexp.MustCompile(`duplicate (field|attribute|block|value)|may only occur once|expected at most \d+ .* blocks`)},
+	{CodeLabels, regexp.MustCompile(`expects (\d+|a) label|unexpected labels`)},
+	{CodeConstraint, regexp.MustCompile(`does not match (anything within enum|pattern)|is (less|greater) than the (minimum|maximum)|conflicts with|requires .* to also be set|is read-only`)},
+	{CodeTypeMismatch, regexp.MustCompile(`expected (a|an) .* but got|must be an integer but got|cannot be both block and attribute|is out of range for|error converting|invalid duration`)},
+}
+
+// ErrorCode returns the stable code for the class of err, an error returned
+// by parsing or unmarshalling, or a Diagnostic, or "" if it has none.
+//
+// Codes are stable across releases, unlike messages, so tests and tooling can
+// match on them.
+func ErrorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	if diagnostic, ok := err.(Diagnostic); ok {
+		return diagnostic.Code
+	}
+	msg := err.Error()
+	if ferr := splitFieldError(err); ferr != nil {
+		return ErrorCode(ferr.Err)
+	} else if perr, ok := err.(participle.Error); ok {
+		msg = perr.Message()
+	}
+	for _, class := range errorCodes {
+		if class.message.MatchString(msg) {
+			return class.code
+		}
+	}
+	return ""
+}
+
+// A MessageCatalog translates or rephrases the messages of diagnostics, eg.
+// for products shipping non-English error messages.
+type MessageCatalog interface {
+	// Message returns the message for a diagnostic with code and the
+	// arguments listed for that code, or false to use the default message.
+	Message(code string, args []interface{}) (string, bool)
+}
+
+// MessageCatalogFunc is a function implementing MessageCatalog.
+type MessageCatalogFunc func(code string, args []interface{}) (string, bool)
+
+// Message calls f.
+func (f MessageCatalogFunc) Message(code string, args []interface{}) (string, bool) {
+	return f(code, args)
+}
+
+// WithMessageCatalog replaces the messages of diagnostics reported by
+// CheckCompatibility with those from catalog.
+func WithMessageCatalog(catalog MessageCatalog) MarshalOption {
+	return func(options *marshalOptions) {
+		options.messages = catalog
+	}
+}
+
+// Localize returns d with its message replaced by that from catalog, if
+// catalog has one for its code.
+func (d Diagnostic) Localize(catalog MessageCatalog) Diagnostic {
+	if catalog == nil || d.Code == "" {
+		return d
+	}
+	if msg, ok := catalog.Message(d.Code, d.Args); ok {
+		d.Msg = msg
+	}
+	return d
+}
@@ -0,0 +1,102 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCatalog(t *testing.T) {
+	type config struct {
+		Port int `hcl:"port"`
+	}
+	catalog := MessageCatalogFunc(func(code string, args []interface{}) (string, bool) {
+		switch code {
+		case CodeIgnoredAttribute:
+			if args[1] != "" {
+				return fmt.Sprintf("l'attribut %q serait ignoré ; vouliez-vous dire %q ?", args...), true
+			}
+			return fmt.Sprintf("l'attribut %q serait ignoré", args[0]), true
+		case CodeMissingAttribute:
+			return fmt.Sprintf("attribut obligatoire %q manquant", args[0]), true
+		}
+		return "", false
+	})
+	data := []byte("prot = 80\nname = \"x\"\n")
+	diagnostics, err := CheckCompatibility(data, &config{}, WithMessageCatalog(catalog))
+	require.NoError(t, err)
+	messages := []string{}
+	for _, diagnostic := range diagnostics {
+		messages = append(messages, diagnostic.Code+" "+diagnostic.Error())
+	}
+	require.Equal(t, []string{
+		`HCL0003 1:1: attribut obligatoire "port" manquant`,
+		`HCL0001 1:1: l'attribut "prot" serait ignoré ; vouliez-vous dire "port" ?`,
+		`HCL0001 2:1: l'attribut "name" serait ignoré`,
+	}, messages)
+
+	// Diagnostics are localized when rendered.
+	diagnostics, err = CheckCompatibility(data, &config{})
+	require.NoError(t, err)
+	require.Equal(t, `attribute "name" would be ignored`, diagnostics[2].Msg)
+	w := &strings.Builder{}
+	err = NewDiagnosticWriter(w, DiagnosticMessages(catalog)).WriteError(diagnostics[2], data)
+	require.NoError(t, err)
+	require.Equal(t, `error: l'attribut "name" serait ignoré
+ --> 2:1
+  |
+2 | name = "x"
+  | ^
+`, w.String())
+}
+
+func TestErrorCode(t *testing.T) {
+	type server struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port,optional"`
+	}
+	type config struct {
+		Host    string   `hcl:"host"`
+		Mode    string   `hcl:"mode,optional" enum:"a,b"`
+		Servers []server `hcl:"server,block"`
+	}
+	tests := []struct {
+		source string
+		code   string
+	}{
+		{`host = `, CodeSyntax},
+		{`host = "x" ?`, CodeSyntax},
+		{`host = "\xff"`, CodeInvalidUTF8},
+		{`host = 1`, CodeTypeMismatch},
+		{`server "a" { port = "x" }`, CodeMissingAttribute},
+		{`host = "" server "a" { port = "x" }`, CodeTypeMismatch},
+		{`host = "" prot = 1`, CodeUnknownField},
+		{`host = "" host = ""`, CodeDuplicate},
+		{`host = "" server "a" "b" {}`, CodeLabels},
+		{`host = "" mode = "c"`, CodeConstraint},
+	}
+	for _, test := range tests {
+		var out config
+		err := Unmarshal([]byte(test.source), &out)
+		require.Error(t, err, test.source)
+		require.Equal(t, test.code, ErrorCode(err), "%s: %s", test.source, err)
+	}
+
+	_, err := ParseString("a = [[1]]", WithMaxParseDepth(1))
+	require.Equal(t, CodeLimitExceeded, ErrorCode(err))
+	require.Equal(t, "", ErrorCode(nil))
+	require.Equal(t, "", ErrorCode(fmt.Errorf("something else")))
+
+	// Errors with codes are localized when rendered.
+	catalog := MessageCatalogFunc(func(code string, args []interface{}) (string, bool) {
+		return fmt.Sprintf("erreur de syntaxe : %s", args[0]), code == CodeSyntax
+	})
+	_, err = ParseString("a = }")
+	require.Error(t, err)
+	w := &strings.Builder{}
+	err = NewDiagnosticWriter(w, DiagnosticMessages(catalog)).WriteError(err, []byte("a = }"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(w.String(), `error: erreur de syntaxe : unexpected token "}"`), w.String())
+}
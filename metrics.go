@@ -0,0 +1,94 @@
+package hcl
+
+import (
+	"io"
+	"time"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Metrics receives measurements of parsing and unmarshalling, eg. to monitor
+// the health of configuration processing in production services.
+//
+// Methods are called synchronously, so a Metrics shared between goroutines
+// must be safe for concurrent use.
+type Metrics interface {
+	// Parsed is called after each document is parsed, successfully or not.
+	Parsed(stats ParseStats)
+	// Decoded is called after each document or block is unmarshalled,
+	// successfully or not.
+	Decoded(stats DecodeStats)
+}
+
+// ParseStats describes the parsing of a document.
+type ParseStats struct {
+	Duration time.Duration
+	// Size of the document in bytes.
+	Size int
+	// Number of attributes and blocks parsed, including those nested in blocks.
+	Entries int
+	// Err is the error parsing failed with, if any.
+	Err error
+}
+
+// DecodeStats describes the unmarshalling of a document or block.
+type DecodeStats struct {
+	Duration time.Duration
+	// Number of attributes and blocks decoded, including those nested in blocks.
+	Entries int
+	// Err is the error unmarshalling failed with, if any.
+	Err error
+}
+
+// WithParseMetrics reports the duration, size, number of entries and error of
+// each document parsed to m.
+func WithParseMetrics(m Metrics) ParseOption {
+	return func(options *parseOptions) {
+		options.metrics = m
+	}
+}
+
+// WithMetrics reports measurements of each document unmarshalled to m,
+// including parsing it if it is unmarshalled from HCL source.
+func WithMetrics(m Metrics) MarshalOption {
+	return func(options *marshalOptions) {
+		options.metrics = m
+	}
+}
+
+// reportParse reports the parsing of a document of size bytes into dst,
+// started at start, to the metrics, if any.
+func (o *parseOptions) reportParse(start time.Time, size int, dst Node, err error) {
+	if o.metrics == nil {
+		return
+	}
+	stats := ParseStats{Duration: time.Since(start), Size: size, Err: err}
+	if err == nil {
+		stats.Entries = countEntries(dst)
+	}
+	o.metrics.Parsed(stats)
+}
+
+// reportDecode reports the unmarshalling of node, started at start, to the
+// metrics, if any.
+func (o *marshalOptions) reportDecode(start time.Time, node Node, err error) {
+	if o.metrics == nil {
+		return
+	}
+	o.metrics.Decoded(DecodeStats{Duration: time.Since(start), Entries: countEntries(node), Err: err})
+}
+
+// countingReader counts the bytes read from r, for ParseStats.Size.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+// Name of the underlying reader, for positions.
+func (c *countingReader) Name() string { return lexer.NameOfReader(c.r) }
@@ -0,0 +1,80 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMetrics struct {
+	parsed  []ParseStats
+	decoded []DecodeStats
+}
+
+func (r *recordingMetrics) Parsed(stats ParseStats)   { r.parsed = append(r.parsed, stats) }
+func (r *recordingMetrics) Decoded(stats DecodeStats) { r.decoded = append(r.decoded, stats) }
+
+func TestWithMetrics(t *testing.T) {
+	type server struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Debug  bool     `hcl:"debug"`
+		Server []server `hcl:"server,block"`
+	}
+	source := `
+debug = true
+server "web" {
+  port = 8080
+}
+`
+	metrics := &recordingMetrics{}
+	var cfg config
+	err := Unmarshal([]byte(source), &cfg, WithMetrics(metrics))
+	require.NoError(t, err)
+	require.Len(t, metrics.parsed, 1)
+	require.Equal(t, len(source), metrics.parsed[0].Size)
+	require.Equal(t, 3, metrics.parsed[0].Entries)
+	require.NoError(t, metrics.parsed[0].Err)
+	require.Len(t, metrics.decoded, 1)
+	require.Equal(t, 3, metrics.decoded[0].Entries)
+	require.NoError(t, metrics.decoded[0].Err)
+
+	metrics = &recordingMetrics{}
+	err = Unmarshal([]byte(`debug = "yes"`), &cfg, WithMetrics(metrics))
+	require.Error(t, err)
+	require.Len(t, metrics.parsed, 1)
+	require.NoError(t, metrics.parsed[0].Err)
+	require.Len(t, metrics.decoded, 1)
+	require.Equal(t, err, metrics.decoded[0].Err)
+
+	metrics = &recordingMetrics{}
+	err = Unmarshal([]byte(`debug = `), &cfg, WithMetrics(metrics))
+	require.Error(t, err)
+	require.Len(t, metrics.parsed, 1)
+	require.Equal(t, err, metrics.parsed[0].Err)
+	require.Empty(t, metrics.decoded)
+}
+
+func TestWithParseMetrics(t *testing.T) {
+	source := "a = 1\nb {\n  c = 2\n}\nd = 3\n"
+	metrics := &recordingMetrics{}
+	_, err := ParseReader(strings.NewReader(source), WithParseMetrics(metrics))
+	require.NoError(t, err)
+	require.Len(t, metrics.parsed, 1)
+	require.Equal(t, len(source), metrics.parsed[0].Size)
+	require.Equal(t, 4, metrics.parsed[0].Entries)
+
+	metrics = &recordingMetrics{}
+	docs := NewDocumentReader(strings.NewReader("a = 1\n---\nb = 2\nc = 3\n"), WithParseMetrics(metrics))
+	for {
+		_, err := docs.Next()
+		if err != nil {
+			break
+		}
+	}
+	require.Len(t, metrics.parsed, 2)
+	require.Equal(t, []int{1, 2}, []int{metrics.parsed[0].Entries, metrics.parsed[1].Entries})
+}
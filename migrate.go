@@ -0,0 +1,155 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// RenameRule renames attributes or blocks called From to To.
+type RenameRule struct {
+	// Dotted path of the names of the enclosing blocks, after any renames have
+	// been applied to them, eg. "service.http". Empty for the root.
+	Path string
+	From string
+	To   string
+}
+
+// RenameRules derives the rules for migrating legacy names to canonical names
+// from the alias:"" tags of v.
+func RenameRules(v interface{}, options ...MarshalOption) ([]RenameRule, error) {
+	opt := &marshalOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct but got %T", v)
+	}
+	return renameRules(nil, t, opt, map[reflect.Type]bool{})
+}
+
+func renameRules(path []string, t reflect.Type, opt *marshalOptions, seen map[reflect.Type]bool) ([]RenameRule, error) {
+	// Recursive types would otherwise never terminate.
+	if seen[t] {
+		return nil, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+	fields, err := flattenFields(reflect.New(t).Elem(), opt)
+	if err != nil {
+		return nil, err
+	}
+	rules := []RenameRule{}
+	for _, field := range fields {
+		tag := parseTag(t, field, opt)
+		if tag.name == "" || tag.label || tag.remain {
+			continue
+		}
+		for _, alias := range tag.aliases {
+			rules = append(rules, RenameRule{Path: strings.Join(path, "."), From: alias, To: tag.name})
+		}
+		if !tag.block {
+			continue
+		}
+		elt := field.t.Type
+		for elt.Kind() == reflect.Ptr || elt.Kind() == reflect.Slice {
+			elt = elt.Elem()
+		}
+		if elt.Kind() != reflect.Struct {
+			continue
+		}
+		sub, err := renameRules(append(path[:len(path):len(path)], tag.name), elt, opt, seen)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, sub...)
+	}
+	return rules, nil
+}
+
+// Migrate rewrites the names of attributes and blocks in src according to rules.
+//
+// Only the names themselves are replaced, so formatting and comments are
+// preserved.
+func Migrate(src []byte, rules []RenameRule) ([]byte, error) {
+	src, bom, isCRLF := normaliseSource(src)
+	ast, err := ParseBytes(src)
+	if err != nil {
+		return nil, err
+	}
+	tokenLexer, err := lex.Lex(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := lexer.ConsumeAll(tokenLexer)
+	if err != nil {
+		return nil, err
+	}
+	idents := []lexer.Token{}
+	ident := lex.Symbols()["Ident"]
+	for _, token := range tokens {
+		if token.Type == ident {
+			idents = append(idents, token)
+		}
+	}
+	renames := map[string]string{}
+	for _, rule := range rules {
+		renames[rule.Path+"\x00"+rule.From] = rule.To
+	}
+	m := &migrator{idents: idents, renames: renames}
+	if err := m.entries(nil, ast.Entries); err != nil {
+		return nil, err
+	}
+	// Apply edits from the end so earlier offsets remain valid.
+	sort.Slice(m.edits, func(i, j int) bool { return m.edits[i].offset > m.edits[j].offset })
+	out := append([]byte{}, src...)
+	for _, edit := range m.edits {
+		tail := append([]byte(edit.value), out[edit.offset+edit.length:]...)
+		out = append(out[:edit.offset], tail...)
+	}
+	return restoreSource(out, bom, isCRLF), nil
+}
+
+type migrator struct {
+	idents  []lexer.Token
+	renames map[string]string
+	edits   []migrateEdit
+}
+
+// migrateEdit replaces length bytes at offset with value.
+type migrateEdit struct {
+	offset int
+	length int
+	value  string
+}
+
+func (m *migrator) entries(path []string, entries []*Entry) error {
+	for _, entry := range entries {
+		key := entry.Key()
+		prefix := strings.Join(path, ".") + "\x00"
+		if to, ok := m.renames[prefix+key]; ok {
+			// The name is the first identifier of the entry, following any comments.
+			i := sort.Search(len(m.idents), func(i int) bool { return m.idents[i].Pos.Offset >= entry.Pos.Offset })
+			if i == len(m.idents) || m.idents[i].Value != key {
+				return participle.Errorf(entry.Pos, "could not locate name %q", key)
+			}
+			m.edits = append(m.edits, migrateEdit{offset: m.idents[i].Pos.Offset, length: len(key), value: to})
+			key = to
+		}
+		if entry.Block != nil {
+			if err := m.entries(append(path[:len(path):len(path)], key), entry.Block.Body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	src := `// Wait before retrying.
+wait   = 10 # seconds
+
+filter "a" {
+  // Legacy.
+  match = "*"
+}
+
+server {
+  wait = 5
+}
+`
+	rules := []RenameRule{
+		{From: "wait", To: "timeout"},
+		{From: "filter", To: "rule"},
+		{Path: "rule", From: "match", To: "pattern"},
+	}
+	actual, err := Migrate([]byte(src), rules)
+	require.NoError(t, err)
+	require.Equal(t, `// Wait before retrying.
+timeout   = 10 # seconds
+
+rule "a" {
+  // Legacy.
+  pattern = "*"
+}
+
+server {
+  wait = 5
+}
+`, string(actual))
+}
+
+func TestRenameRules(t *testing.T) {
+	type rule struct {
+		Name    string `hcl:"name,label"`
+		Pattern string `hcl:"pattern" alias:"match"`
+	}
+	type config struct {
+		Timeout int     `hcl:"timeout" alias:"wait,wait_secs"`
+		Rules   []rule  `hcl:"rule,block" alias:"filter"`
+		Next    *config `hcl:"next,block"`
+	}
+	rules, err := RenameRules(&config{})
+	require.NoError(t, err)
+	require.Equal(t, []RenameRule{
+		{From: "wait", To: "timeout"},
+		{From: "wait_secs", To: "timeout"},
+		{From: "filter", To: "rule"},
+		{Path: "rule", From: "match", To: "pattern"},
+	}, rules)
+}
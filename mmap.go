@@ -0,0 +1,21 @@
+package hcl
+
+// ParseFileMmap parses the HCL file at path, for very large files such as
+// generated artifacts.
+//
+// Where the platform supports it the file is memory-mapped rather than read
+// into memory, so that the only copy of its contents is that made by the
+// lexer. Otherwise, or if the file can't be mapped, it is read as usual.
+// Positions refer to path.
+func ParseFileMmap(path string, options ...ParseOption) (*AST, error) {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unmap()
+	ast := &AST{}
+	if err := parseSource(path, data, ast, newParseOptions(options)); err != nil {
+		return nil, err
+	}
+	return ast, nil
+}
@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package hcl
+
+import (
+	"io/ioutil"
+)
+
+// mmapFile reads the file at path, as memory-mapping is not supported.
+func mmapFile(path string) ([]byte, func(), error) {
+	data, err := ioutil.ReadFile(path)
+	return data, func() {}, err
+}
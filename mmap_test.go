@@ -0,0 +1,44 @@
+package hcl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFileMmap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcl-mmap")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	source := "// Server.\r\nserver \"web\" {\r\n  port = 8080\r\n}\r\n"
+	path := filepath.Join(dir, "config.hcl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(source), 0600))
+	ast, err := ParseFileMmap(path, WithKeepSource())
+	require.NoError(t, err)
+	expected, err := ParseBytes([]byte(source), WithKeepSource())
+	require.NoError(t, err)
+	require.True(t, ASTEqual(expected, ast, IgnorePositions()))
+	require.Equal(t, path+":3:3", ast.Entries[0].Block.Body[0].Pos.String())
+	out, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, source, string(out))
+
+	empty := filepath.Join(dir, "empty.hcl")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0600))
+	ast, err = ParseFileMmap(empty)
+	require.NoError(t, err)
+	require.Empty(t, ast.Entries)
+
+	invalid := filepath.Join(dir, "invalid.hcl")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("a = \n"), 0600))
+	_, err = ParseFileMmap(invalid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), invalid+":2:1:")
+
+	_, err = ParseFileMmap(filepath.Join(dir, "missing.hcl"))
+	require.Error(t, err)
+}
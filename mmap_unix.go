@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package hcl
+
+import (
+	"io/ioutil"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the file at path into memory read-only, returning its
+// contents and a function to unmap them.
+func mmapFile(path string) ([]byte, func(), error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close() // nolint: errcheck
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size == 0 || int64(int(size)) != size || !info.Mode().IsRegular() {
+		data, err := ioutil.ReadAll(f)
+		return data, func() {}, err
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		// Some filesystems don't support mapping files.
+		data, err := ioutil.ReadAll(f)
+		return data, func() {}, err
+	}
+	return data, func() { _ = syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,66 @@
+package hcl
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WithNameTransform derives the HCL names of struct fields without hcl:"" or
+// json:"" tags by applying fn to their Go names, eg. WithNameTransform(SnakeCase).
+func WithNameTransform(fn func(name string) string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.nameTransform = fn
+	}
+}
+
+// CaseInsensitive specifies whether to match attribute and block names to
+// fields regardless of case when unmarshalling.
+func CaseInsensitive(v bool) MarshalOption {
+	return func(options *marshalOptions) {
+		options.caseInsensitive = v
+	}
+}
+
+// SnakeCase converts a CamelCase Go name to snake_case, eg. "HTTPServer" to "http_server".
+func SnakeCase(name string) string {
+	return strings.Join(splitWords(name), "_")
+}
+
+// LowerCamelCase converts a CamelCase Go name to lowerCamelCase, eg. "HTTPServer" to "httpServer".
+func LowerCamelCase(name string) string {
+	words := splitWords(name)
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits a CamelCase name into lower case words, keeping runs of
+// upper case letters such as acronyms together. Underscores also separate words.
+func splitWords(name string) []string {
+	runes := []rune(name)
+	words := []string{}
+	word := []rune{}
+	for i, r := range runes {
+		if r == '_' {
+			if len(word) > 0 {
+				words = append(words, strings.ToLower(string(word)))
+				word = word[:0]
+			}
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			acronymEnd := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || acronymEnd {
+				words = append(words, strings.ToLower(string(word)))
+				word = word[:0]
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, strings.ToLower(string(word)))
+	}
+	return words
+}
@@ -0,0 +1,72 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNameTransforms(t *testing.T) {
+	tests := []struct {
+		name  string
+		snake string
+		camel string
+	}{
+		{"Port", "port", "port"},
+		{"MaxRetries", "max_retries", "maxRetries"},
+		{"HTTPServer", "http_server", "httpServer"},
+		{"UserID", "user_id", "userId"},
+		{"ID", "id", "id"},
+		{"Listen_Addr", "listen_addr", "listenAddr"},
+		{"TLS2Config", "tls2_config", "tls2Config"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.snake, SnakeCase(test.name))
+			require.Equal(t, test.camel, LowerCamelCase(test.name))
+		})
+	}
+}
+
+func TestWithNameTransform(t *testing.T) {
+	type server struct {
+		ListenAddr string
+		MaxConns   int
+	}
+	type config struct {
+		HTTPServer server
+	}
+	src := &config{HTTPServer: server{ListenAddr: ":80", MaxConns: 10}}
+	data, err := Marshal(src, InferHCLTags(true), WithNameTransform(SnakeCase))
+	require.NoError(t, err)
+	require.Equal(t, `http_server {
+  listen_addr = ":80"
+  max_conns = 10
+}
+`, string(data))
+
+	actual := &config{}
+	err = Unmarshal(data, actual, InferHCLTags(true), WithNameTransform(SnakeCase))
+	require.NoError(t, err)
+	require.Equal(t, src, actual)
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	type config struct {
+		Name  string `hcl:"name"`
+		Port  int    `hcl:"port" alias:"listen_port"`
+		Debug bool
+	}
+	src := `
+NAME = "api"
+Listen_Port = 8080
+debug = true
+`
+	actual := &config{}
+	err := Unmarshal([]byte(src), actual, CaseInsensitive(true))
+	require.NoError(t, err)
+	require.Equal(t, &config{Name: "api", Port: 8080, Debug: true}, actual)
+
+	err = Unmarshal([]byte(src), &config{})
+	require.Error(t, err)
+}
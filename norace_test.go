@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package hcl
+
+// raceEnabled is true when the race detector, which adds allocations, is on.
+const raceEnabled = false
@@ -0,0 +1,129 @@
+package hcl
+
+import (
+	"sort"
+)
+
+// NormalizeOption configures Normalize.
+type NormalizeOption func(options *normalizeOptions)
+
+type normalizeOptions struct {
+	preserveBlockOrder bool
+}
+
+// PreserveBlockOrder retains the relative order of blocks when normalizing,
+// for documents in which the order of blocks is significant. Attributes are
+// still moved before blocks.
+func PreserveBlockOrder() NormalizeOption {
+	return func(options *normalizeOptions) {
+		options.preserveBlockOrder = true
+	}
+}
+
+// Normalize rewrites ast in place into a canonical form, suitable for hashing
+// and diffing. Documents that differ only in layout normalize to the same AST,
+// and print identically.
+//
+// In each body, attributes are sorted by key and precede blocks, which are
+// sorted by name and then labels. Blocks with the same name and labels retain
+// their relative order. Heredocs and raw strings are converted to strings,
+// numbers lose their source formatting, map entries are sorted by key, and
+// repeated empty blocks with the same name and labels are collapsed into one.
+// Comments are retained with the entries they belong to; use StripComments to
+// remove them.
+func Normalize(ast *AST, options ...NormalizeOption) error {
+	opt := &normalizeOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	ast.BlankLines = false
+	ast.CRLF = false
+	ast.BOM = false
+	ast.Entries = normalizeEntries(ast.Entries, opt)
+	return AddParentRefs(ast)
+}
+
+func normalizeEntries(entries []*Entry, opt *normalizeOptions) []*Entry {
+	out := make([]*Entry, 0, len(entries))
+	empty := map[string]bool{}
+	for _, entry := range entries {
+		entry.BlankLineBefore = false
+		entry.source = nil
+		if entry.Attribute != nil {
+			normalizeValue(entry.Attribute.Value)
+			out = append(out, entry)
+			continue
+		}
+		block := entry.Block
+		block.Body = normalizeEntries(block.Body, opt)
+		if len(block.Body) == 0 && len(block.Comments) == 0 && len(block.TrailingComments) == 0 {
+			key := blockIdentity(block)
+			if empty[key] {
+				continue
+			}
+			empty[key] = true
+		}
+		out = append(out, entry)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Attribute != nil) != (b.Attribute != nil) {
+			return a.Attribute != nil
+		}
+		if a.Attribute != nil {
+			return a.Attribute.Key < b.Attribute.Key
+		}
+		if opt.preserveBlockOrder {
+			return false
+		}
+		return blockLess(a.Block, b.Block)
+	})
+	return out
+}
+
+// blockLess orders blocks by name, and then by labels.
+func blockLess(a, b *Block) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	for i := 0; i < len(a.Labels) && i < len(b.Labels); i++ {
+		if a.Labels[i] != b.Labels[i] {
+			return a.Labels[i] < b.Labels[i]
+		}
+	}
+	return len(a.Labels) < len(b.Labels)
+}
+
+func normalizeValue(v *Value) {
+	if v == nil {
+		return
+	}
+	switch {
+	case v.Number != nil:
+		v.Literal = ""
+		v.Base = 0
+
+	case v.Str != nil:
+		v.Raw = false
+
+	case v.HeredocDelimiter != "":
+		s := v.GetHeredoc()
+		v.Str = &s
+		v.HeredocDelimiter = ""
+		v.Heredoc = nil
+
+	case v.HaveList:
+		for _, e := range v.List {
+			normalizeValue(e)
+		}
+
+	case v.HaveMap:
+		for _, e := range v.Map {
+			normalizeValue(e.Key)
+			normalizeValue(e.Value)
+		}
+		sort.SliceStable(v.Map, func(i, j int) bool {
+			return v.Map[i].Key.String() < v.Map[j].Key.String()
+		})
+	}
+}
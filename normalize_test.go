@@ -0,0 +1,70 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	normalize := func(source string, options ...NormalizeOption) string {
+		ast, err := ParseString(source)
+		require.NoError(t, err)
+		require.NoError(t, Normalize(ast, options...))
+		data, err := MarshalAST(ast)
+		require.NoError(t, err)
+		return string(data)
+	}
+	a := normalize(`
+service "web" {
+  port = 0x50
+  env = {b: 2, a: 1.50}
+}
+
+name = <<EOF
+hello
+EOF
+
+service "api" {}
+cache {}
+service "api" {}
+
+count = 1e3
+`)
+	require.Equal(t, `count = 1000
+name = "hello"
+
+cache {
+}
+
+service "api" {
+}
+
+service "web" {
+  env = {
+    "a": 1.5,
+    "b": 2,
+  }
+  port = 80
+}
+`, a)
+	b := normalize(`count = 1000
+service "api" {}
+service "web" {
+  env = {"a": 1.5, "b": 2}
+  port = 80
+}
+cache {}
+name = "hello"
+`)
+	require.Equal(t, a, b)
+
+	require.Equal(t, `x = 1
+
+b {
+}
+
+a {
+}
+`, normalize("b {}\nx = 1\na {}\n", PreserveBlockOrder()))
+}
@@ -0,0 +1,116 @@
+package hcl
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var numberType = reflect.TypeOf(Number(""))
+
+// Number is a number in its literal form, eg. "1.20" or "0x1F".
+//
+// As with json.Number, interpretation is deferred to the caller, and the
+// source text of a number is preserved through round trips.
+type Number string
+
+func (n Number) String() string { return string(n) }
+
+// Float64 returns the number as a float64.
+func (n Number) Float64() (float64, error) {
+	f, err := parseNumber(string(n))
+	if err != nil {
+		return 0, err
+	}
+	v, _ := f.Float64()
+	return v, nil
+}
+
+// Int64 returns the number as an int64.
+func (n Number) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 0, 64)
+}
+
+// parseNumber parses a number literal, which may be prefixed with 0x, 0o or 0b
+// and contain underscores between digits.
+func parseNumber(s string) (*big.Float, error) {
+	n, _, err := big.ParseFloat(s, 0, 64, big.ToNearestEven)
+	if err != nil {
+		return nil, err
+	}
+	if n.IsInf() {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// literalMatches returns true if literal is a valid representation of n.
+func literalMatches(literal string, n *big.Float) bool {
+	m, err := parseNumber(literal)
+	return err == nil && m.Cmp(n) == 0
+}
+
+// numberToValue converts a Number to a Value, retaining its literal form.
+func numberToValue(n Number) (*Value, error) {
+	f, err := parseNumber(string(n))
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", string(n))
+	}
+	v := &Value{Number: f}
+	if formatNumber(f, 10) != string(n) {
+		v.Literal = string(n)
+	}
+	return v, nil
+}
+
+// NumberKind is whether a number was written as an integer or a float.
+type NumberKind int
+
+const (
+	// NumberInt is an integer, eg. 3, 0x1F or 1_000.
+	NumberInt NumberKind = iota
+	// NumberFloat is a number written with a fraction or exponent, eg. 3.0 or
+	// 1e3, or that isn't integral.
+	NumberFloat
+)
+
+func (k NumberKind) String() string {
+	switch k {
+	case NumberInt:
+		return "int"
+	case NumberFloat:
+		return "float"
+	default:
+		return fmt.Sprintf("NumberKind(%d)", int(k))
+	}
+}
+
+// NumberKind returns whether the number v was written as an integer or a
+// float, from its literal text if it was retained, so that eg. 3.0 is a float
+// although its value is integral.
+//
+// Numbers that aren't written from source, eg. when marshalled, are floats
+// only if they aren't integral.
+func (v *Value) NumberKind() NumberKind {
+	if v.Literal != "" {
+		return literalKind(v.Literal)
+	}
+	if v.Number != nil && !v.Number.IsInt() {
+		return NumberFloat
+	}
+	return NumberInt
+}
+
+// literalKind returns the kind of a number literal.
+func literalKind(literal string) NumberKind {
+	literal = strings.TrimLeft(literal, "+-")
+	if len(literal) > 1 && literal[0] == '0' && strings.ContainsAny(literal[1:2], "xXoObB") {
+		return NumberInt
+	}
+	if strings.ContainsAny(literal, ".eE") {
+		return NumberFloat
+	}
+	return NumberInt
+}
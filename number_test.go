@@ -0,0 +1,111 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumber(t *testing.T) {
+	type config struct {
+		Version  Number   `hcl:"version"`
+		Mask     Number   `hcl:"mask"`
+		Count    Number   `hcl:"count"`
+		Versions []Number `hcl:"versions"`
+		Default  Number   `hcl:"default,optional" default:"1.10"`
+	}
+	src := `version = 1.20
+mask = 0xFF
+count = 1_000
+versions = [1.9, 1.10]
+`
+	actual := &config{}
+	err := Unmarshal([]byte(src), actual)
+	require.NoError(t, err)
+	require.Equal(t, &config{
+		Version:  "1.20",
+		Mask:     "0xFF",
+		Count:    "1_000",
+		Versions: []Number{"1.9", "1.10"},
+		Default:  "1.10",
+	}, actual)
+
+	f, err := actual.Version.Float64()
+	require.NoError(t, err)
+	require.Equal(t, 1.2, f)
+	i, err := actual.Mask.Int64()
+	require.NoError(t, err)
+	require.Equal(t, int64(255), i)
+	i, err = actual.Count.Int64()
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), i)
+
+	data, err := Marshal(actual)
+	require.NoError(t, err)
+	require.Equal(t, src, string(data))
+
+	_, err = Marshal(&config{Version: "one"})
+	require.EqualError(t, err, `version: invalid number "one"`)
+
+	err = Unmarshal([]byte(`version = "1.20"`), &config{})
+	require.EqualError(t, err, `1:11: version: expected a number but got "1.20"`)
+}
+
+func TestParsePreservesNumberLiterals(t *testing.T) {
+	src := "a = 1.20\nb = 0o755\nc = 2\nd = 1e3\n"
+	ast, err := ParseString(src)
+	require.NoError(t, err)
+	require.Equal(t, "", ast.Entries[2].Attribute.Value.Literal)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, src, string(data))
+
+	// Literals that no longer match the number are ignored.
+	ast.Entries[0].Attribute.Value.Number.SetInt64(3)
+	data, err = MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, "a = 3\nb = 0o755\nc = 2\nd = 1e3\n", string(data))
+}
+
+func TestNumberKind(t *testing.T) {
+	ast, err := ParseString(`
+replicas = 3
+ratio = 3.0
+scale = 1e3
+half = 0.5
+mask = 0x1e
+delta = -2
+timeout = 1.5s
+`)
+	require.NoError(t, err)
+	kinds := map[string]NumberKind{}
+	for _, entry := range ast.Entries {
+		kinds[entry.Attribute.Key] = entry.Attribute.Value.NumberKind()
+	}
+	require.Equal(t, map[string]NumberKind{
+		"replicas": NumberInt,
+		"ratio":    NumberFloat,
+		"scale":    NumberFloat,
+		"half":     NumberFloat,
+		"mask":     NumberInt,
+		"delta":    NumberInt,
+		"timeout":  NumberFloat,
+	}, kinds)
+
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "replicas = 3\nratio = 3.0\n")
+	out, err := GenerateStruct(ast, "config", "Config")
+	require.NoError(t, err)
+	require.Contains(t, string(out), "Replicas int ")
+	require.Contains(t, string(out), "Ratio    float64 ")
+
+	type config struct {
+		Replicas int     `hcl:"replicas"`
+		Ratio    float64 `hcl:"ratio"`
+	}
+	actual := config{}
+	err = Unmarshal([]byte("replicas = 3\nratio = 3"), &actual)
+	require.NoError(t, err)
+	require.Equal(t, config{Replicas: 3, Ratio: 3}, actual)
+}
@@ -0,0 +1,311 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// WithOverrideSource sets the filename of the positions reported in errors
+// from ApplyOverrides, eg. "env" or "flags". Defaults to "override".
+func WithOverrideSource(name string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.overrideSource = name
+	}
+}
+
+// ApplyOverrides sets the fields of v, a pointer to a struct that has usually
+// already been unmarshalled, from overrides keyed by the dotted paths
+// produced by Flatten, eg. "server.0.port" = "8080". This layers overrides
+// from environment variables or command-line flags over a configuration file.
+//
+// Values are converted to the types of their fields as with WithWeakTypes, so
+// "8080" sets both a string and an int field. Lists and maps may be given in
+// HCL syntax, eg. `["a", "b"]`. Blocks and list elements with an index one
+// past the last are appended.
+//
+// Errors are positioned within the override value, in the file named by
+// WithOverrideSource, and are prefixed with the path of the override.
+func ApplyOverrides(v interface{}, overrides map[string]string, options ...MarshalOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct, not %T", v)
+	}
+	opt := newMarshalOptions(options...)
+	opt.weakTypes = true
+	if opt.overrideSource == "" {
+		opt.overrideSource = "override"
+	}
+	paths := make([]string, 0, len(overrides))
+	for path := range overrides {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return pathLess(paths[i], paths[j]) })
+	for _, path := range paths {
+		value, err := parseOverride(overrides[path], opt.overrideSource)
+		if err != nil {
+			return withFieldPath(path, err)
+		}
+		if err := applyOverride(rv.Elem(), strings.Split(path, "."), value, opt); err != nil {
+			return withFieldPath(path, err)
+		}
+	}
+	return nil
+}
+
+// parseOverride parses an override value, which is a string unless it is a
+// list or map in HCL syntax.
+func parseOverride(s string, source string) (*Value, error) {
+	pos := lexer.Position{Filename: source, Line: 1, Column: 1}
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return &Value{Pos: pos, Str: &s}, nil
+	}
+	value, err := ParseValue(s)
+	if perr, ok := err.(participle.Error); ok {
+		pos.Line, pos.Column, pos.Offset = perr.Token().Pos.Line, perr.Token().Pos.Column, perr.Token().Pos.Offset
+		return nil, participle.Errorf(pos, "%s", perr.Message())
+	} else if err != nil {
+		return nil, participle.AnnotateError(pos, err)
+	}
+	return value, Visit(value, func(node Node, next func() error) error {
+		if v, ok := node.(*Value); ok {
+			v.Pos.Filename = source
+		}
+		return next()
+	})
+}
+
+// applyOverride sets the field of struct v at the path segments to value.
+func applyOverride(v reflect.Value, segments []string, value *Value, opt *marshalOptions) error {
+	fields, err := flattenFields(v, opt)
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		tag := parseTag(v.Type(), field, opt)
+		if tag.name != segments[0] || tag.label || tag.remain {
+			continue
+		}
+		if tag.block {
+			return applyBlockOverride(field.v, segments, value, opt)
+		}
+		if tag.readonly {
+			return participle.Errorf(value.Pos, "attribute %q is read-only", tag.name)
+		}
+		if len(segments) > 1 {
+			return applyValueOverride(field.v, segments[1:], value, opt)
+		}
+		// Decode the attribute into a copy of the struct, so that the field
+		// is decoded exactly as it is by Unmarshal, without touching the
+		// struct's other fields.
+		decoded := reflect.New(v.Type()).Elem()
+		entry := &Entry{Pos: value.Pos, Attribute: &Attribute{Pos: value.Pos, Key: segments[0], Value: value}}
+		partial := *opt
+		partial.partial = true
+		if err := unmarshalEntries(decoded, []*Entry{entry}, &partial); err != nil {
+			if ferr := splitFieldError(err); ferr != nil {
+				err = ferr.Err
+			}
+			return participle.AnnotateError(value.Pos, err)
+		}
+		decodedFields, err := flattenFields(decoded, opt)
+		if err != nil {
+			return err
+		}
+		field.v.Set(decodedFields[i].v)
+		return nil
+	}
+	return participle.Errorf(value.Pos, "unknown field %q%s", segments[0], didYouMean(segments[0], fieldNames(v.Type(), fields, opt)))
+}
+
+// applyBlockOverride applies an override to the block field v, where segments
+// are the block's name, labels and index, followed by the path within it.
+func applyBlockOverride(v reflect.Value, segments []string, value *Value, opt *marshalOptions) error {
+	elt := v.Type()
+	if elt.Kind() == reflect.Slice {
+		elt = elt.Elem()
+	}
+	ptr := elt.Kind() == reflect.Ptr
+	if ptr {
+		elt = elt.Elem()
+	}
+	if elt.Kind() != reflect.Struct {
+		return participle.Errorf(value.Pos, "can't override block %q of type %s", segments[0], v.Type())
+	}
+	labelFields, err := blockLabelFields(reflect.New(elt).Elem(), opt)
+	if err != nil {
+		return err
+	}
+	if len(segments) < len(labelFields)+3 {
+		return participle.Errorf(value.Pos, "expected %d labels and an index for block %q", len(labelFields), segments[0])
+	}
+	labels := segments[1 : len(labelFields)+1]
+	index, err := strconv.Atoi(segments[len(labelFields)+1])
+	if err != nil || index < 0 {
+		return participle.Errorf(value.Pos, "invalid index %q for block %q", segments[len(labelFields)+1], segments[0])
+	}
+	rest := segments[len(labelFields)+2:]
+
+	// Find the index'th block with matching labels.
+	var (
+		matches []reflect.Value
+		el      reflect.Value
+	)
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			matches = append(matches, v.Index(i))
+		}
+	} else {
+		matches = append(matches, v)
+	}
+	found := -1
+	for _, candidate := range matches {
+		if ptr && candidate.IsNil() {
+			continue
+		}
+		if ptr {
+			candidate = candidate.Elem()
+		}
+		if !labelsMatch(candidate, labelFields, labels, opt) {
+			continue
+		}
+		found++
+		if found == index {
+			el = candidate
+			break
+		}
+	}
+	if !el.IsValid() {
+		switch {
+		case found+1 != index:
+			return participle.Errorf(value.Pos, "block %q has no element %d", strings.Join(segments[:len(labelFields)+1], "."), index)
+		case v.Kind() != reflect.Slice && found >= 0:
+			return participle.Errorf(value.Pos, "block %q may only occur once", segments[0])
+		case v.Kind() != reflect.Slice && !(ptr && v.IsNil()) && !reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface()):
+			return participle.Errorf(value.Pos, "labels of block %q don't match", segments[0])
+		}
+		// Create the block.
+		el = reflect.New(elt).Elem()
+		fields, err := flattenFields(el, opt)
+		if err != nil {
+			return err
+		}
+		for i, label := range labels {
+			if err := unmarshalLabel(labelField(el, fields, labelFields[i], opt), label); err != nil {
+				return participle.Wrapf(value.Pos, err, "invalid label %q for block %q", label, segments[0])
+			}
+		}
+		var created reflect.Value
+		if ptr {
+			created = reflect.New(elt)
+			created.Elem().Set(el)
+		} else {
+			created = el
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.Append(v, created))
+			el = v.Index(v.Len() - 1)
+		} else {
+			v.Set(created)
+			el = v
+		}
+		if ptr {
+			el = el.Elem()
+		}
+	}
+	return applyOverride(el, rest, value, opt)
+}
+
+// blockLabelFields returns the names of the label fields of block struct v.
+func blockLabelFields(v reflect.Value, opt *marshalOptions) ([]string, error) {
+	fields, err := flattenFields(v, opt)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, field := range fields {
+		if tag := parseTag(v.Type(), field, opt); tag.name != "" && tag.label {
+			names = append(names, tag.name)
+		}
+	}
+	return names, nil
+}
+
+// labelField returns the label field of block struct v named name.
+func labelField(v reflect.Value, fields []field, name string, opt *marshalOptions) reflect.Value {
+	for _, field := range fields {
+		if tag := parseTag(v.Type(), field, opt); tag.label && tag.name == name {
+			return field.v
+		}
+	}
+	return reflect.Value{}
+}
+
+// labelsMatch returns true if the label fields of block struct v equal labels.
+func labelsMatch(v reflect.Value, labelFields []string, labels []string, opt *marshalOptions) bool {
+	fields, err := flattenFields(v, opt)
+	if err != nil {
+		return false
+	}
+	for i, name := range labelFields {
+		if fmt.Sprint(labelField(v, fields, name, opt).Interface()) != labels[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyValueOverride sets the element of attribute value v at the path
+// segments, which index lists and key maps, to value.
+func applyValueOverride(v reflect.Value, segments []string, value *Value, opt *marshalOptions) error {
+	if len(segments) == 0 {
+		return unmarshalValue(v, value, opt)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		index, err := strconv.Atoi(segments[0])
+		if err != nil || index < 0 || index > v.Len() {
+			return participle.Errorf(value.Pos, "invalid index %q for a list of length %d", segments[0], v.Len())
+		}
+		if index == v.Len() {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		return applyValueOverride(v.Index(index), segments[1:], value, opt)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return participle.Errorf(value.Pos, "can't override keys of %s", v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		key := reflect.ValueOf(segments[0]).Convert(v.Type().Key())
+		el := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			el.Set(existing)
+		}
+		if err := applyValueOverride(el, segments[1:], value, opt); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, el)
+		return nil
+
+	case reflect.Struct:
+		return applyOverride(v, segments, value, opt)
+
+	default:
+		return participle.Errorf(value.Pos, "can't override %q of %s", segments[0], v.Type())
+	}
+}
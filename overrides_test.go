@@ -0,0 +1,90 @@
+package hcl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type overrideServer struct {
+	Name    string   `hcl:"name,label"`
+	Port    int      `hcl:"port,optional"`
+	Host    string   `hcl:"host,optional"`
+	Aliases []string `hcl:"aliases,optional"`
+}
+
+type overrideConfig struct {
+	Version string            `hcl:"version,optional"`
+	Debug   bool              `hcl:"debug,optional"`
+	Timeout time.Duration     `hcl:"timeout,optional"`
+	Level   string            `hcl:"level,optional" enum:"debug,info"`
+	Env     map[string]string `hcl:"env,optional"`
+	Servers []*overrideServer `hcl:"server,block"`
+}
+
+func TestApplyOverrides(t *testing.T) {
+	config := &overrideConfig{}
+	err := Unmarshal([]byte(`
+version = "1"
+server "web" {
+  port = 80
+}
+server "web" {
+  port = 81
+}
+`), config)
+	require.NoError(t, err)
+	err = ApplyOverrides(config, map[string]string{
+		"version":                "2",
+		"debug":                  "true",
+		"timeout":                "5s",
+		"env.HOME":               "/root",
+		"server.web.1.port":      "8081",
+		"server.web.1.aliases":   `["a", "b"]`,
+		"server.api.0.host":      "localhost",
+		"server.web.0.aliases.0": "www",
+	})
+	require.NoError(t, err)
+	require.Equal(t, &overrideConfig{
+		Version: "2",
+		Debug:   true,
+		Timeout: 5 * time.Second,
+		Env:     map[string]string{"HOME": "/root"},
+		Servers: []*overrideServer{
+			{Name: "web", Port: 80, Aliases: []string{"www"}},
+			{Name: "web", Port: 8081, Aliases: []string{"a", "b"}},
+			{Name: "api", Host: "localhost"},
+		},
+	}, config)
+}
+
+func TestApplyOverridesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		options   []MarshalOption
+		err       string
+	}{
+		{"Type", map[string]string{"server.web.0.port": "eighty"}, nil,
+			`override:1:1: server.web.0.port: expected a number but got "eighty"`},
+		{"Source", map[string]string{"debug": "maybe"}, []MarshalOption{WithOverrideSource("env")},
+			`env:1:1: debug: expected a bool but got "maybe"`},
+		{"Syntax", map[string]string{"server.web.0.aliases": `["a" "b"]`}, nil,
+			`override:1:6: server.web.0.aliases: unexpected token "b" (expected "]")`},
+		{"Unknown", map[string]string{"verison": "1"}, nil,
+			`override:1:1: verison: unknown field "verison"; did you mean "version"?`},
+		{"Enum", map[string]string{"level": "trace"}, nil,
+			`override:1:1: level: value "trace" does not match anything within enum "debug", "info"`},
+		{"Index", map[string]string{"server.web.2.port": "1"}, nil,
+			`override:1:1: server.web.2.port: block "server.web" has no element 2`},
+		{"Labels", map[string]string{"server.port": "1"}, nil,
+			`override:1:1: server.port: expected 1 labels and an index for block "server"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ApplyOverrides(&overrideConfig{}, test.overrides, test.options...)
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -1,14 +1,28 @@
 // Package hcl implements parsing, encoding and decoding of HCL from Go types.
 //
 // Its purpose is to provide idiomatic Go functions and types for HCL.
+//
+// # Concurrency
+//
+// Parsing, marshalling and unmarshalling are safe for concurrent use from
+// multiple goroutines, as are the Register* functions, which may be called at
+// any time. A registration applies to calls that start after it returns. The
+// With* options of the registries, such as WithBlockDecoder and WithUnion,
+// register for a single call instead, and take precedence over global
+// registrations. ASTs and Annotations are not safe for concurrent
+// modification.
 package hcl
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math/big"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/alecthomas/participle"
 	"github.com/alecthomas/participle/lexer"
@@ -23,9 +37,18 @@ type Node interface{ node() }
 type AST struct {
 	Pos lexer.Position `parser:"" json:"-"`
 
-	Entries          []*Entry `parser:"@@*" json:"entries,omitempty"`
-	TrailingComments []string `parser:"@Comment*" json:"trailing_comments,omitempty"`
-	Schema           bool     `parser:"" json:"schema,omitempty"`
+	Directives            []*Directive   `parser:"@@*" json:"directives,omitempty"`
+	Entries               []*Entry       `parser:"@@*" json:"entries,omitempty"`
+	TrailingComments      []string       `parser:"@(Comment | TrailingComment)*" json:"trailing_comments,omitempty"`
+	TrailingCommentStyles []CommentStyle `parser:"" json:"trailing_comment_styles,omitempty"`
+	Schema                bool           `parser:"" json:"schema,omitempty"`
+
+	// The source started with a UTF-8 byte order mark.
+	BOM bool `parser:"" json:"bom,omitempty"`
+	// The source used CRLF line endings.
+	CRLF bool `parser:"" json:"crlf,omitempty"`
+	// Entries record where the source had blank lines between them.
+	BlankLines bool `parser:"" json:"blank_lines,omitempty"`
 }
 
 // Clone the AST.
@@ -34,9 +57,19 @@ func (a *AST) Clone() *AST {
 		return nil
 	}
 	out := &AST{
-		Pos:              a.Pos,
-		TrailingComments: cloneStrings(a.TrailingComments),
-		Schema:           a.Schema,
+		Pos:                   a.Pos,
+		TrailingComments:      cloneStrings(a.TrailingComments),
+		TrailingCommentStyles: cloneCommentStyles(a.TrailingCommentStyles),
+		Schema:                a.Schema,
+		BOM:                   a.BOM,
+		CRLF:                  a.CRLF,
+		BlankLines:            a.BlankLines,
+	}
+	if a.Directives != nil {
+		out.Directives = make([]*Directive, len(a.Directives))
+		for i, directive := range a.Directives {
+			out.Directives[i] = directive.Clone()
+		}
 	}
 	out.Entries = make([]*Entry, len(a.Entries))
 	for i, entry := range a.Entries {
@@ -46,15 +79,33 @@ func (a *AST) Clone() *AST {
 	return out
 }
 
+// Reset the AST to its zero value, retaining allocated capacity so that it
+// can be reused, eg. via a sync.Pool.
+func (a *AST) Reset() {
+	for i := range a.Entries {
+		a.Entries[i] = nil
+	}
+	*a = AST{Entries: a.Entries[:0]}
+}
+
 func (*AST) node() {}
 
 // Entry at the top-level of a HCL file or block.
 type Entry struct {
 	Pos    lexer.Position `parser:"" json:"-"`
+	EndPos lexer.Position `parser:"" json:"-"`
 	Parent Node           `parser:"" json:"-"`
 
 	Attribute *Attribute `parser:"(   @@" json:"attribute,omitempty"`
 	Block     *Block     `parser:"  | @@ )" json:"block,omitempty"`
+
+	// The entry was preceded by a blank line in the source.
+	BlankLineBefore bool `parser:"" json:"blank_line_before,omitempty"`
+
+	// Source text of the entry, retained by WithKeepSource.
+	source *entrySource
+	// Provenance of the entry this entry replaced, if any.
+	overrides *Provenance
 }
 
 func (*Entry) node() {}
@@ -79,22 +130,39 @@ func (e *Entry) Clone() *Entry {
 		return nil
 	}
 	return &Entry{
-		Pos:       e.Pos,
-		Attribute: e.Attribute.Clone(),
-		Block:     e.Block.Clone(),
+		Pos:             e.Pos,
+		EndPos:          e.EndPos,
+		Attribute:       e.Attribute.Clone(),
+		Block:           e.Block.Clone(),
+		BlankLineBefore: e.BlankLineBefore,
+		source:          e.source,
+		overrides:       e.overrides,
 	}
 }
 
+// Reset the Entry to its zero value.
+func (e *Entry) Reset() {
+	*e = Entry{}
+}
+
 // Attribute is a key+value attribute.
 type Attribute struct {
 	Pos    lexer.Position `parser:"" json:"-"`
 	Parent Node           `parser:"" json:"-"`
 
-	Comments []string `parser:"@Comment*" json:"comments,omitempty"`
+	Comments      []string       `parser:"@(Comment | TrailingComment)*" json:"comments,omitempty"`
+	CommentStyles []CommentStyle `parser:"" json:"comment_styles,omitempty"`
+	// Number of leading Comments separated from the attribute by a blank
+	// line, such as section headings, which aren't part of its DocComment.
+	DetachedComments int `parser:"" json:"detached_comments,omitempty"`
 
 	Key   string `parser:"@Ident '='" json:"key"`
 	Value *Value `parser:"@@" json:"value"`
 
+	// Comments on the same line as, and following, the attribute.
+	TrailingComments      []string       `parser:"@TrailingComment*" json:"trailing_comments,omitempty"`
+	TrailingCommentStyles []CommentStyle `parser:"" json:"trailing_comment_styles,omitempty"`
+
 	// This will be populated during unmarshalling.
 	Default *Value `parser:"" json:"default,omitempty"`
 
@@ -103,6 +171,17 @@ type Attribute struct {
 
 	// Set for schemas when the attribute is optional.
 	Optional bool `parser:"" json:"optional,omitempty"`
+
+	// Set for schemas to the deprecation message of a deprecated attribute.
+	Deprecated string `parser:"" json:"deprecated,omitempty"`
+
+	// Set for schemas when the attribute is read-only, ie. may not be set in input.
+	ReadOnly bool `parser:"" json:"read_only,omitempty"`
+
+	// Set for schemas to the constraints on the attribute's value.
+	Pattern string `parser:"" json:"pattern,omitempty"`
+	Min     *Value `parser:"" json:"min,omitempty"`
+	Max     *Value `parser:"" json:"max,omitempty"`
 }
 
 func (*Attribute) node() {}
@@ -117,29 +196,60 @@ func (a *Attribute) Clone() *Attribute {
 		return nil
 	}
 	return &Attribute{
-		Pos:      a.Pos,
-		Comments: cloneStrings(a.Comments),
-		Key:      a.Key,
-		Value:    a.Value.Clone(),
-		Optional: a.Optional,
+		Pos:                   a.Pos,
+		Comments:              cloneStrings(a.Comments),
+		CommentStyles:         cloneCommentStyles(a.CommentStyles),
+		DetachedComments:      a.DetachedComments,
+		Key:                   a.Key,
+		Value:                 a.Value.Clone(),
+		TrailingComments:      cloneStrings(a.TrailingComments),
+		TrailingCommentStyles: cloneCommentStyles(a.TrailingCommentStyles),
+		Default:               a.Default.Clone(),
+		Enum:                  cloneValues(a.Enum),
+		Optional:              a.Optional,
+		Deprecated:            a.Deprecated,
+		ReadOnly:              a.ReadOnly,
+		Pattern:               a.Pattern,
+		Min:                   a.Min.Clone(),
+		Max:                   a.Max.Clone(),
 	}
 }
 
+// Reset the Attribute to its zero value.
+func (a *Attribute) Reset() {
+	*a = Attribute{}
+}
+
 // Block represents am optionally labelled HCL block.
 type Block struct {
 	Pos    lexer.Position `parser:"" json:"-"`
 	Parent Node           `parser:"" json:"-"`
 
-	Comments []string `parser:"@Comment*" json:"comments,omitempty"`
+	Comments      []string       `parser:"@(Comment | TrailingComment)*" json:"comments,omitempty"`
+	CommentStyles []CommentStyle `parser:"" json:"comment_styles,omitempty"`
+	// Number of leading Comments separated from the block by a blank line,
+	// such as section headings, which aren't part of its DocComment.
+	DetachedComments int `parser:"" json:"detached_comments,omitempty"`
 
 	Name   string   `parser:"@Ident" json:"name"`
 	Labels []string `parser:"@( Ident | String )*" json:"labels,omitempty"`
 	Body   []*Entry `parser:"'{' @@*" json:"body"`
 
-	TrailingComments []string `parser:"@Comment* '}'" json:"trailing_comments,omitempty"`
+	TrailingComments      []string       `parser:"@(Comment | TrailingComment)* '}'" json:"trailing_comments,omitempty"`
+	TrailingCommentStyles []CommentStyle `parser:"" json:"trailing_comment_styles,omitempty"`
 
 	// The block can be repeated. This is surfaced in schemas.
 	Repeated bool `parser:"" json:"repeated,omitempty"`
+	// Bounds on the number of occurrences of a repeated block, if non-zero.
+	// These are surfaced in schemas.
+	MinItems int `parser:"" json:"min_items,omitempty"`
+	MaxItems int `parser:"" json:"max_items,omitempty"`
+	// The block has the same schema as an enclosing block, so its body is
+	// omitted. RecursivePath is the dot-separated path of block names to the
+	// enclosing block, or empty for the document itself. These are surfaced
+	// in schemas.
+	Recursive     bool   `parser:"" json:"recursive,omitempty"`
+	RecursivePath string `parser:"" json:"recursive_path,omitempty"`
 }
 
 func (*Block) node() {}
@@ -150,28 +260,45 @@ func (b *Block) Clone() *Block {
 		return nil
 	}
 	out := &Block{
-		Pos:              b.Pos,
-		Comments:         cloneStrings(b.Comments),
-		Name:             b.Name,
-		Labels:           cloneStrings(b.Labels),
-		Body:             make([]*Entry, len(b.Body)),
-		TrailingComments: cloneStrings(b.TrailingComments),
-		Repeated:         b.Repeated,
+		Pos:                   b.Pos,
+		Comments:              cloneStrings(b.Comments),
+		CommentStyles:         cloneCommentStyles(b.CommentStyles),
+		DetachedComments:      b.DetachedComments,
+		Name:                  b.Name,
+		Labels:                cloneStrings(b.Labels),
+		Body:                  make([]*Entry, len(b.Body)),
+		TrailingComments:      cloneStrings(b.TrailingComments),
+		TrailingCommentStyles: cloneCommentStyles(b.TrailingCommentStyles),
+		Repeated:              b.Repeated,
+		MinItems:              b.MinItems,
+		MaxItems:              b.MaxItems,
+		Recursive:             b.Recursive,
+		RecursivePath:         b.RecursivePath,
 	}
 	for i, entry := range b.Body {
 		out.Body[i] = entry.Clone()
 	}
+	addParentRefs(nil, out)
 	return out
 }
 
+// Reset the Block to its zero value, retaining allocated capacity.
+func (b *Block) Reset() {
+	for i := range b.Body {
+		b.Body[i] = nil
+	}
+	*b = Block{Labels: b.Labels[:0], Body: b.Body[:0]}
+}
+
 // MapEntry represents a key+value in a map.
 type MapEntry struct {
 	Pos    lexer.Position `parser:"" json:"-"`
 	Parent Node           `parser:"" json:"-"`
 
-	Comments []string `parser:"@Comment*" json:"comments,omitempty"`
+	Comments      []string       `parser:"@(Comment | TrailingComment)*" json:"comments,omitempty"`
+	CommentStyles []CommentStyle `parser:"" json:"comment_styles,omitempty"`
 
-	Key   *Value `parser:"@@ ':'" json:"key"`
+	Key   *Value `parser:"@@ (':' | '=')" json:"key"`
 	Value *Value `parser:"@@" json:"value"`
 }
 
@@ -183,13 +310,19 @@ func (e *MapEntry) Clone() *MapEntry {
 		return nil
 	}
 	return &MapEntry{
-		Pos:      e.Pos,
-		Key:      e.Key.Clone(),
-		Value:    e.Value.Clone(),
-		Comments: cloneStrings(e.Comments),
+		Pos:           e.Pos,
+		Key:           e.Key.Clone(),
+		Value:         e.Value.Clone(),
+		Comments:      cloneStrings(e.Comments),
+		CommentStyles: cloneCommentStyles(e.CommentStyles),
 	}
 }
 
+// Reset the MapEntry to its zero value.
+func (e *MapEntry) Reset() {
+	*e = MapEntry{}
+}
+
 // Bool represents a parsed boolean value.
 type Bool bool
 
@@ -200,16 +333,36 @@ type Value struct {
 	Pos    lexer.Position `parser:"" json:"-"`
 	Parent Node           `parser:"" json:"-"`
 
-	Bool             *Bool       `parser:"(  @('true' | 'false')" json:"bool,omitempty"`
-	Number           *big.Float  `parser:" | @Number" json:"number,omitempty"`
+	Bool    *Bool      `parser:"(  @('true' | 'false')" json:"bool,omitempty"`
+	Number  *big.Float `parser:"" json:"number,omitempty"`
+	Literal string     `parser:" | @Number" json:"literal,omitempty"` // Source text of Number, if not in canonical form.
+	// Unit suffix of Number, eg. "s" for 30s or "MB" for 512MB.
+	Unit             string      `parser:"" json:"unit,omitempty"`
 	Type             *string     `parser:" | @('number':Ident | 'string':Ident | 'boolean':Ident)" json:"type,omitempty"`
+	ListBlocks       *listBlocks `parser:" | @@" json:"-"` // Moved to the elements of the enclosing List after parsing.
 	Str              *string     `parser:" | @(String | Ident)" json:"str,omitempty"`
+	RawStr           *string     `parser:" | @RawString" json:"-"`    // Moved to Str, with Raw set, after parsing.
+	SingleStr        *string     `parser:" | @SingleString" json:"-"` // Moved to Str, with Quote set, after parsing.
 	HeredocDelimiter string      `parser:" | (@Heredoc" json:"heredoc_delimiter,omitempty"`
 	Heredoc          *string     `parser:"     @(Body | EOL)* End)" json:"heredoc,omitempty"`
 	HaveList         bool        `parser:" | ( @'['" json:"have_list,omitempty"` // Need this to detect empty lists.
 	List             []*Value    `parser:"     ( @@ ( ',' @@ )* )? ','? ']' )" json:"list,omitempty"`
 	HaveMap          bool        `parser:" | ( @'{'" json:"have_map,omitempty"` // Need this to detect empty maps.
-	Map              []*MapEntry `parser:"     ( @@ ( ',' @@ )* ','? )? '}' ) )" json:"map,omitempty"`
+	Map              []*MapEntry `parser:"     ( @@ ( ','? @@ )* ','? )? '}' ) )" json:"map,omitempty"`
+
+	// Base used to format an integral Number, one of 2, 8, 10 or 16. Defaults to 10.
+	Base int `parser:"" json:"base,omitempty"`
+	// Raw is true if Str is formatted as a raw string literal, in which
+	// escapes are not processed, eg. `C:\Windows`.
+	Raw bool `parser:"" json:"raw,omitempty"`
+	// Quote is how Str is quoted, if not with double quotes.
+	Quote QuoteStyle `parser:"" json:"quote,omitempty"`
+	// Block is a block nested in a list, eg. each element of
+	// `rules = [rule "a" { deny = true } rule "b" {}]`.
+	Block *Block `parser:"" json:"block,omitempty"`
+
+	// Interpolation markers in Str that were escaped in the source.
+	escapes *escapes
 }
 
 // Clone the AST.
@@ -219,38 +372,85 @@ func (v *Value) Clone() *Value {
 	}
 	out := &Value{}
 	*out = *v
+	out.Parent = nil
 	switch {
-	case out.Number != nil:
+	case v.Bool != nil:
+		b := *v.Bool
+		out.Bool = &b
+
+	case v.Number != nil:
 		out.Number = &big.Float{}
 		out.Number.Copy(v.Number)
 
+	case v.Type != nil:
+		out.Type = cloneString(v.Type)
+
+	case v.Str != nil:
+		out.Str = cloneString(v.Str)
+
+	case v.HeredocDelimiter != "":
+		out.Heredoc = cloneString(v.Heredoc)
+
+	case v.Block != nil:
+		out.Block = v.Block.Clone()
+
 	case v.HaveList:
-		out.List = make([]*Value, len(v.List))
-		for i, value := range v.List {
-			out.List[i] = value.Clone()
-		}
+		out.List = cloneValues(v.List)
 
 	case v.HaveMap:
 		out.Map = make([]*MapEntry, len(v.Map))
-		for i, entry := range out.Map {
+		for i, entry := range v.Map {
 			out.Map[i] = entry.Clone()
 		}
 	}
 	return out
 }
 
+// Reset the Value to its zero value, retaining allocated capacity.
+func (v *Value) Reset() {
+	for i := range v.List {
+		v.List[i] = nil
+	}
+	for i := range v.Map {
+		v.Map[i] = nil
+	}
+	*v = Value{List: v.List[:0], Map: v.Map[:0]}
+}
+
 func (*Value) node() {}
 
+// formatNumber formats n, using a 0b, 0o or 0x prefixed literal if n is an
+// integer and base is 2, 8 or 16 respectively.
+func formatNumber(n *big.Float, base int) string {
+	prefix := map[int]string{2: "0b", 8: "0o", 16: "0x"}[base]
+	if prefix == "" || !n.IsInt() {
+		return n.String()
+	}
+	i, _ := n.Int(nil)
+	sign := ""
+	if i.Sign() < 0 {
+		sign = "-"
+		i.Neg(i)
+	}
+	return sign + prefix + i.Text(base)
+}
+
 func (v *Value) String() string {
 	switch {
 	case v.Bool != nil:
 		return fmt.Sprintf("%v", *v.Bool)
 
 	case v.Number != nil:
-		return v.Number.String()
+		if v.Literal != "" && v.Base == 0 && literalMatches(v.Literal, v.Number) {
+			return v.Literal + v.Unit
+		}
+		return formatNumber(v.Number, v.Base) + v.Unit
 
 	case v.Str != nil:
-		return fmt.Sprintf("%q", *v.Str)
+		if v.Raw && !strings.Contains(*v.Str, "`") {
+			return "`" + *v.Str + "`"
+		}
+		return quoteStringStyle(*v.Str, v.Quote)
 
 	case v.HeredocDelimiter != "":
 		heredoc := ""
@@ -259,6 +459,13 @@ func (v *Value) String() string {
 		}
 		return fmt.Sprintf("<<%s%s\n%s", v.HeredocDelimiter, heredoc, v.HeredocDelimiter)
 
+	case v.Block != nil:
+		header := v.Block.Name
+		for _, label := range v.Block.Labels {
+			header += " " + quoteString(label)
+		}
+		return header + " {...}"
+
 	case v.HaveList:
 		entries := []string{}
 		for _, e := range v.List {
@@ -299,36 +506,71 @@ func (v *Value) GetHeredoc() string {
 	return dedent(heredoc)
 }
 
+// numberPattern matches a number literal, without a unit.
+const numberPattern = `[-+]?(0[xX][0-9a-fA-F](_?[0-9a-fA-F])*|0[oO][0-7](_?[0-7])*|0[bB][01](_?[01])*|([0-9](_?[0-9])*)?\.?[0-9](_?[0-9])*([eE][-+]?[0-9]+)?)`
+
 var (
-	lex = lexer.Must(stateful.New(stateful.Rules{
-		"Root": {
-			{"Ident", `\b[[:alpha:]]\w*(-\w+)*\b`, nil},
-			{"Number", `\b^[-+]?[0-9]*\.?[0-9]+([eE][-+]?[0-9]+)?\b`, nil},
-			{"Heredoc", `<<[-]?(\w+\b)`, stateful.Push("Heredoc")},
-			{"String", `"(\\\d\d\d|\\.|[^"])*"`, nil},
-			{"Punct", `[][{}=:,]`, nil},
-			{"Comment", `(?:(?://|#)[^\n]*)|/\*.*?\*/`, nil},
-			{"whitespace", `\s+`, nil},
-		},
-		"Heredoc": {
-			{"End", `\n\b\1\b`, stateful.Pop()},
-			{"EOL", `\n`, nil},
-			{"Body", `[^\n]+`, nil},
-		},
+	// Rules of the lexer, shared with Scanner.
+	rootLexerRules = []stateful.Rule{
+		{"Ident", identifierPatterns[IdentifiersASCII], nil},
+		{"Number", `^` + numberPattern + `([[:alpha:]]+)?\b`, nil},
+		{"Heredoc", `<<[-]?(\w+\b)`, stateful.Push("Heredoc")},
+		{"String", `"(\\\d\d\d|\\.|[^"])*"`, nil},
+		{"RawString", "`[^`]*`", nil},
+		{"SingleString", `'(\\\d\d\d|\\.|[^'])*'`, nil},
+		{"Directive", `@[[:alpha:]]\w*`, nil},
+		{"Punct", `[][{}=:,]`, nil},
+		{"Comment", `(?:(?://|#)[^\n]*)|(?s:/\*.*?\*/)`, nil},
+		{"whitespace", `\s+`, nil},
+	}
+	heredocLexerRules = []stateful.Rule{
+		{"End", `\n\b\1\b`, stateful.Pop()},
+		{"EOL", `\n`, nil},
+		{"Body", `[^\n]+`, nil},
+	}
+	baseLex = lexer.Must(stateful.New(stateful.Rules{
+		"Root":    rootLexerRules,
+		"Heredoc": heredocLexerRules,
 	}))
-	parser = participle.MustBuild(&AST{},
+	lex           = commentLexerDefinition{baseLex}
+	parserOptions = []participle.Option{
 		participle.Lexer(lex),
-		participle.Unquote("String"),
+		participle.Map(unquoteString, "String"),
+		participle.Map(unquoteRawString, "RawString"),
+		participle.Map(unquoteSingleString, "SingleString"),
 		participle.Map(cleanHeredocStart, "Heredoc"),
-		participle.Map(stripComment, "Comment"),
 		// We need lookahead to ensure prefixed comments are associated with the right nodes.
-		participle.UseLookahead(50))
+		participle.UseLookahead(50),
+	}
+	parser      = participle.MustBuild(&AST{}, parserOptions...)
+	valueParser = participle.MustBuild(&Value{}, parserOptions...)
 )
 
-var stripCommentRe = regexp.MustCompile(`^//\s*|^/\*|\*/$`)
+// Unquote a string literal, unescaping interpolation markers ("$${" -> "${").
+func unquoteString(token lexer.Token) (lexer.Token, error) {
+	value, err := strconv.Unquote(token.Value)
+	if err != nil {
+		return token, participle.Errorf(token.Pos, "invalid string literal %s: %s", token.Value, err)
+	}
+	if !utf8.ValidString(value) {
+		return token, participle.Errorf(token.Pos, "invalid UTF-8 in string literal %s", token.Value)
+	}
+	token.Value = strings.ReplaceAll(value, "$${", "${")
+	return token, nil
+}
+
+// Quote a string as a HCL string literal, escaping interpolation markers ("${" -> "$${").
+func quoteString(s string) string {
+	return strings.ReplaceAll(strconv.Quote(s), "${", "$${")
+}
 
-func stripComment(token lexer.Token) (lexer.Token, error) {
-	token.Value = stripCommentRe.ReplaceAllString(token.Value, "")
+// Strip the backticks from a raw string literal.
+func unquoteRawString(token lexer.Token) (lexer.Token, error) {
+	value := token.Value[1 : len(token.Value)-1]
+	if !utf8.ValidString(value) {
+		return token, participle.Errorf(token.Pos, "invalid UTF-8 in string literal %s", token.Value)
+	}
+	token.Value = value
 	return token, nil
 }
 
@@ -338,34 +580,276 @@ func cleanHeredocStart(token lexer.Token) (lexer.Token, error) {
 	return token, nil
 }
 
+// ParseOption configures parsing.
+type ParseOption func(options *parseOptions)
+
+type parseOptions struct {
+	interner Interner
+
+	// Limits on untrusted input.
+	maxDepth        int
+	maxInputSize    int
+	maxEntries      int
+	maxStringLength int
+	// Entries already parsed from the same input, counted against maxEntries.
+	entriesBefore int
+
+	// Retain the source text of entries.
+	keepSource bool
+	// Line directives are mapped by the caller, which is parsing part of a
+	// larger input.
+	ignoreLineDirectives bool
+	// Directives allowed for a single call, taking precedence over those
+	// registered with RegisterDirective.
+	directives map[string]DirectiveHandler
+	// Characters allowed in identifiers.
+	identifiers IdentifierProfile
+	// Receives measurements of each document parsed.
+	metrics Metrics
+}
+
+func newParseOptions(options []ParseOption) *parseOptions {
+	opt := &parseOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	return opt
+}
+
 // Parse HCL from an io.Reader.
-func Parse(r io.Reader) (*AST, error) {
-	hcl := &AST{}
-	err := parser.Parse(r, hcl)
+func Parse(r io.Reader, options ...ParseOption) (*AST, error) {
+	opt := newParseOptions(options)
+	if opt.maxInputSize > 0 {
+		r = &sizeLimitReader{r: r, remaining: opt.maxInputSize, max: opt.maxInputSize}
+	}
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
-	return hcl, AddParentRefs(hcl)
+	hcl := &AST{}
+	if err := parseSource(lexer.NameOfReader(r), data, hcl, opt); err != nil {
+		return nil, err
+	}
+	return hcl, nil
 }
 
 // ParseString parses HCL from a string.
-func ParseString(str string) (*AST, error) {
+func ParseString(str string, options ...ParseOption) (*AST, error) {
+	return ParseBytes([]byte(str), options...)
+}
+
+// ParseBytes parses HCL from bytes.
+func ParseBytes(data []byte, options ...ParseOption) (*AST, error) {
 	hcl := &AST{}
-	err := parser.ParseString(str, hcl)
-	if err != nil {
+	if err := parseSource("", data, hcl, newParseOptions(options)); err != nil {
 		return nil, err
 	}
-	return hcl, AddParentRefs(hcl)
+	return hcl, nil
 }
 
-// ParseBytes parses HCL from bytes.
-func ParseBytes(data []byte) (*AST, error) {
-	hcl := &AST{}
-	err := parser.ParseBytes(data, hcl)
+// ParseInto parses HCL from bytes into dst, replacing its existing contents.
+//
+// This allows ASTs to be reused across parses, eg. via a sync.Pool.
+func ParseInto(dst *AST, data []byte, options ...ParseOption) error {
+	dst.Reset()
+	return parseSource("", data, dst, newParseOptions(options))
+}
+
+// parseSource parses data, read from filename if known, into dst.
+//
+// A leading UTF-8 byte order mark is removed and CRLF line endings are
+// converted to LF before parsing, and recorded in dst so that they can be
+// restored when printing, as are blank lines between entries.
+func parseSource(filename string, data []byte, dst *AST, opt *parseOptions) error {
+	if opt.metrics == nil {
+		return parseData(filename, data, dst, opt)
+	}
+	start := time.Now()
+	err := parseData(filename, data, dst, opt)
+	opt.reportParse(start, len(data), dst, err)
+	return err
+}
+
+// parseData parses data into dst, as parseSource does, without reporting
+// metrics.
+func parseData(filename string, data []byte, dst *AST, opt *parseOptions) error {
+	if opt.maxInputSize > 0 && len(data) > opt.maxInputSize {
+		return inputSizeError(opt.maxInputSize)
+	}
+	data, bom, crlf := normaliseSource(data)
+	if opt.ignoreLineDirectives {
+		return parseNormalised(filename, data, bom, crlf, dst, opt)
+	}
+	directives := scanLineDirectives(data, 0)
+	if err := parseNormalised(filename, data, bom, crlf, dst, opt); err != nil {
+		return directives.mapError(err)
+	}
+	return directives.mapPositions(dst)
+}
+
+// parseNormalised parses data, normalised by normaliseSource, into dst.
+func parseNormalised(filename string, data []byte, bom, crlf bool, dst *AST, opt *parseOptions) error {
+	var r io.Reader = bytes.NewReader(data)
+	if filename != "" {
+		r = &namedReader{Reader: r, name: filename}
+	}
+	var err error
+	if opt.limited() {
+		err = parseLimited(r, dst, opt)
+	} else {
+		err = opt.parser().Parse(r, dst)
+	}
+	if err != nil {
+		return err
+	}
+	dst.BOM, dst.CRLF, dst.BlankLines = bom, crlf, true
+	if err := recordBlankLines(dst, data); err != nil {
+		return err
+	}
+	if err := finishParse(dst, opt); err != nil {
+		return err
+	}
+	if bytes.Contains(data, escapedMarker) {
+		if err := recordEscapes(dst, data); err != nil {
+			return err
+		}
+	}
+	if opt.keepSource {
+		return keepSource(dst, data)
+	}
+	return nil
+}
+
+// finishParse normalises a freshly parsed node and adds parent references.
+func finishParse(node Node, opt *parseOptions) error {
+	if err := parseListBlocks(node); err != nil {
+		return err
+	}
+	if err := normaliseComments(node); err != nil {
+		return err
+	}
+	if err := parseNumbers(node); err != nil {
+		return err
+	}
+	if err := parseRawStrings(node); err != nil {
+		return err
+	}
+	if opt.interner != nil {
+		if err := internStrings(node, opt.interner); err != nil {
+			return err
+		}
+	}
+	if err := AddParentRefs(node); err != nil {
+		return err
+	}
+	return checkDirectives(node, opt)
+}
+
+// parseListBlocks moves the blocks captured by the parser into the elements
+// of their enclosing list, one per block.
+func parseListBlocks(node Node) error {
+	return Visit(node, func(node Node, next func() error) error {
+		v, ok := node.(*Value)
+		if !ok {
+			return next()
+		}
+		if v.ListBlocks != nil {
+			return participle.Errorf(v.Pos, "blocks are only allowed as list elements")
+		}
+		for i := 0; i < len(v.List); i++ {
+			captured := v.List[i].ListBlocks
+			if captured == nil {
+				continue
+			}
+			elements := make([]*Value, len(captured.blocks))
+			for j, block := range captured.blocks {
+				elements[j] = &Value{Pos: block.Pos, Block: block}
+			}
+			v.List = append(v.List[:i], append(elements, v.List[i+1:]...)...)
+			i += len(elements) - 1
+		}
+		return next()
+	})
+}
+
+// parseRawStrings moves raw and single quoted string literals captured by
+// the parser into Str.
+func parseRawStrings(node Node) error {
+	return Visit(node, func(node Node, next func() error) error {
+		if v, ok := node.(*Value); ok && v.RawStr != nil {
+			v.Str, v.RawStr, v.Raw = v.RawStr, nil, true
+		}
+		if v, ok := node.(*Value); ok && v.SingleStr != nil {
+			v.Str, v.SingleStr, v.Quote = v.SingleStr, nil, QuoteSingle
+		}
+		return next()
+	})
+}
+
+// parseNumbers converts the number literals captured by the parser into
+// Numbers, retaining the literal only if it differs from the canonical form.
+func parseNumbers(node Node) error {
+	return Visit(node, func(node Node, next func() error) error {
+		if v, ok := node.(*Value); ok && v.Literal != "" && v.Number == nil {
+			v.Literal, v.Unit = splitUnit(v.Literal)
+			n, err := parseNumber(v.Literal)
+			if err != nil {
+				return participle.Errorf(v.Pos, "invalid number %s", v.Literal)
+			}
+			v.Number = n
+			if formatNumber(n, 10) == v.Literal {
+				v.Literal = ""
+			}
+		}
+		return next()
+	})
+}
+
+// ParseValue parses a single HCL value, such as a string, number, list or map,
+// outside of a document, eg. from a command-line flag.
+//
+// As with documents, a leading byte order mark is removed and CRLF line
+// endings are converted to LF.
+func ParseValue(src string) (*Value, error) {
+	data, _, _ := normaliseSource([]byte(src))
+	value := &Value{}
+	err := valueParser.ParseBytes(data, value)
 	if err != nil {
 		return nil, err
 	}
-	return hcl, AddParentRefs(hcl)
+	if err := finishParse(value, &parseOptions{}); err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, escapedMarker) {
+		if err := recordEscapes(value, data); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// FormatValue formats a single HCL value such that it can be parsed by ParseValue.
+func FormatValue(v *Value) string {
+	return v.String()
+}
+
+func cloneString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	out := *s
+	return &out
+}
+
+func cloneValues(values []*Value) []*Value {
+	if values == nil {
+		return nil
+	}
+	out := make([]*Value, len(values))
+	for i, value := range values {
+		out[i] = value.Clone()
+	}
+	return out
 }
 
 func cloneStrings(strings []string) []string {
@@ -29,6 +29,11 @@ func TestClone(t *testing.T) {
 	require.NoError(t, err)
 	clone := ast.Clone()
 	require.Equal(t, ast, clone)
+
+	ast, err = ParseString(`map = {"a": [1, 2], b: {c: "d"}}`)
+	require.NoError(t, err)
+	clone = ast.Clone()
+	require.Equal(t, ast, clone)
 }
 
 func TestParse(t *testing.T) {
@@ -82,12 +87,70 @@ EOF
 			`,
 			expected: hcl(&Entry{
 				Attribute: &Attribute{
-					Key:      "attr",
-					Value:    hbool(true),
-					Comments: []string{"A comment"},
+					Key:           "attr",
+					Value:         hbool(true),
+					Comments:      []string{"A comment"},
+					CommentStyles: []CommentStyle{{Marker: "//"}},
 				},
 			}),
 		},
+		{name: "CommentStyles",
+			hcl: `
+				# Hash
+				// Slash
+				/* Block */
+				a = 1 /* Trailing
+				   block */
+				/*
+				  Multi-line
+				  block
+				*/
+				b = 2
+			`,
+			expected: hcl(
+				&Entry{Attribute: &Attribute{
+					Key:                   "a",
+					Value:                 num(1),
+					Comments:              []string{"Hash", "Slash", "Block"},
+					CommentStyles:         []CommentStyle{{Marker: "#"}, {Marker: "//"}, {Marker: "/*"}},
+					TrailingComments:      []string{"Trailing\n\t\t\t\t   block"},
+					TrailingCommentStyles: []CommentStyle{{Marker: "/*", Trailing: true}},
+				}},
+				&Entry{Attribute: &Attribute{
+					Key:           "b",
+					Value:         num(2),
+					Comments:      []string{"Multi-line\n\t\t\t\t  block"},
+					CommentStyles: []CommentStyle{{Marker: "/*"}},
+				}},
+			),
+		},
+		{name: "AttributeTrailingComments",
+			hcl: `
+				port = 8080 # external
+				// Leading
+				host = "localhost" // local /* only */
+				block {
+					a = 1
+				}
+			`,
+			expected: hcl(
+				&Entry{Attribute: &Attribute{
+					Key:                   "port",
+					Value:                 num(8080),
+					TrailingComments:      []string{"external"},
+					TrailingCommentStyles: []CommentStyle{{Marker: "#", Trailing: true}},
+				}},
+				&Entry{Attribute: &Attribute{
+					Key:                   "host",
+					Value:                 str("localhost"),
+					Comments:              []string{"Leading"},
+					CommentStyles:         []CommentStyle{{Marker: "//"}},
+					TrailingComments:      []string{"local /* only */"},
+					TrailingCommentStyles: []CommentStyle{{Marker: "//", Trailing: true}},
+				}},
+				block("block", nil, attr("a", num(1))),
+			),
+		},
 		{name: "Attributes",
 			hcl: `
 				true_bool = true
@@ -141,6 +204,18 @@ EOF
 		{name: "EmptyList",
 			hcl:      `a = []`,
 			expected: hcl(attr("a", list()))},
+		{name: "StringEscapes",
+			hcl: `str = "a\nb\t\u00e9\U0001F600 $${var}"`,
+			expected: hcl(attr("str", &Value{
+				Str:     strp("a\nb\t\u00e9\U0001F600 ${var}"),
+				escapes: &escapes{str: "a\nb\t\u00e9\U0001F600 ${var}", offsets: []int{11}},
+			}))},
+		{name: "InvalidUTF8String",
+			hcl:  `str = "\xff"`,
+			fail: true},
+		{name: "RawString",
+			hcl:      "re = `^C:\\Windows\\\\d+\"$${x}\n`",
+			expected: hcl(attr("re", &Value{Str: strp("^C:\\Windows\\\\d+\"$${x}\n"), Raw: true}))},
 		{name: "TrailingComments",
 			hcl: `
 					a = true
@@ -163,6 +238,51 @@ EOF
 	}
 }
 
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected *Value
+	}{
+		{`"string"`, str("string")},
+		{`1.234`, num(1.234)},
+		{`true`, hbool(true)},
+		{`[1, 2, 3]`, list(num(1), num(2), num(3))},
+		{`{"a": 1, "b": "str"}`, hmap(hkv("a", num(1)), hkv("b", str("str")))},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			value, err := ParseValue(test.src)
+			require.NoError(t, err)
+			normaliseValue(value)
+			require.Equal(t,
+				repr.String(test.expected, repr.Indent("  ")),
+				repr.String(value, repr.Indent("  ")))
+			require.Equal(t, test.src, FormatValue(value))
+		})
+	}
+	_, err := ParseValue(`a = 1`)
+	require.Error(t, err)
+}
+
+func TestParseValueNormalisesSource(t *testing.T) {
+	value, err := ParseValue("\ufeff<<EOF\r\nhello\r\nEOF\r\n")
+	require.NoError(t, err)
+	require.Equal(t, "hello", value.GetHeredoc())
+	value, err = ParseValue("[\r\n  1,\r\n  2,\r\n]")
+	require.NoError(t, err)
+	require.Equal(t, "[1, 2]", FormatValue(value))
+}
+
+func TestParseObjectSyntax(t *testing.T) {
+	value, err := ParseValue("{\n  a = 1\n  b = \"str\"\n}")
+	require.NoError(t, err)
+	normaliseValue(value)
+	require.Equal(t,
+		repr.String(hmap(hkv("a", num(1)), hkv("b", str("str"))), repr.Indent("  ")),
+		repr.String(value, repr.Indent("  ")))
+	require.Equal(t, `{"a": 1, "b": "str"}`, FormatValue(value))
+}
+
 func heredoc(delim, s string) *Value {
 	return &Value{HeredocDelimiter: delim, Heredoc: &s}
 }
@@ -173,6 +293,7 @@ func hbool(b bool) *Value {
 
 func normaliseAST(hcl *AST) *AST {
 	hcl.Pos = lexer.Position{}
+	hcl.BlankLines = false
 	normaliseEntries(hcl.Entries)
 	return hcl
 }
@@ -181,6 +302,8 @@ func normaliseEntries(entries []*Entry) {
 	for _, entry := range entries {
 		entry.Parent = nil
 		entry.Pos = lexer.Position{}
+		entry.EndPos = lexer.Position{}
+		entry.BlankLineBefore = false
 		if entry.Block != nil {
 			entry.Block.Pos = lexer.Position{}
 			entry.Block.Parent = nil
@@ -226,6 +349,9 @@ func hcl(entries ...*Entry) *AST {
 
 func trailingComments(ast *AST, comments ...string) *AST {
 	ast.TrailingComments = comments
+	for range comments {
+		ast.TrailingCommentStyles = append(ast.TrailingCommentStyles, CommentStyle{Marker: "//"})
+	}
 	return ast
 }
 
@@ -252,3 +378,22 @@ func num(n float64) *Value {
 	b, _, _ := big.ParseFloat(s, 10, 64, 0)
 	return &Value{Number: b}
 }
+
+func TestParseInto(t *testing.T) {
+	ast := &AST{}
+	err := ParseInto(ast, []byte(complexHCLExample))
+	require.NoError(t, err)
+	err = ParseInto(ast, []byte("a = [1, 2]\nb {\n  c = {d: 1}\n}\n"))
+	require.NoError(t, err)
+	expected, err := ParseString("a = [1, 2]\nb {\n  c = {d: 1}\n}\n")
+	require.NoError(t, err)
+	require.Equal(t, expected, ast)
+}
+
+func TestReset(t *testing.T) {
+	ast, err := ParseString(complexHCLExample)
+	require.NoError(t, err)
+	ast.Reset()
+	require.Empty(t, ast.Entries)
+	require.Equal(t, lexer.Position{}, ast.Pos)
+}
@@ -0,0 +1,81 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+var pathType = reflect.TypeOf(Path{})
+
+// Path is a filesystem path that is normalised when unmarshalled.
+//
+// Forward slashes are converted to the platform separator, a leading "~" is
+// expanded to the user's home directory, and relative paths are resolved
+// against the directory of the configuration file. The base directory is
+// taken from the decode context if set with ContextWithBaseDir, then from the
+// BaseDir option if provided, otherwise from the filename in the position of
+// the value, which is set when parsing from an *os.File.
+type Path struct {
+	// Path is the normalised path.
+	Path string
+	// Raw is the path as it appeared in the configuration.
+	Raw string
+	// BaseDir is the directory a relative path was resolved against, or empty if
+	// the path was absolute or no base directory was known.
+	BaseDir string
+}
+
+func (p Path) String() string { return p.Path }
+
+// MarshalText marshals the Path as it originally appeared, so that relative
+// paths remain relative when round-tripped.
+func (p Path) MarshalText() ([]byte, error) {
+	if p.Raw != "" {
+		return []byte(p.Raw), nil
+	}
+	return []byte(p.Path), nil
+}
+
+// BaseDir sets the directory that relative Path values are resolved against.
+func BaseDir(dir string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.baseDir = dir
+	}
+}
+
+// NewPath normalises raw, resolving it against baseDir if it is relative.
+func NewPath(raw, baseDir string) (Path, error) {
+	p := filepath.FromSlash(raw)
+	if p == "~" || strings.HasPrefix(p, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Path{}, err
+		}
+		p = home + p[1:]
+	}
+	out := Path{Raw: raw}
+	if !filepath.IsAbs(p) && baseDir != "" {
+		out.BaseDir = filepath.Clean(filepath.FromSlash(baseDir))
+		p = filepath.Join(out.BaseDir, p)
+	}
+	out.Path = filepath.Clean(p)
+	return out, nil
+}
+
+// pathBaseDir returns the directory relative paths in v should be resolved against.
+func pathBaseDir(v *Value, opt *marshalOptions) string {
+	if opt.ctx != nil {
+		if dir, ok := BaseDirFromContext(opt.ctx); ok {
+			return dir
+		}
+	}
+	if opt.baseDir != "" {
+		return opt.baseDir
+	}
+	if v.Pos.Filename != "" {
+		return filepath.Dir(v.Pos.Filename)
+	}
+	return ""
+}
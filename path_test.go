@@ -0,0 +1,95 @@
+package hcl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	base := filepath.FromSlash("/etc/app")
+	tests := []struct {
+		name     string
+		raw      string
+		baseDir  string
+		expected Path
+	}{
+		{name: "Relative",
+			raw:      "data/db.sqlite",
+			baseDir:  base,
+			expected: Path{Path: filepath.FromSlash("/etc/app/data/db.sqlite"), Raw: "data/db.sqlite", BaseDir: base}},
+		{name: "RelativeWithoutBase",
+			raw:      "./data//db.sqlite",
+			expected: Path{Path: filepath.FromSlash("data/db.sqlite"), Raw: "./data//db.sqlite"}},
+		{name: "ParentDir",
+			raw:      "../shared",
+			baseDir:  base,
+			expected: Path{Path: filepath.FromSlash("/etc/shared"), Raw: "../shared", BaseDir: base}},
+		{name: "Home",
+			raw:      "~/.app",
+			baseDir:  base,
+			expected: Path{Path: filepath.Join(home, ".app"), Raw: "~/.app"}},
+		{name: "TildeInName",
+			raw:      "~app",
+			baseDir:  base,
+			expected: Path{Path: filepath.Join(base, "~app"), Raw: "~app", BaseDir: base}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := NewPath(test.raw, test.baseDir)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestUnmarshalPath(t *testing.T) {
+	base := filepath.FromSlash("/etc/app")
+	runTests(t, []test{
+		{name: "Attribute",
+			hcl: `
+				path = "data"
+				paths = ["a", "/b"]
+			`,
+			options: []MarshalOption{BaseDir(base)},
+			dest: struct {
+				Path  Path   `hcl:"path"`
+				Paths []Path `hcl:"paths"`
+			}{
+				Path: Path{Path: filepath.Join(base, "data"), Raw: "data", BaseDir: base},
+				Paths: []Path{
+					{Path: filepath.Join(base, "a"), Raw: "a", BaseDir: base},
+					{Path: filepath.FromSlash("/b"), Raw: "/b"},
+				},
+			}},
+		{name: "Default",
+			hcl:     ``,
+			options: []MarshalOption{BaseDir(base)},
+			dest: struct {
+				Path Path `hcl:"path" default:"data"`
+			}{
+				Path: Path{Path: filepath.Join(base, "data"), Raw: "data", BaseDir: base},
+			}},
+		{name: "NotAString",
+			hcl: `path = 1`,
+			dest: struct {
+				Path Path `hcl:"path"`
+			}{},
+			fail: `1:8: path: expected a path but got 1`},
+	})
+}
+
+func TestMarshalPath(t *testing.T) {
+	type config struct {
+		Path Path `hcl:"path"`
+	}
+	p, err := NewPath("data", filepath.FromSlash("/etc/app"))
+	require.NoError(t, err)
+	data, err := Marshal(&config{Path: p})
+	require.NoError(t, err)
+	require.Equal(t, "path = \"data\"\n", string(data))
+}
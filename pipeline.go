@@ -0,0 +1,219 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Transform rewrites a document, eg. to expand includes or apply defaults.
+//
+// It may modify ast in place, and returns the transformed document.
+type Transform interface {
+	Transform(ast *AST) (*AST, error)
+}
+
+// TransformFunc is a function implementing Transform.
+type TransformFunc func(ast *AST) (*AST, error)
+
+// Transform calls f(ast).
+func (f TransformFunc) Transform(ast *AST) (*AST, error) { return f(ast) }
+
+// ProfileTransform merges the selected profile overlay over the rest of a
+// document, as WithProfile does.
+func ProfileTransform(name string) Transform {
+	return entriesTransform(func(entries []*Entry) ([]*Entry, error) {
+		return applyProfile(entries, name)
+	})
+}
+
+// TemplatesTransform expands template blocks, as WithTemplates does.
+func TemplatesTransform(define, extends string) Transform {
+	return entriesTransform(func(entries []*Entry) ([]*Entry, error) {
+		return expandTemplates(entries, define, extends)
+	})
+}
+
+// DefaultsTransform applies defaults blocks, as WithDefaultsBlock does.
+func DefaultsTransform(name string) Transform {
+	return entriesTransform(func(entries []*Entry) ([]*Entry, error) {
+		return applyDefaultsBlocks(entries, name)
+	})
+}
+
+// ValueSourcesTransform resolves "${<name>:<ref>}" references in attribute
+// values with the source registered under name in sources, as
+// WithValueSource does, eg. to expand environment variables:
+//
+//	hcl.ValueSourcesTransform(map[string]hcl.ValueSource{
+//	  "env": hcl.ValueSourceFunc(func(ref string) (string, error) { return os.Getenv(ref), nil }),
+//	})
+func ValueSourcesTransform(sources map[string]ValueSource) Transform {
+	opt := &marshalOptions{valueSources: sources}
+	return TransformFunc(func(ast *AST) (*AST, error) {
+		err := Visit(ast, func(node Node, next func() error) error {
+			if attr, ok := node.(*Attribute); ok {
+				value, err := resolveValueSources(attr.Value, opt)
+				if err != nil {
+					return err
+				}
+				attr.Value = value
+				return nil
+			}
+			return next()
+		})
+		return ast, err
+	})
+}
+
+// PolicyTransform checks a document against policies, failing with
+// Violations if there are any, and otherwise leaving it unchanged.
+func PolicyTransform(policies ...Policy) Transform {
+	return TransformFunc(func(ast *AST) (*AST, error) {
+		violations, err := CheckPolicies(ast, policies...)
+		if err != nil {
+			return nil, err
+		}
+		if len(violations) > 0 {
+			return nil, Violations(violations)
+		}
+		return ast, nil
+	})
+}
+
+// entriesTransform is a Transform rewriting the top-level entries of a
+// document.
+func entriesTransform(fn func(entries []*Entry) ([]*Entry, error)) Transform {
+	return TransformFunc(func(ast *AST) (*AST, error) {
+		entries, err := fn(ast.Entries)
+		if err != nil {
+			return nil, err
+		}
+		ast.Entries = entries
+		return ast, nil
+	})
+}
+
+// Violations is the error returned by PolicyTransform.
+type Violations []Violation
+
+func (v Violations) Error() string {
+	lines := make([]string, len(v))
+	for i, violation := range v {
+		lines[i] = violation.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// StageError is an error returned by a stage of a Pipeline.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (s *StageError) Error() string { return s.Stage + ": " + s.Err.Error() }
+
+// Unwrap returns the error returned by the stage.
+func (s *StageError) Unwrap() error { return s.Err }
+
+// StageEvent describes a stage of a Pipeline that has run.
+type StageEvent struct {
+	Stage    string
+	Duration time.Duration
+	// Number of top-level entries before and after the stage.
+	EntriesBefore, EntriesAfter int
+	Err                         error
+}
+
+func (e StageEvent) String() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s failed after %s: %s", e.Stage, e.Duration, e.Err)
+	}
+	return fmt.Sprintf("%s: %d -> %d entries in %s", e.Stage, e.EntriesBefore, e.EntriesAfter, e.Duration)
+}
+
+// PipelineOption configures a Pipeline.
+type PipelineOption func(p *Pipeline)
+
+// PipelineTrace calls fn after each stage of a Pipeline runs.
+func PipelineTrace(fn func(event StageEvent)) PipelineOption {
+	return func(p *Pipeline) {
+		p.trace = fn
+	}
+}
+
+// A Pipeline applies named transforms to a document, in a declared order, eg.
+//
+//	pipeline := hcl.NewPipeline().
+//	  Then("includes", includes).
+//	  Then("profiles", hcl.ProfileTransform("prod")).
+//	  Then("defaults", hcl.DefaultsTransform("defaults")).
+//	  Then("env", hcl.ValueSourcesTransform(sources)).
+//	  Then("validation", hcl.PolicyTransform(policies...))
+//
+// Errors from a stage are returned as a *StageError naming it.
+type Pipeline struct {
+	stages []pipelineStage
+	trace  func(event StageEvent)
+}
+
+type pipelineStage struct {
+	name      string
+	transform Transform
+}
+
+// NewPipeline creates an empty Pipeline.
+func NewPipeline(options ...PipelineOption) *Pipeline {
+	p := &Pipeline{}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Then appends a stage named name, applying transform, returning the
+// Pipeline.
+func (p *Pipeline) Then(name string, transform Transform) *Pipeline {
+	p.stages = append(p.stages, pipelineStage{name: name, transform: transform})
+	return p
+}
+
+// Stages returns the names of the stages, in order.
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, stage := range p.stages {
+		names[i] = stage.name
+	}
+	return names
+}
+
+// Run applies each stage in order to a copy of ast, stopping at the first
+// error. ast is not modified.
+func (p *Pipeline) Run(ast *AST) (*AST, error) {
+	ast = ast.Clone()
+	for _, stage := range p.stages {
+		event := StageEvent{Stage: stage.name, EntriesBefore: len(ast.Entries)}
+		start := time.Now()
+		out, err := stage.transform.Transform(ast)
+		if err == nil && out == nil {
+			err = fmt.Errorf("no document returned")
+		}
+		if err == nil {
+			err = AddParentRefs(out)
+		}
+		event.Duration = time.Since(start)
+		if err != nil {
+			event.Err = err
+		} else {
+			event.EntriesAfter = len(out.Entries)
+		}
+		if p.trace != nil {
+			p.trace(event)
+		}
+		if err != nil {
+			return nil, &StageError{Stage: stage.name, Err: err}
+		}
+		ast = out
+	}
+	return ast, nil
+}
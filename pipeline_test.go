@@ -0,0 +1,69 @@
+package hcl
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	ast, err := ParseString(`
+defaults "service" {
+  region = "${env:REGION}"
+}
+
+service "api" {
+  debug = true
+}
+
+profile "prod" {
+  service "api" {
+    debug = false
+  }
+}
+`)
+	require.NoError(t, err)
+	env := map[string]string{"REGION": "eu-west-1"}
+	events := []string{}
+	pipeline := NewPipeline(PipelineTrace(func(event StageEvent) {
+		events = append(events, fmt.Sprintf("%s %d -> %d", event.Stage, event.EntriesBefore, event.EntriesAfter))
+	})).
+		Then("profiles", ProfileTransform("prod")).
+		Then("defaults", DefaultsTransform("defaults")).
+		Then("env", ValueSourcesTransform(map[string]ValueSource{
+			"env": ValueSourceFunc(func(ref string) (string, error) { return env[ref], nil }),
+		})).
+		Then("validation", PolicyTransform(Deny("**.debug", Equals(true), "debug must not be enabled")))
+	require.Equal(t, []string{"profiles", "defaults", "env", "validation"}, pipeline.Stages())
+
+	out, err := pipeline.Run(ast)
+	require.NoError(t, err)
+	data, err := MarshalAST(out)
+	require.NoError(t, err)
+	require.Equal(t, `service "api" {
+  debug = false
+  region = "eu-west-1"
+}
+`, string(data))
+	require.Equal(t, []string{"profiles 3 -> 2", "defaults 2 -> 1", "env 1 -> 1", "validation 1 -> 1"}, events)
+	// The input is unchanged.
+	require.Len(t, ast.Entries, 3)
+
+	ast.Entries = ast.Entries[:2]
+	_, err = pipeline.Run(ast)
+	require.EqualError(t, err, "profiles: unknown profile \"prod\"")
+
+	noProfile := NewPipeline().
+		Then("defaults", DefaultsTransform("defaults")).
+		Then("validation", PolicyTransform(Deny("**.debug", Equals(true), "debug must not be enabled")))
+	_, err = noProfile.Run(ast)
+	require.EqualError(t, err, "validation: 7:11: debug must not be enabled")
+	stageErr := &StageError{}
+	require.True(t, errors.As(err, &stageErr))
+	require.Equal(t, "validation", stageErr.Stage)
+	violations := Violations{}
+	require.True(t, errors.As(err, &violations))
+	require.Len(t, violations, 1)
+}
@@ -0,0 +1,159 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Violation of a Policy.
+//
+// Violation implements participle.Error, so it can be rendered with
+// DiagnosticWriter.WriteError.
+type Violation struct {
+	Pos lexer.Position
+	Msg string
+}
+
+func (v Violation) Error() string {
+	if v.Pos.Line == 0 {
+		return v.Msg
+	}
+	return fmt.Sprintf("%s: %s", v.Pos, v.Msg)
+}
+
+// Message returns the violation message without position information.
+func (v Violation) Message() string { return v.Msg }
+
+// Token returns a token positioned at the node in violation.
+func (v Violation) Token() lexer.Token { return lexer.Token{Pos: v.Pos} }
+
+// A Policy checks a document for violations of rules, such as "debug must not
+// be enabled in production".
+type Policy interface {
+	Check(ast *AST) ([]Violation, error)
+}
+
+// PolicyFunc is a function implementing Policy.
+type PolicyFunc func(ast *AST) ([]Violation, error)
+
+// Check calls f.
+func (f PolicyFunc) Check(ast *AST) ([]Violation, error) { return f(ast) }
+
+// CheckPolicies checks ast against policies, returning all violations ordered
+// by position.
+func CheckPolicies(ast *AST, policies ...Policy) ([]Violation, error) {
+	violations := []Violation{}
+	for _, policy := range policies {
+		found, err := policy.Check(ast)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, found...)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i].Pos, violations[j].Pos
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return violations, nil
+}
+
+// Deny returns a Policy reporting a violation with message for each value
+// matching the Query path for which match returns true, eg.
+//
+//	hcl.Deny("**.debug", hcl.Equals(true), "debug must not be enabled in production")
+//
+// If match is nil, every block or value matching path is a violation.
+func Deny(path string, match func(v *Value) bool, message string) Policy {
+	return PolicyFunc(func(ast *AST) ([]Violation, error) {
+		matches, err := Query(ast, path)
+		if err != nil {
+			return nil, err
+		}
+		violations := []Violation{}
+		for _, node := range matches {
+			if value, ok := node.(*Value); match != nil && (!ok || !match(value)) {
+				continue
+			}
+			violations = append(violations, Violation{Pos: nodePos(node), Msg: message})
+		}
+		return violations, nil
+	})
+}
+
+// Equals returns a function matching values equal to v, after marshalling it to HCL.
+func Equals(v interface{}) func(value *Value) bool {
+	expected, err := valueToValue(reflect.ValueOf(v), newMarshalOptions())
+	if err != nil {
+		panic(fmt.Sprintf("can't compare with %T: %s", v, err))
+	}
+	return func(value *Value) bool {
+		return ASTEqual(expected, value, IgnorePositions(), IgnoreComments())
+	}
+}
+
+// PolicyInput converts ast to nested maps and slices, as used for JSON, for
+// evaluation by an external policy engine such as OPA.
+//
+// Attributes are keyed by name. Blocks are collected into a list under their
+// name, with their labels in a "__labels__" key, and the Query path locating
+// them in a "__path__" key. A policy can report a violation at a block by
+// returning its path, to be resolved with ViolationAt.
+func PolicyInput(ast *AST) (map[string]interface{}, error) {
+	return policyBody("", ast.Entries)
+}
+
+func policyBody(parent string, entries []*Entry) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	counts := map[string]int{}
+	for _, entry := range entries {
+		if entry.Attribute != nil {
+			value, err := valueToInterface(entry.Attribute.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[entry.Attribute.Key] = value
+			continue
+		}
+		block := entry.Block
+		path := fmt.Sprintf("%s%s[%d]", parent, block.Name, counts[block.Name])
+		counts[block.Name]++
+		body, err := policyBody(path+".", block.Body)
+		if err != nil {
+			return nil, err
+		}
+		labels := make([]interface{}, len(block.Labels))
+		for i, label := range block.Labels {
+			labels[i] = label
+		}
+		body["__labels__"] = labels
+		body["__path__"] = path
+		blocks, _ := out[block.Name].([]interface{})
+		out[block.Name] = append(blocks, body)
+	}
+	return out, nil
+}
+
+// ViolationAt returns a Violation with message, positioned at the first node
+// in ast matching the Query path, or without a position if there is none.
+func ViolationAt(ast *AST, path, message string) Violation {
+	violation := Violation{Msg: message}
+	if matches, err := Query(ast, path); err == nil && len(matches) > 0 {
+		violation.Pos = nodePos(matches[0])
+	}
+	return violation
+}
+
+// nodePos returns the position of a node returned by Query.
+func nodePos(node Node) lexer.Position {
+	switch node := node.(type) {
+	case *Block:
+		return node.Pos
+	case *Value:
+		return node.Pos
+	default:
+		return lexer.Position{}
+	}
+}
@@ -0,0 +1,80 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const policyConfig = `
+env = "prod"
+debug = false
+
+service "api" {
+  debug = true
+  replicas = 1
+}
+
+service "web" {
+  replicas = 3
+}
+`
+
+func TestCheckPolicies(t *testing.T) {
+	ast, err := ParseString(policyConfig)
+	require.NoError(t, err)
+	minReplicas := PolicyFunc(func(ast *AST) ([]Violation, error) {
+		violations := []Violation{}
+		matches, err := Query(ast, "service.replicas")
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if n, _ := match.(*Value).Number.Int64(); n < 2 {
+				violations = append(violations, Violation{Pos: match.(*Value).Pos, Msg: "at least 2 replicas are required"})
+			}
+		}
+		return violations, nil
+	})
+	violations, err := CheckPolicies(ast,
+		minReplicas,
+		Deny("**.debug", Equals(true), "debug must not be enabled in production"),
+		Deny("legacy", nil, "legacy blocks are not supported"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []Violation{
+		{Pos: violations[0].Pos, Msg: "debug must not be enabled in production"},
+		{Pos: violations[1].Pos, Msg: "at least 2 replicas are required"},
+	}, violations)
+	require.Equal(t, "6:11: debug must not be enabled in production", violations[0].Error())
+	require.Equal(t, "7:14: at least 2 replicas are required", violations[1].Error())
+}
+
+func TestPolicyInput(t *testing.T) {
+	ast, err := ParseString(policyConfig)
+	require.NoError(t, err)
+	input, err := PolicyInput(ast)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"env":   "prod",
+		"debug": false,
+		"service": []interface{}{
+			map[string]interface{}{
+				"__labels__": []interface{}{"api"},
+				"__path__":   "service[0]",
+				"debug":      true,
+				"replicas":   1.0,
+			},
+			map[string]interface{}{
+				"__labels__": []interface{}{"web"},
+				"__path__":   "service[1]",
+				"replicas":   3.0,
+			},
+		},
+	}, input)
+
+	violation := ViolationAt(ast, "service[1]", "web is deprecated")
+	require.Equal(t, "10:1: web is deprecated", violation.Error())
+	violation = ViolationAt(ast, "missing", "oops")
+	require.Equal(t, "oops", violation.Error())
+}
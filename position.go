@@ -0,0 +1,90 @@
+package hcl
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// ColumnUnit is the unit in which columns are counted.
+type ColumnUnit int
+
+const (
+	// ColumnRunes counts columns in Unicode code points. This is the unit of
+	// the Column of all positions reported by this package.
+	ColumnRunes ColumnUnit = iota
+	// ColumnBytes counts columns in bytes of UTF-8.
+	ColumnBytes
+	// ColumnUTF16 counts columns in UTF-16 code units, as used by the
+	// Language Server Protocol.
+	ColumnUTF16
+)
+
+// ColumnOf returns the 1-based column of pos within source, counted in unit.
+func ColumnOf(source []byte, pos lexer.Position, unit ColumnUnit) int {
+	if unit == ColumnRunes {
+		return pos.Column
+	}
+	offset := resolveOffset(pos, source)
+	start := bytes.LastIndexByte(source[:offset], '\n') + 1
+	return columnWidth(source[start:offset], unit) + 1
+}
+
+// PositionAt returns the position within source of the 1-based line and
+// column, where column is counted in unit.
+//
+// Columns beyond the end of the line resolve to the end of the line, and
+// columns within a multi-byte character resolve to the start of it.
+func PositionAt(source []byte, line, column int, unit ColumnUnit) lexer.Position {
+	pos := lexer.Position{Line: 1, Column: 1}
+	for pos.Line < line {
+		nl := bytes.IndexByte(source[pos.Offset:], '\n')
+		if nl == -1 {
+			break
+		}
+		pos.Offset += nl + 1
+		pos.Line++
+	}
+	for width := 1; pos.Offset < len(source) && source[pos.Offset] != '\n'; pos.Column++ {
+		r, size := utf8.DecodeRune(source[pos.Offset:])
+		width += runeWidth(r, size, unit)
+		if width > column {
+			break
+		}
+		pos.Offset += size
+	}
+	return pos
+}
+
+// columnWidth returns the number of columns in unit spanned by b.
+func columnWidth(b []byte, unit ColumnUnit) int {
+	switch unit {
+	case ColumnBytes:
+		return len(b)
+	case ColumnUTF16:
+		width := 0
+		for len(b) > 0 {
+			r, size := utf8.DecodeRune(b)
+			width += runeWidth(r, size, unit)
+			b = b[size:]
+		}
+		return width
+	default:
+		return utf8.RuneCount(b)
+	}
+}
+
+func runeWidth(r rune, size int, unit ColumnUnit) int {
+	switch unit {
+	case ColumnBytes:
+		return size
+	case ColumnUTF16:
+		if r >= 0x10000 {
+			return 2
+		}
+		return 1
+	default:
+		return 1
+	}
+}
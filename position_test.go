@@ -0,0 +1,46 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiByteColumns(t *testing.T) {
+	source := []byte("// ünïcode comment\nname = \"日本語\"\nemoji = [\"😀\", true]\n")
+	ast, err := ParseBytes(source)
+	require.NoError(t, err)
+	name := ast.Entries[0].Attribute.Value
+	require.Equal(t, 2, name.Pos.Line)
+	require.Equal(t, 8, name.Pos.Column)
+	b := ast.Entries[1].Attribute.Value.List[1]
+	require.Equal(t, lexer.Position{Line: 3, Column: 15, Offset: 57}, b.Pos)
+
+	require.Equal(t, 15, ColumnOf(source, b.Pos, ColumnRunes))
+	require.Equal(t, 18, ColumnOf(source, b.Pos, ColumnBytes))
+	require.Equal(t, 16, ColumnOf(source, b.Pos, ColumnUTF16))
+}
+
+func TestPositionAt(t *testing.T) {
+	source := []byte("a = 1\nb = [\"😀\", true]\n")
+	tests := []struct {
+		name     string
+		line     int
+		column   int
+		unit     ColumnUnit
+		expected lexer.Position
+	}{
+		{"Runes", 2, 11, ColumnRunes, lexer.Position{Line: 2, Column: 11, Offset: 19}},
+		{"Bytes", 2, 14, ColumnBytes, lexer.Position{Line: 2, Column: 11, Offset: 19}},
+		{"UTF16", 2, 12, ColumnUTF16, lexer.Position{Line: 2, Column: 11, Offset: 19}},
+		{"WithinRune", 2, 8, ColumnUTF16, lexer.Position{Line: 2, Column: 7, Offset: 12}},
+		{"PastEndOfLine", 1, 20, ColumnRunes, lexer.Position{Line: 1, Column: 6, Offset: 5}},
+		{"PastEndOfFile", 5, 1, ColumnRunes, lexer.Position{Line: 3, Column: 1, Offset: 25}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, PositionAt(source, test.line, test.column, test.unit))
+		})
+	}
+}
@@ -0,0 +1,662 @@
+package hcl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle"
+)
+
+// BlankLinePolicy controls where a Printer emits blank lines between entries.
+type BlankLinePolicy int
+
+const (
+	// BlankLinesAroundBlocks separates blocks from each other and from attributes.
+	BlankLinesAroundBlocks BlankLinePolicy = iota
+	// BlankLinesNever never emits blank lines.
+	BlankLinesNever
+	// BlankLinesBetweenEntries separates every entry with a blank line.
+	BlankLinesBetweenEntries
+	// BlankLinesPreserve emits blank lines where they were in the parsed
+	// source. This is the default when printing a parsed AST.
+	BlankLinesPreserve
+)
+
+// PrinterOption configures a Printer.
+type PrinterOption func(p *Printer)
+
+// Indent sets a prefix for each line and the indentation for each level of nesting.
+//
+// The default is no prefix and two spaces of indentation.
+func Indent(prefix, indent string) PrinterOption {
+	return func(p *Printer) {
+		p.prefix = prefix
+		p.indent = indent
+	}
+}
+
+// Compact emits each top-level entry on a single line, eg. `server "a" { port = 1 }`.
+//
+// Comments are emitted as /* */ comments, and heredocs as strings.
+func Compact() PrinterOption {
+	return func(p *Printer) {
+		p.compact = true
+	}
+}
+
+// CommentPrefix sets the prefix used for line comments, either "//" (the default) or "#".
+func CommentPrefix(prefix string) PrinterOption {
+	return func(p *Printer) {
+		p.commentPrefix = prefix
+	}
+}
+
+// MaxWidth wraps lists and maps onto multiple lines if they would otherwise
+// exceed width columns.
+//
+// Maps and lists that fit within width are kept on a single line. If width is
+// <= 0 (the default), lists are never wrapped and maps are always wrapped.
+func MaxWidth(width int) PrinterOption {
+	return func(p *Printer) {
+		p.width = width
+	}
+}
+
+// WrapComments reflows line comments that would otherwise exceed width
+// columns, including their indentation and comment prefix, eg. long help text
+// in generated configuration and schemas.
+//
+// Each paragraph of a comment, separated by blank lines, containing a line
+// that is too long is rewrapped at word boundaries. Lines starting with
+// whitespace, eg. indented examples, are kept as is, as are words longer than
+// the width. Entries commented out in examples are comments too, and are
+// wrapped likewise if they are too long. If width is <= 0 (the default),
+// comments are never wrapped.
+func WrapComments(width int) PrinterOption {
+	return func(p *Printer) {
+		p.commentWidth = width
+	}
+}
+
+// QuoteStrings sets the style used to quote the strings of attribute values,
+// other than those with a style of their own, eg. from a quote:"" tag.
+//
+// Strings that can't be written in the style are double quoted.
+func QuoteStrings(style QuoteStyle) PrinterOption {
+	return func(p *Printer) {
+		p.quote = style
+	}
+}
+
+// BlankLines sets the policy for emitting blank lines between entries.
+func BlankLines(policy BlankLinePolicy) PrinterOption {
+	return func(p *Printer) {
+		p.blankLines = policy
+		p.blankLinesSet = true
+	}
+}
+
+// Printer renders an AST as HCL.
+//
+// This is the printer used by Marshal and MarshalAST, and may be used with
+// any AST regardless of how it was produced.
+type Printer struct {
+	w             *bufio.Writer
+	prefix        string
+	indent        string
+	compact       bool
+	commentPrefix string
+	width         int
+	commentWidth  int
+	blankLines    BlankLinePolicy
+	blankLinesSet bool
+	// Print entries retained by WithKeepSource as if they weren't.
+	ignoreSource bool
+	// Style of quoting strings without one of their own.
+	quote QuoteStyle
+}
+
+// NewPrinter creates a new Printer.
+func NewPrinter(options ...PrinterOption) *Printer {
+	p := &Printer{indent: "  ", commentPrefix: "//"}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// Print renders node as HCL.
+func (p *Printer) Print(node Node) ([]byte, error) {
+	w := &bytes.Buffer{}
+	err := p.Fprint(w, node)
+	return w.Bytes(), err
+}
+
+// Fprint renders node as HCL to w.
+//
+// Output is buffered, and the first error writing to w is returned.
+func (p *Printer) Fprint(w io.Writer, node Node) error {
+	if p.commentPrefix != "//" && p.commentPrefix != "#" {
+		return fmt.Errorf("invalid comment prefix %q", p.commentPrefix)
+	}
+	pp := *p
+	// Restore the byte order mark, line endings and blank lines of parsed files.
+	if ast, ok := node.(*AST); ok {
+		if ast.BlankLines && !p.blankLinesSet {
+			pp.blankLines = BlankLinesPreserve
+		}
+		if ast.CRLF {
+			w = &crlfWriter{w: w}
+		}
+		if ast.BOM {
+			if _, err := w.Write(utf8BOM); err != nil {
+				return err
+			}
+		}
+	}
+	pp.w = bufio.NewWriter(w)
+	if err := pp.node(node); err != nil {
+		return err
+	}
+	// Write errors are sticky, so Flush reports the first of them.
+	return pp.w.Flush()
+}
+
+func (p *Printer) node(node Node) error {
+	switch node := node.(type) {
+	case *AST:
+		return p.ast(p.prefix, node)
+	case *Block:
+		return p.block(p.prefix, node)
+	case *Attribute:
+		return p.attribute(p.prefix, node)
+	case *Value:
+		return p.value(p.prefix, len(p.prefix), node)
+	default:
+		return fmt.Errorf("can't marshal node of type %T", node)
+	}
+}
+
+func (p *Printer) ast(indent string, node *AST) error {
+	for _, directive := range node.Directives {
+		p.comments(indent, directive.Comments, directive.CommentStyles)
+		p.write(indent, directive.String())
+		p.trailingComments(directive.TrailingComments, directive.TrailingCommentStyles)
+		p.write("\n")
+	}
+	if len(node.Directives) > 0 && len(node.Entries) > 0 && !p.compact {
+		p.blankLine()
+	}
+	err := p.entries(indent, node.Entries)
+	if err != nil {
+		return err
+	}
+	p.comments(indent, node.TrailingComments, node.TrailingCommentStyles)
+	return nil
+}
+
+func (p *Printer) entries(indent string, entries []*Entry) error {
+	policy := p.blankLines
+	if p.compact {
+		policy = BlankLinesNever
+	}
+	prevAttr := true
+	for i, entry := range entries {
+		if entry.Block == nil && entry.Attribute == nil {
+			return participle.Errorf(entry.Pos, "entry has neither an attribute nor a block")
+		}
+		if i > 0 && blankLineBetween(policy, prevAttr, entry) {
+			p.blankLine()
+		}
+		if err := p.entry(indent, entry); err != nil {
+			return err
+		}
+		prevAttr = entry.Block == nil
+	}
+	return nil
+}
+
+// entry writes an attribute or block, or its source text if it was retained
+// by WithKeepSource and the entry is unchanged.
+func (p *Printer) entry(indent string, entry *Entry) error {
+	if entry.source != nil && !p.compact && !p.ignoreSource {
+		printed, err := p.isolated(entry)
+		if err != nil {
+			return err
+		}
+		if printed == entry.source.printed {
+			p.write(indent, entry.source.text, "\n")
+			return nil
+		}
+	}
+	if entry.Block != nil {
+		return p.block(indent, entry.Block)
+	}
+	return p.attribute(indent, entry.Attribute)
+}
+
+// isolated renders entry on its own, without indentation.
+func (p *Printer) isolated(entry *Entry) (string, error) {
+	w := &strings.Builder{}
+	pp := *p
+	pp.w = bufio.NewWriter(w)
+	pp.ignoreSource = true
+	var err error
+	if entry.Block != nil {
+		err = pp.block("", entry.Block)
+	} else {
+		err = pp.attribute("", entry.Attribute)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := pp.w.Flush(); err != nil {
+		return "", err
+	}
+	return w.String(), nil
+}
+
+// blankLineBetween returns true if policy calls for a blank line before entry.
+func blankLineBetween(policy BlankLinePolicy, prevAttr bool, entry *Entry) bool {
+	switch policy {
+	case BlankLinesNever:
+		return false
+	case BlankLinesBetweenEntries:
+		return true
+	case BlankLinesPreserve:
+		return entry.BlankLineBefore
+	default:
+		return entry.Block != nil || !prevAttr
+	}
+}
+
+func (p *Printer) attribute(indent string, attribute *Attribute) error {
+	p.leadingComments(indent, attribute.Comments, attribute.CommentStyles, attribute.DetachedComments)
+	p.write(indent, attribute.Key, " = ")
+	value := attribute.Value
+	if p.quote != "" && p.quote != QuoteDouble {
+		value = value.Clone()
+		defaultQuoteStyle(value, p.quote)
+	}
+	err := p.value(indent, len(indent)+len(attribute.Key)+3, value)
+	if err != nil {
+		return err
+	}
+	if annotation := attributeAnnotation(attribute); annotation != "" {
+		p.annotation(annotation)
+	}
+	p.trailingComments(attribute.TrailingComments, attribute.TrailingCommentStyles)
+	p.write("\n")
+	return nil
+}
+
+// value writes a value starting at column col.
+func (p *Printer) value(indent string, col int, value *Value) error {
+	if err := checkUTF8(value); err != nil {
+		return err
+	}
+	if p.compact {
+		p.write(compactValue(value))
+		return nil
+	}
+	if value.HaveList && hasBlockElements(value) {
+		return p.list(indent, value.List)
+	}
+	if p.width > 0 && (value.HaveList || value.HaveMap) {
+		if s := compactValue(value); col+utf8.RuneCountInString(s) <= p.width && !strings.Contains(s, "\n") {
+			p.write(s)
+			return nil
+		}
+		if value.HaveList {
+			return p.list(indent, value.List)
+		}
+	}
+	if value.HaveMap {
+		return p.mapEntries(indent, value.Map)
+	}
+	p.write(value.String())
+	return nil
+}
+
+// hasBlockElements returns true if the list v contains blocks.
+func hasBlockElements(v *Value) bool {
+	for _, element := range v.List {
+		if element.Block != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Printer) list(indent string, elements []*Value) error {
+	p.write("[\n")
+	for i, element := range elements {
+		// Consecutive blocks in lists are separated by newlines, as in bodies.
+		if element.Block != nil {
+			end := "\n"
+			if i+1 < len(elements) && elements[i+1].Block == nil {
+				end = ",\n"
+			}
+			if err := p.blockEnding(indent+p.indent, element.Block, end); err != nil {
+				return err
+			}
+			continue
+		}
+		p.write(indent, p.indent)
+		if err := p.value(indent+p.indent, len(indent)+len(p.indent), element); err != nil {
+			return err
+		}
+		p.write(",\n")
+	}
+	p.write(indent, "]")
+	return nil
+}
+
+func (p *Printer) mapEntries(indent string, entries []*MapEntry) error {
+	p.write("{\n")
+	for _, entry := range entries {
+		p.comments(indent+p.indent, entry.Comments, entry.CommentStyles)
+		key := entry.Key.String()
+		p.write(indent, p.indent, key, ": ")
+		if err := p.value(indent+p.indent, len(indent)+len(p.indent)+len(key)+2, entry.Value); err != nil {
+			return err
+		}
+		p.write(",\n")
+	}
+	p.write(indent, "}")
+	return nil
+}
+
+func (p *Printer) block(indent string, block *Block) error {
+	return p.blockEnding(indent, block, "\n")
+}
+
+// blockEnding writes a block followed by end, eg. ",\n" for a block in a list
+// followed by a value.
+func (p *Printer) blockEnding(indent string, block *Block, end string) error {
+	p.leadingComments(indent, block.Comments, block.CommentStyles, block.DetachedComments)
+	p.write(indent)
+	if err := p.inlineBlock(block); err != nil {
+		return err
+	}
+	if p.compact {
+		p.write(end)
+		return nil
+	}
+	if annotation := blockAnnotation(block); annotation != "" {
+		p.annotation(annotation)
+	}
+	p.write("\n")
+	err := p.entries(indent+p.indent, block.Body)
+	if err != nil {
+		return err
+	}
+	p.comments(indent+p.indent, block.TrailingComments, block.TrailingCommentStyles)
+	p.write(indent, "}", end)
+	return nil
+}
+
+// inlineBlock writes the block header, and in compact mode the entire block
+// on a single line.
+func (p *Printer) inlineBlock(block *Block) error {
+	p.write(block.Name, " ")
+	for _, label := range block.Labels {
+		if !utf8.ValidString(label) {
+			return participle.Errorf(block.Pos, "invalid UTF-8 in label %q of block %q", label, block.Name)
+		}
+		p.write(quoteString(label), " ")
+	}
+	p.write("{")
+	if !p.compact {
+		return nil
+	}
+	if annotation := blockAnnotation(block); annotation != "" {
+		p.annotation(annotation)
+	}
+	for _, entry := range block.Body {
+		p.write(" ")
+		if entry.Block != nil {
+			p.inlineComments(entry.Block.Comments)
+			if err := p.inlineBlock(entry.Block); err != nil {
+				return err
+			}
+			continue
+		}
+		attr := entry.Attribute
+		p.inlineComments(attr.Comments)
+		if err := checkUTF8(attr.Value); err != nil {
+			return err
+		}
+		p.write(attr.Key, " = ", compactValue(attr.Value))
+		if annotation := attributeAnnotation(attr); annotation != "" {
+			p.annotation(annotation)
+		}
+		p.trailingComments(attr.TrailingComments, attr.TrailingCommentStyles)
+	}
+	p.write(" }")
+	return nil
+}
+
+// attributeAnnotation returns the schema annotation for an attribute, if any.
+func attributeAnnotation(attr *Attribute) string {
+	annotations := []string{}
+	if attr.Optional {
+		annotations = append(annotations, "optional")
+	}
+	if attr.ReadOnly {
+		annotations = append(annotations, "readonly")
+	}
+	if attr.Deprecated != "" {
+		annotations = append(annotations, "deprecated: "+attr.Deprecated)
+	}
+	if attr.Pattern != "" {
+		annotations = append(annotations, "pattern: "+attr.Pattern)
+	}
+	if attr.Min != nil {
+		annotations = append(annotations, "min: "+attr.Min.String())
+	}
+	if attr.Max != nil {
+		annotations = append(annotations, "max: "+attr.Max.String())
+	}
+	if len(annotations) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(annotations, ", ") + ")"
+}
+
+// trailingComments writes comments at the end of the current line.
+func (p *Printer) trailingComments(comments []string, styles []CommentStyle) {
+	for i, comment := range comments {
+		marker := commentStyle(styles, i).Marker
+		if marker == "" {
+			marker = p.commentPrefix
+		}
+		if p.compact || marker == "/*" || strings.Contains(comment, "\n") {
+			fmt.Fprintf(p.w, " /* %s */", inlineComment(comment))
+		} else {
+			fmt.Fprintf(p.w, " %s %s", marker, comment)
+		}
+	}
+}
+
+// write writes strings to the output, avoiding the overhead of fmt.
+func (p *Printer) write(strs ...string) {
+	for _, s := range strs {
+		_, _ = p.w.WriteString(s)
+	}
+}
+
+// blankLine writes an empty line, with any trailing whitespace in the prefix removed.
+func (p *Printer) blankLine() {
+	p.write(strings.TrimRight(p.prefix, " \t"), "\n")
+}
+
+// blockAnnotation returns the schema annotation for a block, if any.
+func blockAnnotation(block *Block) string {
+	annotations := []string{}
+	if block.Repeated {
+		annotations = append(annotations, "repeated")
+		if block.MinItems != 0 {
+			annotations = append(annotations, fmt.Sprintf("min %d", block.MinItems))
+		}
+		if block.MaxItems != 0 {
+			annotations = append(annotations, fmt.Sprintf("max %d", block.MaxItems))
+		}
+	}
+	if block.Recursive {
+		path := block.RecursivePath
+		if path == "" {
+			path = "document"
+		}
+		annotations = append(annotations, "recursive: same as "+path)
+	}
+	if len(annotations) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(annotations, ", ") + ")"
+}
+
+// annotation writes a trailing comment on the current line.
+func (p *Printer) annotation(text string) {
+	if p.compact {
+		fmt.Fprintf(p.w, " /* %s */", text)
+	} else {
+		fmt.Fprintf(p.w, " %s %s", p.commentPrefix, text)
+	}
+}
+
+// comments writes comments on their own lines, using their original style if known.
+func (p *Printer) comments(indent string, comments []string, styles []CommentStyle) {
+	if p.compact {
+		for _, comment := range comments {
+			fmt.Fprintf(p.w, "%s/* %s */\n", indent, inlineComment(comment))
+		}
+		return
+	}
+	for i, comment := range comments {
+		marker := commentStyle(styles, i).Marker
+		if marker == "" {
+			marker = p.commentPrefix
+		}
+		if marker == "/*" {
+			fmt.Fprintf(p.w, "%s/* %s */\n", indent, strings.ReplaceAll(comment, "*/", "* /"))
+			continue
+		}
+		lines := strings.Split(comment, "\n")
+		if p.commentWidth > 0 {
+			lines = wrapCommentLines(lines, p.commentWidth-utf8.RuneCountInString(indent+marker+" "))
+		}
+		for _, line := range lines {
+			if line == "" {
+				fmt.Fprintf(p.w, "%s%s\n", indent, marker)
+			} else {
+				p.write(indent, marker, " ", line, "\n")
+			}
+		}
+	}
+}
+
+// wrapCommentLines rewraps each paragraph of lines containing a line longer
+// than width runes, leaving lines starting with whitespace unchanged.
+func wrapCommentLines(lines []string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	out := make([]string, 0, len(lines))
+	for start := 0; start < len(lines); {
+		end := start
+		long := false
+		for end < len(lines) && lines[end] != "" && !strings.HasPrefix(lines[end], " ") && !strings.HasPrefix(lines[end], "\t") {
+			long = long || utf8.RuneCountInString(lines[end]) > width
+			end++
+		}
+		if end == start {
+			out = append(out, lines[start])
+			start++
+			continue
+		}
+		if !long {
+			out = append(out, lines[start:end]...)
+			start = end
+			continue
+		}
+		line := ""
+		for _, word := range strings.Fields(strings.Join(lines[start:end], " ")) {
+			switch {
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width:
+				out = append(out, line)
+				line = word
+			default:
+				line += " " + word
+			}
+		}
+		out = append(out, line)
+		start = end
+	}
+	return out
+}
+
+// leadingComments writes the comments preceding an attribute or block,
+// separating the first detached of them from it with a blank line.
+func (p *Printer) leadingComments(indent string, comments []string, styles []CommentStyle, detached int) {
+	if detached <= 0 || detached > len(comments) || p.compact || p.blankLines == BlankLinesNever {
+		p.comments(indent, comments, styles)
+		return
+	}
+	split := detached
+	if split > len(styles) {
+		split = len(styles)
+	}
+	p.comments(indent, comments[:detached], styles[:split])
+	p.blankLine()
+	p.comments(indent, comments[detached:], styles[split:])
+}
+
+func (p *Printer) inlineComments(comments []string) {
+	for _, comment := range comments {
+		fmt.Fprintf(p.w, "/* %s */ ", inlineComment(comment))
+	}
+}
+
+// inlineComment makes a comment safe to embed in a single line /* */ comment.
+func inlineComment(comment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(comment, "*/", "* /"), "\n", " ")
+}
+
+// compactValue formats a value on a single line, converting heredocs to strings.
+func compactValue(v *Value) string {
+	switch {
+	case v.HeredocDelimiter != "":
+		return quoteString(v.GetHeredoc())
+
+	case v.Block != nil:
+		data, err := NewPrinter(Compact()).Print(v.Block)
+		if err != nil {
+			return v.String()
+		}
+		return strings.TrimSuffix(string(data), "\n")
+
+	case v.HaveList:
+		entries := []string{}
+		for _, e := range v.List {
+			entries = append(entries, compactValue(e))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(entries, ", "))
+
+	case v.HaveMap:
+		entries := []string{}
+		for _, e := range v.Map {
+			entries = append(entries, fmt.Sprintf("%s: %s", compactValue(e.Key), compactValue(e.Value)))
+		}
+		return fmt.Sprintf("{%s}", strings.Join(entries, ", "))
+
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,297 @@
+package hcl
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinter(t *testing.T) {
+	ast := hcl(
+		attr("short", list(num(1), num(2))),
+		attr("long", list(str("alpha"), str("bravo"), str("charlie"), str("delta"))),
+		attr("tags", hmap(hkv("env", str("prod")))),
+		block("server", []string{"a"},
+			attr("hosts", list(str("x.example.com"), str("y.example.com"))),
+		),
+	)
+	ast.Entries[0].Attribute.Comments = []string{"A comment."}
+	tests := []struct {
+		name     string
+		options  []PrinterOption
+		expected string
+	}{
+		{name: "Default",
+			expected: `// A comment.
+short = [1, 2]
+long = ["alpha", "bravo", "charlie", "delta"]
+tags = {
+  "env": "prod",
+}
+
+server "a" {
+  hosts = ["x.example.com", "y.example.com"]
+}
+`},
+		{name: "HashComments",
+			options: []PrinterOption{CommentPrefix("#"), BlankLines(BlankLinesNever)},
+			expected: `# A comment.
+short = [1, 2]
+long = ["alpha", "bravo", "charlie", "delta"]
+tags = {
+  "env": "prod",
+}
+server "a" {
+  hosts = ["x.example.com", "y.example.com"]
+}
+`},
+		{name: "BlankLinesBetweenEntries",
+			options: []PrinterOption{BlankLines(BlankLinesBetweenEntries)},
+			expected: `// A comment.
+short = [1, 2]
+
+long = ["alpha", "bravo", "charlie", "delta"]
+
+tags = {
+  "env": "prod",
+}
+
+server "a" {
+  hosts = ["x.example.com", "y.example.com"]
+}
+`},
+		{name: "MaxWidth",
+			options: []PrinterOption{MaxWidth(30), Indent("", "\t")},
+			expected: `// A comment.
+short = [1, 2]
+long = [
+	"alpha",
+	"bravo",
+	"charlie",
+	"delta",
+]
+tags = {"env": "prod"}
+
+server "a" {
+	hosts = [
+		"x.example.com",
+		"y.example.com",
+	]
+}
+`},
+		{name: "CompactIgnoresBlankLines",
+			options: []PrinterOption{BlankLines(BlankLinesBetweenEntries), Compact()},
+			expected: `/* A comment. */
+short = [1, 2]
+long = ["alpha", "bravo", "charlie", "delta"]
+tags = {"env": "prod"}
+server "a" { hosts = ["x.example.com", "y.example.com"] }
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := NewPrinter(test.options...).Print(ast)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, string(actual))
+		})
+	}
+}
+
+func TestPrinterInvalidCommentPrefix(t *testing.T) {
+	_, err := NewPrinter(CommentPrefix(";")).Print(hcl())
+	require.EqualError(t, err, `invalid comment prefix ";"`)
+}
+
+func TestPrinterCommentStyles(t *testing.T) {
+	ast := hcl(
+		attr("a", num(1)),
+		block("b", nil, attr("c", num(2))),
+	)
+	ast.Entries[0].Attribute.Comments = []string{"Hash", "Block\ncomment", "Default"}
+	ast.Entries[0].Attribute.CommentStyles = []CommentStyle{{Marker: "#"}, {Marker: "/*"}}
+	ast.Entries[1].Block.TrailingComments = []string{"End of block"}
+	ast.Entries[1].Block.TrailingCommentStyles = []CommentStyle{{Marker: "//"}}
+	actual, err := NewPrinter(CommentPrefix("#")).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, `# Hash
+/* Block
+comment */
+# Default
+a = 1
+
+b {
+  c = 2
+  // End of block
+}
+`, string(actual))
+}
+
+func TestPrinterAttributeTrailingComments(t *testing.T) {
+	ast := hcl(
+		attr("port", num(8080)),
+		attr("host", str("localhost")),
+	)
+	ast.Entries[0].Attribute.TrailingComments = []string{"external"}
+	ast.Entries[0].Attribute.TrailingCommentStyles = []CommentStyle{{Marker: "#", Trailing: true}}
+	ast.Entries[1].Attribute.TrailingComments = []string{"local"}
+	actual, err := NewPrinter().Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, "port = 8080 # external\nhost = \"localhost\" // local\n", string(actual))
+
+	actual, err = NewPrinter(Compact()).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, "port = 8080 /* external */\nhost = \"localhost\" /* local */\n", string(actual))
+}
+
+type failingWriter struct {
+	remaining int
+}
+
+func (f *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > f.remaining {
+		n := f.remaining
+		f.remaining = 0
+		return n, errors.New("disk full")
+	}
+	f.remaining -= len(b)
+	return len(b), nil
+}
+
+func TestPrinterWriteError(t *testing.T) {
+	ast := hcl()
+	for i := 0; i < 1000; i++ {
+		ast.Entries = append(ast.Entries, attr("key", str("a reasonably long value")))
+	}
+	err := NewPrinter().Fprint(&failingWriter{remaining: 100}, ast)
+	require.EqualError(t, err, "disk full")
+	err = MarshalASTToWriter(ast, &failingWriter{remaining: 100})
+	require.EqualError(t, err, "disk full")
+}
+
+func TestPrinterEmptyEntry(t *testing.T) {
+	ast := hcl(&Entry{Pos: lexer.Position{Line: 2, Column: 1}})
+	_, err := MarshalAST(ast)
+	require.EqualError(t, err, "2:1: entry has neither an attribute nor a block")
+}
+
+func TestPrinterPreservesBlankLines(t *testing.T) {
+	source := `// Header.
+a = 1
+b = 2
+
+c {
+  d = 3
+
+  e = 4
+}
+f {
+}
+g = 5
+`
+	ast, err := ParseString(source)
+	require.NoError(t, err)
+	actual, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, source, string(actual))
+
+	// Clones retain blank lines.
+	actual, err = MarshalAST(ast.Clone())
+	require.NoError(t, err)
+	require.Equal(t, source, string(actual))
+
+	// An explicit policy takes precedence.
+	actual, err = NewPrinter(BlankLines(BlankLinesNever)).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, "// Header.\na = 1\nb = 2\nc {\n  d = 3\n  e = 4\n}\nf {\n}\ng = 5\n", string(actual))
+}
+
+func TestPrinterKeepSource(t *testing.T) {
+	source := `name   =   "app"  // the app
+tags = [ "a",
+         "b" ]
+
+server "a" {
+	port = 0x50
+	limits = {cpu: 2}
+}
+`
+	ast, err := ParseString(source, WithKeepSource())
+	require.NoError(t, err)
+	actual, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, source, string(actual))
+
+	// Only modified entries, and blocks containing them, are reformatted.
+	ast.Entries[2].Block.Body[0].Attribute.Value = &Value{Number: big.NewFloat(8080)}
+	actual, err = MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `name   =   "app"  // the app
+tags = [ "a",
+         "b" ]
+
+server "a" {
+  port = 8080
+  limits = {cpu: 2}
+}
+`, string(actual))
+
+	// Without WithKeepSource, entries are reformatted.
+	ast, err = ParseString(source)
+	require.NoError(t, err)
+	actual, err = MarshalAST(ast)
+	require.NoError(t, err)
+	require.NotEqual(t, source, string(actual))
+}
+
+func TestPrinterRawStrings(t *testing.T) {
+	source := "path = `C:\\Program Files\\`\nre = `^\"[a-z]+\"$`\n"
+	ast, err := ParseString(source)
+	require.NoError(t, err)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, source, string(data))
+
+	// Strings containing backticks can't be raw.
+	value := &Value{Str: strp("a`b"), Raw: true}
+	require.Equal(t, "\"a`b\"", value.String())
+}
+
+func TestPrinterWrapComments(t *testing.T) {
+	type server struct {
+		Port int `hcl:"port" help:"Port to listen on. Ports below 1024 require the service to run as root or with CAP_NET_BIND_SERVICE."`
+	}
+	type config struct {
+		Name   string `hcl:"name" help:"Name of the service.\n\nExample:\n  name = \"api\""`
+		Server server `hcl:"server,block" help:"Server configuration."`
+	}
+	value := &config{Name: "api", Server: server{Port: 8080}}
+	data, err := Marshal(value, WithPrinterOptions(WrapComments(40)))
+	require.NoError(t, err)
+	require.Equal(t, `// Name of the service.
+//
+// Example:
+//   name = "api"
+name = "api"
+
+// Server configuration.
+server {
+  // Port to listen on. Ports below 1024
+  // require the service to run as root
+  // or with CAP_NET_BIND_SERVICE.
+  port = 8080
+}
+`, string(data))
+
+	data, err = Marshal(value)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "  // Port to listen on. Ports below 1024 require the service to run as root or with CAP_NET_BIND_SERVICE.\n")
+
+	ast, err := ParseString("# An unbreakable_word_longer_than_the_width and more.\na = 1\n")
+	require.NoError(t, err)
+	data, err = NewPrinter(WrapComments(20)).Print(ast)
+	require.NoError(t, err)
+	require.Equal(t, "# An\n# unbreakable_word_longer_than_the_width\n# and more.\na = 1\n", string(data))
+}
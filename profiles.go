@@ -0,0 +1,73 @@
+package hcl
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/alecthomas/participle"
+)
+
+// profileBlock is the name of the blocks declaring profiles.
+const profileBlock = "profile"
+
+// WithProfile selects the profile overlay merged over the rest of a document
+// before decoding.
+//
+// A document declares profiles with "profile" blocks, each labelled with the
+// profile's name. The body of the selected profile is merged over the other
+// entries of the document as with Merge, and the profile blocks themselves
+// are removed. Selecting a profile the document doesn't declare is an error.
+// WithProfile("") removes the profile blocks without merging any.
+//
+// eg. with WithProfile("prod"):
+//
+//	log_level = "debug"
+//	server {
+//	  replicas = 1
+//	}
+//
+//	profile "prod" {
+//	  log_level = "info"
+//	  server {
+//	    replicas = 3
+//	  }
+//	}
+func WithProfile(name string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.profile = &name
+	}
+}
+
+// applyProfile merges the body of the profile named name over the other
+// entries, removing the profile blocks.
+func applyProfile(entries []*Entry, name string) ([]*Entry, error) {
+	profiles := map[string]*Block{}
+	out := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		block := entry.Block
+		if block == nil || block.Name != profileBlock {
+			out = append(out, entry)
+			continue
+		}
+		if len(block.Labels) != 1 {
+			return nil, participle.Errorf(block.Pos, "profile block expects a label for its name")
+		}
+		if _, ok := profiles[block.Labels[0]]; ok {
+			return nil, participle.Errorf(block.Pos, "duplicate profile %q", block.Labels[0])
+		}
+		profiles[block.Labels[0]] = block
+	}
+	if name == "" {
+		return out, nil
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q%s", name, didYouMean(name, names))
+	}
+	return (&merger{}).merge(out, profile.Body, "")
+}
@@ -0,0 +1,62 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfiles(t *testing.T) {
+	type server struct {
+		Replicas int `hcl:"replicas"`
+		Port     int `hcl:"port,optional"`
+	}
+	type config struct {
+		LogLevel string  `hcl:"log_level"`
+		Server   *server `hcl:"server,block"`
+	}
+	data := []byte(`
+log_level = "debug"
+server {
+  replicas = 1
+  port = 8080
+}
+
+profile "prod" {
+  log_level = "info"
+  server {
+    replicas = 3
+  }
+}
+
+profile "staging" {
+  server {
+    replicas = 2
+  }
+}
+`)
+	var out config
+	err := Unmarshal(data, &out, WithProfile("prod"))
+	require.NoError(t, err)
+	require.Equal(t, config{LogLevel: "info", Server: &server{Replicas: 3, Port: 8080}}, out)
+
+	out = config{}
+	err = Unmarshal(data, &out, WithProfile(""))
+	require.NoError(t, err)
+	require.Equal(t, config{LogLevel: "debug", Server: &server{Replicas: 1, Port: 8080}}, out)
+
+	err = Unmarshal(data, &out, WithProfile("prd"))
+	require.EqualError(t, err, `unknown profile "prd"; did you mean "prod"?`)
+	require.Equal(t, CodeUnknownField, ErrorCode(err))
+
+	err = Unmarshal(data, &out)
+	require.Error(t, err)
+
+	err = Unmarshal([]byte(`
+log_level = "debug"
+server { replicas = 1 }
+profile { log_level = "info" }
+`), &out, WithProfile("prod"))
+	require.EqualError(t, err, `4:1: profile block expects a label for its name`)
+	require.Equal(t, CodeLabels, ErrorCode(err))
+}
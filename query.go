@@ -0,0 +1,247 @@
+package hcl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/alecthomas/participle/lexer/stateful"
+)
+
+// A query is a dot-separated path of segments, eg. server[*].listener[tls].port
+type query struct {
+	Segments []*querySegment `parser:"@@ ( '.' @@ )*"`
+}
+
+// A querySegment matches entries by name, or map entries by key, and then
+// filters the matches with its selectors.
+type querySegment struct {
+	Name      string           `parser:"@( Ident | '*' '*'? )"`
+	Selectors []*querySelector `parser:"( '[' @@ ']' )*"`
+}
+
+type querySelector struct {
+	Any   bool    `parser:"  @'*'"`
+	Index *int    `parser:"| @Int"`
+	Label *string `parser:"| @( String | Ident )"`
+}
+
+var queryParser = participle.MustBuild(&query{},
+	participle.Lexer(lexer.Must(stateful.New(stateful.Rules{
+		"Root": {
+			{Name: "Ident", Pattern: `[[:alpha:]_][\w-]*`},
+			{Name: "Int", Pattern: `\d+`},
+			{Name: "String", Pattern: `"(\\.|[^"])*"`},
+			{Name: "Punct", Pattern: `[][.*]`},
+			{Name: "whitespace", Pattern: `\s+`},
+		},
+	}))),
+	participle.Unquote("String"),
+)
+
+// Query returns the blocks and values in node matching path.
+//
+// node is an *AST or *Block. path is a dot-separated list of attribute keys or
+// block names, "*" to match any, or "**" to match the enclosing block and
+// every block nested within it. Each may be followed by selectors in square
+// brackets:
+//
+//	[*]       all matches (the default)
+//	[2]       the third match, or the third element of a list
+//	[label]   blocks whose next label is "label", or the value of a map key
+//
+// Attributes match as their *Value, and a path may continue into map values by
+// key. For example, "server[*].listener[tls].port" matches the port attribute
+// of listener "tls" blocks in every server block.
+func Query(node Node, path string) ([]Node, error) {
+	q := &query{}
+	if err := queryParser.ParseString(path, q); err != nil {
+		return nil, err
+	}
+	var matches []Node
+	switch node := node.(type) {
+	case *AST:
+		matches = []Node{&Block{Body: node.Entries}}
+	case *Block:
+		matches = []Node{node}
+	default:
+		return nil, fmt.Errorf("can't query %T", node)
+	}
+	for _, segment := range q.Segments {
+		matches = segment.apply(matches)
+	}
+	return matches, nil
+}
+
+func (s *querySegment) apply(nodes []Node) []Node {
+	out := []Node{}
+	for _, node := range nodes {
+		// Selectors apply to the matches within each parent separately.
+		matches := []Node{}
+		switch node := node.(type) {
+		case *Block:
+			if s.Name == "**" {
+				matches = descendants(matches, node)
+				break
+			}
+			for _, entry := range node.Body {
+				if s.Name != "*" && entry.Key() != s.Name {
+					continue
+				}
+				if entry.Block != nil {
+					matches = append(matches, entry.Block)
+				} else {
+					matches = append(matches, entry.Attribute.Value)
+				}
+			}
+		case *Value:
+			for _, entry := range node.Map {
+				if s.Name == "*" || (entry.Key.Str != nil && *entry.Key.Str == s.Name) {
+					matches = append(matches, entry.Value)
+				}
+			}
+		}
+		out = append(out, s.selectAll(matches)...)
+	}
+	return out
+}
+
+// descendants appends block and all blocks nested within it to out.
+func descendants(out []Node, block *Block) []Node {
+	out = append(out, block)
+	for _, entry := range block.Body {
+		if entry.Block != nil {
+			out = descendants(out, entry.Block)
+		}
+	}
+	return out
+}
+
+// selectAll applies each selector of the segment to matches in turn.
+func (s *querySegment) selectAll(matches []Node) []Node {
+	// Labels of matched blocks are consumed by successive selectors.
+	labels := map[*Block]int{}
+	for _, selector := range s.Selectors {
+		next := []Node{}
+		blocks := 0
+		for _, match := range matches {
+			switch match := match.(type) {
+			case *Block:
+				switch {
+				case selector.Any:
+					next = append(next, match)
+				case selector.Index != nil:
+					if blocks == *selector.Index {
+						next = append(next, match)
+					}
+					blocks++
+				case selector.Label != nil:
+					i := labels[match]
+					if i < len(match.Labels) && match.Labels[i] == *selector.Label {
+						labels[match] = i + 1
+						next = append(next, match)
+					}
+				}
+
+			case *Value:
+				next = append(next, selectValue(match, selector)...)
+			}
+		}
+		matches = next
+	}
+	return matches
+}
+
+// selectValue applies selector to a list or map value.
+func selectValue(v *Value, selector *querySelector) []Node {
+	out := []Node{}
+	switch {
+	case selector.Any && !v.HaveList && !v.HaveMap:
+		out = append(out, v)
+	case selector.Any:
+		for _, el := range v.List {
+			out = append(out, el)
+		}
+		for _, entry := range v.Map {
+			out = append(out, entry.Value)
+		}
+	case selector.Index != nil:
+		if *selector.Index < len(v.List) {
+			out = append(out, v.List[*selector.Index])
+		}
+	case selector.Label != nil:
+		for _, entry := range v.Map {
+			if entry.Key.Str != nil && *entry.Key.Str == *selector.Label {
+				out = append(out, entry.Value)
+			}
+		}
+	}
+	return out
+}
+
+// Path returns the canonical identifier of the block, which is the Query path
+// matching it alone from the root of its document, eg. server["web"].tls.
+//
+// Labels are quoted selectors. A block that can't be told apart from its
+// siblings by its name and labels is followed by its index among them, eg.
+// rule[1]. Parent references must be populated, as they are by parsing.
+func (b *Block) Path() string {
+	var siblings []*Entry
+	parent := ""
+	if entry, ok := b.Parent.(*Entry); ok {
+		switch container := entry.Parent.(type) {
+		case *AST:
+			siblings = container.Entries
+		case *Block:
+			siblings = container.Body
+			parent = container.Path() + "."
+		}
+	}
+	w := &strings.Builder{}
+	w.WriteString(parent)
+	w.WriteString(b.Name)
+	for _, label := range b.Labels {
+		w.WriteString("[" + strconv.Quote(label) + "]")
+	}
+	// Count the siblings the path so far would match, as Query would.
+	index, matches := 0, 0
+	for _, sibling := range siblings {
+		if sibling.Block == b {
+			index = matches
+		}
+		if sibling.Block != nil && sibling.Block.Name == b.Name && hasLabelPrefix(sibling.Block.Labels, b.Labels) {
+			matches++
+		}
+	}
+	if matches > 1 {
+		w.WriteString("[" + strconv.Itoa(index) + "]")
+	}
+	return w.String()
+}
+
+// Path returns the canonical identifier of the attribute, which is the Query
+// path matching its value from the root of its document, eg.
+// server["web"].tls.cert.
+func (a *Attribute) Path() string {
+	if entry, ok := a.Parent.(*Entry); ok {
+		if block, ok := entry.Parent.(*Block); ok {
+			return block.Path() + "." + a.Key
+		}
+	}
+	return a.Key
+}
+
+// hasLabelPrefix returns true if labels starts with prefix.
+func hasLabelPrefix(labels, prefix []string) bool {
+	if len(labels) < len(prefix) {
+		return false
+	}
+	for i, label := range prefix {
+		if labels[i] != label {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	ast, err := ParseString(`
+server "a" {
+  listener "tls" {
+    port = 443
+  }
+  listener "plain" {
+    port = 80
+  }
+  tags = ["x", "y"]
+  env = {
+    "region": "us-east-1",
+  }
+}
+
+server "b" {
+  listener "tls" {
+    port = 8443
+  }
+}
+`)
+	require.NoError(t, err)
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"server[*].listener[tls].port", []string{"4:12: 443", "17:12: 8443"}},
+		{"server.listener.port", []string{"4:12: 443", "7:12: 80", "17:12: 8443"}},
+		{"server[1].listener[0].port", []string{"17:12: 8443"}},
+		{`server["a"].*[plain].port`, []string{"7:12: 80"}},
+		{"server[a].tags[1]", []string{`9:16: "y"`}},
+		{"server[a].tags[*]", []string{`9:11: "x"`, `9:16: "y"`}},
+		{"server[a].env.region", []string{`11:15: "us-east-1"`}},
+		{"server[a].env[region]", []string{`11:15: "us-east-1"`}},
+		{"server[c]", []string{}},
+		{"missing.port", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			matches, err := Query(ast, test.query)
+			require.NoError(t, err)
+			actual := []string{}
+			for _, match := range matches {
+				v, ok := match.(*Value)
+				require.True(t, ok)
+				actual = append(actual, v.Pos.String()+": "+v.String())
+			}
+			require.Equal(t, test.expected, actual)
+		})
+	}
+
+	matches, err := Query(ast, "server[b]")
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	require.Equal(t, 15, matches[0].(*Block).Pos.Line)
+
+	_, err = Query(ast, "server[")
+	require.EqualError(t, err, `1:8: unexpected token "<EOF>" (expected "*" | <int> | <string> | <ident>)`)
+}
+
+func TestNodePaths(t *testing.T) {
+	ast, err := ParseString(`
+server "web" {
+  tls {
+    cert = "web.pem"
+  }
+}
+server "web" "internal" {
+  port = 81
+}
+rule { deny = true }
+rule { deny = false }
+name = "x"
+`)
+	require.NoError(t, err)
+	web := ast.Entries[0].Block
+	tls := web.Body[0].Block
+	cert := tls.Body[0].Attribute
+	internal := ast.Entries[1].Block
+	paths := []string{
+		web.Path(),
+		tls.Path(),
+		cert.Path(),
+		internal.Path(),
+		internal.Body[0].Attribute.Path(),
+		ast.Entries[2].Block.Path(),
+		ast.Entries[3].Block.Path(),
+		ast.Entries[4].Attribute.Path(),
+	}
+	require.Equal(t, []string{
+		`server["web"][0]`,
+		`server["web"][0].tls`,
+		`server["web"][0].tls.cert`,
+		`server["web"]["internal"]`,
+		`server["web"]["internal"].port`,
+		`rule[0]`,
+		`rule[1]`,
+		`name`,
+	}, paths)
+
+	// Paths are Query paths matching the node alone.
+	for _, node := range []Node{web, tls, internal, ast.Entries[3].Block} {
+		matches, err := Query(ast, node.(*Block).Path())
+		require.NoError(t, err)
+		require.Equal(t, []Node{node}, matches)
+	}
+	matches, err := Query(ast, cert.Path())
+	require.NoError(t, err)
+	require.Equal(t, []Node{cert.Value}, matches)
+}
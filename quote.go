@@ -0,0 +1,229 @@
+package hcl
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// QuoteStyle is how a string value is quoted.
+type QuoteStyle string
+
+// Styles of quoting strings.
+const (
+	// QuoteDouble quotes strings with double quotes, eg. "value". This is the
+	// default.
+	QuoteDouble QuoteStyle = "double"
+	// QuoteSingle quotes strings with single quotes, eg. 'value'.
+	QuoteSingle QuoteStyle = "single"
+	// QuoteNone leaves strings that are identifiers unquoted, eg. value.
+	// Other strings are double quoted.
+	QuoteNone QuoteStyle = "none"
+)
+
+// parseQuoteStyle parses the quote:"" tag of a field.
+func parseQuoteStyle(style string) (QuoteStyle, bool) {
+	switch QuoteStyle(style) {
+	case QuoteDouble, QuoteSingle, QuoteNone:
+		return QuoteStyle(style), true
+	}
+	return "", false
+}
+
+var (
+	bareStringRe = regexp.MustCompile(`^[[:alpha:]]\w*(-\w+)*$`)
+
+	// Between single and double quoted strings, the escaping of quotes
+	// differs. Escaped backslashes are replaced with themselves so that the
+	// backslash of an escaped backslash can't start an escaped quote.
+	doubleToSingleQuoted = strings.NewReplacer(`\\`, `\\`, `\"`, `"`, `'`, `\'`)
+	singleToDoubleQuoted = strings.NewReplacer(`\\`, `\\`, `\'`, `'`, `"`, `\"`)
+)
+
+// isBareString returns true if s can be written without quotes, as an
+// identifier that isn't parsed as another type of value.
+func isBareString(s string) bool {
+	switch s {
+	case "true", "false", "number", "string", "boolean":
+		return false
+	}
+	return bareStringRe.MatchString(s)
+}
+
+// QuoteString quotes s as a double quoted HCL string literal that parses back
+// to s, for building HCL from untrusted strings, eg.
+//
+//	fmt.Sprintf("name = %s", hcl.QuoteString(name))
+//
+// Quotes, backslashes, control characters and interpolation markers ("${") are
+// escaped, so s can't end the literal or inject other HCL. Invalid UTF-8 is
+// replaced with the Unicode replacement character, as it can't be parsed.
+func QuoteString(s string) string {
+	return quoteString(strings.ToValidUTF8(s, "\uFFFD"))
+}
+
+// EscapeIdent returns s unquoted if it is an identifier that parses back to the
+// string s, and otherwise quoted as with QuoteString, for building block
+// labels and map keys from untrusted strings, eg.
+//
+//	fmt.Sprintf("service %s {}", hcl.EscapeIdent(name))
+//
+// Attribute keys and block names can't be quoted, so must be checked with
+// IsIdent instead.
+func EscapeIdent(s string) string {
+	if isBareString(s) {
+		return s
+	}
+	return QuoteString(s)
+}
+
+// IsIdent returns true if s is a valid HCL identifier, as used for attribute
+// keys and block names.
+func IsIdent(s string) bool {
+	return bareStringRe.MatchString(s)
+}
+
+// quoteStringStyle quotes s as a HCL string literal in style, falling back to
+// double quotes if s can't be written in style.
+func quoteStringStyle(s string, style QuoteStyle) string {
+	switch style {
+	case QuoteSingle:
+		quoted := quoteString(s)
+		return "'" + doubleToSingleQuoted.Replace(quoted[1:len(quoted)-1]) + "'"
+	case QuoteNone:
+		if isBareString(s) {
+			return s
+		}
+	}
+	return quoteString(s)
+}
+
+// Unquote a single quoted string literal, via the equivalent double quoted one.
+func unquoteSingleString(token lexer.Token) (lexer.Token, error) {
+	literal := token.Value
+	token.Value = `"` + singleToDoubleQuoted.Replace(literal[1:len(literal)-1]) + `"`
+	unquoted, err := unquoteString(token)
+	if err != nil {
+		return token, participle.Errorf(token.Pos, "invalid string literal %s", literal)
+	}
+	return unquoted, nil
+}
+
+// defaultQuoteStyle sets the style used to quote v, or the elements of v if it
+// is a list or map, where they don't have one.
+func defaultQuoteStyle(v *Value, style QuoteStyle) {
+	switch {
+	case v.Str != nil:
+		if v.Quote == "" {
+			v.Quote = style
+		}
+	case v.HaveList:
+		for _, el := range v.List {
+			defaultQuoteStyle(el, style)
+		}
+	case v.HaveMap:
+		for _, entry := range v.Map {
+			defaultQuoteStyle(entry.Value, style)
+		}
+	}
+}
+
+// setQuoteStyle sets the style used to quote v, or the elements of v if it is
+// a list or map.
+func setQuoteStyle(v *Value, style QuoteStyle) {
+	switch {
+	case v.Str != nil:
+		v.Quote = style
+	case v.HaveList:
+		for _, el := range v.List {
+			setQuoteStyle(el, style)
+		}
+	case v.HaveMap:
+		for _, entry := range v.Map {
+			setQuoteStyle(entry.Value, style)
+		}
+	}
+}
+
+var escapedMarker = []byte("$${")
+
+// escapes are the offsets in str of interpolation markers ("${") that were
+// escaped as "$${" in the source, and so are not value source references.
+type escapes struct {
+	str     string
+	offsets []int
+}
+
+// escapeOffsets returns the offsets of the escaped interpolation markers in
+// the string v. They are forgotten once Str is changed, after which all of its
+// markers are treated as references.
+func (v *Value) escapeOffsets() []int {
+	if v.escapes == nil || v.Str == nil || *v.Str != v.escapes.str {
+		return nil
+	}
+	return v.escapes.offsets
+}
+
+// setEscapes records the offsets of the escaped interpolation markers in the
+// string v.
+func (v *Value) setEscapes(offsets []int) {
+	v.escapes = nil
+	if len(offsets) > 0 {
+		v.escapes = &escapes{str: *v.Str, offsets: offsets}
+	}
+}
+
+// recordEscapes records the interpolation markers in the quoted strings in
+// node that are escaped as "$${" in data, the normalised source node was
+// parsed from.
+func recordEscapes(node Node, data []byte) error {
+	return Visit(node, func(node Node, next func() error) error {
+		if v, ok := node.(*Value); ok && v.Str != nil && !v.Raw && strings.Contains(*v.Str, "${") {
+			v.setEscapes(escapedMarkers(data, v.Pos.Offset))
+		}
+		return next()
+	})
+}
+
+// escapedMarkers returns the offsets in the unquoted string of the markers
+// escaped as "$${" in the string literal starting at offset in data.
+func escapedMarkers(data []byte, offset int) []int {
+	if offset >= len(data) || (data[offset] != '"' && data[offset] != '\'') {
+		return nil
+	}
+	quote := data[offset]
+	var escapes []int
+	// Length of the unquoted string preceding start.
+	n := 0
+	start := offset + 1
+	for i := start; i < len(data) && data[i] != quote; i++ {
+		switch {
+		case data[i] == '\\':
+			i++
+		case bytes.HasPrefix(data[i:], escapedMarker):
+			n += unquotedLen(string(data[start:i]), quote)
+			escapes = append(escapes, n)
+			n += len("${")
+			i += len("$${") - 1
+			start = i + 1
+		}
+	}
+	return escapes
+}
+
+// unquotedLen returns the length of the unquoted content of a string literal
+// quoted with quote, containing no escaped markers.
+func unquotedLen(content string, quote byte) int {
+	if quote == '\'' {
+		content = singleToDoubleQuoted.Replace(content)
+	}
+	s, err := strconv.Unquote(`"` + content + `"`)
+	if err != nil {
+		return len(content)
+	}
+	return len(s)
+}
@@ -0,0 +1,121 @@
+package hcl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSingleQuotedStrings(t *testing.T) {
+	ast, err := ParseString(`
+a = 'it\'s'
+b = 'say "hi"'
+c = ['x', "y"]
+`)
+	require.NoError(t, err)
+	require.Equal(t, "it's", *ast.Entries[0].Attribute.Value.Str)
+	require.Equal(t, QuoteSingle, ast.Entries[0].Attribute.Value.Quote)
+	require.Equal(t, `say "hi"`, *ast.Entries[1].Attribute.Value.Str)
+
+	out, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `a = 'it\'s'
+b = 'say "hi"'
+c = ['x', "y"]
+`, string(out))
+
+	_, err = ParseString(`a = 'unterminated`)
+	require.Error(t, err)
+}
+
+func TestQuoteTag(t *testing.T) {
+	type config struct {
+		Region  string   `hcl:"region" quote:"none"`
+		Keyword string   `hcl:"keyword" quote:"none"`
+		Path    string   `hcl:"path" quote:"none"`
+		Name    string   `hcl:"name" quote:"single"`
+		Zones   []string `hcl:"zones" quote:"none"`
+		Other   string   `hcl:"other"`
+	}
+	in := config{
+		Region:  "us-east-1",
+		Keyword: "number",
+		Path:    "/tmp/x",
+		Name:    "it's",
+		Zones:   []string{"a", "b c"},
+		Other:   "plain",
+	}
+	out, err := Marshal(&in)
+	require.NoError(t, err)
+	require.Equal(t, `region = us-east-1
+keyword = "number"
+path = "/tmp/x"
+name = 'it\'s'
+zones = [a, "b c"]
+other = "plain"
+`, string(out))
+
+	var decoded config
+	err = Unmarshal(out, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, in, decoded)
+
+	require.Panics(t, func() {
+		var bad struct {
+			Name string `hcl:"name" quote:"backtick"`
+		}
+		_, _ = Marshal(&bad)
+	})
+}
+
+func TestQuoteStringsPrinterOption(t *testing.T) {
+	ast, err := ParseString(`
+a = "x"
+b = "two words"
+c = 'y'
+`)
+	require.NoError(t, err)
+	w := &bytes.Buffer{}
+	err = NewPrinter(QuoteStrings(QuoteNone)).Fprint(w, ast)
+	require.NoError(t, err)
+	require.Equal(t, `a = x
+b = "two words"
+c = 'y'
+`, w.String())
+}
+
+func TestQuoteString(t *testing.T) {
+	for _, s := range []string{
+		``,
+		`plain`,
+		`a "quoted" \ string`,
+		"multi\nline\ttabbed\x00",
+		"${interpolated} $${escaped} $$${both}",
+		"\"\nevil = true\n#",
+		"é\U0001F600",
+	} {
+		ast, err := ParseString("key = " + QuoteString(s))
+		require.NoError(t, err, s)
+		require.Len(t, ast.Entries, 1)
+		require.Equal(t, s, *ast.Entries[0].Attribute.Value.Str)
+	}
+	require.Equal(t, "\"a\uFFFDb\"", QuoteString("a\xffb"))
+}
+
+func TestEscapeIdent(t *testing.T) {
+	require.Equal(t, "web", EscapeIdent("web"))
+	require.Equal(t, "web-1", EscapeIdent("web-1"))
+	require.Equal(t, `"true"`, EscapeIdent("true"))
+	require.Equal(t, `"1web"`, EscapeIdent("1web"))
+	require.Equal(t, `"web\" {}\nevil {"`, EscapeIdent("web\" {}\nevil {"))
+	for _, s := range []string{"web", "web-1", "number", "my service", "a\"b"} {
+		ast, err := ParseString("service " + EscapeIdent(s) + " {}\nports = {" + EscapeIdent(s) + ": 1}")
+		require.NoError(t, err, s)
+		require.Len(t, ast.Entries, 2)
+		require.Equal(t, []string{s}, ast.Entries[0].Block.Labels)
+	}
+	require.True(t, IsIdent("server_name"))
+	require.False(t, IsIdent("server name"))
+	require.False(t, IsIdent(""))
+}
@@ -0,0 +1,186 @@
+package hcl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GenerateRandomAST returns a random, valid document for property-based
+// testing, eg. that Parse(MarshalAST(ast)) is equal to ast, ignoring positions.
+//
+// The same seed always produces the same document. complexity bounds the
+// nesting depth of blocks and values, and the number of entries in each body
+// and elements in each list or map. A complexity of zero produces a single
+// scalar attribute.
+func GenerateRandomAST(seed int64, complexity int) *AST {
+	if complexity < 0 {
+		complexity = 0
+	}
+	g := &randomGenerator{rand: rand.New(rand.NewSource(seed)), complexity: complexity}
+	ast := &AST{Entries: g.entries(complexity, 1)}
+	addParentRefs(nil, ast)
+	return ast
+}
+
+// WriteFuzzCorpus writes n random documents, generated by GenerateRandomAST
+// from consecutive seeds starting at seed, to dir for use as a seed corpus
+// when fuzzing the parser with go-fuzz.
+func WriteFuzzCorpus(dir string, seed int64, n, complexity int) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		data, err := MarshalAST(GenerateRandomAST(seed+int64(i), complexity))
+		if err != nil {
+			return err
+		}
+		name := filepath.Join(dir, fmt.Sprintf("random-%d.hcl", seed+int64(i)))
+		if err := ioutil.WriteFile(name, data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type randomGenerator struct {
+	rand       *rand.Rand
+	complexity int
+}
+
+// entries generates a body of at least min entries with unique keys.
+func (g *randomGenerator) entries(depth, min int) []*Entry {
+	n := min + g.rand.Intn(g.complexity+1)
+	out := make([]*Entry, 0, n)
+	attrs := map[string]bool{}
+	blocks := map[string]bool{}
+	for i := 0; i < n; i++ {
+		if depth > 0 && g.rand.Intn(3) == 0 {
+			name := g.ident()
+			if attrs[name] {
+				continue
+			}
+			blocks[name] = true
+			out = append(out, &Entry{Block: g.block(name, depth-1)})
+			continue
+		}
+		key := g.ident()
+		if attrs[key] || blocks[key] {
+			continue
+		}
+		attrs[key] = true
+		attr := &Attribute{Key: key, Value: g.value(depth)}
+		attr.Comments, attr.CommentStyles = g.comments()
+		out = append(out, &Entry{Attribute: attr})
+	}
+	return out
+}
+
+func (g *randomGenerator) block(name string, depth int) *Block {
+	block := &Block{Name: name, Body: g.entries(depth, 0)}
+	for i := g.rand.Intn(3); i > 0; i-- {
+		block.Labels = append(block.Labels, g.string())
+	}
+	block.Comments, block.CommentStyles = g.comments()
+	return block
+}
+
+func (g *randomGenerator) comments() ([]string, []CommentStyle) {
+	if g.rand.Intn(4) != 0 {
+		return nil, nil
+	}
+	return []string{g.words()}, []CommentStyle{{Marker: "//"}}
+}
+
+func (g *randomGenerator) value(depth int) *Value {
+	kinds := 4
+	if depth > 0 {
+		kinds = 6
+	}
+	switch g.rand.Intn(kinds) {
+	case 0:
+		b := Bool(g.rand.Intn(2) == 0)
+		return &Value{Bool: &b}
+
+	case 1:
+		n := big.NewFloat(float64(g.rand.Intn(20001)-10000) / 8)
+		return &Value{Number: n}
+
+	case 2:
+		s := g.string()
+		return &Value{Str: &s}
+
+	case 3:
+		lines := []string{}
+		for i := g.rand.Intn(3); i >= 0; i-- {
+			lines = append(lines, g.words())
+		}
+		heredoc := "\n" + strings.Join(lines, "\n")
+		return &Value{HeredocDelimiter: "EOF", Heredoc: &heredoc}
+
+	case 4:
+		v := &Value{HaveList: true}
+		for i := g.rand.Intn(g.complexity + 1); i > 0; i-- {
+			v.List = append(v.List, g.value(depth-1))
+		}
+		return v
+
+	default:
+		v := &Value{HaveMap: true}
+		keys := map[string]bool{}
+		for i := g.rand.Intn(g.complexity + 1); i > 0; i-- {
+			key := g.string()
+			if keys[key] {
+				continue
+			}
+			keys[key] = true
+			v.Map = append(v.Map, &MapEntry{Key: &Value{Str: &key}, Value: g.value(depth - 1)})
+		}
+		return v
+	}
+}
+
+const (
+	randomIdentStart = "abcdefghijklmnopqrstuvwxyz"
+	randomIdentRest  = randomIdentStart + "0123456789_"
+)
+
+// ident generates an identifier that is not a keyword.
+func (g *randomGenerator) ident() string {
+	for {
+		w := &strings.Builder{}
+		w.WriteByte(randomIdentStart[g.rand.Intn(len(randomIdentStart))])
+		for i := g.rand.Intn(8); i > 0; i-- {
+			w.WriteByte(randomIdentRest[g.rand.Intn(len(randomIdentRest))])
+		}
+		switch s := w.String(); s {
+		case "true", "false", "number", "string", "boolean":
+		default:
+			return s
+		}
+	}
+}
+
+// randomStringRunes includes runes that must be escaped when quoted.
+var randomStringRunes = []rune("abcXYZ019 _-.:/\"\\\n\t${}é日本😀")
+
+func (g *randomGenerator) string() string {
+	w := &strings.Builder{}
+	for i := g.rand.Intn(12); i > 0; i-- {
+		w.WriteRune(randomStringRunes[g.rand.Intn(len(randomStringRunes))])
+	}
+	return w.String()
+}
+
+// words generates text safe to use in comments and heredocs.
+func (g *randomGenerator) words() string {
+	words := []string{}
+	for i := g.rand.Intn(4); i >= 0; i-- {
+		words = append(words, g.ident())
+	}
+	return strings.Join(words, " ")
+}
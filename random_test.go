@@ -0,0 +1,41 @@
+package hcl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRandomASTRoundTrip(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		ast := GenerateRandomAST(seed, int(seed%5))
+		data, err := MarshalAST(ast)
+		require.NoError(t, err)
+		parsed, err := ParseBytes(data)
+		require.NoError(t, err, "seed %d:\n%s", seed, data)
+		require.True(t, ASTEqual(ast, parsed, IgnorePositions()), "seed %d:\n%s", seed, data)
+	}
+}
+
+func TestGenerateRandomASTDeterministic(t *testing.T) {
+	require.True(t, ASTEqual(GenerateRandomAST(42, 3), GenerateRandomAST(42, 3)))
+	require.False(t, ASTEqual(GenerateRandomAST(42, 3), GenerateRandomAST(43, 3)))
+	require.Len(t, GenerateRandomAST(1, 0).Entries, 1)
+}
+
+func TestWriteFuzzCorpus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hcl-corpus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, WriteFuzzCorpus(dir, 10, 3, 2))
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "random-11.hcl"))
+	require.NoError(t, err)
+	_, err = ParseBytes(data)
+	require.NoError(t, err)
+}
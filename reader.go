@@ -0,0 +1,442 @@
+package hcl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+	"unicode"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// ParseReader parses HCL from r incrementally, one top-level entry at a time,
+// rather than reading all of r into memory before parsing, as Parse does.
+//
+// Memory used while parsing is bounded by the size of the largest top-level
+// attribute or block, plus the resulting AST, so it is suitable for very large
+// machine-generated documents consisting of many entries. The resulting AST is
+// the same as that from Parse.
+//
+// Limits on the input size and number of entries apply to the whole input.
+func ParseReader(r io.Reader, options ...ParseOption) (*AST, error) {
+	opt := newParseOptions(options)
+	if opt.metrics == nil {
+		return parseReader(r, opt)
+	}
+	start := time.Now()
+	counter := &countingReader{r: r}
+	ast, err := parseReader(counter, opt)
+	opt.reportParse(start, counter.n, ast, err)
+	return ast, err
+}
+
+// parseReader parses r incrementally, as ParseReader does, without reporting
+// metrics.
+func parseReader(r io.Reader, opt *parseOptions) (*AST, error) {
+	filename := lexer.NameOfReader(r)
+	if opt.maxInputSize > 0 {
+		r = &sizeLimitReader{r: r, remaining: opt.maxInputSize, max: opt.maxInputSize}
+	}
+	s := &entryScanner{r: bufio.NewReader(r), splitAt: -1}
+	dst := &AST{Pos: lexer.Position{Filename: filename, Line: 1, Column: 1}, BlankLines: true}
+	// The last top-level entry of the previous chunk, which ends at the first
+	// token of this one.
+	var last *Entry
+	// Offset and line of the start of the current chunk in the normalised input.
+	offset, line := 0, 0
+	// Line directives in the input so far.
+	var directives lineDirectives
+	for {
+		chunk, err := s.next()
+		if err != nil {
+			return nil, err
+		}
+		if chunk == nil {
+			break
+		}
+		chunkOpt := *opt
+		chunkOpt.maxInputSize = 0
+		chunkOpt.entriesBefore = opt.entriesBefore + countEntries(dst)
+		chunkOpt.ignoreLineDirectives = true
+		chunkOpt.metrics = nil
+		directives = append(directives, scanLineDirectives(chunk, line)...)
+		parsed := &AST{}
+		err = parseSource(filename, chunk, parsed, &chunkOpt)
+		at := origin{lines: line, offset: offset}
+		if err != nil {
+			return nil, directives.mapError(at.shiftError(err))
+		}
+		if err := at.shiftPositions(parsed); err != nil {
+			return nil, err
+		}
+		if err := directives.mapPositions(parsed); err != nil {
+			return nil, err
+		}
+		if last != nil {
+			last.EndPos = directives.mapPosition(at.shiftPosition(firstToken(filename, chunk)))
+		}
+		if len(parsed.Entries) > 0 {
+			last = parsed.Entries[len(parsed.Entries)-1]
+		}
+		dst.Directives = append(dst.Directives, parsed.Directives...)
+		dst.Entries = append(dst.Entries, parsed.Entries...)
+		dst.TrailingComments = append(dst.TrailingComments, parsed.TrailingComments...)
+		dst.TrailingCommentStyles = append(dst.TrailingCommentStyles, parsed.TrailingCommentStyles...)
+		offset += len(chunk)
+		line += bytes.Count(chunk, []byte("\n"))
+	}
+	dst.BOM, dst.CRLF = s.bom, s.crlf
+	return dst, AddParentRefs(dst)
+}
+
+// origin is the position in a larger input of the start of a part of it.
+type origin struct {
+	// Lines and bytes preceding the part.
+	lines, offset int
+	// Columns preceding the part on its first line.
+	columns int
+}
+
+// shiftPosition returns pos, in a part of a larger input starting at o, as a
+// position in the larger input.
+func (o origin) shiftPosition(pos lexer.Position) lexer.Position {
+	if pos.Line == 0 {
+		return pos
+	}
+	if pos.Line == 1 {
+		pos.Column += o.columns
+	}
+	pos.Line += o.lines
+	pos.Offset += o.offset
+	return pos
+}
+
+// shiftPositions moves the positions in node, parsed from a part of a larger
+// input starting at o, to positions in the larger input.
+func (o origin) shiftPositions(node Node) error {
+	return mapPositions(node, o.shiftPosition)
+}
+
+// shiftError moves the position of err, from parsing a part of a larger input
+// starting at o, to a position in the larger input.
+func (o origin) shiftError(err error) error {
+	return mapErrorPosition(err, o.shiftPosition)
+}
+
+// mapPositions replaces each position in node with f(position).
+func mapPositions(node Node, f func(lexer.Position) lexer.Position) error {
+	return Visit(node, func(node Node, next func() error) error {
+		switch node := node.(type) {
+		case *AST:
+			node.Pos = f(node.Pos)
+		case *Entry:
+			node.Pos = f(node.Pos)
+			node.EndPos = f(node.EndPos)
+		case *Attribute:
+			node.Pos = f(node.Pos)
+		case *Block:
+			node.Pos = f(node.Pos)
+		case *MapEntry:
+			node.Pos = f(node.Pos)
+		case *Directive:
+			node.Pos = f(node.Pos)
+		case *Value:
+			node.Pos = f(node.Pos)
+		}
+		return next()
+	})
+}
+
+// mapErrorPosition replaces the position of err, if it has one, with f(position).
+func mapErrorPosition(err error, f func(lexer.Position) lexer.Position) error {
+	if perr, ok := err.(participle.Error); ok {
+		return participle.Errorf(f(perr.Token().Pos), "%s", perr.Message())
+	}
+	return err
+}
+
+// firstToken returns the position of the first token in chunk, which does
+// not start at the beginning of the input.
+func firstToken(filename string, chunk []byte) lexer.Position {
+	i := bytes.IndexFunc(chunk, func(r rune) bool { return !unicode.IsSpace(r) })
+	if i == -1 {
+		i = len(chunk)
+	}
+	return lexer.Position{
+		Filename: filename,
+		Offset:   i,
+		Line:     bytes.Count(chunk[:i], []byte("\n")) + 1,
+		Column:   i - bytes.LastIndexByte(chunk[:i], '\n'),
+	}
+}
+
+// countEntries returns the number of attributes and blocks in node.
+func countEntries(node Node) int {
+	n := 0
+	_ = Visit(node, func(node Node, next func() error) error {
+		if _, ok := node.(*Entry); ok {
+			n++
+		}
+		return next()
+	})
+	return n
+}
+
+// entryScanner splits HCL source into chunks of whole top-level entries.
+//
+// A chunk ends at the newline ending the line on which an entry is complete,
+// once the next entry starts on a following line, so that comments preceding
+// an entry are in the same chunk as it. The source is normalised as it is
+// read: a leading byte order mark is removed, and CRLF line endings are
+// converted to LF.
+type entryScanner struct {
+	r   *bufio.Reader
+	buf []byte
+	eof bool
+
+	bom, crlf, sawNewline bool
+
+	// Nesting of braces and brackets.
+	depth int
+	// The current top-level entry is an attribute, and has been assigned.
+	equals bool
+	// The current top-level entry is a block.
+	block bool
+	// The current top-level entry is complete, so the next token starts another.
+	complete bool
+	// Offset in buf at which to split once the next entry starts, or -1.
+	splitAt int
+	// The previous character was part of an identifier or number.
+	inToken bool
+}
+
+// next returns the next chunk, or nil at the end of the input.
+func (s *entryScanner) next() ([]byte, error) {
+	for !s.eof {
+		c, err := s.readByte()
+		if err == io.EOF {
+			s.eof = true
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if chunk := s.scan(c); chunk != nil {
+			return chunk, nil
+		}
+	}
+	if len(s.buf) == 0 {
+		return nil, nil
+	}
+	chunk := s.buf
+	s.buf = nil
+	return chunk, nil
+}
+
+// readByte reads the next byte of normalised source.
+func (s *entryScanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if len(s.buf) == 0 && !s.sawNewline && c == utf8BOM[0] && !s.bom {
+		if next, err := s.r.Peek(len(utf8BOM) - 1); err == nil && bytes.Equal(next, utf8BOM[1:]) {
+			_, _ = s.r.Discard(len(utf8BOM) - 1)
+			s.bom = true
+			return s.readByte()
+		}
+	}
+	if c == '\r' {
+		if next, err := s.r.Peek(1); err == nil && next[0] == '\n' {
+			if !s.sawNewline {
+				s.crlf = true
+			}
+			return s.readByte()
+		}
+	}
+	if c == '\n' {
+		s.sawNewline = true
+	}
+	return c, nil
+}
+
+// scan appends c to the current chunk, returning the preceding chunk if c
+// starts a new entry following a complete one.
+func (s *entryScanner) scan(c byte) []byte {
+	var chunk []byte
+	inToken := isTokenChar(c)
+	switch {
+	case c == '\n':
+		if s.depth == 0 && s.complete && s.splitAt == -1 {
+			s.splitAt = len(s.buf)
+		}
+
+	case c == ' ' || c == '\t' || c == '\r':
+
+	case c == '#' || (c == '/' && s.peek() == '/'):
+		s.buf = append(s.buf, c)
+		s.copyUntil(func(c byte) bool { return c == '\n' }, false)
+		s.inToken = false
+		return nil
+
+	case c == '/' && s.peek() == '*':
+		s.buf = append(s.buf, c)
+		s.copyBlockComment()
+		s.inToken = false
+		return nil
+
+	default:
+		// A new entry follows a complete one.
+		if s.depth == 0 && s.complete && !(inToken && s.inToken) {
+			if s.splitAt != -1 {
+				chunk = s.buf[:s.splitAt:s.splitAt]
+				s.buf = append([]byte{}, s.buf[s.splitAt:]...)
+			}
+			s.equals, s.block, s.complete, s.splitAt = false, false, false, -1
+		}
+		s.token(c)
+		s.inToken = inToken
+		return chunk
+	}
+	s.inToken = false
+	s.buf = append(s.buf, c)
+	return nil
+}
+
+// token appends the significant character c, and the rest of any string or
+// heredoc it starts, updating the state of the current entry.
+func (s *entryScanner) token(c byte) {
+	s.buf = append(s.buf, c)
+	switch c {
+	case '{', '[':
+		if s.depth == 0 && c == '{' && !s.equals {
+			s.block = true
+		}
+		s.depth++
+
+	case '}', ']':
+		s.depth--
+		if s.depth == 0 && (s.equals || s.block) {
+			s.complete = true
+		}
+
+	case '=':
+		if s.depth == 0 {
+			s.equals = true
+		}
+
+	case '"':
+		s.copyString()
+		if s.depth == 0 && s.equals {
+			s.complete = true
+		}
+
+	case '`':
+		s.copyUntil(func(c byte) bool { return c == '`' }, true)
+		if s.depth == 0 && s.equals {
+			s.complete = true
+		}
+
+	case '<':
+		if s.peek() != '<' {
+			return
+		}
+		s.copyHeredoc()
+		if s.depth == 0 && s.equals {
+			s.complete = true
+		}
+
+	default:
+		if s.depth == 0 && s.equals && isTokenChar(c) {
+			s.complete = true
+		}
+	}
+}
+
+func (s *entryScanner) peek() byte {
+	next, err := s.r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return next[0]
+}
+
+// copyUntil copies bytes until end returns true for one, which is left unread
+// unless inclusive.
+func (s *entryScanner) copyUntil(end func(c byte) bool, inclusive bool) {
+	for {
+		if !inclusive {
+			if c := s.peek(); c != 0 && end(c) {
+				return
+			}
+		}
+		c, err := s.readByte()
+		if err != nil {
+			return
+		}
+		s.buf = append(s.buf, c)
+		if end(c) {
+			return
+		}
+	}
+}
+
+func (s *entryScanner) copyBlockComment() {
+	prev := byte(0)
+	s.copyUntil(func(c byte) bool {
+		done := prev == '*' && c == '/' && len(s.buf) > 2
+		prev = c
+		return done
+	}, true)
+}
+
+// copyString copies the rest of a string literal.
+func (s *entryScanner) copyString() {
+	escaped := false
+	s.copyUntil(func(c byte) bool {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			return true
+		}
+		return false
+	}, true)
+}
+
+// copyHeredoc copies the rest of a heredoc, whose initial '<' has been copied.
+func (s *entryScanner) copyHeredoc() {
+	start := len(s.buf)
+	s.copyUntil(func(c byte) bool { return c != '<' && c != '-' && !isWordChar(c) }, false)
+	delimiter := bytes.TrimLeft(s.buf[start:], "<-")
+	if len(delimiter) == 0 {
+		return
+	}
+	delimiter = append([]byte{}, delimiter...)
+	for {
+		s.copyUntil(func(c byte) bool { return c == '\n' }, true)
+		next, _ := s.r.Peek(len(delimiter) + 1)
+		if len(next) == 0 {
+			return
+		}
+		if bytes.HasPrefix(next, delimiter) && (len(next) == len(delimiter) || !isWordChar(next[len(delimiter)])) {
+			for range delimiter {
+				c, _ := s.readByte()
+				s.buf = append(s.buf, c)
+			}
+			return
+		}
+	}
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// isTokenChar returns true for characters of identifiers, numbers and keywords.
+func isTokenChar(c byte) bool {
+	return isWordChar(c) || c == '.' || c == '-' || c == '+' || c >= 0x80
+}
@@ -0,0 +1,93 @@
+package hcl
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReader(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"Attributes", "a = 1\nb = \"two\"\nc = [1, 2,\n  3]\n"},
+		{"Blocks", "block label {\n  a = 1\n}\n\n// Comment.\nother { b = {c: 2} }\n"},
+		{"SameLine", "a = 1 b = 2\nc = 3 // Trailing.\n"},
+		{"Comments", "/* Multi\n line } */\na = \"{ # [\"\n# Detached.\n\n# Attached.\nb = true\n// Trailing.\n"},
+		{"Heredoc", "a = <<EOF\nb = {\nEOFX\nEOF\nc = <<-END\n  }\nEND\n"},
+		{"Escapes", "a = \"\\\"}\"\nb = -1.5e3\nc = 0x1F\n"},
+		{"RawString", "a = `}\n\"{`\nb = [`]`]\n"},
+		{"CRLF", "\ufeffa = 1\r\nblock {\r\n  b = 2\r\n}\r\n"},
+		{"Empty", ""},
+		{"OnlyComments", "// Nothing.\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expected, err := ParseString(test.source)
+			require.NoError(t, err)
+			actual, err := ParseReader(strings.NewReader(test.source))
+			require.NoError(t, err)
+			require.True(t, ASTEqual(expected, actual), "%s", test.source)
+			require.Equal(t, positions(expected), positions(actual))
+		})
+	}
+}
+
+func TestParseReaderRandom(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		data, err := MarshalAST(GenerateRandomAST(seed, int(seed%5)))
+		require.NoError(t, err)
+		expected, err := ParseBytes(data)
+		require.NoError(t, err)
+		actual, err := ParseReader(bytes.NewReader(data))
+		require.NoError(t, err, "seed %d:\n%s", seed, data)
+		require.True(t, ASTEqual(expected, actual), "seed %d:\n%s", seed, data)
+		require.Equal(t, positions(expected), positions(actual), "seed %d", seed)
+	}
+}
+
+func TestParseReaderErrors(t *testing.T) {
+	source := "a = 1\nb = 2\nc = }\n"
+	_, expected := ParseString(source)
+	require.Error(t, expected)
+	_, err := ParseReader(strings.NewReader(source))
+	require.EqualError(t, err, expected.Error())
+
+	source = strings.Repeat("a = 1\n", 10)
+	_, err = ParseReader(strings.NewReader(source), WithMaxEntries(5))
+	require.EqualError(t, err, "6:3: maximum of 5 entries exceeded")
+	_, err = ParseReader(strings.NewReader(source), WithMaxInputSize(20))
+	require.EqualError(t, err, "input exceeds maximum size of 20 bytes")
+}
+
+// positions returns the position of every entry in node.
+func positions(node Node) []string {
+	out := []string{}
+	_ = Visit(node, func(node Node, next func() error) error {
+		if entry, ok := node.(*Entry); ok {
+			out = append(out, entry.Pos.String()+"-"+entry.EndPos.String())
+		}
+		return next()
+	})
+	return out
+}
+
+func TestParseReaderChunks(t *testing.T) {
+	source := strings.Repeat("// Comment.\nblock {\n  a = \"}\"\n}\nb = [\n  1,\n]\n", 1000)
+	s := &entryScanner{r: bufio.NewReader(strings.NewReader(source)), splitAt: -1}
+	chunks := 0
+	for {
+		chunk, err := s.next()
+		require.NoError(t, err)
+		if chunk == nil {
+			break
+		}
+		require.True(t, len(chunk) < 40, "%q", chunk)
+		chunks++
+	}
+	require.Equal(t, 2000, chunks)
+}
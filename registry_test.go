@@ -0,0 +1,113 @@
+package hcl
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerCallRegistrations(t *testing.T) {
+	// WithUnion takes precedence over the union registered in init().
+	config := &unionConfig{}
+	err := Unmarshal([]byte(`allow "a" { cidr = "10.0.0.0/8" }
+deny "b" {}
+`), config, WithUnion((*unionRule)(nil), map[string]interface{}{"allow": allowRule{}}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `2:1: found extra fields "deny"`)
+
+	type config2 struct {
+		Version int `hcl:"version"`
+	}
+	decoded := []string{}
+	err = Unmarshal([]byte(`version = 1
+datasource "a" {}
+`), &config2{}, WithBlockDecoder("datasource", func(block *Block) error {
+		decoded = append(decoded, block.Labels[0])
+		return nil
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, decoded)
+	err = Unmarshal([]byte("version = 1\ndatasource \"a\" {}\n"), &config2{})
+	require.Error(t, err)
+
+	type service struct {
+		Port int `hcl:"port"`
+	}
+	v, kind, _, err := UnmarshalKind([]byte("kind = \"Service\"\nport = 80\n"), WithKind("Service", &service{}))
+	require.NoError(t, err)
+	require.Equal(t, "Service", kind)
+	require.Equal(t, &service{Port: 80}, v)
+	data, err := MarshalKind(v, "", WithKind("Service", &service{}))
+	require.NoError(t, err)
+	require.Equal(t, "kind = \"Service\"\nport = 80\n", string(data))
+	_, _, _, err = UnmarshalKind([]byte("kind = \"Service\"\nport = 80\n"))
+	require.Error(t, err)
+
+	ast, err := ParseString("@include \"a.hcl\"\n", WithDirective("include", func(*Directive) error { return nil }))
+	require.NoError(t, err)
+	require.Equal(t, "include", ast.Directives[0].Name)
+	_, err = ParseString("@include \"a.hcl\"\n")
+	require.EqualError(t, err, `1:1: unknown directive @include`)
+}
+
+func TestConcurrentRegistries(t *testing.T) {
+	type service struct {
+		Port int `hcl:"port"`
+	}
+	type config struct {
+		Version int `hcl:"version"`
+	}
+	stop := make(chan struct{})
+	registered := make(chan struct{})
+	go func() {
+		defer close(registered)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			name := fmt.Sprintf("other%d", i%4)
+			RegisterBlockDecoder(name, func(*Block) error { return nil })
+			RegisterKind(name, &service{})
+			RegisterDirective(name, func(*Directive) error { return nil })
+			RegisterBlockDecoder(name, nil)
+			RegisterKind(name, nil)
+			RegisterDirective(name, nil)
+		}
+	}()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				source := fmt.Sprintf("@require %d\nversion = %d\ndatasource \"a\" {}\n", i, j)
+				ast, err := ParseString(source, WithDirective("require", func(*Directive) error { return nil }))
+				require.NoError(t, err)
+				decoded := 0
+				actual := &config{}
+				err = UnmarshalAST(ast, actual, WithBlockDecoder("datasource", func(*Block) error {
+					decoded++
+					return nil
+				}))
+				require.NoError(t, err)
+				require.Equal(t, &config{Version: j}, actual)
+				require.Equal(t, 1, decoded)
+
+				v, _, _, err := UnmarshalKind([]byte(fmt.Sprintf("kind = \"Service\"\nport = %d\n", j)), WithKind("Service", &service{}))
+				require.NoError(t, err)
+				require.Equal(t, &service{Port: j}, v)
+
+				rules := &unionConfig{}
+				require.NoError(t, Unmarshal([]byte(`allow "a" { cidr = "10.0.0.0/8" }`), rules))
+				require.Len(t, rules.Rules, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(stop)
+	<-registered
+}
@@ -0,0 +1,138 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/alecthomas/participle"
+)
+
+// A ScalarEncoder converts a value of a type registered with RegisterScalar
+// to a HCL value.
+type ScalarEncoder func(v interface{}) (*Value, error)
+
+// A ScalarDecoder converts a HCL value to a value of a type registered with
+// RegisterScalar.
+type ScalarDecoder func(v *Value) (interface{}, error)
+
+type scalar struct {
+	encode ScalarEncoder
+	decode ScalarDecoder
+}
+
+var (
+	scalarsLock sync.RWMutex
+	scalars     = map[reflect.Type]scalar{}
+)
+
+// RegisterScalar registers functions converting values of type t to and from
+// HCL values, so that types such as uuid.UUID or decimal.Decimal can be used
+// as attributes without implementing encoding.TextMarshaler, eg.
+//
+//	hcl.RegisterScalar(reflect.TypeOf(decimal.Decimal{}),
+//		func(v interface{}) (*hcl.Value, error) {
+//			s := v.(decimal.Decimal).String()
+//			return &hcl.Value{Str: &s}, nil
+//		},
+//		func(v *hcl.Value) (interface{}, error) {
+//			if v.Number == nil {
+//				return nil, fmt.Errorf("expected a number but got %s", v)
+//			}
+//			return decimal.NewFromString(v.String())
+//		})
+//
+// Registered functions take precedence over all other handling of t, including
+// its marshalling interfaces. Either function may be nil to handle that
+// direction as usual. Registering nil for both removes any existing
+// registration.
+func RegisterScalar(t reflect.Type, encode ScalarEncoder, decode ScalarDecoder) {
+	scalarsLock.Lock()
+	defer scalarsLock.Unlock()
+	if encode == nil && decode == nil {
+		delete(scalars, t)
+		return
+	}
+	scalars[t] = scalar{encode: encode, decode: decode}
+}
+
+// WithScalar registers functions converting values of type t, as with
+// RegisterScalar, for a single call, taking precedence over any registered
+// for t. Nil functions disable the conversion of t during the call.
+func WithScalar(t reflect.Type, encode ScalarEncoder, decode ScalarDecoder) MarshalOption {
+	return func(options *marshalOptions) {
+		if options.scalars == nil {
+			options.scalars = map[reflect.Type]scalar{}
+		}
+		options.scalars[t] = scalar{encode: encode, decode: decode}
+	}
+}
+
+func (o *marshalOptions) lookupScalar(t reflect.Type) scalar {
+	if scalar, ok := o.scalars[t]; ok {
+		return scalar
+	}
+	scalarsLock.RLock()
+	defer scalarsLock.RUnlock()
+	return scalars[t]
+}
+
+// encodeScalar converts v with the encoder registered for its type, if any.
+func encodeScalar(v reflect.Value, opt *marshalOptions) (*Value, bool, error) {
+	encode := opt.lookupScalar(v.Type()).encode
+	if encode == nil {
+		return nil, false, nil
+	}
+	value, err := encode(v.Interface())
+	if err != nil {
+		return nil, true, err
+	}
+	if value == nil {
+		return nil, true, fmt.Errorf("encoding %s returned no value", v.Type())
+	}
+	return value, true, nil
+}
+
+// decodeScalar sets rv from v with the decoder registered for its type, if
+// any.
+func decodeScalar(rv reflect.Value, v *Value, opt *marshalOptions) (bool, error) {
+	decode := opt.lookupScalar(rv.Type()).decode
+	if decode == nil {
+		return false, nil
+	}
+	out, err := decode(v)
+	if err != nil {
+		if _, ok := err.(participle.Error); ok {
+			return true, err
+		}
+		return true, participle.Wrapf(v.Pos, err, "invalid value")
+	}
+	ov := reflect.ValueOf(out)
+	switch {
+	case !ov.IsValid():
+		return true, participle.Errorf(v.Pos, "decoding %s returned nil", rv.Type())
+	case ov.Type().AssignableTo(rv.Type()):
+		rv.Set(ov)
+	case ov.Type().ConvertibleTo(rv.Type()):
+		rv.Set(ov.Convert(rv.Type()))
+	default:
+		return true, participle.Errorf(v.Pos, "decoding %s returned %s", rv.Type(), ov.Type())
+	}
+	return true, nil
+}
+
+// scalarSchema describes type t with the type of the value its registered
+// encoder produces for the zero value, or false if it has none.
+func scalarSchema(t reflect.Type, opt *marshalOptions) (*Value, bool) {
+	value, ok, err := encodeScalar(reflect.New(t).Elem(), opt)
+	switch {
+	case !ok:
+		return nil, false
+	case err == nil && value.Number != nil:
+		return &Value{Type: &numType}, true
+	case err == nil && value.Bool != nil:
+		return &Value{Type: &boolType}, true
+	default:
+		return &Value{Type: &strType}, true
+	}
+}
@@ -0,0 +1,89 @@
+package hcl
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// money is a struct, which would otherwise be decoded from a block.
+type money struct {
+	Cents int64
+}
+
+// version is an array, which would otherwise be decoded from a list.
+type version [3]int
+
+func TestScalars(t *testing.T) {
+	RegisterScalar(reflect.TypeOf(money{}),
+		func(v interface{}) (*Value, error) {
+			return &Value{Number: new(big.Float).Quo(big.NewFloat(float64(v.(money).Cents)), big.NewFloat(100))}, nil
+		},
+		func(v *Value) (interface{}, error) {
+			if v.Number == nil {
+				return nil, fmt.Errorf("expected an amount but got %s", v)
+			}
+			f, _ := v.Number.Float64()
+			return money{Cents: int64(f*100 + 0.5)}, nil
+		})
+	defer RegisterScalar(reflect.TypeOf(money{}), nil, nil)
+	versionScalar := WithScalar(reflect.TypeOf(version{}),
+		func(v interface{}) (*Value, error) {
+			ver := v.(version)
+			s := fmt.Sprintf("%d.%d.%d", ver[0], ver[1], ver[2])
+			return &Value{Str: &s}, nil
+		},
+		func(v *Value) (interface{}, error) {
+			var ver version
+			if v.Str == nil || strings.Count(*v.Str, ".") != 2 {
+				return nil, fmt.Errorf("expected a version but got %s", v)
+			}
+			_, err := fmt.Sscanf(*v.Str, "%d.%d.%d", &ver[0], &ver[1], &ver[2])
+			return ver, err
+		})
+
+	type config struct {
+		Price   money    `hcl:"price"`
+		Fees    []money  `hcl:"fees"`
+		Version *version `hcl:"version"`
+	}
+	var out config
+	err := Unmarshal([]byte(`
+price = 12.5
+fees = [1, 0.25]
+version = "1.2.3"
+`), &out, versionScalar)
+	require.NoError(t, err)
+	require.Equal(t, config{
+		Price:   money{1250},
+		Fees:    []money{{100}, {25}},
+		Version: &version{1, 2, 3},
+	}, out)
+
+	data, err := Marshal(&out, versionScalar)
+	require.NoError(t, err)
+	require.Equal(t, `price = 12.5
+fees = [1, 0.25]
+version = "1.2.3"
+`, string(data))
+
+	schema, err := Schema(&out, versionScalar)
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `price = number
+fees = [number]
+version = string
+`, string(data))
+
+	err = Unmarshal([]byte(`
+price = 1
+fees = []
+version = "1.2"
+`), &out, versionScalar)
+	require.EqualError(t, err, `4:11: version: invalid value: expected a version but got "1.2"`)
+}
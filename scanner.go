@@ -0,0 +1,186 @@
+package hcl
+
+import (
+	"fmt"
+	"io"
+	"unicode"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+	"github.com/alecthomas/participle/lexer/stateful"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind string
+
+// Kinds of the tokens of HCL.
+const (
+	TokenEOF    TokenKind = "EOF"
+	TokenIdent  TokenKind = "Ident"
+	TokenNumber TokenKind = "Number"
+	// TokenHeredoc is a whole heredoc, from "<<" to its terminator.
+	TokenHeredoc   TokenKind = "Heredoc"
+	TokenString    TokenKind = "String"
+	TokenRawString TokenKind = "RawString"
+	// TokenSingleString is a single quoted string, eg. 'value'.
+	TokenSingleString TokenKind = "SingleString"
+	// TokenDirective is the name of a directive, eg. "@include".
+	TokenDirective TokenKind = "Directive"
+	// TokenPunct is one of "[", "]", "{", "}", "=", ":" or ",".
+	TokenPunct   TokenKind = "Punct"
+	TokenComment TokenKind = "Comment"
+)
+
+// A Token scanned from HCL source.
+type Token struct {
+	Kind TokenKind
+	// Value is the source text of the token, eg. a String includes its quotes.
+	Value string
+	Pos   lexer.Position
+}
+
+func (t Token) String() string {
+	if t.Kind == TokenEOF {
+		return "EOF"
+	}
+	return fmt.Sprintf("%s %q", t.Kind, t.Value)
+}
+
+// TokenRule specifies a kind of token by the regular expression matching it.
+type TokenRule struct {
+	Kind    TokenKind
+	Pattern string
+}
+
+// TokenRules returns the rules for the kinds of HCL tokens, in the order in
+// which they are tried. Whitespace is skipped.
+func TokenRules() []TokenRule {
+	rules := []TokenRule{}
+	for _, rule := range rootLexerRules {
+		if unicode.IsUpper(rune(rule.Name[0])) {
+			rules = append(rules, TokenRule{Kind: TokenKind(rule.Name), Pattern: rule.Pattern})
+		}
+	}
+	return rules
+}
+
+// ScannerOption configures a Scanner.
+type ScannerOption func(s *Scanner)
+
+// WithTokenRules adds kinds of tokens to a Scanner, eg. for the keywords or
+// literals of a dialect of HCL. They are tried in order before those of HCL,
+// so take precedence over them. Tokens of kinds starting with a lower case
+// letter are skipped, like whitespace.
+func WithTokenRules(rules ...TokenRule) ScannerOption {
+	return func(s *Scanner) {
+		s.rules = append(s.rules, rules...)
+	}
+}
+
+// Scanner splits HCL source into tokens, with a token of lookahead, for
+// projects parsing dialects of HCL with their own grammar.
+type Scanner struct {
+	rules  []TokenRule
+	lexer  lexer.Lexer
+	kinds  map[rune]TokenKind
+	peeked *Token
+	err    error
+	// Position of the last token returned by Next.
+	pos lexer.Position
+}
+
+// NewScanner creates a Scanner reading HCL source from r.
+func NewScanner(r io.Reader, options ...ScannerOption) (*Scanner, error) {
+	s := &Scanner{}
+	for _, option := range options {
+		option(s)
+	}
+	def := baseLex
+	if len(s.rules) > 0 {
+		root := make([]stateful.Rule, 0, len(s.rules)+len(rootLexerRules))
+		for _, rule := range s.rules {
+			root = append(root, stateful.Rule{Name: string(rule.Kind), Pattern: rule.Pattern})
+		}
+		var err error
+		def, err = stateful.New(stateful.Rules{
+			"Root":    append(root, rootLexerRules...),
+			"Heredoc": heredocLexerRules,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	s.lexer, err = def.Lex(r)
+	if err != nil {
+		return nil, err
+	}
+	s.kinds = map[rune]TokenKind{lexer.EOF: TokenEOF}
+	for name, symbol := range def.Symbols() {
+		s.kinds[symbol] = TokenKind(name)
+	}
+	s.pos = lexer.Position{Filename: lexer.NameOfReader(r), Line: 1, Column: 1}
+	return s, nil
+}
+
+// Peek returns the next token without consuming it.
+func (s *Scanner) Peek() (Token, error) {
+	if s.peeked == nil && s.err == nil {
+		token, err := s.scan()
+		if err != nil {
+			s.err = err
+		} else {
+			s.peeked = &token
+		}
+	}
+	if s.err != nil {
+		return Token{}, s.err
+	}
+	return *s.peeked, nil
+}
+
+// Next consumes and returns the next token, which is of kind TokenEOF at the
+// end of the source.
+func (s *Scanner) Next() (Token, error) {
+	token, err := s.Peek()
+	if err != nil {
+		return Token{}, err
+	}
+	if token.Kind != TokenEOF {
+		s.peeked = nil
+	}
+	s.pos = token.Pos
+	return token, nil
+}
+
+// Pos returns the position of the last token returned by Next, or of the
+// start of the source if there is none.
+func (s *Scanner) Pos() lexer.Position {
+	return s.pos
+}
+
+// scan the next token from the lexer, joining the parts of heredocs.
+func (s *Scanner) scan() (Token, error) {
+	token, err := s.lexer.Next()
+	if err != nil {
+		return Token{}, err
+	}
+	out := Token{Kind: s.kinds[token.Type], Value: token.Value, Pos: token.Pos}
+	if out.Kind != TokenHeredoc {
+		return out, nil
+	}
+	start := out.Value
+	for {
+		part, err := s.lexer.Next()
+		if err != nil {
+			return Token{}, err
+		}
+		if part.EOF() {
+			return Token{}, participle.Errorf(out.Pos, "unterminated heredoc %s", start)
+		}
+		out.Value += part.Value
+		if s.kinds[part.Type] == "End" {
+			return out, nil
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scanAll(t *testing.T, s *Scanner) []string {
+	t.Helper()
+	out := []string{}
+	for {
+		token, err := s.Next()
+		require.NoError(t, err)
+		if token.Kind == TokenEOF {
+			return out
+		}
+		out = append(out, token.String())
+	}
+}
+
+func TestScanner(t *testing.T) {
+	s, err := NewScanner(strings.NewReader(`// Comment.
+name = "app"
+ports = [80, 0x1bb]
+doc = <<EOF
+hello
+EOF
+`))
+	require.NoError(t, err)
+	token, err := s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, TokenComment, token.Kind)
+	require.Equal(t, "1:1", s.Pos().String())
+	require.Equal(t, []string{
+		`Comment "// Comment."`,
+		`Ident "name"`, `Punct "="`, `String "\"app\""`,
+		`Ident "ports"`, `Punct "="`, `Punct "["`, `Number "80"`, `Punct ","`, `Number "0x1bb"`, `Punct "]"`,
+		`Ident "doc"`, `Punct "="`, `Heredoc "<<EOF\nhello\nEOF"`,
+	}, scanAll(t, s))
+	require.Equal(t, "7:1", s.Pos().String())
+}
+
+func TestScannerTokenRules(t *testing.T) {
+	s, err := NewScanner(strings.NewReader(`@include "base.hcl"
+timeout = 30s`), WithTokenRules(
+		TokenRule{Kind: "Duration", Pattern: `\d+[smh]\b`},
+	))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`Directive "@include"`, `String "\"base.hcl\""`,
+		`Ident "timeout"`, `Punct "="`, `Duration "30s"`,
+	}, scanAll(t, s))
+
+	kinds := []TokenKind{}
+	for _, rule := range TokenRules() {
+		kinds = append(kinds, rule.Kind)
+	}
+	require.Equal(t, []TokenKind{TokenIdent, TokenNumber, TokenHeredoc, TokenString, TokenRawString, TokenSingleString, TokenDirective, TokenPunct, TokenComment}, kinds)
+}
+
+func TestScannerErrors(t *testing.T) {
+	s, err := NewScanner(strings.NewReader("a = @"))
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = s.Next()
+		require.NoError(t, err)
+	}
+	_, err = s.Next()
+	require.EqualError(t, err, `1:5: no lexer rules in state "Root" matched input text "@"`)
+
+	s, err = NewScanner(strings.NewReader("a = <<EOF\nhello\n"))
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = s.Next()
+		require.NoError(t, err)
+	}
+	_, err = s.Next()
+	require.EqualError(t, err, `1:5: unterminated heredoc <<EOF`)
+}
@@ -7,7 +7,8 @@ import (
 
 // Schema reflects a schema from a Go value.
 //
-// A schema is itself HCL.
+// A schema is itself HCL. With MarshalMode(ModeSchema|ModeData), the default of
+// each attribute set in v is its value.
 func Schema(v interface{}, options ...MarshalOption) (*AST, error) {
 	ast, err := marshalToAST(v, true, newMarshalOptions(options...))
 	if err != nil {
@@ -27,9 +28,9 @@ func MustSchema(v interface{}, options ...MarshalOption) *AST {
 
 // BlockSchema reflects a block schema for a Go struct.
 func BlockSchema(name string, v interface{}, options ...MarshalOption) (*AST, error) {
-	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
-		return nil, fmt.Errorf("expected a pointer to a struct not %T", v)
+	rv, err := structPointer(reflect.ValueOf(v), v)
+	if err != nil {
+		return nil, err
 	}
 	block, err := valueToBlock(rv.Elem(), tag{name: name, block: true}, true, newMarshalOptions(options...))
 	if err != nil {
@@ -56,23 +57,43 @@ var (
 	boolType = "boolean"
 )
 
-func attrSchema(t reflect.Type) (*Value, error) {
-	if t == durationType || t == timeType || typeImplements(t, textMarshalerInterface) || typeImplements(t, jsonMarshalerInterface) {
+func attrSchema(t reflect.Type, opt *marshalOptions) (*Value, error) {
+	if value, ok := scalarSchema(t, opt); ok {
+		return value, nil
+	}
+	if t == durationType || t == timeType || t == bytesType || typeImplements(t, textMarshalerInterface) || typeImplements(t, jsonMarshalerInterface) {
 		return &Value{Type: &strType}, nil
 	}
+	if t == numberType {
+		return &Value{Type: &numType}, nil
+	}
+	if t == tristateType {
+		return &Value{Type: &boolType}, nil
+	}
 	switch t.Kind() {
 	case reflect.String:
 		return &Value{Type: &strType}, nil
 
 	case reflect.Slice:
-		el, err := attrSchema(t.Elem())
+		el, err := elemSchema(t.Elem(), opt)
 		if err != nil {
 			return nil, err
 		}
 		return &Value{List: []*Value{el}, HaveList: true}, nil
 
+	case reflect.Array:
+		list := make([]*Value, t.Len())
+		for i := range list {
+			el, err := elemSchema(t.Elem(), opt)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = el
+		}
+		return &Value{List: list, HaveList: true}, nil
+
 	case reflect.Map:
-		el, err := attrSchema(t.Elem())
+		el, err := elemSchema(t.Elem(), opt)
 		if err != nil {
 			return nil, err
 		}
@@ -90,20 +111,67 @@ func attrSchema(t reflect.Type) (*Value, error) {
 		panic("struct " + t.String() + " used as attribute, is it missing a \"block\" tag?")
 
 	case reflect.Ptr:
-		return attrSchema(t.Elem())
+		return attrSchema(t.Elem(), opt)
 
 	default:
 		panic(fmt.Sprintf("unsupported attribute type %s during schema reflection", t))
 	}
 }
 
+// elemSchema describes an element of a list or map attribute, in which
+// structs are objects, eg. `{ cpu: number }`.
+func elemSchema(t reflect.Type, opt *marshalOptions) (*Value, error) {
+	st := t
+	for st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if _, ok := scalarSchema(st, opt); ok {
+		return attrSchema(t, opt)
+	}
+	if st.Kind() != reflect.Struct || st == timeType || typeImplements(st, textMarshalerInterface) || typeImplements(st, jsonMarshalerInterface) {
+		return attrSchema(t, opt)
+	}
+	block := &Block{Name: st.Name()}
+	if err := schemaBlockBody(block, reflect.New(st).Elem(), opt); err != nil {
+		return nil, err
+	}
+	return blockToObject(block)
+}
+
 func sliceToBlockSchema(t reflect.Type, tag tag, opt *marshalOptions) (*Block, error) {
 	block := &Block{
 		Name:     tag.name,
 		Comments: tag.comments(),
 		Repeated: true,
+		MinItems: tag.minItems,
+		MaxItems: tag.maxItems,
+	}
+	return block, schemaBlockBody(block, reflect.New(t.Elem()).Elem(), opt)
+}
+
+// schemaBlockBody describes the struct v as the labels and body of a schema
+// block. If its type is already being described by an enclosing block, the
+// block refers to that block instead, as it would otherwise never terminate.
+func schemaBlockBody(block *Block, v reflect.Value, opt *marshalOptions) (err error) {
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if path, ok := opt.schemaTypes[t]; ok {
+		block.Recursive, block.RecursivePath = true, path
+		fields, err := flattenFields(reflect.New(t).Elem(), opt)
+		if err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if tag := parseTag(t, field, opt); tag.label {
+				block.Labels = append(block.Labels, tag.name)
+			}
+		}
+		return nil
 	}
-	var err error
-	block.Body, block.Labels, err = structToEntries(reflect.New(t.Elem()).Elem(), true, opt)
-	return block, err
+	opt.schemaPath = append(opt.schemaPath, block.Name)
+	defer func() { opt.schemaPath = opt.schemaPath[:len(opt.schemaPath)-1] }()
+	block.Body, block.Labels, err = structToEntries(v, true, opt)
+	return err
 }
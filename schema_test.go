@@ -262,3 +262,74 @@ options {
     `
 	require.Equal(t, strings.TrimSpace(expectedSchema), strings.TrimSpace(string(data)))
 }
+
+func TestDeprecatedSchema(t *testing.T) {
+	type config struct {
+		Old string `hcl:"old,optional" deprecated:"use new instead"`
+		New string `hcl:"new"`
+	}
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `old = string // (optional, deprecated: use new instead)
+new = string
+`, string(data))
+}
+
+func TestBlockOccurrencesSchema(t *testing.T) {
+	type upstream struct {
+		Host string `hcl:"host"`
+	}
+	type config struct {
+		Upstreams []upstream `hcl:"upstream,block,min=1,max=5"`
+		Mirrors   []upstream `hcl:"mirror,block,max=2"`
+	}
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	require.Equal(t, 1, schema.Entries[0].Block.MinItems)
+	require.Equal(t, 5, schema.Entries[0].Block.MaxItems)
+	data, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `upstream { // (repeated, min 1, max 5)
+  host = string
+}
+
+mirror { // (repeated, max 2)
+  host = string
+}
+`, string(data))
+}
+
+func TestConstraintsSchema(t *testing.T) {
+	type config struct {
+		Name string `hcl:"name" pattern:"^[a-z-]+$"`
+		Port int    `hcl:"port,optional" min:"1" max:"65535"`
+	}
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `name = string // (pattern: ^[a-z-]+$)
+port = number // (optional, min: 1, max: 65535)
+`, string(data))
+}
+
+func TestInlineSchema(t *testing.T) {
+	type limits struct {
+		CPU    int `hcl:"cpu"`
+		Memory int `hcl:"mem,optional"`
+	}
+	type config struct {
+		Limits limits `hcl:"limits,inline"`
+	}
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err := MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `limits = {
+  "cpu": number,
+  "mem": number,
+} // (optional)
+`, string(data))
+}
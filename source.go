@@ -0,0 +1,174 @@
+package hcl
+
+import (
+	"bytes"
+	"io"
+)
+
+var (
+	utf8BOM = []byte("\xef\xbb\xbf")
+	crlf    = []byte("\r\n")
+	lf      = []byte("\n")
+)
+
+// normaliseSource removes a leading UTF-8 byte order mark from data and
+// converts CRLF line endings to LF.
+//
+// It returns whether there was a byte order mark, and whether the first line
+// ending was CRLF.
+func normaliseSource(data []byte) (out []byte, bom bool, isCRLF bool) {
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		bom = true
+	}
+	if eol := bytes.IndexByte(data, '\n'); eol > 0 && data[eol-1] == '\r' {
+		isCRLF = true
+	}
+	if bytes.Contains(data, crlf) {
+		data = bytes.ReplaceAll(data, crlf, lf)
+	}
+	return data, bom, isCRLF
+}
+
+// restoreSource reverses normaliseSource.
+func restoreSource(data []byte, bom, isCRLF bool) []byte {
+	if isCRLF {
+		data = bytes.ReplaceAll(data, lf, crlf)
+	}
+	if bom {
+		data = append(append([]byte{}, utf8BOM...), data...)
+	}
+	return data
+}
+
+// namedReader associates a filename with a reader, for positions.
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (n *namedReader) Name() string { return n.name }
+
+// crlfWriter converts LF line endings to CRLF.
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (c *crlfWriter) Write(b []byte) (int, error) {
+	if _, err := c.w.Write(bytes.ReplaceAll(b, lf, crlf)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// recordBlankLines marks the entries in node that are preceded by a blank
+// line in data, the normalised source node was parsed from.
+func recordBlankLines(node Node, data []byte) error {
+	return Visit(node, func(node Node, next func() error) error {
+		if entry, ok := node.(*Entry); ok {
+			entry.BlankLineBefore = blankLineBefore(data, entry.Pos.Offset)
+			switch {
+			case entry.Attribute != nil:
+				entry.Attribute.DetachedComments = detachedComments(data, entry.Pos.Offset, len(entry.Attribute.Comments))
+			case entry.Block != nil:
+				entry.Block.DetachedComments = detachedComments(data, entry.Pos.Offset, len(entry.Block.Comments))
+			}
+		}
+		return next()
+	})
+}
+
+// blankLineBefore returns true if the line preceding offset in data is blank.
+func blankLineBefore(data []byte, offset int) bool {
+	newlines := 0
+	for i := offset - 1; i >= 0; i-- {
+		switch data[i] {
+		case ' ', '\t':
+		case '\n':
+			newlines++
+			if newlines == 2 {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// detachedComments returns how many of the n comments starting at offset in
+// data are followed by a blank line, and so are detached from the entry that
+// follows them.
+func detachedComments(data []byte, offset, n int) int {
+	detached := 0
+	for i, comment := offset, 0; i < len(data) && comment < n; comment++ {
+		rest := data[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end == -1 {
+				return detached
+			}
+			i += end + 4
+		case bytes.HasPrefix(rest, []byte("#")), bytes.HasPrefix(rest, []byte("//")):
+			end := bytes.IndexByte(rest, '\n')
+			if end == -1 {
+				return detached
+			}
+			i += end
+		default:
+			return detached
+		}
+		newlines := 0
+	whitespace:
+		for ; i < len(data); i++ {
+			switch data[i] {
+			case '\n':
+				newlines++
+			case ' ', '\t', '\r':
+			default:
+				break whitespace
+			}
+		}
+		if newlines > 1 {
+			detached = comment + 1
+		}
+	}
+	return detached
+}
+
+// entrySource is the source text of an entry, and the entry as printed when
+// it was parsed, to detect whether it has since been modified.
+type entrySource struct {
+	text    string
+	printed string
+}
+
+// WithKeepSource retains the source text of each attribute and block when
+// parsing, so that printing the AST reproduces entries that have not since
+// been modified exactly as they were written, rather than reformatting them.
+//
+// This keeps diffs minimal when tools modify part of a file.
+func WithKeepSource() ParseOption {
+	return func(options *parseOptions) {
+		options.keepSource = true
+	}
+}
+
+// keepSource records the source text of each entry in node, parsed from data.
+func keepSource(node Node, data []byte) error {
+	printer := NewPrinter()
+	return Visit(node, func(node Node, next func() error) error {
+		entry, ok := node.(*Entry)
+		if !ok {
+			return next()
+		}
+		text := bytes.TrimRight(data[entry.Pos.Offset:entry.EndPos.Offset], " \t\n")
+		printed, err := printer.isolated(entry)
+		if err != nil {
+			return err
+		}
+		entry.source = &entrySource{text: string(text), printed: printed}
+		return next()
+	})
+}
@@ -0,0 +1,48 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCRLFAndBOM(t *testing.T) {
+	src := "\xef\xbb\xbf// A comment.\r\nname = \"app\" // Trailing.\r\n\r\nblock {\r\n  doc = <<EOF\r\nline one\r\nline two\r\nEOF\r\n}\r\n"
+	ast, err := ParseString(src)
+	require.NoError(t, err)
+	require.True(t, ast.BOM)
+	require.True(t, ast.CRLF)
+	require.Equal(t, []string{"A comment."}, ast.Entries[0].Attribute.Comments)
+	require.Equal(t, []string{"Trailing."}, ast.Entries[0].Attribute.TrailingComments)
+	require.Equal(t, "line one\nline two", ast.Entries[1].Block.Body[0].Attribute.Value.GetHeredoc())
+
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, src, string(data))
+
+	var config struct {
+		Name  string `hcl:"name"`
+		Block struct {
+			Doc string `hcl:"doc"`
+		} `hcl:"block,block"`
+	}
+	err = Unmarshal([]byte(src), &config)
+	require.NoError(t, err)
+	require.Equal(t, "app", config.Name)
+	require.Equal(t, "line one\nline two", config.Block.Doc)
+}
+
+func TestParseLF(t *testing.T) {
+	ast, err := ParseString("a = 1\nb = 2\n")
+	require.NoError(t, err)
+	require.False(t, ast.BOM)
+	require.False(t, ast.CRLF)
+}
+
+func TestMigrateCRLF(t *testing.T) {
+	src := "\xef\xbb\xbfold = 1\r\nother = 2\r\n"
+	out, err := Migrate([]byte(src), []RenameRule{{From: "old", To: "new"}})
+	require.NoError(t, err)
+	require.Equal(t, strings.Replace(src, "old", "new", 1), string(out))
+}
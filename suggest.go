@@ -0,0 +1,96 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// suggest returns the candidate closest to name, the unknown name of an
+// attribute or block, if it is close enough to plausibly be a misspelling.
+func suggest(name string, candidates []string) (string, bool) {
+	best, bestDistance := "", -1
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+	for _, candidate := range sorted {
+		if candidate == name {
+			continue
+		}
+		distance := editDistance(name, candidate)
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	threshold := len([]rune(name)) / 3
+	if threshold < 1 {
+		threshold = 1
+	}
+	if bestDistance == -1 || bestDistance > threshold {
+		return "", false
+	}
+	return best, true
+}
+
+// didYouMean returns a suggestion for the unknown name to append to an error
+// message, or "" if there is none.
+func didYouMean(name string, candidates []string) string {
+	if suggestion, ok := suggest(name, candidates); ok {
+		return fmt.Sprintf("; did you mean %q?", suggestion)
+	}
+	return ""
+}
+
+// editDistance returns the number of single character insertions, deletions,
+// substitutions and transpositions of adjacent characters transforming a into b.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	// Rows i-2, i-1 and i of the distance matrix.
+	prev2 := make([]int, len(t)+1)
+	prev := make([]int, len(t)+1)
+	row := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			row[j] = min3(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] && prev2[j-2]+1 < row[j] {
+				row[j] = prev2[j-2] + 1
+			}
+		}
+		prev2, prev, row = prev, row, prev2
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// fieldNames returns the attribute and block names of the fields of struct
+// type t, including the members of unions.
+func fieldNames(t reflect.Type, fields []field, opt *marshalOptions) []string {
+	names := []string{}
+	for _, field := range fields {
+		tag := parseTag(t, field, opt)
+		if tag.name == "" || tag.label || tag.remain {
+			continue
+		}
+		names = append(names, tag.name)
+		if tag.union != nil {
+			names = append(names, tag.union.names...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,39 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("port", "port"))
+	require.Equal(t, 1, editDistance("prot", "port"))
+	require.Equal(t, 1, editDistance("hots", "host"))
+	require.Equal(t, 1, editDistance("port", "ports"))
+	require.Equal(t, 4, editDistance("", "port"))
+	require.Equal(t, 3, editDistance("kitten", "sitting"))
+}
+
+func TestSuggestions(t *testing.T) {
+	type config struct {
+		Host    string `hcl:"host"`
+		Port    int    `hcl:"port,optional"`
+		Servers []struct {
+			Name string `hcl:"name,label"`
+		} `hcl:"server,block,optional"`
+	}
+	var out config
+	err := Unmarshal([]byte("host = \"localhost\"\nprot = 80\nsrever web {}\nbogus = 1\n"), &out)
+	require.EqualError(t, err, `2:1: found extra fields "bogus", "prot" (did you mean "port"?), "srever" (did you mean "server"?)`)
+
+	diagnostics, err := CheckCompatibility([]byte("host = \"\"\nport = 1\nhots = \"\"\n"), &out)
+	require.NoError(t, err)
+	require.Equal(t, `3:1: attribute "hots" would be ignored; did you mean "host"?`, diagnostics[0].Error())
+
+	spec := NewBlockSpec("").Attribute("port", TypeNumber, false)
+	ast, err := ParseString("prot = 1")
+	require.NoError(t, err)
+	_, err = spec.Decode(ast)
+	require.EqualError(t, err, `1:1: unknown attribute "prot"; did you mean "port"?`)
+}
@@ -0,0 +1,239 @@
+package hcl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
+)
+
+// templateMarker records the template line that the following output
+// originates from. NUL can't appear in HCL, so markers can't be confused with
+// output.
+const templateMarker = "\x00%d\x00"
+
+// TemplateFuncs returns functions for text/templates generating HCL, which
+// safely escape the values they're passed:
+//
+//	quote   quotes a string, as with QuoteString, eg. name = {{ quote .Name }}
+//	ident   escapes a label or map key, as with EscapeIdent, eg. service {{ ident .Name }} {
+//	value   formats any value, as with Marshal, eg. ports = {{ value .Ports }}
+//
+// These are available to templates executed by ExecuteTemplate.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"quote": QuoteString,
+		"ident": EscapeIdent,
+		"value": templateValue,
+	}
+}
+
+// templateValue formats v as a HCL value.
+func templateValue(v interface{}) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("can't format nil as a HCL value")
+	}
+	value, err := valueToValue(reflect.ValueOf(v), newMarshalOptions())
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// ExecuteTemplate executes tmpl as a text/template with data, and parses the
+// output as HCL.
+//
+// The functions of TemplateFuncs are available to tmpl.
+//
+// Positions in the AST and in parse errors refer to the lines of the template
+// rather than to those of its output, so errors in generated configuration
+// can be located in the template. Lines produced by a "range" map to the line
+// of the template producing them. Columns are only exact for lines without
+// actions.
+//
+// Executing the template fails if it refers to a missing map key.
+func ExecuteTemplate(tmpl string, data interface{}, options ...ParseOption) (*AST, error) {
+	t, err := template.New("hcl").Option("missingkey=error").Funcs(TemplateFuncs()).Parse(markTemplate(tmpl))
+	if err != nil {
+		return nil, err
+	}
+	w := &strings.Builder{}
+	if err := t.Execute(w, data); err != nil {
+		return nil, err
+	}
+	out, lines := unmarkTemplate(w.String())
+	source := []byte(tmpl)
+	remap := func(pos lexer.Position) lexer.Position {
+		if pos.Line == 0 {
+			return pos
+		}
+		line := lines[len(lines)-1]
+		if pos.Line <= len(lines) {
+			line = lines[pos.Line-1]
+		}
+		mapped := PositionAt(source, line, pos.Column, ColumnRunes)
+		mapped.Filename = pos.Filename
+		return mapped
+	}
+	ast, err := ParseBytes([]byte(out), options...)
+	if err != nil {
+		if perr, ok := err.(participle.Error); ok {
+			return nil, participle.Errorf(remap(perr.Token().Pos), "%s", perr.Message())
+		}
+		return nil, err
+	}
+	err = Visit(ast, func(node Node, next func() error) error {
+		switch node := node.(type) {
+		case *AST:
+			node.Pos = remap(node.Pos)
+		case *Entry:
+			node.Pos = remap(node.Pos)
+			node.EndPos = remap(node.EndPos)
+		case *Attribute:
+			node.Pos = remap(node.Pos)
+		case *Block:
+			node.Pos = remap(node.Pos)
+		case *MapEntry:
+			node.Pos = remap(node.Pos)
+		case *Directive:
+			node.Pos = remap(node.Pos)
+		case *Value:
+			node.Pos = remap(node.Pos)
+		}
+		return next()
+	})
+	return ast, err
+}
+
+// markTemplate inserts a marker before each newline of tmpl outside of an
+// action, recording the number of the line that follows.
+//
+// Where a newline is trimmed by a preceding "-}}" action, the marker is
+// instead inserted before the text that follows the trimmed whitespace, as it
+// would otherwise prevent the whitespace from being trimmed.
+func markTemplate(tmpl string) string {
+	w := &strings.Builder{}
+	line := 1
+	inAction := false
+	// Only whitespace has followed a "-}}" action, and whether it included a newline.
+	trimming, trimmedNewline := false, false
+	for i := 0; i < len(tmpl); i++ {
+		rest := tmpl[i:]
+		if trimming && trimmedNewline && !strings.ContainsRune(" \t\r\n", rune(rest[0])) {
+			fmt.Fprintf(w, templateMarker, line)
+			trimming, trimmedNewline = false, false
+		}
+		switch {
+		case !inAction && strings.HasPrefix(rest, "{{"):
+			inAction = true
+			trimming = false
+			w.WriteString("{{")
+			i++
+			continue
+
+		case inAction && strings.HasPrefix(rest, "}}"):
+			inAction = false
+			trimming = strings.HasSuffix(w.String(), " -")
+			w.WriteString("}}")
+			i++
+			continue
+
+		case inAction && (rest[0] == '"' || rest[0] == '`' || rest[0] == '\''):
+			// Copy quoted strings, which may contain delimiters, verbatim.
+			end := quotedEnd(rest)
+			line += strings.Count(rest[:end], "\n")
+			w.WriteString(rest[:end])
+			i += end - 1
+			continue
+
+		case inAction && strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest, "*/")
+			if end == -1 {
+				end = len(rest)
+			} else {
+				end += 2
+			}
+			line += strings.Count(rest[:end], "\n")
+			w.WriteString(rest[:end])
+			i += end - 1
+			continue
+
+		case rest[0] == '\n':
+			line++
+			if !inAction && !trimming {
+				fmt.Fprintf(w, templateMarker, line)
+			}
+			trimmedNewline = trimming
+
+		case !inAction && !strings.ContainsRune(" \t\r", rune(rest[0])):
+			trimming = false
+		}
+		w.WriteByte(rest[0])
+	}
+	return w.String()
+}
+
+// quotedEnd returns the offset just past the end of the quoted string at the
+// start of s.
+func quotedEnd(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// unmarkTemplate removes markers from the output of a template, returning the
+// output and the template line of each line of the output.
+//
+// A line of output maps to the marker preceding the newline before it, or to
+// a marker preceding its first non-whitespace character. Lines without
+// markers, such as those of a multi-line value, map to the line of the
+// preceding marker.
+func unmarkTemplate(out string) (string, []int) {
+	w := &bytes.Buffer{}
+	lines := []int{1}
+	current := 1
+	// No non-whitespace characters have been written to the current line.
+	fresh := true
+	for i := 0; i < len(out); i++ {
+		switch out[i] {
+		case 0:
+			end := strings.IndexByte(out[i+1:], 0)
+			if end == -1 {
+				end = len(out) - i - 1
+			}
+			i += end + 1
+			n, err := strconv.Atoi(out[i-end : i])
+			if err != nil {
+				continue
+			}
+			current = n
+			// Markers preceding a newline apply to the line that follows it.
+			if fresh && (i+1 == len(out) || out[i+1] != '\n') {
+				lines[len(lines)-1] = n
+			}
+			continue
+		case '\n':
+			lines = append(lines, current)
+			fresh = true
+		case ' ', '\t', '\r':
+		default:
+			fresh = false
+		}
+		w.WriteByte(out[i])
+	}
+	return w.String(), lines
+}
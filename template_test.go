@@ -0,0 +1,107 @@
+package hcl
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/require"
+)
+
+const serviceTemplate = `// Generated.
+env = "{{ .Env }}"
+{{ range .Services -}}
+service "{{ .Name }}" {
+  replicas = {{ .Replicas }}
+}
+{{ end -}}
+debug = false
+`
+
+type templateService struct {
+	Name     string
+	Replicas string
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	ast, err := ExecuteTemplate(serviceTemplate, map[string]interface{}{
+		"Env": "prod",
+		"Services": []templateService{
+			{Name: "api", Replicas: "2"},
+			{Name: "web", Replicas: "3"},
+		},
+	})
+	require.NoError(t, err)
+	data, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `// Generated.
+env = "prod"
+service "api" {
+  replicas = 2
+}
+service "web" {
+  replicas = 3
+}
+debug = false
+`, string(data))
+
+	lines := []int{}
+	for _, entry := range ast.Entries {
+		lines = append(lines, entry.Pos.Line)
+	}
+	require.Equal(t, []int{1, 4, 4, 8}, lines)
+	require.Equal(t, 5, ast.Entries[2].Block.Body[0].Pos.Line)
+	require.Equal(t, 8, ast.Entries[3].Attribute.Value.Pos.Line)
+}
+
+func TestExecuteTemplateErrors(t *testing.T) {
+	_, err := ExecuteTemplate(serviceTemplate, map[string]interface{}{
+		"Env": "prod",
+		"Services": []templateService{
+			{Name: "api", Replicas: "2"},
+			{Name: "web", Replicas: "= 3"},
+		},
+	})
+	require.EqualError(t, err, `5:3: unexpected token "replicas" (expected "}")`)
+
+	_, err = ExecuteTemplate(serviceTemplate, map[string]interface{}{})
+	require.Error(t, err)
+
+	_, err = ExecuteTemplate(`a = {{ .A`, nil)
+	require.Error(t, err)
+}
+
+func TestMarkTemplate(t *testing.T) {
+	tmpl := "a = 1\n{{ if true -}}\n  b = 2\n{{- end }}\nc = {{ `}}\n` }}\nd = 4\n"
+	w := &strings.Builder{}
+	err := template.Must(template.New("").Parse(markTemplate(tmpl))).Execute(w, nil)
+	require.NoError(t, err)
+	out, lines := unmarkTemplate(w.String())
+	require.Equal(t, "a = 1\nb = 2\nc = }}\n\nd = 4\n", out)
+	require.Equal(t, []int{1, 3, 5, 5, 7, 8}, lines)
+}
+
+func TestExecuteTemplateFuncs(t *testing.T) {
+	ast, err := ExecuteTemplate(`service {{ ident .Name }} {
+  description = {{ quote .Description }}
+  ports = {{ value .Ports }}
+  labels = {{ value .Labels }}
+}
+`, map[string]interface{}{
+		"Name":        "web\" {}\nevil {",
+		"Description": "${danger}\"\nadmin = true",
+		"Ports":       []int{80, 443},
+		"Labels":      map[string]string{"tier": "front end"},
+	})
+	require.NoError(t, err)
+	require.Len(t, ast.Entries, 1)
+	block := ast.Entries[0].Block
+	require.Equal(t, []string{"web\" {}\nevil {"}, block.Labels)
+	require.Len(t, block.Body, 3)
+	require.Equal(t, "${danger}\"\nadmin = true", *block.Body[0].Attribute.Value.Str)
+	require.Equal(t, "[80, 443]", block.Body[1].Attribute.Value.String())
+	require.Equal(t, `{"tier": "front end"}`, block.Body[2].Attribute.Value.String())
+
+	_, err = ExecuteTemplate(`a = {{ value .Missing }}`, map[string]interface{}{"Missing": nil})
+	require.Error(t, err)
+}
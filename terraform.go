@@ -0,0 +1,168 @@
+package hcl
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// terraformSchema is the schema of a resource in the JSON format of
+// "terraform providers schema -json".
+type terraformSchema struct {
+	Version int             `json:"version"`
+	Block   *terraformBlock `json:"block"`
+}
+
+type terraformBlock struct {
+	Attributes      map[string]*terraformAttribute `json:"attributes,omitempty"`
+	BlockTypes      map[string]*terraformBlockType `json:"block_types,omitempty"`
+	Description     string                         `json:"description,omitempty"`
+	DescriptionKind string                         `json:"description_kind,omitempty"`
+}
+
+type terraformAttribute struct {
+	Type            interface{} `json:"type"`
+	Description     string      `json:"description,omitempty"`
+	DescriptionKind string      `json:"description_kind,omitempty"`
+	Required        bool        `json:"required,omitempty"`
+	Optional        bool        `json:"optional,omitempty"`
+	Computed        bool        `json:"computed,omitempty"`
+	Deprecated      bool        `json:"deprecated,omitempty"`
+}
+
+type terraformBlockType struct {
+	NestingMode string          `json:"nesting_mode"`
+	Block       *terraformBlock `json:"block"`
+	MinItems    int             `json:"min_items,omitempty"`
+	MaxItems    int             `json:"max_items,omitempty"`
+}
+
+// TerraformSchema reflects the schema of v, as with Schema, in the JSON format
+// Terraform providers describe resources with, as output by "terraform
+// providers schema -json", eg.
+//
+//	{"version": 0, "block": {"attributes": {"port": {"type": "number", "required": true}}}}
+//
+// so that tools understanding that format, such as editor plugins, can be
+// reused.
+//
+// Repeated blocks are nested as lists, and blocks with a single label as maps
+// keyed by it. Blocks with more than one label, and recursive blocks, can't be
+// described and are an error. Read-only attributes are computed.
+func TerraformSchema(v interface{}, options ...MarshalOption) ([]byte, error) {
+	schema, err := Schema(v, options...)
+	if err != nil {
+		return nil, err
+	}
+	block, err := terraformBlockSchema(schema.Entries, "")
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&terraformSchema{Block: block}, "", "  ")
+}
+
+func terraformBlockSchema(entries []*Entry, description string) (*terraformBlock, error) {
+	out := &terraformBlock{}
+	if description != "" {
+		out.Description, out.DescriptionKind = description, "plain"
+	}
+	for _, entry := range entries {
+		if attr := entry.Attribute; attr != nil {
+			if out.Attributes == nil {
+				out.Attributes = map[string]*terraformAttribute{}
+			}
+			t, err := terraformType(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			tattr := &terraformAttribute{
+				Type:       t,
+				Required:   !attr.Optional && !attr.ReadOnly,
+				Optional:   attr.Optional && !attr.ReadOnly,
+				Computed:   attr.ReadOnly,
+				Deprecated: attr.Deprecated != "",
+			}
+			if description := entry.DocComment(); description != "" {
+				tattr.Description, tattr.DescriptionKind = description, "plain"
+			}
+			out.Attributes[attr.Key] = tattr
+			continue
+		}
+		block := entry.Block
+		if block.Recursive {
+			return nil, fmt.Errorf("recursive block %q can't be described in a Terraform schema", block.Name)
+		}
+		nested := &terraformBlockType{NestingMode: "single", MinItems: block.MinItems, MaxItems: block.MaxItems}
+		switch {
+		case len(block.Labels) > 1:
+			return nil, fmt.Errorf("block %q has %d labels, but Terraform schemas allow at most one", block.Name, len(block.Labels))
+		case len(block.Labels) == 1:
+			nested.NestingMode = "map"
+		case block.Repeated:
+			nested.NestingMode = "list"
+		}
+		body, err := terraformBlockSchema(block.Body, entry.DocComment())
+		if err != nil {
+			return nil, err
+		}
+		nested.Block = body
+		if out.BlockTypes == nil {
+			out.BlockTypes = map[string]*terraformBlockType{}
+		}
+		out.BlockTypes[block.Name] = nested
+	}
+	return out, nil
+}
+
+// terraformType converts a schema value to a type in Terraform's JSON
+// encoding of cty types, eg. ["list", "string"].
+func terraformType(v *Value) (interface{}, error) {
+	switch {
+	case v.Type != nil && *v.Type == boolType:
+		return "bool", nil
+
+	case v.Type != nil:
+		return *v.Type, nil
+
+	case v.HaveList && len(v.List) == 1:
+		el, err := terraformType(v.List[0])
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{"list", el}, nil
+
+	case v.HaveList:
+		elements := make([]interface{}, 0, len(v.List))
+		for _, el := range v.List {
+			t, err := terraformType(el)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, t)
+		}
+		return []interface{}{"tuple", elements}, nil
+
+	case v.HaveMap && len(v.Map) == 1 && v.Map[0].Key.Type != nil:
+		el, err := terraformType(v.Map[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{"map", el}, nil
+
+	case v.HaveMap:
+		attrs := map[string]interface{}{}
+		for _, entry := range v.Map {
+			if entry.Key.Str == nil {
+				return nil, fmt.Errorf("unsupported object key %s in schema", entry.Key)
+			}
+			t, err := terraformType(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			attrs[*entry.Key.Str] = t
+		}
+		return []interface{}{"object", attrs}, nil
+
+	default:
+		return "dynamic", nil
+	}
+}
@@ -0,0 +1,79 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerraformSchema(t *testing.T) {
+	type limit struct {
+		CPU int `hcl:"cpu"`
+	}
+	type rule struct {
+		Action string `hcl:"action" help:"What to do."`
+	}
+	type listener struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Name      string           `hcl:"name" help:"Name of the service."`
+		Replicas  *int             `hcl:"replicas,optional"`
+		ID        string           `hcl:"id,readonly"`
+		Old       bool             `hcl:"old,optional" deprecated:"use name"`
+		Tags      []string         `hcl:"tags,optional"`
+		Env       map[string]int   `hcl:"env,optional"`
+		Limits    []limit          `hcl:"limits,optional"`
+		Rules     []rule           `hcl:"rule,block,min=1" help:"Routing rules."`
+		Listeners []listener       `hcl:"listener,block"`
+		Extra     map[string]limit `hcl:"extra,optional"`
+	}
+	data, err := TerraformSchema(&config{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "version": 0,
+  "block": {
+    "attributes": {
+      "name": {"type": "string", "description": "Name of the service.", "description_kind": "plain", "required": true},
+      "replicas": {"type": "number", "optional": true},
+      "id": {"type": "string", "computed": true},
+      "old": {"type": "bool", "optional": true, "deprecated": true},
+      "tags": {"type": ["list", "string"], "optional": true},
+      "env": {"type": ["map", "number"], "optional": true},
+      "limits": {"type": ["list", ["object", {"cpu": "number"}]], "optional": true},
+      "extra": {"type": ["map", ["object", {"cpu": "number"}]], "optional": true}
+    },
+    "block_types": {
+      "rule": {
+        "nesting_mode": "list",
+        "block": {
+          "attributes": {
+            "action": {"type": "string", "description": "What to do.", "description_kind": "plain", "required": true}
+          },
+          "description": "Routing rules.",
+          "description_kind": "plain"
+        },
+        "min_items": 1
+      },
+      "listener": {
+        "nesting_mode": "map",
+        "block": {
+          "attributes": {
+            "port": {"type": "number", "required": true}
+          }
+        }
+      }
+    }
+  }
+}`, string(data))
+
+	type multi struct {
+		Resources []struct {
+			Type string `hcl:"type,label"`
+			Name string `hcl:"name,label"`
+		} `hcl:"resource,block"`
+	}
+	_, err = TerraformSchema(&multi{})
+	require.EqualError(t, err, `block "resource" has 2 labels, but Terraform schemas allow at most one`)
+}
@@ -0,0 +1,104 @@
+package hcl
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// TraceKind is the kind of a TraceEvent.
+type TraceKind int
+
+// Trace event kinds.
+const (
+	// TraceMatched is a field populated from an attribute, block or label.
+	TraceMatched TraceKind = iota
+	// TraceDefaulted is a field populated from its default:"" tag.
+	TraceDefaulted
+	// TraceCoerced is a value converted to the type of its field by WithWeakTypes.
+	TraceCoerced
+	// TraceSkipped is an optional field that was not set, or an attribute or
+	// block that no field was populated from.
+	TraceSkipped
+)
+
+func (k TraceKind) String() string {
+	switch k {
+	case TraceDefaulted:
+		return "defaulted"
+	case TraceCoerced:
+		return "coerced"
+	case TraceSkipped:
+		return "skipped"
+	default:
+		return "matched"
+	}
+}
+
+// TraceEvent describes a decision made while unmarshalling.
+type TraceEvent struct {
+	Kind TraceKind
+	// Path of the attribute, block or label, as passed to WithUnusedCallback,
+	// eg. "server.web.port".
+	Path string
+	// Field is the Go field, eg. "Server.Port", or empty for attributes and
+	// blocks that no field was populated from.
+	Field string
+	// Pos of the attribute or block, if any.
+	Pos lexer.Position
+	// Value that the field was populated with, if it is an attribute or label.
+	Value string
+}
+
+func (e TraceEvent) String() string {
+	w := &strings.Builder{}
+	w.WriteString(e.Kind.String())
+	w.WriteString(" ")
+	w.WriteString(e.Path)
+	if e.Field != "" {
+		w.WriteString(" -> ")
+		w.WriteString(e.Field)
+	}
+	if e.Value != "" {
+		w.WriteString(" = ")
+		w.WriteString(e.Value)
+	}
+	if e.Pos.Line != 0 {
+		w.WriteString(" at ")
+		w.WriteString(e.Pos.String())
+	}
+	return w.String()
+}
+
+// WithTrace calls fn for each field matched, defaulted, coerced or skipped
+// while unmarshalling, eg. to log why a field does not have the value
+// expected:
+//
+//	hcl.WithTrace(func(event hcl.TraceEvent) { log.Println(event) })
+func WithTrace(fn func(event TraceEvent)) MarshalOption {
+	return func(options *marshalOptions) {
+		options.tracer = fn
+	}
+}
+
+// trace reports event for the entry with the given names, relative to the
+// current block, to the trace callback, if any.
+func (o *marshalOptions) trace(event TraceEvent, value *Value, names ...string) {
+	if o.tracer == nil {
+		return
+	}
+	event.Path = strings.Join(append(append([]string{}, o.path...), names...), ".")
+	if value != nil {
+		event.Value = value.String()
+	}
+	o.tracer(event)
+}
+
+// traceField reports an event for field f of struct type parent.
+func (o *marshalOptions) traceField(kind TraceKind, parent reflect.Type, f reflect.StructField, pos lexer.Position, value *Value, names ...string) {
+	if o.tracer == nil {
+		return
+	}
+	o.trace(TraceEvent{Kind: kind, Field: parent.Name() + "." + f.Name, Pos: pos}, value, names...)
+}
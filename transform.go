@@ -0,0 +1,283 @@
+package hcl
+
+import (
+	"github.com/alecthomas/participle"
+)
+
+// WithDefaultsBlock enables "defaults" blocks with the given name.
+//
+// Attributes in a defaults block are applied to each sibling block, with a
+// name matching one of the defaults block's labels, that does not already
+// contain the attribute. A defaults block without labels applies to all
+// sibling blocks. The defaults blocks themselves are removed before decoding.
+//
+// eg. with WithDefaultsBlock("defaults"):
+//
+//	defaults "service" {
+//	  port = 80
+//	}
+//
+//	service "www" {}
+//	service "api" { port = 8080 }
+func WithDefaultsBlock(name string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.defaultsBlock = name
+	}
+}
+
+// WithTemplates enables reusable template blocks.
+//
+// Blocks named define, with a single label, declare a template. Any block may
+// then include the body of one or more templates by setting the extends
+// attribute to a template name or list of names. Entries in the block override
+// those from templates, and earlier templates override later ones. Templates
+// may themselves extend other templates.
+//
+// eg. with WithTemplates("define", "extends"):
+//
+//	define "common" {
+//	  timeout = "10s"
+//	  retries = 3
+//	}
+//
+//	service "api" {
+//	  extends = "common"
+//	  retries = 5
+//	}
+func WithTemplates(define, extends string) MarshalOption {
+	return func(options *marshalOptions) {
+		options.templateBlock = define
+		options.extendsAttr = extends
+	}
+}
+
+// preprocessEntries applies AST transforms enabled by options prior to decoding.
+//
+// The original entries are not modified.
+func preprocessEntries(entries []*Entry, opt *marshalOptions) ([]*Entry, error) {
+	if opt.defaultsBlock == "" && opt.templateBlock == "" && opt.profile == nil && opt.keyring == nil {
+		return entries, nil
+	}
+	out := cloneEntries(entries)
+	var err error
+	if opt.keyring != nil {
+		if err := decryptValues(out, opt.keyring); err != nil {
+			return nil, err
+		}
+	}
+	if opt.profile != nil {
+		out, err = applyProfile(out, *opt.profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt.templateBlock != "" {
+		out, err = expandTemplates(out, opt.templateBlock, opt.extendsAttr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opt.defaultsBlock != "" {
+		out, err = applyDefaultsBlocks(out, opt.defaultsBlock)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, entry := range out {
+		if err := AddParentRefs(entry); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func cloneEntries(entries []*Entry) []*Entry {
+	out := make([]*Entry, len(entries))
+	for i, entry := range entries {
+		out[i] = entry.Clone()
+	}
+	return out
+}
+
+// preprocessBlock applies AST transforms to the body of a block.
+func preprocessBlock(block *Block, opt *marshalOptions) (*Block, error) {
+	body, err := preprocessEntries(block.Body, opt)
+	if err != nil {
+		return nil, err
+	}
+	out := *block
+	out.Body = body
+	return &out, nil
+}
+
+func applyDefaultsBlocks(entries []*Entry, name string) ([]*Entry, error) {
+	// Collect defaults, keyed by block name, with "" applying to all blocks.
+	defaults := map[string][]*Attribute{}
+	seen := map[string]map[string]bool{}
+	out := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		block := entry.Block
+		if block == nil || block.Name != name {
+			out = append(out, entry)
+			continue
+		}
+		targets := block.Labels
+		if len(targets) == 0 {
+			targets = []string{""}
+		}
+		for _, child := range block.Body {
+			if child.Attribute == nil {
+				return nil, participle.Errorf(child.Pos, "%s block may only contain attributes", name)
+			}
+			for _, target := range targets {
+				if seen[target] == nil {
+					seen[target] = map[string]bool{}
+				}
+				if seen[target][child.Attribute.Key] {
+					return nil, participle.Errorf(child.Pos, "duplicate default for %q", child.Attribute.Key)
+				}
+				seen[target][child.Attribute.Key] = true
+				defaults[target] = append(defaults[target], child.Attribute)
+			}
+		}
+	}
+	for _, entry := range out {
+		block := entry.Block
+		if block == nil {
+			continue
+		}
+		body, err := applyDefaultsBlocks(block.Body, name)
+		if err != nil {
+			return nil, err
+		}
+		block.Body = body
+		present := map[string]bool{}
+		for _, child := range block.Body {
+			present[child.Key()] = true
+		}
+		for _, attrs := range [][]*Attribute{defaults[block.Name], defaults[""]} {
+			for _, attr := range attrs {
+				if present[attr.Key] {
+					continue
+				}
+				present[attr.Key] = true
+				block.Body = append(block.Body, &Entry{Pos: attr.Pos, Attribute: attr.Clone()})
+			}
+		}
+	}
+	return out, nil
+}
+
+func expandTemplates(entries []*Entry, define, extends string) ([]*Entry, error) {
+	e := &templateExpander{
+		extends:   extends,
+		templates: map[string]*Block{},
+		resolved:  map[string][]*Entry{},
+		resolving: map[string]bool{},
+	}
+	out := make([]*Entry, 0, len(entries))
+	for _, entry := range entries {
+		block := entry.Block
+		if block == nil || block.Name != define {
+			out = append(out, entry)
+			continue
+		}
+		if len(block.Labels) != 1 {
+			return nil, participle.Errorf(block.Pos, "%s block expects 1 label, got %d", define, len(block.Labels))
+		}
+		name := block.Labels[0]
+		if _, ok := e.templates[name]; ok {
+			return nil, participle.Errorf(block.Pos, "duplicate template %q", name)
+		}
+		e.templates[name] = block
+	}
+	return e.expandEntries(out)
+}
+
+type templateExpander struct {
+	extends   string
+	templates map[string]*Block
+	resolved  map[string][]*Entry
+	resolving map[string]bool
+}
+
+func (e *templateExpander) expandEntries(entries []*Entry) ([]*Entry, error) {
+	for _, entry := range entries {
+		if entry.Block == nil {
+			continue
+		}
+		body, err := e.expandBody(entry.Block.Body)
+		if err != nil {
+			return nil, err
+		}
+		entry.Block.Body = body
+	}
+	return entries, nil
+}
+
+// expandBody merges the templates referenced by the extends attribute, if any, into body.
+func (e *templateExpander) expandBody(body []*Entry) ([]*Entry, error) {
+	var extends *Value
+	out := make([]*Entry, 0, len(body))
+	for _, entry := range body {
+		if entry.Attribute != nil && entry.Attribute.Key == e.extends {
+			extends = entry.Attribute.Value
+			continue
+		}
+		out = append(out, entry)
+	}
+	out, err := e.expandEntries(out)
+	if err != nil || extends == nil {
+		return out, err
+	}
+	var names []*Value
+	switch {
+	case extends.Str != nil:
+		names = []*Value{extends}
+	case extends.HaveList:
+		names = extends.List
+	default:
+		return nil, participle.Errorf(extends.Pos, "%s must be a template name or list of names but is %s", e.extends, extends)
+	}
+	for _, name := range names {
+		if name.Str == nil {
+			return nil, participle.Errorf(name.Pos, "template name must be a string but is %s", name)
+		}
+		template, err := e.resolve(*name.Str, name)
+		if err != nil {
+			return nil, err
+		}
+		present := map[string]bool{}
+		for _, entry := range out {
+			present[entry.Key()] = true
+		}
+		for _, entry := range template {
+			if !present[entry.Key()] {
+				out = append(out, entry.Clone())
+			}
+		}
+	}
+	return out, nil
+}
+
+// resolve returns the fully expanded body of a template.
+func (e *templateExpander) resolve(name string, ref *Value) ([]*Entry, error) {
+	if body, ok := e.resolved[name]; ok {
+		return body, nil
+	}
+	template, ok := e.templates[name]
+	if !ok {
+		return nil, participle.Errorf(ref.Pos, "unknown template %q", name)
+	}
+	if e.resolving[name] {
+		return nil, participle.Errorf(ref.Pos, "template %q extends itself", name)
+	}
+	e.resolving[name] = true
+	body, err := e.expandBody(cloneEntries(template.Body))
+	delete(e.resolving, name)
+	if err != nil {
+		return nil, err
+	}
+	e.resolved[name] = body
+	return body, nil
+}
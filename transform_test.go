@@ -0,0 +1,108 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/alecthomas/participle/lexer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultsBlock(t *testing.T) {
+	type service struct {
+		Name string `hcl:"name,label"`
+		Host string `hcl:"host"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Services []service `hcl:"service,block"`
+	}
+	ast := hcl(
+		block("defaults", []string{"service"}, attr("port", num(80))),
+		block("defaults", nil, attr("host", str("localhost"))),
+		block("service", []string{"www"}),
+		block("service", []string{"api"}, attr("port", num(8080)), attr("host", str("api.internal"))),
+	)
+	actual := &config{}
+	err := UnmarshalAST(ast, actual, WithDefaultsBlock("defaults"))
+	require.NoError(t, err)
+	require.Equal(t, &config{Services: []service{
+		{Name: "www", Host: "localhost", Port: 80},
+		{Name: "api", Host: "api.internal", Port: 8080},
+	}}, actual)
+	// The original AST is not modified.
+	require.Len(t, ast.Entries, 4)
+	require.Len(t, ast.Entries[2].Block.Body, 0)
+}
+
+func TestDefaultsBlockErrors(t *testing.T) {
+	type config struct{}
+	nested := block("nested", nil)
+	nested.Pos = lexer.Position{Line: 2, Column: 3}
+	ast := hcl(block("defaults", nil, nested))
+	err := UnmarshalAST(ast, &config{}, WithDefaultsBlock("defaults"))
+	require.EqualError(t, err, "2:3: defaults block may only contain attributes")
+
+	duplicate := attr("x", num(2))
+	duplicate.Pos = lexer.Position{Line: 3, Column: 3}
+	ast = hcl(block("defaults", []string{"a"}, attr("x", num(1)), duplicate))
+	err = UnmarshalAST(ast, &config{}, WithDefaultsBlock("defaults"))
+	require.EqualError(t, err, `3:3: duplicate default for "x"`)
+}
+
+func TestTemplates(t *testing.T) {
+	type service struct {
+		Name    string `hcl:"name,label"`
+		Timeout string `hcl:"timeout"`
+		Retries int    `hcl:"retries"`
+		Debug   bool   `hcl:"debug,optional"`
+	}
+	type config struct {
+		Services []service `hcl:"service,block"`
+	}
+	ast := hcl(
+		block("define", []string{"base"}, attr("timeout", str("10s")), attr("retries", num(3))),
+		block("define", []string{"debug"}, attr("extends", str("base")), attr("debug", hbool(true))),
+		block("service", []string{"api"}, attr("extends", str("base")), attr("retries", num(5))),
+		block("service", []string{"dev"}, attr("extends", list(str("debug"), str("base")))),
+	)
+	actual := &config{}
+	err := UnmarshalAST(ast, actual, WithTemplates("define", "extends"))
+	require.NoError(t, err)
+	require.Equal(t, &config{Services: []service{
+		{Name: "api", Timeout: "10s", Retries: 5},
+		{Name: "dev", Timeout: "10s", Retries: 3, Debug: true},
+	}}, actual)
+}
+
+func TestTemplatesErrors(t *testing.T) {
+	type config struct {
+		Service struct{} `hcl:"service,block"`
+	}
+	ref := func(name string) *Value {
+		v := str(name)
+		v.Pos = lexer.Position{Line: 5, Column: 13}
+		return v
+	}
+	tests := []struct {
+		name string
+		ast  *AST
+		fail string
+	}{
+		{name: "UnknownTemplate",
+			ast:  hcl(block("service", nil, attr("extends", ref("missing")))),
+			fail: `5:13: unknown template "missing"`},
+		{name: "Cycle",
+			ast: hcl(
+				block("define", []string{"a"}, attr("extends", str("b"))),
+				block("define", []string{"b"}, attr("extends", ref("a"))),
+				block("service", nil, attr("extends", str("a"))),
+			),
+			fail: `5:13: template "a" extends itself`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := UnmarshalAST(test.ast, &config{}, WithTemplates("define", "extends"))
+			require.EqualError(t, err, test.fail)
+		})
+	}
+}
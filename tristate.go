@@ -0,0 +1,80 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/alecthomas/participle"
+)
+
+var (
+	tristateType = reflect.TypeOf(TristateUnset)
+	boolGoType   = reflect.TypeOf(false)
+)
+
+// Tristate is a boolean that may also be unset, distinguishing an attribute
+// that is explicitly false from one that is absent.
+//
+// A Tristate field is decoded from a boolean attribute, and is unset if the
+// attribute is absent, so is always optional. When marshalled, an unset
+// Tristate is omitted, and a set one is written even if it is false.
+type Tristate int8
+
+// Values of a Tristate.
+const (
+	TristateUnset Tristate = iota
+	TristateFalse
+	TristateTrue
+)
+
+// NewTristate returns the Tristate set to b.
+func NewTristate(b bool) Tristate {
+	if b {
+		return TristateTrue
+	}
+	return TristateFalse
+}
+
+// IsSet returns true if t is true or false.
+func (t Tristate) IsSet() bool { return t != TristateUnset }
+
+// Bool returns true if t is true.
+func (t Tristate) Bool() bool { return t == TristateTrue }
+
+// BoolOr returns the value of t, or def if t is unset.
+func (t Tristate) BoolOr(def bool) bool {
+	if t == TristateUnset {
+		return def
+	}
+	return t == TristateTrue
+}
+
+func (t Tristate) String() string {
+	switch t {
+	case TristateTrue:
+		return "true"
+	case TristateFalse:
+		return "false"
+	default:
+		return "unset"
+	}
+}
+
+// unmarshalTristate sets rv, a Tristate, from the boolean v.
+func unmarshalTristate(rv reflect.Value, v *Value) error {
+	if v.Bool == nil {
+		return participle.Errorf(v.Pos, "expected a bool but got %s", v)
+	}
+	rv.SetInt(int64(NewTristate(bool(*v.Bool))))
+	return nil
+}
+
+// tristateToValue converts a set Tristate to a boolean. Unset Tristates are
+// omitted from structs and maps, but can't be elements of lists.
+func tristateToValue(t Tristate) (*Value, error) {
+	if t != TristateTrue && t != TristateFalse {
+		return nil, fmt.Errorf("can't marshal unset %s", tristateType)
+	}
+	b := Bool(t == TristateTrue)
+	return &Value{Bool: &b}, nil
+}
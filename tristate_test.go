@@ -0,0 +1,65 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTristate(t *testing.T) {
+	type config struct {
+		Debug    Tristate            `hcl:"debug"`
+		Cache    Tristate            `hcl:"cache"`
+		Tracing  Tristate            `hcl:"tracing"`
+		Features map[string]Tristate `hcl:"features,optional"`
+		Flags    []Tristate          `hcl:"flags,optional"`
+	}
+	var out config
+	err := Unmarshal([]byte(`
+debug = false
+cache = true
+features = { a: true, b: false }
+flags = [true, false]
+`), &out)
+	require.NoError(t, err)
+	require.Equal(t, config{
+		Debug:    TristateFalse,
+		Cache:    TristateTrue,
+		Tracing:  TristateUnset,
+		Features: map[string]Tristate{"a": TristateTrue, "b": TristateFalse},
+		Flags:    []Tristate{TristateTrue, TristateFalse},
+	}, out)
+	require.True(t, out.Debug.IsSet())
+	require.False(t, out.Debug.BoolOr(true))
+	require.False(t, out.Tracing.IsSet())
+	require.True(t, out.Tracing.BoolOr(true))
+
+	out.Features["c"] = TristateUnset
+	data, err := Marshal(&out)
+	require.NoError(t, err)
+	require.Equal(t, `debug = false
+cache = true
+features = {
+  "a": true,
+  "b": false,
+}
+flags = [true, false]
+`, string(data))
+
+	out.Flags = append(out.Flags, TristateUnset)
+	_, err = Marshal(&out)
+	require.EqualError(t, err, `flags[2]: can't marshal unset hcl.Tristate`)
+
+	err = Unmarshal([]byte(`debug = 1`), &out)
+	require.EqualError(t, err, `1:9: debug: expected a bool but got 1`)
+
+	err = Unmarshal([]byte(`debug = "false"`), &out, WithWeakTypes())
+	require.NoError(t, err)
+	require.Equal(t, TristateFalse, out.Debug)
+
+	schema, err := Schema(&config{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "debug = boolean // (optional)\n")
+}
@@ -0,0 +1,214 @@
+package hcl
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/participle"
+)
+
+// union of concrete block types implementing an interface, selected by block name.
+type union struct {
+	iface reflect.Type
+	names []string
+	types map[string]reflect.Type
+}
+
+var (
+	unionsLock sync.RWMutex
+	unions     = map[reflect.Type]*union{}
+)
+
+// RegisterUnion registers the concrete types implementing an interface, for
+// fields of that interface type, or slices of it, tagged as blocks.
+//
+// iface is a nil pointer to the interface, and members maps block names to
+// values of the concrete types, which must be structs or pointers to structs.
+// During unmarshalling, each block with a member's name is decoded into a new
+// value of that member's type, and when marshalling each value is encoded as
+// a block with its type's name, eg.
+//
+//	hcl.RegisterUnion((*Rule)(nil), map[string]interface{}{
+//		"allow": AllowRule{},
+//		"deny":  &DenyRule{},
+//	})
+//
+//	type Config struct {
+//		Rules []Rule `hcl:"rule,block"`
+//	}
+//
+// decodes "allow {}" and "deny {}" blocks into Rules, in source order.
+//
+// Registering a union with no members removes any existing registration.
+func RegisterUnion(iface interface{}, members map[string]interface{}) {
+	t, u := newUnion("RegisterUnion", iface, members)
+	unionsLock.Lock()
+	defer unionsLock.Unlock()
+	if u == nil {
+		delete(unions, t)
+		return
+	}
+	unions[t] = u
+}
+
+// WithUnion registers a union, as with RegisterUnion, for a single call to
+// Marshal or Unmarshal, taking precedence over any union registered for the
+// same interface. With no members, no union is used for the interface.
+func WithUnion(iface interface{}, members map[string]interface{}) MarshalOption {
+	t, u := newUnion("WithUnion", iface, members)
+	return func(options *marshalOptions) {
+		if options.unions == nil {
+			options.unions = map[reflect.Type]*union{}
+		}
+		options.unions[t] = u
+	}
+}
+
+// newUnion validates the arguments of RegisterUnion or WithUnion, called
+// caller, returning the interface type and its union, or nil if there are no
+// members.
+func newUnion(caller string, iface interface{}, members map[string]interface{}) (reflect.Type, *union) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Sprintf("%s expects a nil pointer to an interface, not %T", caller, iface))
+	}
+	t = t.Elem()
+	u := &union{iface: t, types: map[string]reflect.Type{}}
+	for name, member := range members {
+		mt := reflect.TypeOf(member)
+		if mt == nil || !mt.Implements(t) {
+			panic(fmt.Sprintf("union member %q of type %T does not implement %s", name, member, t))
+		}
+		if mt.Kind() != reflect.Struct && (mt.Kind() != reflect.Ptr || mt.Elem().Kind() != reflect.Struct) {
+			panic(fmt.Sprintf("union member %q of type %T must be a struct or a pointer to a struct", name, member))
+		}
+		u.names = append(u.names, name)
+		u.types[name] = mt
+	}
+	sort.Strings(u.names)
+	if len(members) == 0 {
+		return t, nil
+	}
+	return t, u
+}
+
+// lookupUnion returns the union registered for a field of type t, an
+// interface or a slice of interfaces, or nil if there is none.
+func (o *marshalOptions) lookupUnion(t reflect.Type) *union {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Interface {
+		return nil
+	}
+	if u, ok := o.unions[t]; ok {
+		return u
+	}
+	unionsLock.RLock()
+	defer unionsLock.RUnlock()
+	return unions[t]
+}
+
+// nameOf returns the block name of the member of type t.
+func (u *union) nameOf(t reflect.Type) (string, bool) {
+	for _, name := range u.names {
+		if u.types[name] == t {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func (u *union) has(name string) bool {
+	_, ok := u.types[name]
+	return ok
+}
+
+// unmarshalUnion decodes blocks into fv, a field of a union interface type or
+// a slice of it.
+func unmarshalUnion(fv reflect.Value, tag tag, u *union, entries []*Entry, opt *marshalOptions) error {
+	if fv.Kind() != reflect.Slice && len(entries) > 1 {
+		return participle.Errorf(entries[1].Pos, "duplicate field %q at %s", tag.name, entries[0].Pos)
+	}
+	for _, entry := range entries {
+		if entry.Block == nil {
+			return participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", entry.Key())
+		}
+		mt, ok := u.types[entry.Block.Name]
+		if !ok {
+			return participle.Errorf(entry.Pos, "unknown %s block %q, expected one of %s",
+				u.iface.Name(), entry.Block.Name, strings.Join(u.names, ", "))
+		}
+		member := reflect.New(mt).Elem()
+		target := member
+		if mt.Kind() == reflect.Ptr {
+			member.Set(reflect.New(mt.Elem()))
+			target = member.Elem()
+		}
+		if err := unmarshalBlock(target, entry.Block, opt); err != nil {
+			err = annotateFieldError(entry.Pos, err)
+			if fv.Kind() == reflect.Slice {
+				err = withFieldPath(fmt.Sprintf("[%d]", fv.Len()), err)
+			}
+			return err
+		}
+		if fv.Kind() == reflect.Slice {
+			fv.Set(reflect.Append(fv, member))
+		} else {
+			fv.Set(member)
+		}
+	}
+	return nil
+}
+
+// unionToBlocks encodes fv, a field of a union interface type or a slice of
+// it, as blocks named after the type of each value. In schemas, a block is
+// described for each member.
+func unionToBlocks(fv reflect.Value, tag tag, u *union, schema bool, opt *marshalOptions) ([]*Block, error) {
+	blocks := []*Block{}
+	if schema {
+		for _, name := range u.names {
+			member := tag
+			member.name = name
+			block, err := valueToBlock(reflect.New(u.types[name]).Elem(), member, true, opt)
+			if err != nil {
+				return nil, err
+			}
+			block.Repeated = fv.Kind() == reflect.Slice
+			blocks = append(blocks, block)
+		}
+		return blocks, nil
+	}
+	values := []reflect.Value{fv}
+	if fv.Kind() == reflect.Slice {
+		values = values[:0]
+		for i := 0; i < fv.Len(); i++ {
+			values = append(values, fv.Index(i))
+		}
+	}
+	for i, v := range values {
+		if v.IsNil() {
+			continue
+		}
+		v = v.Elem()
+		name, ok := u.nameOf(v.Type())
+		if !ok {
+			err := fmt.Errorf("%s is not a registered %s type", v.Type(), u.iface.Name())
+			if fv.Kind() == reflect.Slice {
+				return nil, withFieldPath(fmt.Sprintf("[%d]", i), err)
+			}
+			return nil, err
+		}
+		member := tag
+		member.name = name
+		block, err := valueToBlock(v, member, false, opt)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
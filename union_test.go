@@ -0,0 +1,86 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type unionRule interface{ allowed() bool }
+
+type allowRule struct {
+	Name string `hcl:"name,label"`
+	CIDR string `hcl:"cidr"`
+}
+
+func (allowRule) allowed() bool { return true }
+
+type denyRule struct {
+	Name string `hcl:"name,label"`
+	Log  bool   `hcl:"log,optional"`
+}
+
+func (*denyRule) allowed() bool { return false }
+
+type logRule struct{}
+
+func (logRule) allowed() bool { return true }
+
+type unionConfig struct {
+	Rules []unionRule `hcl:"rule,block"`
+}
+
+func init() {
+	RegisterUnion((*unionRule)(nil), map[string]interface{}{
+		"allow": allowRule{},
+		"deny":  &denyRule{},
+	})
+}
+
+func TestUnion(t *testing.T) {
+	source := `allow "office" {
+  cidr = "10.0.0.0/8"
+}
+
+deny "rest" {
+  log = true
+}
+`
+	config := &unionConfig{}
+	require.NoError(t, Unmarshal([]byte(source), config))
+	require.Equal(t, &unionConfig{Rules: []unionRule{
+		allowRule{Name: "office", CIDR: "10.0.0.0/8"},
+		&denyRule{Name: "rest", Log: true},
+	}}, config)
+
+	data, err := Marshal(config)
+	require.NoError(t, err)
+	require.Equal(t, source, string(data))
+
+	err = Unmarshal([]byte(`rule "x" {}`), &unionConfig{})
+	require.EqualError(t, err, `1:1: rule: unknown unionRule block "rule", expected one of allow, deny`)
+
+	_, err = Marshal(&unionConfig{Rules: []unionRule{logRule{}}})
+	require.EqualError(t, err, `rule[0]: hcl.logRule is not a registered unionRule type`)
+
+	single := &struct {
+		Default unionRule `hcl:"default,block"`
+	}{}
+	require.NoError(t, Unmarshal([]byte(`deny "all" {}`), single))
+	require.Equal(t, &denyRule{Name: "all"}, single.Default)
+	err = Unmarshal([]byte("deny \"a\" {}\nallow \"b\" {\n  cidr = \"\"\n}\n"), single)
+	require.EqualError(t, err, `2:1: default: duplicate field "default" at 1:1`)
+
+	schema, err := Schema(&unionConfig{})
+	require.NoError(t, err)
+	data, err = MarshalAST(schema)
+	require.NoError(t, err)
+	require.Equal(t, `allow "name" { // (repeated)
+  cidr = string
+}
+
+deny "name" { // (repeated)
+  log = boolean // (optional)
+}
+`, string(data))
+}
@@ -0,0 +1,132 @@
+package hcl
+
+import (
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/alecthomas/participle"
+)
+
+var (
+	numberUnitRe = regexp.MustCompile(`^(` + numberPattern + `)([[:alpha:]]*)$`)
+	byteSizeType = reflect.TypeOf(ByteSize(0))
+)
+
+// splitUnit splits a number literal into the number and its unit, if any.
+func splitUnit(literal string) (number, unit string) {
+	match := numberUnitRe.FindStringSubmatch(literal)
+	if match == nil {
+		return literal, ""
+	}
+	return match[1], match[len(match)-1]
+}
+
+// ByteSize is a number of bytes, decoded from a number with a unit such as
+// 512MB or 1GiB, or from a number without a unit, in bytes.
+//
+// Units are B, KB, MB, GB, TB and PB in powers of 1000, and KiB, MiB, GiB,
+// TiB and PiB in powers of 1024.
+type ByteSize int64
+
+// Sizes of the units of ByteSize.
+const (
+	Byte     ByteSize = 1
+	Kilobyte          = 1000 * Byte
+	Megabyte          = 1000 * Kilobyte
+	Gigabyte          = 1000 * Megabyte
+	Terabyte          = 1000 * Gigabyte
+	Petabyte          = 1000 * Terabyte
+	Kibibyte          = 1024 * Byte
+	Mebibyte          = 1024 * Kibibyte
+	Gibibyte          = 1024 * Mebibyte
+	Tebibyte          = 1024 * Gibibyte
+	Pebibyte          = 1024 * Tebibyte
+)
+
+// byteUnits in decreasing order of size.
+var byteUnits = []struct {
+	unit string
+	size ByteSize
+}{
+	{"PiB", Pebibyte}, {"PB", Petabyte},
+	{"TiB", Tebibyte}, {"TB", Terabyte},
+	{"GiB", Gibibyte}, {"GB", Gigabyte},
+	{"MiB", Mebibyte}, {"MB", Megabyte},
+	{"KiB", Kibibyte}, {"KB", Kilobyte},
+	{"B", Byte},
+}
+
+// split returns b as a number of the largest unit it is a whole multiple of.
+func (b ByteSize) split() (int64, string) {
+	if b == 0 {
+		return 0, "B"
+	}
+	for _, u := range byteUnits {
+		if b%u.size == 0 {
+			return int64(b / u.size), u.unit
+		}
+	}
+	return int64(b), "B"
+}
+
+func (b ByteSize) String() string {
+	n, unit := b.split()
+	return strconv.FormatInt(n, 10) + unit
+}
+
+// unmarshalUnitValue decodes a number with a unit, which is only valid for
+// time.Duration and ByteSize fields.
+func unmarshalUnitValue(rv reflect.Value, v *Value, opt *marshalOptions) error {
+	number := v.Number.Text('f', -1)
+	switch {
+	case rv.Type() == durationType:
+		var (
+			d   time.Duration
+			err error
+		)
+		if opt.extendedDurations {
+			d, err = parseExtendedDuration(number + v.Unit)
+		} else {
+			d, err = time.ParseDuration(number + v.Unit)
+		}
+		if err != nil {
+			return participle.Wrapf(v.Pos, err, "invalid duration")
+		}
+		rv.SetInt(int64(d))
+		return nil
+
+	case rv.Type() == byteSizeType:
+		for _, u := range byteUnits {
+			if u.unit != v.Unit {
+				continue
+			}
+			size := new(big.Float).Mul(v.Number, new(big.Float).SetInt64(int64(u.size)))
+			if !size.IsInt() {
+				return participle.Errorf(v.Pos, "%s is not a whole number of bytes", v)
+			}
+			n, accuracy := size.Int64()
+			if accuracy != big.Exact {
+				return participle.Errorf(v.Pos, "value %s is out of range for %s", v, rv.Type())
+			}
+			rv.SetInt(n)
+			return nil
+		}
+		return participle.Errorf(v.Pos, "unknown unit %q in %s", v.Unit, v)
+
+	case rv.Kind() == reflect.Interface && rv.NumMethod() == 0:
+		rv.Set(reflect.ValueOf(v.String()))
+		return nil
+
+	default:
+		return participle.Errorf(v.Pos, "unexpected unit %q in %s for %s", v.Unit, v, rv.Type())
+	}
+}
+
+// byteSizeToValue converts a ByteSize to a number with a unit.
+func byteSizeToValue(b ByteSize) *Value {
+	n, unit := b.split()
+	return &Value{Number: new(big.Float).SetInt64(n), Unit: unit}
+}
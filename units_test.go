@@ -0,0 +1,83 @@
+package hcl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnits(t *testing.T) {
+	ast, err := ParseString(`
+timeout = 30s
+interval = 1.5h
+mem = 512MB
+cache = 0x10MiB
+sizes = [1GB, 2KiB]
+`)
+	require.NoError(t, err)
+	value := ast.Entries[0].Attribute.Value
+	require.Equal(t, "s", value.Unit)
+	require.Equal(t, "30", value.Number.String())
+	require.Equal(t, "0x10", ast.Entries[3].Attribute.Value.Literal)
+	require.Equal(t, "MiB", ast.Entries[3].Attribute.Value.Unit)
+
+	out, err := MarshalAST(ast)
+	require.NoError(t, err)
+	require.Equal(t, `timeout = 30s
+interval = 1.5h
+mem = 512MB
+cache = 0x10MiB
+sizes = [1GB, 2KiB]
+`, string(out))
+
+	var config struct {
+		Timeout  time.Duration `hcl:"timeout"`
+		Interval time.Duration `hcl:"interval"`
+		Mem      ByteSize      `hcl:"mem"`
+		Cache    ByteSize      `hcl:"cache"`
+		Sizes    []ByteSize    `hcl:"sizes"`
+	}
+	err = UnmarshalAST(ast, &config)
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, config.Timeout)
+	require.Equal(t, 90*time.Minute, config.Interval)
+	require.Equal(t, 512*Megabyte, config.Mem)
+	require.Equal(t, 16*Mebibyte, config.Cache)
+	require.Equal(t, []ByteSize{Gigabyte, 2 * Kibibyte}, config.Sizes)
+
+	config.Timeout = time.Minute
+	config.Mem = 1536 * Mebibyte
+	out, err = Marshal(&config)
+	require.NoError(t, err)
+	require.Equal(t, `timeout = "1m0s"
+interval = "1h30m0s"
+mem = 1536MiB
+cache = 16MiB
+sizes = [1GB, 2KiB]
+`, string(out))
+}
+
+func TestUnitErrors(t *testing.T) {
+	var config struct {
+		Count int           `hcl:"count,optional"`
+		Mem   ByteSize      `hcl:"mem,optional"`
+		Wait  time.Duration `hcl:"wait,optional"`
+	}
+	err := Unmarshal([]byte(`count = 3s`), &config)
+	require.EqualError(t, err, `1:9: count: unexpected unit "s" in 3s for int`)
+	err = Unmarshal([]byte(`mem = 3parsecs`), &config)
+	require.EqualError(t, err, `1:7: mem: unknown unit "parsecs" in 3parsecs`)
+	err = Unmarshal([]byte(`mem = 1.5B`), &config)
+	require.EqualError(t, err, `1:7: mem: 1.5B is not a whole number of bytes`)
+	err = Unmarshal([]byte(`wait = 3MB`), &config)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "1:8: wait: invalid duration")
+}
+
+func TestByteSizeString(t *testing.T) {
+	require.Equal(t, "0B", ByteSize(0).String())
+	require.Equal(t, "1000KiB", ByteSize(1024000).String())
+	require.Equal(t, "1023B", ByteSize(1023).String())
+	require.Equal(t, "2GB", (2 * Gigabyte).String())
+}
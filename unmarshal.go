@@ -1,10 +1,17 @@
 package hcl
 
 import (
+	"context"
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math"
+	"math/big"
+	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -22,28 +29,75 @@ var (
 	remainType               = reflect.TypeOf([]*Entry{})
 	durationType             = reflect.TypeOf(time.Duration(0))
 	timeType                 = reflect.TypeOf(time.Time{})
+	bytesType                = reflect.TypeOf([]byte(nil))
+	fileModeType             = reflect.TypeOf(os.FileMode(0))
 )
 
 // Unmarshal HCL into a Go struct.
 func Unmarshal(data []byte, v interface{}, options ...MarshalOption) error {
-	ast, err := ParseBytes(data)
+	ast, err := ParseBytes(data, newMarshalOptions(options...).parseOptions()...)
 	if err != nil {
 		return err
 	}
 	return UnmarshalAST(ast, v, options...)
 }
 
+// UnmarshalContext unmarshals HCL into a Go struct, passing ctx to any value sources.
+//
+// If ctx is cancelled or its deadline expires, decoding is aborted with
+// ctx.Err() before the next field is decoded.
+func UnmarshalContext(ctx context.Context, data []byte, v interface{}, options ...MarshalOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ast, err := ParseBytes(data, newMarshalOptions(options...).parseOptions()...)
+	if err != nil {
+		return err
+	}
+	return UnmarshalAST(ast, v, append([]MarshalOption{withContext(ctx)}, options...)...)
+}
+
 // UnmarshalAST unmarshalls an already parsed or constructed AST into a Go struct.
+//
+// A document containing only attributes may also be unmarshalled into a map
+// with string keys, such as a map[string]interface{}.
 func UnmarshalAST(ast *AST, v interface{}, options ...MarshalOption) error {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr {
 		return fmt.Errorf("%T must be a pointer", v)
 	}
+	if rv.IsNil() {
+		return fmt.Errorf("can't unmarshal into nil %T", v)
+	}
 	opt := &marshalOptions{}
 	for _, option := range options {
 		option(opt)
 	}
-	return unmarshalEntries(rv.Elem(), ast.Entries, opt)
+	if opt.metrics == nil {
+		return unmarshalAST(rv, ast, opt)
+	}
+	start := time.Now()
+	err := unmarshalAST(rv, ast, opt)
+	opt.reportDecode(start, ast, err)
+	return err
+}
+
+func unmarshalAST(rv reflect.Value, ast *AST, opt *marshalOptions) error {
+	ast, err := migrateSchemaVersion(ast, opt)
+	if err != nil {
+		return err
+	}
+	entries, err := preprocessEntries(ast.Entries, opt)
+	if err != nil {
+		return err
+	}
+	if rv.Elem().Kind() == reflect.Map {
+		return unmarshalMapDocument(rv.Elem(), entries, opt)
+	}
+	if err := unmarshalEntries(rv.Elem(), entries, opt); err != nil {
+		return err
+	}
+	return resolveFallbacks(rv.Elem(), opt)
 }
 
 // UnmarshalBlock into a struct.
@@ -57,21 +111,168 @@ func UnmarshalBlock(block *Block, v interface{}, options ...MarshalOption) error
 	for _, option := range options {
 		option(opt)
 	}
-	return unmarshalBlock(rv, block, opt)
+	if opt.metrics == nil {
+		return unmarshalRootBlock(rv, block, opt)
+	}
+	start := time.Now()
+	err := unmarshalRootBlock(rv, block, opt)
+	opt.reportDecode(start, block, err)
+	return err
+}
+
+func unmarshalRootBlock(rv reflect.Value, block *Block, opt *marshalOptions) error {
+	block, err := preprocessBlock(block, opt)
+	if err != nil {
+		return err
+	}
+	if err := unmarshalBlock(rv, block, opt); err != nil {
+		return err
+	}
+	return resolveFallbacks(rv, opt)
 }
 
-func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) error {
+// UnmarshalBlocks unmarshals each top-level block in data with the given name
+// into an element of the slice out points to, eg. a *[]Service or a
+// *[]*Service, ignoring the rest of the document.
+//
+// This avoids declaring a root struct for tools that only process one kind of
+// block. As with block fields, out is replaced unless AppendSlices is set.
+func UnmarshalBlocks(data []byte, name string, out interface{}, options ...MarshalOption) error {
+	ast, err := ParseBytes(data, newMarshalOptions(options...).parseOptions()...)
+	if err != nil {
+		return err
+	}
+	return UnmarshalBlocksAST(ast, name, out, options...)
+}
+
+// UnmarshalBlocksAST is UnmarshalBlocks for an already parsed or constructed AST.
+func UnmarshalBlocksAST(ast *AST, name string, out interface{}, options ...MarshalOption) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%T must be a pointer to a slice of structs", out)
+	}
+	slice := rv.Elem()
+	elt := slice.Type().Elem()
+	ptr := elt.Kind() == reflect.Ptr
+	if ptr {
+		elt = elt.Elem()
+	}
+	if elt.Kind() != reflect.Struct {
+		return fmt.Errorf("%T must be a pointer to a slice of structs", out)
+	}
+	opt := newMarshalOptions(options...)
+	ast, err := migrateSchemaVersion(ast, opt)
+	if err != nil {
+		return err
+	}
+	entries, err := preprocessEntries(ast.Entries, opt)
+	if err != nil {
+		return err
+	}
+	if !opt.appendSlices {
+		slice.Set(reflect.Zero(slice.Type()))
+	}
+	for _, entry := range entries {
+		if entry.Block == nil || entry.Block.Name != name {
+			continue
+		}
+		el := reflect.New(elt)
+		if err := unmarshalBlock(el.Elem(), entry.Block, opt); err != nil {
+			return withFieldPath(fmt.Sprintf("%s[%d]", name, slice.Len()), annotateFieldError(entry.Pos, err))
+		}
+		if err := resolveFallbacks(el.Elem(), opt); err != nil {
+			return err
+		}
+		if !ptr {
+			el = el.Elem()
+		}
+		slice.Set(reflect.Append(slice, el))
+	}
+	return nil
+}
+
+// DecodeBlock unmarshals the single top-level block in ast with the given name
+// and labels into a struct, ignoring the rest of the document.
+//
+// If the struct has no label fields, the block's labels are not decoded.
+func DecodeBlock(ast *AST, name string, labels []string, v interface{}, options ...MarshalOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T must be a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	opt := &marshalOptions{}
+	for _, option := range options {
+		option(opt)
+	}
+	ast, err := migrateSchemaVersion(ast, opt)
+	if err != nil {
+		return err
+	}
+	entries, err := preprocessEntries(ast.Entries, opt)
+	if err != nil {
+		return err
+	}
+	id := name
+	for _, label := range labels {
+		id += " " + strconv.Quote(label)
+	}
+	var block *Block
+	for _, entry := range entries {
+		if entry.Block == nil || entry.Block.Name != name || !stringsEqual(entry.Block.Labels, labels) {
+			continue
+		}
+		if block != nil {
+			return participle.Errorf(entry.Pos, "duplicate block %s", id)
+		}
+		block = entry.Block
+	}
+	if block == nil {
+		return fmt.Errorf("block %s not found", id)
+	}
+	hasLabels := false
+	fields, err := flattenFields(rv, opt)
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if parseTag(rv.Type(), field, opt).label {
+			hasLabels = true
+		}
+	}
+	if !hasLabels {
+		unlabelled := *block
+		unlabelled.Labels = nil
+		block = &unlabelled
+	}
+	if err := unmarshalBlock(rv, block, opt); err != nil {
+		return err
+	}
+	return resolveFallbacks(rv, opt)
+}
+
+func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) (err error) {
+	// Name of the field being decoded, to prefix errors with.
+	fieldName := ""
+	defer func() {
+		if err != nil && fieldName != "" {
+			err = withFieldPath(fieldName, err)
+		}
+	}()
 	if v.Kind() != reflect.Struct {
 		return fmt.Errorf("%T must be a struct", v.Interface())
 	}
+	partial := opt.partial || opt.partialRoot
+	opt.partialRoot = false
+	vt := v.Type()
 	// Collect entries from the source into a map.
 	seen := map[string]*Entry{}
 	mentries := make(map[string][]*Entry, len(entries))
 	for _, entry := range entries {
 		key := entry.Key()
 		existing, ok := mentries[key]
-		if ok {
-			// Mismatch in type.
+		// Mismatch in type, unless the field converts between the two.
+		if ok && !opt.mixedBlockSyntax {
 			if !((existing[0].Block == nil) == (entry.Block == nil)) {
 				return participle.Errorf(existing[0].Pos, "%s: %s cannot be both block and attribute", entry.Pos, key)
 			}
@@ -80,12 +281,23 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 		seen[key] = entry
 	}
 	// Collect the fields of the target struct.
-	fields, err := flattenFields(v)
+	fields, err := flattenFields(v, opt)
 	if err != nil {
 		return err
 	}
+	// The first entry set for each oneof group.
+	oneofs := map[string]*Entry{}
+	// The first entry set for each field, and fields with dependencies on others.
+	present := map[string]*Entry{}
+	dependent := []dependentField{}
+	// Fields of type Blocks, populated with unclaimed blocks.
+	blocksFields := []field{}
 	// Apply HCL entries to our fields.
 	for _, field := range fields {
+		fieldName = ""
+		if err := opt.checkContext(); err != nil {
+			return err
+		}
 		tag := parseTag(v.Type(), field, opt) // nolint: govet
 		switch {
 		case tag.name == "":
@@ -95,9 +307,14 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 			delete(seen, tag.name)
 			continue
 
+		case tag.remain && field.t.Type == blocksType:
+			// Populated once all other fields have claimed their blocks.
+			blocksFields = append(blocksFields, field)
+			continue
+
 		case tag.remain:
 			if field.t.Type != remainType {
-				panic(fmt.Sprintf("\"remain\" field %q must be of type []*hcl.Entry but is %T", field.t.Name, field.t.Type))
+				panic(fmt.Sprintf("\"remain\" field %q must be of type []*hcl.Entry or hcl.Blocks but is %T", field.t.Name, field.t.Type))
 			}
 			remaining := []*Entry{}
 			for _, entries := range mentries {
@@ -110,11 +327,99 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 			return nil
 		}
 
+		// Map block fields are checked whether or not there are blocks to decode
+		// into them, so that a bad type fails on any input.
+		if ft := field.t.Type; tag.block {
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Map {
+				if err := checkBlockMapType(ft); err != nil {
+					return withFieldPath(tag.name, err)
+				}
+			}
+		}
+
+		// Merge entries using legacy names, or names differing only in case when
+		// matching case-insensitively, into those for the canonical name, in source order.
+		// Blocks named after the members of a union are merged likewise.
+		if len(tag.aliases) > 0 || opt.caseInsensitive || tag.union != nil {
+			merged := []*Entry{}
+			// Union blocks already claimed by another field are skipped.
+			unclaimed := map[string]bool{}
+			for key := range mentries {
+				unclaimed[key] = true
+			}
+			for _, entry := range entries {
+				key := entry.Key()
+				if key == tag.name {
+					merged = append(merged, entry)
+					continue
+				}
+				matched := (opt.caseInsensitive && strings.EqualFold(key, tag.name)) ||
+					(tag.union != nil && entry.Block != nil && unclaimed[key] && tag.union.has(key))
+				for _, alias := range tag.aliases {
+					if key == alias || (opt.caseInsensitive && strings.EqualFold(key, alias)) {
+						opt.warn(entry.Pos, "%q is an alias for %q", key, tag.name)
+						matched = true
+						break
+					}
+				}
+				if !matched {
+					continue
+				}
+				merged = append(merged, entry)
+				delete(mentries, key)
+				delete(seen, key)
+				seen[tag.name] = entry
+			}
+			mentries[tag.name] = merged
+		}
+
 		haventSeen := seen[tag.name] == nil
 		entries := mentries[tag.name]
+		if opt.mixedBlockSyntax {
+			entries, err = mixBlockSyntax(field, tag, entries)
+			if err != nil {
+				return err
+			}
+		}
+		if len(entries) == 0 && !tag.optional && haventSeen && !partial {
+			return fmt.Errorf("missing required attribute %q", tag.name)
+		}
+		if tag.readonly && len(entries) > 0 {
+			return participle.Errorf(entries[0].Pos, "attribute %q is read-only", tag.name)
+		}
+		if tag.block && !partial {
+			if err := checkOccurrences(tag, entries, opt.blockPos); err != nil {
+				return err
+			}
+		}
+		if tag.oneof != "" && len(entries) > 0 {
+			if other, ok := oneofs[tag.oneof]; ok {
+				first, second := sourceOrder(other, entries[0])
+				return participle.Errorf(second.Pos, "%q conflicts with %q at %s, only one of group %q may be set",
+					second.Key(), first.Key(), first.Pos, tag.oneof)
+			}
+			oneofs[tag.oneof] = entries[0]
+		}
+		present[tag.name] = nil
+		if len(entries) > 0 {
+			present[tag.name] = entries[0]
+		}
+		if len(tag.requiredWith) > 0 || len(tag.conflictsWith) > 0 {
+			dependent = append(dependent, dependentField{field: field, tag: tag})
+		}
+		fieldName = tag.name
 		if len(entries) == 0 {
-			if !tag.optional && haventSeen {
-				return fmt.Errorf("missing required attribute %q", tag.name)
+			// Values already present in the target, eg. defaults set before
+			// unmarshalling, take precedence over defaults from tags.
+			if !field.v.IsZero() {
+				opt.traceField(TraceSkipped, vt, field.t, lexer.Position{}, nil, tag.name)
+				continue
+			}
+			if tag.defaultFrom != "" {
+				opt.fallbacks = append(opt.fallbacks, &fallback{target: field.v, name: tag.name, path: tag.defaultFrom})
 			}
 			// apply defaults here as there's no value for this field
 			v, err := defaultValueFromTag(field, tag.defaultValue)
@@ -122,25 +427,56 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 				return err
 			}
 			if v != nil {
+				v, err = resolveValueSources(v, opt)
+				if err != nil {
+					return err
+				}
 				// check enum before assigning default value
 				err := checkEnum(v, field, tag.enum)
 				if err != nil {
 					return fmt.Errorf("default value conflicts with enum: %v", err)
 				}
-				err = unmarshalValue(field.v, v)
+				if err := checkConstraints(v, tag); err != nil {
+					return fmt.Errorf("invalid default value: %v", err)
+				}
+				err = unmarshalValue(field.v, v, opt)
 				if err != nil {
 					return fmt.Errorf("error applying default value to field %q, %v", field.t.Name, err)
 				}
+				opt.traceField(TraceDefaulted, vt, field.t, lexer.Position{}, v, tag.name)
+			} else {
+				opt.traceField(TraceSkipped, vt, field.t, lexer.Position{}, nil, tag.name)
 			}
 
 			continue
 		}
 		delete(seen, tag.name)
+		for _, entry := range entries {
+			if opt.tracer == nil {
+				break
+			}
+			var value *Value
+			if entry.Attribute != nil {
+				value = entry.Attribute.Value
+			}
+			opt.traceField(TraceMatched, vt, field.t, entry.Pos, value, tag.name)
+		}
 
 		entry := entries[0]
 		entries = entries[1:]
 		mentries[tag.name] = entries
 
+		var val *Value
+		if entry.Attribute != nil {
+			if tag.deprecated != "" {
+				opt.warn(entry.Pos, "attribute %q is deprecated: %s", tag.name, tag.deprecated)
+			}
+			val, err = resolveValueSources(entry.Attribute.Value, opt)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Field is a pointer, create value if necessary, then move field down.
 		if field.v.Kind() == reflect.Ptr {
 			if field.v.IsNil() {
@@ -152,23 +488,46 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 
 		// Check for unmarshaler interfaces and other special cases.
 		if entry.Attribute != nil {
-			val := entry.Attribute.Value
-			if uv, ok := implements(field.v, jsonUnmarshalerInterface); ok {
+			if ok, err := decodeScalar(field.v, val, opt); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			} else if ok, err := decodeContextUnmarshaler(field.v, val, opt); ok {
+				if err != nil {
+					return err
+				}
+				continue
+			} else if uv, ok := implements(field.v, jsonUnmarshalerInterface); ok {
 				err := uv.Interface().(json.Unmarshaler).UnmarshalJSON([]byte(val.String()))
 				if err != nil {
 					return participle.Wrapf(val.Pos, err, "invalid value")
 				}
 				continue
 			} else if uv, ok := implements(field.v, textUnmarshalerInterface); ok {
+				if val.Str == nil {
+					return participle.Errorf(val.Pos, "expected a string but got %s", val)
+				}
 				err := uv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(*val.Str))
 				if err != nil {
 					return participle.Wrapf(val.Pos, err, "invalid value")
 				}
 				continue
-			} else if entry.Attribute.Value.Str != nil {
+			} else if field.v.Type() == pathType {
+				err := unmarshalValue(field.v, val, opt)
+				if err != nil {
+					return err
+				}
+				continue
+			} else if val.Str != nil {
 				switch field.v.Interface().(type) {
 				case time.Duration:
-					d, err := time.ParseDuration(*val.Str)
+					var d time.Duration
+					if opt.extendedDurations {
+						d, err = parseExtendedDuration(*val.Str)
+					} else {
+						d, err = time.ParseDuration(*val.Str)
+					}
 					if err != nil {
 						return participle.Wrapf(val.Pos, err, "invalid duration")
 					}
@@ -182,21 +541,53 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 					}
 					field.v.Set(reflect.ValueOf(t))
 					continue
+
+				case []byte:
+					b, err := decodeBytes(*val.Str, tag.encoding)
+					if err != nil {
+						return participle.Wrapf(val.Pos, err, "invalid bytes")
+					}
+					field.v.SetBytes(b)
+					continue
 				}
 			}
 		}
 
+		if tag.union != nil {
+			mentries[tag.name] = nil
+			if err := unmarshalUnion(field.v, tag, tag.union, append([]*Entry{entry}, entries...), opt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Map of blocks, keyed by their first label.
+		if tag.block && field.v.Kind() == reflect.Map {
+			mentries[tag.name] = nil
+			if err := unmarshalBlockMap(field.v, tag, append([]*Entry{entry}, entries...), opt); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch field.v.Kind() {
 		case reflect.Struct:
 			if len(entries) > 0 {
 				return participle.Errorf(entry.Pos, "duplicate field %q at %s", entry.Key(), entry.Pos)
 			}
+			block := entry.Block
 			if entry.Attribute != nil {
-				return participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", tag.name)
+				if !tag.inline {
+					return participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", tag.name)
+				}
+				block, err = objectToBlock(tag.name, val)
+				if err != nil {
+					return err
+				}
 			}
-			err := unmarshalBlock(field.v, entry.Block, opt)
+			err := unmarshalBlock(field.v, block, opt)
 			if err != nil {
-				return participle.AnnotateError(entry.Pos, err)
+				return annotateFieldError(entry.Pos, err)
 			}
 
 		case reflect.Slice:
@@ -208,22 +599,31 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 				ptr = true
 			}
 
-			if elt.Kind() == reflect.Struct {
+			// Lists of blocks or objects, eg. `rules = [rule "a" {}]`, are
+			// decoded as values.
+			listOfBlocks := entry.Attribute != nil && val.HaveList
+			if !listOfBlocks && elt.Kind() == reflect.Struct && elt != pathType && !typeImplements(elt, textUnmarshalerInterface) && opt.lookupScalar(elt).decode == nil {
 				mentries[field.t.Name] = nil
 				entries = append([]*Entry{entry}, entries...)
-				for _, entry := range entries {
+				if !opt.appendSlices {
+					field.v.Set(reflect.Zero(field.v.Type()))
+				}
+				// Grow the slice up front so elements are decoded in place.
+				start := field.v.Len()
+				field.v.Set(reflect.AppendSlice(field.v, reflect.MakeSlice(field.v.Type(), len(entries), len(entries))))
+				for i, entry := range entries {
 					if entry.Attribute != nil {
 						return participle.Errorf(entry.Pos, "expected a block for %q but got an attribute", tag.name)
 					}
-					el := reflect.New(elt).Elem()
+					el := field.v.Index(start + i)
+					if ptr {
+						el.Set(reflect.New(elt))
+						el = el.Elem()
+					}
 					err := unmarshalBlock(el, entry.Block, opt)
 					if err != nil {
-						return participle.AnnotateError(entry.Pos, err)
-					}
-					if ptr {
-						el = el.Addr()
+						return withFieldPath(fmt.Sprintf("[%d]", start+i), annotateFieldError(entry.Pos, err))
 					}
-					field.v.Set(reflect.Append(field.v, el))
 				}
 				continue
 			}
@@ -237,33 +637,151 @@ func unmarshalEntries(v reflect.Value, entries []*Entry, opt *marshalOptions) er
 			if entry.Block != nil {
 				return participle.Errorf(entry.Pos, "expected an attribute for %q but got a block", tag.name)
 			}
-			value := entry.Attribute.Value
+			value := val
+			if opt.weakTypes {
+				value = weakenValue(field.v.Type(), value)
+				if value != val {
+					opt.traceField(TraceCoerced, vt, field.t, value.Pos, value, tag.name)
+				}
+			}
 			// check enum before unmarshalling actual value
 			err := checkEnum(value, field, tag.enum)
 			if err != nil {
 				return err
 			}
-			err = unmarshalValue(field.v, value)
+			if err := checkConstraints(value, tag); err != nil {
+				return err
+			}
+			if tag.set {
+				value, err = uniqueValues(value, tag)
+				if err != nil {
+					return err
+				}
+			}
+			err = unmarshalValue(field.v, value, opt)
 			if err != nil {
-				return participle.AnnotateError(value.Pos, err)
+				return annotateFieldError(value.Pos, err)
 			}
 		}
 	}
+	fieldName = ""
+	if partial {
+		dependent = nil
+	}
+	if err := checkDependencies(v.Type(), dependent, present); err != nil {
+		return err
+	}
 
+	// Blocks not claimed by a field may be claimed by a registered decoder.
 	if len(seen) > 0 {
+		claimed := map[string]bool{}
+		for _, entry := range entries {
+			if entry.Block == nil || seen[entry.Key()] == nil {
+				continue
+			}
+			decoder := opt.lookupBlockDecoder(entry.Block.Name)
+			if decoder == nil {
+				continue
+			}
+			if err := decoder(entry.Block); err != nil {
+				return participle.AnnotateError(entry.Pos, err)
+			}
+			claimed[entry.Block.Name] = true
+		}
+		for key := range claimed {
+			delete(seen, key)
+		}
+	}
+	for _, field := range blocksFields {
+		if blocks := collectBlocks(entries, seen); len(blocks) > 0 {
+			field.v.Set(reflect.ValueOf(blocks))
+		}
+	}
+
+	if len(seen) > 0 && opt.tracer != nil {
+		keys := make([]string, 0, len(seen))
+		for key := range seen {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			opt.trace(TraceEvent{Kind: TraceSkipped, Pos: seen[key].Pos}, nil, key)
+		}
+	}
+	if len(seen) > 0 {
+		keys := make([]string, 0, len(seen))
+		for key := range seen {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var known []string
+		for _, key := range keys {
+			entry := seen[key]
+			code := CodeIgnoredAttribute
+			if entry.Block != nil {
+				code = CodeIgnoredBlock
+			}
+			if !isSuppressed(entry, code) {
+				continue
+			}
+			delete(seen, key)
+			if opt.suppressed != nil {
+				if known == nil {
+					known = fieldNames(vt, fields, opt)
+				}
+				opt.suppressed(ignoredDiagnostic(strings.Join(opt.path, "."), entry, known, opt.messages))
+			}
+		}
+	}
+	if len(seen) > 0 && opt.unused != nil {
+		keys := make([]string, 0, len(seen))
+		for key := range seen {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			path := append(append([]string{}, opt.path...), key)
+			opt.unused(strings.Join(path, "."), seen[key].Pos)
+		}
+		return nil
+	}
+	if len(seen) > 0 {
+		keys := make([]string, 0, len(seen))
+		for key := range seen {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		known := fieldNames(vt, fields, opt)
 		need := []string{}
 		var pos *lexer.Position
-		for key, entry := range seen {
-			if pos == nil {
+		for _, key := range keys {
+			if entry := seen[key]; pos == nil || entry.Pos.Offset < pos.Offset {
 				pos = &entry.Pos
 			}
-			need = append(need, strconv.Quote(key))
+			if suggestion, ok := suggest(key, known); ok {
+				need = append(need, fmt.Sprintf("%q (did you mean %q?)", key, suggestion))
+			} else {
+				need = append(need, strconv.Quote(key))
+			}
 		}
 		return participle.Errorf(*pos, "found extra fields %s", strings.Join(need, ", "))
 	}
 	return nil
 }
 
+// decodeBytes decodes a string attribute into a []byte using the encoding from
+// an encoding:"" tag, defaulting to base64.
+func decodeBytes(s string, enc string) ([]byte, error) {
+	switch enc {
+	case "", "base64":
+		return base64.StdEncoding.DecodeString(s)
+	case "hex":
+		return hex.DecodeString(s)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", enc)
+	}
+}
+
 func checkEnum(v *Value, f field, enum string) error { // nolint: interfacer
 	if enum == "" {
 		return nil
@@ -294,34 +812,197 @@ func checkEnum(v *Value, f field, enum string) error { // nolint: interfacer
 	}
 }
 
+// uniqueValues checks that the elements of a list for a "set" field are unique,
+// or removes duplicates if the field is tagged with "dedupe".
+func uniqueValues(v *Value, tag tag) (*Value, error) {
+	if !v.HaveList {
+		return v, nil
+	}
+	seen := map[string]bool{}
+	out := make([]*Value, 0, len(v.List))
+	for _, el := range v.List {
+		key := el.String()
+		if seen[key] {
+			if tag.dedupe {
+				continue
+			}
+			return nil, participle.Errorf(el.Pos, "duplicate value %s in set %q", key, tag.name)
+		}
+		seen[key] = true
+		out = append(out, el)
+	}
+	if len(out) == len(v.List) {
+		return v, nil
+	}
+	dedupe := *v
+	dedupe.List = out
+	return &dedupe, nil
+}
+
+// sortValues sorts a list of values into a deterministic order, numerically
+// if all elements are numbers.
+func sortValues(values []*Value) {
+	numeric := true
+	for _, v := range values {
+		if v.Number == nil {
+			numeric = false
+			break
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		if numeric {
+			return values[i].Number.Cmp(values[j].Number) < 0
+		}
+		return values[i].String() < values[j].String()
+	})
+}
+
 func unmarshalBlock(v reflect.Value, block *Block, opt *marshalOptions) error {
-	fields, err := flattenFields(v)
+	fields, err := flattenFields(v, opt)
 	if err != nil {
 		return participle.AnnotateError(block.Pos, err)
 	}
-	labels := block.Labels
+	labelFields := []field{}
+	labelNames := []string{}
+	labelTags := []string{}
 	for _, field := range fields {
 		tag := parseTag(v.Type(), field, opt) // nolint: govet
 		if tag.name == "" || !tag.label {
 			continue
 		}
-		if len(labels) == 0 {
-			return participle.Errorf(block.Pos, "missing label %q", tag.name)
+		labelFields = append(labelFields, field)
+		labelNames = append(labelNames, strconv.Quote(tag.name))
+		labelTags = append(labelTags, tag.name)
+	}
+	if len(block.Labels) != len(labelFields) {
+		noun := "labels"
+		if len(labelFields) == 1 {
+			noun = "label"
 		}
-		if field.v.Kind() != reflect.String {
-			panic("label field " + fieldID(v.Type(), field.t) + " must be a string")
+		names := ""
+		if len(labelNames) > 0 {
+			names = " (" + strings.Join(labelNames, ", ") + ")"
 		}
-		label := labels[0]
-		labels = labels[1:]
-		field.v.SetString(label)
+		return participle.Errorf(block.Pos, "block %q expects %d %s%s, got %d", block.Name, len(labelFields), noun, names, len(block.Labels))
+	}
+	depth, pos := len(opt.path), opt.blockPos
+	opt.path = append(append(opt.path, block.Name), block.Labels...)
+	opt.blockPos = block.Pos
+	defer func() { opt.path, opt.blockPos = opt.path[:depth], pos }()
+	if opt.blockContext != nil {
+		defer opt.enterBlockContext(block)()
 	}
-	if len(labels) > 0 {
-		return participle.Errorf(block.Pos, "too many labels for block %q", block.Name)
+	for i, field := range labelFields {
+		if err := unmarshalLabel(field.v, block.Labels[i]); err != nil {
+			return participle.Wrapf(block.Pos, err, "invalid label %q for block %q", block.Labels[i], block.Name)
+		}
+		if err := unmarshalLabelParts(v, fields, field, block, block.Labels[i], opt); err != nil {
+			return err
+		}
+		if opt.tracer != nil {
+			label := block.Labels[i]
+			opt.traceField(TraceMatched, v.Type(), field.t, block.Pos, &Value{Pos: block.Pos, Str: &label}, labelTags[i])
+		}
 	}
 	return unmarshalEntries(v, block.Body, opt)
 }
 
-func unmarshalValue(rv reflect.Value, v *Value) error {
+// unmarshalLabel converts a block label into a string, numeric, boolean or
+// encoding.TextUnmarshaler field.
+func unmarshalLabel(rv reflect.Value, label string) error {
+	if uv, ok := implements(rv, textUnmarshalerInterface); ok {
+		return uv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(label))
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(label)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(label, 0, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(label, 0, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(label, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(n)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(label)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return unmarshalLabel(rv.Elem(), label)
+
+	default:
+		return fmt.Errorf("unsupported label type %s", rv.Type())
+	}
+	return nil
+}
+
+func unmarshalValue(rv reflect.Value, v *Value, opt *marshalOptions) error {
+	if rv.Type() == tristateType {
+		if opt.weakTypes {
+			v = weakenValue(boolGoType, v)
+		}
+		return unmarshalTristate(rv, v)
+	}
+	if opt.weakTypes {
+		v = weakenValue(rv.Type(), v)
+	}
+	if ok, err := decodeScalar(rv, v, opt); ok {
+		return err
+	}
+	if ok, err := decodeContextUnmarshaler(rv, v, opt); ok {
+		return err
+	}
+	if v.Number != nil && v.Unit != "" {
+		return unmarshalUnitValue(rv, v, opt)
+	}
+	if rv.Type() == pathType {
+		if v.Str == nil {
+			return participle.Errorf(v.Pos, "expected a path but got %s", v)
+		}
+		p, err := NewPath(*v.Str, pathBaseDir(v, opt))
+		if err != nil {
+			return participle.Wrapf(v.Pos, err, "invalid path")
+		}
+		rv.Set(reflect.ValueOf(p))
+		return nil
+	}
+	if rv.Type() == numberType {
+		if v.Number == nil {
+			return participle.Errorf(v.Pos, "expected a number but got %s", v)
+		}
+		rv.SetString(v.String())
+		return nil
+	}
+	if uv, ok := implements(rv, textUnmarshalerInterface); ok && rv.Kind() != reflect.Ptr {
+		if v.Str == nil {
+			return participle.Errorf(v.Pos, "expected a string but got %s", v)
+		}
+		if err := uv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(*v.Str)); err != nil {
+			return participle.Wrapf(v.Pos, err, "invalid value")
+		}
+		return nil
+	}
 	switch rv.Kind() {
 	case reflect.String:
 		switch {
@@ -339,21 +1020,48 @@ func unmarshalValue(rv reflect.Value, v *Value) error {
 		if v.Number == nil {
 			return participle.Errorf(v.Pos, "expected a number but got %s", v)
 		}
-		n, _ := v.Number.Int64()
+		n, accuracy := v.Number.Int64()
+		if !opt.truncateNumbers {
+			if !v.Number.IsInt() {
+				return participle.Errorf(v.Pos, "expected an integer but got %s", v)
+			}
+			if accuracy != big.Exact || rv.OverflowInt(n) {
+				return participle.Errorf(v.Pos, "value %s is out of range for %s", v, rv.Type())
+			}
+		}
 		rv.SetInt(n)
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if v.Number == nil {
 			return participle.Errorf(v.Pos, "expected a number but got %s", v)
 		}
-		n, _ := v.Number.Uint64()
+		n, accuracy := v.Number.Uint64()
+		if !opt.truncateNumbers {
+			if !v.Number.IsInt() {
+				return participle.Errorf(v.Pos, "expected an integer but got %s", v)
+			}
+			if accuracy != big.Exact || rv.OverflowUint(n) {
+				return participle.Errorf(v.Pos, "value %s is out of range for %s", v, rv.Type())
+			}
+		}
 		rv.SetUint(n)
 
 	case reflect.Float32, reflect.Float64:
+		if v.Str != nil && opt.nonFiniteFloats == NonFiniteFloatsAsStrings {
+			n, err := strconv.ParseFloat(*v.Str, 64)
+			if err != nil || !(math.IsInf(n, 0) || math.IsNaN(n)) {
+				return participle.Errorf(v.Pos, `expected a number, "+Inf", "-Inf" or "NaN" but got %s`, v)
+			}
+			rv.SetFloat(n)
+			return nil
+		}
 		if v.Number == nil {
 			return participle.Errorf(v.Pos, "expected a number but got %s", v)
 		}
 		n, _ := v.Number.Float64()
+		if !opt.truncateNumbers && (math.IsInf(n, 0) || rv.OverflowFloat(n)) {
+			return participle.Errorf(v.Pos, "value %s is out of range for %s", v, rv.Type())
+		}
 		rv.SetFloat(n)
 
 	case reflect.Map:
@@ -364,7 +1072,10 @@ func unmarshalValue(rv reflect.Value, v *Value) error {
 		if t.Key().Kind() != reflect.String {
 			panic(fmt.Sprintf("map keys must be strings but we have %s", t.Key()))
 		}
-		rv.Set(reflect.MakeMap(t))
+		// Entries are merged into an existing map.
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(t))
+		}
 		for _, entry := range v.Map {
 			key := reflect.New(t.Key()).Elem()
 			value := reflect.New(t.Elem()).Elem()
@@ -376,35 +1087,62 @@ func unmarshalValue(rv reflect.Value, v *Value) error {
 			default:
 				panic(fmt.Errorf("map key must be a string or type but is %s", entry.Key))
 			}
-			err := unmarshalValue(value, entry.Value)
+			err := unmarshalValue(value, entry.Value, opt)
 			if err != nil {
-				return participle.Wrapf(entry.Value.Pos, err, "invalid map value")
+				return withFieldPath(fmt.Sprintf("[%q]", key), annotateFieldError(entry.Value.Pos, err))
 			}
 			rv.SetMapIndex(key, value)
 		}
 
 	case reflect.Slice:
+		if rv.Type() == bytesType && v.Str != nil {
+			b, err := decodeBytes(*v.Str, "")
+			if err != nil {
+				return participle.Wrapf(v.Pos, err, "invalid bytes")
+			}
+			rv.SetBytes(b)
+			return nil
+		}
 		if !v.HaveList {
 			return fmt.Errorf("expected a list but got %s", v)
 		}
 		t := rv.Type().Elem()
 		lv := reflect.MakeSlice(rv.Type(), 0, 4)
-		for _, entry := range v.List {
+		if opt.appendSlices {
+			lv = reflect.AppendSlice(lv, rv)
+		}
+		start := lv.Len()
+		for i, entry := range v.List {
 			value := reflect.New(t).Elem()
-			err := unmarshalValue(value, entry)
+			err := unmarshalValue(value, entry, opt)
 			if err != nil {
-				return participle.Wrapf(entry.Pos, err, "invalid list element")
+				return withFieldPath(fmt.Sprintf("[%d]", start+i), annotateFieldError(entry.Pos, err))
 			}
 			lv = reflect.Append(lv, value)
 		}
 		rv.Set(lv)
 
+	case reflect.Array:
+		// Fixed-size arrays are tuples, so each element is decoded independently.
+		if !v.HaveList {
+			return participle.Errorf(v.Pos, "expected a list but got %s", v)
+		}
+		if len(v.List) != rv.Len() {
+			return participle.Errorf(v.Pos, "expected a list of %d elements but got %d", rv.Len(), len(v.List))
+		}
+		for i, entry := range v.List {
+			err := unmarshalValue(rv.Index(i), entry, opt)
+			if err != nil {
+				return withFieldPath(fmt.Sprintf("[%d]", i), annotateFieldError(entry.Pos, err))
+			}
+		}
+
 	case reflect.Ptr:
 		if rv.IsNil() {
 			pv := reflect.New(rv.Type().Elem())
 			rv.Set(pv)
 		}
-		return unmarshalValue(rv.Elem(), v)
+		return unmarshalValue(rv.Elem(), v, opt)
 
 	case reflect.Bool:
 		if v.Bool == nil {
@@ -412,28 +1150,130 @@ func unmarshalValue(rv reflect.Value, v *Value) error {
 		}
 		rv.SetBool(bool(*v.Bool))
 
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			if v.Str == nil {
+				return participle.Errorf(v.Pos, "expected a time but got %s", v)
+			}
+			t, err := time.Parse(time.RFC3339, *v.Str)
+			if err != nil {
+				return participle.Wrapf(v.Pos, err, "invalid time")
+			}
+			rv.Set(reflect.ValueOf(t))
+			return nil
+		}
+		// Structs nested in lists may be blocks, eg. `[rule "a" {}]`.
+		if v.Block != nil {
+			return unmarshalBlock(rv, v.Block, opt)
+		}
+		// Structs nested in values are objects, eg. `{ cpu: 1 }`.
+		if !v.HaveMap {
+			return participle.Errorf(v.Pos, "expected a map but got %s", v)
+		}
+		block, err := objectToBlock(rv.Type().Name(), v)
+		if err != nil {
+			return err
+		}
+		return unmarshalBlock(rv, block, opt)
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return participle.Errorf(v.Pos, "can't unmarshal into non-empty interface %s", rv.Type())
+		}
+		generic, err := valueToInterface(v)
+		if err != nil {
+			return err
+		}
+		if generic == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+		} else {
+			rv.Set(reflect.ValueOf(generic))
+		}
+
 	default:
 		panic(rv.Kind().String())
 	}
 	return nil
 }
 
+// valueToInterface converts a Value to a generic Go value.
+//
+// Strings, heredocs and types become string, numbers become float64, booleans
+// become bool, lists become []interface{} and maps become map[string]interface{}.
+func valueToInterface(v *Value) (interface{}, error) {
+	switch {
+	case v.Str != nil:
+		return *v.Str, nil
+
+	case v.Type != nil:
+		return *v.Type, nil
+
+	case v.HeredocDelimiter != "":
+		return v.GetHeredoc(), nil
+
+	case v.Number != nil:
+		n, _ := v.Number.Float64()
+		return n, nil
+
+	case v.Bool != nil:
+		return bool(*v.Bool), nil
+
+	case v.HaveList:
+		out := make([]interface{}, 0, len(v.List))
+		for _, el := range v.List {
+			value, err := valueToInterface(el)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, value)
+		}
+		return out, nil
+
+	case v.HaveMap:
+		out := make(map[string]interface{}, len(v.Map))
+		for _, entry := range v.Map {
+			var key string
+			switch {
+			case entry.Key.Str != nil:
+				key = *entry.Key.Str
+			case entry.Key.Type != nil:
+				key = *entry.Key.Type
+			default:
+				return nil, participle.Errorf(entry.Key.Pos, "map key must be a string or type but is %s", entry.Key)
+			}
+			value, err := valueToInterface(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = value
+		}
+		return out, nil
+
+	default:
+		return nil, participle.Errorf(v.Pos, "invalid value")
+	}
+}
+
 type field struct {
 	t reflect.StructField
 	v reflect.Value
 }
 
-func flattenFields(v reflect.Value) ([]field, error) {
+func flattenFields(v reflect.Value, opt *marshalOptions) ([]field, error) {
 	out := []field{}
 	t := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Field(i)
 		ft := t.Field(i)
-		if ft.Anonymous {
+		squash := squashed(ft, opt)
+		if ft.Anonymous || squash {
 			if f.Kind() != reflect.Struct {
+				if squash {
+					return nil, fmt.Errorf("%s: squashed field must be a struct", ft.Name)
+				}
 				return nil, fmt.Errorf("%s: anonymous field must be a struct", ft.Name)
 			}
-			sub, err := flattenFields(f)
+			sub, err := flattenFields(f, opt)
 			if err != nil {
 				return nil, fmt.Errorf("%s: %s", ft.Name, err)
 			}
@@ -445,19 +1285,73 @@ func flattenFields(v reflect.Value) ([]field, error) {
 	return out, nil
 }
 
+// squashed returns true if the fields of struct field ft are spliced into
+// its parent, via the "squash" or "flatten" tag options.
+func squashed(ft reflect.StructField, opt *marshalOptions) bool {
+	tag, ok := ft.Tag.Lookup(opt.tagKey())
+	if !ok {
+		return false
+	}
+	for _, option := range strings.Split(tag, ",")[1:] {
+		if option == "squash" || option == "flatten" {
+			return true
+		}
+	}
+	return false
+}
+
 func fieldID(parent reflect.Type, t reflect.StructField) string {
 	return fmt.Sprintf("%s.%s.%s", parent.PkgPath(), parent.Name(), t.Name)
 }
 
 type tag struct {
-	name         string
-	optional     bool
-	label        bool
-	block        bool
-	remain       bool
+	name     string
+	optional bool
+	label    bool
+	block    bool
+	remain   bool
+	// Struct field that is marshalled as a map attribute rather than a block.
+	inline bool
+	// Attribute that is marshalled, but may not be set in input.
+	readonly bool
+	// String attribute that is encrypted when marshalled with a Keyring.
+	secret       bool
 	help         string
 	defaultValue string
+	defaultFrom  string
 	enum         string
+	encoding     string
+	deprecated   string
+	aliases      []string
+	// At most one field of a oneof group may be set.
+	oneof string
+	// Names of sibling fields that must be set, or must not be set, if this
+	// field is set.
+	requiredWith  []string
+	conflictsWith []string
+	// Constraints on string and numeric values.
+	pattern *regexp.Regexp
+	min     *big.Float
+	max     *big.Float
+	// Slice with unique, unordered elements. Duplicates are removed if dedupe is set.
+	set    bool
+	dedupe bool
+	// Base to format integers in when marshalling.
+	base int
+	// Style to quote strings in when marshalling.
+	quote QuoteStyle
+	// Emitted as a comment on the same line as the attribute.
+	trailingComment string
+	// Bounds on the number of occurrences of a slice of blocks, if non-zero.
+	minItems int
+	maxItems int
+	// Concrete block types of an interface field, registered with RegisterUnion.
+	union *union
+	// Composes a label from part fields, from the label:"" tag.
+	labelTemplate *labelTemplate
+	// Name of a part of a composed label. Parts aren't entries, so have no
+	// name.
+	part string
 }
 
 func (t tag) comments() []string {
@@ -470,24 +1364,79 @@ func (t tag) comments() []string {
 func parseTag(parent reflect.Type, f field, opt *marshalOptions) tag {
 	t := f.t
 	help := t.Tag.Get("help")
-	defaultValue := t.Tag.Get("default")
-	enum := t.Tag.Get("enum")
-	s, ok := t.Tag.Lookup("hcl")
+	var aliases []string
+	if alias := t.Tag.Get("alias"); alias != "" {
+		aliases = strings.Split(alias, ",")
+	}
+	var requiredWith, conflictsWith []string
+	if names := t.Tag.Get("required_with"); names != "" {
+		requiredWith = strings.Split(names, ",")
+	}
+	if names := t.Tag.Get("conflicts_with"); names != "" {
+		conflictsWith = strings.Split(names, ",")
+	}
+	// Options common to all attributes.
+	attr := tag{
+		help:         help,
+		defaultValue: t.Tag.Get("default"),
+		defaultFrom:  t.Tag.Get("default_from"),
+		enum:         t.Tag.Get("enum"),
+		encoding:     t.Tag.Get("encoding"),
+		deprecated:   t.Tag.Get("deprecated"),
+		oneof:        t.Tag.Get("oneof"),
+		aliases:      aliases,
+
+		requiredWith:  requiredWith,
+		conflictsWith: conflictsWith,
+
+		trailingComment: t.Tag.Get("trailing_comment"),
+
+		min: parseBound(parent, t, "min"),
+		max: parseBound(parent, t, "max"),
+	}
+	if pattern := t.Tag.Get("pattern"); pattern != "" {
+		attr.pattern = compilePattern(parent, t, pattern)
+	}
+	if quote := t.Tag.Get("quote"); quote != "" {
+		style, ok := parseQuoteStyle(quote)
+		if !ok {
+			panic("invalid quote " + quote + " on " + fieldID(parent, t))
+		}
+		attr.quote = style
+	}
+	if base := t.Tag.Get("base"); base != "" {
+		switch base {
+		case "2", "8", "10", "16":
+			attr.base, _ = strconv.Atoi(base)
+		default:
+			panic("invalid base " + base + " on " + fieldID(parent, t))
+		}
+	} else if opt.octalFileModes && t.Type == fileModeType {
+		attr.base = 8
+	}
+	s, ok := t.Tag.Lookup(opt.tagKey())
 
-	isBlock := false
 	if !ok && opt.inferHCLTags {
 		// if the struct field is a struct or pointer to struct set the tag as block
 		tt := t.Type
 		for tt.Kind() == reflect.Ptr {
 			tt = tt.Elem()
 		}
-		isBlock = tt.Kind() == reflect.Struct
+		attr.block = tt.Kind() == reflect.Struct
 	}
 
 	if !ok {
 		s, ok = t.Tag.Lookup("json")
 		if !ok {
-			return tag{name: t.Name, block: isBlock, optional: true, help: help, defaultValue: defaultValue, enum: enum}
+			attr.name = t.Name
+			if opt.nameTransform != nil {
+				attr.name = opt.nameTransform(t.Name)
+			}
+			attr.optional = true
+			if opt.hcl1Tags {
+				return hcl1Tag(attr, t.Type, opt)
+			}
+			return attr
 		}
 	}
 	parts := strings.Split(s, ",")
@@ -495,26 +1444,216 @@ func parseTag(parent reflect.Type, f field, opt *marshalOptions) tag {
 	if name == "-" {
 		return tag{}
 	}
-	id := fieldID(parent, t)
 	if name == "" {
 		name = t.Name
 	}
-	if len(parts) == 1 {
-		return tag{name: name, block: isBlock, help: help, defaultValue: defaultValue, optional: defaultValue != "", enum: enum}
-	}
-	option := parts[1]
-	switch option {
-	case "optional", "omitempty":
-		return tag{name: name, block: isBlock, optional: true, help: help, defaultValue: defaultValue, enum: enum}
-	case "label":
-		return tag{name: name, label: true, help: help}
-	case "block":
-		return tag{name: name, block: true, optional: true, help: help}
-	case "remain":
-		return tag{name: name, remain: true, help: help}
+	attr.name = name
+	// Tristates are unset when absent, so are always optional.
+	attr.optional = attr.defaultValue != "" || attr.defaultFrom != "" || t.Type == tristateType
+	for i, option := range parts[1:] {
+		if opt.hcl1Tags {
+			switch option {
+			case "key":
+				option = "label"
+			case "decodedFields", "unusedKeys":
+				return tag{}
+			}
+		}
+		switch option {
+		case "optional", "omitempty":
+			attr.optional = true
+		case "readonly":
+			attr.readonly = true
+			attr.optional = true
+		case "secret":
+			tt := t.Type
+			for tt.Kind() == reflect.Ptr {
+				tt = tt.Elem()
+			}
+			if tt.Kind() != reflect.String {
+				panic("\"secret\" field " + fieldID(parent, t) + " must be a string")
+			}
+			attr.secret = true
+		case "set":
+			attr.set = true
+		case "dedupe":
+			attr.set = true
+			attr.dedupe = true
+		case "label":
+			label := tag{name: name, label: true, help: help}
+			if source := t.Tag.Get("label"); source != "" {
+				label.labelTemplate = compileLabelTemplate(parent, t, source)
+			}
+			return label
+		case "part":
+			return tag{part: name}
+		case "block":
+			block := tag{name: elemName(t, name), block: true, optional: true, help: help, aliases: aliases,
+				minItems: attr.minItems, maxItems: attr.maxItems,
+				requiredWith: requiredWith, conflictsWith: conflictsWith,
+				union: opt.lookupUnion(t.Type)}
+			// Other options following "block" are ignored.
+			for _, option := range parts[i+2:] {
+				if _, err := block.parseOccurrences(option); err != nil {
+					panic(err.Error() + " on " + fieldID(parent, t))
+				}
+			}
+			if block.minItems != 0 || block.maxItems != 0 {
+				tt := t.Type
+				for tt.Kind() == reflect.Ptr {
+					tt = tt.Elem()
+				}
+				if tt.Kind() != reflect.Slice {
+					panic("block " + fieldID(parent, t) + " with \"min\" or \"max\" must be a slice")
+				}
+				if block.maxItems != 0 && block.maxItems < block.minItems {
+					panic("\"max\" is less than \"min\" on " + fieldID(parent, t))
+				}
+			}
+			return block
+		case "remain":
+			return tag{name: name, remain: true, help: help}
+		case "inline":
+			tt := t.Type
+			for tt.Kind() == reflect.Ptr {
+				tt = tt.Elem()
+			}
+			if tt.Kind() != reflect.Struct {
+				panic("\"inline\" field " + fieldID(parent, t) + " must be a struct")
+			}
+			// Like blocks, inline fields are optional.
+			attr.inline = true
+			attr.optional = true
+		default:
+			if ok, err := attr.parseOccurrences(option); err != nil {
+				panic(err.Error() + " on " + fieldID(parent, t))
+			} else if !ok {
+				panic("invalid HCL tag option " + option + " on " + fieldID(parent, t))
+			}
+		}
+	}
+	if attr.minItems != 0 || attr.maxItems != 0 {
+		panic("\"min\" and \"max\" are only valid for blocks, on " + fieldID(parent, t))
+	}
+	if t.Tag.Get("label") != "" {
+		panic("label:\"\" is only valid on labels, on " + fieldID(parent, t))
+	}
+	if attr.set && t.Type.Kind() != reflect.Slice {
+		panic("\"set\" field " + fieldID(parent, t) + " must be a slice")
+	}
+	if opt.hcl1Tags {
+		return hcl1Tag(attr, t.Type, opt)
+	}
+	return attr
+}
+
+// parseOccurrences parses a "min=<n>" or "max=<n>" tag option, returning
+// false if option is neither.
+func (t *tag) parseOccurrences(option string) (bool, error) {
+	var bound *int
+	switch {
+	case strings.HasPrefix(option, "min="):
+		bound = &t.minItems
+	case strings.HasPrefix(option, "max="):
+		bound = &t.maxItems
 	default:
-		panic("invalid HCL tag option " + option + " on " + id)
+		return false, nil
+	}
+	n, err := strconv.Atoi(option[len("min="):])
+	if err != nil || n < 0 {
+		return true, fmt.Errorf("invalid HCL tag option %s, expected a non-negative integer", option)
+	}
+	*bound = n
+	return true, nil
+}
+
+// dependentField is a field with required_with or conflicts_with constraints.
+type dependentField struct {
+	field field
+	tag   tag
+}
+
+// checkDependencies checks the required_with and conflicts_with constraints
+// of fields, given the first entry present for each field name.
+func checkDependencies(parent reflect.Type, fields []dependentField, present map[string]*Entry) error {
+	for _, dep := range fields {
+		for _, names := range [][]string{dep.tag.requiredWith, dep.tag.conflictsWith} {
+			for _, name := range names {
+				if _, ok := present[name]; !ok {
+					panic(fmt.Sprintf("unknown field %q in constraint on %s", name, fieldID(parent, dep.field.t)))
+				}
+			}
+		}
+		entry := present[dep.tag.name]
+		if entry == nil {
+			continue
+		}
+		for _, name := range dep.tag.requiredWith {
+			if present[name] == nil {
+				return participle.Errorf(entry.Pos, "%q requires %q to also be set", dep.tag.name, name)
+			}
+		}
+		for _, name := range dep.tag.conflictsWith {
+			if other := present[name]; other != nil {
+				first, second := sourceOrder(entry, other)
+				return participle.Errorf(second.Pos, "%q conflicts with %q at %s", second.Key(), first.Key(), first.Pos)
+			}
+		}
+	}
+	return nil
+}
+
+// sourceOrder returns a and b in the order they appear in the source.
+func sourceOrder(a, b *Entry) (*Entry, *Entry) {
+	if b.Pos.Line < a.Pos.Line || (b.Pos.Line == a.Pos.Line && b.Pos.Column < a.Pos.Column) {
+		return b, a
+	}
+	return a, b
+}
+
+// checkOccurrences checks that the number of blocks is within the bounds of tag.
+//
+// Missing blocks are reported at pos, the position of the enclosing block, if
+// any.
+func checkOccurrences(tag tag, entries []*Entry, pos lexer.Position) error {
+	switch {
+	case len(entries) < tag.minItems && len(entries) == 0:
+		err := fmt.Errorf("expected at least %d %q %s but got none", tag.minItems, tag.name, blocksNoun(tag.minItems))
+		if pos.Line == 0 {
+			return err
+		}
+		return participle.AnnotateError(pos, err)
+	case len(entries) < tag.minItems:
+		return participle.Errorf(entries[0].Pos, "expected at least %d %q %s but got %d", tag.minItems, tag.name, blocksNoun(tag.minItems), len(entries))
+	case tag.maxItems != 0 && len(entries) > tag.maxItems:
+		return participle.Errorf(entries[tag.maxItems].Pos, "expected at most %d %q %s but got %d", tag.maxItems, tag.name, blocksNoun(tag.maxItems), len(entries))
+	}
+	return nil
+}
+
+// blocksNoun returns "block" or "blocks", for n blocks.
+func blocksNoun(n int) string {
+	if n == 1 {
+		return "block"
+	}
+	return "blocks"
+}
+
+// elemName returns the per-element block name for slices of blocks, as
+// overridden by the elem:"" tag.
+func elemName(t reflect.StructField, name string) string {
+	elem := t.Tag.Get("elem")
+	if elem == "" {
+		return name
+	}
+	tt := t.Type
+	for tt.Kind() == reflect.Ptr {
+		tt = tt.Elem()
+	}
+	if tt.Kind() != reflect.Slice {
+		return name
 	}
+	return elem
 }
 
 func implements(v reflect.Value, iface reflect.Type) (reflect.Value, bool) {
@@ -1,20 +1,25 @@
 package hcl
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"os"
 	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/alecthomas/participle"
+	"github.com/alecthomas/participle/lexer"
 	"github.com/alecthomas/repr"
 	"github.com/stretchr/testify/require"
 )
 
-type Number int
+type wordNumber int
 
-func (n *Number) UnmarshalJSON(b []byte) error {
+func (n *wordNumber) UnmarshalJSON(b []byte) error {
 	s, _ := strconv.Unquote(string(b))
 	switch s {
 	case "one":
@@ -100,7 +105,7 @@ func TestUnmarshal(t *testing.T) {
 			dest: struct {
 				Name string `hcl:"name"`
 			}{},
-			fail: "2:5: duplicate field \"name\" at 3:5",
+			fail: "2:5: name: duplicate field \"name\" at 3:5",
 		},
 		{name: "BlockForAttribute",
 			hcl: `
@@ -109,7 +114,7 @@ func TestUnmarshal(t *testing.T) {
 			dest: struct {
 				Name string `hcl:"name"`
 			}{},
-			fail: "2:5: expected an attribute for \"name\" but got a block",
+			fail: "2:5: name: expected an attribute for \"name\" but got a block",
 		},
 		{name: "ScalarAttributes",
 			hcl: `
@@ -202,7 +207,7 @@ func TestUnmarshal(t *testing.T) {
 			dest: struct {
 				Block labelledBlock `hcl:"block,block"`
 			}{},
-			fail: "2:5: missing label \"name\"",
+			fail: "2:5: block: block \"block\" expects 1 label (\"name\"), got 0",
 		},
 		{name: "TooManyLabels",
 			hcl: `
@@ -213,7 +218,62 @@ func TestUnmarshal(t *testing.T) {
 			dest: struct {
 				Block labelledBlock `hcl:"block,block"`
 			}{},
-			fail: "2:5: too many labels for block \"block\"",
+			fail: "2:5: block: block \"block\" expects 1 label (\"name\"), got 2",
+		},
+		{name: "MultipleLabels",
+			hcl: `
+				route "GET" "/users" {}
+			`,
+			dest: struct {
+				Route struct {
+					Method string `hcl:"method,label"`
+					Path   string `hcl:"path,label"`
+				} `hcl:"route,block"`
+			}{
+				Route: struct {
+					Method string `hcl:"method,label"`
+					Path   string `hcl:"path,label"`
+				}{Method: "GET", Path: "/users"},
+			},
+		},
+		{name: "MultipleLabelsArity",
+			hcl: `
+				route "GET" {}
+			`,
+			dest: struct {
+				Route struct {
+					Method string `hcl:"method,label"`
+					Path   string `hcl:"path,label"`
+				} `hcl:"route,block"`
+			}{},
+			fail: "2:5: route: block \"route\" expects 2 labels (\"method\", \"path\"), got 1",
+		},
+		{name: "TypedLabels",
+			hcl: `
+				listener "8080" "127.0.0.1" {}
+			`,
+			dest: struct {
+				Listener struct {
+					Port int    `hcl:"port,label"`
+					IP   net.IP `hcl:"ip,label"`
+				} `hcl:"listener,block"`
+			}{
+				Listener: struct {
+					Port int    `hcl:"port,label"`
+					IP   net.IP `hcl:"ip,label"`
+				}{Port: 8080, IP: net.IPv4(127, 0, 0, 1)},
+			},
+		},
+		{name: "InvalidTypedLabel",
+			hcl: `
+				listener "http" {}
+			`,
+			dest: struct {
+				Listener struct {
+					Port int `hcl:"port,label"`
+				} `hcl:"listener,block"`
+			}{},
+			fail: "2:5: listener: invalid label \"http\" for block \"listener\": strconv.ParseInt: parsing \"http\": invalid syntax",
 		},
 		{name: "SliceOfBlocks",
 			hcl: `
@@ -233,6 +293,24 @@ func TestUnmarshal(t *testing.T) {
 				},
 			},
 		},
+		{name: "SliceOfBlocksWithElemName",
+			hcl: `
+				rule "one" {
+					attr = "one"
+				}
+				rule "two" {
+					attr = "two"
+				}
+			`,
+			dest: struct {
+				Rules []labelledBlock `hcl:"rules,block" elem:"rule"`
+			}{
+				Rules: []labelledBlock{
+					{Name: "one", Attr: "one"},
+					{Name: "two", Attr: "two"},
+				},
+			},
+		},
 		{name: "Duration",
 			hcl: `
 				duration = "5s"
@@ -253,6 +331,28 @@ func TestUnmarshal(t *testing.T) {
 				Time: timestamp,
 			},
 		},
+		{name: "Bytes",
+			hcl: `
+				base64 = "aGVsbG8="
+				hex = "776f726c64"
+			`,
+			dest: struct {
+				Base64 []byte `hcl:"base64"`
+				Hex    []byte `hcl:"hex" encoding:"hex"`
+			}{
+				Base64: []byte("hello"),
+				Hex:    []byte("world"),
+			},
+		},
+		{name: "InvalidBytes",
+			hcl: `
+				hex = "xyz"
+			`,
+			dest: struct {
+				Hex []byte `hcl:"hex" encoding:"hex"`
+			}{},
+			fail: "2:11: hex: invalid bytes: encoding/hex: invalid byte: U+0078 'x'",
+		},
 		{name: "TextUnmarshaler",
 			hcl: `
 				ip = "8.8.8.8"
@@ -268,11 +368,30 @@ func TestUnmarshal(t *testing.T) {
 				number = "one"
 			`,
 			dest: struct {
-				Number Number `hcl:"number"`
+				Number wordNumber `hcl:"number"`
 			}{
 				Number: 1,
 			},
 		},
+		{name: "Interface",
+			hcl: `
+				str = "str"
+				num = 1.5
+				list = [1, "two", true]
+				map = {key: ["value"]}
+			`,
+			dest: struct {
+				Str  interface{} `hcl:"str"`
+				Num  interface{} `hcl:"num"`
+				List interface{} `hcl:"list"`
+				Map  interface{} `hcl:"map"`
+			}{
+				Str:  "str",
+				Num:  1.5,
+				List: []interface{}{1.0, "two", true},
+				Map:  map[string]interface{}{"key": []interface{}{"value"}},
+			},
+		},
 		{name: "PointerScalars",
 			hcl: `
 				ptr = "one"
@@ -595,7 +714,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error converting "abc" to int`,
+			fail: `integer: error parsing default value: error converting "abc" to int`,
 		},
 		{
 			name: "Wrong Float",
@@ -606,7 +725,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error converting "abc" to float`,
+			fail: `integer: error parsing default value: error converting "abc" to float`,
 		},
 		{
 			name: "Wrong Bool",
@@ -617,7 +736,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error converting "abc" to bool`,
+			fail: `integer: error parsing default value: error converting "abc" to bool`,
 		},
 		{
 			name: "Wrong Map",
@@ -628,7 +747,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error parsing map "abc" into pairs`,
+			fail: `integer: error parsing default value: error parsing map "abc" into pairs`,
 		},
 		{
 			name: "Wrong Map Value",
@@ -639,7 +758,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error parsing map "test" into value, error parsing default value: error converting "test" to int`,
+			fail: `integer: error parsing default value: error parsing map "test" into value, error parsing default value: error converting "test" to int`,
 		},
 		{
 			name: "Wrong Map Separator",
@@ -650,7 +769,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error parsing map "2,key2" into value, error parsing default value: error converting "2,key2" to int`,
+			fail: `integer: error parsing default value: error parsing map "2,key2" into value, error parsing default value: error converting "2,key2" to int`,
 		},
 		{
 			name: "Wrong Slice",
@@ -661,7 +780,7 @@ name = "a"
 			}{
 				Name: "a",
 			},
-			fail: `error parsing default value: error applying "a" to list: error parsing default value: error converting "a" to int`,
+			fail: `integer: error parsing default value: error applying "a" to list: error parsing default value: error converting "a" to int`,
 		}}
 
 	runTests(t, tests)
@@ -706,7 +825,7 @@ name = "test"
 			}{
 				Name: "test",
 			},
-			fail: `value "test" does not match anything within enum "a", "b", "c"`,
+			fail: `name: value "test" does not match anything within enum "a", "b", "c"`,
 		},
 		{
 			name: "Float Mismatch",
@@ -718,7 +837,7 @@ val = 2.33
 			}{
 				Val: 2.33,
 			},
-			fail: `value 2.33 does not match anything within enum 2.11, 2.21, 5.22`,
+			fail: `val: value 2.33 does not match anything within enum 2.11, 2.21, 5.22`,
 		},
 		{
 			name: "Int Mismatch",
@@ -730,7 +849,7 @@ val = 17
 			}{
 				Val: 17,
 			},
-			fail: `value 17 does not match anything within enum 10, 25, 100`,
+			fail: `val: value 17 does not match anything within enum 10, 25, 100`,
 		},
 		{
 			name: "String Default Value Conflicts",
@@ -741,7 +860,7 @@ val = 17
 			}{
 				Str: "d",
 			},
-			fail: `default value conflicts with enum: value "d" does not match anything within enum "a", "b", "c"`,
+			fail: `str: default value conflicts with enum: value "d" does not match anything within enum "a", "b", "c"`,
 		},
 		{
 			name: "Int Default Value Conflicts",
@@ -751,7 +870,7 @@ val = 17
 			}{
 				Val: 9,
 			},
-			fail: `default value conflicts with enum: value 9 does not match anything within enum 5, 8, 10`,
+			fail: `val: default value conflicts with enum: value 9 does not match anything within enum 5, 8, 10`,
 		},
 		{
 			name: "Float Default Value Conflicts",
@@ -761,7 +880,7 @@ val = 17
 			}{
 				Val: 9.01,
 			},
-			fail: `default value conflicts with enum: value 9.01 does not match anything within enum 5.2, 8, 10.9`,
+			fail: `val: default value conflicts with enum: value 9.01 does not match anything within enum 5.2, 8, 10.9`,
 		},
 		{
 			name: "Int Enum Parse Error",
@@ -771,7 +890,7 @@ val = 17
 			}{
 				Val: 9,
 			},
-			fail: `default value conflicts with enum: error parsing enum: error converting "5.2" to int`,
+			fail: `val: default value conflicts with enum: error parsing enum: error converting "5.2" to int`,
 		},
 		{
 			name: "Float Enum Parse Error",
@@ -781,9 +900,1064 @@ val = 17
 			}{
 				Val: 9.2,
 			},
-			fail: `default value conflicts with enum: error parsing enum: error converting "a" to float`,
+			fail: `val: default value conflicts with enum: error parsing enum: error converting "a" to float`,
 		},
 	}
 
 	runTests(t, tests)
 }
+
+func TestUnmarshalUnusedCallback(t *testing.T) {
+	type service struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	type config struct {
+		Services []service `hcl:"service,block"`
+	}
+	extra := attr("host", str("localhost"))
+	extra.Pos = lexer.Position{Line: 4, Column: 3}
+	version := attr("version", num(2))
+	version.Pos = lexer.Position{Line: 1, Column: 1}
+	ast := hcl(
+		version,
+		block("service", []string{"api"}, attr("port", num(80)), extra),
+	)
+	unused := []string{}
+	actual := &config{}
+	err := UnmarshalAST(ast, actual, WithUnusedCallback(func(path string, pos lexer.Position) {
+		unused = append(unused, fmt.Sprintf("%s at %s", path, pos))
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &config{Services: []service{{Name: "api", Port: 80}}}, actual)
+	require.Equal(t, []string{"service.api.host at 4:3", "version at 1:1"}, unused)
+}
+
+func TestUnmarshalDeprecated(t *testing.T) {
+	type config struct {
+		Old     string `hcl:"old,optional" deprecated:"use new instead"`
+		New     string `hcl:"new,optional"`
+		Omitted string `hcl:"omitted,optional" deprecated:"no longer used"`
+	}
+	old := attr("old", str("value"))
+	old.Pos = lexer.Position{Line: 2, Column: 1}
+	warnings := []Warning{}
+	actual := &config{}
+	err := UnmarshalAST(hcl(old), actual, WithWarningCallback(func(warning Warning) {
+		warnings = append(warnings, warning)
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &config{Old: "value"}, actual)
+	require.Equal(t, []Warning{{Pos: old.Pos, Msg: `attribute "old" is deprecated: use new instead`}}, warnings)
+	require.EqualError(t, warnings[0], `2:1: attribute "old" is deprecated: use new instead`)
+}
+
+func TestUnmarshalAlias(t *testing.T) {
+	type rule struct {
+		Name string `hcl:"name,label"`
+	}
+	type config struct {
+		Timeout int    `hcl:"timeout" alias:"wait,wait_secs"`
+		Rules   []rule `hcl:"rule,block" alias:"filter"`
+	}
+	wait := attr("wait_secs", num(10))
+	wait.Pos = lexer.Position{Line: 1, Column: 1}
+	filter := block("filter", []string{"b"})
+	filter.Pos = lexer.Position{Line: 3, Column: 1}
+	ast := hcl(
+		wait,
+		block("rule", []string{"a"}),
+		filter,
+		block("rule", []string{"c"}),
+	)
+	warnings := []string{}
+	actual := &config{}
+	err := UnmarshalAST(ast, actual, WithWarningCallback(func(warning Warning) {
+		warnings = append(warnings, warning.Error())
+	}))
+	require.NoError(t, err)
+	expected := &config{Timeout: 10, Rules: []rule{{Name: "a"}, {Name: "b"}, {Name: "c"}}}
+	require.Equal(t, expected, actual)
+	require.Equal(t, []string{
+		`1:1: "wait_secs" is an alias for "timeout"`,
+		`3:1: "filter" is an alias for "rule"`,
+	}, warnings)
+
+	data, err := Marshal(actual)
+	require.NoError(t, err)
+	require.Equal(t, `timeout = 10
+
+rule "a" {
+}
+
+rule "b" {
+}
+
+rule "c" {
+}
+`, string(data))
+
+	err = UnmarshalAST(hcl(attr("wait", num(1)), attr("timeout", num(2))), &config{})
+	require.Error(t, err)
+}
+
+func TestUnmarshalSet(t *testing.T) {
+	runTests(t, []test{
+		{name: "Unique",
+			hcl: `tags = ["b", "a"]`,
+			dest: struct {
+				Tags []string `hcl:"tags,set"`
+			}{Tags: []string{"b", "a"}}},
+		{name: "Duplicate",
+			hcl: `tags = ["a", "b", "a"]`,
+			dest: struct {
+				Tags []string `hcl:"tags,set"`
+			}{},
+			fail: `1:19: tags: duplicate value "a" in set "tags"`},
+		{name: "Dedupe",
+			hcl: `ports = [80, 443, 80]`,
+			dest: struct {
+				Ports []int `hcl:"ports,optional,dedupe"`
+			}{Ports: []int{80, 443}}},
+	})
+}
+
+func TestUnmarshalTuple(t *testing.T) {
+	runTests(t, []test{
+		{name: "Interface",
+			hcl: `tuple = [1, "two", true, [3]]`,
+			dest: struct {
+				Tuple []interface{} `hcl:"tuple"`
+			}{Tuple: []interface{}{1.0, "two", true, []interface{}{3.0}}}},
+		{name: "Array",
+			hcl: `
+				tuple = [1, "two", true]
+				pair = [1, 2]
+			`,
+			dest: struct {
+				Tuple [3]interface{} `hcl:"tuple"`
+				Pair  [2]int         `hcl:"pair"`
+			}{Tuple: [3]interface{}{1.0, "two", true}, Pair: [2]int{1, 2}}},
+		{name: "ArrayLength",
+			hcl: `pair = [1, 2, 3]`,
+			dest: struct {
+				Pair [2]int `hcl:"pair"`
+			}{},
+			fail: `1:8: pair: expected a list of 2 elements but got 3`},
+		{name: "ArrayElementType",
+			hcl: `pair = [1, "two"]`,
+			dest: struct {
+				Pair [2]int `hcl:"pair"`
+			}{},
+			fail: `1:12: pair[1]: expected a number but got "two"`},
+		{name: "ArrayDefault",
+			hcl: ``,
+			dest: struct {
+				Pair [2]string `hcl:"pair,optional" default:"a,b"`
+			}{Pair: [2]string{"a", "b"}}},
+		{name: "ArrayDefaultLength",
+			hcl: ``,
+			dest: struct {
+				Pair [2]string `hcl:"pair,optional" default:"a,b,c"`
+			}{},
+			fail: `pair: error parsing default value: expected 2 elements but got 3`},
+	})
+}
+
+func TestUnmarshalNumberLiterals(t *testing.T) {
+	runTests(t, []test{
+		{name: "Literals",
+			hcl: `
+				hex = 0xFF
+				octal = 0o755
+				binary = 0b1010
+				underscores = 1_000_000
+				exponent = 1e3
+				float_exponent = 1.5E-3
+				negative_hex = -0x10
+			`,
+			dest: struct {
+				Hex           int         `hcl:"hex"`
+				Octal         os.FileMode `hcl:"octal"`
+				Binary        uint8       `hcl:"binary"`
+				Underscores   int64       `hcl:"underscores"`
+				Exponent      int         `hcl:"exponent"`
+				FloatExponent float64     `hcl:"float_exponent"`
+				NegativeHex   int         `hcl:"negative_hex"`
+			}{
+				Hex:           255,
+				Octal:         0755,
+				Binary:        10,
+				Underscores:   1000000,
+				Exponent:      1000,
+				FloatExponent: 0.0015,
+				NegativeHex:   -16,
+			}},
+		{name: "InvalidOctal",
+			hcl: `octal = 0o8`,
+			dest: struct {
+				Octal int `hcl:"octal"`
+			}{},
+			fail: `1:9: no lexer rules in state "Root" matched input text "0o8"`},
+	})
+}
+
+func TestUnmarshalFloats(t *testing.T) {
+	runTests(t, []test{
+		{name: "Literals",
+			hcl: `
+				negative_zero = -0.0
+				plus = +1.5
+				scientific = -2.5e-3
+			`,
+			dest: struct {
+				NegativeZero float64 `hcl:"negative_zero"`
+				Plus         float64 `hcl:"plus"`
+				Scientific   float64 `hcl:"scientific"`
+			}{NegativeZero: math.Copysign(0, -1), Plus: 1.5, Scientific: -0.0025}},
+		{name: "NonFiniteStrings",
+			hcl: `
+				pos = "+Inf"
+				neg = "-Inf"
+			`,
+			options: []MarshalOption{NonFiniteFloats(NonFiniteFloatsAsStrings)},
+			dest: struct {
+				Pos float64 `hcl:"pos"`
+				Neg float32 `hcl:"neg"`
+			}{Pos: math.Inf(1), Neg: float32(math.Inf(-1))}},
+		{name: "NonFiniteStringsDisabled",
+			hcl: `pos = "+Inf"`,
+			dest: struct {
+				Pos float64 `hcl:"pos"`
+			}{},
+			fail: `1:7: pos: expected a number but got "+Inf"`},
+		{name: "InvalidNonFiniteString",
+			hcl:     `pos = "1.5"`,
+			options: []MarshalOption{NonFiniteFloats(NonFiniteFloatsAsStrings)},
+			dest: struct {
+				Pos float64 `hcl:"pos"`
+			}{},
+			fail: `1:7: pos: expected a number, "+Inf", "-Inf" or "NaN" but got "1.5"`},
+	})
+}
+
+func TestUnmarshalFieldPath(t *testing.T) {
+	type tls struct {
+		Ciphers []int `hcl:"ciphers"`
+	}
+	type server struct {
+		Name string `hcl:"name,label"`
+		TLS  tls    `hcl:"tls,block"`
+	}
+	type config struct {
+		Servers []server `hcl:"servers,block"`
+	}
+	src := `
+servers "a" { tls { ciphers = [1] } }
+servers "b" { tls { ciphers = [2] } }
+servers "c" { tls { ciphers = ["x"] } }
+`
+	err := Unmarshal([]byte(src), &config{})
+	require.EqualError(t, err, `4:32: servers[2].tls.ciphers[0]: expected a number but got "x"`)
+	ferr := &FieldError{}
+	require.True(t, errors.As(err, &ferr))
+	require.Equal(t, "servers[2].tls.ciphers[0]", ferr.Path)
+	perr, ok := err.(participle.Error)
+	require.True(t, ok)
+	require.Equal(t, 4, perr.Token().Pos.Line)
+
+	// Errors for missing fields are not attributed to the preceding field.
+	err = Unmarshal([]byte(`a = "x"`), &struct {
+		A string `hcl:"a"`
+		B string `hcl:"b"`
+	}{})
+	require.EqualError(t, err, `missing required attribute "b"`)
+}
+
+func TestDecodeBlock(t *testing.T) {
+	ast, err := ParseString(`
+version = 2
+service "api" {
+  port = 8080
+}
+service "web" {
+  port = 80
+}
+unknown {
+  anything = true
+}
+`)
+	require.NoError(t, err)
+
+	type service struct {
+		Port int `hcl:"port"`
+	}
+	actual := &service{}
+	err = DecodeBlock(ast, "service", []string{"web"}, actual)
+	require.NoError(t, err)
+	require.Equal(t, &service{Port: 80}, actual)
+
+	type labelledService struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	labelled := &labelledService{}
+	err = DecodeBlock(ast, "service", []string{"api"}, labelled)
+	require.NoError(t, err)
+	require.Equal(t, &labelledService{Name: "api", Port: 8080}, labelled)
+
+	err = DecodeBlock(ast, "service", []string{"db"}, actual)
+	require.EqualError(t, err, `block service "db" not found`)
+}
+
+func TestUnmarshalBlocksTopLevel(t *testing.T) {
+	src := []byte(`
+version = 2
+service "api" {
+  port = 8080
+}
+unknown {
+  anything = true
+}
+service "web" {
+  port = 80
+}
+`)
+	type service struct {
+		Name string `hcl:"name,label"`
+		Port int    `hcl:"port"`
+	}
+	services := []service{{Name: "old"}}
+	err := UnmarshalBlocks(src, "service", &services)
+	require.NoError(t, err)
+	require.Equal(t, []service{{Name: "api", Port: 8080}, {Name: "web", Port: 80}}, services)
+
+	pointers := []*service{{Name: "old"}}
+	err = UnmarshalBlocks(src, "service", &pointers, AppendSlices(true))
+	require.NoError(t, err)
+	require.Equal(t, []*service{{Name: "old"}, {Name: "api", Port: 8080}, {Name: "web", Port: 80}}, pointers)
+
+	err = UnmarshalBlocks(src, "missing", &services)
+	require.NoError(t, err)
+	require.Empty(t, services)
+
+	err = UnmarshalBlocks([]byte("service \"api\" {\n  port = \"http\"\n}\n"), "service", &services)
+	require.EqualError(t, err, `2:10: service[0].port: expected a number but got "http"`)
+
+	err = UnmarshalBlocks(src, "service", services)
+	require.EqualError(t, err, "[]hcl.service must be a pointer to a slice of structs")
+	err = UnmarshalBlocks(src, "service", &[]string{})
+	require.EqualError(t, err, "*[]string must be a pointer to a slice of structs")
+}
+
+func TestUnmarshalBlockOccurrences(t *testing.T) {
+	type upstream struct {
+		Host string `hcl:"host,label"`
+	}
+	type proxy struct {
+		Upstreams []upstream `hcl:"upstream,block,min=1,max=2"`
+	}
+	type config struct {
+		Proxy proxy `hcl:"proxy,block"`
+	}
+	runTests(t, []test{
+		{name: "WithinBounds",
+			hcl: `
+				proxy {
+					upstream "a" {}
+					upstream "b" {}
+				}
+			`,
+			dest: config{Proxy: proxy{Upstreams: []upstream{{Host: "a"}, {Host: "b"}}}},
+		},
+		{name: "TooFew",
+			hcl: `
+				proxy {}
+			`,
+			dest: config{},
+			fail: `2:5: proxy: expected at least 1 "upstream" block but got none`,
+		},
+		{name: "TooMany",
+			hcl: `
+				proxy {
+					upstream "a" {}
+					upstream "b" {}
+					upstream "c" {}
+				}
+			`,
+			dest: config{},
+			fail: `5:6: proxy: expected at most 2 "upstream" blocks but got 3`,
+		},
+	})
+	// Missing blocks are reported at the enclosing block, even when it is
+	// decoded on its own.
+	ast, err := ParseString("\nproxy {}\n")
+	require.NoError(t, err)
+	err = UnmarshalBlock(ast.Entries[0].Block, &proxy{})
+	require.EqualError(t, err, `2:1: expected at least 1 "upstream" block but got none`)
+
+	type pair struct {
+		Upstreams []upstream `hcl:"upstream,block,min=2"`
+	}
+	err = Unmarshal([]byte(`upstream "a" {}`), &pair{})
+	require.EqualError(t, err, `1:1: expected at least 2 "upstream" blocks but got 1`)
+
+	type attrBounds struct {
+		Attr []string `hcl:"attr,min=1"`
+	}
+	require.PanicsWithValue(t, `"min" and "max" are only valid for blocks, on github.com/alecthomas/hcl.attrBounds.Attr`, func() {
+		_ = Unmarshal([]byte(`attr = ["a"]`), &attrBounds{})
+	})
+}
+
+func TestUnmarshalOneOf(t *testing.T) {
+	type auth struct {
+		Password string `hcl:"password,optional" oneof:"credentials"`
+		Token    string `hcl:"token,optional" oneof:"credentials"`
+		KeyFile  string `hcl:"key_file,optional" oneof:"credentials"`
+		User     string `hcl:"user,optional"`
+	}
+	type config struct {
+		Auth auth `hcl:"auth,block"`
+	}
+	runTests(t, []test{
+		{name: "One",
+			hcl: `
+				auth {
+					user = "bob"
+					token = "secret"
+				}
+			`,
+			dest: config{Auth: auth{User: "bob", Token: "secret"}},
+		},
+		{name: "Conflict",
+			hcl: `
+				auth {
+					key_file = "id_rsa"
+					password = "hunter2"
+				}
+			`,
+			dest: config{},
+			fail: `4:6: auth: "password" conflicts with "key_file" at 3:6, only one of group "credentials" may be set`,
+		},
+	})
+}
+
+func TestUnmarshalDependencies(t *testing.T) {
+	type listener struct {
+		TLSCert  string `hcl:"tls_cert,optional" required_with:"tls_key" conflicts_with:"insecure"`
+		TLSKey   string `hcl:"tls_key,optional" required_with:"tls_cert"`
+		Insecure bool   `hcl:"insecure,optional"`
+	}
+	type config struct {
+		Listener listener `hcl:"listener,block"`
+	}
+	runTests(t, []test{
+		{name: "Satisfied",
+			hcl: `
+				listener {
+					tls_cert = "cert.pem"
+					tls_key = "key.pem"
+				}
+			`,
+			dest: config{Listener: listener{TLSCert: "cert.pem", TLSKey: "key.pem"}},
+		},
+		{name: "Unset",
+			hcl: `
+				listener {
+					insecure = true
+				}
+			`,
+			dest: config{Listener: listener{Insecure: true}},
+		},
+		{name: "RequiredWith",
+			hcl: `
+				listener {
+					tls_key = "key.pem"
+				}
+			`,
+			dest: config{},
+			fail: `3:6: listener: "tls_key" requires "tls_cert" to also be set`,
+		},
+		{name: "ConflictsWith",
+			hcl: `
+				listener {
+					insecure = true
+					tls_cert = "cert.pem"
+					tls_key = "key.pem"
+				}
+			`,
+			dest: config{},
+			fail: `4:6: listener: "tls_cert" conflicts with "insecure" at 3:6`,
+		},
+	})
+}
+
+func TestUnmarshalConstraints(t *testing.T) {
+	type config struct {
+		Name  string   `hcl:"name,optional" pattern:"^[a-z-]+$"`
+		Port  int      `hcl:"port,optional" min:"1" max:"65535"`
+		Ratio float64  `hcl:"ratio,optional" min:"0" max:"1" default:"0.5"`
+		Tags  []string `hcl:"tags,optional" pattern:"^[a-z]+$"`
+	}
+	runTests(t, []test{
+		{name: "Valid",
+			hcl:  "name = \"web-api\"\nport = 0x50\ntags = [\"a\", \"b\"]",
+			dest: config{Name: "web-api", Port: 80, Ratio: 0.5, Tags: []string{"a", "b"}},
+		},
+		{name: "Pattern",
+			hcl:  `name = "Web API"`,
+			dest: config{},
+			fail: `1:8: name: value "Web API" does not match pattern ^[a-z-]+$`,
+		},
+		{name: "PatternListElement",
+			hcl:  `tags = ["a", "B"]`,
+			dest: config{},
+			fail: `1:14: tags: value "B" does not match pattern ^[a-z]+$`,
+		},
+		{name: "Min",
+			hcl:  `port = 0`,
+			dest: config{},
+			fail: `1:8: port: value 0 is less than the minimum of 1`,
+		},
+		{name: "Max",
+			hcl:  `ratio = 1.5`,
+			dest: config{},
+			fail: `1:9: ratio: value 1.5 is greater than the maximum of 1`,
+		},
+	})
+}
+
+func TestUnmarshalInline(t *testing.T) {
+	type limits struct {
+		CPU    int `hcl:"cpu"`
+		Memory int `hcl:"mem,optional"`
+	}
+	type config struct {
+		Limits   limits  `hcl:"limits,inline"`
+		Requests *limits `hcl:"requests,inline"`
+	}
+	runTests(t, []test{
+		{name: "Object",
+			hcl:  `limits = { cpu: 2, "mem": 512 }`,
+			dest: config{Limits: limits{CPU: 2, Memory: 512}},
+		},
+		{name: "Block",
+			hcl:  "limits {\n  cpu = 2\n}\nrequests {\n  cpu = 1\n}",
+			dest: config{Limits: limits{CPU: 2}, Requests: &limits{CPU: 1}},
+		},
+		{name: "Pointer",
+			hcl:  `requests = { cpu: 1 }`,
+			dest: config{Requests: &limits{CPU: 1}},
+		},
+		{name: "MissingKey",
+			hcl:  `limits = { mem: 512 }`,
+			dest: config{},
+			fail: `1:1: limits: missing required attribute "cpu"`,
+		},
+		{name: "ExtraKey",
+			hcl:  `limits = { cpu: 1, gpu: 1 }`,
+			dest: config{},
+			fail: `1:20: limits: found extra fields "gpu" (did you mean "cpu"?)`,
+		},
+		{name: "NotAnObject",
+			hcl:  `limits = 2`,
+			dest: config{},
+			fail: `1:10: limits: expected a map or block for "limits" but got 2`,
+		},
+	})
+}
+
+func TestUnmarshalMixedBlockSyntax(t *testing.T) {
+	type settings struct {
+		Debug bool   `hcl:"debug,optional"`
+		Level string `hcl:"level,optional"`
+	}
+	type rule struct {
+		Port int `hcl:"port"`
+	}
+	type config struct {
+		Settings *settings         `hcl:"settings,block"`
+		Rules    []rule            `hcl:"rule,block"`
+		Tags     map[string]string `hcl:"tags,optional"`
+	}
+	mixed := []MarshalOption{MixedBlockSyntax(true)}
+	runTests(t, []test{
+		{name: "ObjectForBlock",
+			hcl:     "settings = {\n  debug = true\n  level = \"info\"\n}",
+			dest:    config{Settings: &settings{Debug: true, Level: "info"}},
+			options: mixed,
+		},
+		{name: "ListForRepeatedBlock",
+			hcl:     "rule = [{ port = 80 }, { port = 443 }]\nrule {\n  port = 8080\n}",
+			dest:    config{Rules: []rule{{Port: 80}, {Port: 443}, {Port: 8080}}},
+			options: mixed,
+		},
+		{name: "BlockForMap",
+			hcl:     "tags {\n  env = \"prod\"\n}",
+			dest:    config{Tags: map[string]string{"env": "prod"}},
+			options: mixed,
+		},
+		{name: "Disabled",
+			hcl:  `settings = { debug = true }`,
+			dest: config{},
+			fail: `1:1: settings: expected a block for "settings" but got an attribute`,
+		},
+	})
+}
+
+func TestUnmarshalSquash(t *testing.T) {
+	type tls struct {
+		Cert string `hcl:"tls_cert,optional"`
+		Key  string `hcl:"tls_key,optional"`
+	}
+	type listener struct {
+		Port int `hcl:"port"`
+		TLS  tls `hcl:",squash"`
+	}
+	type config struct {
+		Listener listener `hcl:",flatten"`
+		Name     string   `hcl:"name"`
+	}
+	runTests(t, []test{
+		{name: "Squashed",
+			hcl:  "name = \"api\"\nport = 443\ntls_cert = \"cert.pem\"",
+			dest: config{Name: "api", Listener: listener{Port: 443, TLS: tls{Cert: "cert.pem"}}},
+		},
+		{name: "MissingSquashedField",
+			hcl:  `name = "api"`,
+			dest: config{},
+			fail: `missing required attribute "port"`,
+		},
+		{name: "NotAStruct",
+			hcl: `name = "api"`,
+			dest: struct {
+				Name string `hcl:"name,squash"`
+			}{},
+			fail: `Name: squashed field must be a struct`,
+		},
+	})
+
+	data, err := Marshal(&config{Name: "api", Listener: listener{Port: 443, TLS: tls{Key: "key.pem"}}})
+	require.NoError(t, err)
+	require.Equal(t, "port = 443\ntls_key = \"key.pem\"\nname = \"api\"\n", string(data))
+}
+
+func TestUnmarshalWeakTypes(t *testing.T) {
+	type config struct {
+		Port    int      `hcl:"port,optional" min:"1"`
+		Ratio   float64  `hcl:"ratio,optional"`
+		Name    string   `hcl:"name,optional"`
+		Debug   bool     `hcl:"debug,optional"`
+		Verbose *bool    `hcl:"verbose,optional"`
+		Hosts   []string `hcl:"hosts,optional"`
+		Ports   []int    `hcl:"ports,optional"`
+	}
+	verbose := true
+	weak := []MarshalOption{WithWeakTypes()}
+	runTests(t, []test{
+		{name: "Coerced",
+			hcl:     "port = \"8080\"\nratio = \" 0.5 \"\nname = 42\ndebug = \"0\"\nverbose = 1\nhosts = \"localhost\"\nports = [\"80\", 443]",
+			dest:    config{Port: 8080, Ratio: 0.5, Name: "42", Verbose: &verbose, Hosts: []string{"localhost"}, Ports: []int{80, 443}},
+			options: weak,
+		},
+		{name: "BoolToString",
+			hcl:     "name = true",
+			dest:    config{Name: "true"},
+			options: weak,
+		},
+		{name: "ConstraintsApplyToCoercedValues",
+			hcl:     `port = "0"`,
+			dest:    config{},
+			fail:    `1:8: port: value 0 is less than the minimum of 1`,
+			options: weak,
+		},
+		{name: "Unparseable",
+			hcl:     `port = "http"`,
+			dest:    config{},
+			fail:    `1:8: port: expected a number but got "http"`,
+			options: weak,
+		},
+		{name: "StrictByDefault",
+			hcl:  `port = "8080"`,
+			dest: config{},
+			fail: `1:8: port: expected a number but got "8080"`,
+		},
+	})
+}
+
+func TestUnmarshalLossyNumbers(t *testing.T) {
+	type config struct {
+		Int   int     `hcl:"int,optional"`
+		Small int8    `hcl:"small,optional"`
+		Uint  uint    `hcl:"uint,optional"`
+		Float float32 `hcl:"float,optional"`
+	}
+	runTests(t, []test{
+		{name: "Exact",
+			hcl:  "int = 1e3\nsmall = -128\nuint = 0xFF\nfloat = 0.1",
+			dest: config{Int: 1000, Small: -128, Uint: 255, Float: 0.1},
+		},
+		{name: "Fraction",
+			hcl:  `int = 1.5`,
+			dest: config{},
+			fail: `1:7: int: expected an integer but got 1.5`,
+		},
+		{name: "Overflow",
+			hcl:  `small = 300`,
+			dest: config{},
+			fail: `1:9: small: value 300 is out of range for int8`,
+		},
+		{name: "Negative",
+			hcl:  `uint = -1`,
+			dest: config{},
+			fail: `1:8: uint: value -1 is out of range for uint`,
+		},
+		{name: "Int64Overflow",
+			hcl:  `int = 1e30`,
+			dest: config{},
+			fail: `1:7: int: value 1e30 is out of range for int`,
+		},
+		{name: "FloatOverflow",
+			hcl:  `float = 1e300`,
+			dest: config{},
+			fail: `1:9: float: value 1e300 is out of range for float32`,
+		},
+		{name: "Truncated",
+			hcl:     "int = 1.5\nsmall = 300",
+			dest:    config{Int: 1, Small: 44},
+			options: []MarshalOption{TruncateNumbers(true)},
+		},
+	})
+}
+
+func TestUnmarshalNestedSlices(t *testing.T) {
+	type config struct {
+		Matrix [][]int     `hcl:"matrix,optional"`
+		Groups [][]string  `hcl:"groups,optional"`
+		Deep   [][][]bool  `hcl:"deep,optional"`
+		Ptrs   []*[]string `hcl:"ptrs,optional"`
+	}
+	runTests(t, []test{
+		{name: "Nested",
+			hcl: "matrix = [[1, 2], [], [3]]\ngroups = [[\"a\"], [\"b\", \"c\"]]\ndeep = [[[true]], [[], [false]]]\nptrs = [[\"x\"]]",
+			dest: config{
+				Matrix: [][]int{{1, 2}, {}, {3}},
+				Groups: [][]string{{"a"}, {"b", "c"}},
+				Deep:   [][][]bool{{{true}}, {{}, {false}}},
+				Ptrs:   []*[]string{{"x"}},
+			},
+		},
+		{name: "ElementError",
+			hcl:  `matrix = [[1], [2, "x"]]`,
+			dest: config{},
+			fail: `1:20: matrix[1][1]: expected a number but got "x"`,
+		},
+		{name: "NotAList",
+			hcl:  `groups = [["a"], "b"]`,
+			dest: config{},
+			fail: `1:18: groups[1]: expected a list but got "b"`,
+		},
+	})
+}
+
+func TestUnmarshalBlockMap(t *testing.T) {
+	type rule struct {
+		Method string `hcl:"method,label"`
+		Target string `hcl:"target"`
+	}
+	type host struct {
+		Port int `hcl:"port"`
+	}
+	type config struct {
+		Routes map[string][]rule `hcl:"route,block"`
+		Hosts  map[string]*host  `hcl:"host,block"`
+	}
+	runTests(t, []test{
+		{name: "Grouped",
+			hcl: `
+				route "/api" "GET" { target = "a" }
+				route "/web" "GET" { target = "b" }
+				route "/api" "POST" { target = "c" }
+				host "x" { port = 80 }
+			`,
+			dest: config{
+				Routes: map[string][]rule{
+					"/api": {{Method: "GET", Target: "a"}, {Method: "POST", Target: "c"}},
+					"/web": {{Method: "GET", Target: "b"}},
+				},
+				Hosts: map[string]*host{"x": {Port: 80}},
+			},
+		},
+		{name: "DuplicateKey",
+			hcl:  "host \"x\" { port = 80 }\nhost \"x\" { port = 81 }",
+			dest: config{},
+			fail: `2:1: host: duplicate block host "x"`,
+		},
+		{name: "MissingKey",
+			hcl:  `host { port = 80 }`,
+			dest: config{},
+			fail: `1:1: host: block "host" expects a label for its key`,
+		},
+		{name: "ElementError",
+			hcl:  `host "x" {}`,
+			dest: config{},
+			fail: `1:1: host["x"]: missing required attribute "port"`,
+		},
+	})
+
+	type badKeys struct {
+		Hosts map[int]host `hcl:"host,block"`
+	}
+	type badValues struct {
+		Hosts map[string]string `hcl:"host,block"`
+	}
+	runTests(t, []test{
+		{name: "BadKeyType",
+			hcl:  `host "1" { port = 80 }`,
+			dest: badKeys{},
+			fail: `host: map of blocks must have string keys but has int`,
+		},
+		{name: "BadKeyTypeWithoutBlocks",
+			hcl:  ``,
+			dest: badKeys{},
+			fail: `host: map of blocks must have string keys but has int`,
+		},
+		{name: "BadValueType",
+			hcl:  ``,
+			dest: badValues{},
+			fail: `host: map of blocks must have struct values but has string`,
+		},
+	})
+}
+
+func TestUnmarshalBlocks(t *testing.T) {
+	type plugin struct {
+		Name    string `hcl:"name,label"`
+		Enabled bool   `hcl:"enabled,optional"`
+	}
+	type config struct {
+		Name    string `hcl:"name"`
+		Plugins Blocks `hcl:"plugins,remain"`
+		Debug   bool   `hcl:"debug,optional"`
+	}
+	src := `
+name = "app"
+cache "redis" {
+  enabled = true
+}
+metrics "prometheus" {}
+debug = true
+`
+	cfg := &config{}
+	err := Unmarshal([]byte(src), cfg)
+	require.NoError(t, err)
+	require.Equal(t, "app", cfg.Name)
+	require.True(t, cfg.Debug)
+	require.Len(t, cfg.Plugins, 2)
+	require.Equal(t, "cache", cfg.Plugins[0].Name)
+	require.Equal(t, []string{"redis"}, cfg.Plugins[0].Labels)
+	require.Equal(t, "metrics", cfg.Plugins[1].Name)
+	p := &plugin{}
+	require.NoError(t, cfg.Plugins[0].Decode(p))
+	require.Equal(t, &plugin{Name: "redis", Enabled: true}, p)
+
+	data, err := Marshal(cfg)
+	require.NoError(t, err)
+	require.Equal(t, `name = "app"
+
+cache "redis" {
+  enabled = true
+}
+
+metrics "prometheus" {
+}
+
+debug = true
+`, string(data))
+
+	// Attributes are not collected.
+	err = Unmarshal([]byte("name = \"app\"\nextra = 1"), &config{})
+	require.EqualError(t, err, `2:1: found extra fields "extra"`)
+}
+
+func TestUnmarshalMapDocument(t *testing.T) {
+	strs := map[string]string{}
+	err := Unmarshal([]byte("name = \"app\"\nenv = \"prod\""), &strs)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"name": "app", "env": "prod"}, strs)
+
+	var values map[string]interface{}
+	err = Unmarshal([]byte("port = 80\ntags = [\"a\"]\nlimits = { cpu: 2 }"), &values)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"port":   80.0,
+		"tags":   []interface{}{"a"},
+		"limits": map[string]interface{}{"cpu": 2.0},
+	}, values)
+
+	err = Unmarshal([]byte("name = \"app\"\nport = 80"), &map[string]string{})
+	require.EqualError(t, err, `2:8: port: expected a type or string but got 80`)
+
+	err = Unmarshal([]byte("server {}"), &map[string]string{})
+	require.EqualError(t, err, `1:1: can't unmarshal block "server" into map[string]string`)
+
+	err = Unmarshal([]byte("a = \"1\"\na = \"2\""), &map[string]string{})
+	require.EqualError(t, err, `2:1: duplicate field "a" at 1:1`)
+}
+
+func TestUnmarshalTagName(t *testing.T) {
+	type listener struct {
+		Port int `config:"port"`
+	}
+	type server struct {
+		Name     string   `config:"name,label"`
+		Listener listener `config:",squash"`
+		Hosts    []string `config:"hosts,optional"`
+	}
+	type config struct {
+		Servers []server `config:"server,block" hcl:"ignored,block"`
+	}
+	options := []MarshalOption{WithTagName("config")}
+	runTests(t, []test{
+		{name: "Custom",
+			hcl:     `server "a" { port = 80 }`,
+			dest:    config{Servers: []server{{Name: "a", Listener: listener{Port: 80}}}},
+			options: options,
+		},
+		{name: "HCLTagsIgnored",
+			hcl:     `ignored "a" { port = 80 }`,
+			dest:    config{},
+			fail:    `1:1: found extra fields "ignored"`,
+			options: options,
+		},
+	})
+
+	data, err := Marshal(&config{Servers: []server{{Name: "a", Listener: listener{Port: 80}}}}, options...)
+	require.NoError(t, err)
+	require.Equal(t, "server \"a\" {\n  port = 80\n}\n", string(data))
+}
+
+func TestUnmarshalTrace(t *testing.T) {
+	type listener struct {
+		Port    int  `hcl:"port"`
+		Debug   bool `hcl:"debug,optional"`
+		Retries int  `hcl:"retries,optional" default:"3"`
+	}
+	type server struct {
+		Name      string     `hcl:"name,label"`
+		Listeners []listener `hcl:"listener,block"`
+	}
+	type config struct {
+		Servers []server `hcl:"server,block"`
+	}
+	events := []string{}
+	err := Unmarshal([]byte(`
+server "web" {
+  listener {
+    port = "8080"
+    extra = true
+  }
+}
+`), &config{},
+		WithWeakTypes(),
+		WithUnusedCallback(func(string, lexer.Position) {}),
+		WithTrace(func(event TraceEvent) { events = append(events, event.String()) }))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`matched server -> config.Servers at 2:1`,
+		`matched server.web.name -> server.Name = "web" at 2:1`,
+		`matched server.web.listener -> server.Listeners at 3:3`,
+		`matched server.web.listener.port -> listener.Port = "8080" at 4:5`,
+		`coerced server.web.listener.port -> listener.Port = 8080 at 4:12`,
+		`skipped server.web.listener.debug -> listener.Debug`,
+		`defaulted server.web.listener.retries -> listener.Retries = 3`,
+		`skipped server.web.listener.extra at 5:5`,
+	}, events)
+}
+
+func TestExtendedDurations(t *testing.T) {
+	type config struct {
+		Duration time.Duration `hcl:"duration"`
+	}
+	options := []MarshalOption{ExtendedDurations(true)}
+	runTests(t, []test{
+		{name: "Days", hcl: `duration = "2d"`, dest: config{Duration: 48 * time.Hour}, options: options},
+		{name: "Composite", hcl: `duration = "1w1d1h30m"`, dest: config{Duration: 193*time.Hour + 30*time.Minute}, options: options},
+		{name: "Fractional", hcl: `duration = "1.5d"`, dest: config{Duration: 36 * time.Hour}, options: options},
+		{name: "Negative", hcl: `duration = "-1d500ms"`, dest: config{Duration: -(24*time.Hour + 500*time.Millisecond)}, options: options},
+		{name: "Standard", hcl: `duration = "90s"`, dest: config{Duration: 90 * time.Second}, options: options},
+		{name: "UnknownUnit", hcl: `duration = "2y"`, dest: config{}, fail: `1:12: duration: invalid duration: time: invalid duration "2y"`, options: options},
+		{name: "MissingUnit", hcl: `duration = "2d3"`, dest: config{}, fail: `1:12: duration: invalid duration: time: invalid duration "2d3"`, options: options},
+	})
+
+	err := Unmarshal([]byte(`duration = "2d"`), &config{})
+	require.Error(t, err)
+
+	for _, test := range []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{90 * time.Minute, "1h30m"},
+		{8*24*time.Hour + time.Second, "1w1d1s"},
+		{-1500 * time.Millisecond, "-1.5s"},
+		{250 * time.Millisecond, "250ms"},
+	} {
+		data, err := Marshal(&config{Duration: test.duration}, options...)
+		require.NoError(t, err)
+		require.Equal(t, "duration = \""+test.expected+"\"\n", string(data))
+		actual := &config{}
+		err = Unmarshal(data, actual, options...)
+		require.NoError(t, err)
+		require.Equal(t, test.duration, actual.Duration)
+	}
+}
+
+func TestUnmarshalIntoPopulated(t *testing.T) {
+	type server struct {
+		Host string `hcl:"host,optional"`
+		Port int    `hcl:"port,optional" default:"80"`
+	}
+	type config struct {
+		Name    string            `hcl:"name,optional" default:"app"`
+		Tags    []string          `hcl:"tags,optional"`
+		Labels  map[string]string `hcl:"labels,optional"`
+		Server  server            `hcl:"server,block"`
+		Workers []server          `hcl:"worker,block"`
+		Sites   map[string]server `hcl:"site,block"`
+	}
+	defaults := func() *config {
+		return &config{
+			Name:    "defaults",
+			Tags:    []string{"a"},
+			Labels:  map[string]string{"env": "dev", "team": "core"},
+			Server:  server{Host: "localhost", Port: 8080},
+			Workers: []server{{Host: "w0"}},
+			Sites:   map[string]server{"www": {Host: "www.internal", Port: 8000}},
+		}
+	}
+	src := []byte(`
+tags = ["b"]
+labels = { env: "prod" }
+server { port = 9090 }
+worker { host = "w1" }
+site "www" { port = 443 }
+site "api" {}
+`)
+	actual := defaults()
+	require.NoError(t, Unmarshal(src, actual))
+	require.Equal(t, &config{
+		Name:    "defaults",
+		Tags:    []string{"b"},
+		Labels:  map[string]string{"env": "prod", "team": "core"},
+		Server:  server{Host: "localhost", Port: 9090},
+		Workers: []server{{Host: "w1", Port: 80}},
+		Sites:   map[string]server{"www": {Host: "www.internal", Port: 443}, "api": {Port: 80}},
+	}, actual)
+
+	actual = defaults()
+	require.NoError(t, Unmarshal(src, actual, AppendSlices(true)))
+	require.Equal(t, []string{"a", "b"}, actual.Tags)
+	require.Equal(t, []server{{Host: "w0"}, {Host: "w1", Port: 80}}, actual.Workers)
+}
@@ -12,12 +12,23 @@ func StripComments(node Node) error {
 		switch node := node.(type) {
 		case *Attribute:
 			node.Comments = nil
+			node.CommentStyles = nil
+			node.TrailingComments = nil
+			node.TrailingCommentStyles = nil
 
 		case *Block:
 			node.Comments = nil
+			node.CommentStyles = nil
 
 		case *MapEntry:
 			node.Comments = nil
+			node.CommentStyles = nil
+
+		case *Directive:
+			node.Comments = nil
+			node.CommentStyles = nil
+			node.TrailingComments = nil
+			node.TrailingCommentStyles = nil
 		}
 		return next()
 	})
@@ -34,10 +45,19 @@ func AddParentRefs(node Node) error {
 func addParentRefs(parent, node Node) {
 	switch node := node.(type) {
 	case *AST:
+		for _, directive := range node.Directives {
+			addParentRefs(node, directive)
+		}
 		for _, entry := range node.Entries {
 			addParentRefs(node, entry)
 		}
 
+	case *Directive:
+		node.Parent = parent
+		for _, arg := range node.Args {
+			addParentRefs(node, arg)
+		}
+
 	case *Block:
 		node.Parent = parent
 		for _, entry := range node.Body {
@@ -58,6 +78,8 @@ func addParentRefs(parent, node Node) {
 	case *Value:
 		node.Parent = parent
 		switch {
+		case node.Block != nil:
+			addParentRefs(node, node.Block)
 		case node.HaveList:
 			for _, entry := range node.List {
 				addParentRefs(node, entry)
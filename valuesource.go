@@ -0,0 +1,165 @@
+package hcl
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/participle"
+)
+
+// ValueSource resolves references in string values to their contents at decode time.
+//
+// A source registered under the name "vault" resolves references of the form
+// "${vault:<ref>}", where ref is passed to Resolve verbatim.
+type ValueSource interface {
+	Resolve(ref string) (string, error)
+}
+
+// ContextValueSource is a ValueSource that supports cancellation.
+//
+// If a source implements this interface, ResolveContext will be called in
+// preference to Resolve with the context passed to UnmarshalContext.
+type ContextValueSource interface {
+	ValueSource
+	ResolveContext(ctx context.Context, ref string) (string, error)
+}
+
+// ValueSourceFunc is a function implementing ValueSource.
+type ValueSourceFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f ValueSourceFunc) Resolve(ref string) (string, error) { return f(ref) }
+
+// WithValueSource registers a ValueSource to resolve "${<name>:<ref>}" references.
+//
+// References are resolved in string attribute values, including those within
+// lists and maps, and in default values. References to unregistered sources are
+// left untouched, as are escaped references such as "$${vault:ref}", which
+// decode as the literal text "${vault:ref}".
+func WithValueSource(name string, source ValueSource) MarshalOption {
+	return func(options *marshalOptions) {
+		if options.valueSources == nil {
+			options.valueSources = map[string]ValueSource{}
+		}
+		options.valueSources[name] = source
+	}
+}
+
+var valueSourceRefRe = regexp.MustCompile(`\$\{([[:alpha:]][\w-]*):([^}]*)\}`)
+
+// resolveValueSources returns v with all value source references resolved.
+//
+// v is returned as-is if it contains no references, otherwise a copy is returned.
+func resolveValueSources(v *Value, opt *marshalOptions) (*Value, error) {
+	if len(opt.valueSources) == 0 {
+		return v, nil
+	}
+	found := false
+	_ = visitStrings(v, func(value *Value) error {
+		s := *value.Str
+		for _, match := range valueSourceRefRe.FindAllStringSubmatchIndex(s, -1) {
+			if _, ok := opt.valueSources[s[match[2]:match[3]]]; ok && !escaped(value, match[0]) {
+				found = true
+			}
+		}
+		return nil
+	})
+	if !found {
+		return v, nil
+	}
+	out := v.Clone()
+	err := visitStrings(out, func(value *Value) error {
+		s, escapes, err := expandValueSourceRefs(value, opt)
+		if err != nil {
+			return err
+		}
+		value.Str = &s
+		value.setEscapes(escapes)
+		return nil
+	})
+	return out, err
+}
+
+// visitStrings calls fn for each string in v, including list elements and map values.
+func visitStrings(v *Value, fn func(v *Value) error) error {
+	switch {
+	case v.Str != nil:
+		return fn(v)
+	case v.HaveList:
+		for _, el := range v.List {
+			if err := visitStrings(el, fn); err != nil {
+				return err
+			}
+		}
+	case v.HaveMap:
+		for _, entry := range v.Map {
+			if err := visitStrings(entry.Value, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expandValueSourceRefs returns the string v with references resolved, and
+// the offsets of the escaped markers in it.
+func expandValueSourceRefs(v *Value, opt *marshalOptions) (string, []int, error) {
+	s := *v.Str
+	out := strings.Builder{}
+	last := 0
+	// Offsets of escaped markers are shifted by the references resolved
+	// before them.
+	shift := 0
+	offsets := v.escapeOffsets()
+	var escapes []int
+	for _, match := range valueSourceRefRe.FindAllStringSubmatchIndex(s, -1) {
+		name, ref := s[match[2]:match[3]], s[match[4]:match[5]]
+		source, ok := opt.valueSources[name]
+		if !ok || escaped(v, match[0]) {
+			continue
+		}
+		ctx := opt.context()
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+		var resolved string
+		var err error
+		if cs, ok := source.(ContextValueSource); ok {
+			resolved, err = cs.ResolveContext(ctx, ref)
+		} else {
+			resolved, err = source.Resolve(ref)
+		}
+		if err != nil {
+			return "", nil, participle.Wrapf(v.Pos, err, "failed to resolve %q", s[match[0]:match[1]])
+		}
+		escapes = appendShifted(escapes, offsets, last, match[0], shift)
+		out.WriteString(s[last:match[0]])
+		out.WriteString(resolved)
+		shift += len(resolved) - (match[1] - match[0])
+		last = match[1]
+	}
+	escapes = appendShifted(escapes, offsets, last, len(s), shift)
+	out.WriteString(s[last:])
+	return out.String(), escapes, nil
+}
+
+// escaped returns true if the marker at offset in the string v was escaped.
+func escaped(v *Value, offset int) bool {
+	for _, escape := range v.escapeOffsets() {
+		if escape == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// appendShifted appends the offsets in [start, end) to dst, shifted by shift.
+func appendShifted(dst, offsets []int, start, end, shift int) []int {
+	for _, offset := range offsets {
+		if offset >= start && offset < end {
+			dst = append(dst, offset+shift)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,175 @@
+package hcl
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testValueSource(values map[string]string) ValueSource {
+	return ValueSourceFunc(func(ref string) (string, error) {
+		value, ok := values[ref]
+		if !ok {
+			return "", fmt.Errorf("%s not found", ref)
+		}
+		return value, nil
+	})
+}
+
+func TestValueSources(t *testing.T) {
+	vault := WithValueSource("vault", testValueSource(map[string]string{"secret/db#password": "hunter2"}))
+	file := WithValueSource("file", testValueSource(map[string]string{"token.txt": "abc123"}))
+	runTests(t, []test{
+		{name: "Attribute",
+			hcl: `
+				password = "${vault:secret/db#password}"
+				dsn = "postgres://app:${vault:secret/db#password}@db"
+				tokens = ["${file:token.txt}"]
+				headers = {"Authorization": "Bearer ${file:token.txt}"}
+				other = "${env:HOME}"
+			`,
+			options: []MarshalOption{vault, file},
+			dest: struct {
+				Password string            `hcl:"password"`
+				DSN      string            `hcl:"dsn"`
+				Tokens   []string          `hcl:"tokens"`
+				Headers  map[string]string `hcl:"headers"`
+				Other    string            `hcl:"other"`
+			}{
+				Password: "hunter2",
+				DSN:      "postgres://app:hunter2@db",
+				Tokens:   []string{"abc123"},
+				Headers:  map[string]string{"Authorization": "Bearer abc123"},
+				Other:    "${env:HOME}",
+			}},
+		{name: "Default",
+			hcl:     ``,
+			options: []MarshalOption{file},
+			dest: struct {
+				Token string `hcl:"token" default:"${file:token.txt}"`
+			}{
+				Token: "abc123",
+			}},
+		{name: "Escaped",
+			hcl: `
+				literal = "$${vault:secret/db#password}"
+				mixed = '$${file:token.txt} ${file:token.txt} $${file:token.txt}'
+				list = ["$${file:token.txt}"]
+			`,
+			options: []MarshalOption{vault, file},
+			dest: struct {
+				Literal string   `hcl:"literal"`
+				Mixed   string   `hcl:"mixed"`
+				List    []string `hcl:"list"`
+			}{
+				Literal: "${vault:secret/db#password}",
+				Mixed:   "${file:token.txt} abc123 ${file:token.txt}",
+				List:    []string{"${file:token.txt}"},
+			}},
+		{name: "NotFound",
+			hcl:     `password = "${vault:secret/missing}"`,
+			options: []MarshalOption{vault},
+			dest: struct {
+				Password string `hcl:"password"`
+			}{},
+			fail: `1:12: password: failed to resolve "${vault:secret/missing}": secret/missing not found`},
+	})
+}
+
+func TestResolveValueSourcesDoesNotMutate(t *testing.T) {
+	opt := newMarshalOptions(WithValueSource("file", testValueSource(map[string]string{"a": "b"})))
+	value := list(str("${file:a}"), num(1))
+	actual, err := resolveValueSources(value, opt)
+	require.NoError(t, err)
+	require.Equal(t, `["b", 1]`, actual.String())
+	require.Equal(t, "${file:a}", *value.List[0].Str)
+}
+
+func TestResolveValueSourcesShiftsEscapes(t *testing.T) {
+	opt := newMarshalOptions(WithValueSource("file", testValueSource(map[string]string{"a": "long value"})))
+	value, err := ParseValue(`"${file:a} $${file:a}"`)
+	require.NoError(t, err)
+	actual, err := resolveValueSources(value, opt)
+	require.NoError(t, err)
+	require.Equal(t, "long value ${file:a}", *actual.Str)
+	require.Equal(t, []int{11}, actual.escapeOffsets())
+	actual, err = resolveValueSources(actual, opt)
+	require.NoError(t, err)
+	require.Equal(t, "long value ${file:a}", *actual.Str)
+}
+
+func TestResolveValueSourcesForgetsEscapesOfEditedStrings(t *testing.T) {
+	opt := newMarshalOptions(WithValueSource("file", testValueSource(map[string]string{"a": "b"})))
+	value, err := ParseValue(`"$${file:a}"`)
+	require.NoError(t, err)
+	edited := "${file:a}!"
+	value.Str = &edited
+	actual, err := resolveValueSources(value, opt)
+	require.NoError(t, err)
+	require.Equal(t, "b!", *actual.Str)
+}
+
+type contextValueSource struct{}
+
+func (contextValueSource) Resolve(ref string) (string, error) { return ref, nil }
+
+func (contextValueSource) ResolveContext(ctx context.Context, ref string) (string, error) {
+	return ctx.Value(contextKey{}).(string), nil
+}
+
+type contextKey struct{}
+
+func TestUnmarshalContext(t *testing.T) {
+	type config struct {
+		Token string `hcl:"token"`
+	}
+	ctx := context.WithValue(context.Background(), contextKey{}, "from context")
+	actual := &config{}
+	err := UnmarshalContext(ctx, []byte(`token = "${ctx:token}"`), actual, WithValueSource("ctx", contextValueSource{}))
+	require.NoError(t, err)
+	require.Equal(t, &config{Token: "from context"}, actual)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = UnmarshalContext(ctx, []byte(`token = "${ctx:token}"`), &config{}, WithValueSource("ctx", contextValueSource{}))
+	require.Equal(t, context.Canceled, err)
+}
+
+// cancellingValue cancels the decode context when it is decoded.
+type cancellingValue struct{}
+
+func (cancellingValue) UnmarshalHCL(ctx context.Context, v *Value) error {
+	ctx.Value(contextKey{}).(context.CancelFunc)()
+	return nil
+}
+
+func TestUnmarshalContextCancelledWhileDecoding(t *testing.T) {
+	type service struct {
+		Port int `hcl:"port"`
+	}
+	type config struct {
+		Cancel  cancellingValue `hcl:"cancel"`
+		Service service         `hcl:"service,block"`
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, contextKey{}, cancel)
+	actual := &config{}
+	err := UnmarshalContext(ctx, []byte("cancel = true\nservice {\n  port = 8080\n}\n"), actual)
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, &config{}, actual)
+
+	actual = &config{}
+	err = UnmarshalContext(context.WithValue(context.Background(), contextKey{}, context.CancelFunc(func() {})), []byte("cancel = true\nservice {\n  port = 8080\n}\n"), actual)
+	require.NoError(t, err)
+	require.Equal(t, 8080, actual.Service.Port)
+}
+
+func TestResolveValueSourcesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opt := newMarshalOptions(withContext(ctx), WithValueSource("ctx", contextValueSource{}))
+	_, err := resolveValueSources(str("${ctx:token}"), opt)
+	require.Equal(t, context.Canceled, err)
+}
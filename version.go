@@ -0,0 +1,80 @@
+package hcl
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle"
+)
+
+// schemaVersionAttr is the reserved attribute recording the schema version
+// of a document, when enabled with WithSchemaVersion.
+const schemaVersionAttr = "schema_version"
+
+// SchemaMigration upgrades documents from schema version From to From+1.
+type SchemaMigration struct {
+	From int
+	// Migrate rewrites the document in place. The schema_version attribute
+	// is updated by the caller.
+	Migrate func(ast *AST) error
+}
+
+// WithSchemaVersion pins documents to a schema version, recorded in a
+// reserved top-level "schema_version" attribute.
+//
+// When unmarshalling, the attribute is required, and documents with a newer
+// version are rejected. Documents with an older version are upgraded one
+// version at a time by migrations, and rejected if a migration is missing.
+// The attribute is removed before decoding, so the target struct does not
+// need a field for it. Documents are not modified; migrations are applied to
+// a copy.
+//
+// When marshalling, the attribute is emitted first.
+func WithSchemaVersion(version int, migrations ...SchemaMigration) MarshalOption {
+	return func(options *marshalOptions) {
+		options.schemaVersion = version
+		options.schemaMigrations = map[int]SchemaMigration{}
+		for _, migration := range migrations {
+			options.schemaMigrations[migration.From] = migration
+		}
+	}
+}
+
+// migrateSchemaVersion checks the schema version of ast, returning a copy
+// upgraded to the version pinned by WithSchemaVersion without the reserved
+// attribute, or ast itself if schema versions are not enabled.
+func migrateSchemaVersion(ast *AST, opt *marshalOptions) (*AST, error) {
+	if opt.schemaVersion == 0 {
+		return ast, nil
+	}
+	index := -1
+	for i, entry := range ast.Entries {
+		if entry.Attribute != nil && entry.Attribute.Key == schemaVersionAttr {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, participle.Errorf(ast.Pos, "missing required attribute %q, expected %d", schemaVersionAttr, opt.schemaVersion)
+	}
+	value := ast.Entries[index].Attribute.Value
+	if value.Number == nil || !value.Number.IsInt() {
+		return nil, participle.Errorf(value.Pos, "%s must be an integer but got %s", schemaVersionAttr, value)
+	}
+	n, _ := value.Number.Int64()
+	version := int(n)
+	if version > opt.schemaVersion {
+		return nil, participle.Errorf(value.Pos, "%s %d is newer than the supported version %d", schemaVersionAttr, version, opt.schemaVersion)
+	}
+	out := ast.Clone()
+	out.Entries = append(out.Entries[:index:index], out.Entries[index+1:]...)
+	for ; version < opt.schemaVersion; version++ {
+		migration, ok := opt.schemaMigrations[version]
+		if !ok || migration.Migrate == nil {
+			return nil, participle.Errorf(value.Pos, "no migration from %s %d to %d", schemaVersionAttr, version, version+1)
+		}
+		if err := migration.Migrate(out); err != nil {
+			return nil, fmt.Errorf("migrating from %s %d: %v", schemaVersionAttr, version, err)
+		}
+	}
+	return out, AddParentRefs(out)
+}
@@ -0,0 +1,49 @@
+package hcl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	type config struct {
+		Listen string `hcl:"listen"`
+	}
+	// Version 2 renamed "bind" to "listen".
+	migrations := []SchemaMigration{{From: 1, Migrate: func(ast *AST) error {
+		for _, entry := range ast.Entries {
+			if entry.Attribute != nil && entry.Attribute.Key == "bind" {
+				entry.Attribute.Key = "listen"
+			}
+		}
+		return nil
+	}}}
+	src := []byte("schema_version = 1\nbind = \":80\"\n")
+	out := &config{}
+	require.NoError(t, Unmarshal(src, out, WithSchemaVersion(2, migrations...)))
+	require.Equal(t, &config{Listen: ":80"}, out)
+
+	out = &config{}
+	require.NoError(t, Unmarshal([]byte("schema_version = 2\nlisten = \":80\"\n"), out, WithSchemaVersion(2)))
+	require.Equal(t, &config{Listen: ":80"}, out)
+
+	// The document is not modified by migrations.
+	ast, err := ParseBytes(src)
+	require.NoError(t, err)
+	require.NoError(t, UnmarshalAST(ast, &config{}, WithSchemaVersion(2, migrations...)))
+	require.Equal(t, "bind", ast.Entries[1].Attribute.Key)
+
+	err = Unmarshal(src, &config{}, WithSchemaVersion(2))
+	require.EqualError(t, err, "1:18: no migration from schema_version 1 to 2")
+	err = Unmarshal([]byte("schema_version = 3\n"), &config{}, WithSchemaVersion(2))
+	require.EqualError(t, err, "1:18: schema_version 3 is newer than the supported version 2")
+	err = Unmarshal([]byte(`schema_version = "2"`), &config{}, WithSchemaVersion(2))
+	require.EqualError(t, err, `1:18: schema_version must be an integer but got "2"`)
+	err = Unmarshal([]byte(`listen = ":80"`), &config{}, WithSchemaVersion(2))
+	require.EqualError(t, err, `1:1: missing required attribute "schema_version", expected 2`)
+
+	data, err := Marshal(&config{Listen: ":80"}, WithSchemaVersion(2))
+	require.NoError(t, err)
+	require.Equal(t, "schema_version = 2\nlisten = \":80\"\n", string(data))
+}
@@ -11,6 +11,11 @@ func Visit(node Node, visit func(node Node, next func() error) error) error {
 	return visit(node, func() error {
 		switch node := node.(type) {
 		case *AST:
+			for _, directive := range node.Directives {
+				if err := Visit(directive, visit); err != nil {
+					return err
+				}
+			}
 			for _, entry := range node.Entries {
 				if err := Visit(entry, visit); err != nil {
 					return err
@@ -39,6 +44,10 @@ func Visit(node Node, visit func(node Node, next func() error) error) error {
 
 		case *Value:
 			switch {
+			case node.Block != nil:
+				if err := Visit(node.Block, visit); err != nil {
+					return err
+				}
 			case node.HaveList:
 				for _, entry := range node.List {
 					if err := Visit(entry, visit); err != nil {
@@ -53,6 +62,13 @@ func Visit(node Node, visit func(node Node, next func() error) error) error {
 				}
 			}
 
+		case *Directive:
+			for _, arg := range node.Args {
+				if err := Visit(arg, visit); err != nil {
+					return err
+				}
+			}
+
 		case *Entry:
 			if node.Attribute != nil {
 				return Visit(node.Attribute, visit)
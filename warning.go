@@ -0,0 +1,47 @@
+package hcl
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/participle/lexer"
+)
+
+// Warning is a non-fatal problem found while unmarshalling, such as the use of
+// a deprecated attribute.
+//
+// Warning implements participle.Error, so it can be rendered with
+// DiagnosticWriter.WriteWarning.
+type Warning struct {
+	Pos lexer.Position
+	Msg string
+}
+
+func (w Warning) Error() string {
+	if w.Pos.Line == 0 {
+		return w.Msg
+	}
+	return fmt.Sprintf("%s: %s", w.Pos, w.Msg)
+}
+
+// Message returns the warning message without position information.
+func (w Warning) Message() string { return w.Msg }
+
+// Token returns a token positioned at the entry that triggered the warning.
+func (w Warning) Token() lexer.Token { return lexer.Token{Pos: w.Pos} }
+
+// WithWarningCallback calls fn for each warning emitted while unmarshalling.
+//
+// Warnings are discarded if no callback is registered.
+func WithWarningCallback(fn func(warning Warning)) MarshalOption {
+	return func(options *marshalOptions) {
+		options.warning = fn
+	}
+}
+
+// warn reports a warning to the registered callback, if any.
+func (o *marshalOptions) warn(pos lexer.Position, format string, args ...interface{}) {
+	if o.warning == nil {
+		return
+	}
+	o.warning(Warning{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
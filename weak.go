@@ -0,0 +1,86 @@
+package hcl
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// WithWeakTypes enables forgiving conversions between value types when
+// unmarshalling, for hand-written configuration:
+//
+//   - numbers and bools to strings
+//   - "true", "false", "1" and "0", and the numbers 1 and 0, to bools
+//   - strings containing numbers to numbers
+//   - a single scalar value to a one-element list
+func WithWeakTypes() MarshalOption {
+	return func(options *marshalOptions) {
+		options.weakTypes = true
+	}
+}
+
+// weakenValue converts v to the type of value expected by t, if the
+// conversion is one of those enabled by WithWeakTypes, returning a copy.
+// Otherwise v is returned unchanged.
+func weakenValue(t reflect.Type, v *Value) *Value {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == pathType || t == durationType || typeImplements(t, textUnmarshalerInterface) || typeImplements(t, jsonUnmarshalerInterface) {
+		return v
+	}
+	scalar := !v.HaveList && !v.HaveMap
+	out := &Value{Pos: v.Pos, Parent: v.Parent}
+	switch t.Kind() {
+	case reflect.String:
+		if t == numberType {
+			break
+		}
+		switch {
+		case v.Number != nil:
+			s := v.String()
+			out.Str = &s
+			return out
+		case v.Bool != nil:
+			s := v.String()
+			out.Str = &s
+			return out
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if v.Str == nil {
+			break
+		}
+		if n, err := parseNumber(strings.TrimSpace(*v.Str)); err == nil {
+			out.Number = n
+			return out
+		}
+
+	case reflect.Bool:
+		var b Bool
+		switch {
+		case v.Str != nil && (*v.Str == "true" || *v.Str == "1"):
+			b = true
+		case v.Str != nil && (*v.Str == "false" || *v.Str == "0"):
+			b = false
+		case v.Number != nil && v.Number.Sign() == 0:
+			b = false
+		case v.Number != nil && v.Number.Cmp(big.NewFloat(1)) == 0:
+			b = true
+		default:
+			return v
+		}
+		out.Bool = &b
+		return out
+
+	case reflect.Slice:
+		if t != bytesType && scalar {
+			out.HaveList = true
+			out.List = []*Value{v}
+			return out
+		}
+	}
+	return v
+}